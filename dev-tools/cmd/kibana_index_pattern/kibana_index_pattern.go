@@ -4,7 +4,9 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/kibana"
 	"github.com/elastic/beats/libbeat/version"
 )
@@ -12,9 +14,21 @@ import (
 func main() {
 	beatVersion := version.GetDefaultVersion()
 	index := flag.String("index", "", "The name of the index pattern. (required)")
+	title := flag.String("title", "", "The title of the index pattern, if it differs from the index name.")
+	id := flag.String("id", "", "The saved object id of the index pattern, instead of the id derived from the index name.")
+	timeField := flag.String("time-field", "@timestamp", "The name of the time field of the index pattern.")
 	beatName := flag.String("beat-name", "", "The name of the beat. (required)")
 	beatDir := flag.String("beat-dir", "", "The local beat directory. (required)")
+	fieldsFiles := flag.String("fields", "", "Comma separated list of fields.yml files to merge, instead of the fields.yml of the beat directory.")
 	version := flag.String("version", beatVersion, "The beat version.")
+	namespace := flag.String("namespace", "", "Only include the fields of this namespace, like a module name.")
+	fieldAttrs := flag.Bool("field-attrs", false, "Add field labels and descriptions as Kibana fieldAttrs.")
+	locale := flag.String("locale", "", "The locale of the field labels added as Kibana fieldAttrs, like de or pt-BR.")
+	keepSafeChars := flag.Bool("keep-safe-chars", false, "Keep hyphens in the beat name and namespace of the index pattern file names.")
+	checkOnly := flag.Bool("check-only", false, "Only check if the index pattern is up to date, exit with 1 and print the differences otherwise.")
+	formats := flag.String("formats", "", "Comma separated list of the index pattern formats to generate, 5.x, default or 8.x. All formats of the version are generated by default.")
+	validate := flag.Bool("validate", false, "Validate the structure of the index patterns before writing them.")
+	defaultFieldOutput := flag.String("default-field-output", "", "Also write the index settings with the index.query.default_field list of the fields to this file.")
 	flag.Parse()
 
 	if *index == "" {
@@ -32,13 +46,61 @@ func main() {
 		os.Exit(1)
 	}
 
-	indexPatternGenerator, err := kibana.NewGenerator(*index, *beatName, *beatDir, *version)
+	var indexPatternGenerator *kibana.IndexPatternGenerator
+	var err error
+	if *fieldsFiles != "" {
+		indexPatternGenerator, err = kibana.NewGeneratorFromFiles(*index, *beatName, *beatDir, *version, strings.Split(*fieldsFiles, ","))
+	} else {
+		indexPatternGenerator, err = kibana.NewGenerator(*index, *beatName, *beatDir, *version)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, err.Error())
 		os.Exit(1)
 	}
+	indexPatternGenerator.SetFieldAttrs(*fieldAttrs)
+	indexPatternGenerator.SetLocale(*locale)
+	indexPatternGenerator.SetKeepSafeChars(*keepSafeChars)
+	indexPatternGenerator.SetTitle(*title)
+	indexPatternGenerator.SetID(*id)
+	indexPatternGenerator.SetValidate(*validate)
+	if *formats != "" {
+		indexPatternGenerator.Formats = common.MakeStringSet(strings.Split(*formats, ",")...)
+	}
+	indexPatternGenerator.TimeFieldName = *timeField
+
+	if *checkOnly {
+		var ok bool
+		var diff string
+		if *namespace != "" {
+			ok, diff, err = indexPatternGenerator.CheckNamespace(*namespace)
+		} else {
+			ok, diff, err = indexPatternGenerator.Check()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if !ok {
+			fmt.Fprintf(os.Stdout, "-- The index pattern is not up to date, run `make update`:\n%v", diff)
+			os.Exit(1)
+		}
+		return
+	}
 
-	pattern, err := indexPatternGenerator.Generate()
+	if *defaultFieldOutput != "" {
+		if err := indexPatternGenerator.GenerateDefaultFieldSettings(*defaultFieldOutput); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stdout, "-- The default field settings were created under %v\n", *defaultFieldOutput)
+	}
+
+	var pattern []string
+	if *namespace != "" {
+		pattern, err = indexPatternGenerator.GenerateNamespace(*namespace)
+	} else {
+		pattern, err = indexPatternGenerator.Generate()
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, err.Error())
 		os.Exit(1)
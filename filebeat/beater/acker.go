@@ -10,6 +10,13 @@ type eventACKer struct {
 	out successLogger
 }
 
+// privateACKer is implemented by the private data of events requiring to be
+// notified once each event is acknowledged, like the events of the aws-s3
+// prospector.
+type privateACKer interface {
+	ACK()
+}
+
 type successLogger interface {
 	Published(states []file.State)
 }
@@ -25,6 +32,11 @@ func (a *eventACKer) ackEvents(data []interface{}) {
 			continue
 		}
 
+		if acker, ok := datum.(privateACKer); ok {
+			acker.ACK()
+			continue
+		}
+
 		st, ok := datum.(file.State)
 		if !ok {
 			continue
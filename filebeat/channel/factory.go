@@ -30,6 +30,7 @@ type prospectorOutletConfig struct {
 	// event processing
 	common.EventMetadata `config:",inline"`      // Fields and tags to add to events.
 	Processors           processors.PluginConfig `config:"processors"`
+	ProcessingPipeline   string                  `config:"processing_pipeline"` // named processing pipeline
 
 	// implicit event fields
 	Type string `config:"type"` // prospector.type
@@ -102,12 +103,13 @@ func (f *OutletFactory) Create(cfg *common.Config) (Outleter, error) {
 	}
 
 	client, err := f.pipeline.ConnectWith(beat.ClientConfig{
-		PublishMode:   beat.GuaranteedSend,
-		EventMetadata: config.EventMetadata,
-		Meta:          meta,
-		Fields:        fields,
-		Processor:     processors,
-		Events:        f.eventer,
+		PublishMode:        beat.GuaranteedSend,
+		EventMetadata:      config.EventMetadata,
+		Meta:               meta,
+		Fields:             fields,
+		Processor:          processors,
+		ProcessingPipeline: config.ProcessingPipeline,
+		Events:             f.eventer,
 	})
 	if err != nil {
 		return nil, err
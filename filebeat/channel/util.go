@@ -3,6 +3,7 @@ package channel
 import (
 	"github.com/elastic/beats/filebeat/util"
 	"github.com/elastic/beats/libbeat/common/atomic"
+	"github.com/elastic/beats/libbeat/monitoring"
 )
 
 type subOutlet struct {
@@ -86,3 +87,22 @@ func CloseOnSignal(outlet Outleter, sig <-chan struct{}) Outleter {
 	}
 	return outlet
 }
+
+type countingOutlet struct {
+	Outleter
+	events *monitoring.Int
+}
+
+// CountEvents counts the events accepted by the outlet. State updates without
+// an event are not counted.
+func CountEvents(out Outleter, events *monitoring.Int) Outleter {
+	return &countingOutlet{Outleter: out, events: events}
+}
+
+func (o *countingOutlet) OnEvent(d *util.Data) bool {
+	ok := o.Outleter.OnEvent(d)
+	if ok && d.HasEvent() {
+		o.events.Inc()
+	}
+	return ok
+}
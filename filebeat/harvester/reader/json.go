@@ -79,12 +79,37 @@ func (r *JSON) Next() (Message, error) {
 		return message, err
 	}
 
+	var order []string
+	if r.cfg.PreserveOrder {
+		order = r.fieldOrder(message.Content)
+	}
+
 	var fields common.MapStr
 	message.Content, fields = r.decodeJSON(message.Content)
 	message.AddFields(common.MapStr{"json": fields})
+	if len(order) > 0 {
+		message.Meta = common.MapStr{jsontransform.FieldOrderKey: order}
+	}
 	return message, nil
 }
 
+// fieldOrder returns the paths of the keys in the order of the JSON document,
+// relative to the event the keys are written to.
+func (r *JSON) fieldOrder(text []byte) []string {
+	order, err := jsontransform.FieldOrder(text)
+	if err != nil {
+		// The decoding error is reported by decodeJSON.
+		return nil
+	}
+
+	if !r.cfg.KeysUnderRoot {
+		for i, key := range order {
+			order[i] = "json." + key
+		}
+	}
+	return order
+}
+
 func createJSONError(message string) common.MapStr {
 	return common.MapStr{"message": message, "type": "json"}
 }
@@ -5,6 +5,7 @@ type JSONConfig struct {
 	KeysUnderRoot bool   `config:"keys_under_root"`
 	OverwriteKeys bool   `config:"overwrite_keys"`
 	AddErrorKey   bool   `config:"add_error_key"`
+	PreserveOrder bool   `config:"preserve_order"`
 }
 
 func (c *JSONConfig) Validate() error {
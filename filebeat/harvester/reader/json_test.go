@@ -1,6 +1,7 @@
 package reader
 
 import (
+	"io"
 	"testing"
 	"time"
 
@@ -314,3 +315,58 @@ func TestAddJSONFields(t *testing.T) {
 		})
 	}
 }
+
+type messageReader struct {
+	messages []Message
+}
+
+func (r *messageReader) Next() (Message, error) {
+	if len(r.messages) == 0 {
+		return Message{}, io.EOF
+	}
+	m := r.messages[0]
+	r.messages = r.messages[1:]
+	return m, nil
+}
+
+func TestJSONPreserveOrder(t *testing.T) {
+	text := `{"z": 1, "a": {"y": [{"k": 1}], "b": "x"}, "m": null}`
+
+	tests := []struct {
+		Config   JSONConfig
+		Expected []string
+	}{
+		{
+			Config:   JSONConfig{},
+			Expected: nil,
+		},
+		{
+			Config:   JSONConfig{PreserveOrder: true},
+			Expected: []string{"json.z", "json.a", "json.a.y", "json.a.y.0.k", "json.a.b", "json.m"},
+		},
+		{
+			Config:   JSONConfig{PreserveOrder: true, KeysUnderRoot: true},
+			Expected: []string{"z", "a", "a.y", "a.y.0.k", "a.b", "m"},
+		},
+	}
+
+	for _, test := range tests {
+		r := NewJSON(&messageReader{messages: []Message{{Content: []byte(text), Bytes: len(text)}}}, &test.Config)
+		message, err := r.Next()
+		assert.NoError(t, err)
+
+		if test.Expected == nil {
+			assert.Nil(t, message.Meta)
+			continue
+		}
+		assert.Equal(t, common.MapStr{"field_order": test.Expected}, message.Meta)
+	}
+}
+
+func TestJSONPreserveOrderInvalid(t *testing.T) {
+	text := `{"z": 1, "a": `
+	r := NewJSON(&messageReader{messages: []Message{{Content: []byte(text), Bytes: len(text)}}}, &JSONConfig{PreserveOrder: true})
+	message, err := r.Next()
+	assert.NoError(t, err)
+	assert.Nil(t, message.Meta)
+}
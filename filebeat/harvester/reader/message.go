@@ -13,6 +13,7 @@ type Message struct {
 	Content []byte        // actual content read
 	Bytes   int           // total number of bytes read to generate the message
 	Fields  common.MapStr // optional fields that can be added by reader
+	Meta    common.MapStr // optional event metadata that can be added by reader
 }
 
 // IsEmpty returns true in case the message is empty
@@ -233,8 +233,9 @@ func (mlr *Multiline) readFailed() (Message, error) {
 // run clear or finalize before.
 func (mlr *Multiline) load(m Message) {
 	mlr.addLine(m)
-	// Timestamp of first message is taken as overall timestamp
+	// Timestamp and metadata of first message are taken for the overall message
 	mlr.message.Ts = m.Ts
+	mlr.message.Meta = m.Meta
 	mlr.message.AddFields(m.Fields)
 }
 
@@ -0,0 +1,54 @@
+package reader
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/protobuf"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// Protobuf reads length prefixed protobuf messages, decoding each message
+// into the `protobuf` field.
+type Protobuf struct {
+	frames  *protobuf.FrameReader
+	decoder *protobuf.Decoder
+}
+
+// NewProtobuf creates a new reader decoding the messages of r. Messages larger
+// than maxBytes cause an error, as the stream can not be resynchronized.
+func NewProtobuf(r io.Reader, decoder *protobuf.Decoder, framing protobuf.FramingConfig, maxBytes int) *Protobuf {
+	return &Protobuf{
+		frames:  protobuf.NewFrameReader(r, framing, maxBytes),
+		decoder: decoder,
+	}
+}
+
+// Next reads and decodes the next message. If the message can not be
+// decoded, the message contains the decoding error.
+func (r *Protobuf) Next() (Message, error) {
+	data, n, err := r.frames.Next()
+	if err != nil {
+		return Message{}, err
+	}
+
+	message := Message{
+		Ts:    time.Now(),
+		Bytes: n,
+	}
+
+	fields, err := r.decoder.Decode(data)
+	if err != nil {
+		logp.Err("Error decoding protobuf message: %v", err)
+		message.AddFields(common.MapStr{"error": common.MapStr{
+			"message": fmt.Sprintf("Error decoding protobuf message: %v", err),
+			"type":    "protobuf",
+		}})
+		return message, nil
+	}
+
+	message.AddFields(common.MapStr{"protobuf": fields})
+	return message, nil
+}
@@ -0,0 +1,54 @@
+package reader
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/protobuf"
+)
+
+func TestProtobufReader(t *testing.T) {
+	decoder, err := protobuf.NewDecoder(protobuf.Config{
+		DescriptorSet: "../../../libbeat/common/protobuf/testdata/sample.desc",
+		MessageType:   "sample.Event",
+	})
+	require.NoError(t, err)
+
+	stream := []byte{
+		// message: "hello", count: 2
+		9, 0x0a, 5, 'h', 'e', 'l', 'l', 'o', 0x10, 2,
+		// count: truncated
+		1, 0x10,
+		// empty message
+		0,
+	}
+
+	r := NewProtobuf(bytes.NewReader(stream), decoder, protobuf.FramingConfig{}, 100)
+
+	message, err := r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, 10, message.Bytes)
+	assert.Empty(t, message.Content)
+	assert.Equal(t, common.MapStr{
+		"protobuf": common.MapStr{"message": "hello", "count": int64(2)},
+	}, message.Fields)
+
+	message, err = r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, 2, message.Bytes)
+	errType, _ := message.Fields.GetValue("error.type")
+	assert.Equal(t, "protobuf", errType)
+
+	message, err = r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, 1, message.Bytes)
+	assert.Equal(t, common.MapStr{"protobuf": common.MapStr{}}, message.Fields)
+
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,65 @@
+package awss3
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/atomic"
+)
+
+// messageACK tracks the events published for a SQS message. Once all events
+// have been acknowledged by the publisher pipeline, onDone is called to delete
+// the message from the queue. If reading any of the objects of the message
+// failed, the message is not deleted, so it is received again after the
+// visibility timeout.
+//
+// Each published event references the tracker by its eventACK private data.
+// The pipeline ACK handler calls ACK for each acknowledged event.
+type messageACK struct {
+	pending atomic.Int64
+	failed  atomic.Bool
+	onDone  func()
+}
+
+// newMessageACK creates a tracker. The message is considered being read until
+// close is called.
+func newMessageACK(onDone func()) *messageACK {
+	return &messageACK{
+		pending: atomic.MakeInt64(1),
+		onDone:  onDone,
+	}
+}
+
+// add registers an event being published.
+func (a *messageACK) add() { a.pending.Inc() }
+
+// fail marks the message as not completely read. It must be called before
+// close.
+func (a *messageACK) fail() { a.failed.Store(true) }
+
+// close signals all events of the message have been published.
+func (a *messageACK) close() { a.done() }
+
+// ACK acknowledges a published event.
+func (a *messageACK) ACK() { a.done() }
+
+func (a *messageACK) done() {
+	if a.pending.Dec() == 0 && !a.failed.Load() {
+		a.onDone()
+	}
+}
+
+// eventACK is the private data of a published event. The fields of the event
+// are owned by eventACK, and returned to the pool once the event has been
+// acknowledged.
+type eventACK struct {
+	message *messageACK
+	pool    *common.MapStrPool
+	fields  common.MapStr
+}
+
+// ACK releases the event fields and acknowledges the event to the message.
+// The fields must not be accessed after calling ACK.
+func (a *eventACK) ACK() {
+	a.pool.Put(a.fields)
+	a.fields = nil
+	a.message.ACK()
+}
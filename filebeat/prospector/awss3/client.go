@@ -0,0 +1,188 @@
+package awss3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common/aws"
+)
+
+const sqsAPIVersion = "2012-11-05"
+
+// sqsMessage is a message received from a SQS queue.
+type sqsMessage struct {
+	ID            string `xml:"MessageId"`
+	ReceiptHandle string `xml:"ReceiptHandle"`
+	Body          string `xml:"Body"`
+}
+
+// sqsAPI is the subset of the SQS API used by the prospector.
+type sqsAPI interface {
+	ReceiveMessages(ctx context.Context) ([]sqsMessage, error)
+	DeleteMessage(ctx context.Context, receiptHandle string) error
+}
+
+// s3API is the subset of the S3 API used by the prospector.
+type s3API interface {
+	GetObject(ctx context.Context, region, bucket, key string) (io.ReadCloser, error)
+}
+
+// apiError is an error returned by the AWS APIs.
+type apiError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%v (%d): %v", e.Code, e.StatusCode, e.Message)
+}
+
+// sqsClient calls the SQS query API of a single queue.
+type sqsClient struct {
+	http              *http.Client
+	queueURL          string
+	region            string
+	creds             aws.Credentials
+	visibilityTimeout time.Duration
+	waitTime          time.Duration
+	maxMessages       int
+}
+
+type receiveMessageResponse struct {
+	Messages []sqsMessage `xml:"ReceiveMessageResult>Message"`
+}
+
+func (c *sqsClient) ReceiveMessages(ctx context.Context) ([]sqsMessage, error) {
+	var resp receiveMessageResponse
+	err := c.call(ctx, url.Values{
+		"Action":              {"ReceiveMessage"},
+		"MaxNumberOfMessages": {strconv.Itoa(c.maxMessages)},
+		"VisibilityTimeout":   {strconv.Itoa(int(c.visibilityTimeout / time.Second))},
+		"WaitTimeSeconds":     {strconv.Itoa(int(c.waitTime / time.Second))},
+	}, &resp)
+	return resp.Messages, err
+}
+
+func (c *sqsClient) DeleteMessage(ctx context.Context, receiptHandle string) error {
+	return c.call(ctx, url.Values{
+		"Action":        {"DeleteMessage"},
+		"ReceiptHandle": {receiptHandle},
+	}, nil)
+}
+
+func (c *sqsClient) call(ctx context.Context, params url.Values, v interface{}) error {
+	params.Set("Version", sqsAPIVersion)
+	body := []byte(params.Encode())
+
+	req, err := http.NewRequest("POST", c.queueURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	aws.SignRequest(req, body, c.creds, c.region, "sqs", time.Now())
+
+	resp, err := c.http.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return readAPIError(resp)
+	}
+	if v == nil {
+		return nil
+	}
+	return xml.NewDecoder(resp.Body).Decode(v)
+}
+
+// s3Client downloads objects from S3. If endpoint is set, objects are
+// requested from the endpoint using path-style URLs.
+type s3Client struct {
+	http     *http.Client
+	creds    aws.Credentials
+	endpoint string
+}
+
+func (c *s3Client) GetObject(ctx context.Context, region, bucket, key string) (io.ReadCloser, error) {
+	u, err := c.objectURL(region, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.URL = u
+	req.Header.Set("X-Amz-Content-Sha256", aws.EmptyBodySHA256)
+	aws.SignRequest(req, nil, c.creds, region, "s3", time.Now())
+
+	resp, err := c.http.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, readAPIError(resp)
+	}
+	return resp.Body, nil
+}
+
+// objectURL returns the URL of an object. Virtual-hosted style URLs are used,
+// unless the bucket name contains dots, which are not valid in the TLS
+// certificate of the virtual host.
+func (c *s3Client) objectURL(region, bucket, key string) (*url.URL, error) {
+	path := "/" + key
+	host := bucket + ".s3." + region + ".amazonaws.com"
+	if c.endpoint != "" || strings.Contains(bucket, ".") {
+		path = "/" + bucket + path
+		host = "s3." + region + ".amazonaws.com"
+	}
+
+	u := &url.URL{Scheme: "https", Host: host}
+	if c.endpoint != "" {
+		var err error
+		if u, err = url.Parse(c.endpoint); err != nil {
+			return nil, err
+		}
+	}
+
+	u.Path = path
+	u.RawPath = aws.URIEncode(path, false)
+	return u, nil
+}
+
+func readAPIError(resp *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	// S3 returns the error as root element, SQS wraps it in an ErrorResponse.
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+		Error   struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		} `xml:"Error"`
+	}
+	xml.Unmarshal(body, &e)
+
+	err := &apiError{StatusCode: resp.StatusCode, Code: e.Code, Message: e.Message}
+	if e.Error.Code != "" {
+		err.Code, err.Message = e.Error.Code, e.Error.Message
+	}
+	if err.Code == "" {
+		err.Code = http.StatusText(resp.StatusCode)
+	}
+	return err
+}
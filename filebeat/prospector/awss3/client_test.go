@@ -0,0 +1,142 @@
+package awss3
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common/aws"
+)
+
+var testCredentials = aws.Credentials{
+	AccessKeyID:     "AKIDEXAMPLE",
+	SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+}
+
+func TestSQSClient(t *testing.T) {
+	var actions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "/123456789012/queue", r.URL.Path)
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"))
+
+		action := r.PostForm.Get("Action")
+		actions = append(actions, action)
+		switch action {
+		case "ReceiveMessage":
+			assert.Equal(t, "10", r.PostForm.Get("MaxNumberOfMessages"))
+			assert.Equal(t, "300", r.PostForm.Get("VisibilityTimeout"))
+			assert.Equal(t, "20", r.PostForm.Get("WaitTimeSeconds"))
+			w.Write([]byte(`<ReceiveMessageResponse>
+				<ReceiveMessageResult>
+					<Message>
+						<MessageId>id-1</MessageId>
+						<ReceiptHandle>handle-1</ReceiptHandle>
+						<Body>{"Records": []}</Body>
+					</Message>
+				</ReceiveMessageResult>
+			</ReceiveMessageResponse>`))
+		case "DeleteMessage":
+			if r.PostForm.Get("ReceiptHandle") != "handle-1" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`<ErrorResponse><Error><Type>Sender</Type>` +
+					`<Code>ReceiptHandleIsInvalid</Code><Message>invalid handle</Message>` +
+					`</Error></ErrorResponse>`))
+			}
+		}
+	}))
+	defer server.Close()
+
+	client := &sqsClient{
+		http:              http.DefaultClient,
+		queueURL:          server.URL + "/123456789012/queue",
+		region:            "us-east-1",
+		creds:             testCredentials,
+		visibilityTimeout: defaultConfig.VisibilityTimeout,
+		waitTime:          defaultConfig.WaitTime,
+		maxMessages:       defaultConfig.MaxNumberOfMessages,
+	}
+
+	messages, err := client.ReceiveMessages(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []sqsMessage{{ID: "id-1", ReceiptHandle: "handle-1", Body: `{"Records": []}`}}, messages)
+
+	assert.NoError(t, client.DeleteMessage(context.Background(), "handle-1"))
+
+	err = client.DeleteMessage(context.Background(), "other")
+	if assert.IsType(t, &apiError{}, err) {
+		assert.Equal(t, "ReceiptHandleIsInvalid", err.(*apiError).Code)
+		assert.Equal(t, http.StatusBadRequest, err.(*apiError).StatusCode)
+	}
+
+	assert.Equal(t, []string{"ReceiveMessage", "DeleteMessage", "DeleteMessage"}, actions)
+}
+
+func TestS3ClientGetObject(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/s3/aws4_request")
+
+		if r.URL.EscapedPath() != "/logs/dir/a%20b%2Bc.log" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+			return
+		}
+		w.Write([]byte("line\n"))
+	}))
+	defer server.Close()
+
+	client := &s3Client{http: http.DefaultClient, creds: testCredentials, endpoint: server.URL}
+
+	body, err := client.GetObject(context.Background(), "eu-west-1", "logs", "dir/a b+c.log")
+	require.NoError(t, err)
+	content, err := ioutil.ReadAll(body)
+	body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "line\n", string(content))
+
+	_, err = client.GetObject(context.Background(), "eu-west-1", "logs", "missing.log")
+	if assert.IsType(t, &apiError{}, err) {
+		assert.Equal(t, "NoSuchKey", err.(*apiError).Code)
+	}
+}
+
+func TestS3ObjectURL(t *testing.T) {
+	client := &s3Client{}
+
+	u, err := client.objectURL("eu-west-1", "logs", "dir/a b.log")
+	require.NoError(t, err)
+	assert.Equal(t, "https://logs.s3.eu-west-1.amazonaws.com/dir/a%20b.log", u.String())
+
+	u, err = client.objectURL("eu-west-1", "my.logs", "a.log")
+	require.NoError(t, err)
+	assert.Equal(t, "https://s3.eu-west-1.amazonaws.com/my.logs/a.log", u.String())
+}
+
+func TestConfigRegion(t *testing.T) {
+	tests := map[string]string{
+		"https://sqs.eu-west-1.amazonaws.com/123456789012/queue":   "eu-west-1",
+		"https://us-east-2.queue.amazonaws.com/123456789012/queue": "us-east-2",
+	}
+	for queueURL, expected := range tests {
+		c := config{QueueURL: queueURL}
+		region, err := c.region()
+		require.NoError(t, err)
+		assert.Equal(t, expected, region)
+	}
+
+	c := config{QueueURL: "https://localhost/queue"}
+	_, err := c.region()
+	assert.Error(t, err)
+
+	c.Region = "ap-south-1"
+	region, err := c.region()
+	require.NoError(t, err)
+	assert.Equal(t, "ap-south-1", region)
+}
@@ -0,0 +1,92 @@
+package awss3
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/filebeat/harvester"
+	"github.com/elastic/beats/libbeat/common/aws"
+)
+
+var defaultConfig = config{
+	ForwarderConfig: harvester.ForwarderConfig{
+		Type: "aws-s3",
+	},
+	VisibilityTimeout:   300 * time.Second,
+	WaitTime:            20 * time.Second,
+	MaxNumberOfMessages: 10,
+	APITimeout:          60 * time.Second,
+}
+
+type config struct {
+	harvester.ForwarderConfig `config:",inline"`
+	QueueURL                  string        `config:"queue_url" validate:"required"`
+	Region                    string        `config:"region"`
+	AccessKeyID               string        `config:"access_key_id"`
+	SecretAccessKey           string        `config:"secret_access_key"`
+	SessionToken              string        `config:"session_token"`
+	VisibilityTimeout         time.Duration `config:"visibility_timeout"`
+	WaitTime                  time.Duration `config:"wait_time" validate:"min=0"`
+	MaxNumberOfMessages       int           `config:"max_number_of_messages" validate:"min=1, max=10"`
+	APITimeout                time.Duration `config:"api_timeout" validate:"positive"`
+}
+
+func (c *config) Validate() error {
+	if _, err := url.Parse(c.QueueURL); err != nil {
+		return fmt.Errorf("invalid queue_url '%v': %v", c.QueueURL, err)
+	}
+	if c.VisibilityTimeout <= 0 || c.VisibilityTimeout > 12*time.Hour {
+		return fmt.Errorf("visibility_timeout %v must be greater than 0 and at most 12h", c.VisibilityTimeout)
+	}
+	if c.WaitTime > 20*time.Second {
+		return fmt.Errorf("wait_time %v must be at most 20s", c.WaitTime)
+	}
+	return nil
+}
+
+// credentials returns the configured AWS credentials, falling back to the
+// standard AWS environment variables.
+func (c *config) credentials() (aws.Credentials, error) {
+	creds := aws.Credentials{
+		AccessKeyID:     c.AccessKeyID,
+		SecretAccessKey: c.SecretAccessKey,
+		SessionToken:    c.SessionToken,
+	}
+	if creds.AccessKeyID == "" && creds.SecretAccessKey == "" {
+		creds = aws.Credentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+	}
+
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return creds, fmt.Errorf("access_key_id and secret_access_key are required")
+	}
+	return creds, nil
+}
+
+// region returns the configured region or the region of the SQS queue URL,
+// like https://sqs.us-east-1.amazonaws.com/123456789012/queue.
+func (c *config) region() (string, error) {
+	if c.Region != "" {
+		return c.Region, nil
+	}
+
+	u, err := url.Parse(c.QueueURL)
+	if err != nil {
+		return "", err
+	}
+
+	parts := strings.Split(u.Hostname(), ".")
+	switch {
+	case len(parts) > 2 && parts[0] == "sqs":
+		return parts[1], nil
+	case len(parts) > 2 && parts[1] == "queue":
+		return parts[0], nil
+	}
+	return "", fmt.Errorf("can not get the region from queue_url '%v', region must be set", c.QueueURL)
+}
@@ -0,0 +1,380 @@
+package awss3
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/filebeat/channel"
+	"github.com/elastic/beats/filebeat/harvester"
+	"github.com/elastic/beats/filebeat/prospector"
+	"github.com/elastic/beats/filebeat/util"
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+const errorBackoff = 10 * time.Second
+
+var errOutletClosed = errors.New("prospector outlet closed")
+
+// fieldsPool provides the maps of the published events. Each line of an object
+// is published as an event, so the maps are reused for reducing the
+// allocations. The maps are released when the event is acknowledged.
+var fieldsPool = common.NewMapStrPool(4)
+
+func init() {
+	err := prospector.Register("aws-s3", NewProspector)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Prospector polls a SQS queue for notifications of new S3 objects, and
+// publishes the lines of the objects. A SQS message is deleted once all events
+// read from the objects it references have been acknowledged. Messages are
+// received again after the visibility timeout if the objects could not be
+// read or the events were not acknowledged in time, so events are delivered
+// at least once.
+type Prospector struct {
+	config    config
+	region    string
+	outlet    channel.Outleter
+	forwarder *harvester.Forwarder
+	sqs       sqsAPI
+	s3        s3API
+	backoff   time.Duration
+
+	started bool
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewProspector creates a new aws-s3 prospector
+func NewProspector(cfg *common.Config, outletFactory channel.Factory, context prospector.Context) (prospector.Prospectorer, error) {
+	cfgwarn.Experimental("aws-s3 prospector type is used")
+
+	config := defaultConfig
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, err
+	}
+
+	creds, err := config.credentials()
+	if err != nil {
+		return nil, err
+	}
+
+	region, err := config.region()
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			ResponseHeaderTimeout: config.WaitTime + config.APITimeout,
+		},
+	}
+
+	outlet, err := outletFactory(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	sqs := &sqsClient{
+		http:              client,
+		queueURL:          config.QueueURL,
+		region:            region,
+		creds:             creds,
+		visibilityTimeout: config.VisibilityTimeout,
+		waitTime:          config.WaitTime,
+		maxMessages:       config.MaxNumberOfMessages,
+	}
+	s3 := &s3Client{http: client, creds: creds}
+
+	return newProspector(config, region, outlet, sqs, s3), nil
+}
+
+func newProspector(config config, region string, outlet channel.Outleter, sqs sqsAPI, s3 s3API) *Prospector {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Prospector{
+		config:    config,
+		region:    region,
+		outlet:    outlet,
+		forwarder: harvester.NewForwarder(outlet),
+		sqs:       sqs,
+		s3:        s3,
+		backoff:   errorBackoff,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Run starts polling the SQS queue
+func (p *Prospector) Run() {
+	if p.started {
+		return
+	}
+	p.started = true
+
+	logp.Info("Starting aws-s3 prospector for queue %v", p.config.QueueURL)
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer p.outlet.Close()
+		p.run()
+	}()
+}
+
+// Stop stops polling the SQS queue and waits for the current messages to be
+// processed. Messages whose events are not acknowledged yet are not deleted.
+func (p *Prospector) Stop() {
+	logp.Info("Stopping aws-s3 prospector for queue %v", p.config.QueueURL)
+	p.cancel()
+	p.wg.Wait()
+}
+
+// Wait stops the prospector
+func (p *Prospector) Wait() {
+	p.Stop()
+}
+
+func (p *Prospector) run() {
+	for {
+		messages, err := p.sqs.ReceiveMessages(p.ctx)
+		if err != nil {
+			if p.ctx.Err() != nil {
+				return
+			}
+
+			logp.Err("Failed to receive messages from SQS queue %v: %v", p.config.QueueURL, err)
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-time.After(p.backoff):
+			}
+			continue
+		}
+
+		for _, msg := range messages {
+			if err := p.processMessage(msg); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// processMessage publishes the events of the objects referenced by the
+// message. Only an error closing the prospector is returned.
+func (p *Prospector) processMessage(msg sqsMessage) error {
+	ack := newMessageACK(func() { p.deleteMessage(msg) })
+	defer ack.close()
+
+	records, err := parseNotification(msg.Body)
+	if err != nil {
+		logp.Err("Failed to parse SQS message %v: %v", msg.ID, err)
+		ack.fail()
+		return nil
+	}
+
+	for _, record := range records {
+		err := p.processObject(ack, record)
+		if err == nil {
+			continue
+		}
+
+		ack.fail()
+		if err == errOutletClosed || p.ctx.Err() != nil {
+			return errOutletClosed
+		}
+		logp.Err("Failed to read S3 object s3://%v/%v of SQS message %v: %v",
+			record.bucket, record.key, msg.ID, err)
+		return nil
+	}
+	return nil
+}
+
+func (p *Prospector) processObject(ack *messageACK, record s3Record) error {
+	region := record.region
+	if region == "" {
+		region = p.region
+	}
+
+	body, err := p.s3.GetObject(p.ctx, region, record.bucket, record.key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	reader, err := newObjectReader(body)
+	if err != nil {
+		return err
+	}
+
+	source := "s3://" + record.bucket + "/" + record.key
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if err := p.publish(ack, record, source, offset, line); err != nil {
+				return err
+			}
+			offset += int64(len(line))
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (p *Prospector) publish(ack *messageACK, record s3Record, source string, offset int64, line []byte) error {
+	fields := newEventFields(record, source, offset, line)
+
+	data := util.NewData()
+	data.Event = beat.Event{
+		Timestamp: time.Now(),
+		Fields:    fields,
+		Private:   &eventACK{message: ack, pool: fieldsPool, fields: fields},
+	}
+
+	ack.add()
+	if err := p.forwarder.Send(data); err != nil {
+		ack.fail()
+		ack.done()
+		return errOutletClosed
+	}
+	return nil
+}
+
+// newEventFields creates the fields of an event from the pool. The fields only
+// contain maps owned by the event, so they can be released by Put.
+func newEventFields(record s3Record, source string, offset int64, line []byte) common.MapStr {
+	bucket := fieldsPool.Get()
+	bucket["name"] = record.bucket
+
+	object := fieldsPool.Get()
+	object["key"] = record.key
+
+	s3 := fieldsPool.Get()
+	s3["bucket"] = bucket
+	s3["object"] = object
+
+	aws := fieldsPool.Get()
+	aws["s3"] = s3
+
+	fields := fieldsPool.Get()
+	fields["message"] = string(bytes.TrimRight(line, "\r\n"))
+	fields["source"] = source
+	fields["offset"] = offset
+	fields["aws"] = aws
+	return fields
+}
+
+func (p *Prospector) deleteMessage(msg sqsMessage) {
+	// Events are acknowledged by the publisher pipeline, deleting the
+	// message must not block it.
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), p.config.APITimeout)
+		defer cancel()
+
+		if err := p.sqs.DeleteMessage(ctx, msg.ReceiptHandle); err != nil {
+			logp.Err("Failed to delete SQS message %v: %v", msg.ID, err)
+			return
+		}
+		logp.Debug("aws-s3", "Deleted SQS message %v", msg.ID)
+	}()
+}
+
+// newObjectReader returns a reader of the object contents, decompressing
+// gzip compressed objects.
+func newObjectReader(body io.Reader) (*bufio.Reader, error) {
+	reader := bufio.NewReader(body)
+	magic, err := reader.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		return bufio.NewReader(gz), nil
+	}
+	return reader, nil
+}
+
+// s3Record is an object referenced by a S3 event notification.
+type s3Record struct {
+	region string
+	bucket string
+	key    string
+}
+
+type s3Notification struct {
+	Records []struct {
+		EventSource string `json:"eventSource"`
+		EventName   string `json:"eventName"`
+		AWSRegion   string `json:"awsRegion"`
+		S3          struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+			Object struct {
+				Key string `json:"key"`
+			} `json:"object"`
+		} `json:"s3"`
+	} `json:"Records"`
+
+	// Message contains the S3 event notification, if it is delivered to the
+	// queue by a SNS topic.
+	Message string `json:"Message"`
+}
+
+// parseNotification returns the created objects of a S3 event notification.
+// Test events and events of other types are ignored.
+func parseNotification(body string) ([]s3Record, error) {
+	var notification s3Notification
+	if err := json.Unmarshal([]byte(body), &notification); err != nil {
+		return nil, err
+	}
+	if len(notification.Records) == 0 && notification.Message != "" {
+		return parseNotification(notification.Message)
+	}
+
+	var records []s3Record
+	for _, record := range notification.Records {
+		if record.EventSource != "aws:s3" || !strings.HasPrefix(record.EventName, "ObjectCreated:") {
+			continue
+		}
+
+		// Object keys are URL encoded in the notifications.
+		key, err := url.QueryUnescape(record.S3.Object.Key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid object key '%v': %v", record.S3.Object.Key, err)
+		}
+
+		records = append(records, s3Record{
+			region: record.AWSRegion,
+			bucket: record.S3.Bucket.Name,
+			key:    key,
+		})
+	}
+	return records, nil
+}
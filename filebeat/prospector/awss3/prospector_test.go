@@ -0,0 +1,330 @@
+package awss3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/filebeat/util"
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// mockSQS is a queue keeping received messages in flight until they are
+// deleted or redelivered.
+type mockSQS struct {
+	mu       sync.Mutex
+	visible  []sqsMessage
+	inflight map[string]sqsMessage
+	deleted  chan string
+}
+
+func newMockSQS(messages ...sqsMessage) *mockSQS {
+	return &mockSQS{
+		visible:  messages,
+		inflight: map[string]sqsMessage{},
+		deleted:  make(chan string, 100),
+	}
+}
+
+func (q *mockSQS) ReceiveMessages(ctx context.Context) ([]sqsMessage, error) {
+	q.mu.Lock()
+	messages := q.visible
+	q.visible = nil
+	for _, msg := range messages {
+		q.inflight[msg.ReceiptHandle] = msg
+	}
+	q.mu.Unlock()
+
+	if len(messages) > 0 {
+		return messages, nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(10 * time.Millisecond):
+		return nil, nil
+	}
+}
+
+func (q *mockSQS) DeleteMessage(ctx context.Context, receiptHandle string) error {
+	q.mu.Lock()
+	delete(q.inflight, receiptHandle)
+	q.mu.Unlock()
+
+	q.deleted <- receiptHandle
+	return nil
+}
+
+// redeliver makes the messages in flight visible again, like after the
+// visibility timeout expired.
+func (q *mockSQS) redeliver() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for handle, msg := range q.inflight {
+		q.visible = append(q.visible, msg)
+		delete(q.inflight, handle)
+	}
+}
+
+func (q *mockSQS) waitDeleted(t *testing.T, n int) []string {
+	var handles []string
+	for len(handles) < n {
+		select {
+		case handle := <-q.deleted:
+			handles = append(handles, handle)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for deleted messages, got %v", handles)
+		}
+	}
+	sort.Strings(handles)
+	return handles
+}
+
+func (q *mockSQS) assertNotDeleted(t *testing.T) {
+	select {
+	case handle := <-q.deleted:
+		t.Fatalf("unexpected deletion of message %v", handle)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+type mockS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *mockS3) put(bucket, key string, content []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[bucket+"/"+key] = content
+}
+
+func (s *mockS3) GetObject(ctx context.Context, region, bucket, key string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content, found := s.objects[bucket+"/"+key]
+	if !found {
+		return nil, &apiError{StatusCode: http.StatusNotFound, Code: "NoSuchKey"}
+	}
+	return ioutil.NopCloser(bytes.NewReader(content)), nil
+}
+
+// mockOutlet records the published events. Events are acknowledged by the
+// tests.
+type mockOutlet struct {
+	events chan beat.Event
+}
+
+func (o *mockOutlet) OnEvent(d *util.Data) bool {
+	o.events <- d.GetEvent()
+	return true
+}
+
+func (o *mockOutlet) Close() error { return nil }
+
+func (o *mockOutlet) wait(t *testing.T, n int) []beat.Event {
+	var events []beat.Event
+	for len(events) < n {
+		select {
+		case event := <-o.events:
+			events = append(events, event)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for events, got %v", len(events))
+		}
+	}
+	return events
+}
+
+func ack(events ...beat.Event) {
+	for _, event := range events {
+		event.Private.(*eventACK).ACK()
+	}
+}
+
+func notification(keys ...string) string {
+	var records []string
+	for _, key := range keys {
+		records = append(records, `{
+			"eventSource": "aws:s3",
+			"eventName": "ObjectCreated:Put",
+			"awsRegion": "eu-west-1",
+			"s3": {"bucket": {"name": "logs"}, "object": {"key": "`+key+`"}}
+		}`)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"Records": [`)
+	for i, record := range records {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString(record)
+	}
+	buf.WriteString(`]}`)
+	return buf.String()
+}
+
+func gzipped(t *testing.T, content string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func startProspector(q *mockSQS, s3 *mockS3) (*Prospector, *mockOutlet) {
+	outlet := &mockOutlet{events: make(chan beat.Event, 100)}
+	p := newProspector(defaultConfig, "us-east-1", outlet, q, s3)
+	p.backoff = 10 * time.Millisecond
+	p.Run()
+	return p, outlet
+}
+
+func TestProspectorDeletesMessageOnACK(t *testing.T) {
+	q := newMockSQS(sqsMessage{
+		ID:            "1",
+		ReceiptHandle: "handle-1",
+		Body:          notification("app/a.log", "app/b%3D1.log.gz"),
+	})
+	s3 := &mockS3{objects: map[string][]byte{
+		"logs/app/a.log":      []byte("line1\nline2\r\n"),
+		"logs/app/b=1.log.gz": gzipped(t, "line3\nline4"),
+	}}
+
+	p, outlet := startProspector(q, s3)
+	defer p.Stop()
+
+	events := outlet.wait(t, 4)
+	for i, expected := range []struct {
+		message, key string
+		offset       int64
+	}{
+		{"line1", "app/a.log", 0},
+		{"line2", "app/a.log", 6},
+		{"line3", "app/b=1.log.gz", 0},
+		{"line4", "app/b=1.log.gz", 6},
+	} {
+		assert.Equal(t, common.MapStr{
+			"message": expected.message,
+			"source":  "s3://logs/" + expected.key,
+			"offset":  expected.offset,
+			"aws": common.MapStr{
+				"s3": common.MapStr{
+					"bucket": common.MapStr{"name": "logs"},
+					"object": common.MapStr{"key": expected.key},
+				},
+			},
+		}, events[i].Fields)
+	}
+
+	// The message is only deleted once all its events are acknowledged.
+	ack(events[:3]...)
+	q.assertNotDeleted(t)
+
+	ack(events[3])
+	assert.Equal(t, []string{"handle-1"}, q.waitDeleted(t, 1))
+
+	// The fields of acknowledged events are returned to the pool.
+	for _, event := range events {
+		assert.Empty(t, event.Fields)
+	}
+}
+
+func TestProspectorPartialBatchFailure(t *testing.T) {
+	q := newMockSQS(
+		sqsMessage{ID: "1", ReceiptHandle: "handle-1", Body: notification("a.log")},
+		sqsMessage{ID: "2", ReceiptHandle: "handle-2", Body: notification("b.log", "missing.log")},
+		sqsMessage{ID: "3", ReceiptHandle: "handle-3", Body: notification("c.log")},
+	)
+	s3 := &mockS3{objects: map[string][]byte{
+		"logs/a.log": []byte("a\n"),
+		"logs/b.log": []byte("b\n"),
+		"logs/c.log": []byte("c\n"),
+	}}
+
+	p, outlet := startProspector(q, s3)
+	defer p.Stop()
+
+	events := outlet.wait(t, 3)
+	ack(events...)
+
+	// The message referencing an object failing to be read is not deleted.
+	assert.Equal(t, []string{"handle-1", "handle-3"}, q.waitDeleted(t, 2))
+	q.assertNotDeleted(t)
+
+	// It is received again after the visibility timeout, publishing its
+	// events another time.
+	s3.put("logs", "missing.log", []byte("missing\n"))
+	q.redeliver()
+
+	events = outlet.wait(t, 2)
+	assert.Equal(t, "b", events[0].Fields["message"])
+	assert.Equal(t, "missing", events[1].Fields["message"])
+	ack(events...)
+	assert.Equal(t, []string{"handle-2"}, q.waitDeleted(t, 1))
+}
+
+func TestProspectorRedeliversUnACKedMessages(t *testing.T) {
+	q := newMockSQS(sqsMessage{ID: "1", ReceiptHandle: "handle-1", Body: notification("a.log")})
+	s3 := &mockS3{objects: map[string][]byte{"logs/a.log": []byte("a\n")}}
+
+	p, outlet := startProspector(q, s3)
+	outlet.wait(t, 1)
+	p.Stop()
+	q.assertNotDeleted(t)
+
+	// Events not acknowledged before stopping are published again.
+	q.redeliver()
+	p, outlet = startProspector(q, s3)
+	defer p.Stop()
+
+	ack(outlet.wait(t, 1)...)
+	assert.Equal(t, []string{"handle-1"}, q.waitDeleted(t, 1))
+}
+
+func TestProspectorDeletesMessagesWithoutObjects(t *testing.T) {
+	q := newMockSQS(sqsMessage{
+		ID:            "1",
+		ReceiptHandle: "handle-1",
+		Body:          `{"Service": "Amazon S3", "Event": "s3:TestEvent", "Bucket": "logs"}`,
+	})
+
+	p, _ := startProspector(q, &mockS3{})
+	defer p.Stop()
+
+	assert.Equal(t, []string{"handle-1"}, q.waitDeleted(t, 1))
+}
+
+func TestParseNotification(t *testing.T) {
+	records, err := parseNotification(notification("dir/a+b%2Bc.log"))
+	require.NoError(t, err)
+	assert.Equal(t, []s3Record{{region: "eu-west-1", bucket: "logs", key: "dir/a b+c.log"}}, records)
+
+	// Notifications delivered by SNS topics.
+	records, err = parseNotification(`{"Type": "Notification", "Message": ` +
+		`"{\"Records\": [{\"eventSource\": \"aws:s3\", \"eventName\": \"ObjectCreated:Copy\", ` +
+		`\"s3\": {\"bucket\": {\"name\": \"logs\"}, \"object\": {\"key\": \"a.log\"}}}]}"}`)
+	require.NoError(t, err)
+	assert.Equal(t, []s3Record{{bucket: "logs", key: "a.log"}}, records)
+
+	records, err = parseNotification(`{"Records": [{"eventSource": "aws:s3", "eventName": "ObjectRemoved:Delete"}]}`)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	_, err = parseNotification("not json")
+	assert.Error(t, err)
+}
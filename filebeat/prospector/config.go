@@ -15,6 +15,7 @@ var (
 )
 
 type prospectorConfig struct {
+	ID            string        `config:"id"`
 	ScanFrequency time.Duration `config:"scan_frequency" validate:"min=0,nonzero"`
 	Type          string        `config:"type"`
 	InputType     string        `config:"input_type"`
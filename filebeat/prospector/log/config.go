@@ -12,6 +12,7 @@ import (
 	"github.com/elastic/beats/filebeat/input/file"
 	"github.com/elastic/beats/libbeat/common/cfgwarn"
 	"github.com/elastic/beats/libbeat/common/match"
+	"github.com/elastic/beats/libbeat/common/protobuf"
 	"github.com/elastic/beats/libbeat/logp"
 )
 
@@ -81,6 +82,7 @@ type config struct {
 	MaxBytes     int                     `config:"max_bytes" validate:"min=0,nonzero"`
 	Multiline    *reader.MultilineConfig `config:"multiline"`
 	JSON         *reader.JSONConfig      `config:"json"`
+	Protobuf     *protobuf.Config        `config:"protobuf"`
 }
 
 type LogConfig struct {
@@ -151,6 +153,11 @@ func (c *config) Validate() error {
 		return fmt.Errorf("When using the JSON decoder and line filtering together, you need to specify a message_key value")
 	}
 
+	if c.Protobuf != nil &&
+		(c.JSON != nil || c.Multiline != nil || len(c.IncludeLines) > 0 || len(c.ExcludeLines) > 0) {
+		return fmt.Errorf("The protobuf decoder can't be used together with the JSON decoder, multiline or line filtering")
+	}
+
 	if c.ScanSort != "" {
 		cfgwarn.Experimental("scan_sort is used.")
 
@@ -25,8 +25,10 @@ import (
 
 	"github.com/elastic/beats/libbeat/beat"
 	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/protobuf"
 	"github.com/elastic/beats/libbeat/logp"
 	"github.com/elastic/beats/libbeat/monitoring"
+	"github.com/elastic/beats/libbeat/monitoring/inputmon"
 
 	"github.com/elastic/beats/filebeat/channel"
 	"github.com/elastic/beats/filebeat/harvester"
@@ -70,11 +72,15 @@ type Harvester struct {
 	// file reader pipeline
 	reader          reader.Reader
 	encodingFactory encoding.EncodingFactory
+	protobuf        *protobuf.Decoder
 	encoding        encoding.Encoding
 
 	// event/state publishing
 	forwarder    *harvester.Forwarder
 	publishState func(*util.Data) bool
+
+	// metrics of the prospector
+	metrics *inputmon.Metrics
 }
 
 // NewHarvester creates a new harvester
@@ -84,6 +90,7 @@ func NewHarvester(
 	states *file.States,
 	publishState func(*util.Data) bool,
 	outlet channel.Outleter,
+	metrics *inputmon.Metrics,
 ) (*Harvester, error) {
 
 	h := &Harvester{
@@ -91,6 +98,7 @@ func NewHarvester(
 		state:        state,
 		states:       states,
 		publishState: publishState,
+		metrics:      metrics,
 		done:         make(chan struct{}),
 		stopWg:       &sync.WaitGroup{},
 		id:           uuid.NewV4(),
@@ -106,6 +114,14 @@ func NewHarvester(
 	}
 	h.encodingFactory = encodingFactory
 
+	if h.config.Protobuf != nil {
+		decoder, err := protobuf.NewDecoder(*h.config.Protobuf)
+		if err != nil {
+			return nil, err
+		}
+		h.protobuf = decoder
+	}
+
 	// Add ttl if clean_inactive is set
 	if h.config.CleanInactive > 0 {
 		h.state.TTL = h.config.CleanInactive
@@ -232,6 +248,7 @@ func (h *Harvester) Run() error {
 				logp.Info("File is inactive: %s. Closing because close_inactive of %v reached.", h.state.Source, h.config.CloseInactive)
 			default:
 				logp.Err("Read line error: %s; File: ", err, h.state.Source)
+				h.metrics.Errors.Inc()
 			}
 			return nil
 		}
@@ -247,6 +264,7 @@ func (h *Harvester) Run() error {
 		// the old offset is reported
 		state := h.getState()
 		state.Offset += int64(message.Bytes)
+		h.metrics.Bytes.Add(int64(message.Bytes))
 
 		// Create state event
 		data := util.NewData()
@@ -272,7 +290,7 @@ func (h *Harvester) Run() error {
 
 			if h.config.JSON != nil && len(jsonFields) > 0 {
 				reader.MergeJSONFields(fields, jsonFields, &text, *h.config.JSON)
-			} else if &text != nil {
+			} else if h.protobuf == nil {
 				if fields == nil {
 					fields = common.MapStr{}
 				}
@@ -281,6 +299,7 @@ func (h *Harvester) Run() error {
 
 			data.Event = beat.Event{
 				Timestamp: message.Ts,
+				Meta:      message.Meta,
 				Fields:    fields,
 			}
 		}
@@ -498,6 +517,11 @@ func (h *Harvester) newLogFileReader() (reader.Reader, error) {
 		return nil, err
 	}
 
+	// Protobuf messages are binary, they are read without decoding lines.
+	if h.protobuf != nil {
+		return reader.NewProtobuf(h.log, h.protobuf, h.config.Protobuf.Framing, h.config.MaxBytes), nil
+	}
+
 	r, err = reader.NewEncode(h.log, h.encoding, h.config.BufferSize)
 	if err != nil {
 		return nil, err
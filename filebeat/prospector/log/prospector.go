@@ -15,8 +15,10 @@ import (
 	"github.com/elastic/beats/filebeat/prospector"
 	"github.com/elastic/beats/filebeat/util"
 	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/startlimit"
 	"github.com/elastic/beats/libbeat/logp"
 	"github.com/elastic/beats/libbeat/monitoring"
+	"github.com/elastic/beats/libbeat/monitoring/inputmon"
 )
 
 const (
@@ -45,6 +47,7 @@ type Prospector struct {
 	outlet      channel.Outleter
 	stateOutlet channel.Outleter
 	done        chan struct{}
+	metrics     *inputmon.Metrics
 }
 
 // NewProspector instantiates a new Log
@@ -78,6 +81,7 @@ func NewProspector(
 		stateOutlet: stateOut,
 		states:      &file.States{},
 		done:        context.Done,
+		metrics:     context.Metrics,
 	}
 
 	if err := cfg.Unpack(&p.config); err != nil {
@@ -579,6 +583,7 @@ func (p *Prospector) createHarvester(state file.State) (*Harvester, error) {
 			return p.stateOutlet.OnEvent(d)
 		},
 		outlet,
+		p.metrics,
 	)
 
 	return h, err
@@ -592,6 +597,14 @@ func (p *Prospector) startHarvester(state file.State, offset int64) error {
 		return fmt.Errorf("Harvester limit reached")
 	}
 
+	// Harvesters of all prospectors are set up a limited number at a time,
+	// the others wait for their turn.
+	limiter := startlimit.Default()
+	if !limiter.Acquire(p.done) {
+		return errors.New("Prospector stopped before the harvester was started")
+	}
+	defer limiter.Release()
+
 	// Set state to "not" finished to indicate that a harvester is running
 	state.Finished = false
 	state.Offset = offset
@@ -604,6 +617,7 @@ func (p *Prospector) startHarvester(state file.State, offset int64) error {
 
 	err = h.Setup()
 	if err != nil {
+		p.metrics.Errors.Inc()
 		return fmt.Errorf("Error setting up harvester: %s", err)
 	}
 
@@ -1,6 +1,8 @@
 package prospector
 
 import (
+	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
@@ -9,7 +11,9 @@ import (
 	"github.com/elastic/beats/filebeat/channel"
 	"github.com/elastic/beats/filebeat/input/file"
 	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/supervisor"
 	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/monitoring/inputmon"
 )
 
 // Prospectorer is the interface common to all prospectors
@@ -28,6 +32,7 @@ type Prospector struct {
 	ID           uint64
 	Once         bool
 	beatDone     chan struct{}
+	metrics      *inputmon.Metrics
 }
 
 // NewProspector instantiates a new prospector
@@ -57,6 +62,14 @@ func New(
 		return nil, err
 	}
 
+	// The metrics are registered under the configured id, or the ID derived
+	// from the configuration, which is stable across restarts.
+	metricsID := prospector.config.ID
+	if metricsID == "" {
+		metricsID = strconv.FormatUint(prospector.ID, 10)
+	}
+	prospector.metrics = inputmon.NewMetrics(metricsID)
+
 	var f Factory
 	f, err = GetFactory(prospector.config.Type)
 	if err != nil {
@@ -67,9 +80,10 @@ func New(
 		States:   states,
 		Done:     prospector.done,
 		BeatDone: prospector.beatDone,
+		Metrics:  prospector.metrics,
 	}
 	var prospectorer Prospectorer
-	prospectorer, err = f(conf, outlet, context)
+	prospectorer, err = f(conf, countEvents(outlet, prospector.metrics), context)
 	if err != nil {
 		return prospector, err
 	}
@@ -83,6 +97,10 @@ func (p *Prospector) Start() {
 	p.wg.Add(1)
 	logp.Info("Starting prospector of type: %v; ID: %d ", p.config.Type, p.ID)
 
+	if err := p.metrics.Register(nil); err != nil {
+		logp.Warn("Metrics of prospector %d are not available: %v", p.ID, err)
+	}
+
 	onceWg := sync.WaitGroup{}
 	if p.Once {
 		// Make sure start is only completed when Run did a complete first scan
@@ -98,7 +116,9 @@ func (p *Prospector) Start() {
 			p.wg.Done()
 		}()
 
-		p.Run()
+		// Restart the prospector with backoff if it panics.
+		name := fmt.Sprintf("prospector of type %v (ID: %d)", p.config.Type, p.ID)
+		supervisor.Run(name, supervisor.DefaultConfig, p.done, p.Run)
 	}()
 }
 
@@ -140,4 +160,18 @@ func (p *Prospector) stop() {
 	} else {
 		p.prospectorer.Stop()
 	}
+
+	p.metrics.Unregister()
+}
+
+// countEvents wraps the outlet factory, counting the events published by the
+// prospector in the metrics.
+func countEvents(outlet channel.Factory, metrics *inputmon.Metrics) channel.Factory {
+	return func(cfg *common.Config) (channel.Outleter, error) {
+		out, err := outlet(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return channel.CountEvents(out, metrics.Events), nil
+	}
 }
@@ -1,3 +1,139 @@
 // +build !integration
 
 package prospector
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/filebeat/channel"
+	"github.com/elastic/beats/filebeat/util"
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/monitoring"
+)
+
+func init() {
+	Register("test_metrics", newTestMetricsProspector)
+}
+
+// testMetricsProspector publishes the configured number of events and state
+// updates, and reports the configured number of errors, on its first run.
+type testMetricsProspector struct {
+	config struct {
+		Events int `config:"events"`
+		States int `config:"states"`
+		Errors int `config:"errors"`
+	}
+	outlet  channel.Outleter
+	context Context
+	once    sync.Once
+}
+
+func newTestMetricsProspector(cfg *common.Config, outlet channel.Factory, context Context) (Prospectorer, error) {
+	p := &testMetricsProspector{context: context}
+	if err := cfg.Unpack(&p.config); err != nil {
+		return nil, err
+	}
+
+	var err error
+	p.outlet, err = outlet(cfg)
+	return p, err
+}
+
+func (p *testMetricsProspector) Run() {
+	p.once.Do(func() {
+		for i := 0; i < p.config.Events; i++ {
+			data := util.NewData()
+			data.Event = beat.Event{Fields: common.MapStr{"message": "test"}}
+			p.outlet.OnEvent(data)
+		}
+		for i := 0; i < p.config.States; i++ {
+			p.outlet.OnEvent(util.NewData())
+		}
+		for i := 0; i < p.config.Errors; i++ {
+			p.context.Metrics.Errors.Inc()
+		}
+	})
+}
+
+func (p *testMetricsProspector) Stop() {}
+func (p *testMetricsProspector) Wait() {}
+
+type acceptOutlet struct{}
+
+func (acceptOutlet) Close() error                 { return nil }
+func (acceptOutlet) OnEvent(data *util.Data) bool { return true }
+
+func createMetricsProspector(t *testing.T, settings map[string]interface{}) *Prospector {
+	settings["type"] = "test_metrics"
+	cfg, err := common.NewConfigFrom(settings)
+	require.NoError(t, err)
+
+	outlet := func(*common.Config) (channel.Outleter, error) { return acceptOutlet{}, nil }
+	p, err := New(cfg, outlet, make(chan struct{}), nil)
+	require.NoError(t, err)
+	return p
+}
+
+func getMetric(name string) int64 {
+	v, ok := monitoring.Default.Get(name).(*monitoring.Int)
+	if !ok {
+		return -1
+	}
+	return v.Get()
+}
+
+func TestProspectorMetricsPerInput(t *testing.T) {
+	a := createMetricsProspector(t, map[string]interface{}{
+		"id":     "metrics-a",
+		"events": 3,
+		"states": 2,
+	})
+	b := createMetricsProspector(t, map[string]interface{}{
+		"id":     "metrics-b",
+		"events": 1,
+		"errors": 2,
+	})
+
+	a.Start()
+	b.Start()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for getMetric("input.metrics-a.events.in") < 3 || getMetric("input.metrics-b.errors") < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for input metrics")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// State updates are not counted as events.
+	assert.Equal(t, int64(3), getMetric("input.metrics-a.events.in"))
+	assert.Equal(t, int64(0), getMetric("input.metrics-a.errors"))
+	assert.Equal(t, int64(1), getMetric("input.metrics-b.events.in"))
+	assert.Equal(t, int64(2), getMetric("input.metrics-b.errors"))
+
+	// The metrics are removed once the prospector is stopped.
+	a.Stop()
+	assert.Nil(t, monitoring.Default.Get("input.metrics-a"))
+	assert.NotNil(t, monitoring.Default.Get("input.metrics-b"))
+
+	b.Stop()
+	assert.Nil(t, monitoring.Default.Get("input.metrics-b"))
+}
+
+func TestProspectorMetricsDefaultID(t *testing.T) {
+	settings := func() map[string]interface{} {
+		return map[string]interface{}{"events": 1}
+	}
+	a := createMetricsProspector(t, settings())
+	b := createMetricsProspector(t, settings())
+
+	// The ID is derived from the configuration, and stable across restarts.
+	assert.Equal(t, a.ID, b.ID)
+	assert.Equal(t, a.metrics.ID, b.metrics.ID)
+}
@@ -7,12 +7,17 @@ import (
 	"github.com/elastic/beats/filebeat/input/file"
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/monitoring/inputmon"
 )
 
 type Context struct {
 	States   []file.State
 	Done     chan struct{}
 	BeatDone chan struct{}
+
+	// Metrics of the prospector. Events published to the outlet are counted
+	// by the prospector runner, bytes read and errors by the prospector.
+	Metrics *inputmon.Metrics
 }
 
 type Factory func(config *common.Config, outletFactory channel.Factory, context Context) (Prospectorer, error)
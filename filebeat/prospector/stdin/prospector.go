@@ -10,6 +10,7 @@ import (
 	"github.com/elastic/beats/filebeat/prospector/log"
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/monitoring/inputmon"
 )
 
 func init() {
@@ -26,6 +27,7 @@ type Prospector struct {
 	cfg       *common.Config
 	outlet    channel.Outleter
 	registry  *harvester.Registry
+	metrics   *inputmon.Metrics
 }
 
 // NewStdin creates a new stdin prospector
@@ -41,6 +43,7 @@ func NewProspector(cfg *common.Config, outlet channel.Factory, context prospecto
 		cfg:      cfg,
 		outlet:   out,
 		registry: harvester.NewRegistry(),
+		metrics:  context.Metrics,
 	}
 
 	p.harvester, err = p.createHarvester(file.State{Source: "-"})
@@ -72,6 +75,7 @@ func (p *Prospector) createHarvester(state file.State) (*log.Harvester, error) {
 		p.cfg,
 		state, nil, nil,
 		p.outlet,
+		p.metrics,
 	)
 
 	return h, err
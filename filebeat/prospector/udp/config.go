@@ -10,11 +10,20 @@ var defaultConfig = config{
 	},
 	MaxMessageSize: 10240,
 	// TODO: What should be default port?
-	Host: "localhost:8080",
+	Host:      "localhost:8080",
+	QueueSize: 1000,
 }
 
 type config struct {
 	harvester.ForwarderConfig `config:",inline"`
 	Host                      string `config:"host"`
 	MaxMessageSize            int    `config:"max_message_size"`
+
+	// Size of the socket receive buffer (SO_RCVBUF) in bytes. The operating
+	// system default is used if not set.
+	ReadBuffer int `config:"read_buffer" validate:"min=0"`
+
+	// Number of messages buffered between reading the socket and publishing
+	// the events. Messages are dropped if the queue is full.
+	QueueSize int `config:"queue_size" validate:"min=1"`
 }
@@ -2,31 +2,43 @@ package udp
 
 import (
 	"net"
+	"sync"
 	"time"
 
 	"github.com/elastic/beats/libbeat/beat"
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/monitoring/inputmon"
 
 	"github.com/elastic/beats/filebeat/harvester"
 	"github.com/elastic/beats/filebeat/util"
 )
 
+var debugf = logp.MakeDebug("udp")
+
 type Harvester struct {
 	forwarder *harvester.Forwarder
 	done      chan struct{}
 	cfg       *common.Config
-	listener  net.PacketConn
+	metrics   *inputmon.Metrics
+
+	mutex    sync.Mutex
+	listener *net.UDPConn
 }
 
-func NewHarvester(forwarder *harvester.Forwarder, cfg *common.Config) *Harvester {
+func NewHarvester(forwarder *harvester.Forwarder, cfg *common.Config, metrics *inputmon.Metrics) *Harvester {
 	return &Harvester{
 		done:      make(chan struct{}),
 		cfg:       cfg,
 		forwarder: forwarder,
+		metrics:   metrics,
 	}
 }
 
+// Run reads the messages received on the socket. Messages are queued before
+// they are published, so reading the socket is not blocked by the outputs and
+// bursts of messages do not overflow the socket receive buffer. Messages are
+// dropped if the queue is full.
 func (h *Harvester) Run() error {
 	config := defaultConfig
 	err := h.cfg.Unpack(&config)
@@ -34,15 +46,26 @@ func (h *Harvester) Run() error {
 		return err
 	}
 
-	h.listener, err = net.ListenPacket("udp", config.Host)
+	listener, err := listen(config)
 	if err != nil {
 		return err
 	}
-	defer h.listener.Close()
+	defer listener.Close()
+
+	if !h.setListener(listener) {
+		return nil
+	}
+
+	logp.Info("Started listening for udp on: %s", listener.LocalAddr())
 
-	logp.Info("Started listening for udp on: %s", config.Host)
+	queue := make(chan *util.Data, config.QueueSize)
+	defer close(queue)
+	go h.forward(queue)
 
 	buffer := make([]byte, config.MaxMessageSize)
+	oob := make([]byte, dropCountOOBSize)
+	var kernelDrops uint32
+	dropping := false
 
 	for {
 		select {
@@ -51,11 +74,25 @@ func (h *Harvester) Run() error {
 		default:
 		}
 
-		length, _, err := h.listener.ReadFrom(buffer)
+		length, oobn, _, _, err := listener.ReadMsgUDP(buffer, oob)
 		if err != nil {
+			select {
+			case <-h.done:
+				return nil
+			default:
+			}
 			logp.Err("Error reading from buffer: %v", err.Error())
+			h.metrics.Errors.Inc()
 			continue
 		}
+
+		// The kernel reports the total number of packets dropped on the socket.
+		if drops, ok := parseDropCount(oob[:oobn]); ok {
+			h.metrics.Dropped.Add(int64(drops - kernelDrops))
+			kernelDrops = drops
+		}
+
+		h.metrics.Bytes.Add(int64(length))
 		data := util.NewData()
 		data.Event = beat.Event{
 			Timestamp: time.Now(),
@@ -63,12 +100,78 @@ func (h *Harvester) Run() error {
 				"message": string(buffer[:length]),
 			},
 		}
-		h.forwarder.Send(data)
+
+		select {
+		case queue <- data:
+			dropping = false
+		default:
+			h.metrics.Dropped.Inc()
+			if !dropping {
+				logp.Warn("udp queue is full, dropping messages")
+				dropping = true
+			}
+		}
 	}
 }
 
+// listen opens the socket, setting the size of the receive buffer if
+// configured.
+func listen(config config) (*net.UDPConn, error) {
+	addr, err := net.ResolveUDPAddr("udp", config.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.ReadBuffer > 0 {
+		if err := listener.SetReadBuffer(config.ReadBuffer); err != nil {
+			listener.Close()
+			return nil, err
+		}
+	}
+
+	if err := enableDropCount(listener); err != nil {
+		debugf("Dropped packets are not counted: %v", err)
+	}
+	return listener, nil
+}
+
+// forward publishes the queued messages, until the outlet is closed.
+func (h *Harvester) forward(queue <-chan *util.Data) {
+	for data := range queue {
+		if err := h.forwarder.Send(data); err != nil {
+			return
+		}
+	}
+}
+
+// setListener sets the listener to be closed on Stop. It returns false if the
+// harvester has been stopped already.
+func (h *Harvester) setListener(listener *net.UDPConn) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	select {
+	case <-h.done:
+		return false
+	default:
+	}
+	h.listener = listener
+	return true
+}
+
 func (h *Harvester) Stop() {
 	logp.Info("Stopping udp harvester")
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
 	close(h.done)
-	h.listener.Close()
+	if h.listener != nil {
+		h.listener.Close()
+	}
 }
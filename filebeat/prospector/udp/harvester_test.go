@@ -0,0 +1,120 @@
+// +build !integration
+
+package udp
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/filebeat/harvester"
+	"github.com/elastic/beats/filebeat/util"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/monitoring/inputmon"
+)
+
+// blockingOutlet records the events, blocking until it is released.
+type blockingOutlet struct {
+	release chan struct{}
+
+	mutex  sync.Mutex
+	events []*util.Data
+}
+
+func (o *blockingOutlet) OnEvent(data *util.Data) bool {
+	<-o.release
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.events = append(o.events, data)
+	return true
+}
+
+func (o *blockingOutlet) count() int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return len(o.events)
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatalf("timeout waiting for %v", what)
+}
+
+func startHarvester(t *testing.T, outlet harvester.Outlet, settings map[string]interface{}) (*Harvester, net.Addr) {
+	settings["host"] = "127.0.0.1:0"
+	cfg, err := common.NewConfigFrom(settings)
+	require.NoError(t, err)
+
+	h := NewHarvester(harvester.NewForwarder(outlet), cfg, inputmon.NewMetrics("udp"))
+	go h.Run()
+
+	var addr net.Addr
+	waitFor(t, "listener", func() bool {
+		h.mutex.Lock()
+		defer h.mutex.Unlock()
+		if h.listener != nil {
+			addr = h.listener.LocalAddr()
+		}
+		return addr != nil
+	})
+	return h, addr
+}
+
+func TestHarvesterQueueDropsBurst(t *testing.T) {
+	outlet := &blockingOutlet{release: make(chan struct{})}
+	h, addr := startHarvester(t, outlet, map[string]interface{}{"queue_size": 10})
+	defer h.Stop()
+
+	conn, err := net.Dial("udp", addr.String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// The outlet blocks, so one message is held by the forwarder and the
+	// queue buffers 10 messages. All other messages are dropped.
+	const burst = 100
+	for i := 0; i < burst; i++ {
+		_, err := conn.Write([]byte("message"))
+		require.NoError(t, err)
+	}
+	waitFor(t, "messages read", func() bool { return h.metrics.Bytes.Get() == int64(burst*len("message")) })
+
+	dropped := h.metrics.Dropped.Get()
+	assert.True(t, dropped == burst-11 || dropped == burst-10, "dropped %v", dropped)
+
+	// Queued messages are published once the outlet is not blocked anymore.
+	close(outlet.release)
+	waitFor(t, "messages published", func() bool { return int64(outlet.count()) == burst-dropped })
+	assert.Equal(t, "message", outlet.events[0].Event.Fields["message"])
+	assert.Equal(t, int64(0), h.metrics.Errors.Get())
+}
+
+func TestHarvesterStopBeforeRun(t *testing.T) {
+	cfg, err := common.NewConfigFrom(map[string]interface{}{"host": "127.0.0.1:0"})
+	require.NoError(t, err)
+
+	h := NewHarvester(harvester.NewForwarder(&blockingOutlet{}), cfg, inputmon.NewMetrics("udp"))
+	h.Stop()
+	assert.NoError(t, h.Run())
+}
+
+func TestConfigValidation(t *testing.T) {
+	for _, settings := range []map[string]interface{}{
+		{"read_buffer": -1},
+		{"queue_size": 0},
+	} {
+		cfg, err := common.NewConfigFrom(settings)
+		require.NoError(t, err)
+
+		config := defaultConfig
+		assert.Error(t, cfg.Unpack(&config), "%v", settings)
+	}
+}
@@ -33,7 +33,7 @@ func NewProspector(cfg *common.Config, outlet channel.Factory, context prospecto
 	forwarder := harvester.NewForwarder(out)
 	return &Prospector{
 		outlet:    out,
-		harvester: NewHarvester(forwarder, cfg),
+		harvester: NewHarvester(forwarder, cfg, context.Metrics),
 		started:   false,
 	}, nil
 }
@@ -0,0 +1,46 @@
+// +build linux
+
+package udp
+
+import (
+	"net"
+	"syscall"
+	"unsafe"
+)
+
+// dropCountOOBSize is the size of the control message carrying the drop count.
+var dropCountOOBSize = syscall.CmsgSpace(4)
+
+// enableDropCount makes the kernel report the number of packets dropped on
+// the socket, because the receive buffer was full, with each packet read.
+func enableDropCount(conn *net.UDPConn) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var sockErr error
+	err = raw.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_RXQ_OVFL, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// parseDropCount returns the number of packets dropped on the socket since it
+// has been opened, from the control messages of a packet read.
+func parseDropCount(oob []byte) (uint32, bool) {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return 0, false
+	}
+
+	for _, msg := range msgs {
+		if msg.Header.Level == syscall.SOL_SOCKET && msg.Header.Type == syscall.SO_RXQ_OVFL && len(msg.Data) >= 4 {
+			return *(*uint32)(unsafe.Pointer(&msg.Data[0])), true
+		}
+	}
+	return 0, false
+}
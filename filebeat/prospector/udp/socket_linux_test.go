@@ -0,0 +1,90 @@
+// +build !integration
+
+package udp
+
+import (
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getReadBuffer(t *testing.T, conn *net.UDPConn) int {
+	raw, err := conn.SyscallConn()
+	require.NoError(t, err)
+
+	var size int
+	var sockErr error
+	err = raw.Control(func(fd uintptr) {
+		size, sockErr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_RCVBUF)
+	})
+	require.NoError(t, err)
+	require.NoError(t, sockErr)
+	return size
+}
+
+func testConfig(readBuffer int) config {
+	c := defaultConfig
+	c.Host = "127.0.0.1:0"
+	c.ReadBuffer = readBuffer
+	return c
+}
+
+func TestListenReadBuffer(t *testing.T) {
+	conn, err := listen(testConfig(0))
+	require.NoError(t, err)
+	defaultSize := getReadBuffer(t, conn)
+	conn.Close()
+
+	// Linux doubles the requested size, to account for its bookkeeping
+	// overhead. The size is below the default limit of net.core.rmem_max.
+	const size = 64 * 1024
+	conn, err = listen(testConfig(size))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	assert.Equal(t, 2*size, getReadBuffer(t, conn))
+	assert.NotEqual(t, defaultSize, getReadBuffer(t, conn))
+}
+
+func TestListenCountsKernelDrops(t *testing.T) {
+	conn, err := listen(testConfig(4096))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer client.Close()
+
+	// Overflow the receive buffer without reading, then drain it.
+	msg := make([]byte, 512)
+	for i := 0; i < 100; i++ {
+		client.Write(msg)
+	}
+
+	buffer := make([]byte, 1024)
+	oob := make([]byte, dropCountOOBSize)
+	read := 0
+	for {
+		conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		if _, _, _, _, err := conn.ReadMsgUDP(buffer, oob); err != nil {
+			break
+		}
+		read++
+	}
+	require.True(t, read < 100, "no packets dropped")
+
+	// The drop count is reported with the next packet.
+	_, err = client.Write(msg)
+	require.NoError(t, err)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, oobn, _, _, err := conn.ReadMsgUDP(buffer, oob)
+	require.NoError(t, err)
+
+	drops, ok := parseDropCount(oob[:oobn])
+	assert.True(t, ok)
+	assert.Equal(t, uint32(100-read), drops)
+}
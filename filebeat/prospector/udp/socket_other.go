@@ -0,0 +1,18 @@
+// +build !linux
+
+package udp
+
+import (
+	"errors"
+	"net"
+)
+
+var dropCountOOBSize = 0
+
+func enableDropCount(conn *net.UDPConn) error {
+	return errors.New("counting dropped packets is only supported on linux")
+}
+
+func parseDropCount(oob []byte) (uint32, bool) {
+	return 0, false
+}
@@ -3,7 +3,10 @@ package tcp
 import (
 	"bytes"
 	"errors"
+	"fmt"
+	"io"
 	"net"
+	"regexp"
 )
 
 type ConnCheck func(net.Conn) error
@@ -13,23 +16,80 @@ var (
 	errRecvMismatch   = errors.New("received string mismatch")
 )
 
+const (
+	// maxRecvBytes limits the number of bytes read for matching the response.
+	maxRecvBytes = 64 * 1024
+
+	// maxReportedBytes limits the number of received bytes reported on
+	// mismatch.
+	maxReportedBytes = 1024
+)
+
+// recvMismatchError is returned by the receive check if the received data do
+// not match. It captures the received data for reporting.
+type recvMismatchError struct {
+	received []byte
+}
+
+func (e *recvMismatchError) Error() string { return errRecvMismatch.Error() }
+
+// receiveMatcher matches the data received from the remote host. It unpacks
+// from a string, matching the literal string anywhere in the response, or from
+// an object with a `regex` setting, matching the regular expression.
+type receiveMatcher struct {
+	literal []byte
+	regex   *regexp.Regexp
+}
+
+func (m *receiveMatcher) Unpack(v interface{}) error {
+	switch v := v.(type) {
+	case string:
+		m.literal = []byte(v)
+		return nil
+	case map[string]interface{}:
+		pattern, ok := v["regex"].(string)
+		if !ok || len(v) != 1 {
+			return errors.New("check.receive must be a string or contain a regex setting")
+		}
+
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid check.receive regex '%v': %v", pattern, err)
+		}
+		m.regex = regex
+		return nil
+	}
+	return fmt.Errorf("invalid check.receive value of type %T", v)
+}
+
+func (m *receiveMatcher) isEmpty() bool {
+	return m.regex == nil && len(m.literal) == 0
+}
+
+func (m *receiveMatcher) match(buf []byte) bool {
+	if m.regex != nil {
+		return m.regex.Match(buf)
+	}
+	return bytes.Contains(buf, m.literal)
+}
+
 func (c ConnCheck) Validate(conn net.Conn) error {
 	return c(conn)
 }
 
 func makeValidateConn(config *Config) ConnCheck {
 	send := config.SendString
-	recv := config.ReceiveString
+	recv := config.Receive
 
 	switch {
-	case send == "" && recv == "":
+	case send == "" && recv.isEmpty():
 		return nil
-	case send != "" && recv == "":
+	case send != "" && recv.isEmpty():
 		return checkAll(checkSend([]byte(send)), checkRecvAny)
-	case send == "" && recv != "":
-		return checkRecv([]byte(recv))
+	case send == "" && !recv.isEmpty():
+		return checkRecv(recv)
 	default: // send != "" && recv != "":
-		return checkAll(checkSend([]byte(send)), checkRecv([]byte(recv)))
+		return checkAll(checkSend([]byte(send)), checkRecv(recv))
 	}
 }
 
@@ -52,17 +112,36 @@ func checkSend(buf []byte) ConnCheck {
 	}
 }
 
-func checkRecv(expected []byte) ConnCheck {
+// checkRecv reads from the connection until the received data match, the
+// connection is closed or the read limit is reached. Read errors after
+// receiving data, like the connection deadline expiring, are reported as
+// mismatch.
+func checkRecv(matcher receiveMatcher) ConnCheck {
 	return func(conn net.Conn) error {
-		buf := make([]byte, len(expected))
-		if err := recvBuffer(conn, buf); err != nil {
-			return err
+		var received []byte
+		var buf [1024]byte
+		for len(received) < maxRecvBytes {
+			n, err := conn.Read(buf[:])
+			received = append(received, buf[:n]...)
+			if n > 0 && matcher.match(received) {
+				return nil
+			}
+
+			if err != nil {
+				if len(received) == 0 {
+					if err == io.EOF {
+						return errNoDataReceived
+					}
+					return err
+				}
+				break
+			}
 		}
-		if !bytes.Equal(expected, buf) {
-			// TODO: report received value and expected value in event
-			return errRecvMismatch
+
+		if len(received) > maxReportedBytes {
+			received = received[:maxReportedBytes]
 		}
-		return nil
+		return &recvMismatchError{received: received}
 	}
 }
 
@@ -89,14 +168,3 @@ func sendBuffer(conn net.Conn, buf []byte) error {
 	}
 	return nil
 }
-
-func recvBuffer(conn net.Conn, buf []byte) error {
-	for len(buf) > 0 {
-		n, err := conn.Read(buf)
-		if err != nil {
-			return err
-		}
-		buf = buf[n:]
-	}
-	return nil
-}
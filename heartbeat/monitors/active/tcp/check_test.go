@@ -0,0 +1,168 @@
+package tcp
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+
+	"github.com/elastic/beats/heartbeat/monitors"
+)
+
+// startLineServer starts a server reading a line from each connection and
+// responding with the given response.
+func startLineServer(t *testing.T, response string) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				if _, err := bufio.NewReader(conn).ReadString('\n'); err != nil {
+					return
+				}
+				conn.Write([]byte(response))
+			}()
+		}
+	}()
+	return listener
+}
+
+func runTCPCheck(t *testing.T, response string, settings map[string]interface{}) common.MapStr {
+	listener := startLineServer(t, response)
+	defer listener.Close()
+
+	config := map[string]interface{}{
+		"hosts":      []string{listener.Addr().String()},
+		"timeout":    "1s",
+		"check.send": "PING\r\n",
+	}
+	for k, v := range settings {
+		config[k] = v
+	}
+
+	cfg, err := common.NewConfigFrom(config)
+	require.NoError(t, err)
+
+	jobs, err := create(monitors.Info{}, cfg)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+
+	events := runJob(t, jobs[0].Run)
+	require.Len(t, events, 1)
+	return events[0].Fields
+}
+
+func runJob(t *testing.T, run monitors.JobRunner) []beat.Event {
+	event, cont, err := run()
+	require.NoError(t, err)
+
+	var events []beat.Event
+	if event.Fields != nil {
+		events = append(events, event)
+	}
+	for _, c := range cont {
+		events = append(events, runJob(t, c)...)
+	}
+	return events
+}
+
+func TestCheckReceiveLiteral(t *testing.T) {
+	fields := runTCPCheck(t, "+PONG\r\n", map[string]interface{}{"check.receive": "PONG"})
+
+	status, _ := fields.GetValue("monitor.status")
+	assert.Equal(t, "up", status)
+	assert.NotContains(t, fields, "error")
+}
+
+func TestCheckReceiveLiteralMismatch(t *testing.T) {
+	fields := runTCPCheck(t, "-ERR unknown command\r\n", map[string]interface{}{"check.receive": "PONG"})
+
+	status, _ := fields.GetValue("monitor.status")
+	assert.Equal(t, "down", status)
+
+	received, _ := fields.GetValue("tcp.check.received")
+	assert.Equal(t, "-ERR unknown command\r\n", received)
+
+	errType, _ := fields.GetValue("error.type")
+	assert.Equal(t, "validate", errType)
+}
+
+func TestCheckReceiveRegex(t *testing.T) {
+	fields := runTCPCheck(t, "+PONG 42\r\n", map[string]interface{}{
+		"check.receive.regex": `^\+PONG \d+`,
+	})
+
+	status, _ := fields.GetValue("monitor.status")
+	assert.Equal(t, "up", status)
+}
+
+func TestCheckReceiveRegexMismatch(t *testing.T) {
+	fields := runTCPCheck(t, "+PONG\r\n", map[string]interface{}{
+		"check.receive.regex": `^\+PONG \d+`,
+	})
+
+	status, _ := fields.GetValue("monitor.status")
+	assert.Equal(t, "down", status)
+
+	received, _ := fields.GetValue("tcp.check.received")
+	assert.Equal(t, "+PONG\r\n", received)
+}
+
+func TestCheckReceiveNoData(t *testing.T) {
+	fields := runTCPCheck(t, "", map[string]interface{}{"check.receive": "PONG"})
+
+	status, _ := fields.GetValue("monitor.status")
+	assert.Equal(t, "down", status)
+	assert.NotContains(t, fields["tcp"], "check")
+
+	errType, _ := fields.GetValue("error.type")
+	assert.Equal(t, "io", errType)
+}
+
+func TestCheckReceiveTruncatesReported(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go func() {
+		server.Write(make([]byte, 2*maxReportedBytes))
+		server.Close()
+	}()
+
+	require.NoError(t, client.SetDeadline(time.Now().Add(time.Second)))
+	err := checkRecv(receiveMatcher{literal: []byte("PONG")})(client)
+	if assert.IsType(t, &recvMismatchError{}, err) {
+		assert.Len(t, err.(*recvMismatchError).received, maxReportedBytes)
+	}
+}
+
+func TestReceiveMatcherUnpack(t *testing.T) {
+	var config struct {
+		Receive receiveMatcher `config:"check.receive"`
+	}
+
+	unpack := func(settings map[string]interface{}) error {
+		cfg, err := common.NewConfigFrom(settings)
+		require.NoError(t, err)
+		return cfg.Unpack(&config)
+	}
+
+	assert.Error(t, unpack(map[string]interface{}{"check.receive.regex": "["}))
+	assert.Error(t, unpack(map[string]interface{}{"check.receive.pattern": "a"}))
+
+	require.NoError(t, unpack(map[string]interface{}{"check.receive": "a.b"}))
+	assert.True(t, config.Receive.match([]byte("xa.by")))
+	assert.False(t, config.Receive.match([]byte("axb")))
+}
@@ -27,8 +27,8 @@ type Config struct {
 	Timeout time.Duration `config:"timeout"`
 
 	// validate connection
-	SendString    string `config:"check.send"`
-	ReceiveString string `config:"check.receive"`
+	SendString string         `config:"check.send"`
+	Receive    receiveMatcher `config:"check.receive"`
 }
 
 var DefaultConfig = Config{
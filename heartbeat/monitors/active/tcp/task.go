@@ -138,7 +138,8 @@ func pingHost(
 
 	validateStart := time.Now()
 	err = validator.Validate(conn)
-	if err != nil && err != errRecvMismatch {
+	mismatch, isMismatch := err.(*recvMismatchError)
+	if err != nil && !isMismatch {
 		debugf("check failed with: %v", err)
 		return nil, reason.IOFailed(err)
 	}
@@ -151,8 +152,10 @@ func pingHost(
 			},
 		},
 	}
-	if err != nil {
-		event["error"] = reason.FailValidate(err)
+	if isMismatch {
+		// report the received data, marking the monitor as down
+		event.Put("tcp.check.received", string(mismatch.received))
+		return event, reason.ValidateFailed(mismatch)
 	}
 	return event, nil
 }
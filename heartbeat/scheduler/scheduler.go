@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/elastic/beats/libbeat/common/clock"
 	"github.com/elastic/beats/libbeat/logp"
 )
 
@@ -15,6 +16,11 @@ type Scheduler struct {
 
 	location *time.Location
 
+	// Jobs are timed with the monotonic clock, so they keep running regularly
+	// if the system time jumps.
+	clock clock.Clock
+	jumps *clock.JumpDetector
+
 	jobs   []*job
 	active uint // number of active entries
 
@@ -36,7 +42,8 @@ type Canceller func() error
 // to be re-scheduled.
 type job struct {
 	name     string
-	next     time.Time
+	next     time.Time     // wall clock time of the next run
+	due      time.Duration // monotonic time of the next run
 	schedule Schedule
 	fn       TaskFunc
 
@@ -74,6 +81,9 @@ func NewWithLocation(limit uint, location *time.Location) *Scheduler {
 		limit:    limit,
 		location: location,
 
+		clock: clock.System,
+		jumps: clock.NewJumpDetector(clock.DefaultJumpThreshold),
+
 		running: false,
 		jobs:    nil,
 		active:  0,
@@ -151,9 +161,9 @@ func (s *Scheduler) run() {
 	debugf("Start scheduler.")
 	defer debugf("Scheduler stopped.")
 
-	now := time.Now().In(s.location)
+	now := s.now()
 	for _, j := range s.jobs {
-		j.next = j.schedule.Next(now)
+		j.scheduleFrom(now.Wall, now.Mono)
 	}
 
 	resched := true
@@ -166,13 +176,13 @@ func (s *Scheduler) run() {
 		resched = true
 
 		if (s.limit == 0 || s.active < s.limit) && len(s.jobs) > 0 {
-			next := s.jobs[0].next
-			debugf("Next wakeup time: %v", next)
+			next := s.jobs[0]
+			debugf("Next wakeup time: %v", next.next)
 
 			if timer != nil {
 				timer.Stop()
 			}
-			timer = time.NewTimer(next.Sub(time.Now().In(s.location)))
+			timer = time.NewTimer(next.due - s.clock.Now().Mono)
 		}
 
 		var timeSignal <-chan time.Time
@@ -181,15 +191,18 @@ func (s *Scheduler) run() {
 		}
 
 		select {
-		case now = <-timeSignal:
+		case <-timeSignal:
+			now = s.now()
+			s.checkClockJump(now)
+
 			for _, j := range s.jobs {
-				if now.Before(j.next) {
+				if now.Mono < j.due {
 					break
 				}
 
 				if j.running > 0 {
 					debugf("Scheduled job '%v' still active.", j.name)
-					reschedActive(j, now)
+					j.reschedActive(now)
 					continue
 				}
 
@@ -199,7 +212,7 @@ func (s *Scheduler) run() {
 					continue
 				}
 
-				s.startJob(j)
+				s.startJob(j, now)
 			}
 
 		case sig := <-s.finished:
@@ -215,18 +228,18 @@ func (s *Scheduler) run() {
 
 			// try to start waiting jobs
 			for _, waiting := range s.jobs {
-				if now.Before(waiting.next) {
+				if now.Mono < waiting.due {
 					break
 				}
 
 				if waiting.running > 0 {
 					count++
-					reschedActive(waiting, now)
+					waiting.reschedActive(now)
 					continue
 				}
 
 				debugf("Start waiting job: %v", waiting.name)
-				s.startJob(waiting)
+				s.startJob(waiting, now)
 				break
 			}
 
@@ -276,7 +289,8 @@ func (s *Scheduler) run() {
 			resched = count > 0
 
 		case j := <-s.add:
-			j.next = j.schedule.Next(time.Now().In(s.location))
+			added := s.now()
+			j.scheduleFrom(added.Wall, added.Mono)
 			s.doAdd(j)
 
 		case j := <-s.rm:
@@ -290,17 +304,57 @@ func (s *Scheduler) run() {
 	}
 }
 
-func reschedActive(j *job, now time.Time) {
+// now reads the clock, with the wall clock time in the location of the
+// scheduler.
+func (s *Scheduler) now() clock.Reading {
+	now := s.clock.Now()
+	now.Wall = now.Wall.In(s.location)
+	return now
+}
+
+// checkClockJump logs jumps of the system time. Jobs are due at a monotonic
+// time, so they are not affected by the jump, only the wall clock times of
+// their next runs are shifted to match the changed system time.
+func (s *Scheduler) checkClockJump(now clock.Reading) {
+	jump, detected := s.jumps.Check(now)
+	if !detected {
+		return
+	}
+
+	logp.Warn("Detected a jump of the system time by %v. Scheduled jobs continue to run at their intervals.", jump)
+	for _, j := range s.jobs {
+		j.next = j.next.Add(jump)
+	}
+}
+
+// scheduleFrom schedules the next run after the wall clock time at, which
+// corresponds to the monotonic time mono.
+func (j *job) scheduleFrom(at time.Time, mono time.Duration) {
+	j.next = j.schedule.Next(at)
+	j.due = mono + j.next.Sub(at)
+}
+
+// advance schedules the run following the current one. If the following run
+// has already been missed, e.g. because the process was suspended, the job is
+// scheduled from now instead of catching up on all missed runs at once.
+func (j *job) advance(now clock.Reading) {
+	j.scheduleFrom(j.next, j.due)
+	if j.due <= now.Mono {
+		j.scheduleFrom(now.Wall, now.Mono)
+	}
+}
+
+func (j *job) reschedActive(now clock.Reading) {
 	logp.Info("Scheduled job '%v' already active.", j.name)
-	if !now.Before(j.next) {
-		j.next = j.schedule.Next(j.next)
+	if now.Mono >= j.due {
+		j.advance(now)
 	}
 }
 
-func (s *Scheduler) startJob(j *job) {
+func (s *Scheduler) startJob(j *job, now clock.Reading) {
 	j.running++
-	j.next = j.schedule.Next(j.next)
-	debugf("Start job '%v' at %v.", j.name, time.Now())
+	j.advance(now)
+	debugf("Start job '%v' at %v.", j.name, now.Wall)
 
 	s.runTask(task{j, j.fn})
 }
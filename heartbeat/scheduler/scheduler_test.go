@@ -0,0 +1,138 @@
+package scheduler
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common/clock"
+)
+
+type intervalSchedule time.Duration
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(s))
+}
+
+// jumpingClock is the system clock with a wall clock that jumps when the
+// system time is changed.
+type jumpingClock struct {
+	sync.Mutex
+	offset time.Duration
+}
+
+func (c *jumpingClock) Now() clock.Reading {
+	c.Lock()
+	defer c.Unlock()
+	now := clock.System.Now()
+	now.Wall = now.Wall.Add(c.offset)
+	return now
+}
+
+func (c *jumpingClock) jump(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.offset += d
+}
+
+// runIntervalJob runs a job every interval on a scheduler with the clock and
+// returns the monotonic times of the first n runs, calling jump after each run.
+func runIntervalJob(t *testing.T, c clock.Clock, interval time.Duration, n int, jump func(run int)) []time.Duration {
+	s := New(0)
+	s.clock = c
+
+	runs := make(chan time.Duration, n)
+	s.Add(intervalSchedule(interval), "test", func() []TaskFunc {
+		select {
+		case runs <- clock.System.Now().Mono:
+		default:
+		}
+		return nil
+	})
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	var times []time.Duration
+	for i := 0; i < n; i++ {
+		select {
+		case mono := <-runs:
+			times = append(times, mono)
+			jump(i)
+		case <-time.After(time.Duration(n) * interval * 10):
+			t.Fatalf("job was run %v times only, expected %v runs", i, n)
+		}
+	}
+	return times
+}
+
+func assertRegular(t *testing.T, interval time.Duration, times []time.Duration) {
+	for i := 1; i < len(times); i++ {
+		elapsed := times[i] - times[i-1]
+		assert.True(t, elapsed >= interval/2 && elapsed <= 3*interval,
+			"run %v followed run %v after %v, expected an interval of %v", i, i-1, elapsed, interval)
+	}
+}
+
+func TestIntervalWithClockJumps(t *testing.T) {
+	tests := []struct {
+		name  string
+		jumps map[int]time.Duration
+	}{
+		{"no jump", nil},
+		{"forward jump", map[int]time.Duration{3: time.Hour}},
+		{"backward jump", map[int]time.Duration{3: -time.Hour}},
+		{"forward and backward jumps", map[int]time.Duration{2: 10 * time.Minute, 5: -time.Hour}},
+	}
+
+	interval := 50 * time.Millisecond
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &jumpingClock{}
+			times := runIntervalJob(t, c, interval, 8, func(run int) {
+				if d, exists := test.jumps[run]; exists {
+					c.jump(d)
+				}
+			})
+			assertRegular(t, interval, times)
+		})
+	}
+}
+
+func TestJumpShiftsWallClockTimes(t *testing.T) {
+	c := &jumpingClock{}
+	s := New(0)
+	s.clock = c
+	s.Add(intervalSchedule(time.Minute), "test", func() []TaskFunc { return nil })
+
+	now := s.now()
+	j := s.jobs[0]
+	j.scheduleFrom(now.Wall, now.Mono)
+	s.checkClockJump(now)
+	next, due := j.next, j.due
+
+	c.jump(-time.Hour)
+	s.checkClockJump(s.now())
+	assert.Equal(t, due, j.due, "the monotonic due time must not change")
+	assert.WithinDuration(t, next.Add(-time.Hour), j.next, time.Millisecond)
+}
+
+func TestMissedRunsAreSkipped(t *testing.T) {
+	j := &job{schedule: intervalSchedule(time.Minute)}
+	j.scheduleFrom(time.Date(2018, 3, 1, 10, 0, 0, 0, time.UTC), 0)
+	assert.Equal(t, time.Minute, j.due)
+
+	// The run at 10:01 is started at 10:01 and the following run is due at 10:02.
+	j.advance(clock.Reading{Wall: time.Date(2018, 3, 1, 10, 1, 0, 0, time.UTC), Mono: time.Minute})
+	assert.Equal(t, 2*time.Minute, j.due)
+	assert.Equal(t, time.Date(2018, 3, 1, 10, 2, 0, 0, time.UTC), j.next)
+
+	// After being suspended for an hour, the job is scheduled from now instead
+	// of running the 59 missed runs back to back.
+	resumed := clock.Reading{Wall: time.Date(2018, 3, 1, 11, 2, 0, 0, time.UTC), Mono: 62 * time.Minute}
+	j.advance(resumed)
+	assert.Equal(t, 63*time.Minute, j.due)
+	assert.Equal(t, time.Date(2018, 3, 1, 11, 3, 0, 0, time.UTC), j.next)
+}
@@ -17,7 +17,8 @@ func (b timeOrd) Swap(i, j int) {
 }
 
 // Less reports `earliest` time i should sort before j.
-// zero time is not `earliest` time.
+// zero time is not `earliest` time. Jobs are ordered by their monotonic due
+// time, which is not affected by jumps of the system time.
 func (b timeOrd) Less(i, j int) bool {
 	if b[i].next.IsZero() {
 		return false
@@ -25,5 +26,5 @@ func (b timeOrd) Less(i, j int) bool {
 	if b[j].next.IsZero() {
 		return true
 	}
-	return b[i].next.Before(b[j].next)
+	return b[i].due < b[j].due
 }
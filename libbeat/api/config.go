@@ -1,9 +1,27 @@
 package api
 
+import (
+	"time"
+
+	"github.com/elastic/beats/libbeat/publisher/droplog"
+	"github.com/elastic/beats/libbeat/publisher/tap"
+)
+
 type Config struct {
 	Enabled bool
 	Host    string
 	Port    int
+	Health  HealthConfig
+	Tap     tap.Config
+
+	DroppedEvents droplog.Config `config:"dropped_events"`
+}
+
+// HealthConfig configures the thresholds of the /healthz and /readyz endpoints.
+type HealthConfig struct {
+	// StuckTimeout is the maximum time events can be pending in the pipeline
+	// without any event being ACKed, before the beat is reported as not alive.
+	StuckTimeout time.Duration `config:"stuck_timeout" validate:"positive"`
 }
 
 var (
@@ -11,5 +29,10 @@ var (
 		Enabled: false,
 		Host:    "localhost",
 		Port:    5066,
+		Health: HealthConfig{
+			StuckTimeout: 5 * time.Minute,
+		},
+		Tap:           tap.DefaultConfig,
+		DroppedEvents: droplog.DefaultConfig,
 	}
 )
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// configDiffHandler reports the changes between the running configuration and
+// the configuration loaded from disk, without applying them. Secrets are
+// redacted.
+func configDiffHandler(running *common.Config, load func() (*common.Config, error)) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		changes, err := diffLoadedConfig(running, load)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			print(w, common.MapStr{"error": err.Error()}, r.URL)
+			return
+		}
+
+		if changes == nil {
+			changes = []common.ConfigChange{}
+		}
+		print(w, common.MapStr{"changes": changes}, r.URL)
+	}
+}
+
+func diffLoadedConfig(running *common.Config, load func() (*common.Config, error)) ([]common.ConfigChange, error) {
+	cfg, err := load()
+	if err != nil {
+		return nil, err
+	}
+	return common.DiffConfig(running, cfg)
+}
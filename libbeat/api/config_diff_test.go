@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func TestConfigDiffHandler(t *testing.T) {
+	running, err := common.NewConfigFrom(map[string]interface{}{
+		"logging.level":                 "info",
+		"output.elasticsearch.password": "secret",
+	})
+	require.NoError(t, err)
+	onDisk, err := common.NewConfigFrom(map[string]interface{}{
+		"logging.level":                 "debug",
+		"output.elasticsearch.password": "changed",
+	})
+	require.NoError(t, err)
+
+	h := configDiffHandler(running, func() (*common.Config, error) { return onDisk, nil })
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/config/diff", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Changes []common.ConfigChange `json:"changes"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, []common.ConfigChange{
+		{Path: "logging.level", Type: common.ConfigChanged, Old: "info", New: "debug"},
+		{Path: "output.elasticsearch.password", Type: common.ConfigChanged, Old: "xxxxx", New: "xxxxx"},
+	}, response.Changes)
+	assert.NotContains(t, w.Body.String(), "secret")
+
+	// No changes are reported as an empty list.
+	h = configDiffHandler(running, func() (*common.Config, error) { return running, nil })
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/config/diff", nil))
+	assert.JSONEq(t, `{"changes": []}`, w.Body.String())
+}
+
+func TestConfigDiffHandlerLoadError(t *testing.T) {
+	h := configDiffHandler(common.NewConfig(), func() (*common.Config, error) {
+		return nil, errors.New("error loading config file")
+	})
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/config/diff", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.JSONEq(t, `{"error": "error loading config file"}`, w.Body.String())
+}
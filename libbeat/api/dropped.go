@@ -0,0 +1,47 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/publisher/droplog"
+)
+
+// LoadDropLog creates the log of the last dropped events read from the
+// `/events/dropped` endpoint, if the metrics endpoint and the log are enabled.
+func LoadDropLog(cfg *common.Config, info beat.Info) (*droplog.Log, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+
+	config := DefaultConfig
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("error initializing dropped events log: %v", err)
+	}
+	if !config.DroppedEvents.Enabled {
+		return nil, nil
+	}
+	return droplog.New(info, config.DroppedEvents), nil
+}
+
+// droppedHandler reports the last dropped events, oldest first, with the
+// reason they were dropped. Clients must send the token of the log as bearer
+// token.
+func droppedHandler(l *droplog.Log) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !l.Authorized(bearerToken(r)) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="dropped_events"`)
+			jsonError(w, r, http.StatusUnauthorized, "invalid or missing bearer token")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		print(w, common.MapStr{
+			"size":   l.Size(),
+			"total":  l.Total(),
+			"events": l.Entries(),
+		}, r.URL)
+	}
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/publisher/droplog"
+)
+
+func newTestDropLog() *droplog.Log {
+	config := droplog.DefaultConfig
+	config.Enabled = true
+	config.Token = "secret"
+	config.Size = 2
+	config.RedactFields = []string{"user.password"}
+	return droplog.New(beat.Info{Beat: "testbeat"}, config)
+}
+
+func TestDroppedHandler(t *testing.T) {
+	dropLog := newTestDropLog()
+	for i, reason := range []droplog.Reason{droplog.Filtered, droplog.QueueFull, droplog.OutputFailed} {
+		dropLog.Add(beat.Event{Fields: common.MapStr{
+			"id":      i,
+			"message": "100% full",
+			"user":    common.MapStr{"name": "alice", "password": "hunter2"},
+		}}, reason)
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events/dropped", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	droppedHandler(dropLog)(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.NotContains(t, w.Body.String(), "hunter2")
+
+	var response struct {
+		Size   int    `json:"size"`
+		Total  uint64 `json:"total"`
+		Events []struct {
+			Reason string                 `json:"reason"`
+			Event  map[string]interface{} `json:"event"`
+		} `json:"events"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 2, response.Size)
+	assert.Equal(t, uint64(3), response.Total)
+
+	// The oldest event has been discarded.
+	require.Len(t, response.Events, 2)
+	assert.Equal(t, "queue_full", response.Events[0].Reason)
+	assert.Equal(t, float64(1), response.Events[0].Event["id"])
+	assert.Equal(t, "output_failed", response.Events[1].Reason)
+	assert.Equal(t, float64(2), response.Events[1].Event["id"])
+	assert.Equal(t, "100% full", response.Events[1].Event["message"])
+	assert.Equal(t, map[string]interface{}{"name": "alice", "password": "xxxxx"}, response.Events[1].Event["user"])
+}
+
+func TestDroppedHandlerUnauthorized(t *testing.T) {
+	h := droppedHandler(newTestDropLog())
+	for _, token := range []string{"", "wrong"} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/events/dropped", nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		h(w, r)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, "token %q", token)
+		assert.Equal(t, `Bearer realm="dropped_events"`, w.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestLoadDropLog(t *testing.T) {
+	tests := map[string]struct {
+		settings map[string]interface{}
+		enabled  bool
+		err      bool
+	}{
+		"endpoint disabled": {
+			settings: map[string]interface{}{"enabled": false, "dropped_events.enabled": true, "dropped_events.token": "secret"},
+		},
+		"log disabled": {
+			settings: map[string]interface{}{"enabled": true},
+		},
+		"log enabled": {
+			settings: map[string]interface{}{"enabled": true, "dropped_events.enabled": true, "dropped_events.token": "secret"},
+			enabled:  true,
+		},
+		"log without token": {
+			settings: map[string]interface{}{"enabled": true, "dropped_events.enabled": true},
+			err:      true,
+		},
+		"invalid size": {
+			settings: map[string]interface{}{"enabled": true, "dropped_events.enabled": true, "dropped_events.token": "secret", "dropped_events.size": 0},
+			err:      true,
+		},
+	}
+
+	dropLog, err := LoadDropLog(nil, beat.Info{})
+	assert.NoError(t, err)
+	assert.Nil(t, dropLog)
+
+	for name, test := range tests {
+		cfg, err := common.NewConfigFrom(test.settings)
+		require.NoError(t, err)
+
+		dropLog, err := LoadDropLog(cfg, beat.Info{})
+		if test.err {
+			assert.Error(t, err, name)
+			continue
+		}
+		require.NoError(t, err, name)
+		assert.Equal(t, test.enabled, dropLog != nil, name)
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/monitoring"
+)
+
+// Names of the publisher pipeline metrics used to check the health of a beat.
+const (
+	metricActiveEvents     = "libbeat.pipeline.events.active"
+	metricQueueACKed       = "libbeat.pipeline.queue.acked"
+	metricConnectedOutputs = "libbeat.pipeline.output.connected"
+)
+
+// healthChecker reports the liveness and readiness of a beat based on the
+// publisher pipeline metrics.
+//
+// A beat is alive unless events are pending in the pipeline without any
+// event being ACKed for longer than the stuck timeout. A beat is ready if it
+// is alive and at least one output client is connected.
+type healthChecker struct {
+	metrics      *monitoring.Registry
+	stuckTimeout time.Duration
+	now          func() time.Time
+
+	mutex       sync.Mutex
+	lastACKed   uint64
+	lastChange  time.Time
+	initialized bool
+}
+
+func newHealthChecker(metrics *monitoring.Registry, config HealthConfig) *healthChecker {
+	return &healthChecker{
+		metrics:      metrics,
+		stuckTimeout: config.StuckTimeout,
+		now:          time.Now,
+	}
+}
+
+// alive returns an empty string if the beat is alive, or the reason why it
+// is not.
+func (h *healthChecker) alive() string {
+	active, _ := h.uintMetric(metricActiveEvents)
+	acked, ok := h.uintMetric(metricQueueACKed)
+	if !ok {
+		// The pipeline has not been set up yet.
+		return ""
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	now := h.now()
+	if !h.initialized || acked != h.lastACKed || active == 0 {
+		h.initialized = true
+		h.lastACKed = acked
+		h.lastChange = now
+		return ""
+	}
+
+	if now.Sub(h.lastChange) > h.stuckTimeout {
+		return "no events have been ACKed since " + h.lastChange.UTC().Format(time.RFC3339)
+	}
+	return ""
+}
+
+// ready returns an empty string if the beat is ready, or the reason why it
+// is not.
+func (h *healthChecker) ready() string {
+	if reason := h.alive(); reason != "" {
+		return reason
+	}
+
+	connected, _ := h.uintMetric(metricConnectedOutputs)
+	if connected == 0 {
+		return "output is not connected"
+	}
+	return ""
+}
+
+func (h *healthChecker) uintMetric(name string) (uint64, bool) {
+	v, ok := h.metrics.Get(name).(*monitoring.Uint)
+	if !ok {
+		return 0, false
+	}
+	return v.Get(), true
+}
+
+func (h *healthChecker) livenessHandler(w http.ResponseWriter, r *http.Request) {
+	healthResponse(w, r, h.alive())
+}
+
+func (h *healthChecker) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	healthResponse(w, r, h.ready())
+}
+
+func healthResponse(w http.ResponseWriter, r *http.Request, reason string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	data := common.MapStr{"status": "ok"}
+	if reason != "" {
+		data = common.MapStr{"status": "failed", "reason": reason}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	print(w, data, r.URL)
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/monitoring"
+)
+
+type testPipelineMetrics struct {
+	active, acked, connected *monitoring.Uint
+}
+
+func newTestHealthChecker() (*healthChecker, *testPipelineMetrics, *time.Time) {
+	reg := monitoring.NewRegistry()
+	metrics := &testPipelineMetrics{
+		active:    monitoring.NewUint(reg, metricActiveEvents),
+		acked:     monitoring.NewUint(reg, metricQueueACKed),
+		connected: monitoring.NewUint(reg, metricConnectedOutputs),
+	}
+
+	now := time.Date(2017, 10, 14, 0, 0, 0, 0, time.UTC)
+	h := newHealthChecker(reg, HealthConfig{StuckTimeout: time.Minute})
+	h.now = func() time.Time { return now }
+	return h, metrics, &now
+}
+
+func probe(h http.HandlerFunc, path string) int {
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", path, nil))
+	return w.Code
+}
+
+func TestReadinessOutputConnected(t *testing.T) {
+	h, metrics, _ := newTestHealthChecker()
+
+	assert.Equal(t, http.StatusServiceUnavailable, probe(h.readinessHandler, "/readyz"))
+	assert.Equal(t, http.StatusOK, probe(h.livenessHandler, "/healthz"))
+
+	metrics.connected.Inc()
+	assert.Equal(t, http.StatusOK, probe(h.readinessHandler, "/readyz"))
+
+	metrics.connected.Dec()
+	assert.Equal(t, http.StatusServiceUnavailable, probe(h.readinessHandler, "/readyz"))
+}
+
+func TestLivenessStuckQueue(t *testing.T) {
+	h, metrics, now := newTestHealthChecker()
+	metrics.connected.Inc()
+
+	// Events are pending, but ACKed within the timeout.
+	metrics.active.Set(10)
+	assert.Equal(t, http.StatusOK, probe(h.livenessHandler, "/healthz"))
+	*now = now.Add(50 * time.Second)
+	metrics.acked.Add(5)
+	assert.Equal(t, http.StatusOK, probe(h.livenessHandler, "/healthz"))
+
+	// No progress for longer than the timeout.
+	*now = now.Add(61 * time.Second)
+	assert.Equal(t, http.StatusServiceUnavailable, probe(h.livenessHandler, "/healthz"))
+	assert.Equal(t, http.StatusServiceUnavailable, probe(h.readinessHandler, "/readyz"))
+
+	// Recovers once events are ACKed again.
+	metrics.acked.Add(5)
+	assert.Equal(t, http.StatusOK, probe(h.livenessHandler, "/healthz"))
+	assert.Equal(t, http.StatusOK, probe(h.readinessHandler, "/readyz"))
+}
+
+func TestLivenessIdlePipeline(t *testing.T) {
+	h, _, now := newTestHealthChecker()
+
+	// No pending events is not reported as stuck.
+	assert.Equal(t, http.StatusOK, probe(h.livenessHandler, "/healthz"))
+	*now = now.Add(time.Hour)
+	assert.Equal(t, http.StatusOK, probe(h.livenessHandler, "/healthz"))
+}
+
+func TestHealthWithoutPipeline(t *testing.T) {
+	h := newHealthChecker(monitoring.NewRegistry(), DefaultConfig.Health)
+
+	assert.Equal(t, http.StatusOK, probe(h.livenessHandler, "/healthz"))
+	assert.Equal(t, http.StatusServiceUnavailable, probe(h.readinessHandler, "/readyz"))
+}
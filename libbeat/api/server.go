@@ -11,10 +11,23 @@ import (
 	"github.com/elastic/beats/libbeat/common/cfgwarn"
 	"github.com/elastic/beats/libbeat/logp"
 	"github.com/elastic/beats/libbeat/monitoring"
+	"github.com/elastic/beats/libbeat/publisher/droplog"
+	"github.com/elastic/beats/libbeat/publisher/tap"
 )
 
-// Start starts the metrics api endpoint on the configured host and port
-func Start(cfg *common.Config, info beat.Info) {
+// Start starts the metrics api endpoint on the configured host and port.
+// running is the configuration the beat runs with, load reads the
+// configuration from disk to report the changes to apply on reload.
+// The processed events are streamed from eventTap, and the last dropped
+// events are read from dropLog, if set.
+func Start(
+	cfg *common.Config,
+	info beat.Info,
+	running *common.Config,
+	load func() (*common.Config, error),
+	eventTap *tap.Tap,
+	dropLog *droplog.Log,
+) {
 	cfgwarn.Beta("Metrics endpoint is enabled.")
 	config := DefaultConfig
 	cfg.Unpack(&config)
@@ -27,6 +40,17 @@ func Start(cfg *common.Config, info beat.Info) {
 		mux.HandleFunc("/", rootHandler(info))
 		mux.HandleFunc("/stats", statsHandler)
 
+		health := newHealthChecker(monitoring.Default, config.Health)
+		mux.HandleFunc("/healthz", health.livenessHandler)
+		mux.HandleFunc("/readyz", health.readinessHandler)
+		mux.HandleFunc("/config/diff", configDiffHandler(running, load))
+		if eventTap != nil {
+			mux.HandleFunc("/events/tap", tapHandler(eventTap))
+		}
+		if dropLog != nil {
+			mux.HandleFunc("/events/dropped", droppedHandler(dropLog))
+		}
+
 		url := config.Host + ":" + strconv.Itoa(config.Port)
 		logp.Info("Metrics endpoint listening on: %s", url)
 		endpoint := http.ListenAndServe(url, mux)
@@ -68,8 +92,8 @@ func statsHandler(w http.ResponseWriter, r *http.Request) {
 func print(w http.ResponseWriter, data common.MapStr, u *url.URL) {
 	query := u.Query()
 	if _, ok := query["pretty"]; ok {
-		fmt.Fprintf(w, data.StringToPrint())
+		fmt.Fprint(w, data.StringToPrint())
 	} else {
-		fmt.Fprintf(w, data.String())
+		fmt.Fprint(w, data.String())
 	}
 }
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/publisher/tap"
+)
+
+// LoadTap creates the tap streaming the processed events to the `/events/tap`
+// endpoint, if the metrics endpoint and the tap are enabled.
+func LoadTap(cfg *common.Config, info beat.Info) (*tap.Tap, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+
+	config := DefaultConfig
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("error initializing event tap: %v", err)
+	}
+	if !config.Tap.Enabled {
+		return nil, nil
+	}
+	return tap.New(info, config.Tap), nil
+}
+
+// tapHandler streams the events of the tap as server-sent events, one event
+// per message. Clients must send the token of the tap as bearer token. The
+// stream ends when the client disconnects, or after `max` events if set.
+func tapHandler(t *tap.Tap) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !t.Authorized(bearerToken(r)) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="tap"`)
+			jsonError(w, r, http.StatusUnauthorized, "invalid or missing bearer token")
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			jsonError(w, r, http.StatusInternalServerError, "streaming not supported")
+			return
+		}
+
+		max := 0
+		if v := r.URL.Query().Get("max"); v != "" {
+			var err error
+			if max, err = strconv.Atoi(v); err != nil || max < 1 {
+				jsonError(w, r, http.StatusBadRequest, "max must be a positive number")
+				return
+			}
+		}
+
+		s := t.Subscribe()
+		defer s.Close()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		for sent := 0; max == 0 || sent < max; sent++ {
+			select {
+			case line := <-s.Events():
+				fmt.Fprintf(w, "data: %s\n\n", line)
+				flusher.Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+}
+
+func bearerToken(r *http.Request) string {
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, prefix) {
+		return ""
+	}
+	return strings.TrimPrefix(auth, prefix)
+}
+
+// jsonError responds with the status and the error message as JSON object.
+func jsonError(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	print(w, common.MapStr{"error": msg}, r.URL)
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/publisher/tap"
+)
+
+func newTestTap() *tap.Tap {
+	config := tap.DefaultConfig
+	config.Enabled = true
+	config.Token = "secret"
+	config.SampleRate = 1
+	config.RedactFields = []string{"user.password"}
+	return tap.New(beat.Info{Beat: "testbeat"}, config)
+}
+
+func tapRequest(t *testing.T, url, token string) *http.Response {
+	req, err := http.NewRequest("GET", url, nil)
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+func TestTapHandlerStreamsEvents(t *testing.T) {
+	eventTap := newTestTap()
+	server := httptest.NewServer(http.HandlerFunc(tapHandler(eventTap)))
+	defer server.Close()
+
+	resp := tapRequest(t, server.URL+"/events/tap?max=2", "secret")
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// The subscription is registered once the headers have been sent.
+	for i := 1; i <= 3; i++ {
+		eventTap.Add(beat.Event{Fields: common.MapStr{
+			"id":   i,
+			"user": common.MapStr{"name": "alice", "password": "hunter2"},
+		}})
+	}
+
+	var events []map[string]interface{}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		require.True(t, strings.HasPrefix(line, "data: "), line)
+		assert.NotContains(t, line, "hunter2")
+
+		var event map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event))
+		events = append(events, event)
+	}
+	require.NoError(t, scanner.Err())
+
+	// The stream ends after max events.
+	require.Len(t, events, 2)
+	for i, event := range events {
+		assert.Equal(t, float64(i+1), event["id"])
+		assert.Equal(t, map[string]interface{}{"name": "alice", "password": "xxxxx"}, event["user"])
+	}
+}
+
+func TestTapHandlerUnauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(tapHandler(newTestTap())))
+	defer server.Close()
+
+	for _, token := range []string{"", "wrong"} {
+		resp := tapRequest(t, server.URL+"/events/tap", token)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "token %q", token)
+		assert.Equal(t, `Bearer realm="tap"`, resp.Header.Get("WWW-Authenticate"))
+	}
+}
+
+func TestTapHandlerInvalidMax(t *testing.T) {
+	h := tapHandler(newTestTap())
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/events/tap?max=-1", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	h(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error": "max must be a positive number"}`, w.Body.String())
+}
+
+func TestLoadTap(t *testing.T) {
+	tests := map[string]struct {
+		settings map[string]interface{}
+		enabled  bool
+		err      bool
+	}{
+		"endpoint disabled": {
+			settings: map[string]interface{}{"enabled": false, "tap.enabled": true, "tap.token": "secret"},
+		},
+		"tap disabled": {
+			settings: map[string]interface{}{"enabled": true},
+		},
+		"tap enabled": {
+			settings: map[string]interface{}{"enabled": true, "tap.enabled": true, "tap.token": "secret"},
+			enabled:  true,
+		},
+		"tap without token": {
+			settings: map[string]interface{}{"enabled": true, "tap.enabled": true},
+			err:      true,
+		},
+	}
+
+	eventTap, err := LoadTap(nil, beat.Info{})
+	assert.NoError(t, err)
+	assert.Nil(t, eventTap)
+
+	for name, test := range tests {
+		cfg, err := common.NewConfigFrom(test.settings)
+		require.NoError(t, err)
+
+		eventTap, err := LoadTap(cfg, beat.Info{})
+		if test.err {
+			assert.Error(t, err, name)
+			continue
+		}
+		require.NoError(t, err, name)
+		assert.Equal(t, test.enabled, eventTap != nil, name)
+	}
+}
@@ -16,6 +16,45 @@ type Event struct {
 	Meta      common.MapStr
 	Fields    common.MapStr
 	Private   interface{} // for beats private use
+
+	// OnComplete is an optional callback, reporting the final publishing status
+	// of the event. It is called at most once, either when the event has been
+	// dropped by the pipeline, or after the outputs have ACKed the event or
+	// failed permanently to publish it. Events still pending on shutdown are
+	// not reported.
+	// Note: The callback is run in a go-routine owned by the publisher pipeline
+	//       and must not block.
+	OnComplete func(EventStatus)
+}
+
+// EventStatus reports the final publishing status of an event.
+type EventStatus uint8
+
+const (
+	// EventACKed indicates the event has been published and ACKed by the outputs.
+	EventACKed EventStatus = iota
+
+	// EventDropped indicates the event has been dropped by processors or by the
+	// publisher pipeline, before being passed to the outputs.
+	EventDropped
+
+	// EventFailed indicates the outputs failed to publish the event and will not
+	// retry, e.g. because the retry limit has been reached or the event has been
+	// rejected.
+	EventFailed
+)
+
+func (s EventStatus) String() string {
+	switch s {
+	case EventACKed:
+		return "acked"
+	case EventDropped:
+		return "dropped"
+	case EventFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
 }
 
 var (
@@ -35,9 +35,15 @@ type ClientConfig struct {
 	Fields common.MapStr
 
 	// Processors passes additional processor to the client, to be executed before
-	// the pipeline processors.
+	// the pipeline processors. The processors are closed with the client, if
+	// they implement io.Closer.
 	Processor ProcessorList
 
+	// ProcessingPipeline selects the named processing pipeline the events are
+	// processed by, before the pipeline processors. If empty, no named
+	// pipeline is used.
+	ProcessingPipeline string
+
 	// WaitClose sets the maximum duration to wait on ACK, if client still has events
 	// active non-acknowledged events in the publisher pipeline.
 	// WaitClose is only effective if one of ACKCount, ACKEvents and ACKLastEvents
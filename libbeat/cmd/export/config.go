@@ -8,6 +8,8 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"github.com/elastic/beats/libbeat/cmd/instance"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
 )
 
 func GenExportConfigCmd(name, idxPrefix, beatVersion string) *cobra.Command {
@@ -27,15 +29,9 @@ func GenExportConfigCmd(name, idxPrefix, beatVersion string) *cobra.Command {
 				os.Exit(1)
 			}
 
-			var config map[string]interface{}
-			err = b.RawConfig.Unpack(&config)
+			res, err := exportConfig(b.RawConfig, logp.HasSelector("config-with-passwords"))
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error unpacking config")
-				os.Exit(1)
-			}
-			res, err := yaml.Marshal(config)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error converting config to YAML format")
+				fmt.Fprintf(os.Stderr, "%s\n", err)
 				os.Exit(1)
 			}
 
@@ -43,3 +39,24 @@ func GenExportConfigCmd(name, idxPrefix, beatVersion string) *cobra.Command {
 		},
 	}
 }
+
+// exportConfig encodes the configuration as YAML. Secrets are only exported
+// when explicitly requested, the same way they are only logged with the
+// config-with-passwords selector. Other settings, like the hosts, are exported
+// as configured.
+func exportConfig(cfg *common.Config, withSecrets bool) ([]byte, error) {
+	var config map[string]interface{}
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("Error unpacking config: %v", err)
+	}
+
+	if !withSecrets {
+		common.RedactSecrets(config)
+	}
+
+	res, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("Error converting config to YAML format: %v", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,71 @@
+// +build !integration
+
+package export
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func TestExportConfig(t *testing.T) {
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"output.kafka": map[string]interface{}{
+			"hosts":              []string{"kafka1:9092", "kafka2:9092"},
+			"key":                "%{[host.name]}",
+			"password":           "secret",
+			"ssl.key":            "/etc/pki/client.key",
+			"ssl.key_passphrase": "secret",
+		},
+		"processors": []map[string]interface{}{
+			{"delta": map[string]interface{}{"key": "system.cpu.total.pct"}},
+		},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		withSecrets bool
+		secret      string
+		key         string
+	}{
+		{withSecrets: false, secret: "xxxxx", key: "xxxxx"},
+		{withSecrets: true, secret: "secret", key: "/etc/pki/client.key"},
+	}
+
+	for _, test := range tests {
+		res, err := exportConfig(cfg, test.withSecrets)
+		require.NoError(t, err)
+
+		var exported struct {
+			Output struct {
+				Kafka struct {
+					Hosts    []string `yaml:"hosts"`
+					Key      string   `yaml:"key"`
+					Password string   `yaml:"password"`
+					SSL      struct {
+						Key           string `yaml:"key"`
+						KeyPassphrase string `yaml:"key_passphrase"`
+					} `yaml:"ssl"`
+				} `yaml:"kafka"`
+			} `yaml:"output"`
+			Processors []map[string]map[string]string `yaml:"processors"`
+		}
+		require.NoError(t, yaml.Unmarshal(res, &exported))
+		kafka := exported.Output.Kafka
+
+		// The settings that are no secrets are exported as configured.
+		assert.Equal(t, []string{"kafka1:9092", "kafka2:9092"}, kafka.Hosts)
+		assert.Equal(t, "%{[host.name]}", kafka.Key)
+		assert.Equal(t, []map[string]map[string]string{
+			{"delta": {"key": "system.cpu.total.pct"}},
+		}, exported.Processors)
+
+		assert.Equal(t, test.secret, kafka.Password, test.withSecrets)
+		assert.Equal(t, test.secret, kafka.SSL.KeyPassphrase, test.withSecrets)
+		assert.Equal(t, test.key, kafka.SSL.Key, test.withSecrets)
+	}
+}
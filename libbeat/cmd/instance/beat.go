@@ -22,13 +22,17 @@ import (
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/common/cfgwarn"
 	"github.com/elastic/beats/libbeat/common/file"
+	"github.com/elastic/beats/libbeat/common/startlimit"
 	"github.com/elastic/beats/libbeat/dashboards"
 	"github.com/elastic/beats/libbeat/logp"
 	"github.com/elastic/beats/libbeat/monitoring/report"
 	"github.com/elastic/beats/libbeat/outputs/elasticsearch"
 	"github.com/elastic/beats/libbeat/paths"
 	"github.com/elastic/beats/libbeat/plugin"
+	"github.com/elastic/beats/libbeat/publisher/deadletter"
+	"github.com/elastic/beats/libbeat/publisher/droplog"
 	"github.com/elastic/beats/libbeat/publisher/pipeline"
+	"github.com/elastic/beats/libbeat/publisher/tap"
 	svc "github.com/elastic/beats/libbeat/service"
 	"github.com/elastic/beats/libbeat/template"
 	"github.com/elastic/beats/libbeat/version"
@@ -40,8 +44,16 @@ import (
 	_ "github.com/elastic/beats/libbeat/processors/actions"
 	_ "github.com/elastic/beats/libbeat/processors/add_cloud_metadata"
 	_ "github.com/elastic/beats/libbeat/processors/add_docker_metadata"
+	_ "github.com/elastic/beats/libbeat/processors/add_geoip"
 	_ "github.com/elastic/beats/libbeat/processors/add_kubernetes_metadata"
 	_ "github.com/elastic/beats/libbeat/processors/add_locale"
+	_ "github.com/elastic/beats/libbeat/processors/delta"
+	_ "github.com/elastic/beats/libbeat/processors/es_lookup"
+	_ "github.com/elastic/beats/libbeat/processors/map_fields"
+	_ "github.com/elastic/beats/libbeat/processors/migrate_fields"
+	_ "github.com/elastic/beats/libbeat/processors/stitch"
+	_ "github.com/elastic/beats/libbeat/processors/user_agent"
+	_ "github.com/elastic/beats/libbeat/processors/validate_ecs"
 
 	// Register default monitoring reporting
 	_ "github.com/elastic/beats/libbeat/monitoring/report/elasticsearch"
@@ -53,6 +65,9 @@ type Beat struct {
 
 	Config    beatConfig
 	RawConfig *common.Config // Raw config that can be unpacked to get Beat specific config data.
+
+	tap     *tap.Tap     // tap streams the processed events to the metrics endpoint, if enabled
+	dropLog *droplog.Log // dropLog keeps the last dropped events for the metrics endpoint, if enabled
 }
 
 type beatConfig struct {
@@ -64,11 +79,20 @@ type beatConfig struct {
 	Name     string `config:"name"`
 	MaxProcs int    `config:"max_procs"`
 
+	// MaxConcurrentStarts limits the number of units, like harvesters and
+	// metricsets, starting at the same time.
+	MaxConcurrentStarts int `config:"max_concurrent_starts"`
+
 	// beat internal components configurations
 	HTTP    *common.Config `config:"http"`
 	Path    paths.Path     `config:"path"`
 	Logging logp.Logging   `config:"logging"`
 
+	Deprecation deprecationConfig `config:"deprecation"`
+
+	// Startup configures waiting for the output before running the beater.
+	Startup *common.Config `config:"startup"`
+
 	// output/publishing related configurations
 	Pipeline   pipeline.Config `config:",inline"`
 	Monitoring *common.Config  `config:"xpack.monitoring"`
@@ -200,8 +224,18 @@ func (b *Beat) createBeater(bt beat.Creator) (beat.Beater, error) {
 		return nil, err
 	}
 
+	b.tap, err = api.LoadTap(b.Config.HTTP, b.Info)
+	if err != nil {
+		return nil, err
+	}
+
+	b.dropLog, err = api.LoadDropLog(b.Config.HTTP, b.Info)
+	if err != nil {
+		return nil, err
+	}
+
 	debugf("Initializing output plugins")
-	pipeline, err := pipeline.Load(b.Info, b.Config.Pipeline, b.Config.Output)
+	pipeline, err := pipeline.Load(b.Info, b.Config.Pipeline, b.Config.Output, b.tap, b.dropLog)
 	if err != nil {
 		return nil, fmt.Errorf("error initializing publisher: %v", err)
 	}
@@ -210,6 +244,12 @@ func (b *Beat) createBeater(bt beat.Creator) (beat.Beater, error) {
 	//       but refine publisher to disconnect clients on stop automatically
 	// defer pipeline.Close()
 
+	if b.Config.Deprecation.Events {
+		if err := publishDeprecations(pipeline); err != nil {
+			return nil, fmt.Errorf("error publishing deprecation events: %v", err)
+		}
+	}
+
 	b.Publisher = pipeline
 	beater, err := bt(&b.Beat, sub)
 	if err != nil {
@@ -248,7 +288,17 @@ func (b *Beat) launch(bt beat.Creator) error {
 		return beat.GracefulExit
 	}
 
-	svc.HandleSignals(beater.Stop)
+	gate, err := newStartupGate(b.Config.Startup, b.Info, b.Config.Output)
+	if err != nil {
+		return err
+	}
+
+	// The beater is not run, if it is stopped while waiting for the output.
+	stopping := make(chan struct{})
+	svc.HandleSignals(func() {
+		close(stopping)
+		beater.Stop()
+	})
 
 	err = b.loadDashboards(false)
 	if err != nil {
@@ -266,10 +316,16 @@ func (b *Beat) launch(bt beat.Creator) error {
 	defer logp.LogTotalExpvars(&b.Config.Logging)
 
 	if b.Config.HTTP.Enabled() {
-		api.Start(b.Config.HTTP, b.Info)
+		api.Start(b.Config.HTTP, b.Info, b.RawConfig, loadConfig, b.tap, b.dropLog)
 	}
 
-	return beater.Run(&b.Beat)
+	err = gate.Run(stopping, func() error {
+		return beater.Run(&b.Beat)
+	})
+	if err == errStartupStopped {
+		return nil
+	}
+	return err
 }
 
 // TestConfig check all settings are ok and the beat can be run
@@ -358,6 +414,100 @@ func (b *Beat) Setup(bt beat.Creator, template, dashboards, machineLearning bool
 	}())
 }
 
+// ReplayDeadLetter publishes the events of a dead-letter spool file to the
+// configured output. The file is renamed while being replayed, so events
+// failing again can be spooled to a new file, and removed once all events have
+// been published.
+func (b *Beat) ReplayDeadLetter(path string) error {
+	return handleError(func() error {
+		err := b.Init()
+		if err != nil {
+			return err
+		}
+
+		replayPath := path
+		if !strings.HasSuffix(path, deadletter.ReplaySuffix) {
+			replayPath = path + deadletter.ReplaySuffix
+			if err := os.Rename(path, replayPath); err != nil {
+				return fmt.Errorf("failed to prepare dead-letter spool for replay: %v", err)
+			}
+		}
+
+		file, err := os.Open(replayPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		// Spooled events have already been processed, so processors, fields
+		// and sequence numbers are not applied again. Events not published
+		// are not handed over to the next process of the beat.
+		config := b.Config.Pipeline
+		config.EventMetadata = common.EventMetadata{}
+		config.Global = pipeline.GlobalConfig{}
+		config.Processors = nil
+		config.Sequence = pipeline.SequenceConfig{}
+		config.Shutdown.WarmRestart = pipeline.WarmRestartConfig{}
+
+		p, err := pipeline.Load(b.Info, config, b.Config.Output, nil, nil)
+		if err != nil {
+			return fmt.Errorf("error initializing publisher: %v", err)
+		}
+
+		stats, err := deadletter.Replay(p, deadletter.NewReader(file))
+		p.Close()
+		if err != nil {
+			return fmt.Errorf("failed to replay dead-letter spool: %v", err)
+		}
+
+		fmt.Printf("Replayed %v events: %v acked, %v failed, %v dropped, %v invalid entries skipped\n",
+			stats.Published, stats.ACKed, stats.Failed, stats.Dropped, stats.Skipped)
+
+		file.Close()
+		return os.Remove(replayPath)
+	}())
+}
+
+// ReplayCapture publishes the events of a capture file to the configured
+// output. The captured events have not been processed yet, so they are run
+// through the configured processors, allowing a processor configuration to be
+// tested against a recorded sample of events. The capture file is kept.
+func (b *Beat) ReplayCapture(path string) error {
+	return handleError(func() error {
+		err := b.Init()
+		if err != nil {
+			return err
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		// Replayed events must not overwrite the capture file being read, nor
+		// be handed over to the next process of the beat.
+		config := b.Config.Pipeline
+		config.Capture = nil
+		config.Shutdown.WarmRestart = pipeline.WarmRestartConfig{}
+
+		p, err := pipeline.Load(b.Info, config, b.Config.Output, nil, nil)
+		if err != nil {
+			return fmt.Errorf("error initializing publisher: %v", err)
+		}
+
+		stats, err := deadletter.Replay(p, deadletter.NewReader(file))
+		p.Close()
+		if err != nil {
+			return fmt.Errorf("failed to replay capture file: %v", err)
+		}
+
+		fmt.Printf("Replayed %v events: %v acked, %v failed, %v dropped, %v invalid entries skipped\n",
+			stats.Published, stats.ACKed, stats.Failed, stats.Dropped, stats.Skipped)
+		return nil
+	}())
+}
+
 // handleFlags parses the command line flags. It handles the '-version' flag
 // and invokes the HandleFlags callback if implemented by the Beat.
 func (b *Beat) handleFlags() error {
@@ -389,12 +539,7 @@ func (b *Beat) handleFlags() error {
 func (b *Beat) configure() error {
 	var err error
 
-	cfg, err := cfgfile.Load("")
-	if err != nil {
-		return fmt.Errorf("error loading config file: %v", err)
-	}
-
-	err = cloudid.OverwriteSettings(cfg)
+	cfg, err := loadConfig()
 	if err != nil {
 		return err
 	}
@@ -440,6 +585,9 @@ func (b *Beat) configure() error {
 		runtime.GOMAXPROCS(maxProcs)
 	}
 
+	// The default limit depends on GOMAXPROCS, so it is configured after.
+	startlimit.Configure(b.Config.MaxConcurrentStarts)
+
 	b.Beat.BeatConfig, err = b.BeatConfig()
 	if err != nil {
 		return err
@@ -448,6 +596,20 @@ func (b *Beat) configure() error {
 	return nil
 }
 
+// loadConfig reads the configuration file from disk and applies the Cloud ID
+// settings.
+func loadConfig() (*common.Config, error) {
+	cfg, err := cfgfile.Load("")
+	if err != nil {
+		return nil, fmt.Errorf("error loading config file: %v", err)
+	}
+
+	if err := cloudid.OverwriteSettings(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
 func (b *Beat) loadMeta() error {
 	type meta struct {
 		UUID uuid.UUID `json:"uuid"`
@@ -0,0 +1,52 @@
+package instance
+
+import (
+	"time"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/cfgwarn"
+)
+
+// deprecationConfig configures the reporting of deprecated settings in use.
+type deprecationConfig struct {
+	// Events enables publishing an event for each deprecated setting in use.
+	Events bool `config:"events"`
+}
+
+// publishDeprecations publishes an event for each deprecation reported, so the
+// usage of deprecated settings can be found in dashboards and alerts. Events
+// are dropped if the pipeline is full, not to block the reporting code.
+func publishDeprecations(pipeline beat.Pipeline) error {
+	client, err := pipeline.ConnectWith(beat.ClientConfig{
+		PublishMode: beat.DropIfFull,
+	})
+	if err != nil {
+		return err
+	}
+
+	cfgwarn.SetDeprecationHandler(func(d cfgwarn.Deprecation) {
+		client.Publish(makeDeprecationEvent(d))
+	})
+	return nil
+}
+
+func makeDeprecationEvent(d cfgwarn.Deprecation) beat.Event {
+	deprecation := common.MapStr{
+		"removed_in": d.Version,
+	}
+	if d.Setting != "" {
+		deprecation["setting"] = d.Setting
+	}
+	if d.Replacement != "" {
+		deprecation["replacement"] = d.Replacement
+	}
+
+	return beat.Event{
+		Timestamp: time.Now(),
+		Fields: common.MapStr{
+			"message":     "DEPRECATED: " + d.Message,
+			"deprecation": deprecation,
+		},
+	}
+}
@@ -0,0 +1,54 @@
+// +build !integration
+
+package instance
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/cfgwarn"
+	pubtest "github.com/elastic/beats/libbeat/publisher/testing"
+)
+
+func TestPublishDeprecations(t *testing.T) {
+	client := pubtest.NewChanClient(10)
+	err := publishDeprecations(pubtest.PublisherWithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cfgwarn.SetDeprecationHandler(nil)
+
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"input_type": "log",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, cfgwarn.CheckDeprecatedSetting(cfg, "input_type", "type", "7.0.0"))
+
+	event := client.ReceiveEvent()
+	assert.Equal(t, common.MapStr{
+		"message": "DEPRECATED: setting 'input_type' is deprecated. Use 'type' instead.",
+		"deprecation": common.MapStr{
+			"setting":     "input_type",
+			"replacement": "type",
+			"removed_in":  "7.0.0",
+		},
+	}, event.Fields)
+	assert.False(t, event.Timestamp.IsZero())
+}
+
+func TestMakeDeprecationEventWithoutSetting(t *testing.T) {
+	event := makeDeprecationEvent(cfgwarn.Deprecation{
+		Version: "7.0.0",
+		Message: "config_dir is deprecated.",
+	})
+	assert.Equal(t, common.MapStr{
+		"message": "DEPRECATED: config_dir is deprecated.",
+		"deprecation": common.MapStr{
+			"removed_in": "7.0.0",
+		},
+	}, event.Fields)
+}
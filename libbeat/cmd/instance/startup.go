@@ -0,0 +1,163 @@
+package instance
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs"
+)
+
+// startupConfig configures the startup of the Beat.
+type startupConfig struct {
+	WaitForOutput waitForOutputConfig `config:"wait_for_output"`
+}
+
+// waitForOutputConfig configures waiting for the output to be reachable
+// before the Beat starts collecting events.
+type waitForOutputConfig struct {
+	Enabled bool          `config:"enabled"`
+	Timeout time.Duration `config:"timeout" validate:"min=0"`
+	Backoff struct {
+		Init time.Duration `config:"init" validate:"nonzero"`
+		Max  time.Duration `config:"max" validate:"nonzero"`
+	} `config:"backoff"`
+}
+
+func defaultStartupConfig() startupConfig {
+	config := startupConfig{}
+	config.WaitForOutput.Timeout = 5 * time.Minute
+	config.WaitForOutput.Backoff.Init = time.Second
+	config.WaitForOutput.Backoff.Max = 30 * time.Second
+	return config
+}
+
+var errStartupStopped = errors.New("beat stopped while waiting for the output")
+
+// startupGate delays the start of the beater until the output is reachable,
+// so the inputs do not fill the queue and the logs with connection errors
+// while the output is starting too.
+type startupGate struct {
+	config waitForOutputConfig
+
+	// probe returns nil once the output is reachable.
+	probe func() error
+}
+
+// newStartupGate creates the startup gate of the configuration, nil if
+// waiting for the output is disabled.
+func newStartupGate(cfg *common.Config, info beat.Info, output common.ConfigNamespace) (*startupGate, error) {
+	config := defaultStartupConfig()
+	if cfg != nil {
+		if err := cfg.Unpack(&config); err != nil {
+			return nil, fmt.Errorf("error unpacking the startup configuration: %v", err)
+		}
+	}
+	if !config.WaitForOutput.Enabled || !output.IsSet() {
+		return nil, nil
+	}
+
+	probe, err := newOutputProbe(info, output)
+	if err != nil {
+		return nil, err
+	}
+	return &startupGate{config: config.WaitForOutput, probe: probe}, nil
+}
+
+// newOutputProbe returns a probe connecting to the hosts of the output. The
+// output is reachable if any of its hosts accepts the connection. Outputs not
+// connecting to the network are always reachable.
+func newOutputProbe(info beat.Info, output common.ConfigNamespace) (func() error, error) {
+	group, err := outputs.Load(info, nil, output.Name(), output.Config())
+	if err != nil {
+		return nil, fmt.Errorf("error initializing the output to wait for: %v", err)
+	}
+
+	var clients []outputs.NetworkClient
+	for _, client := range group.Clients {
+		// The backoff of the clients is replaced by the backoff of the gate.
+		if w, ok := client.(interface{ Client() outputs.NetworkClient }); ok {
+			client = w.Client()
+		}
+		if c, ok := client.(outputs.NetworkClient); ok {
+			clients = append(clients, c)
+		}
+	}
+
+	return func() error {
+		if len(clients) == 0 {
+			return nil
+		}
+
+		var err error
+		for _, client := range clients {
+			err = client.Connect()
+			client.Close()
+			if err == nil {
+				return nil
+			}
+		}
+		return err
+	}, nil
+}
+
+// Run calls run once the output is reachable or the timeout elapsed. If done
+// is closed while waiting, run is not called and errStartupStopped is
+// returned.
+func (g *startupGate) Run(done <-chan struct{}, run func() error) error {
+	if g == nil {
+		return run()
+	}
+
+	var timeout <-chan time.Time
+	if g.config.Timeout > 0 {
+		timer := time.NewTimer(g.config.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	logp.Info("Waiting for the output to be reachable before starting")
+	start := time.Now()
+	backoff := g.config.Backoff.Init
+	for {
+		// Probing the output can block until the timeout of the output, it
+		// runs in the background so the gate can still be stopped.
+		result := make(chan error, 1)
+		go func() {
+			result <- g.probe()
+		}()
+
+		var err error
+		select {
+		case err = <-result:
+		case <-done:
+			return errStartupStopped
+		case <-timeout:
+			logp.Warn("Output not reachable after %v, starting anyway", g.config.Timeout)
+			return run()
+		}
+
+		if err == nil {
+			logp.Info("Output reachable after %v", time.Since(start))
+			return run()
+		}
+		logp.Info("Output not reachable, retrying in %v: %v", backoff, err)
+
+		select {
+		case <-time.After(backoff):
+		case <-done:
+			return errStartupStopped
+		case <-timeout:
+			logp.Warn("Output not reachable after %v, starting anyway", g.config.Timeout)
+			return run()
+		}
+
+		backoff *= 2
+		if backoff > g.config.Backoff.Max {
+			backoff = g.config.Backoff.Max
+		}
+	}
+}
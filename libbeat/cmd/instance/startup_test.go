@@ -0,0 +1,201 @@
+// +build !integration
+
+package instance
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// testOutput is a mocked output, becoming reachable when the test sets it.
+type testOutput struct {
+	mutex     sync.Mutex
+	reachable bool
+	probes    int
+}
+
+func (o *testOutput) probe() error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.probes++
+	if !o.reachable {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (o *testOutput) setReachable() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.reachable = true
+}
+
+func newTestGate(timeout time.Duration, probe func() error) *startupGate {
+	config := defaultStartupConfig().WaitForOutput
+	config.Enabled = true
+	config.Timeout = timeout
+	config.Backoff.Init = 10 * time.Millisecond
+	config.Backoff.Max = 10 * time.Millisecond
+	return &startupGate{config: config, probe: probe}
+}
+
+// runGate runs the gate in the background, and returns a channel closed
+// once the inputs have been started.
+func runGate(gate *startupGate, done <-chan struct{}) (started chan struct{}, result chan error) {
+	started = make(chan struct{})
+	result = make(chan error, 1)
+	go func() {
+		result <- gate.Run(done, func() error {
+			close(started)
+			return nil
+		})
+	}()
+	return started, result
+}
+
+func TestStartupGateWaitsForOutput(t *testing.T) {
+	output := &testOutput{}
+	started, result := runGate(newTestGate(0, output.probe), nil)
+
+	// The inputs are not started while the output is not reachable.
+	select {
+	case <-started:
+		t.Fatal("inputs started before the output is reachable")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	output.setReachable()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("inputs not started once the output is reachable")
+	}
+	assert.NoError(t, <-result)
+	assert.True(t, output.probes > 1)
+}
+
+func TestStartupGateTimeout(t *testing.T) {
+	output := &testOutput{}
+	begin := time.Now()
+	started, result := runGate(newTestGate(100*time.Millisecond, output.probe), nil)
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("inputs not started after the timeout")
+	}
+	assert.True(t, time.Since(begin) >= 100*time.Millisecond)
+	assert.NoError(t, <-result)
+}
+
+func TestStartupGateTimeoutBlockingProbe(t *testing.T) {
+	// Probes of unresponsive outputs can block until the timeout of the
+	// output.
+	block := make(chan struct{})
+	defer close(block)
+	probe := func() error {
+		<-block
+		return nil
+	}
+
+	started, _ := runGate(newTestGate(50*time.Millisecond, probe), nil)
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("inputs not started after the timeout")
+	}
+}
+
+func TestStartupGateStopped(t *testing.T) {
+	output := &testOutput{}
+	done := make(chan struct{})
+	started, result := runGate(newTestGate(0, output.probe), done)
+
+	close(done)
+	assert.Equal(t, errStartupStopped, <-result)
+	select {
+	case <-started:
+		t.Fatal("inputs started after the beat has been stopped")
+	default:
+	}
+}
+
+func TestStartupGateDisabled(t *testing.T) {
+	output := common.ConfigNamespace{}
+	gate, err := newStartupGate(nil, beat.Info{}, output)
+	require.NoError(t, err)
+	assert.Nil(t, gate)
+
+	started, result := runGate(gate, nil)
+	<-started
+	assert.NoError(t, <-result)
+}
+
+func TestStartupGateElasticsearch(t *testing.T) {
+	var mutex sync.Mutex
+	available := false
+	pings := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		pings++
+		if !available {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, `{"version":{"number":"6.5.0"}}`)
+	}))
+	defer server.Close()
+
+	var output common.ConfigNamespace
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"elasticsearch.hosts": []string{server.URL},
+	})
+	require.NoError(t, err)
+	require.NoError(t, cfg.Unpack(&output))
+
+	startup, err := common.NewConfigFrom(map[string]interface{}{
+		"wait_for_output.enabled":      true,
+		"wait_for_output.backoff.init": "10ms",
+		"wait_for_output.backoff.max":  "10ms",
+	})
+	require.NoError(t, err)
+
+	info := beat.Info{Beat: "testbeat", IndexPrefix: "testbeat", Version: "6.5.0"}
+	gate, err := newStartupGate(startup, info, output)
+	require.NoError(t, err)
+	require.NotNil(t, gate)
+
+	started, result := runGate(gate, nil)
+	select {
+	case <-started:
+		t.Fatal("inputs started before Elasticsearch is available")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	mutex.Lock()
+	available = true
+	mutex.Unlock()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("inputs not started once Elasticsearch is available")
+	}
+	assert.NoError(t, <-result)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.True(t, pings > 1)
+}
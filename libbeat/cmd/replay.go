@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/beats/libbeat/cmd/instance"
+)
+
+func genReplayDeadLetterCmd(name, idxPrefix, version string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "replay-deadletter <file>",
+		Short: "Replay events from a dead-letter spool file",
+		Long: `This command publishes the events of a dead-letter spool file to the
+configured output. The file is renamed while its events are replayed, and is
+removed once all events have been published. Events failing again are written
+to a new spool file, if the dead-letter spool is enabled.
+
+The beat should be stopped while replaying its spool file.
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				fmt.Fprintf(os.Stderr, "Expected exactly one dead-letter spool file\n")
+				os.Exit(1)
+			}
+
+			beat, err := instance.NewBeat(name, idxPrefix, version)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error initializing beat: %s\n", err)
+				os.Exit(1)
+			}
+
+			if err = beat.ReplayDeadLetter(args[0]); err != nil {
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+func genReplayCaptureCmd(name, idxPrefix, version string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "replay <file>",
+		Short: "Replay captured events through the pipeline",
+		Long: `This command publishes the events of a capture file to the configured
+output. Captured events are recorded before any processing, so they are run
+through the processors configured when replaying. This allows testing changes
+to the processors against a sample of real events, e.g. using the console
+output.
+
+The capture file is not modified, and can be replayed multiple times.
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				fmt.Fprintf(os.Stderr, "Expected exactly one capture file\n")
+				os.Exit(1)
+			}
+
+			beat, err := instance.NewBeat(name, idxPrefix, version)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error initializing beat: %s\n", err)
+				os.Exit(1)
+			}
+
+			if err = beat.ReplayCapture(args[0]); err != nil {
+				os.Exit(1)
+			}
+		},
+	}
+}
@@ -24,12 +24,15 @@ func init() {
 // flags and runs subcommands
 type BeatsRootCmd struct {
 	cobra.Command
-	RunCmd        *cobra.Command
-	SetupCmd      *cobra.Command
-	VersionCmd    *cobra.Command
-	CompletionCmd *cobra.Command
-	ExportCmd     *cobra.Command
-	TestCmd       *cobra.Command
+	RunCmd           *cobra.Command
+	SetupCmd         *cobra.Command
+	VersionCmd       *cobra.Command
+	CompletionCmd    *cobra.Command
+	ExportCmd        *cobra.Command
+	TestCmd          *cobra.Command
+	ReplayCmd        *cobra.Command
+	ReplayCaptureCmd *cobra.Command
+	UnmaskCmd        *cobra.Command
 }
 
 // GenRootCmd returns the root command to use for your beat. It takes
@@ -57,6 +60,9 @@ func GenRootCmdWithIndexPrefixWithRunFlags(name, indexPrefix, version string, be
 	rootCmd.CompletionCmd = genCompletionCmd(name, version, rootCmd)
 	rootCmd.ExportCmd = genExportCmd(name, indexPrefix, version)
 	rootCmd.TestCmd = genTestCmd(name, version, beatCreator)
+	rootCmd.ReplayCmd = genReplayDeadLetterCmd(name, indexPrefix, version)
+	rootCmd.ReplayCaptureCmd = genReplayCaptureCmd(name, indexPrefix, version)
+	rootCmd.UnmaskCmd = genUnmaskCmd()
 
 	// Root command is an alias for run
 	rootCmd.Run = rootCmd.RunCmd.Run
@@ -87,6 +93,9 @@ func GenRootCmdWithIndexPrefixWithRunFlags(name, indexPrefix, version string, be
 	rootCmd.AddCommand(rootCmd.CompletionCmd)
 	rootCmd.AddCommand(rootCmd.ExportCmd)
 	rootCmd.AddCommand(rootCmd.TestCmd)
+	rootCmd.AddCommand(rootCmd.ReplayCmd)
+	rootCmd.AddCommand(rootCmd.ReplayCaptureCmd)
+	rootCmd.AddCommand(rootCmd.UnmaskCmd)
 
 	return rootCmd
 }
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/beats/libbeat/common/mask"
+)
+
+func genUnmaskCmd() *cobra.Command {
+	unmaskCmd := &cobra.Command{
+		Use:   "unmask <value>...",
+		Short: "Decrypt values masked by the reversible_mask or encrypt_fields processors",
+		Long: `This command decrypts the values of fields masked by the reversible_mask
+processor, printing each value on its own line. Values encrypted by the
+encrypt_fields processor are printed JSON encoded. The base64 encoded key is
+read from the environment variable configured by --key-env, so it does not show
+up in the shell history or the process list.
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				fmt.Fprintf(os.Stderr, "Expected at least one masked value\n")
+				os.Exit(1)
+			}
+
+			keyEnv, _ := cmd.Flags().GetString("key-env")
+			encoded := os.Getenv(keyEnv)
+			if encoded == "" {
+				fmt.Fprintf(os.Stderr, "Environment variable %s is not set\n", keyEnv)
+				os.Exit(1)
+			}
+
+			key, err := mask.DecodeKey(encoded)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid key in %s: %s\n", keyEnv, err)
+				os.Exit(1)
+			}
+			cipher, err := mask.NewCipher(key)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error initializing cipher: %s\n", err)
+				os.Exit(1)
+			}
+
+			failed := false
+			for _, masked := range args {
+				value, err := unmask(cipher, masked)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error unmasking %s: %s\n", masked, err)
+					failed = true
+					continue
+				}
+				fmt.Println(value)
+			}
+			if failed {
+				os.Exit(1)
+			}
+		},
+	}
+
+	unmaskCmd.Flags().String("key-env", "REVERSIBLE_MASK_KEY", "Environment variable holding the base64 encoded key")
+	return unmaskCmd
+}
+
+// unmask decrypts a masked value, or a value encrypted by encrypt_fields
+// returned JSON encoded.
+func unmask(cipher *mask.Cipher, masked string) (string, error) {
+	if !strings.HasPrefix(masked, mask.EncryptedPrefix) {
+		return cipher.Unmask(masked)
+	}
+
+	value, err := cipher.Decrypt(masked)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
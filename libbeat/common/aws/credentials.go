@@ -0,0 +1,257 @@
+package aws
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CredentialsConfig configures the credentials used to sign requests. If no
+// access key is configured, the credentials are loaded from the standard AWS
+// environment variables, the shared credentials file, or the IAM role of the
+// EC2 instance, in this order.
+type CredentialsConfig struct {
+	AccessKeyID     string `config:"access_key_id"`
+	SecretAccessKey string `config:"secret_access_key"`
+	SessionToken    string `config:"session_token"`
+	CredentialsFile string `config:"credentials_file"`
+	Profile         string `config:"profile"`
+}
+
+// CredentialsProvider returns the credentials to sign a request with.
+// Credentials must be requested for each request, as temporary credentials
+// are refreshed by the provider once they expire.
+type CredentialsProvider interface {
+	Credentials() (Credentials, error)
+}
+
+// staticCredentials provides fixed credentials.
+type staticCredentials Credentials
+
+func (c staticCredentials) Credentials() (Credentials, error) {
+	return Credentials(c), nil
+}
+
+// metadataEndpoint is the address of the EC2 instance metadata service.
+var metadataEndpoint = "http://169.254.169.254"
+
+const (
+	metadataCredentialsPath = "/latest/meta-data/iam/security-credentials/"
+	metadataTokenPath       = "/latest/api/token"
+
+	// instance credentials are refreshed this long before they expire
+	credentialsExpiryWindow = 5 * time.Minute
+)
+
+// NewCredentialsProvider creates the provider of the configured credentials.
+// Static credentials, environment variables and the shared credentials file
+// are read once. If none of them provides credentials, the temporary
+// credentials of the IAM role of the EC2 instance are used.
+func NewCredentialsProvider(config CredentialsConfig) (CredentialsProvider, error) {
+	if config.AccessKeyID != "" || config.SecretAccessKey != "" {
+		if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+			return nil, fmt.Errorf("access_key_id and secret_access_key must be configured together")
+		}
+		return staticCredentials{
+			AccessKeyID:     config.AccessKeyID,
+			SecretAccessKey: config.SecretAccessKey,
+			SessionToken:    config.SessionToken,
+		}, nil
+	}
+
+	if id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+		return staticCredentials{
+			AccessKeyID:     id,
+			SecretAccessKey: secret,
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+
+	creds, found, err := loadSharedCredentials(config.CredentialsFile, config.Profile)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return staticCredentials(creds), nil
+	}
+
+	return newInstanceCredentials(), nil
+}
+
+// loadSharedCredentials reads the credentials of the profile from the shared
+// credentials file. If no file is configured, AWS_SHARED_CREDENTIALS_FILE or
+// ~/.aws/credentials is used, and a missing file is not an error.
+func loadSharedCredentials(path, profile string) (Credentials, bool, error) {
+	required := path != ""
+	if path == "" {
+		path = os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	}
+	if path == "" {
+		home := os.Getenv("HOME")
+		if home == "" {
+			return Credentials{}, false, nil
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	if profile == "" {
+		profile = "default"
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			return Credentials{}, false, nil
+		}
+		return Credentials{}, false, fmt.Errorf("error reading AWS credentials file: %v", err)
+	}
+	defer f.Close()
+
+	var creds Credentials
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if section != profile {
+			continue
+		}
+
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(kv[1])
+		switch strings.TrimSpace(kv[0]) {
+		case "aws_access_key_id":
+			creds.AccessKeyID = value
+		case "aws_secret_access_key":
+			creds.SecretAccessKey = value
+		case "aws_session_token":
+			creds.SessionToken = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Credentials{}, false, fmt.Errorf("error reading AWS credentials file: %v", err)
+	}
+
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		if required {
+			return Credentials{}, false, fmt.Errorf("no credentials for profile '%v' in %v", profile, path)
+		}
+		return Credentials{}, false, nil
+	}
+	return creds, true, nil
+}
+
+// instanceCredentials provides the temporary credentials of the IAM role of
+// the EC2 instance, from the instance metadata service. The credentials are
+// cached until shortly before they expire.
+type instanceCredentials struct {
+	http *http.Client
+
+	mutex      sync.Mutex
+	creds      Credentials
+	expiration time.Time
+	now        func() time.Time
+}
+
+func newInstanceCredentials() *instanceCredentials {
+	return &instanceCredentials{
+		http: &http.Client{Timeout: 5 * time.Second},
+		now:  time.Now,
+	}
+}
+
+func (c *instanceCredentials) Credentials() (Credentials, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.now().Before(c.expiration.Add(-credentialsExpiryWindow)) {
+		return c.creds, nil
+	}
+
+	// IMDSv2 requires a session token, fall back to IMDSv1 if the token can
+	// not be requested.
+	token, _ := c.request("PUT", metadataTokenPath, "")
+
+	role, err := c.request("GET", metadataCredentialsPath, token)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("error getting the IAM role of the instance: %v", err)
+	}
+	role = strings.TrimSpace(strings.SplitN(role, "\n", 2)[0])
+	if role == "" {
+		return Credentials{}, fmt.Errorf("no IAM role attached to the instance")
+	}
+
+	body, err := c.request("GET", metadataCredentialsPath+role, token)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("error getting the credentials of IAM role '%v': %v", role, err)
+	}
+
+	var resp struct {
+		Code            string
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string
+		Token           string
+		Expiration      time.Time
+	}
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		return Credentials{}, fmt.Errorf("error decoding the credentials of IAM role '%v': %v", role, err)
+	}
+	if resp.Code != "Success" {
+		return Credentials{}, fmt.Errorf("failed to get the credentials of IAM role '%v': %v", role, resp.Code)
+	}
+
+	c.creds = Credentials{
+		AccessKeyID:     resp.AccessKeyID,
+		SecretAccessKey: resp.SecretAccessKey,
+		SessionToken:    resp.Token,
+	}
+	c.expiration = resp.Expiration
+	return c.creds, nil
+}
+
+func (c *instanceCredentials) request(method, path, token string) (string, error) {
+	req, err := http.NewRequest(method, metadataEndpoint+path, nil)
+	if err != nil {
+		return "", err
+	}
+	if method == "PUT" {
+		req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	}
+	if token != "" {
+		req.Header.Set("X-aws-ec2-metadata-token", token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %v", resp.Status)
+	}
+	return string(body), nil
+}
@@ -0,0 +1,171 @@
+// +build !integration
+
+package aws
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setEnv sets the environment variables for the duration of the test. Empty
+// values unset the variable.
+func setEnv(t *testing.T, env map[string]string) func() {
+	old := map[string]string{}
+	for name, value := range env {
+		old[name] = os.Getenv(name)
+		if value == "" {
+			require.NoError(t, os.Unsetenv(name))
+		} else {
+			require.NoError(t, os.Setenv(name, value))
+		}
+	}
+	return func() {
+		for name, value := range old {
+			os.Setenv(name, value)
+		}
+	}
+}
+
+func noAWSEnv(home string) map[string]string {
+	return map[string]string{
+		"AWS_ACCESS_KEY_ID":           "",
+		"AWS_SECRET_ACCESS_KEY":       "",
+		"AWS_SESSION_TOKEN":           "",
+		"AWS_SHARED_CREDENTIALS_FILE": "",
+		"AWS_PROFILE":                 "",
+		"HOME":                        home,
+	}
+}
+
+func TestCredentialsStatic(t *testing.T) {
+	provider, err := NewCredentialsProvider(CredentialsConfig{
+		AccessKeyID:     "id",
+		SecretAccessKey: "secret",
+		SessionToken:    "token",
+	})
+	require.NoError(t, err)
+
+	creds, err := provider.Credentials()
+	require.NoError(t, err)
+	assert.Equal(t, Credentials{AccessKeyID: "id", SecretAccessKey: "secret", SessionToken: "token"}, creds)
+
+	_, err = NewCredentialsProvider(CredentialsConfig{AccessKeyID: "id"})
+	assert.Error(t, err)
+}
+
+func TestCredentialsEnv(t *testing.T) {
+	env := noAWSEnv("")
+	env["AWS_ACCESS_KEY_ID"] = "env-id"
+	env["AWS_SECRET_ACCESS_KEY"] = "env-secret"
+	defer setEnv(t, env)()
+
+	provider, err := NewCredentialsProvider(CredentialsConfig{})
+	require.NoError(t, err)
+
+	creds, err := provider.Credentials()
+	require.NoError(t, err)
+	assert.Equal(t, Credentials{AccessKeyID: "env-id", SecretAccessKey: "env-secret"}, creds)
+}
+
+func TestCredentialsSharedFile(t *testing.T) {
+	home, err := ioutil.TempDir("", "aws")
+	require.NoError(t, err)
+	defer os.RemoveAll(home)
+	defer setEnv(t, noAWSEnv(home))()
+
+	require.NoError(t, os.Mkdir(filepath.Join(home, ".aws"), 0700))
+	content := `
+# comment
+[default]
+aws_access_key_id = default-id
+aws_secret_access_key = default-secret
+
+[logs]
+aws_access_key_id=logs-id
+aws_secret_access_key=logs-secret
+aws_session_token=logs-token
+`
+	path := filepath.Join(home, ".aws", "credentials")
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+
+	provider, err := NewCredentialsProvider(CredentialsConfig{})
+	require.NoError(t, err)
+	creds, err := provider.Credentials()
+	require.NoError(t, err)
+	assert.Equal(t, Credentials{AccessKeyID: "default-id", SecretAccessKey: "default-secret"}, creds)
+
+	provider, err = NewCredentialsProvider(CredentialsConfig{CredentialsFile: path, Profile: "logs"})
+	require.NoError(t, err)
+	creds, err = provider.Credentials()
+	require.NoError(t, err)
+	assert.Equal(t, Credentials{AccessKeyID: "logs-id", SecretAccessKey: "logs-secret", SessionToken: "logs-token"}, creds)
+
+	// Explicitly configured files and profiles must exist.
+	_, err = NewCredentialsProvider(CredentialsConfig{CredentialsFile: path, Profile: "missing"})
+	assert.Error(t, err)
+	_, err = NewCredentialsProvider(CredentialsConfig{CredentialsFile: filepath.Join(home, "missing")})
+	assert.Error(t, err)
+}
+
+func TestCredentialsInstanceRole(t *testing.T) {
+	defer setEnv(t, noAWSEnv(""))()
+
+	now := time.Date(2018, 1, 1, 10, 0, 0, 0, time.UTC)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == metadataTokenPath {
+			assert.Equal(t, "PUT", r.Method)
+			w.Write([]byte("session-token"))
+			return
+		}
+
+		assert.Equal(t, "session-token", r.Header.Get("X-aws-ec2-metadata-token"))
+		switch r.URL.Path {
+		case metadataCredentialsPath:
+			w.Write([]byte("beats-role"))
+		case metadataCredentialsPath + "beats-role":
+			requests++
+			w.Write([]byte(`{
+				"Code": "Success",
+				"AccessKeyId": "role-id",
+				"SecretAccessKey": "role-secret",
+				"Token": "role-token",
+				"Expiration": "` + now.Add(time.Hour).Format(time.RFC3339) + `"
+			}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	defer func(endpoint string) { metadataEndpoint = endpoint }(metadataEndpoint)
+	metadataEndpoint = server.URL
+
+	provider, err := NewCredentialsProvider(CredentialsConfig{})
+	require.NoError(t, err)
+	require.IsType(t, &instanceCredentials{}, provider)
+	provider.(*instanceCredentials).now = func() time.Time { return now }
+
+	creds, err := provider.Credentials()
+	require.NoError(t, err)
+	assert.Equal(t, Credentials{AccessKeyID: "role-id", SecretAccessKey: "role-secret", SessionToken: "role-token"}, creds)
+
+	// Cached until shortly before the credentials expire.
+	now = now.Add(30 * time.Minute)
+	_, err = provider.Credentials()
+	require.NoError(t, err)
+	assert.Equal(t, 1, requests)
+
+	now = now.Add(26 * time.Minute)
+	_, err = provider.Credentials()
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests)
+}
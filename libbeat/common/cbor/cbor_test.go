@@ -0,0 +1,166 @@
+// +build !integration
+
+package cbor
+
+import (
+	"encoding/hex"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func decodeHex(t *testing.T, s string) (interface{}, error) {
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Decode(data, DefaultMaxDepth)
+}
+
+// Examples from Appendix A of RFC 7049.
+func TestDecodeScalars(t *testing.T) {
+	tests := []struct {
+		hex      string
+		expected interface{}
+	}{
+		{"00", int64(0)},
+		{"17", int64(23)},
+		{"1818", int64(24)},
+		{"1903e8", int64(1000)},
+		{"1a000f4240", int64(1000000)},
+		{"1bffffffffffffffff", uint64(math.MaxUint64)},
+		{"20", int64(-1)},
+		{"3863", int64(-100)},
+		{"3bffffffffffffffff", "-18446744073709551616"},
+		{"f90000", float64(0)},
+		{"f93c00", float64(1)},
+		{"f9c400", float64(-4)},
+		{"f97bff", float64(65504)},
+		{"f90001", 5.960464477539063e-8},
+		{"f97c00", math.Inf(1)},
+		{"fa47c35000", float64(100000)},
+		{"fb3ff199999999999a", 1.1},
+		{"f4", false},
+		{"f5", true},
+		{"f6", nil},
+		{"f7", nil},
+		{"60", ""},
+		{"6449455446", "IETF"},
+		{"62c3bc", "ü"},
+		{"7f657374726561646d696e67ff", "streaming"},
+		{"4401020304", "AQIDBA=="},
+		{"5f42010243030405ff", "AQIDBAU="},
+	}
+
+	for _, test := range tests {
+		v, err := decodeHex(t, test.hex)
+		if assert.NoError(t, err, test.hex) {
+			assert.Equal(t, test.expected, v, test.hex)
+		}
+	}
+}
+
+func TestDecodeNested(t *testing.T) {
+	// {"a": 1, "b": [2, 3], "c": {"d": [true, null]}, 7: "seven"}
+	v, err := decodeHex(t, "a461610161628202036163a1616482f5f60765736576656e")
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"a": int64(1),
+		"b": []interface{}{int64(2), int64(3)},
+		"c": common.MapStr{"d": []interface{}{true, nil}},
+		"7": "seven",
+	}, v)
+
+	// Indefinite length: {_ "Fun": true, "Amt": -2} and [_ 1, [2, 3], [_ 4, 5]]
+	v, err = decodeHex(t, "bf6346756ef563416d7421ff")
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{"Fun": true, "Amt": int64(-2)}, v)
+
+	v, err = decodeHex(t, "9f018202039f0405ffff")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		int64(1),
+		[]interface{}{int64(2), int64(3)},
+		[]interface{}{int64(4), int64(5)},
+	}, v)
+}
+
+func TestDecodeTags(t *testing.T) {
+	tests := []struct {
+		hex      string
+		expected interface{}
+	}{
+		// 0("2013-03-21T20:04:00Z")
+		{"c074323031332d30332d32315432303a30343a30305a", common.Time(time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC))},
+		// 1(1363896240)
+		{"c11a514b67b0", common.Time(time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC))},
+		// 1(1363896240.5)
+		{"c1fb41d452d9ec200000", common.Time(time.Date(2013, 3, 21, 20, 4, 0, 500000000, time.UTC))},
+		// 2(h'010000000000000000'), 18446744073709551616
+		{"c249010000000000000000", "18446744073709551616"},
+		// 2(h'0100'), fits in an integer
+		{"c2420100", int64(256)},
+		// 3(h'010000000000000000'), -18446744073709551617
+		{"c349010000000000000000", "-18446744073709551617"},
+		// 32("http://www.example.com"), unknown tags are ignored
+		{"d82076687474703a2f2f7777772e6578616d706c652e636f6d", "http://www.example.com"},
+	}
+
+	for _, test := range tests {
+		v, err := decodeHex(t, test.hex)
+		if assert.NoError(t, err, test.hex) {
+			assert.Equal(t, test.expected, v, test.hex)
+		}
+	}
+}
+
+func TestDecodeMaxDepth(t *testing.T) {
+	// [[[1]]]
+	data := []byte{0x81, 0x81, 0x81, 0x01}
+	v, err := Decode(data, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{[]interface{}{[]interface{}{int64(1)}}}, v)
+
+	_, err = Decode(data, 2)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "CBOR nesting exceeds 2 levels")
+	}
+
+	// Deeply nested data is rejected before it is decoded.
+	deep := []byte(strings.Repeat("\x81", 100000) + "\x01")
+	_, err = Decode(deep, DefaultMaxDepth)
+	assert.Error(t, err)
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	tests := map[string]string{
+		"empty":                    "",
+		"truncated argument":       "19e8",
+		"truncated string":         "64494554",
+		"huge length":              "5bffffffffffffffff",
+		"huge array":               "9bffffffffffffffff",
+		"truncated map":            "a26161",
+		"reserved info":            "1c",
+		"trailing data":            "0000",
+		"break without container":  "ff",
+		"break in definite array":  "8201ff",
+		"invalid utf-8":            "62c328",
+		"unsupported key":          "a1f401",
+		"invalid chunk":            "5f6161ff",
+		"indefinite integer":       "1f",
+		"invalid date":             "c06161",
+		"date not a string":        "c001",
+		"bignum not bytes":         "c201",
+		"unsupported simple value": "f0",
+	}
+
+	for name, h := range tests {
+		_, err := decodeHex(t, h)
+		assert.Error(t, err, name)
+	}
+}
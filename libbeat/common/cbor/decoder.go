@@ -0,0 +1,426 @@
+// Package cbor decodes CBOR data items (RFC 7049) into event values. Maps are
+// decoded as common.MapStr, arrays as []interface{}, byte strings as base64
+// encoded strings. Date/time and bignum tags are decoded into times and
+// integers, the content of other tags is decoded ignoring the tag.
+package cbor
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// DefaultMaxDepth is the default limit of the nesting of arrays, maps and
+// tags.
+const DefaultMaxDepth = 64
+
+// Major types of the CBOR encoding.
+const (
+	majorUnsigned = 0
+	majorNegative = 1
+	majorBytes    = 2
+	majorText     = 3
+	majorArray    = 4
+	majorMap      = 5
+	majorTag      = 6
+	majorSimple   = 7
+)
+
+// Tags with a decoded meaning.
+const (
+	tagDateTime       = 0
+	tagEpochTime      = 1
+	tagPositiveBignum = 2
+	tagNegativeBignum = 3
+)
+
+// indefinite is the additional information of items of indefinite length,
+// and of the break stop code when used with the simple major type.
+const indefinite = 31
+
+var (
+	errTruncated = errors.New("unexpected end of CBOR data")
+	errBreak     = errors.New("unexpected CBOR break stop code")
+)
+
+// Decode decodes a single CBOR data item. Nesting of arrays, maps and tags
+// deeper than maxDepth is rejected. Data following the item is an error.
+func Decode(data []byte, maxDepth int) (interface{}, error) {
+	d := &decoder{buf: data, maxDepth: maxDepth}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, fmt.Errorf("%v at offset %d", err, d.pos)
+	}
+	if d.pos != len(d.buf) {
+		return nil, fmt.Errorf("unexpected data after the CBOR item at offset %d", d.pos)
+	}
+	return v, nil
+}
+
+type decoder struct {
+	buf      []byte
+	pos      int
+	maxDepth int
+}
+
+// head reads the initial byte of a data item, and its argument. For items of
+// indefinite length, the argument is not read and indefinite is returned as
+// additional information.
+func (d *decoder) head() (major byte, info byte, arg uint64, err error) {
+	if d.pos >= len(d.buf) {
+		return 0, 0, 0, errTruncated
+	}
+	b := d.buf[d.pos]
+	d.pos++
+	major, info = b>>5, b&0x1f
+
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info == 24:
+		arg, err = d.uint(1)
+	case info == 25:
+		arg, err = d.uint(2)
+	case info == 26:
+		arg, err = d.uint(4)
+	case info == 27:
+		arg, err = d.uint(8)
+	case info == indefinite:
+		return major, info, 0, nil
+	default:
+		return 0, 0, 0, fmt.Errorf("reserved additional information %d", info)
+	}
+	return major, info, arg, err
+}
+
+func (d *decoder) uint(n int) (uint64, error) {
+	b, err := d.bytes(uint64(n))
+	if err != nil {
+		return 0, err
+	}
+	switch n {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	default:
+		return binary.BigEndian.Uint64(b), nil
+	}
+}
+
+// bytes returns the next n bytes, failing if the data is shorter, so no
+// memory is allocated for lengths exceeding the data.
+func (d *decoder) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.buf)-d.pos) {
+		return nil, errTruncated
+	}
+	b := d.buf[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+func (d *decoder) value(depth int) (interface{}, error) {
+	major, info, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	if info == indefinite && (major == majorUnsigned || major == majorNegative || major == majorTag) {
+		return nil, fmt.Errorf("invalid indefinite length for major type %d", major)
+	}
+
+	switch major {
+	case majorUnsigned:
+		return unsigned(arg), nil
+
+	case majorNegative:
+		if arg > math.MaxInt64 {
+			n := new(big.Int).SetUint64(arg)
+			return bigInt(n.Neg(n).Sub(n, big.NewInt(1))), nil
+		}
+		return -1 - int64(arg), nil
+
+	case majorBytes:
+		b, err := d.string(major, info, arg)
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(b), nil
+
+	case majorText:
+		b, err := d.string(major, info, arg)
+		if err != nil {
+			return nil, err
+		}
+		if !utf8.Valid(b) {
+			return nil, errors.New("invalid UTF-8 text string")
+		}
+		return string(b), nil
+
+	case majorArray:
+		if err := d.checkDepth(depth); err != nil {
+			return nil, err
+		}
+		return d.array(info, arg, depth+1)
+
+	case majorMap:
+		if err := d.checkDepth(depth); err != nil {
+			return nil, err
+		}
+		return d.mapStr(info, arg, depth+1)
+
+	case majorTag:
+		if err := d.checkDepth(depth); err != nil {
+			return nil, err
+		}
+		return d.tag(arg, depth+1)
+
+	default:
+		return d.simple(info, arg)
+	}
+}
+
+func (d *decoder) checkDepth(depth int) error {
+	if depth >= d.maxDepth {
+		return fmt.Errorf("CBOR nesting exceeds %d levels", d.maxDepth)
+	}
+	return nil
+}
+
+// string reads a byte or text string. Strings of indefinite length are the
+// concatenation of definite length chunks of the same major type.
+func (d *decoder) string(major, info byte, arg uint64) ([]byte, error) {
+	if info != indefinite {
+		return d.bytes(arg)
+	}
+
+	var s []byte
+	for {
+		chunkMajor, chunkInfo, chunkArg, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if chunkMajor == majorSimple && chunkInfo == indefinite {
+			return s, nil
+		}
+		if chunkMajor != major || chunkInfo == indefinite {
+			return nil, fmt.Errorf("invalid chunk of major type %d in string of major type %d", chunkMajor, major)
+		}
+		chunk, err := d.bytes(chunkArg)
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, chunk...)
+	}
+}
+
+func (d *decoder) array(info byte, arg uint64, depth int) (interface{}, error) {
+	if info == indefinite {
+		list := []interface{}{}
+		for {
+			v, err := d.value(depth)
+			if err == errBreak {
+				return list, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+	}
+
+	// Each item takes at least one byte.
+	if arg > uint64(len(d.buf)-d.pos) {
+		return nil, errTruncated
+	}
+	list := make([]interface{}, 0, arg)
+	for i := uint64(0); i < arg; i++ {
+		v, err := d.value(depth)
+		if err != nil {
+			return nil, breakError(err)
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+func (d *decoder) mapStr(info byte, arg uint64, depth int) (interface{}, error) {
+	m := common.MapStr{}
+	for i := uint64(0); info == indefinite || i < arg; i++ {
+		k, err := d.value(depth)
+		if err == errBreak && info == indefinite {
+			return m, nil
+		}
+		if err != nil {
+			return nil, breakError(err)
+		}
+
+		var key string
+		switch k := k.(type) {
+		case string:
+			key = k
+		case int64:
+			key = strconv.FormatInt(k, 10)
+		case uint64:
+			key = strconv.FormatUint(k, 10)
+		default:
+			return nil, fmt.Errorf("unsupported map key of type %T", k)
+		}
+
+		v, err := d.value(depth)
+		if err != nil {
+			return nil, breakError(err)
+		}
+		m[key] = v
+	}
+	return m, nil
+}
+
+func (d *decoder) tag(tag uint64, depth int) (interface{}, error) {
+	if tag == tagPositiveBignum || tag == tagNegativeBignum {
+		return d.bignum(tag)
+	}
+
+	v, err := d.value(depth)
+	if err != nil {
+		return nil, breakError(err)
+	}
+
+	switch tag {
+	case tagDateTime:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("date/time tag content must be a text string, got %T", v)
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date/time tag content: %v", err)
+		}
+		return common.Time(t.UTC()), nil
+
+	case tagEpochTime:
+		switch n := v.(type) {
+		case int64:
+			return common.Time(time.Unix(n, 0).UTC()), nil
+		case uint64:
+			if n > math.MaxInt64 {
+				return nil, errors.New("epoch time tag content out of range")
+			}
+			return common.Time(time.Unix(int64(n), 0).UTC()), nil
+		case float64:
+			if math.IsNaN(n) || math.IsInf(n, 0) {
+				return nil, errors.New("epoch time tag content must be finite")
+			}
+			sec, frac := math.Modf(n)
+			return common.Time(time.Unix(int64(sec), int64(frac*1e9)).UTC()), nil
+		default:
+			return nil, fmt.Errorf("epoch time tag content must be a number, got %T", v)
+		}
+	}
+
+	return v, nil
+}
+
+// bignum reads the byte string content of a bignum tag. The bignum is
+// reported as an integer if it fits in 64 bits.
+func (d *decoder) bignum(tag uint64) (interface{}, error) {
+	major, info, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	if major != majorBytes {
+		return nil, fmt.Errorf("bignum tag content must be a byte string, got major type %d", major)
+	}
+	b, err := d.string(major, info, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	n := new(big.Int).SetBytes(b)
+	if tag == tagNegativeBignum {
+		n.Neg(n).Sub(n, big.NewInt(1))
+	}
+	return bigInt(n), nil
+}
+
+func (d *decoder) simple(info byte, arg uint64) (interface{}, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		// null and undefined
+		return nil, nil
+	case 25:
+		return halfFloat(uint16(arg)), nil
+	case 26:
+		return float64(math.Float32frombits(uint32(arg))), nil
+	case 27:
+		return math.Float64frombits(arg), nil
+	case indefinite:
+		return nil, errBreak
+	default:
+		return nil, fmt.Errorf("unsupported simple value %d", arg)
+	}
+}
+
+// breakError reports a break stop code inside of an item of definite length
+// as an error.
+func breakError(err error) error {
+	if err == errBreak {
+		return errors.New("unexpected CBOR break stop code")
+	}
+	return err
+}
+
+// unsigned returns the integer as int64 if it fits.
+func unsigned(n uint64) interface{} {
+	if n <= math.MaxInt64 {
+		return int64(n)
+	}
+	return n
+}
+
+// bigInt returns the bignum as int64 or uint64 if it fits, or as its decimal
+// string otherwise.
+func bigInt(n *big.Int) interface{} {
+	if n.IsInt64() {
+		return n.Int64()
+	}
+	if n.IsUint64() {
+		return n.Uint64()
+	}
+	return n.String()
+}
+
+// halfFloat converts an IEEE 754 half-precision float.
+func halfFloat(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			return math.Inf(int(sign))
+		}
+		return math.NaN()
+	default:
+		return sign * math.Ldexp(mant+1024, exp-25)
+	}
+}
@@ -14,8 +14,10 @@ func Beta(format string, v ...interface{}) {
 // Deprecate logs a deprecation message.
 // The version string contains the version when the future will be removed
 func Deprecate(version string, format string, v ...interface{}) {
-	postfix := fmt.Sprintf(" Will be removed in version: %s", version)
-	logp.Warn("DEPRECATED: "+format+postfix, v...)
+	report(Deprecation{
+		Version: version,
+		Message: fmt.Sprintf(format, v...),
+	})
 }
 
 // Experimental logs the usage of an experimental feature.
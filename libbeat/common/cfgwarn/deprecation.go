@@ -0,0 +1,116 @@
+package cfgwarn
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/monitoring"
+)
+
+// Deprecation describes the usage of a deprecated setting or feature.
+type Deprecation struct {
+	// Setting is the deprecated configuration key. It is empty for deprecated
+	// features reported by Deprecate.
+	Setting string
+
+	// Replacement is the recommended setting to use instead, if any.
+	Replacement string
+
+	// Version is the version the setting will be removed in.
+	Version string
+
+	Message string
+}
+
+var deprecations = monitoring.NewInt(nil, "libbeat.config.deprecations")
+
+var registry = struct {
+	sync.Mutex
+	reported []Deprecation
+	handler  func(Deprecation)
+}{}
+
+// DeprecateSetting logs and reports the usage of a deprecated setting, and the
+// setting to be used instead. The replacement is optional.
+func DeprecateSetting(setting, replacement, version string) {
+	msg := fmt.Sprintf("setting '%v' is deprecated.", setting)
+	if replacement != "" {
+		msg = fmt.Sprintf("setting '%v' is deprecated. Use '%v' instead.", setting, replacement)
+	}
+
+	report(Deprecation{
+		Setting:     setting,
+		Replacement: replacement,
+		Version:     version,
+		Message:     msg,
+	})
+}
+
+// CheckDeprecatedSetting reports the usage of a deprecated setting, if the
+// setting is present in cfg. It returns true if the setting is used.
+func CheckDeprecatedSetting(cfg *common.Config, setting, replacement, version string) bool {
+	segments := strings.Split(setting, ".")
+	current := cfg
+	for _, p := range segments[:len(segments)-1] {
+		if current == nil || !current.HasField(p) {
+			return false
+		}
+		current, _ = current.Child(p, -1)
+	}
+	if current == nil || !current.HasField(segments[len(segments)-1]) {
+		return false
+	}
+
+	DeprecateSetting(current.PathOf(segments[len(segments)-1]), replacement, version)
+	return true
+}
+
+// SetDeprecationHandler installs a handler called for each deprecation
+// reported. The deprecations reported before are passed to the handler right
+// away. The handler is removed if nil.
+func SetDeprecationHandler(handler func(Deprecation)) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	registry.handler = handler
+	if handler != nil {
+		for _, d := range registry.reported {
+			handler(d)
+		}
+	}
+}
+
+// Deprecations returns the distinct deprecations reported so far.
+func Deprecations() []Deprecation {
+	registry.Lock()
+	defer registry.Unlock()
+
+	return append([]Deprecation(nil), registry.reported...)
+}
+
+func report(d Deprecation) {
+	logp.Warn("DEPRECATED: %s Will be removed in version: %s", d.Message, d.Version)
+	deprecations.Inc()
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	if !isReported(d) {
+		registry.reported = append(registry.reported, d)
+	}
+	if registry.handler != nil {
+		registry.handler(d)
+	}
+}
+
+func isReported(d Deprecation) bool {
+	for _, r := range registry.reported {
+		if r == d {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+// +build !integration
+
+package cfgwarn
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func resetDeprecations() {
+	SetDeprecationHandler(nil)
+	registry.reported = nil
+	deprecations.Set(0)
+}
+
+func TestCheckDeprecatedSetting(t *testing.T) {
+	defer resetDeprecations()
+	resetDeprecations()
+
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"input_type": "log",
+		"process": map[string]interface{}{
+			"cpu_ticks": true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var handled []Deprecation
+	SetDeprecationHandler(func(d Deprecation) {
+		handled = append(handled, d)
+	})
+
+	assert.True(t, CheckDeprecatedSetting(cfg, "input_type", "type", "7.0.0"))
+	assert.True(t, CheckDeprecatedSetting(cfg, "process.cpu_ticks", "", "7.0.0"))
+	assert.False(t, CheckDeprecatedSetting(cfg, "document_type", "fields", "7.0.0"))
+	assert.False(t, CheckDeprecatedSetting(cfg, "process.missing", "", "7.0.0"))
+	assert.False(t, CheckDeprecatedSetting(cfg, "missing.cpu_ticks", "", "7.0.0"))
+
+	expected := []Deprecation{
+		{
+			Setting:     "input_type",
+			Replacement: "type",
+			Version:     "7.0.0",
+			Message:     "setting 'input_type' is deprecated. Use 'type' instead.",
+		},
+		{
+			Setting: "process.cpu_ticks",
+			Version: "7.0.0",
+			Message: "setting 'process.cpu_ticks' is deprecated.",
+		},
+	}
+	assert.Equal(t, expected, handled)
+	assert.Equal(t, expected, Deprecations())
+	assert.Equal(t, int64(2), deprecations.Get())
+}
+
+func TestDeprecationHandlerReplay(t *testing.T) {
+	defer resetDeprecations()
+	resetDeprecations()
+
+	Deprecate("7.0.0", "%v is deprecated.", "config_dir")
+	DeprecateSetting("input_type", "type", "6.0.0")
+	DeprecateSetting("input_type", "type", "6.0.0")
+
+	// reported deprecations are passed to the handler once installed
+	var handled []Deprecation
+	SetDeprecationHandler(func(d Deprecation) {
+		handled = append(handled, d)
+	})
+	assert.Equal(t, []Deprecation{
+		{Version: "7.0.0", Message: "config_dir is deprecated."},
+		{
+			Setting:     "input_type",
+			Replacement: "type",
+			Version:     "6.0.0",
+			Message:     "setting 'input_type' is deprecated. Use 'type' instead.",
+		},
+	}, handled)
+	assert.Equal(t, int64(3), deprecations.Get())
+
+	// repeated usage is reported to the handler and counted
+	DeprecateSetting("input_type", "type", "6.0.0")
+	assert.Len(t, handled, 3)
+	assert.Len(t, Deprecations(), 2)
+	assert.Equal(t, int64(4), deprecations.Get())
+
+	SetDeprecationHandler(nil)
+	DeprecateSetting("other", "", "6.0.0")
+	assert.Len(t, handled, 3)
+}
@@ -0,0 +1,73 @@
+// Package clock provides readings of the wall clock together with the
+// monotonic clock. Intervals timed with the monotonic clock are not affected by
+// changes of the system time, like NTP corrections or manual adjustments, and
+// comparing both clocks detects these changes.
+package clock
+
+import "time"
+
+// DefaultJumpThreshold is the difference between the elapsed wall clock time
+// and the elapsed monotonic time from which on a change of the system time is
+// reported as jump.
+const DefaultJumpThreshold = time.Second
+
+// Clock returns readings of the current time.
+type Clock interface {
+	Now() Reading
+}
+
+// Reading is a reading of the wall clock and the monotonic clock taken at the
+// same time.
+type Reading struct {
+	// Wall is the wall clock time, it jumps if the system time is changed.
+	Wall time.Time
+
+	// Mono is the monotonic time elapsed since the start of the clock. It is
+	// not affected by changes of the system time.
+	Mono time.Duration
+}
+
+type systemClock struct {
+	start time.Time
+}
+
+// System is the clock of the operating system.
+var System Clock = systemClock{start: time.Now()}
+
+func (c systemClock) Now() Reading {
+	now := time.Now()
+	return Reading{
+		Wall: now.Round(0), // strip the monotonic clock reading
+		Mono: now.Sub(c.start),
+	}
+}
+
+// JumpDetector detects jumps of the wall clock by comparing the wall clock time
+// elapsed between readings to the elapsed monotonic time.
+type JumpDetector struct {
+	threshold time.Duration
+	last      Reading
+	started   bool
+}
+
+// NewJumpDetector creates a JumpDetector reporting jumps exceeding threshold.
+func NewJumpDetector(threshold time.Duration) *JumpDetector {
+	return &JumpDetector{threshold: threshold}
+}
+
+// Check returns the jump of the wall clock since the previous reading passed to
+// Check, and whether it exceeds the threshold. Forward jumps are positive,
+// backward jumps are negative. The first reading never reports a jump.
+func (d *JumpDetector) Check(r Reading) (time.Duration, bool) {
+	last, started := d.last, d.started
+	d.last, d.started = r, true
+	if !started {
+		return 0, false
+	}
+
+	jump := r.Wall.Sub(last.Wall) - (r.Mono - last.Mono)
+	if jump < 0 {
+		return jump, -jump > d.threshold
+	}
+	return jump, jump > d.threshold
+}
@@ -0,0 +1,49 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSystemClock(t *testing.T) {
+	first := System.Now()
+	time.Sleep(10 * time.Millisecond)
+	second := System.Now()
+
+	assert.True(t, second.Mono-first.Mono >= 10*time.Millisecond)
+	assert.Equal(t, first.Wall, first.Wall.Round(0), "wall clock reading must not carry a monotonic reading")
+
+	jump, detected := NewJumpDetector(DefaultJumpThreshold).Check(first)
+	assert.False(t, detected)
+	assert.Zero(t, jump)
+}
+
+func TestJumpDetector(t *testing.T) {
+	start := time.Date(2018, 3, 1, 10, 0, 0, 0, time.UTC)
+	reading := func(wall, mono time.Duration) Reading {
+		return Reading{Wall: start.Add(wall), Mono: mono}
+	}
+
+	tests := []struct {
+		name     string
+		reading  Reading
+		jump     time.Duration
+		detected bool
+	}{
+		{"regular tick", reading(10*time.Second, 10*time.Second), 0, false},
+		{"small drift", reading(20*time.Second+500*time.Millisecond, 20*time.Second), 500 * time.Millisecond, false},
+		{"forward jump", reading(time.Hour+30*time.Second+500*time.Millisecond, 30*time.Second), time.Hour, true},
+		{"regular tick after jump", reading(time.Hour+40*time.Second+500*time.Millisecond, 40*time.Second), 0, false},
+		{"backward jump", reading(40*time.Second+500*time.Millisecond, 50*time.Second), -time.Hour - 10*time.Second, true},
+	}
+
+	d := NewJumpDetector(DefaultJumpThreshold)
+	d.Check(reading(0, 0))
+	for _, test := range tests {
+		jump, detected := d.Check(test.reading)
+		assert.Equal(t, test.jump, jump, test.name)
+		assert.Equal(t, test.detected, detected, test.name)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/elastic/beats/libbeat/common/file"
 	"github.com/elastic/beats/libbeat/logp"
@@ -56,16 +57,47 @@ const (
 	selectorConfigWithPassword = "config-with-passwords"
 )
 
-var debugBlacklist = MakeStringSet(
-	"password",
-	"passphrase",
-	"key_passphrase",
-	"pass",
-	"proxy_url",
-	"url",
-	"urls",
-	"host",
-	"hosts",
+// redactedValue replaces the values of sensitive settings in logged or
+// exported configurations.
+const redactedValue = "xxxxx"
+
+// sensitiveConfigKeys lists the setting names whose values are redacted in
+// logged configurations, regardless of where they appear in the configuration
+// tree. The secrets are the setting names whose values are redacted in
+// exported configurations too.
+var sensitiveConfigKeys = struct {
+	sync.RWMutex
+	keys    StringSet
+	secrets StringSet
+}{
+	keys: MakeStringSet(
+		"password",
+		"passphrase",
+		"key_passphrase",
+		"pass",
+		"api_key",
+		"token",
+		"key",
+		"proxy_url",
+		"url",
+		"urls",
+		"host",
+		"hosts",
+	),
+	secrets: MakeStringSet(
+		"password",
+		"passphrase",
+		"key_passphrase",
+		"api_key",
+		"token",
+	),
+}
+
+// secretConfigPaths lists the secrets matched by the name of their parent
+// setting. The key of the ssl settings is the private key, while the other key
+// settings, like the partition key of the Kafka output, are no secrets.
+var secretConfigPaths = MakeStringSet(
+	"ssl.key",
 )
 
 // make hasSelector and configDebugf available for unit testing
@@ -396,7 +428,7 @@ func configDebugString(c *Config, filterPrivate bool) string {
 			return fmt.Sprintf("<config error> %v", err)
 		}
 		if filterPrivate {
-			filterDebugObject(content)
+			RedactConfig(content)
 		}
 		j, _ := json.MarshalIndent(content, "", "  ")
 		bufs = append(bufs, string(j))
@@ -407,7 +439,7 @@ func configDebugString(c *Config, filterPrivate bool) string {
 			return fmt.Sprintf("<config error> %v", err)
 		}
 		if filterPrivate {
-			filterDebugObject(content)
+			RedactConfig(content)
 		}
 		j, _ := json.MarshalIndent(content, "", "  ")
 		bufs = append(bufs, string(j))
@@ -419,30 +451,90 @@ func configDebugString(c *Config, filterPrivate bool) string {
 	return strings.Join(bufs, "\n")
 }
 
-func filterDebugObject(c interface{}) {
+// RegisterSensitiveConfigKeys adds setting names whose values must be
+// redacted before a configuration is logged or dumped. Names are matched
+// case-insensitively against the last segment of a setting path.
+func RegisterSensitiveConfigKeys(keys ...string) {
+	sensitiveConfigKeys.Lock()
+	defer sensitiveConfigKeys.Unlock()
+	for _, k := range keys {
+		sensitiveConfigKeys.keys.Add(strings.ToLower(k))
+		sensitiveConfigKeys.secrets.Add(strings.ToLower(k))
+	}
+}
+
+func isSensitiveConfigKey(key string) bool {
+	sensitiveConfigKeys.RLock()
+	defer sensitiveConfigKeys.RUnlock()
+	return sensitiveConfigKeys.keys.Has(strings.ToLower(key))
+}
+
+func isSecretConfigKey(parent, key string) bool {
+	sensitiveConfigKeys.RLock()
+	defer sensitiveConfigKeys.RUnlock()
+	key = strings.ToLower(key)
+	return sensitiveConfigKeys.secrets.Has(key) || secretConfigPaths.Has(strings.ToLower(parent)+"."+key)
+}
+
+// RedactConfig masks the values of sensitive settings in an unpacked
+// configuration object in place. Nested objects and arrays are traversed, so
+// secrets are masked wherever they appear in the tree.
+func RedactConfig(c interface{}) {
 	switch cfg := c.(type) {
 	case map[string]interface{}:
 		for k, v := range cfg {
-			if debugBlacklist.Has(k) {
-				if arr, ok := v.([]interface{}); ok {
-					for i := range arr {
-						arr[i] = "xxxxx"
-					}
-				} else {
-					cfg[k] = "xxxxx"
-				}
+			if isSensitiveConfigKey(k) {
+				redactConfigSetting(cfg, k)
 			} else {
-				filterDebugObject(v)
+				RedactConfig(v)
 			}
 		}
 
 	case []interface{}:
 		for _, elem := range cfg {
-			filterDebugObject(elem)
+			RedactConfig(elem)
 		}
 	}
 }
 
+// RedactSecrets masks the values of secrets, like passwords, tokens and TLS
+// private keys, in an unpacked configuration object in place. Unlike
+// RedactConfig, other settings like the hosts are kept, so the configuration
+// can be exported and used again.
+func RedactSecrets(c interface{}) {
+	redactSecrets("", c)
+}
+
+func redactSecrets(parent string, c interface{}) {
+	switch cfg := c.(type) {
+	case map[string]interface{}:
+		for k, v := range cfg {
+			if isSecretConfigKey(parent, k) {
+				redactConfigSetting(cfg, k)
+			} else {
+				redactSecrets(k, v)
+			}
+		}
+
+	case []interface{}:
+		for _, elem := range cfg {
+			redactSecrets(parent, elem)
+		}
+	}
+}
+
+// redactConfigSetting masks the value of a setting, or all elements if the
+// value is an array.
+func redactConfigSetting(cfg map[string]interface{}, k string) {
+	if arr, ok := cfg[k].([]interface{}); ok {
+		for i := range arr {
+			arr[i] = redactedValue
+		}
+	} else {
+		cfg[k] = redactedValue
+	}
+}
+
 // ownerHasExclusiveWritePerms asserts that the current user or root is the
 // owner of the config file and that the config file is (at most) writable by
 // the owner or root (e.g. group and other cannot have write access).
@@ -0,0 +1,148 @@
+package common
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Types of the changes reported by DiffConfig.
+const (
+	ConfigAdded   = "added"
+	ConfigRemoved = "removed"
+	ConfigChanged = "changed"
+)
+
+// ConfigChange is a setting added, removed or changed between two
+// configurations.
+type ConfigChange struct {
+	// Path is the dotted path of the setting. Array elements are addressed by
+	// their index, like `filebeat.inputs.0.paths`.
+	Path string `json:"path"`
+
+	// Type is one of ConfigAdded, ConfigRemoved or ConfigChanged.
+	Type string `json:"type"`
+
+	// Old and New are the values of the setting in the old and the new
+	// configuration. The values of sensitive settings are redacted.
+	Old interface{} `json:"old,omitempty"`
+	New interface{} `json:"new,omitempty"`
+}
+
+// DiffConfig returns the settings added, removed or changed from the old to
+// the new configuration, sorted by path. Objects and arrays are compared
+// setting by setting. The values are compared before they are redacted, so
+// changed secrets are reported, but never their values.
+func DiffConfig(old, new *Config) ([]ConfigChange, error) {
+	var oldValues, newValues map[string]interface{}
+	if err := old.Unpack(&oldValues); err != nil {
+		return nil, err
+	}
+	if err := new.Unpack(&newValues); err != nil {
+		return nil, err
+	}
+
+	var changes []ConfigChange
+	diffConfigValues("", false, oldValues, newValues, &changes)
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+func diffConfigValues(path string, sensitive bool, old, new interface{}, changes *[]ConfigChange) {
+	switch oldValue := old.(type) {
+	case map[string]interface{}:
+		if newValue, ok := new.(map[string]interface{}); ok {
+			for k, v := range oldValue {
+				diffConfigValues(joinConfigPath(path, k), sensitive || isSensitiveConfigKey(k), v, newValue[k], changes)
+			}
+			for k, v := range newValue {
+				if _, exists := oldValue[k]; !exists {
+					diffConfigValues(joinConfigPath(path, k), sensitive || isSensitiveConfigKey(k), nil, v, changes)
+				}
+			}
+			return
+		}
+
+	case []interface{}:
+		if newValue, ok := new.([]interface{}); ok {
+			for i := 0; i < len(oldValue) || i < len(newValue); i++ {
+				var o, n interface{}
+				if i < len(oldValue) {
+					o = oldValue[i]
+				}
+				if i < len(newValue) {
+					n = newValue[i]
+				}
+				diffConfigValues(joinConfigPath(path, strconv.Itoa(i)), sensitive, o, n, changes)
+			}
+			return
+		}
+	}
+
+	if reflect.DeepEqual(old, new) {
+		return
+	}
+
+	change := ConfigChange{
+		Path: path,
+		Old:  redactConfigValue(old, sensitive),
+		New:  redactConfigValue(new, sensitive),
+	}
+	switch {
+	case old == nil:
+		change.Type = ConfigAdded
+	case new == nil:
+		change.Type = ConfigRemoved
+	default:
+		change.Type = ConfigChanged
+	}
+	*changes = append(*changes, change)
+}
+
+// redactConfigValue returns the value with its secrets redacted. The value is
+// redacted entirely if it is the value of a sensitive setting.
+func redactConfigValue(v interface{}, sensitive bool) interface{} {
+	if v == nil {
+		return nil
+	}
+	if sensitive {
+		return redactedValue
+	}
+
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		// Redact a copy, the values are shared with the compared
+		// configurations.
+		c := copyConfigValue(v)
+		RedactConfig(c)
+		return c
+	}
+	return v
+}
+
+func copyConfigValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(value))
+		for k, elem := range value {
+			c[k] = copyConfigValue(elem)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(value))
+		for i, elem := range value {
+			c[i] = copyConfigValue(elem)
+		}
+		return c
+	}
+	return v
+}
+
+func joinConfigPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
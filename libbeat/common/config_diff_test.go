@@ -0,0 +1,92 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func diffTestConfigs(t *testing.T, old, new map[string]interface{}) []ConfigChange {
+	oldConfig, err := NewConfigFrom(old)
+	require.NoError(t, err)
+	newConfig, err := NewConfigFrom(new)
+	require.NoError(t, err)
+
+	changes, err := DiffConfig(oldConfig, newConfig)
+	require.NoError(t, err)
+	return changes
+}
+
+func TestDiffConfig(t *testing.T) {
+	old := map[string]interface{}{
+		"name": "beat",
+		"output.elasticsearch": map[string]interface{}{
+			"hosts":    []string{"localhost:9200", "other:9200"},
+			"username": "beat",
+		},
+		"fields":        map[string]interface{}{"env": []string{"staging", "eu"}},
+		"logging.level": "info",
+		"queue.mem":     map[string]interface{}{"events": 4096},
+	}
+	new := map[string]interface{}{
+		"name": "beat",
+		"output.elasticsearch": map[string]interface{}{
+			"hosts":    []string{"localhost:9200"},
+			"username": "beat",
+			"worker":   2,
+		},
+		"fields":        map[string]interface{}{"env": []string{"production", "eu"}},
+		"logging.level": "debug",
+		"tags":          []string{"web"},
+	}
+
+	assert.Equal(t, []ConfigChange{
+		{Path: "fields.env.0", Type: ConfigChanged, Old: "staging", New: "production"},
+		{Path: "logging.level", Type: ConfigChanged, Old: "info", New: "debug"},
+		{Path: "output.elasticsearch.hosts.1", Type: ConfigRemoved, Old: redactedValue},
+		{Path: "output.elasticsearch.worker", Type: ConfigAdded, New: uint64(2)},
+		{Path: "queue", Type: ConfigRemoved, Old: map[string]interface{}{
+			"mem": map[string]interface{}{"events": uint64(4096)},
+		}},
+		{Path: "tags", Type: ConfigAdded, New: []interface{}{"web"}},
+	}, diffTestConfigs(t, old, new))
+}
+
+func TestDiffConfigUnchanged(t *testing.T) {
+	config := map[string]interface{}{
+		"output.elasticsearch.hosts": []string{"localhost:9200"},
+		"processors": []map[string]interface{}{
+			{"add_fields": map[string]interface{}{"fields": map[string]interface{}{"a": 1}}},
+		},
+	}
+	assert.Empty(t, diffTestConfigs(t, config, config))
+}
+
+func TestDiffConfigRedactsSecrets(t *testing.T) {
+	old := map[string]interface{}{
+		"output.elasticsearch": map[string]interface{}{
+			"password": "secret1",
+			"ssl":      map[string]interface{}{"key_passphrase": "pass1"},
+		},
+	}
+	new := map[string]interface{}{
+		"output.elasticsearch": map[string]interface{}{
+			"password": "secret2",
+			"ssl":      map[string]interface{}{"key_passphrase": "pass1"},
+		},
+		"output.kafka": map[string]interface{}{
+			"hosts":    []string{"kafka:9092"},
+			"password": "secret3",
+		},
+	}
+
+	// Changed secrets are reported, without their values.
+	assert.Equal(t, []ConfigChange{
+		{Path: "output.elasticsearch.password", Type: ConfigChanged, Old: redactedValue, New: redactedValue},
+		{Path: "output.kafka", Type: ConfigAdded, New: map[string]interface{}{
+			"hosts":    []interface{}{redactedValue},
+			"password": redactedValue,
+		}},
+	}, diffTestConfigs(t, old, new))
+}
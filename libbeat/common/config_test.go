@@ -114,6 +114,143 @@ func TestConfigPrintDebug(t *testing.T) {
 	}
 }
 
+func TestRedactConfig(t *testing.T) {
+	cfg, err := NewConfigFrom(map[string]interface{}{
+		"name": "test",
+		"output.elasticsearch": map[string]interface{}{
+			"username": "beats",
+			"password": "secret",
+			"api_key":  "id:key",
+			"ssl": map[string]interface{}{
+				"certificate":    "/etc/pki/client.crt",
+				"key":            "/etc/pki/client.key",
+				"key_passphrase": "secret",
+			},
+		},
+		"modules": []interface{}{
+			map[string]interface{}{
+				"module": "kubernetes",
+				"token":  "secret",
+				"period": "10s",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var content map[string]interface{}
+	if err := cfg.Unpack(&content); err != nil {
+		t.Fatal(err)
+	}
+	RedactConfig(content)
+
+	assert.Equal(t, map[string]interface{}{
+		"name": "test",
+		"output": map[string]interface{}{
+			"elasticsearch": map[string]interface{}{
+				"username": "beats",
+				"password": "xxxxx",
+				"api_key":  "xxxxx",
+				"ssl": map[string]interface{}{
+					"certificate":    "/etc/pki/client.crt",
+					"key":            "xxxxx",
+					"key_passphrase": "xxxxx",
+				},
+			},
+		},
+		"modules": []interface{}{
+			map[string]interface{}{
+				"module": "kubernetes",
+				"token":  "xxxxx",
+				"period": "10s",
+			},
+		},
+	}, content)
+}
+
+func TestRegisterSensitiveConfigKeys(t *testing.T) {
+	content := map[string]interface{}{
+		"custom_secret": "secret",
+		"nested": map[string]interface{}{
+			"Custom_Secret": []interface{}{"a", "b"},
+			"other":         "value",
+		},
+	}
+
+	RegisterSensitiveConfigKeys("custom_secret")
+	defer func() {
+		sensitiveConfigKeys.Lock()
+		sensitiveConfigKeys.keys.Del("custom_secret")
+		sensitiveConfigKeys.secrets.Del("custom_secret")
+		sensitiveConfigKeys.Unlock()
+	}()
+
+	RedactConfig(content)
+	assert.Equal(t, map[string]interface{}{
+		"custom_secret": "xxxxx",
+		"nested": map[string]interface{}{
+			"Custom_Secret": []interface{}{"xxxxx", "xxxxx"},
+			"other":         "value",
+		},
+	}, content)
+}
+
+func TestRedactSecrets(t *testing.T) {
+	content := map[string]interface{}{
+		"output": map[string]interface{}{
+			"kafka": map[string]interface{}{
+				"hosts":    []interface{}{"kafka:9092"},
+				"key":      "%{[host.name]}",
+				"password": "secret",
+				"ssl": map[string]interface{}{
+					"certificate": "/etc/pki/client.crt",
+					"key":         "/etc/pki/client.key",
+				},
+			},
+		},
+		"processors": []interface{}{
+			map[string]interface{}{
+				"stitch": map[string]interface{}{"key": "trace.id"},
+			},
+		},
+		"modules": []interface{}{
+			map[string]interface{}{
+				"module": "kubernetes",
+				"hosts":  []interface{}{"https://kubernetes:10250"},
+				"token":  "secret",
+			},
+		},
+	}
+
+	RedactSecrets(content)
+	assert.Equal(t, map[string]interface{}{
+		"output": map[string]interface{}{
+			"kafka": map[string]interface{}{
+				"hosts":    []interface{}{"kafka:9092"},
+				"key":      "%{[host.name]}",
+				"password": "xxxxx",
+				"ssl": map[string]interface{}{
+					"certificate": "/etc/pki/client.crt",
+					"key":         "xxxxx",
+				},
+			},
+		},
+		"processors": []interface{}{
+			map[string]interface{}{
+				"stitch": map[string]interface{}{"key": "trace.id"},
+			},
+		},
+		"modules": []interface{}{
+			map[string]interface{}{
+				"module": "kubernetes",
+				"hosts":  []interface{}{"https://kubernetes:10250"},
+				"token":  "xxxxx",
+			},
+		},
+	}, content)
+}
+
 func TestConfigFilePermissions(t *testing.T) {
 	if !IsStrictPerms() {
 		t.Skip("Skipping test because strict.perms is disabled")
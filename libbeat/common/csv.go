@@ -3,6 +3,8 @@ package common
 import (
 	"bytes"
 	"encoding/csv"
+	"errors"
+	"io"
 	"strings"
 )
 
@@ -32,3 +34,25 @@ func DumpInCSVFormat(fields []string, rows [][]string) string {
 	csv := buf.String()
 	return csv
 }
+
+// ParseCSVLine parses a single CSV record, with values separated by separator
+// and quoted according to RFC 4180. Quoted values can contain the separator,
+// escaped quotes and line breaks. An empty line is a record of one empty value.
+func ParseCSVLine(line string, separator rune) ([]string, error) {
+	reader := csv.NewReader(strings.NewReader(line))
+	reader.Comma = separator
+	reader.FieldsPerRecord = -1
+
+	record, err := reader.Read()
+	if err == io.EOF {
+		return []string{""}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := reader.Read(); err != io.EOF {
+		return nil, errors.New("more than one CSV record found")
+	}
+	return record, nil
+}
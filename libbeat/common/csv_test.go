@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_CSVDump(t *testing.T) {
@@ -46,3 +47,34 @@ func Test_CSVDump(t *testing.T) {
 		assert.Equal(t, test.Output, DumpInCSVFormat(test.Fields, test.Rows))
 	}
 }
+
+func TestParseCSVLine(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		separator rune
+		expected  []string
+	}{
+		{"plain", "a,b,c", ',', []string{"a", "b", "c"}},
+		{"empty values", "a,,c,", ',', []string{"a", "", "c", ""}},
+		{"empty line", "", ',', []string{""}},
+		{"quoted separator", `"a,b",c`, ',', []string{"a,b", "c"}},
+		{"escaped quotes", `"say ""hi""",b`, ',', []string{`say "hi"`, "b"}},
+		{"quoted line break", "\"a\nb\",c", ',', []string{"a\nb", "c"}},
+		{"custom separator", `a;"b;c";d`, ';', []string{"a", "b;c", "d"}},
+		{"tab separator", "a\tb", '\t', []string{"a", "b"}},
+	}
+
+	for _, test := range tests {
+		record, err := ParseCSVLine(test.line, test.separator)
+		require.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, record, test.name)
+	}
+}
+
+func TestParseCSVLineErrors(t *testing.T) {
+	for _, line := range []string{`"a,b`, `a"b",c`, "a,b\nc,d"} {
+		_, err := ParseCSVLine(line, ',')
+		assert.Error(t, err, line)
+	}
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"bytes"
 	"encoding"
 	"encoding/json"
 	"fmt"
@@ -158,6 +159,8 @@ func normalizeValue(value interface{}, keys ...string) (interface{}, []error) {
 	case complex64, complex128:
 	case []complex64, []complex128:
 	case Time, []Time:
+	case json.Number:
+		return normalizeJSONNumber(value.(json.Number)), nil
 	case MapStr:
 		return normalizeMap(value.(MapStr), keys...)
 	case []MapStr:
@@ -193,7 +196,7 @@ func normalizeValue(value interface{}, keys ...string) (interface{}, []error) {
 			if err != nil {
 				return m, []error{errors.Wrapf(err, "key=%v: error converting %T to MapStr", joinKeys(keys...), value)}
 			}
-			return m, nil
+			return normalizeMap(m, keys...)
 		default:
 			// Drop Uintptr, UnsafePointer, Chan, Func, Interface, and any other
 			// types not specifically handled above.
@@ -205,14 +208,18 @@ func normalizeValue(value interface{}, keys ...string) (interface{}, []error) {
 }
 
 // marshalUnmarshal converts an interface to a MapStr by marshalling to JSON
-// then unmarshalling the JSON object into a MapStr.
+// then unmarshalling the JSON object into a MapStr. Numbers are decoded as
+// json.Number, such that integers do not lose precision.
 func marshalUnmarshal(in interface{}, out interface{}) error {
 	// Decode and encode as JSON to normalized the types.
 	marshaled, err := json.Marshal(in)
 	if err != nil {
 		return errors.Wrap(err, "error marshalling to JSON")
 	}
-	err = json.Unmarshal(marshaled, out)
+
+	dec := json.NewDecoder(bytes.NewReader(marshaled))
+	dec.UseNumber()
+	err = dec.Decode(out)
 	if err != nil {
 		return errors.Wrap(err, "error unmarshalling from JSON")
 	}
@@ -220,6 +227,22 @@ func marshalUnmarshal(in interface{}, out interface{}) error {
 	return nil
 }
 
+// normalizeJSONNumber converts a json.Number to int64, uint64 or Float, in
+// this order of preference. Numbers not representable by any of these types
+// are returned as string.
+func normalizeJSONNumber(n json.Number) interface{} {
+	if i64, err := n.Int64(); err == nil {
+		return i64
+	}
+	if u64, err := strconv.ParseUint(n.String(), 10, 64); err == nil {
+		return u64
+	}
+	if f64, err := n.Float64(); err == nil {
+		return Float(f64)
+	}
+	return n.String()
+}
+
 // followPointer accepts an interface{} and if the interface is a pointer then
 // the value that v points to is returned. If v is not a pointer then v is
 // returned.
@@ -111,7 +111,7 @@ func TestConvertNestedMapStr(t *testing.T) {
 		},
 		{
 			MapStr{"k": map[string]int{"hits": 1}},
-			MapStr{"k": MapStr{"hits": float64(1)}},
+			MapStr{"k": MapStr{"hits": int64(1)}},
 		},
 	}
 
@@ -147,7 +147,7 @@ func TestConvertNestedStruct(t *testing.T) {
 				"key": MapStr{
 					"key1": MapStr{
 						"A": "hello",
-						"B": float64(5),
+						"B": int64(5),
 					},
 				},
 			},
@@ -165,7 +165,7 @@ func TestConvertNestedStruct(t *testing.T) {
 				"key": []interface{}{
 					MapStr{
 						"A": "hello",
-						"B": float64(5),
+						"B": int64(5),
 					},
 				},
 			},
@@ -203,9 +203,17 @@ func TestNormalizeValue(t *testing.T) {
 
 		// Other map types are converted using marshalUnmarshal which will lose
 		// type information for arrays which become []interface{} and numbers
-		// which all become float64.
+		// which become int64, uint64 or Float.
 		{map[string]string{"foo": "bar"}, MapStr{"foo": "bar"}},
 		{map[string][]string{"list": {"foo", "bar"}}, MapStr{"list": []interface{}{"foo", "bar"}}},
+		{map[string]int64{"id": 1152921504606846977}, MapStr{"id": int64(1152921504606846977)}},
+		{map[string]uint64{"id": 18446744073709551615}, MapStr{"id": uint64(18446744073709551615)}},
+		{map[string]float64{"f": 1.5}, MapStr{"f": Float(1.5)}},
+
+		// JSON numbers are converted to int64 if possible.
+		{json.Number("1152921504606846977"), int64(1152921504606846977)},
+		{json.Number("18446744073709551615"), uint64(18446744073709551615)},
+		{json.Number("1.5"), Float(1.5)},
 
 		{[]string{"foo", "bar"}, []string{"foo", "bar"}},
 		{[]bool{true, false}, []bool{true, false}},
@@ -236,6 +244,29 @@ func TestNormalizeValue(t *testing.T) {
 	}
 }
 
+func TestConvertLargeIntegers(t *testing.T) {
+	type snowflake struct {
+		ID int64 `json:"id"`
+	}
+
+	// 2^60 + 1 can not be represented as float64.
+	const id int64 = 1152921504606846977
+
+	out := ConvertToGenericEvent(MapStr{
+		"struct": snowflake{ID: id},
+		"number": json.Number("1152921504606846977"),
+	})
+
+	assert.Equal(t, MapStr{"id": id}, out["struct"])
+	assert.Equal(t, id, out["number"])
+
+	b, err := json.Marshal(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `{"number":1152921504606846977,"struct":{"id":1152921504606846977}}`, string(b))
+}
+
 func TestNormalizeMapError(t *testing.T) {
 	badInputs := []MapStr{
 		{"func": func() {}},
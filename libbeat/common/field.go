@@ -2,8 +2,11 @@ package common
 
 import (
 	"fmt"
+	"io/ioutil"
+	"regexp"
 	"strings"
 
+	"github.com/elastic/go-ucfg"
 	"github.com/elastic/go-ucfg/yaml"
 )
 
@@ -34,21 +37,33 @@ type Field struct {
 	DocValues      *bool       `config:"doc_values"`
 	CopyTo         string      `config:"copy_to"`
 
+	// DefaultField overrides whether the field is searched by query-string
+	// queries without field, by default text and keyword fields are.
+	DefaultField *bool `config:"default_field"`
+
+	// AliasPath is the full path of the field an alias field points to.
+	AliasPath string `config:"path"`
+
 	// Kibana specific
-	Analyzed     *bool  `config:"analyzed"`
-	Count        int    `config:"count"`
-	Searchable   *bool  `config:"searchable"`
-	Aggregatable *bool  `config:"aggregatable"`
-	Script       string `config:"script"`
+	Analyzed     *bool             `config:"analyzed"`
+	Count        int               `config:"count"`
+	Popularity   int               `config:"popularity"` // alias of count, used if count is not set
+	Searchable   *bool             `config:"searchable"`
+	Aggregatable *bool             `config:"aggregatable"`
+	Script       string            `config:"script"`
+	Label        string            `config:"label"`   // short title shown instead of the field name
+	Labels       map[string]string `config:"labels"`  // localized labels, by locale
+	Runtime      bool              `config:"runtime"` // computed by its script when queried, not mapped
 	// Kibana params
-	Pattern         string              `config:"pattern"`
-	InputFormat     string              `config:"input_format"`
-	OutputFormat    string              `config:"output_format"`
-	OutputPrecision *int                `config:"output_precision"`
-	LabelTemplate   string              `config:"label_template"`
-	UrlTemplate     []VersionizedString `config:"url_template"`
-
-	Path string
+	Pattern         string                 `config:"pattern"`
+	InputFormat     string                 `config:"input_format"`
+	OutputFormat    string                 `config:"output_format"`
+	OutputPrecision *int                   `config:"output_precision"`
+	LabelTemplate   string                 `config:"label_template"`
+	UrlTemplate     []VersionizedString    `config:"url_template"`
+	Params          map[string]interface{} `config:"params"` // passed verbatim, like the ranges of the color format
+
+	Path string `config:",ignore"`
 }
 
 type VersionizedString struct {
@@ -72,13 +87,19 @@ func (d *DynamicType) Unpack(s string) error {
 	return nil
 }
 
+// LoadFieldsYaml loads the fields of a fields.yml file. Errors parsing the
+// file report its path and the line of the syntax error.
 func LoadFieldsYaml(path string) (Fields, error) {
 	keys := []Field{}
 
-	cfg, err := yaml.NewConfigWithFile(path)
+	input, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
+	cfg, err := yaml.NewConfig(input, ucfg.MetaData(ucfg.Meta{Source: path}))
+	if err != nil {
+		return nil, yamlError(path, err)
+	}
 	cfg.Unpack(&keys)
 
 	fields := Fields{}
@@ -89,6 +110,18 @@ func LoadFieldsYaml(path string) (Fields, error) {
 	return fields, nil
 }
 
+// yamlLineError matches the syntax errors of the YAML parser, reporting the
+// line of the error.
+var yamlLineError = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// yamlError adds the path and line to a YAML parse error.
+func yamlError(path string, err error) error {
+	if m := yamlLineError.FindStringSubmatch(err.Error()); m != nil {
+		return fmt.Errorf("invalid YAML in %s, line %s: %s", path, m[1], m[2])
+	}
+	return fmt.Errorf("invalid YAML in %s: %v", path, err)
+}
+
 // HasKey checks if inside fields the given key exists
 // The key can be in the form of a.b.c and it will check if the nested field exist
 // In case the key is `a` and there is a value `a.b` false is return as it only
@@ -115,3 +115,18 @@ dynamic: "strict"`),
 		}
 	}
 }
+
+func TestAliasYaml(t *testing.T) {
+	cfg, err := yaml.NewConfig([]byte(`
+name: host
+type: alias
+path: beat.hostname`))
+	assert.NoError(t, err)
+
+	field := Field{}
+	err = cfg.Unpack(&field)
+	assert.NoError(t, err)
+	assert.Equal(t, "alias", field.Type)
+	assert.Equal(t, "beat.hostname", field.AliasPath)
+	assert.Equal(t, "", field.Path)
+}
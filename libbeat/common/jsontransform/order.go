@@ -0,0 +1,90 @@
+package jsontransform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// FieldOrderKey is the key in the event metadata holding the order of the
+// fields of the source document.
+const FieldOrderKey = "field_order"
+
+// FieldOrder returns the paths of all object keys of a JSON document in the
+// order they appear in the document. Keys of nested objects are returned as
+// dotted paths following the path of the object, e.g. `a`, `a.x`, `a.y`.
+// Keys of objects in arrays follow the index of the object, e.g. `a.0.x`.
+func FieldOrder(text []byte) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(text))
+	dec.UseNumber()
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok != json.Delim('{') {
+		return nil, fmt.Errorf("expected JSON object, found %v", tok)
+	}
+
+	var order []string
+	if err := objectOrder(dec, "", &order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// objectOrder adds the keys of the object to order, the opening delimiter of
+// the object being consumed already.
+func objectOrder(dec *json.Decoder, path string, order *[]string) error {
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("expected object key, found %v", tok)
+		}
+		if path != "" {
+			key = path + "." + key
+		}
+		*order = append(*order, key)
+
+		if err := valueOrder(dec, key, order); err != nil {
+			return err
+		}
+	}
+
+	// closing delimiter
+	_, err := dec.Token()
+	return err
+}
+
+// arrayOrder adds the keys of the objects in the array to order, the opening
+// delimiter of the array being consumed already.
+func arrayOrder(dec *json.Decoder, path string, order *[]string) error {
+	for i := 0; dec.More(); i++ {
+		if err := valueOrder(dec, path+"."+strconv.Itoa(i), order); err != nil {
+			return err
+		}
+	}
+
+	// closing delimiter
+	_, err := dec.Token()
+	return err
+}
+
+func valueOrder(dec *json.Decoder, path string, order *[]string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch tok {
+	case json.Delim('{'):
+		return objectOrder(dec, path, order)
+	case json.Delim('['):
+		return arrayOrder(dec, path, order)
+	}
+	return nil
+}
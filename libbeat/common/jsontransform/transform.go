@@ -2,13 +2,14 @@ package jsontransform
 
 import (
 	"encoding/json"
+	"strconv"
 
 	"github.com/elastic/beats/libbeat/common"
 )
 
 // TransformNumbers walks a json decoded tree an replaces json.Number
-// with int64, float64, or string, in this order of preference (i.e. if it
-// parses as an int, use int. if it parses as a float, use float. etc).
+// with int64, uint64, float64, or string, in this order of preference (i.e. if
+// it parses as an int, use int. if it parses as a float, use float. etc).
 func TransformNumbers(dict common.MapStr) {
 	for k, v := range dict {
 		switch vv := v.(type) {
@@ -16,8 +17,10 @@ func TransformNumbers(dict common.MapStr) {
 			dict[k] = transformNumber(vv)
 		case map[string]interface{}:
 			TransformNumbers(vv)
+		case common.MapStr:
+			TransformNumbers(vv)
 		case []interface{}:
-			transformNumbersArray(vv)
+			TransformNumbersArray(vv)
 		}
 	}
 }
@@ -27,6 +30,10 @@ func transformNumber(value json.Number) interface{} {
 	if err == nil {
 		return i64
 	}
+	u64, err := strconv.ParseUint(value.String(), 10, 64)
+	if err == nil {
+		return u64
+	}
 	f64, err := value.Float64()
 	if err == nil {
 		return f64
@@ -34,15 +41,19 @@ func transformNumber(value json.Number) interface{} {
 	return value.String()
 }
 
-func transformNumbersArray(arr []interface{}) {
+// TransformNumbersArray replaces json.Number values in a json decoded array
+// the same way TransformNumbers does for objects.
+func TransformNumbersArray(arr []interface{}) {
 	for i, v := range arr {
 		switch vv := v.(type) {
 		case json.Number:
 			arr[i] = transformNumber(vv)
 		case map[string]interface{}:
 			TransformNumbers(vv)
+		case common.MapStr:
+			TransformNumbers(vv)
 		case []interface{}:
-			transformNumbersArray(vv)
+			TransformNumbersArray(vv)
 		}
 	}
 }
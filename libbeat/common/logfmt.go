@@ -0,0 +1,99 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// LogfmtPair is a key and value of a logfmt line. Bare is set for keys
+// without value, like `debug` in `level=info debug`.
+type LogfmtPair struct {
+	Key   string
+	Value string
+	Bare  bool
+}
+
+// ParseLogfmt parses a logfmt line, a sequence of space separated `key=value`
+// pairs. Values containing spaces, `=` or quotes are double quoted, with
+// quotes and backslashes escaped by a backslash. Quoted values are unescaped
+// like Go string literals. Keys without `=` are bare keys, a key followed by
+// `=` and no value has an empty value. The pairs are returned in the order of
+// the line, including duplicate keys.
+func ParseLogfmt(line string) ([]LogfmtPair, error) {
+	var pairs []LogfmtPair
+	for pos := skipLogfmtSpaces(line, 0); pos < len(line); pos = skipLogfmtSpaces(line, pos) {
+		start := pos
+		for pos < len(line) && isLogfmtChar(line[pos]) {
+			pos++
+		}
+		if pos == start {
+			return nil, fmt.Errorf("unexpected '%c' at position %d, expected a key", line[pos], pos)
+		}
+		pair := LogfmtPair{Key: line[start:pos]}
+
+		if pos == len(line) || isLogfmtSpace(line[pos]) {
+			pair.Bare = true
+			pairs = append(pairs, pair)
+			continue
+		}
+		if line[pos] != '=' {
+			return nil, fmt.Errorf("unexpected '%c' at position %d in key '%s'", line[pos], pos, pair.Key)
+		}
+		pos++
+
+		switch {
+		case pos < len(line) && line[pos] == '"':
+			end, err := endOfLogfmtQuote(line, pos)
+			if err != nil {
+				return nil, err
+			}
+			value, err := strconv.Unquote(line[pos:end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted value of key '%s': %s", pair.Key, err)
+			}
+			pair.Value = value
+			pos = end
+		default:
+			start = pos
+			for pos < len(line) && isLogfmtChar(line[pos]) {
+				pos++
+			}
+			pair.Value = line[start:pos]
+		}
+
+		if pos < len(line) && !isLogfmtSpace(line[pos]) {
+			return nil, fmt.Errorf("unexpected '%c' at position %d after the value of key '%s'", line[pos], pos, pair.Key)
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, nil
+}
+
+// endOfLogfmtQuote returns the position after the closing quote of the quoted
+// value starting at pos.
+func endOfLogfmtQuote(line string, pos int) (int, error) {
+	for i := pos + 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated quoted value at position %d", pos)
+}
+
+func skipLogfmtSpaces(line string, pos int) int {
+	for pos < len(line) && isLogfmtSpace(line[pos]) {
+		pos++
+	}
+	return pos
+}
+
+func isLogfmtSpace(c byte) bool {
+	return c <= ' '
+}
+
+func isLogfmtChar(c byte) bool {
+	return c > ' ' && c != '=' && c != '"'
+}
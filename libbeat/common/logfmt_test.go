@@ -0,0 +1,99 @@
+// +build !integration
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLogfmt(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected []LogfmtPair
+	}{
+		{
+			line:     "",
+			expected: nil,
+		},
+		{
+			line: "level=info msg=started",
+			expected: []LogfmtPair{
+				{Key: "level", Value: "info"},
+				{Key: "msg", Value: "started"},
+			},
+		},
+		{
+			line: `  msg="hello world"	at=12:00:01  `,
+			expected: []LogfmtPair{
+				{Key: "msg", Value: "hello world"},
+				{Key: "at", Value: "12:00:01"},
+			},
+		},
+		{
+			line: `msg="say \"hi\"" path="C:\\temp" lines="a\nb"`,
+			expected: []LogfmtPair{
+				{Key: "msg", Value: `say "hi"`},
+				{Key: "path", Value: `C:\temp`},
+				{Key: "lines", Value: "a\nb"},
+			},
+		},
+		{
+			line: `query="a=b" empty= quoted=""`,
+			expected: []LogfmtPair{
+				{Key: "query", Value: "a=b"},
+				{Key: "empty", Value: ""},
+				{Key: "quoted", Value: ""},
+			},
+		},
+		{
+			line: "debug level=info verbose",
+			expected: []LogfmtPair{
+				{Key: "debug", Bare: true},
+				{Key: "level", Value: "info"},
+				{Key: "verbose", Bare: true},
+			},
+		},
+		{
+			line: "a=1 a=2 url.path=/",
+			expected: []LogfmtPair{
+				{Key: "a", Value: "1"},
+				{Key: "a", Value: "2"},
+				{Key: "url.path", Value: "/"},
+			},
+		},
+		{
+			line: `unicode="caf\u00e9" raw=café`,
+			expected: []LogfmtPair{
+				{Key: "unicode", Value: "café"},
+				{Key: "raw", Value: "café"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		pairs, err := ParseLogfmt(test.line)
+		if assert.NoError(t, err, test.line) {
+			assert.Equal(t, test.expected, pairs, test.line)
+		}
+	}
+}
+
+func TestParseLogfmtMalformed(t *testing.T) {
+	for _, line := range []string{
+		`=value`,
+		`a=1 =2`,
+		`msg="unterminated`,
+		`msg="escaped quote at end\"`,
+		`msg="bad escape \q"`,
+		`msg="closed"trailing`,
+		`a=b=c`,
+		`a=b"c`,
+		`ke"y=value`,
+		`"key"=value`,
+	} {
+		_, err := ParseLogfmt(line)
+		assert.Error(t, err, line)
+	}
+}
@@ -0,0 +1,57 @@
+package common
+
+import "sync"
+
+// MapStrPool reuses MapStr values for reducing the allocations and GC pressure
+// of inputs publishing events at high rates.
+//
+// A MapStr returned by Get is owned by the caller until it is passed to Put.
+// If a pooled MapStr is published with an event, the owner must keep it until
+// the event has been acknowledged by the publisher pipeline. The pipeline
+// copies the event fields when normalizing an event, and keeps no reference to
+// the published maps after the ACK.
+//
+// Put releases the map and all MapStr values nested in it. The maps must not be
+// accessed after calling Put, and Put must not be called twice for the same
+// map. Values not owned by the caller, like shared metadata, must not be stored
+// in a pooled MapStr.
+type MapStrPool struct {
+	pool sync.Pool
+}
+
+// NewMapStrPool creates a new pool. New maps are created with a capacity of
+// size entries.
+func NewMapStrPool(size int) *MapStrPool {
+	p := &MapStrPool{}
+	p.pool.New = func() interface{} {
+		return make(MapStr, size)
+	}
+	return p
+}
+
+// Get returns an empty MapStr from the pool, or a new MapStr if the pool is
+// empty.
+func (p *MapStrPool) Get() MapStr {
+	return p.pool.Get().(MapStr)
+}
+
+// Put clears m and all MapStr values nested in m, and returns them to the
+// pool.
+func (p *MapStrPool) Put(m MapStr) {
+	if m == nil {
+		return
+	}
+
+	for k, v := range m {
+		switch nested := v.(type) {
+		case MapStr:
+			p.Put(nested)
+		case []MapStr:
+			for _, elem := range nested {
+				p.Put(elem)
+			}
+		}
+		delete(m, k)
+	}
+	p.pool.Put(m)
+}
@@ -0,0 +1,110 @@
+package common
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapStrPoolPut(t *testing.T) {
+	pool := NewMapStrPool(4)
+
+	nested := pool.Get()
+	nested["b"] = 1
+	list := []MapStr{pool.Get(), pool.Get()}
+	list[0]["c"] = 2
+	shared := map[string]interface{}{"d": 3}
+
+	m := pool.Get()
+	m["a"] = nested
+	m["list"] = list
+	m["shared"] = shared
+	m["value"] = "x"
+
+	pool.Put(m)
+	assert.Empty(t, m)
+	assert.Empty(t, nested)
+	assert.Empty(t, list[0])
+
+	// Values not being a MapStr are not released.
+	assert.Equal(t, map[string]interface{}{"d": 3}, shared)
+
+	pool.Put(nil)
+	assert.Empty(t, pool.Get())
+}
+
+func TestMapStrPoolConcurrentUse(t *testing.T) {
+	pool := NewMapStrPool(4)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				m := pool.Get()
+				if !assert.Empty(t, m) {
+					return
+				}
+
+				nested := pool.Get()
+				nested["owner"] = id
+				m["owner"] = id
+				m["nested"] = nested
+
+				// No other user of the pool modifies the maps while owned.
+				if !assert.Equal(t, MapStr{"owner": id, "nested": MapStr{"owner": id}}, m) {
+					return
+				}
+				pool.Put(m)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestMapStrPoolAllocs(t *testing.T) {
+	pool := NewMapStrPool(4)
+	fill := func(get func() MapStr) MapStr {
+		nested := get()
+		nested["name"] = "bucket"
+		m := get()
+		m["offset"] = 1
+		m["nested"] = nested
+		return m
+	}
+
+	allocated := testing.AllocsPerRun(100, func() {
+		fill(func() MapStr { return make(MapStr, 4) })
+	})
+	pooled := testing.AllocsPerRun(100, func() {
+		pool.Put(fill(pool.Get))
+	})
+	assert.True(t, pooled < allocated, "pooled: %v, allocated: %v", pooled, allocated)
+}
+
+func BenchmarkMapStrAlloc(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		nested := make(MapStr, 4)
+		nested["name"] = "bucket"
+		m := make(MapStr, 4)
+		m["message"] = "line"
+		m["nested"] = nested
+	}
+}
+
+func BenchmarkMapStrPool(b *testing.B) {
+	pool := NewMapStrPool(4)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		nested := pool.Get()
+		nested["name"] = "bucket"
+		m := pool.Get()
+		m["message"] = "line"
+		m["nested"] = nested
+		pool.Put(m)
+	}
+}
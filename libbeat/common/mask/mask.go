@@ -0,0 +1,118 @@
+// Package mask encrypts values with AES-GCM, so masked values can be
+// re-identified by the holders of the key.
+package mask
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// EncryptedPrefix marks the values encrypted by Encrypt, so they can be told
+// apart from plain values downstream.
+const EncryptedPrefix = "enc:v1:"
+
+// ErrInvalidMasked indicates a value not being a masked value, or not being
+// masked with the given key.
+var ErrInvalidMasked = errors.New("value is not masked with the given key")
+
+// Cipher masks and unmasks values with a single key.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// DecodeKey decodes a base64 encoded key. The key must be 16, 24 or 32 bytes
+// long, for masking with AES-128, AES-192 or AES-256.
+func DecodeKey(key string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("key must be base64 encoded: %v", err)
+	}
+
+	switch len(raw) {
+	case 16, 24, 32:
+		return raw, nil
+	default:
+		return nil, fmt.Errorf("key must be 16, 24 or 32 bytes long, got %v bytes", len(raw))
+	}
+}
+
+// NewCipher creates the cipher of the key.
+func NewCipher(key []byte) (*Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Mask encrypts the value with a random nonce. The masked value is the base64
+// encoded nonce followed by the ciphertext, so masking the same value twice
+// yields different masked values.
+func (c *Cipher) Mask(value string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(value), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Unmask decrypts a value masked by Mask.
+func (c *Cipher) Unmask(masked string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(masked)
+	if err != nil || len(raw) < c.aead.NonceSize() {
+		return "", ErrInvalidMasked
+	}
+
+	nonce, ciphertext := raw[:c.aead.NonceSize()], raw[c.aead.NonceSize():]
+	value, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrInvalidMasked
+	}
+	return string(value), nil
+}
+
+// Encrypt encrypts a value of any type, JSON encoded, like Mask. The
+// encrypted value is prefixed by EncryptedPrefix.
+func (c *Cipher) Encrypt(value interface{}) (string, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	masked, err := c.Mask(string(encoded))
+	if err != nil {
+		return "", err
+	}
+	return EncryptedPrefix + masked, nil
+}
+
+// Decrypt decrypts a value encrypted by Encrypt, returning the value decoded
+// from JSON.
+func (c *Cipher) Decrypt(encrypted string) (interface{}, error) {
+	if !strings.HasPrefix(encrypted, EncryptedPrefix) {
+		return nil, ErrInvalidMasked
+	}
+	encoded, err := c.Unmask(strings.TrimPrefix(encrypted, EncryptedPrefix))
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(encoded), &value); err != nil {
+		return nil, ErrInvalidMasked
+	}
+	return value, nil
+}
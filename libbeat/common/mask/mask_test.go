@@ -0,0 +1,126 @@
+// +build !integration
+
+package mask
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+
+func newTestCipher(t *testing.T, encoded string) *Cipher {
+	key, err := DecodeKey(encoded)
+	require.NoError(t, err)
+	c, err := NewCipher(key)
+	require.NoError(t, err)
+	return c
+}
+
+func TestMaskRoundTrip(t *testing.T) {
+	c := newTestCipher(t, testKey)
+
+	masked, err := c.Mask("alice@example.com")
+	require.NoError(t, err)
+	assert.NotContains(t, masked, "alice")
+
+	value, err := c.Unmask(masked)
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", value)
+}
+
+func TestMaskNoncePerValue(t *testing.T) {
+	c := newTestCipher(t, testKey)
+
+	first, err := c.Mask("alice")
+	require.NoError(t, err)
+	second, err := c.Mask("alice")
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+
+	for _, masked := range []string{first, second} {
+		value, err := c.Unmask(masked)
+		require.NoError(t, err)
+		assert.Equal(t, "alice", value)
+	}
+}
+
+func TestUnmaskInvalid(t *testing.T) {
+	c := newTestCipher(t, testKey)
+	other := newTestCipher(t, base64.StdEncoding.EncodeToString(make([]byte, 16)))
+
+	masked, err := c.Mask("alice")
+	require.NoError(t, err)
+
+	_, err = other.Unmask(masked)
+	assert.Equal(t, ErrInvalidMasked, err)
+
+	raw, _ := base64.StdEncoding.DecodeString(masked)
+	raw[len(raw)-1] ^= 1
+	_, err = c.Unmask(base64.StdEncoding.EncodeToString(raw))
+	assert.Equal(t, ErrInvalidMasked, err)
+
+	_, err = c.Unmask("not base64!")
+	assert.Equal(t, ErrInvalidMasked, err)
+	_, err = c.Unmask("c2hvcnQ=")
+	assert.Equal(t, ErrInvalidMasked, err)
+}
+
+func TestDecodeKey(t *testing.T) {
+	for _, size := range []int{16, 24, 32} {
+		key, err := DecodeKey(base64.StdEncoding.EncodeToString(make([]byte, size)))
+		assert.NoError(t, err)
+		assert.Len(t, key, size)
+	}
+
+	_, err := DecodeKey("not base64!")
+	assert.Error(t, err)
+	_, err = DecodeKey(base64.StdEncoding.EncodeToString(make([]byte, 20)))
+	assert.Error(t, err)
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	c := newTestCipher(t, testKey)
+
+	for _, value := range []interface{}{
+		"alice",
+		float64(42),
+		true,
+		map[string]interface{}{"number": "4111"},
+		[]interface{}{"a", "b"},
+	} {
+		encrypted, err := c.Encrypt(value)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(encrypted, EncryptedPrefix), encrypted)
+
+		decrypted, err := c.Decrypt(encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, value, decrypted)
+	}
+}
+
+func TestDecryptInvalid(t *testing.T) {
+	c := newTestCipher(t, testKey)
+
+	// masked values have no marker
+	masked, err := c.Mask(`"alice"`)
+	require.NoError(t, err)
+	_, err = c.Decrypt(masked)
+	assert.Equal(t, ErrInvalidMasked, err)
+
+	// masked values not holding JSON
+	masked, err = c.Mask("alice")
+	require.NoError(t, err)
+	_, err = c.Decrypt(EncryptedPrefix + masked)
+	assert.Equal(t, ErrInvalidMasked, err)
+
+	other := newTestCipher(t, base64.StdEncoding.EncodeToString(make([]byte, 16)))
+	encrypted, err := c.Encrypt("alice")
+	require.NoError(t, err)
+	_, err = other.Decrypt(encrypted)
+	assert.Equal(t, ErrInvalidMasked, err)
+}
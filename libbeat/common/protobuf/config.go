@@ -0,0 +1,20 @@
+package protobuf
+
+// Config configures the decoding of a stream of protobuf messages.
+type Config struct {
+	// DescriptorSet is the path of the FileDescriptorSet defining the message
+	// type. MessageType is the fully qualified name of the type.
+	DescriptorSet string        `config:"descriptor_set" validate:"required"`
+	MessageType   string        `config:"message_type" validate:"required"`
+	Framing       FramingConfig `config:"framing"`
+}
+
+// NewDecoder loads the descriptor set and creates the decoder of the
+// configured message type.
+func NewDecoder(config Config) (*Decoder, error) {
+	set, err := LoadDescriptorSet(config.DescriptorSet)
+	if err != nil {
+		return nil, err
+	}
+	return set.NewDecoder(config.MessageType)
+}
@@ -0,0 +1,200 @@
+package protobuf
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// maxDepth limits the nesting of decoded messages.
+const maxDepth = 64
+
+// Decoder decodes the messages of a single message type.
+type Decoder struct {
+	message *messageDescriptor
+}
+
+// Decode decodes an encoded message. Fields are named after their names in
+// the message type. Fields that are not set, and unknown fields, are not
+// reported. Enum values are reported by name, maps are reported as objects.
+func (d *Decoder) Decode(data []byte) (common.MapStr, error) {
+	return decodeMessage(d.message, data, 0)
+}
+
+func decodeMessage(msg *messageDescriptor, data []byte, depth int) (common.MapStr, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("message nesting exceeds %v levels", maxDepth)
+	}
+
+	fields := common.MapStr{}
+	r := &wireReader{buf: data}
+	for !r.done() {
+		number, wire, err := r.key()
+		if err != nil {
+			return nil, err
+		}
+
+		field, ok := msg.fields[number]
+		if !ok || field.typ == typeGroup {
+			if err := r.skip(number, wire); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := decodeField(fields, field, r, wire, depth); err != nil {
+			return nil, fmt.Errorf("error decoding field %v of %v: %v", field.name, msg.name, err)
+		}
+	}
+	return fields, nil
+}
+
+func decodeField(fields common.MapStr, field *fieldDescriptor, r *wireReader, wire, depth int) error {
+	// Repeated scalar fields can be packed in a single bytes value.
+	if wire == wireBytes && field.label == labelRepeated && packable(field.typ) {
+		data, err := r.bytes()
+		if err != nil {
+			return err
+		}
+
+		packed := &wireReader{buf: data}
+		for !packed.done() {
+			v, err := decodeValue(field, packed, expectedWire(field.typ), depth)
+			if err != nil {
+				return err
+			}
+			appendValue(fields, field.name, v)
+		}
+		return nil
+	}
+
+	v, err := decodeValue(field, r, wire, depth)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case field.message != nil && field.message.mapEntry:
+		entry := v.(common.MapStr)
+		m, ok := fields[field.name].(common.MapStr)
+		if !ok {
+			m = common.MapStr{}
+			fields[field.name] = m
+		}
+		key, ok := entry["key"]
+		if !ok {
+			key = zeroKey(field.message)
+		}
+		m[fmt.Sprint(key)] = entry["value"]
+	case field.label == labelRepeated:
+		appendValue(fields, field.name, v)
+	default:
+		fields[field.name] = v
+	}
+	return nil
+}
+
+func appendValue(fields common.MapStr, name string, v interface{}) {
+	values, _ := fields[name].([]interface{})
+	fields[name] = append(values, v)
+}
+
+// zeroKey returns the default key of a map entry, used if the key is not set
+// in the entry.
+func zeroKey(entry *messageDescriptor) interface{} {
+	if key, ok := entry.fields[1]; ok {
+		switch key.typ {
+		case typeString:
+			return ""
+		case typeBool:
+			return false
+		}
+	}
+	return 0
+}
+
+func packable(typ uint64) bool {
+	switch typ {
+	case typeString, typeBytes, typeMessage, typeGroup:
+		return false
+	}
+	return true
+}
+
+// expectedWire returns the wire type of the values of a field type.
+func expectedWire(typ uint64) int {
+	switch typ {
+	case typeDouble, typeFixed64, typeSfixed64:
+		return wireFixed64
+	case typeFloat, typeFixed32, typeSfixed32:
+		return wireFixed32
+	case typeString, typeBytes, typeMessage:
+		return wireBytes
+	}
+	return wireVarint
+}
+
+func decodeValue(field *fieldDescriptor, r *wireReader, wire, depth int) (interface{}, error) {
+	if expected := expectedWire(field.typ); wire != expected {
+		return nil, fmt.Errorf("wire type %v does not match the field type, expected wire type %v", wire, expected)
+	}
+
+	switch field.typ {
+	case typeDouble:
+		v, err := r.fixed64()
+		return math.Float64frombits(v), err
+	case typeFloat:
+		v, err := r.fixed32()
+		return math.Float32frombits(v), err
+	case typeFixed64:
+		return r.fixed64()
+	case typeSfixed64:
+		v, err := r.fixed64()
+		return int64(v), err
+	case typeFixed32:
+		return r.fixed32()
+	case typeSfixed32:
+		v, err := r.fixed32()
+		return int32(v), err
+	case typeString:
+		v, err := r.bytes()
+		return string(v), err
+	case typeBytes:
+		v, err := r.bytes()
+		return append([]byte(nil), v...), err
+	case typeMessage:
+		v, err := r.bytes()
+		if err != nil {
+			return nil, err
+		}
+		return decodeMessage(field.message, v, depth+1)
+	}
+
+	v, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	switch field.typ {
+	case typeInt64:
+		return int64(v), nil
+	case typeUint64:
+		return v, nil
+	case typeInt32:
+		return int32(v), nil
+	case typeUint32:
+		return uint32(v), nil
+	case typeSint32:
+		return int32(uint32(v)>>1) ^ -int32(v&1), nil
+	case typeSint64:
+		return int64(v>>1) ^ -int64(v&1), nil
+	case typeBool:
+		return v != 0, nil
+	case typeEnum:
+		if name, ok := field.enum.values[int32(v)]; ok {
+			return name, nil
+		}
+		return int32(v), nil
+	}
+	return nil, fmt.Errorf("unsupported field type %v", field.typ)
+}
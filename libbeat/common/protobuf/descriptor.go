@@ -0,0 +1,372 @@
+// Package protobuf decodes protobuf messages into events, and encodes events
+// into protobuf messages, using the message types of a descriptor set. The
+// descriptor set is a FileDescriptorSet, as written by
+// `protoc --include_imports --descriptor_set_out`.
+package protobuf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Field types and labels, as defined by FieldDescriptorProto.
+const (
+	typeDouble   = 1
+	typeFloat    = 2
+	typeInt64    = 3
+	typeUint64   = 4
+	typeInt32    = 5
+	typeFixed64  = 6
+	typeFixed32  = 7
+	typeBool     = 8
+	typeString   = 9
+	typeGroup    = 10
+	typeMessage  = 11
+	typeBytes    = 12
+	typeUint32   = 13
+	typeEnum     = 14
+	typeSfixed32 = 15
+	typeSfixed64 = 16
+	typeSint32   = 17
+	typeSint64   = 18
+
+	labelRepeated = 3
+)
+
+// DescriptorSet contains the message and enum types of a descriptor set,
+// by their fully qualified names.
+type DescriptorSet struct {
+	messages map[string]*messageDescriptor
+	enums    map[string]*enumDescriptor
+}
+
+type messageDescriptor struct {
+	name     string
+	fields   map[int32]*fieldDescriptor
+	mapEntry bool
+}
+
+type fieldDescriptor struct {
+	name     string
+	number   int32
+	label    uint64
+	typ      uint64
+	typeName string
+
+	// resolved type of message and enum fields
+	message *messageDescriptor
+	enum    *enumDescriptor
+}
+
+type enumDescriptor struct {
+	name   string
+	values map[int32]string
+}
+
+// LoadDescriptorSet reads the descriptor set from a file.
+func LoadDescriptorSet(path string) (*DescriptorSet, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading descriptor set: %v", err)
+	}
+
+	set, err := ParseDescriptorSet(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing descriptor set %v: %v", path, err)
+	}
+	return set, nil
+}
+
+// ParseDescriptorSet parses an encoded FileDescriptorSet. All types referenced
+// by the messages must be defined in the set.
+func ParseDescriptorSet(data []byte) (*DescriptorSet, error) {
+	set := &DescriptorSet{
+		messages: map[string]*messageDescriptor{},
+		enums:    map[string]*enumDescriptor{},
+	}
+
+	err := parseFields(data, func(number int32, r *wireReader) error {
+		if number != 1 { // file
+			return nil
+		}
+		file, err := r.bytes()
+		if err != nil {
+			return err
+		}
+		return set.parseFile(file)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := set.resolve(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// parseFields calls fn for each field of a descriptor. Fields not read by fn
+// are skipped.
+func parseFields(data []byte, fn func(number int32, r *wireReader) error) error {
+	r := &wireReader{buf: data}
+	for !r.done() {
+		number, wire, err := r.key()
+		if err != nil {
+			return err
+		}
+
+		pos := r.pos
+		if err := fn(number, r); err != nil {
+			return err
+		}
+		if r.pos == pos {
+			if err := r.skip(number, wire); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// FileDescriptorProto
+func (s *DescriptorSet) parseFile(data []byte) error {
+	var pkg string
+	var messages, enums [][]byte
+	err := parseFields(data, func(number int32, r *wireReader) error {
+		var err error
+		var b []byte
+		switch number {
+		case 2: // package
+			b, err = r.bytes()
+			pkg = string(b)
+		case 4: // message_type
+			b, err = r.bytes()
+			messages = append(messages, b)
+		case 5: // enum_type
+			b, err = r.bytes()
+			enums = append(enums, b)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	scope := ""
+	if pkg != "" {
+		scope = "." + pkg
+	}
+	for _, m := range messages {
+		if err := s.parseMessage(scope, m); err != nil {
+			return err
+		}
+	}
+	for _, e := range enums {
+		if err := s.parseEnum(scope, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DescriptorProto
+func (s *DescriptorSet) parseMessage(scope string, data []byte) error {
+	msg := &messageDescriptor{fields: map[int32]*fieldDescriptor{}}
+	var nested, enums [][]byte
+	err := parseFields(data, func(number int32, r *wireReader) error {
+		var err error
+		var b []byte
+		switch number {
+		case 1: // name
+			b, err = r.bytes()
+			msg.name = scope + "." + string(b)
+		case 2: // field
+			b, err = r.bytes()
+			if err == nil {
+				err = msg.parseField(b)
+			}
+		case 3: // nested_type
+			b, err = r.bytes()
+			nested = append(nested, b)
+		case 4: // enum_type
+			b, err = r.bytes()
+			enums = append(enums, b)
+		case 7: // options
+			b, err = r.bytes()
+			if err == nil {
+				err = msg.parseOptions(b)
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.messages[msg.name] = msg
+	for _, m := range nested {
+		if err := s.parseMessage(msg.name, m); err != nil {
+			return err
+		}
+	}
+	for _, e := range enums {
+		if err := s.parseEnum(msg.name, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MessageOptions
+func (m *messageDescriptor) parseOptions(data []byte) error {
+	return parseFields(data, func(number int32, r *wireReader) error {
+		if number != 7 { // map_entry
+			return nil
+		}
+		v, err := r.varint()
+		m.mapEntry = v != 0
+		return err
+	})
+}
+
+// FieldDescriptorProto
+func (m *messageDescriptor) parseField(data []byte) error {
+	field := &fieldDescriptor{}
+	err := parseFields(data, func(number int32, r *wireReader) error {
+		var err error
+		var b []byte
+		var v uint64
+		switch number {
+		case 1: // name
+			b, err = r.bytes()
+			field.name = string(b)
+		case 3: // number
+			v, err = r.varint()
+			field.number = int32(v)
+		case 4: // label
+			field.label, err = r.varint()
+		case 5: // type
+			field.typ, err = r.varint()
+		case 6: // type_name
+			b, err = r.bytes()
+			field.typeName = string(b)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	m.fields[field.number] = field
+	return nil
+}
+
+// EnumDescriptorProto
+func (s *DescriptorSet) parseEnum(scope string, data []byte) error {
+	enum := &enumDescriptor{values: map[int32]string{}}
+	err := parseFields(data, func(number int32, r *wireReader) error {
+		var err error
+		var b []byte
+		switch number {
+		case 1: // name
+			b, err = r.bytes()
+			enum.name = scope + "." + string(b)
+		case 2: // value
+			b, err = r.bytes()
+			if err == nil {
+				err = enum.parseValue(b)
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.enums[enum.name] = enum
+	return nil
+}
+
+// EnumValueDescriptorProto
+func (e *enumDescriptor) parseValue(data []byte) error {
+	var name string
+	var value int32
+	err := parseFields(data, func(number int32, r *wireReader) error {
+		var err error
+		var b []byte
+		var v uint64
+		switch number {
+		case 1: // name
+			b, err = r.bytes()
+			name = string(b)
+		case 2: // number
+			v, err = r.varint()
+			value = int32(v)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// The first name wins for aliases.
+	if _, exists := e.values[value]; !exists {
+		e.values[value] = name
+	}
+	return nil
+}
+
+// resolve links the message and enum fields to their types.
+func (s *DescriptorSet) resolve() error {
+	for _, msg := range s.messages {
+		for _, field := range msg.fields {
+			switch field.typ {
+			case typeMessage, typeGroup:
+				field.message = s.messages[field.typeName]
+				if field.message == nil {
+					return fmt.Errorf("unknown message type %v of field %v.%v", field.typeName, msg.name, field.name)
+				}
+			case typeEnum:
+				field.enum = s.enums[field.typeName]
+				if field.enum == nil {
+					return fmt.Errorf("unknown enum type %v of field %v.%v", field.typeName, msg.name, field.name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// NewDecoder creates a decoder of a message type of the descriptor set. The
+// type is given by its fully qualified name, like `package.Message`.
+func (s *DescriptorSet) NewDecoder(messageType string) (*Decoder, error) {
+	msg, err := s.message(messageType)
+	if err != nil {
+		return nil, err
+	}
+	return &Decoder{message: msg}, nil
+}
+
+// NewEncoder creates an encoder of a message type of the descriptor set. The
+// type is given by its fully qualified name, like `package.Message`.
+func (s *DescriptorSet) NewEncoder(messageType string) (*Encoder, error) {
+	msg, err := s.message(messageType)
+	if err != nil {
+		return nil, err
+	}
+	return &Encoder{message: msg}, nil
+}
+
+func (s *DescriptorSet) message(messageType string) (*messageDescriptor, error) {
+	name := messageType
+	if !strings.HasPrefix(name, ".") {
+		name = "." + name
+	}
+
+	msg, ok := s.messages[name]
+	if !ok {
+		return nil, fmt.Errorf("message type %v not found in descriptor set", messageType)
+	}
+	return msg, nil
+}
@@ -0,0 +1,368 @@
+package protobuf
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// timestampType is the well-known type of timestamps, encoded from times.
+const timestampType = ".google.protobuf.Timestamp"
+
+// Encoder encodes events into the messages of a single message type.
+type Encoder struct {
+	message *messageDescriptor
+}
+
+// Encode encodes the fields into a message. Fields are matched to the fields
+// of the message type by name, fields unknown to the message type are not
+// encoded. Enum values can be given by name or number, objects are encoded
+// into messages and maps. Times are encoded into google.protobuf.Timestamp
+// messages, RFC 3339 strings or Unix milliseconds, depending on the type of
+// the field.
+func (e *Encoder) Encode(fields common.MapStr) ([]byte, error) {
+	return encodeMessage(e.message, fields, 0)
+}
+
+func encodeMessage(msg *messageDescriptor, fields map[string]interface{}, depth int) ([]byte, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("message nesting exceeds %v levels", maxDepth)
+	}
+
+	// Fields are encoded by number, so messages are encoded the same way every
+	// time.
+	numbers := make([]int, 0, len(msg.fields))
+	for number := range msg.fields {
+		numbers = append(numbers, int(number))
+	}
+	sort.Ints(numbers)
+
+	var w wireWriter
+	for _, number := range numbers {
+		field := msg.fields[int32(number)]
+		value, ok := fields[field.name]
+		if !ok || value == nil || field.typ == typeGroup {
+			continue
+		}
+
+		if err := encodeField(&w, field, value, depth); err != nil {
+			return nil, fmt.Errorf("error encoding field %v of %v: %v", field.name, msg.name, err)
+		}
+	}
+	return w, nil
+}
+
+func encodeField(w *wireWriter, field *fieldDescriptor, value interface{}, depth int) error {
+	if field.message != nil && field.message.mapEntry {
+		return encodeMap(w, field, value, depth)
+	}
+	if field.label != labelRepeated {
+		return encodeValue(w, field, value, depth)
+	}
+
+	values := reflect.ValueOf(value)
+	if values.Kind() != reflect.Slice && values.Kind() != reflect.Array {
+		return fmt.Errorf("expected a list for a repeated field, found %T", value)
+	}
+
+	// Repeated scalar values are packed, the default of proto3.
+	if packable(field.typ) {
+		var packed wireWriter
+		for i := 0; i < values.Len(); i++ {
+			if err := encodeScalar(&packed, field, values.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		w.bytes(field.number, packed)
+		return nil
+	}
+
+	for i := 0; i < values.Len(); i++ {
+		if err := encodeValue(w, field, values.Index(i).Interface(), depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeMap encodes an object into the entries of a map field. The keys are
+// converted to the key type of the map.
+func encodeMap(w *wireWriter, field *fieldDescriptor, value interface{}, depth int) error {
+	m, ok := toMap(value)
+	if !ok {
+		return fmt.Errorf("expected an object for a map field, found %T", value)
+	}
+	keyField, valueField := field.message.fields[1], field.message.fields[2]
+	if keyField == nil || valueField == nil {
+		return fmt.Errorf("invalid map entry type %v", field.typeName)
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		key, err := mapKey(keyField, k)
+		if err != nil {
+			return err
+		}
+
+		var entry wireWriter
+		if err := encodeValue(&entry, keyField, key, depth+1); err != nil {
+			return err
+		}
+		if m[k] != nil {
+			if err := encodeValue(&entry, valueField, m[k], depth+1); err != nil {
+				return fmt.Errorf("error encoding key %v: %v", k, err)
+			}
+		}
+		w.bytes(field.number, entry)
+	}
+	return nil
+}
+
+// mapKey converts the key of an object to the key type of a map.
+func mapKey(field *fieldDescriptor, key string) (interface{}, error) {
+	switch field.typ {
+	case typeString:
+		return key, nil
+	case typeBool:
+		return strconv.ParseBool(key)
+	case typeUint32, typeUint64, typeFixed32, typeFixed64:
+		return strconv.ParseUint(key, 10, 64)
+	default:
+		return strconv.ParseInt(key, 10, 64)
+	}
+}
+
+// encodeValue encodes a single value with its key.
+func encodeValue(w *wireWriter, field *fieldDescriptor, value interface{}, depth int) error {
+	switch field.typ {
+	case typeString:
+		s, err := toString(value)
+		if err != nil {
+			return err
+		}
+		w.bytes(field.number, []byte(s))
+		return nil
+
+	case typeBytes:
+		switch v := value.(type) {
+		case []byte:
+			w.bytes(field.number, v)
+		case string:
+			w.bytes(field.number, []byte(v))
+		default:
+			return fmt.Errorf("expected bytes, found %T", value)
+		}
+		return nil
+
+	case typeMessage:
+		data, err := encodeSubMessage(field, value, depth)
+		if err != nil {
+			return err
+		}
+		w.bytes(field.number, data)
+		return nil
+	}
+
+	w.key(field.number, expectedWire(field.typ))
+	return encodeScalar(w, field, value)
+}
+
+func encodeSubMessage(field *fieldDescriptor, value interface{}, depth int) ([]byte, error) {
+	if t, ok := toTime(value); ok && field.typeName == timestampType {
+		var w wireWriter
+		w.key(1, wireVarint)
+		w.uvarint(uint64(t.Unix()))
+		if nanos := t.Nanosecond(); nanos != 0 {
+			w.key(2, wireVarint)
+			w.uvarint(uint64(nanos))
+		}
+		return w, nil
+	}
+
+	m, ok := toMap(value)
+	if !ok {
+		return nil, fmt.Errorf("expected an object for a message field, found %T", value)
+	}
+	return encodeMessage(field.message, m, depth+1)
+}
+
+// encodeScalar encodes a numeric, boolean or enum value without its key.
+func encodeScalar(w *wireWriter, field *fieldDescriptor, value interface{}) error {
+	switch field.typ {
+	case typeDouble:
+		f, err := toFloat(value)
+		if err != nil {
+			return err
+		}
+		w.fixed64(math.Float64bits(f))
+	case typeFloat:
+		f, err := toFloat(value)
+		if err != nil {
+			return err
+		}
+		w.fixed32(math.Float32bits(float32(f)))
+	case typeFixed64, typeSfixed64:
+		n, err := toInteger(value, field.typ)
+		if err != nil {
+			return err
+		}
+		w.fixed64(n)
+	case typeFixed32, typeSfixed32:
+		n, err := toInteger(value, field.typ)
+		if err != nil {
+			return err
+		}
+		w.fixed32(uint32(n))
+	case typeBool:
+		b, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("expected a boolean, found %T", value)
+		}
+		if b {
+			w.uvarint(1)
+		} else {
+			w.uvarint(0)
+		}
+	case typeEnum:
+		n, err := enumNumber(field.enum, value)
+		if err != nil {
+			return err
+		}
+		w.uvarint(uint64(int64(n)))
+	case typeSint32, typeSint64:
+		n, err := toInteger(value, field.typ)
+		if err != nil {
+			return err
+		}
+		v := int64(n)
+		w.uvarint(uint64(v<<1) ^ uint64(v>>63))
+	case typeInt32, typeInt64, typeUint32, typeUint64:
+		n, err := toInteger(value, field.typ)
+		if err != nil {
+			return err
+		}
+		w.uvarint(n)
+	default:
+		return fmt.Errorf("unsupported field type %v", field.typ)
+	}
+	return nil
+}
+
+// toInteger converts a number to an integer in the range of the field type,
+// returned as the bits of its two's complement. Floats must be integral,
+// times are converted to Unix milliseconds.
+func toInteger(value interface{}, typ uint64) (uint64, error) {
+	if t, ok := toTime(value); ok {
+		value = t.UnixNano() / int64(time.Millisecond)
+	}
+
+	var n int64
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := v.Uint()
+		if u > math.MaxInt64 {
+			if typ != typeUint64 && typ != typeFixed64 {
+				return 0, fmt.Errorf("%v overflows the field type", u)
+			}
+			return u, nil
+		}
+		n = int64(u)
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if f != math.Trunc(f) || f > math.MaxInt64 || f < math.MinInt64 {
+			return 0, fmt.Errorf("%v is not an integer", f)
+		}
+		n = int64(f)
+	default:
+		return 0, fmt.Errorf("expected a number, found %T", value)
+	}
+
+	var min, max int64
+	switch typ {
+	case typeInt32, typeSint32, typeSfixed32:
+		min, max = math.MinInt32, math.MaxInt32
+	case typeUint32, typeFixed32:
+		min, max = 0, math.MaxUint32
+	case typeUint64, typeFixed64:
+		min, max = 0, math.MaxInt64
+	default:
+		min, max = math.MinInt64, math.MaxInt64
+	}
+	if n < min || n > max {
+		return 0, fmt.Errorf("%v overflows the field type", n)
+	}
+	return uint64(n), nil
+}
+
+func toFloat(value interface{}) (float64, error) {
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), nil
+	default:
+		return 0, fmt.Errorf("expected a number, found %T", value)
+	}
+}
+
+func toString(value interface{}) (string, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	if t, ok := toTime(value); ok {
+		return t.UTC().Format(time.RFC3339Nano), nil
+	}
+	return "", fmt.Errorf("expected a string, found %T", value)
+}
+
+func toTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case common.Time:
+		return time.Time(v), true
+	}
+	return time.Time{}, false
+}
+
+func toMap(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case common.MapStr:
+		return v, true
+	case map[string]interface{}:
+		return v, true
+	}
+	return nil, false
+}
+
+// enumNumber returns the number of an enum value given by name or number.
+func enumNumber(enum *enumDescriptor, value interface{}) (int32, error) {
+	if name, ok := value.(string); ok {
+		for number, n := range enum.values {
+			if n == name {
+				return number, nil
+			}
+		}
+		return 0, fmt.Errorf("unknown value %v of enum %v", name, enum.name)
+	}
+
+	n, err := toInteger(value, typeInt32)
+	if err != nil {
+		return 0, err
+	}
+	return int32(n), nil
+}
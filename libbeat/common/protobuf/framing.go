@@ -0,0 +1,127 @@
+package protobuf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Framing types, the encoding of the length prefix of each message in a
+// stream.
+const (
+	FramingVarint  = "varint"
+	FramingFixed32 = "fixed32"
+	FramingFixed64 = "fixed64"
+)
+
+// FramingConfig configures the length prefix of the messages of a stream.
+// Fixed size prefixes are big endian by default.
+type FramingConfig struct {
+	Type      string `config:"type"`
+	ByteOrder string `config:"byte_order"`
+}
+
+// Validate validates the framing configuration.
+func (c *FramingConfig) Validate() error {
+	switch c.Type {
+	case "", FramingVarint, FramingFixed32, FramingFixed64:
+	default:
+		return fmt.Errorf("invalid framing type '%v', expected one of %v, %v or %v", c.Type, FramingVarint, FramingFixed32, FramingFixed64)
+	}
+
+	switch c.ByteOrder {
+	case "", "big_endian", "little_endian":
+	default:
+		return fmt.Errorf("invalid byte_order '%v', expected big_endian or little_endian", c.ByteOrder)
+	}
+	return nil
+}
+
+// FrameReader reads length prefixed messages from a stream.
+type FrameReader struct {
+	reader  *bufio.Reader
+	typ     string
+	order   binary.ByteOrder
+	maxSize int
+}
+
+// NewFrameReader creates a reader of the messages of r. Messages larger than
+// maxSize bytes are rejected, if maxSize is set.
+func NewFrameReader(r io.Reader, config FramingConfig, maxSize int) *FrameReader {
+	f := &FrameReader{
+		reader:  bufio.NewReader(r),
+		typ:     config.Type,
+		order:   binary.BigEndian,
+		maxSize: maxSize,
+	}
+	if f.typ == "" {
+		f.typ = FramingVarint
+	}
+	if config.ByteOrder == "little_endian" {
+		f.order = binary.LittleEndian
+	}
+	return f
+}
+
+// Next returns the next message, and the number of bytes read from the stream
+// including the length prefix. If the stream ends within a message,
+// io.ErrUnexpectedEOF is returned.
+func (f *FrameReader) Next() ([]byte, int, error) {
+	size, n, err := f.readLength()
+	if err != nil {
+		return nil, n, err
+	}
+	if f.maxSize > 0 && size > uint64(f.maxSize) {
+		return nil, n, fmt.Errorf("message size %v exceeds the maximum of %v bytes", size, f.maxSize)
+	}
+
+	msg := make([]byte, size)
+	read, err := io.ReadFull(f.reader, msg)
+	n += read
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, n, err
+	}
+	return msg, n, nil
+}
+
+func (f *FrameReader) readLength() (uint64, int, error) {
+	var buf [8]byte
+	switch f.typ {
+	case FramingFixed32:
+		n, err := io.ReadFull(f.reader, buf[:4])
+		if err == io.ErrUnexpectedEOF {
+			return 0, n, err
+		}
+		return uint64(f.order.Uint32(buf[:4])), n, err
+	case FramingFixed64:
+		n, err := io.ReadFull(f.reader, buf[:8])
+		if err == io.ErrUnexpectedEOF {
+			return 0, n, err
+		}
+		return f.order.Uint64(buf[:8]), n, err
+	}
+
+	counter := &countingByteReader{reader: f.reader}
+	size, err := binary.ReadUvarint(counter)
+	if err == io.EOF && counter.count > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return size, counter.count, err
+}
+
+type countingByteReader struct {
+	reader io.ByteReader
+	count  int
+}
+
+func (r *countingByteReader) ReadByte() (byte, error) {
+	b, err := r.reader.ReadByte()
+	if err == nil {
+		r.count++
+	}
+	return b, err
+}
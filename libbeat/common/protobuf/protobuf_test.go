@@ -0,0 +1,402 @@
+// +build !integration
+
+package protobuf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// protoWriter encodes messages for the tests.
+type protoWriter []byte
+
+func (w *protoWriter) key(number int32, wire int) {
+	w.uvarint(uint64(number)<<3 | uint64(wire))
+}
+
+func (w *protoWriter) uvarint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	*w = append(*w, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func (w *protoWriter) varint(number int32, v uint64) {
+	w.key(number, wireVarint)
+	w.uvarint(v)
+}
+
+func (w *protoWriter) fixed32(number int32, v uint32) {
+	w.key(number, wireFixed32)
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	*w = append(*w, buf[:]...)
+}
+
+func (w *protoWriter) fixed64(number int32, v uint64) {
+	w.key(number, wireFixed64)
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	*w = append(*w, buf[:]...)
+}
+
+func (w *protoWriter) bytes(number int32, b []byte) {
+	w.key(number, wireBytes)
+	w.uvarint(uint64(len(b)))
+	*w = append(*w, b...)
+}
+
+func (w *protoWriter) str(number int32, s string) {
+	w.bytes(number, []byte(s))
+}
+
+func loadSampleDecoder(t *testing.T) *Decoder {
+	set, err := LoadDescriptorSet("testdata/sample.desc")
+	require.NoError(t, err)
+
+	decoder, err := set.NewDecoder("sample.Event")
+	require.NoError(t, err)
+	return decoder
+}
+
+func TestDecodeSampleEvent(t *testing.T) {
+	var host protoWriter
+	host.str(1, "web-1")
+	host.bytes(2, []byte{80, 0xbb, 0x03}) // packed 80, 443
+
+	var entry protoWriter
+	entry.str(1, "requests")
+	entry.varint(2, 12)
+
+	var msg protoWriter
+	msg.str(1, "hello")
+	msg.varint(2, 42)
+	msg.varint(3, 3) // zigzag -2
+	msg.fixed64(4, math.Float64bits(0.5))
+	msg.varint(5, 1)
+	msg.varint(6, 2)
+	msg.str(7, "a")
+	msg.str(7, "b")
+	msg.bytes(8, []byte{1, 2, 3})
+	msg.bytes(9, host)
+	msg.bytes(10, entry)
+	msg.bytes(11, []byte{0xff, 0x00})
+	msg.fixed32(12, 7)
+	msg.fixed32(13, math.Float32bits(1.5))
+	msg.varint(99, 1) // unknown field
+
+	fields, err := loadSampleDecoder(t).Decode(msg)
+	require.NoError(t, err)
+
+	assert.Equal(t, common.MapStr{
+		"message": "hello",
+		"count":   int64(42),
+		"delta":   int32(-2),
+		"ratio":   0.5,
+		"ok":      true,
+		"level":   "ERROR",
+		"tags":    []interface{}{"a", "b"},
+		"codes":   []interface{}{int32(1), int32(2), int32(3)},
+		"host": common.MapStr{
+			"name":  "web-1",
+			"ports": []interface{}{uint32(80), uint32(443)},
+		},
+		"counters":    common.MapStr{"requests": int64(12)},
+		"raw":         []byte{0xff, 0x00},
+		"id":          uint32(7),
+		"temperature": float32(1.5),
+	}, fields)
+}
+
+func TestDecodeUnpackedRepeatedAndEnumNumber(t *testing.T) {
+	var msg protoWriter
+	msg.varint(8, 1)
+	msg.varint(8, math.MaxUint64) // -1, encoded in 10 bytes
+	msg.varint(6, 5)              // not a value of the enum
+
+	fields, err := loadSampleDecoder(t).Decode(msg)
+	require.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"codes": []interface{}{int32(1), int32(-1)},
+		"level": int32(5),
+	}, fields)
+}
+
+func TestDecodeInvalidMessage(t *testing.T) {
+	decoder := loadSampleDecoder(t)
+
+	var truncated protoWriter
+	truncated.str(1, "hello")
+	_, err := decoder.Decode(truncated[:len(truncated)-1])
+	assert.Error(t, err)
+
+	var wrongWire protoWriter
+	wrongWire.varint(1, 1)
+	_, err = decoder.Decode(wrongWire)
+	assert.Error(t, err)
+}
+
+func TestDescriptorSetUnknownMessageType(t *testing.T) {
+	set, err := LoadDescriptorSet("testdata/sample.desc")
+	require.NoError(t, err)
+
+	_, err = set.NewDecoder("sample.Missing")
+	assert.Error(t, err)
+
+	_, err = set.NewDecoder(".sample.Event.Host")
+	assert.NoError(t, err)
+}
+
+func TestDescriptorSetUnresolvedType(t *testing.T) {
+	var field protoWriter
+	field.str(1, "host")
+	field.varint(3, 1)
+	field.varint(5, typeMessage)
+	field.str(6, ".sample.Missing")
+
+	var msg protoWriter
+	msg.str(1, "Event")
+	msg.bytes(2, field)
+
+	var file protoWriter
+	file.str(2, "sample")
+	file.bytes(4, msg)
+
+	var set protoWriter
+	set.bytes(1, file)
+
+	_, err := ParseDescriptorSet(set)
+	assert.Error(t, err)
+}
+
+func loadSampleEncoder(t *testing.T) *Encoder {
+	set, err := LoadDescriptorSet("testdata/sample.desc")
+	require.NoError(t, err)
+
+	encoder, err := set.NewEncoder("sample.Event")
+	require.NoError(t, err)
+	return encoder
+}
+
+func TestEncodeSampleEvent(t *testing.T) {
+	fields := common.MapStr{
+		"message": "hello",
+		"count":   42,
+		"delta":   int32(-2),
+		"ratio":   0.5,
+		"ok":      true,
+		"level":   "ERROR",
+		"tags":    []string{"a", "b"},
+		"codes":   []interface{}{1, int64(-1), float64(3)},
+		"host": common.MapStr{
+			"name":  "web-1",
+			"ports": []interface{}{uint32(80), float64(443)},
+		},
+		"counters":    common.MapStr{"requests": 12},
+		"raw":         []byte{0xff, 0x00},
+		"id":          7,
+		"temperature": 1.5,
+		"unknown":     "not encoded",
+	}
+
+	data, err := loadSampleEncoder(t).Encode(fields)
+	require.NoError(t, err)
+
+	decoded, err := loadSampleDecoder(t).Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"message": "hello",
+		"count":   int64(42),
+		"delta":   int32(-2),
+		"ratio":   0.5,
+		"ok":      true,
+		"level":   "ERROR",
+		"tags":    []interface{}{"a", "b"},
+		"codes":   []interface{}{int32(1), int32(-1), int32(3)},
+		"host": common.MapStr{
+			"name":  "web-1",
+			"ports": []interface{}{uint32(80), uint32(443)},
+		},
+		"counters":    common.MapStr{"requests": int64(12)},
+		"raw":         []byte{0xff, 0x00},
+		"id":          uint32(7),
+		"temperature": float32(1.5),
+	}, decoded)
+
+	// The fields are encoded by number, the same way every time.
+	again, err := loadSampleEncoder(t).Encode(fields)
+	require.NoError(t, err)
+	assert.Equal(t, data, again)
+}
+
+func TestEncodeConversions(t *testing.T) {
+	encoder, decoder := loadSampleEncoder(t), loadSampleDecoder(t)
+	ts := time.Date(2017, 10, 14, 8, 0, 0, 500000000, time.UTC)
+
+	data, err := encoder.Encode(common.MapStr{
+		"message": common.Time(ts),
+		"count":   ts,
+		"level":   1,
+		"raw":     "text",
+	})
+	require.NoError(t, err)
+
+	decoded, err := decoder.Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"message": "2017-10-14T08:00:00.5Z",
+		"count":   ts.UnixNano() / int64(time.Millisecond),
+		"level":   "INFO",
+		"raw":     []byte("text"),
+	}, decoded)
+}
+
+func TestEncodeInvalidValues(t *testing.T) {
+	encoder := loadSampleEncoder(t)
+
+	tests := map[string]common.MapStr{
+		"string of number":   {"count": "42"},
+		"fraction":           {"count": 1.5},
+		"overflow":           {"id": int64(1) << 33},
+		"negative unsigned":  {"host": common.MapStr{"ports": []int{-1}}},
+		"unknown enum value": {"level": "TRACE"},
+		"number of string":   {"message": 1},
+		"scalar of list":     {"tags": "a"},
+		"scalar of message":  {"host": "web-1"},
+		"nested mismatch":    {"host": common.MapStr{"name": true}},
+		"map value mismatch": {"counters": common.MapStr{"requests": "12"}},
+	}
+	for name, fields := range tests {
+		_, err := encoder.Encode(fields)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestEncodeStruct(t *testing.T) {
+	data, err := EncodeStruct(common.MapStr{
+		"b": common.MapStr{"ok": true},
+		"a": []interface{}{"x", 2, nil},
+	})
+	require.NoError(t, err)
+
+	var str, two, null, list protoWriter
+	str.str(valueString, "x")
+	two.fixed64(valueNumber, math.Float64bits(2))
+	null.varint(valueNull, 0)
+	list.bytes(listValues, str)
+	list.bytes(listValues, two)
+	list.bytes(listValues, null)
+
+	var listValue protoWriter
+	listValue.bytes(valueList, list)
+
+	var boolValue protoWriter
+	boolValue.varint(valueBool, 1)
+	var okEntry protoWriter
+	okEntry.str(structEntryKey, "ok")
+	okEntry.bytes(structEntryValue, boolValue)
+	var inner protoWriter
+	inner.bytes(structFields, okEntry)
+	var structValue protoWriter
+	structValue.bytes(valueStruct, inner)
+
+	var entryA, entryB protoWriter
+	entryA.str(structEntryKey, "a")
+	entryA.bytes(structEntryValue, listValue)
+	entryB.str(structEntryKey, "b")
+	entryB.bytes(structEntryValue, structValue)
+
+	var expected protoWriter
+	expected.bytes(structFields, entryA)
+	expected.bytes(structFields, entryB)
+
+	assert.Equal(t, []byte(expected), data)
+}
+
+func TestFrameReader(t *testing.T) {
+	tests := []struct {
+		name   string
+		config FramingConfig
+		prefix func(size int) []byte
+	}{
+		{
+			name:   "varint",
+			config: FramingConfig{},
+			prefix: func(size int) []byte {
+				var w protoWriter
+				w.uvarint(uint64(size))
+				return w
+			},
+		},
+		{
+			name:   "fixed32",
+			config: FramingConfig{Type: FramingFixed32},
+			prefix: func(size int) []byte {
+				buf := make([]byte, 4)
+				binary.BigEndian.PutUint32(buf, uint32(size))
+				return buf
+			},
+		},
+		{
+			name:   "fixed64 little endian",
+			config: FramingConfig{Type: FramingFixed64, ByteOrder: "little_endian"},
+			prefix: func(size int) []byte {
+				buf := make([]byte, 8)
+				binary.LittleEndian.PutUint64(buf, uint64(size))
+				return buf
+			},
+		},
+	}
+
+	messages := [][]byte{{}, []byte("first"), bytes.Repeat([]byte("x"), 300)}
+	for _, test := range tests {
+		var stream []byte
+		for _, msg := range messages {
+			stream = append(stream, test.prefix(len(msg))...)
+			stream = append(stream, msg...)
+		}
+
+		r := NewFrameReader(bytes.NewReader(stream), test.config, 0)
+		for _, expected := range messages {
+			msg, n, err := r.Next()
+			require.NoError(t, err, test.name)
+			assert.Equal(t, expected, msg, test.name)
+			assert.Equal(t, len(test.prefix(len(expected)))+len(expected), n, test.name)
+		}
+
+		_, n, err := r.Next()
+		assert.Equal(t, io.EOF, err, test.name)
+		assert.Equal(t, 0, n, test.name)
+
+		// A stream ending within a message is truncated.
+		r = NewFrameReader(bytes.NewReader(stream[:len(stream)-1]), test.config, 0)
+		for range messages[:len(messages)-1] {
+			_, _, err = r.Next()
+			require.NoError(t, err, test.name)
+		}
+		_, _, err = r.Next()
+		assert.Equal(t, io.ErrUnexpectedEOF, err, test.name)
+	}
+}
+
+func TestFrameReaderMaxSize(t *testing.T) {
+	var stream protoWriter
+	stream.uvarint(11)
+	stream = append(stream, "hello world"...)
+
+	_, _, err := NewFrameReader(bytes.NewReader(stream), FramingConfig{}, 10).Next()
+	assert.Error(t, err)
+}
+
+func TestFramingConfigValidate(t *testing.T) {
+	assert.NoError(t, (&FramingConfig{Type: FramingFixed32, ByteOrder: "little_endian"}).Validate())
+	assert.Error(t, (&FramingConfig{Type: "fixed16"}).Validate())
+	assert.Error(t, (&FramingConfig{ByteOrder: "middle_endian"}).Validate())
+}
@@ -0,0 +1,131 @@
+package protobuf
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// Field numbers of the google.protobuf.Struct messages:
+//
+//	message Struct { map<string, Value> fields = 1; }
+//	message Value {
+//	  oneof kind {
+//	    NullValue null_value = 1; double number_value = 2; string string_value = 3;
+//	    bool bool_value = 4; Struct struct_value = 5; ListValue list_value = 6;
+//	  }
+//	}
+//	message ListValue { repeated Value values = 1; }
+const (
+	structFields     = 1
+	structEntryKey   = 1
+	structEntryValue = 2
+	valueNull        = 1
+	valueNumber      = 2
+	valueString      = 3
+	valueBool        = 4
+	valueStruct      = 5
+	valueList        = 6
+	listValues       = 1
+)
+
+// EncodeStruct encodes fields into a google.protobuf.Struct message, the
+// protobuf representation of a JSON object. Like in JSON, numbers are doubles,
+// times are RFC 3339 strings and bytes are base64 encoded strings. Other
+// values are encoded as their string representation.
+func EncodeStruct(fields common.MapStr) ([]byte, error) {
+	return encodeStruct(fields, 0)
+}
+
+func encodeStruct(fields map[string]interface{}, depth int) ([]byte, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("object nesting exceeds %v levels", maxDepth)
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var w wireWriter
+	for _, k := range keys {
+		value, err := encodeStructValue(fields[k], depth)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding field %v: %v", k, err)
+		}
+
+		var entry wireWriter
+		entry.bytes(structEntryKey, []byte(k))
+		entry.bytes(structEntryValue, value)
+		w.bytes(structFields, entry)
+	}
+	return w, nil
+}
+
+// encodeStructValue encodes a value into a google.protobuf.Value message.
+func encodeStructValue(value interface{}, depth int) ([]byte, error) {
+	var w wireWriter
+	switch v := value.(type) {
+	case nil:
+		w.key(valueNull, wireVarint)
+		w.uvarint(0)
+	case bool:
+		w.key(valueBool, wireVarint)
+		if v {
+			w.uvarint(1)
+		} else {
+			w.uvarint(0)
+		}
+	case string:
+		w.bytes(valueString, []byte(v))
+	case []byte:
+		w.bytes(valueString, []byte(base64.StdEncoding.EncodeToString(v)))
+	case time.Time:
+		w.bytes(valueString, []byte(v.UTC().Format(time.RFC3339Nano)))
+	case common.Time:
+		w.bytes(valueString, []byte(time.Time(v).UTC().Format(time.RFC3339Nano)))
+	case common.MapStr:
+		return encodeStructObject(v, depth)
+	case map[string]interface{}:
+		return encodeStructObject(v, depth)
+	default:
+		rv := reflect.ValueOf(value)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			f, _ := toFloat(value)
+			w.key(valueNumber, wireFixed64)
+			w.fixed64(math.Float64bits(f))
+		case reflect.Slice, reflect.Array:
+			var list wireWriter
+			for i := 0; i < rv.Len(); i++ {
+				item, err := encodeStructValue(rv.Index(i).Interface(), depth+1)
+				if err != nil {
+					return nil, err
+				}
+				list.bytes(listValues, item)
+			}
+			w.bytes(valueList, list)
+		default:
+			w.bytes(valueString, []byte(fmt.Sprint(value)))
+		}
+	}
+	return w, nil
+}
+
+func encodeStructObject(fields map[string]interface{}, depth int) ([]byte, error) {
+	s, err := encodeStruct(fields, depth+1)
+	if err != nil {
+		return nil, err
+	}
+	var w wireWriter
+	w.bytes(valueStruct, s)
+	return w, nil
+}
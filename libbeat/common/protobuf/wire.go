@@ -0,0 +1,151 @@
+package protobuf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Wire types of the protobuf encoding.
+const (
+	wireVarint     = 0
+	wireFixed64    = 1
+	wireBytes      = 2
+	wireStartGroup = 3
+	wireEndGroup   = 4
+	wireFixed32    = 5
+)
+
+var errTruncated = errors.New("unexpected end of message")
+
+// wireReader reads the values of an encoded protobuf message.
+type wireReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *wireReader) done() bool {
+	return r.pos >= len(r.buf)
+}
+
+func (r *wireReader) varint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		if n == 0 {
+			return 0, errTruncated
+		}
+		return 0, errors.New("varint overflows 64 bits")
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *wireReader) fixed32() (uint32, error) {
+	if len(r.buf)-r.pos < 4 {
+		return 0, errTruncated
+	}
+	v := binary.LittleEndian.Uint32(r.buf[r.pos:])
+	r.pos += 4
+	return v, nil
+}
+
+func (r *wireReader) fixed64() (uint64, error) {
+	if len(r.buf)-r.pos < 8 {
+		return 0, errTruncated
+	}
+	v := binary.LittleEndian.Uint64(r.buf[r.pos:])
+	r.pos += 8
+	return v, nil
+}
+
+func (r *wireReader) bytes() ([]byte, error) {
+	l, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(r.buf)-r.pos) < l {
+		return nil, errTruncated
+	}
+	b := r.buf[r.pos : r.pos+int(l)]
+	r.pos += int(l)
+	return b, nil
+}
+
+// key reads the key of the next field, returning the field number and the
+// wire type.
+func (r *wireReader) key() (int32, int, error) {
+	k, err := r.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	number, wire := int32(k>>3), int(k&7)
+	if number <= 0 {
+		return 0, 0, fmt.Errorf("invalid field number %v", number)
+	}
+	return number, wire, nil
+}
+
+// skip skips the value of a field of the given wire type. Groups are skipped
+// up to the matching end group.
+func (r *wireReader) skip(number int32, wire int) error {
+	var err error
+	switch wire {
+	case wireVarint:
+		_, err = r.varint()
+	case wireFixed64:
+		_, err = r.fixed64()
+	case wireBytes:
+		_, err = r.bytes()
+	case wireFixed32:
+		_, err = r.fixed32()
+	case wireStartGroup:
+		for {
+			n, w, err := r.key()
+			if err != nil {
+				return err
+			}
+			if w == wireEndGroup {
+				if n != number {
+					return fmt.Errorf("unmatched end of group %v", n)
+				}
+				return nil
+			}
+			if err := r.skip(n, w); err != nil {
+				return err
+			}
+		}
+	default:
+		err = fmt.Errorf("invalid wire type %v", wire)
+	}
+	return err
+}
+
+// wireWriter appends the values of an encoded protobuf message.
+type wireWriter []byte
+
+func (w *wireWriter) key(number int32, wire int) {
+	w.uvarint(uint64(number)<<3 | uint64(wire))
+}
+
+func (w *wireWriter) uvarint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	*w = append(*w, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func (w *wireWriter) fixed32(v uint32) {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	*w = append(*w, buf[:]...)
+}
+
+func (w *wireWriter) fixed64(v uint64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	*w = append(*w, buf[:]...)
+}
+
+func (w *wireWriter) bytes(number int32, b []byte) {
+	w.key(number, wireBytes)
+	w.uvarint(uint64(len(b)))
+	*w = append(*w, b...)
+}
@@ -0,0 +1,88 @@
+// Package startlimit bounds the number of units, like harvesters or
+// metricsets, that are starting at the same time. Units exceeding the limit
+// are queued until a starting unit is done, instead of starting all units at
+// once on beat startup or on a large configuration reload.
+package startlimit
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/elastic/beats/libbeat/monitoring"
+)
+
+// DefaultFactor is the number of units per CPU usable by the Go runtime, as
+// set by GOMAXPROCS, that start at the same time by default.
+const DefaultFactor = 4
+
+var (
+	queued = monitoring.NewInt(nil, "libbeat.startlimit.queued")
+
+	mutex   sync.Mutex
+	current = New(0)
+)
+
+// Limiter limits the number of units starting at the same time.
+type Limiter struct {
+	slots chan struct{}
+}
+
+// New creates a Limiter allowing max units to start at the same time. If max
+// is 0 or less, the DefaultMax is used.
+func New(max int) *Limiter {
+	if max <= 0 {
+		max = DefaultMax()
+	}
+	return &Limiter{slots: make(chan struct{}, max)}
+}
+
+// DefaultMax returns the default limit, relative to GOMAXPROCS.
+func DefaultMax() int {
+	return DefaultFactor * runtime.GOMAXPROCS(0)
+}
+
+// Configure replaces the limiter returned by Default with a limiter allowing
+// max units to start at the same time, or DefaultMax if max is 0 or less.
+// Units started before are released to the limiter they were started with.
+func Configure(max int) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	current = New(max)
+}
+
+// Default returns the limiter shared by all units of the beat.
+func Default() *Limiter {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return current
+}
+
+// Acquire blocks until the unit can start, and reports true. If done is
+// closed while the unit is waiting, Acquire reports false and the unit must
+// not start. Every successful Acquire must be followed by a Release once the
+// unit has started.
+func (l *Limiter) Acquire(done <-chan struct{}) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	queued.Inc()
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// Release allows the next queued unit to start.
+func (l *Limiter) Release() {
+	<-l.slots
+}
+
+// Max returns the number of units allowed to start at the same time.
+func (l *Limiter) Max() int {
+	return cap(l.slots)
+}
@@ -0,0 +1,93 @@
+package startlimit
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiterRespectsMax(t *testing.T) {
+	const max, units = 3, 20
+	l := New(max)
+
+	var starting, peak, started int32
+	var wg sync.WaitGroup
+	wg.Add(units)
+	for i := 0; i < units; i++ {
+		go func() {
+			defer wg.Done()
+			if !l.Acquire(nil) {
+				return
+			}
+			defer l.Release()
+
+			n := atomic.AddInt32(&starting, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&starting, -1)
+			atomic.AddInt32(&started, 1)
+		}()
+	}
+	wg.Wait()
+
+	// All units eventually start, but never more than max at the same time.
+	assert.Equal(t, int32(units), started)
+	assert.True(t, peak > 0 && peak <= max, "peak %v", peak)
+}
+
+func TestLimiterAcquireDone(t *testing.T) {
+	l := New(1)
+	assert.True(t, l.Acquire(nil))
+
+	done := make(chan struct{})
+	result := make(chan bool)
+	go func() { result <- l.Acquire(done) }()
+
+	select {
+	case <-result:
+		t.Fatal("Acquire must wait for a free slot")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(done)
+	assert.False(t, <-result)
+
+	// The slot of the cancelled unit is not taken.
+	l.Release()
+	assert.True(t, l.Acquire(nil))
+}
+
+func TestDefaultMax(t *testing.T) {
+	assert.Equal(t, DefaultFactor*runtime.GOMAXPROCS(0), DefaultMax())
+	assert.Equal(t, DefaultMax(), New(0).Max())
+	assert.Equal(t, DefaultMax(), New(-1).Max())
+	assert.Equal(t, 5, New(5).Max())
+}
+
+func TestConfigure(t *testing.T) {
+	defer Configure(0)
+
+	old := Default()
+	assert.True(t, old.Acquire(nil))
+
+	Configure(2)
+	assert.Equal(t, 2, Default().Max())
+	assert.False(t, old == Default())
+
+	// Units started before are released to their limiter.
+	old.Release()
+	assert.True(t, old.Acquire(nil))
+	old.Release()
+
+	Configure(0)
+	assert.Equal(t, DefaultMax(), Default().Max())
+}
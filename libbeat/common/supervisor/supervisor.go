@@ -0,0 +1,79 @@
+// Package supervisor runs units of work, like inputs or metricsets, in a way
+// that a panic in one unit does not crash the whole beat. Panicking units are
+// restarted with backoff, until they panic too often and get disabled.
+package supervisor
+
+import (
+	"runtime/debug"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/monitoring"
+)
+
+// Config configures the restart behavior of a supervised unit.
+type Config struct {
+	// InitBackoff is the wait time before the first restart. The wait time is
+	// doubled with every restart, up to MaxBackoff.
+	InitBackoff time.Duration
+	MaxBackoff  time.Duration
+
+	// MaxRestarts is the number of restarts after which a unit that panics again
+	// is disabled. A negative value restarts the unit forever.
+	MaxRestarts int
+}
+
+// DefaultConfig is the restart configuration used for beats inputs and
+// metricsets.
+var DefaultConfig = Config{
+	InitBackoff: 1 * time.Second,
+	MaxBackoff:  60 * time.Second,
+	MaxRestarts: 10,
+}
+
+var (
+	panics   = monitoring.NewInt(nil, "libbeat.supervisor.panics")
+	restarts = monitoring.NewInt(nil, "libbeat.supervisor.restarts")
+	disabled = monitoring.NewInt(nil, "libbeat.supervisor.disabled")
+)
+
+// Run executes fn, restarting it after a panic until fn returns normally, done
+// is closed, or the maximum number of restarts is exceeded. Run blocks until
+// the unit is finished and reports false if the unit has been disabled.
+func Run(name string, config Config, done <-chan struct{}, fn func()) bool {
+	backoff := common.NewBackoff(done, config.InitBackoff, config.MaxBackoff)
+
+	for restart := 0; ; restart++ {
+		if !runRecover(name, fn) {
+			return true
+		}
+
+		if config.MaxRestarts >= 0 && restart >= config.MaxRestarts {
+			disabled.Inc()
+			logp.Err("%v panicked %v times and has been disabled", name, restart+1)
+			return false
+		}
+
+		logp.Info("Restarting %v after panic", name)
+		if !backoff.Wait() {
+			return true
+		}
+		restarts.Inc()
+	}
+}
+
+// runRecover runs fn and reports whether fn has panicked.
+func runRecover(name string, fn func()) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			panics.Inc()
+			logp.Err("Recovered from panic in %v: %v", name, r)
+			logp.Err("Stacktrace: %s", debug.Stack())
+		}
+	}()
+
+	fn()
+	return false
+}
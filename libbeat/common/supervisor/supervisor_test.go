@@ -0,0 +1,84 @@
+package supervisor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testConfig = Config{
+	InitBackoff: 10 * time.Millisecond,
+	MaxBackoff:  40 * time.Millisecond,
+	MaxRestarts: 4,
+}
+
+func TestRunNoPanic(t *testing.T) {
+	runs := 0
+	ok := Run("test", testConfig, nil, func() { runs++ })
+	assert.True(t, ok)
+	assert.Equal(t, 1, runs)
+}
+
+func TestRestartWithBackoff(t *testing.T) {
+	var starts []time.Time
+	panicsBefore := panics.Get()
+
+	ok := Run("test", testConfig, nil, func() {
+		starts = append(starts, time.Now())
+		if len(starts) < 3 {
+			panic("boom")
+		}
+	})
+
+	assert.True(t, ok)
+	assert.Len(t, starts, 3)
+	assert.Equal(t, panicsBefore+2, panics.Get())
+
+	// backoff doubles between the restarts
+	assert.True(t, starts[1].Sub(starts[0]) >= 10*time.Millisecond)
+	assert.True(t, starts[2].Sub(starts[1]) >= 20*time.Millisecond)
+}
+
+func TestDisableAfterMaxRestarts(t *testing.T) {
+	runs := 0
+	disabledBefore := disabled.Get()
+	restartsBefore := restarts.Get()
+
+	ok := Run("test", testConfig, nil, func() {
+		runs++
+		panic("boom")
+	})
+
+	assert.False(t, ok)
+	assert.Equal(t, testConfig.MaxRestarts+1, runs)
+	assert.Equal(t, restartsBefore+int64(testConfig.MaxRestarts), restarts.Get())
+	assert.Equal(t, disabledBefore+1, disabled.Get())
+}
+
+func TestStopDuringBackoff(t *testing.T) {
+	done := make(chan struct{})
+	config := testConfig
+	config.InitBackoff = time.Hour
+	config.MaxBackoff = time.Hour
+
+	runs := 0
+	result := make(chan bool)
+	go func() {
+		result <- Run("test", config, done, func() {
+			runs++
+			panic("boom")
+		})
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	close(done)
+
+	select {
+	case ok := <-result:
+		assert.True(t, ok)
+		assert.Equal(t, 1, runs)
+	case <-time.After(time.Second):
+		t.Fatal("supervisor did not stop")
+	}
+}
@@ -0,0 +1,184 @@
+package kibana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Check compares the Index-Pattern for Kibana for 5.x, default and 8.x generated
+// from fields.yml with the files in the beat directory, without writing them.
+// It returns true if the files are up to date. Otherwise it returns false and
+// a diff of the fields that would be changed by generating the files again.
+func (i *IndexPatternGenerator) Check() (bool, string, error) {
+	files, err := i.generateAll(context.Background())
+	if err != nil {
+		return false, "", err
+	}
+	return i.check(files)
+}
+
+// CheckNamespace compares the Index-Pattern for Kibana for 5.x, default and 8.x
+// of a single namespace with the files in the beat directory, like Check.
+func (i *IndexPatternGenerator) CheckNamespace(namespace string) (bool, string, error) {
+	files, err := i.generateNamespace(context.Background(), namespace)
+	if err != nil {
+		return false, "", err
+	}
+	return i.check(files)
+}
+
+func (i *IndexPatternGenerator) check(files []patternFile) (bool, string, error) {
+	var diff bytes.Buffer
+	for _, f := range files {
+		name := f.path
+		if rel, err := filepath.Rel(i.beatDir, f.path); err == nil {
+			name = rel
+		}
+
+		existing, err := ioutil.ReadFile(f.path)
+		if os.IsNotExist(err) {
+			fmt.Fprintf(&diff, "%s: file is missing\n", name)
+			continue
+		}
+		if err != nil {
+			return false, "", err
+		}
+		if bytes.Equal(existing, f.content) {
+			continue
+		}
+
+		lines, err := diffPatterns(existing, f.content)
+		if err != nil {
+			fmt.Fprintf(&diff, "%s: can not compare file: %v\n", name, err)
+			continue
+		}
+		if len(lines) == 0 {
+			lines = []string{"  formatting or order of the fields differs"}
+		}
+		fmt.Fprintf(&diff, "%s:\n%s\n", name, strings.Join(lines, "\n"))
+	}
+	return diff.Len() == 0, diff.String(), nil
+}
+
+// diffPatterns compares two index patterns field by field. Lines are prefixed
+// by `+` for entries added, `-` for entries removed and `~` for entries changed
+// by generating the index pattern again.
+func diffPatterns(existing, generated []byte) ([]string, error) {
+	before, err := patternEntries(existing)
+	if err != nil {
+		return nil, err
+	}
+	after, err := patternEntries(generated)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(before)+len(after))
+	for k := range before {
+		keys = append(keys, k)
+	}
+	for k := range after {
+		if _, exists := before[k]; !exists {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var lines []string
+	for _, k := range keys {
+		b, inBefore := before[k]
+		a, inAfter := after[k]
+		switch {
+		case !inBefore:
+			lines = append(lines, fmt.Sprintf("  + %s: %s", k, a))
+		case !inAfter:
+			lines = append(lines, fmt.Sprintf("  - %s: %s", k, b))
+		case a != b:
+			lines = append(lines, fmt.Sprintf("  ~ %s: %s -> %s", k, b, a))
+		}
+	}
+	return lines, nil
+}
+
+// patternEntries flattens an index pattern into comparable entries. Fields
+// and their formats and attributes are stored as JSON encoded strings in the
+// index pattern, and are split into one entry per field.
+func patternEntries(content []byte) (map[string]string, error) {
+	var pattern map[string]interface{}
+	if err := json.Unmarshal(content, &pattern); err != nil {
+		return nil, err
+	}
+
+	entries := map[string]string{}
+	attributes := pattern
+	if object, ok := pattern["attributes"].(map[string]interface{}); ok {
+		// Data views are a single saved object.
+		attributes = object
+		for k, v := range pattern {
+			if k != "attributes" {
+				entries["object "+k] = encodeEntry(v)
+			}
+		}
+	} else if objects, ok := pattern["objects"].([]interface{}); ok {
+		if len(objects) != 1 {
+			return nil, fmt.Errorf("expected a single index pattern object, found %d", len(objects))
+		}
+		object, ok := objects[0].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid index pattern object")
+		}
+		attributes, _ = object["attributes"].(map[string]interface{})
+
+		for k, v := range pattern {
+			if k != "objects" {
+				entries[k] = encodeEntry(v)
+			}
+		}
+		for k, v := range object {
+			if k != "attributes" {
+				entries["object "+k] = encodeEntry(v)
+			}
+		}
+	}
+
+	for k, v := range attributes {
+		s, isString := v.(string)
+		switch {
+		case k == "fields" && isString:
+			var fields []map[string]interface{}
+			if err := json.Unmarshal([]byte(s), &fields); err != nil {
+				return nil, fmt.Errorf("invalid fields: %v", err)
+			}
+			for _, f := range fields {
+				name, _ := f["name"].(string)
+				entries["field "+name] = encodeEntry(f)
+			}
+		case (k == "fieldFormatMap" || k == "fieldAttrs" || k == "runtimeFieldMap") && isString:
+			var m map[string]interface{}
+			if err := json.Unmarshal([]byte(s), &m); err != nil {
+				return nil, fmt.Errorf("invalid %s: %v", k, err)
+			}
+			for name, value := range m {
+				entries[k+" "+name] = encodeEntry(value)
+			}
+		default:
+			entries[k] = encodeEntry(v)
+		}
+	}
+	return entries, nil
+}
+
+func encodeEntry(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
@@ -0,0 +1,118 @@
+package kibana
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCheckGenerator(t *testing.T, fixture string) *IndexPatternGenerator {
+	beatDir, err := filepath.Abs(filepath.Join("testdata", "check", fixture))
+	require.NoError(t, err)
+
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "7.0.0")
+	require.NoError(t, err)
+	return generator
+}
+
+func TestCheckMatching(t *testing.T) {
+	generator := newCheckGenerator(t, "matching")
+
+	ok, diff, err := generator.Check()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Empty(t, diff)
+}
+
+func TestCheckDrifted(t *testing.T) {
+	generator := newCheckGenerator(t, "drifted")
+
+	ok, diff, err := generator.Check()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	for _, file := range []string{
+		"_meta/kibana/5.x/index-pattern/beat.json:\n",
+		"_meta/kibana/default/index-pattern/beat.json:\n",
+	} {
+		assert.Contains(t, diff, file)
+	}
+	assert.Contains(t, diff, `  + field status: {"aggregatable":true`)
+	assert.Contains(t, diff, `  - field old: {"aggregatable":true`)
+	assert.Contains(t, diff, `  + fieldFormatMap bytes: {"id":"bytes"}`)
+	assert.NotContains(t, diff, "field message")
+}
+
+func TestCheckDoesNotWrite(t *testing.T) {
+	generator := newCheckGenerator(t, "drifted")
+	path := filepath.Join(generator.targetDirDefault, "beat.json")
+	before, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+
+	files, err := generator.GenerateBytes()
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+	assert.NotEqual(t, before, files[path])
+
+	_, _, err = generator.Check()
+	require.NoError(t, err)
+
+	after, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+}
+
+func TestCheckMissingFile(t *testing.T) {
+	beatDir := tmpPath()
+	defer teardown(beatDir)
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "7.0.0")
+	require.NoError(t, err)
+
+	ok, diff, err := generator.Check()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Contains(t, diff, "_meta/kibana/5.x/index-pattern/beat.json: file is missing\n")
+
+	_, err = generator.Generate()
+	require.NoError(t, err)
+	ok, diff, err = generator.Check()
+	assert.NoError(t, err)
+	assert.True(t, ok, diff)
+}
+
+func TestCheckNamespace(t *testing.T) {
+	beatDir, err := filepath.Abs("./testdata/extensive")
+	require.NoError(t, err)
+	defer teardown(beatDir)
+	generator, err := NewGenerator("metricbeat-*", "metricbeat", beatDir, "7.0.0-alpha1")
+	require.NoError(t, err)
+
+	_, err = generator.GenerateNamespace("docker")
+	require.NoError(t, err)
+	ok, diff, err := generator.CheckNamespace("docker")
+	assert.NoError(t, err)
+	assert.True(t, ok, diff)
+
+	// The index pattern of all fields has not been generated.
+	ok, _, err = generator.Check()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, _, err = generator.CheckNamespace("notexistent")
+	assert.Error(t, err)
+}
+
+func TestDiffPatternsFormatting(t *testing.T) {
+	existing := []byte(`{"title": "beat-*", "fields": "[{\"name\":\"a\"},{\"name\":\"b\"}]"}`)
+	generated := []byte(`{"title": "beat-*", "fields": "[{\"name\":\"b\"},{\"name\":\"a\"}]"}`)
+
+	lines, err := diffPatterns(existing, generated)
+	assert.NoError(t, err)
+	assert.Empty(t, lines)
+
+	_, err = diffPatterns([]byte("invalid"), generated)
+	assert.Error(t, err)
+}
@@ -0,0 +1,125 @@
+package kibana
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// DefaultFields returns the paths of the fields searched by query-string
+// queries without field, in the order of the fields.yml files. These are the
+// indexed `text` and `keyword` fields, including multi-fields and fields
+// without type, which are mapped as keyword. Object fields with an
+// `object_type` of text or keyword are added as wildcard like `labels.*`.
+//
+// Setting `default_field` in fields.yml overrides the selection, `true` adds
+// fields searchable as text of any type and `false` removes text and keyword
+// fields. The setting of a group or object applies to all its fields, unless
+// they set it themselves. Disabled, not indexed, runtime and alias fields are
+// never added.
+func (i *IndexPatternGenerator) DefaultFields() ([]string, error) {
+	fields, err := loadFieldsFiles(i.fieldsYamls)
+	if err != nil {
+		return nil, err
+	}
+	return defaultFields(fields, "", nil), nil
+}
+
+// DefaultFieldSettings returns the index settings setting the default fields
+// as `index.query.default_field`, to be added to the index template.
+func (i *IndexPatternGenerator) DefaultFieldSettings() (common.MapStr, error) {
+	fields, err := i.DefaultFields()
+	if err != nil {
+		return nil, err
+	}
+	return common.MapStr{
+		"index": common.MapStr{
+			"query": common.MapStr{
+				"default_field": fields,
+			},
+		},
+	}, nil
+}
+
+// GenerateDefaultFieldSettings writes the DefaultFieldSettings as indented
+// JSON to path, creating its directory if needed.
+func (i *IndexPatternGenerator) GenerateDefaultFieldSettings(path string) error {
+	settings, err := i.DefaultFieldSettings()
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0644)
+}
+
+// defaultFields collects the default fields below path. inherited is the
+// default_field setting of the closest parent setting it.
+func defaultFields(fields common.Fields, path string, inherited *bool) []string {
+	var paths []string
+	for _, f := range fields {
+		if !fieldEnabled(f) {
+			continue
+		}
+
+		fieldPath := f.Name
+		if path != "" {
+			fieldPath = path + "." + f.Name
+		}
+
+		selected := inherited
+		if f.DefaultField != nil {
+			selected = f.DefaultField
+		}
+
+		if isContainer(f) {
+			paths = append(paths, defaultFields(f.Fields, fieldPath, selected)...)
+			continue
+		}
+
+		if f.Type == "object" {
+			if isDefaultField(common.Field{Type: f.ObjectType}, selected) {
+				paths = append(paths, fieldPath+".*")
+			}
+			continue
+		}
+
+		if isDefaultField(f, selected) {
+			paths = append(paths, fieldPath)
+		}
+		for _, mf := range f.MultiFields {
+			mfSelected := selected
+			if mf.DefaultField != nil {
+				mfSelected = mf.DefaultField
+			}
+			if isDefaultField(mf, mfSelected) {
+				paths = append(paths, fieldPath+"."+mf.Name)
+			}
+		}
+	}
+	return paths
+}
+
+// isDefaultField returns true if the field is searched by default. selected
+// is the default_field setting applying to the field, if any.
+func isDefaultField(f common.Field, selected *bool) bool {
+	switch {
+	case f.Type == "alias" || f.Runtime:
+		return false
+	case f.Index != nil && !*f.Index:
+		return false
+	case selected != nil:
+		return *selected
+	default:
+		return f.Type == "" || f.Type == "keyword" || f.Type == "text"
+	}
+}
@@ -0,0 +1,59 @@
+package kibana
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultFields(t *testing.T) {
+	beatDir := filepath.Join(tmpPath(), "default_field")
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "7.0.0")
+	require.NoError(t, err)
+
+	fields, err := generator.DefaultFields()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"message",
+		"message.raw",
+		"tags",
+		"source",
+		"status_code",
+		"labels.*",
+		"http.method",
+		"internal.name",
+		"user.name",
+		"user.roles.role",
+	}, fields)
+}
+
+func TestGenerateDefaultFieldSettings(t *testing.T) {
+	beatDir := filepath.Join(tmpPath(), "default_field")
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "7.0.0")
+	require.NoError(t, err)
+
+	tmpDir, err := ioutil.TempDir("", "default-field")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "settings", "default_field.json")
+	require.NoError(t, generator.GenerateDefaultFieldSettings(path))
+
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	var settings struct {
+		Index struct {
+			Query struct {
+				DefaultField []string `json:"default_field"`
+			} `json:"query"`
+		} `json:"index"`
+	}
+	require.NoError(t, json.Unmarshal(content, &settings))
+	assert.Len(t, settings.Index.Query.DefaultField, 10)
+	assert.Equal(t, "message", settings.Index.Query.DefaultField[0])
+}
@@ -0,0 +1,146 @@
+package kibana
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+)
+
+// PatternDiff reports the fields of an index pattern changed by generating it
+// again. The fields are listed in the order of the index patterns.
+type PatternDiff struct {
+	// Added are the names of the fields only in the generated index pattern.
+	Added []string
+
+	// Removed are the names of the fields only in the existing index pattern.
+	Removed []string
+
+	// Changed are the fields whose attributes differ.
+	Changed []FieldDiff
+}
+
+// FieldDiff reports the attributes of a field changed by generating the index
+// pattern again.
+type FieldDiff struct {
+	Name       string
+	Attributes []AttributeDiff
+}
+
+// AttributeDiff is an attribute of a field, like `aggregatable`, with its
+// existing and generated values. The value is nil if the attribute is missing
+// in the existing or generated field.
+type AttributeDiff struct {
+	Name      string
+	Existing  interface{}
+	Generated interface{}
+}
+
+// Empty returns true if no fields are added, removed or changed.
+func (d *PatternDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Diff compares the fields of an existing index pattern file with the index
+// pattern generated in memory, without writing any files. The existing file
+// is compared with the generated index pattern of the same format, so it can
+// be a 5.x, default or 8.x index pattern. In contrast to Check, only the
+// fields are compared, not their formats or the other attributes of the index
+// pattern.
+func (i *IndexPatternGenerator) Diff(existingPath string) (*PatternDiff, error) {
+	content, err := ioutil.ReadFile(existingPath)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := patternFile{path: existingPath, content: content}.indexPattern()
+	if err != nil {
+		return nil, fmt.Errorf("invalid index pattern %s: %v", existingPath, err)
+	}
+	if len(existing.Objects) != 1 {
+		return nil, fmt.Errorf("expected a single index pattern object in %s, found %d", existingPath, len(existing.Objects))
+	}
+
+	files, err := i.generateAll(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	objectType := existing.Objects[0].Type
+	for _, f := range files {
+		generated, err := f.indexPattern()
+		if err != nil {
+			return nil, err
+		}
+		if generated.Objects[0].Type == objectType {
+			return diffFields(existing.Objects[0].Attributes, generated.Objects[0].Attributes)
+		}
+	}
+	return nil, fmt.Errorf("no index pattern of the format of %s is generated for version %s", existingPath, i.version)
+}
+
+func diffFields(existing, generated IndexPatternAttributes) (*PatternDiff, error) {
+	before, err := existing.DecodeFields()
+	if err != nil {
+		return nil, fmt.Errorf("invalid fields of the existing index pattern: %v", err)
+	}
+	after, err := generated.DecodeFields()
+	if err != nil {
+		return nil, fmt.Errorf("invalid fields of the generated index pattern: %v", err)
+	}
+
+	beforeByName := fieldsByName(before)
+	afterByName := fieldsByName(after)
+
+	diff := &PatternDiff{}
+	for _, f := range before {
+		name, _ := f["name"].(string)
+		if _, found := afterByName[name]; !found {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	for _, f := range after {
+		name, _ := f["name"].(string)
+		b, found := beforeByName[name]
+		if !found {
+			diff.Added = append(diff.Added, name)
+			continue
+		}
+		if attributes := diffAttributes(b, f); len(attributes) > 0 {
+			diff.Changed = append(diff.Changed, FieldDiff{Name: name, Attributes: attributes})
+		}
+	}
+	return diff, nil
+}
+
+func fieldsByName(fields []map[string]interface{}) map[string]map[string]interface{} {
+	m := make(map[string]map[string]interface{}, len(fields))
+	for _, f := range fields {
+		name, _ := f["name"].(string)
+		m[name] = f
+	}
+	return m
+}
+
+// diffAttributes returns the attributes differing between two versions of a
+// field, sorted by name.
+func diffAttributes(existing, generated map[string]interface{}) []AttributeDiff {
+	names := make([]string, 0, len(existing)+len(generated))
+	for name := range existing {
+		names = append(names, name)
+	}
+	for name := range generated {
+		if _, found := existing[name]; !found {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var diffs []AttributeDiff
+	for _, name := range names {
+		before, after := existing[name], generated[name]
+		if !reflect.DeepEqual(before, after) {
+			diffs = append(diffs, AttributeDiff{Name: name, Existing: before, Generated: after})
+		}
+	}
+	return diffs
+}
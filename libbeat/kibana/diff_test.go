@@ -0,0 +1,135 @@
+package kibana
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func TestDiffMatching(t *testing.T) {
+	generator := newCheckGenerator(t, "matching")
+
+	for _, dir := range []string{generator.targetDir5x, generator.targetDirDefault} {
+		diff, err := generator.Diff(filepath.Join(dir, "beat.json"))
+		require.NoError(t, err, dir)
+		assert.True(t, diff.Empty(), dir)
+	}
+}
+
+func TestDiffDrifted(t *testing.T) {
+	generator := newCheckGenerator(t, "drifted")
+
+	for _, dir := range []string{generator.targetDir5x, generator.targetDirDefault} {
+		diff, err := generator.Diff(filepath.Join(dir, "beat.json"))
+		require.NoError(t, err, dir)
+		assert.Equal(t, []string{"status"}, diff.Added, dir)
+		assert.Equal(t, []string{"old"}, diff.Removed, dir)
+		assert.Empty(t, diff.Changed, dir)
+	}
+}
+
+func TestDiffChangedAttributes(t *testing.T) {
+	generator := newCheckGenerator(t, "matching")
+	patterns, err := generator.GenerateIndexPatterns()
+	require.NoError(t, err)
+
+	// Modify the generated default index pattern to get an existing one with
+	// changed attributes.
+	pattern := patterns[1]
+	fields, err := pattern.Objects[0].Attributes.DecodeFields()
+	require.NoError(t, err)
+	for _, f := range fields {
+		switch f["name"] {
+		case "message":
+			f["aggregatable"] = true
+		case "bytes":
+			delete(f, "searchable")
+			f["format"] = "bytes"
+		}
+	}
+	encoded, err := json.Marshal(fields)
+	require.NoError(t, err)
+	pattern.Objects[0].Attributes.Fields = string(encoded)
+
+	content, err := json.Marshal(pattern)
+	require.NoError(t, err)
+	path := writeTempPattern(t, content)
+	defer os.Remove(path)
+
+	diff, err := generator.Diff(path)
+	require.NoError(t, err)
+	assert.Empty(t, diff.Added)
+	assert.Empty(t, diff.Removed)
+	assert.Equal(t, []FieldDiff{
+		{
+			Name: "bytes",
+			Attributes: []AttributeDiff{
+				{Name: "format", Existing: "bytes"},
+				{Name: "searchable", Generated: true},
+			},
+		},
+		{
+			Name: "message",
+			Attributes: []AttributeDiff{
+				{Name: "aggregatable", Existing: true, Generated: false},
+			},
+		},
+	}, diff.Changed)
+}
+
+func TestDiffDoesNotWrite(t *testing.T) {
+	generator := newCheckGenerator(t, "drifted")
+	path := filepath.Join(generator.targetDirDefault, "beat.json")
+	before, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+
+	_, err = generator.Diff(path)
+	require.NoError(t, err)
+
+	after, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+}
+
+func TestDiffErrors(t *testing.T) {
+	generator := newCheckGenerator(t, "matching")
+
+	dataView, err := json.Marshal(common.MapStr{"type": "data-view", "attributes": common.MapStr{"fields": "[]"}})
+	require.NoError(t, err)
+	files := map[string][]byte{
+		"invalid json":   []byte("{"),
+		"invalid fields": []byte(`{"objects": [{"type": "index-pattern", "attributes": {"fields": "{"}}]}`),
+		// The data views are only generated for 8.0.0 and later.
+		"data view not generated": dataView,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := writeTempPattern(t, content)
+			defer os.Remove(path)
+
+			_, err := generator.Diff(path)
+			assert.Error(t, err)
+		})
+	}
+
+	_, err = generator.Diff(filepath.Join(generator.beatDir, "missing.json"))
+	assert.Error(t, err)
+}
+
+func writeTempPattern(t *testing.T, content []byte) string {
+	f, err := ioutil.TempFile("", "index-pattern")
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, err = f.Write(content)
+	require.NoError(t, err)
+	return f.Name()
+}
@@ -0,0 +1,63 @@
+package kibana
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// Fields are the parsed fields of fields.yml files. They are loaded once by
+// LoadFields, and can be used to generate the index patterns of several index
+// names with GenerateFromFields, also concurrently. Fields are never modified
+// after loading.
+type Fields struct {
+	fields common.Fields
+}
+
+// LoadFields parses the fields.yml of the beat directory.
+func LoadFields(beatDir string) (*Fields, error) {
+	return LoadFieldsFromFiles([]string{filepath.Join(beatDir, "fields.yml")})
+}
+
+// LoadFieldsFromFiles parses and concatenates the fields of several fields.yml
+// files, like NewGeneratorFromFiles. Each field can only be defined in one of
+// the files.
+func LoadFieldsFromFiles(fieldsYamls []string) (*Fields, error) {
+	if len(fieldsYamls) == 0 {
+		return nil, errors.New("no fields.yml files given")
+	}
+	for _, fieldsYaml := range fieldsYamls {
+		if _, err := os.Stat(fieldsYaml); err != nil {
+			return nil, err
+		}
+	}
+
+	fields, err := loadFieldsFiles(fieldsYamls)
+	if err != nil {
+		return nil, err
+	}
+	return &Fields{fields: fields}, nil
+}
+
+// GenerateFromFields creates the Index-Pattern for Kibana for 5.x, default and
+// 8.x of the index name from fields loaded by LoadFields, instead of the
+// fields.yml files of the generator. The index patterns are titled title, or
+// the index name if title is empty. The other settings of the generator, like
+// the version and time field, apply as for Generate. No files are written, so
+// the generator and fields can be used by several goroutines at the same time,
+// as long as the settings of the generator are not changed.
+func (i *IndexPatternGenerator) GenerateFromFields(fields *Fields, indexName, title string) ([]IndexPattern, error) {
+	indexName = cleanIndexName(indexName)
+	if title == "" {
+		title = indexName
+	}
+
+	files, err := i.generatePatterns(context.Background(), cleanID(indexName), title, i.targetFilename, fields.fields)
+	if err != nil {
+		return nil, err
+	}
+	return indexPatterns(files, i.moduleTags)
+}
@@ -0,0 +1,86 @@
+package kibana
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFields(t *testing.T) {
+	beatDir := tmpPath()
+
+	fields, err := LoadFields(beatDir)
+	require.NoError(t, err)
+	assert.NotEmpty(t, fields.fields)
+
+	_, err = LoadFields(filepath.Join(beatDir, "notexistent"))
+	assert.Error(t, err)
+	_, err = LoadFieldsFromFiles(nil)
+	assert.Error(t, err)
+
+	// fields defined in several files are rejected as by the generator
+	_, err = LoadFieldsFromFiles([]string{
+		filepath.Join(beatDir, "fields.yml"),
+		filepath.Join(beatDir, "fields.yml"),
+	})
+	assert.Error(t, err)
+}
+
+func TestGenerateFromFields(t *testing.T) {
+	beatDir := tmpPath()
+	defer teardown(beatDir)
+
+	fields, err := LoadFields(beatDir)
+	require.NoError(t, err)
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "8.0.0-alpha1")
+	require.NoError(t, err)
+
+	patterns, err := generator.GenerateFromFields(fields, "other-*", "")
+	require.NoError(t, err)
+	require.Len(t, patterns, 3)
+	assert.Equal(t, "other-*", patterns[1].Objects[0].Attributes.Title)
+	assert.Equal(t, "other-*", patterns[1].Objects[0].ID)
+
+	patterns, err = generator.GenerateFromFields(fields, "other-*", "Other")
+	require.NoError(t, err)
+	assert.Equal(t, "Other", patterns[1].Objects[0].Attributes.Title)
+	assert.Equal(t, "other-*", patterns[1].Objects[0].ID)
+}
+
+func TestGenerateFromFieldsConcurrently(t *testing.T) {
+	beatDir := tmpPath()
+	defer teardown(beatDir)
+
+	fields, err := LoadFields(beatDir)
+	require.NoError(t, err)
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "8.0.0-alpha1")
+	require.NoError(t, err)
+
+	const n = 8
+	results := make([][]IndexPattern, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = generator.GenerateFromFields(fields, fmt.Sprintf("beat-%d-*", i), "")
+		}(i)
+	}
+	wg.Wait()
+
+	// each result is the same as generated from the fields.yml for the index name
+	for i := 0; i < n; i++ {
+		require.NoError(t, errs[i])
+		indexName := fmt.Sprintf("beat-%d-*", i)
+		expectedGenerator, err := NewGenerator(indexName, "beat", beatDir, "8.0.0-alpha1")
+		require.NoError(t, err)
+		expected, err := expectedGenerator.GenerateIndexPatterns()
+		require.NoError(t, err)
+		assert.Equal(t, expected, results[i], "index %v", indexName)
+	}
+}
@@ -0,0 +1,148 @@
+package kibana
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+	setup "github.com/elastic/beats/libbeat/setup/kibana"
+)
+
+const (
+	bulkCreateAPI = "/api/saved_objects/_bulk_create"
+	importAPI     = "/api/saved_objects/_import"
+)
+
+// Import imports the objects of a generated index pattern into Kibana, and
+// returns the decoded response. The objects must match the version of Kibana,
+// which is the version reported by the client: the objects of the default
+// index pattern are created with the saved objects API of Kibana 6.x and 7.x,
+// data views are imported with the import API of Kibana 8.x. Kibana 5.x has no
+// saved objects API, its index patterns are loaded into Elasticsearch.
+// Existing objects are overwritten.
+func Import(client *setup.Client, pattern IndexPattern) (common.MapStr, error) {
+	version, err := common.NewVersion(client.GetVersion())
+	if err != nil {
+		return nil, fmt.Errorf("invalid Kibana version '%v': %v", client.GetVersion(), err)
+	}
+	if version.Major < 6 {
+		return nil, fmt.Errorf("importing index patterns requires Kibana 6.0 or newer, found %v", version)
+	}
+
+	if len(pattern.Objects) == 0 {
+		return nil, errors.New("index pattern has no objects to import")
+	}
+	for _, object := range pattern.Objects {
+		if object.Type == "" || object.ID == "" {
+			return nil, fmt.Errorf("index pattern '%v' has no type or id, it can not be imported into Kibana %v",
+				object.Attributes.Title, version)
+		}
+	}
+
+	if version.Major >= 8 {
+		return importObjects(client, pattern.Objects)
+	}
+	return bulkCreateObjects(client, pattern.Objects)
+}
+
+// bulkCreateObjects creates the objects with the saved objects API.
+func bulkCreateObjects(client *setup.Client, objects []IndexPatternObject) (common.MapStr, error) {
+	type createObject struct {
+		Type       string                 `json:"type"`
+		ID         string                 `json:"id"`
+		Attributes IndexPatternAttributes `json:"attributes"`
+	}
+
+	body := make([]createObject, len(objects))
+	for i, object := range objects {
+		body[i] = createObject{Type: object.Type, ID: object.ID, Attributes: object.Attributes}
+	}
+	content, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("fail to marshal the index pattern: %v", err)
+	}
+
+	params := url.Values{}
+	params.Set("overwrite", "true")
+	headers := http.Header{}
+	headers.Set("kbn-xsrf", "true")
+
+	response, err := request(client, bulkCreateAPI, params, headers, content)
+	if err != nil {
+		return nil, err
+	}
+
+	// Objects failing to be created are reported with an error per object.
+	if saved, ok := response["saved_objects"].([]interface{}); ok {
+		var failed []string
+		for _, s := range saved {
+			object, _ := s.(map[string]interface{})
+			if cause, exists := object["error"]; exists {
+				failed = append(failed, fmt.Sprintf("%v: %v", object["id"], cause))
+			}
+		}
+		if len(failed) > 0 {
+			return response, fmt.Errorf("fail to create the index pattern objects: %v", strings.Join(failed, "; "))
+		}
+	}
+	return response, nil
+}
+
+// importObjects imports the objects with the import API, which reads the
+// objects from an NDJSON file uploaded as multipart form.
+func importObjects(client *setup.Client, objects []IndexPatternObject) (common.MapStr, error) {
+	var ndjson bytes.Buffer
+	for _, object := range objects {
+		line, err := json.Marshal(object)
+		if err != nil {
+			return nil, fmt.Errorf("fail to marshal the index pattern: %v", err)
+		}
+		ndjson.Write(line)
+		ndjson.WriteByte('\n')
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, err := form.CreateFormFile("file", "index-pattern.ndjson")
+	if err != nil {
+		return nil, err
+	}
+	file.Write(ndjson.Bytes())
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Set("overwrite", "true")
+	headers := http.Header{}
+	headers.Set("kbn-xsrf", "true")
+	headers.Set("Content-Type", form.FormDataContentType())
+
+	response, err := request(client, importAPI, params, headers, body.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if success, _ := response["success"].(bool); !success {
+		return response, fmt.Errorf("fail to import the index pattern objects: %v", response["errors"])
+	}
+	return response, nil
+}
+
+func request(client *setup.Client, path string, params url.Values, headers http.Header, body []byte) (common.MapStr, error) {
+	status, result, err := client.RequestWithHeaders("POST", path, params, headers, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("POST %v fails: %v. Response: %s", path, err, result)
+	}
+
+	var response common.MapStr
+	if err := json.Unmarshal(result, &response); err != nil {
+		return nil, fmt.Errorf("fail to unmarshal the response of POST %v (status %v): %v", path, status, err)
+	}
+	return response, nil
+}
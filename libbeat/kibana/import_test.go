@@ -0,0 +1,165 @@
+package kibana
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+	setup "github.com/elastic/beats/libbeat/setup/kibana"
+)
+
+type importRequest struct {
+	path   string
+	query  string
+	header http.Header
+	body   []byte
+}
+
+// newMockKibana returns a Kibana server of the version, recording the import
+// requests and answering them with response.
+func newMockKibana(t *testing.T, version, response string) (*httptest.Server, *[]importRequest) {
+	var requests []importRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/status" {
+			fmt.Fprintf(w, `{"name": "kibana", "version": {"number": "%s"}}`, version)
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		requests = append(requests, importRequest{
+			path:   r.URL.Path,
+			query:  r.URL.RawQuery,
+			header: r.Header,
+			body:   body,
+		})
+		w.Write([]byte(response))
+	}))
+	return server, &requests
+}
+
+func newTestClient(t *testing.T, url string) *setup.Client {
+	cfg, err := common.NewConfigFrom(map[string]interface{}{"host": url})
+	require.NoError(t, err)
+	client, err := setup.NewKibanaClient(cfg)
+	require.NoError(t, err)
+	return client
+}
+
+func generateTestPatterns(t *testing.T, version string) []IndexPattern {
+	beatDir := tmpPath()
+	defer teardown(beatDir)
+	generator, err := NewGenerator("beat-*", "b eat ?!", beatDir, version)
+	require.NoError(t, err)
+	patterns, err := generator.GenerateIndexPatterns()
+	require.NoError(t, err)
+	return patterns
+}
+
+func TestImportBulkCreate(t *testing.T) {
+	server, requests := newMockKibana(t, "7.0.0",
+		`{"saved_objects": [{"id": "beat-*", "type": "index-pattern"}]}`)
+	defer server.Close()
+
+	pattern := generateTestPatterns(t, "7.0.0")[1]
+	response, err := Import(newTestClient(t, server.URL), pattern)
+	require.NoError(t, err)
+	assert.Equal(t, "beat-*", response["saved_objects"].([]interface{})[0].(map[string]interface{})["id"])
+
+	require.Len(t, *requests, 1)
+	req := (*requests)[0]
+	assert.Equal(t, "/api/saved_objects/_bulk_create", req.path)
+	assert.Equal(t, "overwrite=true", req.query)
+	assert.Equal(t, "true", req.header.Get("kbn-xsrf"))
+	assert.Equal(t, "application/json", req.header.Get("Content-Type"))
+
+	var objects []struct {
+		Type       string                 `json:"type"`
+		ID         string                 `json:"id"`
+		Attributes IndexPatternAttributes `json:"attributes"`
+	}
+	require.NoError(t, json.Unmarshal(req.body, &objects))
+	require.Len(t, objects, 1)
+	assert.Equal(t, "index-pattern", objects[0].Type)
+	assert.Equal(t, "beat-*", objects[0].ID)
+	assert.Equal(t, pattern.Objects[0].Attributes, objects[0].Attributes)
+}
+
+func TestImportBulkCreateFailure(t *testing.T) {
+	server, _ := newMockKibana(t, "6.5.0",
+		`{"saved_objects": [{"id": "beat-*", "error": {"statusCode": 409, "message": "conflict"}}]}`)
+	defer server.Close()
+
+	_, err := Import(newTestClient(t, server.URL), generateTestPatterns(t, "7.0.0")[1])
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "conflict")
+	}
+}
+
+func TestImportDataView(t *testing.T) {
+	server, requests := newMockKibana(t, "8.1.0", `{"success": true, "successCount": 1}`)
+	defer server.Close()
+
+	pattern := generateTestPatterns(t, "8.0.0-alpha1")[2]
+	response, err := Import(newTestClient(t, server.URL), pattern)
+	require.NoError(t, err)
+	assert.Equal(t, common.MapStr{"success": true, "successCount": float64(1)}, response)
+
+	require.Len(t, *requests, 1)
+	req := (*requests)[0]
+	assert.Equal(t, "/api/saved_objects/_import", req.path)
+	assert.Equal(t, "overwrite=true", req.query)
+	assert.Equal(t, "true", req.header.Get("kbn-xsrf"))
+	assert.True(t, strings.HasPrefix(req.header.Get("Content-Type"), "multipart/form-data; boundary="))
+
+	// The objects are uploaded as NDJSON file.
+	r, err := http.NewRequest("POST", "/", strings.NewReader(string(req.body)))
+	require.NoError(t, err)
+	r.Header = req.header
+	file, _, err := r.FormFile("file")
+	require.NoError(t, err)
+	content, err := ioutil.ReadAll(file)
+	require.NoError(t, err)
+
+	var object IndexPatternObject
+	require.NoError(t, json.Unmarshal(content, &object))
+	assert.Equal(t, "data-view", object.Type)
+	assert.Equal(t, "beat-*", object.ID)
+	assert.Equal(t, pattern.Objects[0].Attributes, object.Attributes)
+	assert.True(t, strings.HasSuffix(string(content), "}\n"))
+}
+
+func TestImportDataViewFailure(t *testing.T) {
+	server, _ := newMockKibana(t, "8.1.0", `{"success": false, "errors": [{"id": "beat-*"}]}`)
+	defer server.Close()
+
+	_, err := Import(newTestClient(t, server.URL), generateTestPatterns(t, "8.0.0-alpha1")[2])
+	assert.Error(t, err)
+}
+
+func TestImportUnsupported(t *testing.T) {
+	server, requests := newMockKibana(t, "6.0.0", `{}`)
+	defer server.Close()
+	client := newTestClient(t, server.URL)
+
+	// 5.x index patterns have no type and id.
+	_, err := Import(client, generateTestPatterns(t, "7.0.0")[0])
+	assert.Error(t, err)
+
+	_, err = Import(client, IndexPattern{})
+	assert.Error(t, err)
+	assert.Len(t, *requests, 0)
+
+	server5x, _ := newMockKibana(t, "5.6.0", `{}`)
+	defer server5x.Close()
+	_, err = Import(newTestClient(t, server5x.URL), generateTestPatterns(t, "7.0.0")[1])
+	assert.Error(t, err)
+}
@@ -0,0 +1,247 @@
+package kibana
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// IndexPattern is a generated index pattern, in the format of the saved
+// objects exported by Kibana. The 5.x index patterns and the 8.x data views,
+// which are written as a single object or its attributes, are returned with a
+// single object, so the index patterns of all versions are accessed alike.
+type IndexPattern struct {
+	// Path is the file the index pattern is written to by Generate.
+	Path string `json:"-"`
+
+	// Version is the version of the beat. It is only set for the default
+	// index pattern.
+	Version string `json:"version,omitempty"`
+
+	Objects []IndexPatternObject `json:"objects"`
+
+	// Modules maps the names of the fields and runtime fields to their
+	// module, the top-level group of fields.yml defining them. It is only set
+	// if enabled by SetModuleTags, and is not part of the index pattern.
+	Modules map[string]string `json:"-"`
+}
+
+// IndexPatternObject is the saved object of an index pattern or data view.
+type IndexPatternObject struct {
+	// Type is `index-pattern`, or `data-view` for Kibana 8.x. It is empty for
+	// Kibana 5.x.
+	Type string `json:"type,omitempty"`
+	ID   string `json:"id,omitempty"`
+
+	// Version is the version of the saved object. It is not set for data
+	// views, which have a CoreMigrationVersion instead.
+	Version              int    `json:"version,omitempty"`
+	CoreMigrationVersion string `json:"coreMigrationVersion,omitempty"`
+
+	Attributes IndexPatternAttributes `json:"attributes"`
+	References []interface{}          `json:"references,omitempty"`
+}
+
+// IndexPatternAttributes are the attributes of an index pattern. The fields,
+// their formats and attributes are JSON encoded like in the saved objects, use
+// DecodeFields to access the fields.
+type IndexPatternAttributes struct {
+	Title          string `json:"title"`
+	TimeFieldName  string `json:"timeFieldName"`
+	Fields         string `json:"fields"`
+	FieldFormatMap string `json:"fieldFormatMap"`
+
+	// FieldAttrs is only set if enabled by SetFieldAttrs.
+	FieldAttrs string `json:"fieldAttrs,omitempty"`
+
+	// RuntimeFieldMap is only set if runtime fields are defined, for Kibana
+	// 7.11.0 and later.
+	RuntimeFieldMap string `json:"runtimeFieldMap,omitempty"`
+}
+
+// DecodeFields returns the decoded fields of the index pattern.
+func (a IndexPatternAttributes) DecodeFields() ([]map[string]interface{}, error) {
+	var fields []map[string]interface{}
+	if err := json.Unmarshal([]byte(a.Fields), &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// FilterModules returns a copy of the index pattern restricted to the fields
+// of the modules, so only the fields of some modules of a Beat can be imported
+// into Kibana. The meta fields and the time field are kept. The formats,
+// attributes and runtime fields are filtered like the fields. The index
+// pattern must be tagged with the modules of its fields, see SetModuleTags.
+func (p IndexPattern) FilterModules(modules ...string) (IndexPattern, error) {
+	if p.Modules == nil {
+		return IndexPattern{}, errors.New("index pattern has no module tags, enable them with SetModuleTags")
+	}
+
+	selected := map[string]bool{}
+	for _, module := range modules {
+		selected[module] = true
+	}
+	found := map[string]bool{}
+	for _, module := range p.Modules {
+		found[module] = true
+	}
+	var unknown []string
+	for _, module := range modules {
+		if !found[module] {
+			unknown = append(unknown, module)
+		}
+	}
+	if len(unknown) > 0 {
+		return IndexPattern{}, fmt.Errorf("no fields found for modules %s", strings.Join(unknown, ", "))
+	}
+
+	filtered := p
+	filtered.Objects = make([]IndexPatternObject, len(p.Objects))
+	filtered.Modules = map[string]string{}
+	for name, module := range p.Modules {
+		if selected[module] {
+			filtered.Modules[name] = module
+		}
+	}
+
+	for i, object := range p.Objects {
+		a := object.Attributes
+		keep := func(name string) bool {
+			if module, ok := p.Modules[name]; ok {
+				return selected[module]
+			}
+			return strings.HasPrefix(name, "_") || name == a.TimeFieldName
+		}
+
+		var fields []json.RawMessage
+		if err := json.Unmarshal([]byte(a.Fields), &fields); err != nil {
+			return IndexPattern{}, err
+		}
+		kept := make([]json.RawMessage, 0, len(fields))
+		for _, field := range fields {
+			var f struct {
+				Name string `json:"name"`
+			}
+			if err := json.Unmarshal(field, &f); err != nil {
+				return IndexPattern{}, err
+			}
+			if keep(f.Name) {
+				kept = append(kept, field)
+			}
+		}
+		fieldsBytes, err := json.Marshal(kept)
+		if err != nil {
+			return IndexPattern{}, err
+		}
+		a.Fields = string(fieldsBytes)
+
+		if a.FieldFormatMap, err = filterFieldMap(a.FieldFormatMap, keep); err != nil {
+			return IndexPattern{}, err
+		}
+		if a.FieldAttrs, err = filterFieldMap(a.FieldAttrs, keep); err != nil {
+			return IndexPattern{}, err
+		}
+		if a.RuntimeFieldMap, err = filterFieldMap(a.RuntimeFieldMap, keep); err != nil {
+			return IndexPattern{}, err
+		}
+		// Like for generated index patterns, the runtimeFieldMap is omitted
+		// without runtime fields.
+		if a.RuntimeFieldMap == "{}" {
+			a.RuntimeFieldMap = ""
+		}
+
+		object.Attributes = a
+		filtered.Objects[i] = object
+	}
+	return filtered, nil
+}
+
+// filterFieldMap keeps the entries of a JSON encoded object by field name.
+// Empty attributes are returned as is.
+func filterFieldMap(encoded string, keep func(name string) bool) (string, error) {
+	if encoded == "" {
+		return "", nil
+	}
+
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(encoded), &m); err != nil {
+		return "", err
+	}
+	for name := range m {
+		if !keep(name) {
+			delete(m, name)
+		}
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// GenerateIndexPatterns creates the Index-Pattern for Kibana for 5.x, default
+// and 8.x like GenerateInMemory, but returns them as typed index patterns.
+func (i *IndexPatternGenerator) GenerateIndexPatterns() ([]IndexPattern, error) {
+	files, err := i.generateAll(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return indexPatterns(files, i.moduleTags)
+}
+
+// indexPatterns decodes the index patterns of the files, tagged with the
+// modules of their fields if tagModules is set.
+func indexPatterns(files []patternFile, tagModules bool) ([]IndexPattern, error) {
+	patterns := make([]IndexPattern, 0, len(files))
+	for _, f := range files {
+		pattern, err := f.indexPattern()
+		if err != nil {
+			return nil, err
+		}
+		if tagModules {
+			pattern.Modules = f.modules
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// indexPattern decodes the content of the file into an IndexPattern. The
+// format is told apart by the keys of the file: default index patterns have
+// a list of objects, data views are an object with a type, 5.x index patterns
+// only have the attributes.
+func (f patternFile) indexPattern() (IndexPattern, error) {
+	var probe struct {
+		Objects json.RawMessage `json:"objects"`
+		Type    string          `json:"type"`
+	}
+	if err := json.Unmarshal(f.content, &probe); err != nil {
+		return IndexPattern{}, err
+	}
+
+	pattern := IndexPattern{Path: f.path}
+	switch {
+	case probe.Objects != nil:
+		if err := json.Unmarshal(f.content, &pattern); err != nil {
+			return IndexPattern{}, err
+		}
+
+	case probe.Type != "":
+		var object IndexPatternObject
+		if err := json.Unmarshal(f.content, &object); err != nil {
+			return IndexPattern{}, err
+		}
+		pattern.Objects = []IndexPatternObject{object}
+
+	default:
+		var attributes IndexPatternAttributes
+		if err := json.Unmarshal(f.content, &attributes); err != nil {
+			return IndexPattern{}, err
+		}
+		pattern.Objects = []IndexPatternObject{{Attributes: attributes}}
+	}
+	return pattern, nil
+}
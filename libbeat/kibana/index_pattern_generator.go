@@ -1,142 +1,778 @@
 package kibana
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/elastic/beats/libbeat/common"
 )
 
 type IndexPatternGenerator struct {
+	// TimeFieldName is the name of the time field of the index patterns. It
+	// defaults to `@timestamp`.
+	TimeFieldName string
+
+	// Formats are the formats of the index patterns generated, `5.x`,
+	// `default` and `8.x`. Only the directories of these formats are
+	// created. It defaults to all formats supported by the version.
+	Formats common.StringSet
+
 	indexName        string
+	beatName         string
+	title            string
+	id               string
 	version          string
-	fieldsYaml       string
+	beatDir          string
+	fieldsYamls      []string
 	targetDirDefault string
 	targetDir5x      string
+	targetDir8x      string
 	targetFilename   string
+	fieldAttrs       bool
+	locale           string
+	keepSafeChars    bool
+	validate         bool
+	moduleTags       bool
 }
 
-// Create an instance of the Kibana Index Pattern Generator
+// Create an instance of the Kibana Index Pattern Generator. For versions 8.0.0
+// and later, a data view for Kibana 8.x is generated in addition to the 5.x and
+// default index patterns. The version must be a semantic version like 7.0.0,
+// optionally with a pre-release suffix like 7.0.0-alpha1. The target
+// directories are created by Generate. Set Formats to generate only some of
+// the formats.
 func NewGenerator(indexName, beatName, beatDir, version string) (*IndexPatternGenerator, error) {
-	beatName = clean(beatName)
+	return NewGeneratorFromFiles(indexName, beatName, beatDir, version, []string{filepath.Join(beatDir, "fields.yml")})
+}
 
-	fieldsYaml := filepath.Join(beatDir, "fields.yml")
-	if _, err := os.Stat(fieldsYaml); err != nil {
-		return nil, err
+// NewGeneratorFromFiles creates an instance of the Kibana Index Pattern
+// Generator for the fields of several fields.yml files, instead of the
+// fields.yml of the beat directory. The fields of the files are concatenated
+// in the order of the files, and each field can only be defined in one of
+// them. The index patterns are written to the beat directory like for
+// NewGenerator.
+func NewGeneratorFromFiles(indexName, beatName, beatDir, version string, fieldsYamls []string) (*IndexPatternGenerator, error) {
+	// The version is parsed like for selecting the format of the index
+	// patterns, so invalid versions are reported before generating them.
+	if _, err := common.NewVersion(version); err != nil {
+		return nil, fmt.Errorf("invalid version '%v': %v", version, err)
 	}
 
-	return &IndexPatternGenerator{
-		indexName:        indexName,
+	if len(fieldsYamls) == 0 {
+		return nil, errors.New("no fields.yml files given")
+	}
+	for _, fieldsYaml := range fieldsYamls {
+		if _, err := os.Stat(fieldsYaml); err != nil {
+			return nil, err
+		}
+	}
+
+	generator := &IndexPatternGenerator{
+		TimeFieldName:    defaultTimeFieldName,
+		Formats:          common.MakeStringSet("5.x", "default"),
+		indexName:        cleanIndexName(indexName),
+		beatName:         beatName,
 		version:          version,
-		fieldsYaml:       fieldsYaml,
-		targetDirDefault: createTargetDir(beatDir, "default"),
-		targetDir5x:      createTargetDir(beatDir, "5.x"),
-		targetFilename:   beatName + ".json",
-	}, nil
+		beatDir:          beatDir,
+		fieldsYamls:      fieldsYamls,
+		targetDirDefault: targetDir(beatDir, "default", "index-pattern"),
+		targetDir5x:      targetDir(beatDir, "5.x", "index-pattern"),
+		targetFilename:   clean(beatName, false) + ".json",
+	}
+	if supportsDataViews(version) {
+		generator.Formats.Add("8.x")
+		generator.targetDir8x = targetDir(beatDir, "8.x", "data-view")
+	}
+	return generator, nil
+}
+
+// SetFieldAttrs enables adding the labels and descriptions of fields to the
+// `fieldAttrs` attribute of the default index pattern, so Kibana shows them as
+// custom labels and tooltips. Kibana supports fieldAttrs since 7.11.0, they are
+// not added for older versions, nor to the 5.x index pattern.
+func (i *IndexPatternGenerator) SetFieldAttrs(enabled bool) {
+	i.fieldAttrs = enabled
+}
+
+// SetLocale selects the localized labels of the fields added to fieldAttrs,
+// like `de` or `pt-BR`. Fields without a label for the locale, nor for its
+// language, use their default label.
+func (i *IndexPatternGenerator) SetLocale(locale string) {
+	i.locale = locale
+}
+
+// SetKeepSafeChars enables keeping hyphens, in addition to the underscores
+// kept by default, when the beat name and namespaces are cleaned for the file
+// names of the index patterns. Like this, a beat named `my-custom-beat` is not
+// written to the same file as a beat named `mycustombeat`.
+func (i *IndexPatternGenerator) SetKeepSafeChars(enabled bool) {
+	i.keepSafeChars = enabled
+	i.targetFilename = clean(i.beatName, enabled) + ".json"
+}
+
+// SetValidate enables validating the generated index patterns with Validate
+// before they are written by Generate, so patterns Kibana would reject are not
+// written.
+func (i *IndexPatternGenerator) SetValidate(enabled bool) {
+	i.validate = enabled
+}
+
+// SetModuleTags enables tagging the fields of the index patterns returned by
+// GenerateIndexPatterns and GenerateFromFields with their module, the
+// top-level group of fields.yml defining them, like `system` in Metricbeat.
+// The tags are required to restrict an index pattern to some modules with
+// FilterModules. They are not part of the index patterns, so the written
+// index patterns are not affected.
+func (i *IndexPatternGenerator) SetModuleTags(enabled bool) {
+	i.moduleTags = enabled
+}
+
+// SetTitle sets the title of the generated index patterns, instead of the
+// index name. The ids of the index patterns are still derived from the index
+// name. The title does not apply to the namespaced index patterns created by
+// GenerateNamespace, which are titled by their namespaced index name, so they
+// can be told apart in Kibana.
+func (i *IndexPatternGenerator) SetTitle(title string) {
+	i.title = title
+}
+
+// SetID sets the id of the generated index patterns, used verbatim instead of
+// the id derived from the index name. Like this, several index patterns of the
+// same beat can be imported with stable, distinct ids. An empty id restores
+// the derived id. Like the title, the id does not apply to the namespaced
+// index patterns created by GenerateNamespace, nor to the index patterns
+// created by GenerateFromFields, as they would share the id otherwise. The
+// 5.x index patterns have no id.
+func (i *IndexPatternGenerator) SetID(id string) {
+	i.id = id
+}
+
+// SetTargetDir sets the directory the index pattern of a format is written to,
+// instead of its directory in `_meta/kibana` of the beat directory. The
+// formats are `5.x`, `default` and `8.x`, the latter only for versions
+// generating data views. The directory is created by Generate if it does not
+// exist. Formats must not share a directory, as their files have the same
+// name.
+func (i *IndexPatternGenerator) SetTargetDir(format, dir string) error {
+	switch format {
+	case "5.x":
+		i.targetDir5x = dir
+	case "default":
+		i.targetDirDefault = dir
+	case "8.x":
+		if i.targetDir8x == "" {
+			return fmt.Errorf("data views for Kibana 8.x are not generated for version %s", i.version)
+		}
+		i.targetDir8x = dir
+	default:
+		return fmt.Errorf("unknown index pattern format '%s', use 5.x, default or 8.x", format)
+	}
+	return nil
+}
+
+// patternFile is a generated index pattern and the path it is written to.
+type patternFile struct {
+	path    string
+	format  string
+	pattern common.MapStr
+	content []byte
+	stats   FormatStats
+	// modules maps the names of the fields to their module.
+	modules map[string]string
+}
+
+// GenerateStats reports the fields of the index patterns generated, by
+// format.
+type GenerateStats struct {
+	Formats map[string]FormatStats
+}
+
+// FormatStats counts the fields of the index pattern of a format. The counts
+// match the generated index pattern.
+type FormatStats struct {
+	Fields       int // entries of `fields`, including the meta fields and multi-fields
+	Skipped      int // disabled fields, and runtime fields not supported by the format
+	FieldFormats int // entries of `fieldFormatMap`
 }
 
-// Create the Index-Pattern for Kibana for 5.x and default.
+// Create the Index-Pattern for Kibana for 5.x, default and 8.x, or only for
+// the Formats set.
 func (i *IndexPatternGenerator) Generate() ([]string, error) {
-	commonFields, err := common.LoadFieldsYaml(i.fieldsYaml)
+	return i.GenerateContext(context.Background())
+}
+
+// GenerateContext creates the Index-Pattern for Kibana for 5.x, default and
+// 8.x like Generate, stopping once ctx is done. The context is checked
+// between the groups of fields and before writing each file, and its error is
+// returned on cancellation. Files written before the cancellation are kept.
+func (i *IndexPatternGenerator) GenerateContext(ctx context.Context) ([]string, error) {
+	paths, _, err := i.generateAndWrite(ctx)
+	return paths, err
+}
+
+// GenerateWithStats creates the Index-Pattern for Kibana for 5.x, default and
+// 8.x like Generate, and also returns the number of fields added to each
+// index pattern. Duplicated fields fail the generation like for Generate, so
+// they are not counted as skipped.
+func (i *IndexPatternGenerator) GenerateWithStats() ([]string, GenerateStats, error) {
+	paths, files, err := i.generateAndWrite(context.Background())
+	if err != nil {
+		return nil, GenerateStats{}, err
+	}
+
+	stats := GenerateStats{Formats: make(map[string]FormatStats, len(files))}
+	for _, f := range files {
+		stats.Formats[f.format] = f.stats
+	}
+	return paths, stats, nil
+}
+
+func (i *IndexPatternGenerator) generateAndWrite(ctx context.Context) ([]string, []patternFile, error) {
+	files, err := i.generateAll(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if i.validate {
+		for _, f := range files {
+			if err := Validate(f.pattern); err != nil {
+				return nil, nil, fmt.Errorf("invalid index pattern %s: %v", f.path, err)
+			}
+		}
+	}
+	if err := i.createTargetDirs(); err != nil {
+		return nil, nil, err
+	}
+	paths, err := writeFiles(ctx, files)
+	if err != nil {
+		return nil, nil, err
+	}
+	return paths, files, nil
+}
+
+// GenerateInMemory creates the Index-Pattern for Kibana for 5.x, default and
+// 8.x like Generate, but returns the index patterns instead of writing them.
+// No files or directories are created in the beat directory. See
+// GenerateIndexPatterns for typed index patterns.
+func (i *IndexPatternGenerator) GenerateInMemory() ([]common.MapStr, error) {
+	files, err := i.generateAll(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	patterns := make([]common.MapStr, 0, len(files))
+	for _, f := range files {
+		patterns = append(patterns, f.pattern)
+	}
+	return patterns, nil
+}
+
+// GenerateNamespace creates the Index-Pattern for Kibana for 5.x, default and
+// 8.x restricted to the fields of a single namespace, like a metricbeat module.
+// The namespace is added to the index name, so `metricbeat-*` becomes
+// `metricbeat-system-*` for the namespace `system`.
+func (i *IndexPatternGenerator) GenerateNamespace(namespace string) ([]string, error) {
+	files, err := i.generateNamespace(context.Background(), namespace)
 	if err != nil {
 		return nil, err
 	}
+	if err := i.createTargetDirs(); err != nil {
+		return nil, err
+	}
+	return writeFiles(context.Background(), files)
+}
 
-	index5xPath, err := i.generate5x(commonFields)
+// GenerateBytes creates the Index-Pattern for Kibana for 5.x, default and 8.x
+// without writing them. The content of the index patterns is returned by the
+// path of the file they are written to by Generate.
+func (i *IndexPatternGenerator) GenerateBytes() (map[string][]byte, error) {
+	files, err := i.generateAll(context.Background())
 	if err != nil {
 		return nil, err
 	}
+	return filesToMap(files), nil
+}
 
-	index6xPath, err := i.generate6x(commonFields)
+// GenerateNamespaceBytes creates the Index-Pattern for Kibana for 5.x, default
+// and 8.x of a single namespace without writing them, like GenerateBytes.
+func (i *IndexPatternGenerator) GenerateNamespaceBytes(namespace string) (map[string][]byte, error) {
+	files, err := i.generateNamespace(context.Background(), namespace)
 	if err != nil {
 		return nil, err
 	}
+	return filesToMap(files), nil
+}
+
+func (i *IndexPatternGenerator) generateAll(ctx context.Context) ([]patternFile, error) {
+	commonFields, err := loadFieldsFiles(i.fieldsYamls)
+	if err != nil {
+		return nil, err
+	}
+
+	title := i.indexName
+	if i.title != "" {
+		title = i.title
+	}
+	id := cleanID(i.indexName)
+	if i.id != "" {
+		id = i.id
+	}
+	return i.generatePatterns(ctx, id, title, i.targetFilename, commonFields)
+}
+
+func (i *IndexPatternGenerator) generateNamespace(ctx context.Context, namespace string) ([]patternFile, error) {
+	commonFields, err := loadFieldsFiles(i.fieldsYamls)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := filterNamespace(commonFields, namespace, "")
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields found for namespace %s", namespace)
+	}
+	// The time field is also available in namespaced patterns.
+	if !inNamespace(i.TimeFieldName, namespace) {
+		fields = append(filterNamespace(commonFields, i.TimeFieldName, ""), fields...)
+	}
+
+	indexName := namespacedIndexName(i.indexName, namespace)
+	filename := strings.TrimSuffix(i.targetFilename, ".json") + "-" + clean(namespace, i.keepSafeChars) + ".json"
+	return i.generatePatterns(ctx, cleanID(indexName), indexName, filename, fields)
+}
+
+// loadFieldsFiles loads and concatenates the fields of the fields.yml files.
+// It returns an error listing the fields defined in more than one of the files
+// and the files defining them. Fields duplicated within a single file are
+// reported by the transformer.
+func loadFieldsFiles(fieldsYamls []string) (common.Fields, error) {
+	var fields common.Fields
+	definedIn := map[string][]string{}
+	for _, fieldsYaml := range fieldsYamls {
+		fileFields, err := common.LoadFieldsYaml(fieldsYaml)
+		if err != nil {
+			return nil, err
+		}
+
+		paths := map[string]bool{}
+		collectPaths(fileFields, "", paths)
+		for path := range paths {
+			definedIn[path] = append(definedIn[path], fieldsYaml)
+		}
+		fields = append(fields, fileFields...)
+	}
+
+	var duplicates []string
+	for path, files := range definedIn {
+		if len(files) > 1 {
+			duplicates = append(duplicates, fmt.Sprintf("<%s> in files %s", path, strings.Join(files, ", ")))
+		}
+	}
+	if len(duplicates) > 0 {
+		sort.Strings(duplicates)
+		return nil, fmt.Errorf("ERROR: Fields are duplicated: %s. Please update and try again.", strings.Join(duplicates, "; "))
+	}
+	return fields, nil
+}
+
+// collectPaths adds the paths of the fields added to the index patterns to
+// paths. Disabled fields and groups are skipped like by the transformer.
+func collectPaths(fields common.Fields, path string, paths map[string]bool) {
+	for _, f := range fields {
+		if !fieldEnabled(f) {
+			continue
+		}
+
+		fieldPath := f.Name
+		if path != "" {
+			fieldPath = path + "." + f.Name
+		}
+
+		if isContainer(f) {
+			collectPaths(f.Fields, fieldPath, paths)
+			continue
+		}
+		paths[fieldPath] = true
+	}
+}
+
+// generatePatterns creates the index patterns titled title, with the saved
+// object id id. The generation stops with the error of ctx once it is done.
+func (i *IndexPatternGenerator) generatePatterns(ctx context.Context, id, title, filename string, fields common.Fields) ([]patternFile, error) {
+	if runtime := runtimeFieldPaths(fields, ""); len(runtime) > 0 && !supportsRuntimeFields(i.version) {
+		return nil, fmt.Errorf("ERROR: Runtime fields <%s> require Kibana %s or newer, found version %s. Please update and try again.",
+			strings.Join(runtime, ", "), runtimeFieldsVersion, i.version)
+	}
+
+	if err := i.checkFormats(); err != nil {
+		return nil, err
+	}
+
+	var files []patternFile
+	if i.Formats.Has("5.x") {
+		index5x, err := i.generate5x(ctx, title, filename, fields)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, index5x)
+	}
+
+	if i.Formats.Has("default") {
+		index6x, err := i.generate6x(ctx, id, title, filename, fields)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, index6x)
+	}
 
-	return []string{index5xPath, index6xPath}, nil
+	if i.Formats.Has("8.x") {
+		index8x, err := i.generate8x(ctx, id, title, filename, fields)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, index8x)
+	}
+	return files, nil
 }
 
-func (i *IndexPatternGenerator) generate5x(fields common.Fields) (string, error) {
+func (i *IndexPatternGenerator) generate5x(ctx context.Context, title, filename string, fields common.Fields) (patternFile, error) {
 	version, _ := common.NewVersion("5.0.0")
-	transformed, err := generate(i.indexName, version, fields)
+	transformed, stats, modules, err := generate(ctx, i.TimeFieldName, title, version, fields, false, false, "")
 	if err != nil {
-		return "", err
+		return patternFile{}, err
 	}
 
-	file5x := filepath.Join(i.targetDir5x, i.targetFilename)
-	err = dumpToFile(file5x, transformed)
-	return file5x, err
+	return newPatternFile(filepath.Join(i.targetDir5x, filename), "5.x", transformed, stats, modules)
 }
 
-func (i *IndexPatternGenerator) generate6x(fields common.Fields) (string, error) {
+func (i *IndexPatternGenerator) generate6x(ctx context.Context, id, title, filename string, fields common.Fields) (patternFile, error) {
 	version, _ := common.NewVersion("6.0.0")
-	transformed, err := generate(i.indexName, version, fields)
+	fieldAttrs := i.fieldAttrs && supportsFieldAttrs(i.version)
+	transformed, stats, modules, err := generate(ctx, i.TimeFieldName, title, version, fields, fieldAttrs, true, i.locale)
 	if err != nil {
-		return "", err
+		return patternFile{}, err
 	}
 	out := common.MapStr{
 		"version": i.version,
 		"objects": []common.MapStr{
 			common.MapStr{
 				"type":       "index-pattern",
-				"id":         i.indexName,
+				"id":         id,
 				"version":    1,
 				"attributes": transformed,
 			},
 		},
 	}
-	file6x := filepath.Join(i.targetDirDefault, i.targetFilename)
-	err = dumpToFile(file6x, out)
-	return file6x, err
+	return newPatternFile(filepath.Join(i.targetDirDefault, filename), "default", out, stats, modules)
 }
 
-func generate(indexName string, version *common.Version, f common.Fields) (common.MapStr, error) {
-	transformer, err := newTransformer("@timestamp", indexName, version, f)
+// generate8x creates the data view for Kibana 8.x. Data views are exported as
+// a single saved object of type `data-view`, instead of a list of objects.
+func (i *IndexPatternGenerator) generate8x(ctx context.Context, id, title, filename string, fields common.Fields) (patternFile, error) {
+	version, _ := common.NewVersion("8.0.0")
+	transformed, stats, modules, err := generate(ctx, i.TimeFieldName, title, version, fields, i.fieldAttrs, true, i.locale)
 	if err != nil {
-		return nil, err
+		return patternFile{}, err
+	}
+	out := common.MapStr{
+		"type":                 "data-view",
+		"id":                   id,
+		"coreMigrationVersion": i.version,
+		"attributes":           transformed,
+		"references":           []common.MapStr{},
+	}
+	return newPatternFile(filepath.Join(i.targetDir8x, filename), "8.x", out, stats, modules)
+}
+
+// generate transforms the fields into the attributes of an index pattern. It
+// also returns the module of each field.
+func generate(ctx context.Context, timeFieldName, title string, version *common.Version, f common.Fields, fieldAttrs, runtimeFields bool, locale string) (common.MapStr, FormatStats, map[string]string, error) {
+	transformer, err := newTransformer(timeFieldName, title, version, f)
+	if err != nil {
+		return nil, FormatStats{}, nil, err
 	}
+	transformer.ctx = ctx
+	transformer.fieldAttrs = fieldAttrs
+	transformer.locale = locale
+	transformer.runtimeFields = runtimeFields
 	transformed, err := transformer.transformFields()
 	if err != nil {
-		return nil, err
+		return nil, FormatStats{}, nil, err
+	}
+	stats := FormatStats{
+		Fields:       len(transformer.transformedFields),
+		Skipped:      transformer.skipped,
+		FieldFormats: len(transformer.transformedFieldFormatMap),
 	}
 
 	fieldsBytes, err := json.Marshal(transformed["fields"])
 	if err != nil {
-		return nil, err
+		return nil, FormatStats{}, nil, err
 	}
 	transformed["fields"] = string(fieldsBytes)
 
 	fieldFormatBytes, err := json.Marshal(transformed["fieldFormatMap"])
 	if err != nil {
-		return nil, err
+		return nil, FormatStats{}, nil, err
 	}
 	transformed["fieldFormatMap"] = string(fieldFormatBytes)
-	return transformed, nil
+
+	if fieldAttrs, ok := transformed["fieldAttrs"]; ok {
+		fieldAttrsBytes, err := json.Marshal(fieldAttrs)
+		if err != nil {
+			return nil, FormatStats{}, nil, err
+		}
+		transformed["fieldAttrs"] = string(fieldAttrsBytes)
+	}
+
+	if runtimeFieldMap, ok := transformed["runtimeFieldMap"]; ok {
+		runtimeFieldMapBytes, err := json.Marshal(runtimeFieldMap)
+		if err != nil {
+			return nil, FormatStats{}, nil, err
+		}
+		transformed["runtimeFieldMap"] = string(runtimeFieldMapBytes)
+	}
+	return transformed, stats, transformer.modules, nil
+}
+
+const defaultTimeFieldName = "@timestamp"
+
+var (
+	nameCleaner      = regexp.MustCompile("[^a-zA-Z0-9_]+")
+	safeNameCleaner  = regexp.MustCompile("[^a-zA-Z0-9_-]+")
+	idCleaner        = regexp.MustCompile("[^a-zA-Z0-9_.*-]+")
+	indexNameCleaner = regexp.MustCompile(`[\\/?"<>|#\s]+`)
+)
+
+// clean removes all characters but letters, digits and underscores from the
+// name. Hyphens are also kept if keepSafeChars is set.
+func clean(name string, keepSafeChars bool) string {
+	if keepSafeChars {
+		return safeNameCleaner.ReplaceAllString(name, "")
+	}
+	return nameCleaner.ReplaceAllString(name, "")
 }
 
-func clean(name string) string {
-	reg := regexp.MustCompile("[^a-zA-Z0-9_]+")
-	return reg.ReplaceAllString(name, "")
+// cleanID removes all characters not supported in saved object ids. In
+// contrast to clean, wildcards, dots and dashes are kept.
+func cleanID(name string) string {
+	return idCleaner.ReplaceAllString(name, "")
 }
 
-func dumpToFile(f string, pattern common.MapStr) error {
+// cleanIndexName removes all characters not allowed in Elasticsearch index
+// patterns. Characters required for cross cluster search patterns like
+// `remote:metricbeat-*` or `remote1:beat-*,remote2:beat-*` are kept.
+func cleanIndexName(name string) string {
+	return indexNameCleaner.ReplaceAllString(name, "")
+}
+
+// namespacedIndexName adds the namespace to each pattern of a (possibly comma
+// separated) index name, keeping a trailing wildcard at the end.
+func namespacedIndexName(indexName, namespace string) string {
+	patterns := strings.Split(indexName, ",")
+	for idx, pattern := range patterns {
+		pattern = strings.TrimSuffix(pattern, "*")
+		if !strings.HasSuffix(pattern, "-") {
+			pattern += "-"
+		}
+		patterns[idx] = pattern + namespace + "-*"
+	}
+	return strings.Join(patterns, ",")
+}
+
+// inNamespace returns true if the field path is the namespace or is prefixed
+// by it.
+func inNamespace(path, namespace string) bool {
+	return path == namespace || strings.HasPrefix(path, namespace+".")
+}
+
+// filterNamespace returns the fields whose path is the namespace or is
+// prefixed by it. Groups on the way to the namespace only keep the matching
+// children.
+func filterNamespace(fields common.Fields, namespace, path string) common.Fields {
+	var filtered common.Fields
+	for _, f := range fields {
+		fieldPath := f.Name
+		if path != "" {
+			fieldPath = path + "." + f.Name
+		}
+
+		if inNamespace(fieldPath, namespace) {
+			filtered = append(filtered, f)
+			continue
+		}
+
+		if isContainer(f) && strings.HasPrefix(namespace, fieldPath+".") {
+			if children := filterNamespace(f.Fields, namespace, fieldPath); len(children) > 0 {
+				f.Fields = children
+				filtered = append(filtered, f)
+			}
+		}
+	}
+	return filtered
+}
+
+func newPatternFile(path, format string, pattern common.MapStr, stats FormatStats, modules map[string]string) (patternFile, error) {
 	patternIndent, err := json.MarshalIndent(pattern, "", "  ")
 	if err != nil {
-		return err
+		return patternFile{}, err
+	}
+	return patternFile{path: path, format: format, pattern: pattern, content: patternIndent, stats: stats, modules: modules}, nil
+}
+
+func writeFiles(ctx context.Context, files []patternFile) ([]string, error) {
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(f.path, f.content, 0644); err != nil {
+			return nil, err
+		}
+		paths = append(paths, f.path)
+	}
+	return paths, nil
+}
+
+func filesToMap(files []patternFile) map[string][]byte {
+	m := make(map[string][]byte, len(files))
+	for _, f := range files {
+		m[f.path] = f.content
+	}
+	return m
+}
+
+// supportsDataViews returns true if version is 8.0.0 or later. Pre-release
+// suffixes like `8.0.0-alpha1` are ignored. Versions that can not be parsed are
+// considered older versions.
+func supportsDataViews(version string) bool {
+	if idx := strings.IndexAny(version, "-+"); idx >= 0 {
+		version = version[:idx]
+	}
+	major, err := strconv.Atoi(strings.SplitN(version, ".", 2)[0])
+	return err == nil && major >= 8
+}
+
+// runtimeFieldsVersion is the first version of Kibana supporting runtime
+// fields.
+const runtimeFieldsVersion = "7.11.0"
+
+// supportsRuntimeFields returns true if the version generates index patterns
+// for a Kibana version supporting runtime fields. The 5.x index patterns are
+// generated without the runtime fields.
+func supportsRuntimeFields(version string) bool {
+	v, err := common.NewVersion(version)
+	if err != nil {
+		return false
 	}
-	err = ioutil.WriteFile(f, patternIndent, 0644)
+	min, _ := common.NewVersion(runtimeFieldsVersion)
+	return !v.LessThan(min)
+}
+
+// fieldAttrsVersion is the first version of Kibana supporting the fieldAttrs
+// attribute of index patterns.
+const fieldAttrsVersion = "7.11.0"
+
+// supportsFieldAttrs returns true if the version generates index patterns for
+// a Kibana version supporting fieldAttrs.
+func supportsFieldAttrs(version string) bool {
+	v, err := common.NewVersion(version)
 	if err != nil {
-		return err
+		return false
+	}
+	min, _ := common.NewVersion(fieldAttrsVersion)
+	return !v.LessThan(min)
+}
+
+// runtimeFieldPaths returns the paths of the runtime fields added to the index
+// patterns.
+func runtimeFieldPaths(fields common.Fields, path string) []string {
+	var paths []string
+	for _, f := range fields {
+		if !fieldEnabled(f) {
+			continue
+		}
+
+		fieldPath := f.Name
+		if path != "" {
+			fieldPath = path + "." + f.Name
+		}
+
+		if isContainer(f) {
+			paths = append(paths, runtimeFieldPaths(f.Fields, fieldPath)...)
+		} else if f.Runtime {
+			paths = append(paths, fieldPath)
+		}
+	}
+	return paths
+}
+
+// checkFormats checks the formats to generate are known, and supported by the
+// version.
+func (i *IndexPatternGenerator) checkFormats() error {
+	if i.Formats.Count() == 0 {
+		return errors.New("no index pattern format to generate, use 5.x, default or 8.x")
+	}
+	for format := range i.Formats {
+		switch format {
+		case "5.x", "default":
+		case "8.x":
+			if i.targetDir8x == "" {
+				return fmt.Errorf("data views for Kibana 8.x are not generated for version %s", i.version)
+			}
+		default:
+			return fmt.Errorf("unknown index pattern format '%s', use 5.x, default or 8.x", format)
+		}
 	}
 	return nil
 }
 
-func createTargetDir(baseDir string, version string) string {
-	targetDir := filepath.Join(baseDir, "_meta", "kibana", version, "index-pattern")
-	if _, err := os.Stat(targetDir); os.IsNotExist(err) {
-		os.MkdirAll(targetDir, 0777)
+// createTargetDirs creates the directories of the index patterns generated, if
+// they do not exist yet. It fails if several formats are written to the same
+// directory, as they would overwrite each other.
+func (i *IndexPatternGenerator) createTargetDirs() error {
+	var dirs []string
+	if i.Formats.Has("5.x") {
+		dirs = append(dirs, i.targetDir5x)
+	}
+	if i.Formats.Has("default") {
+		dirs = append(dirs, i.targetDirDefault)
 	}
-	return targetDir
+	if i.Formats.Has("8.x") {
+		dirs = append(dirs, i.targetDir8x)
+	}
+
+	seen := map[string]bool{}
+	for _, dir := range dirs {
+		clean := filepath.Clean(dir)
+		if seen[clean] {
+			return fmt.Errorf("several index pattern formats are written to %s", dir)
+		}
+		seen[clean] = true
+	}
+
+	for _, dir := range dirs {
+		if err := createTargetDir(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func targetDir(baseDir, version, objectType string) string {
+	return filepath.Join(baseDir, "_meta", "kibana", version, objectType)
+}
+
+func createTargetDir(dir string) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return os.MkdirAll(dir, 0777)
+	}
+	return nil
 }
@@ -1,6 +1,7 @@
 package kibana
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -10,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/elastic/beats/libbeat/common"
 )
@@ -19,45 +21,375 @@ func TestNewGenerator(t *testing.T) {
 	defer teardown(beatDir)
 
 	// checks for fields.yml
-	generator, err := NewGenerator("beat-index", "mybeat.", filepath.Join(beatDir, "notexistent"), "7.0")
+	generator, err := NewGenerator("beat-index", "mybeat.", filepath.Join(beatDir, "notexistent"), "7.0.0")
 	assert.Error(t, err)
 
-	generator, err = NewGenerator("beat-index", "mybeat.", beatDir, "7.0")
+	generator, err = NewGenerator("beat-index", "mybeat.", beatDir, "7.0.0")
 	assert.NoError(t, err)
-	assert.Equal(t, "7.0", generator.version)
+	assert.Equal(t, "7.0.0", generator.version)
 	assert.Equal(t, "beat-index", generator.indexName)
-	assert.Equal(t, filepath.Join(beatDir, "fields.yml"), generator.fieldsYaml)
+	assert.Equal(t, []string{filepath.Join(beatDir, "fields.yml")}, generator.fieldsYamls)
 
-	// creates file dir and sets name
+	// sets file dirs and name, directories are created on Generate
 	expectedDir := filepath.Join(beatDir, "_meta/kibana/default/index-pattern")
 	assert.Equal(t, expectedDir, generator.targetDirDefault)
-	_, err = os.Stat(generator.targetDirDefault)
-	assert.NoError(t, err)
-
 	expectedDir = filepath.Join(beatDir, "_meta/kibana/5.x/index-pattern")
 	assert.Equal(t, expectedDir, generator.targetDir5x)
+	assert.Equal(t, "mybeat.json", generator.targetFilename)
+
+	_, err = os.Stat(filepath.Join(beatDir, "_meta"))
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = generator.Generate()
+	assert.NoError(t, err)
+	_, err = os.Stat(generator.targetDirDefault)
+	assert.NoError(t, err)
 	_, err = os.Stat(generator.targetDir5x)
 	assert.NoError(t, err)
 
-	assert.Equal(t, "mybeat.json", generator.targetFilename)
+	// data views are only generated for 8.x
+	assert.Empty(t, generator.targetDir8x)
+	_, err = os.Stat(filepath.Join(beatDir, "_meta/kibana/8.x"))
+	assert.True(t, os.IsNotExist(err))
+
+	generator, err = NewGenerator("beat-index", "mybeat.", beatDir, "8.0.0-alpha1")
+	assert.NoError(t, err)
+	expectedDir = filepath.Join(beatDir, "_meta/kibana/8.x/data-view")
+	assert.Equal(t, expectedDir, generator.targetDir8x)
+	_, err = generator.Generate()
+	assert.NoError(t, err)
+	_, err = os.Stat(generator.targetDir8x)
+	assert.NoError(t, err)
 }
 
-func TestCleanName(t *testing.T) {
+func TestNewGeneratorVersion(t *testing.T) {
+	beatDir := tmpPath()
+	defer teardown(beatDir)
+
+	for _, version := range []string{"7.0.0", "7.0.0-alpha1", "6.5.4", "8.1.0-SNAPSHOT"} {
+		_, err := NewGenerator("beat-*", "beat", beatDir, version)
+		assert.NoError(t, err, "version %v", version)
+	}
+
+	for _, version := range []string{"banana", "", "7", "7.0", "7.0.x", "7.0.0.1", "a.b.c-alpha1"} {
+		_, err := NewGenerator("beat-*", "beat", beatDir, version)
+		if assert.Error(t, err, "version %v", version) {
+			assert.Contains(t, err.Error(), fmt.Sprintf("invalid version '%v'", version))
+		}
+	}
+}
+
+func TestSupportsDataViews(t *testing.T) {
 	tests := []struct {
 		input    string
-		expected string
+		expected bool
+	}{
+		{input: "5.6.0", expected: false},
+		{input: "7.0", expected: false},
+		{input: "7.0.0-alpha1", expected: false},
+		{input: "8.0.0", expected: true},
+		{input: "8.0.0-alpha1", expected: true},
+		{input: "8.1.0-SNAPSHOT", expected: true},
+		{input: "8", expected: true},
+		{input: "10.2.3", expected: true},
+		{input: "", expected: false},
+		{input: "invalid", expected: false},
+	}
+	for idx, test := range tests {
+		output := supportsDataViews(test.input)
+		msg := fmt.Sprintf("(%v): Expected <%v> Received: <%v> for %s", idx, test.expected, output, test.input)
+		assert.Equal(t, test.expected, output, msg)
+	}
+}
+
+func TestCleanName(t *testing.T) {
+	tests := []struct {
+		input         string
+		keepSafeChars bool
+		expected      string
 	}{
 		{input: " beat index pattern", expected: "beatindexpattern"},
 		{input: "Beat@Index.!", expected: "BeatIndex"},
 		{input: "beatIndex", expected: "beatIndex"},
+		{input: "my-custom-beat", expected: "mycustombeat"},
+		{input: "my_custom_beat", expected: "my_custom_beat"},
+		{input: "my-custom_beat!", expected: "mycustom_beat"},
+		{input: " beat index pattern", keepSafeChars: true, expected: "beatindexpattern"},
+		{input: "Beat@Index.!", keepSafeChars: true, expected: "BeatIndex"},
+		{input: "my-custom-beat", keepSafeChars: true, expected: "my-custom-beat"},
+		{input: "my_custom_beat", keepSafeChars: true, expected: "my_custom_beat"},
+		{input: "my-custom_beat!", keepSafeChars: true, expected: "my-custom_beat"},
 	}
 	for idx, test := range tests {
-		output := clean(test.input)
+		output := clean(test.input, test.keepSafeChars)
 		msg := fmt.Sprintf("(%v): Expected <%s> Received: <%s>", idx, test.expected, output)
 		assert.Equal(t, test.expected, output, msg)
 	}
 }
 
+func TestCleanIndexName(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "metricbeat-*", expected: "metricbeat-*"},
+		{input: "remote:metricbeat-*", expected: "remote:metricbeat-*"},
+		{input: "r1:beat-*,r2:beat-*", expected: "r1:beat-*,r2:beat-*"},
+		{input: " beat/index?#", expected: "beatindex"},
+	}
+	for idx, test := range tests {
+		output := cleanIndexName(test.input)
+		msg := fmt.Sprintf("(%v): Expected <%s> Received: <%s>", idx, test.expected, output)
+		assert.Equal(t, test.expected, output, msg)
+	}
+}
+
+func TestCleanID(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "metricbeat-*", expected: "metricbeat-*"},
+		{input: "remote:metricbeat-*", expected: "remotemetricbeat-*"},
+		{input: "r1:beat-*,r2:beat-*", expected: "r1beat-*r2beat-*"},
+	}
+	for idx, test := range tests {
+		output := cleanID(test.input)
+		msg := fmt.Sprintf("(%v): Expected <%s> Received: <%s>", idx, test.expected, output)
+		assert.Equal(t, test.expected, output, msg)
+	}
+}
+
+func TestGenerateCrossClusterSearch(t *testing.T) {
+	beatDir := tmpPath()
+	defer teardown(beatDir)
+	generator, err := NewGenerator("remote:metricbeat-*", "metricbeat", beatDir, "7.0.0-alpha1")
+	assert.NoError(t, err)
+	_, err = generator.Generate()
+	assert.NoError(t, err)
+
+	created5x, err := readJson(filepath.Join(beatDir, "_meta/kibana/5.x/index-pattern/metricbeat.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, "remote:metricbeat-*", created5x["title"])
+
+	created, err := readJson(filepath.Join(beatDir, "_meta/kibana/default/index-pattern/metricbeat.json"))
+	assert.NoError(t, err)
+	obj := created["objects"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "remotemetricbeat-*", obj["id"])
+	assert.Equal(t, "remote:metricbeat-*", obj["attributes"].(map[string]interface{})["title"])
+}
+
+func TestGenerateTitle(t *testing.T) {
+	beatDir := tmpPath()
+	defer teardown(beatDir)
+	generator, err := NewGenerator("metricbeat-*", "metric beat", beatDir, "7.0.0-alpha1")
+	assert.NoError(t, err)
+	generator.SetTitle("Metricbeat production")
+	_, err = generator.Generate()
+	assert.NoError(t, err)
+
+	created5x, err := readJson(filepath.Join(beatDir, "_meta/kibana/5.x/index-pattern/metricbeat.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Metricbeat production", created5x["title"])
+
+	created, err := readJson(filepath.Join(beatDir, "_meta/kibana/default/index-pattern/metricbeat.json"))
+	assert.NoError(t, err)
+	obj := created["objects"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "metricbeat-*", obj["id"])
+	assert.Equal(t, "Metricbeat production", obj["attributes"].(map[string]interface{})["title"])
+
+	// Without a title, the index name is used.
+	generator.SetTitle("")
+	_, err = generator.Generate()
+	assert.NoError(t, err)
+
+	created, err = readJson(filepath.Join(beatDir, "_meta/kibana/default/index-pattern/metricbeat.json"))
+	assert.NoError(t, err)
+	obj = created["objects"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "metricbeat-*", obj["id"])
+	assert.Equal(t, "metricbeat-*", obj["attributes"].(map[string]interface{})["title"])
+}
+
+func TestGenerateID(t *testing.T) {
+	beatDir := tmpPath()
+	defer teardown(beatDir)
+	generator, err := NewGenerator("metricbeat-*", "metricbeat", beatDir, "8.0.0")
+	require.NoError(t, err)
+
+	// The id is used verbatim, even with characters removed from derived ids.
+	generator.SetID("metricbeat-production:v1")
+	_, err = generator.Generate()
+	require.NoError(t, err)
+
+	created, err := readJson(filepath.Join(beatDir, "_meta/kibana/default/index-pattern/metricbeat.json"))
+	require.NoError(t, err)
+	obj := created["objects"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "metricbeat-production:v1", obj["id"])
+	assert.Equal(t, "metricbeat-*", obj["attributes"].(map[string]interface{})["title"])
+
+	created8x, err := readJson(filepath.Join(beatDir, "_meta/kibana/8.x/data-view/metricbeat.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "metricbeat-production:v1", created8x["id"])
+	// No references point to the derived id.
+	for _, ref := range created8x["references"].([]interface{}) {
+		assert.Equal(t, "metricbeat-production:v1", ref.(map[string]interface{})["id"])
+	}
+
+	// Without an id, the id is derived from the index name.
+	generator.SetID("")
+	_, err = generator.Generate()
+	require.NoError(t, err)
+
+	created, err = readJson(filepath.Join(beatDir, "_meta/kibana/default/index-pattern/metricbeat.json"))
+	require.NoError(t, err)
+	obj = created["objects"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "metricbeat-*", obj["id"])
+}
+
+func TestGenerateTimeFieldName(t *testing.T) {
+	beatDir := tmpPath()
+	defer teardown(beatDir)
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "7.0.0-alpha1")
+	assert.NoError(t, err)
+	assert.Equal(t, "@timestamp", generator.TimeFieldName)
+
+	generator.TimeFieldName = "event.created"
+	_, err = generator.Generate()
+	assert.NoError(t, err)
+
+	created5x, err := readJson(filepath.Join(beatDir, "_meta/kibana/5.x/index-pattern/beat.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, "event.created", created5x["timeFieldName"])
+
+	created, err := readJson(filepath.Join(beatDir, "_meta/kibana/default/index-pattern/beat.json"))
+	assert.NoError(t, err)
+	obj := created["objects"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "event.created", obj["attributes"].(map[string]interface{})["timeFieldName"])
+}
+
+func TestGenerateFieldAttrs(t *testing.T) {
+	beatDir := tmpPath()
+	defer teardown(beatDir)
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "7.11.0")
+	assert.NoError(t, err)
+	generator.SetFieldAttrs(true)
+	_, err = generator.Generate()
+	assert.NoError(t, err)
+
+	created5x, err := readJson(filepath.Join(beatDir, "_meta/kibana/5.x/index-pattern/beat.json"))
+	assert.NoError(t, err)
+	assert.NotContains(t, created5x, "fieldAttrs")
+
+	created, err := readJson(filepath.Join(beatDir, "_meta/kibana/default/index-pattern/beat.json"))
+	assert.NoError(t, err)
+	obj := created["objects"].([]interface{})[0].(map[string]interface{})
+	attributes := obj["attributes"].(map[string]interface{})
+
+	// Like fields and fieldFormatMap, fieldAttrs is a JSON encoded string.
+	var fieldAttrs map[string]interface{}
+	err = json.Unmarshal([]byte(attributes["fieldAttrs"].(string)), &fieldAttrs)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"customLabel":       "Long",
+		"customDescription": "A long value.",
+	}, fieldAttrs["long"])
+	assert.NotContains(t, fieldAttrs, "multifield_field.keyword")
+}
+
+func TestGenerateFieldAttrsLocale(t *testing.T) {
+	beatDir := tmpPath()
+	defer teardown(beatDir)
+
+	for locale, expected := range map[string]string{
+		"":      "Long",
+		"de":    "Lang",
+		"de-AT": "Lang",
+		"pt_BR": "Long",
+		"pt-BR": "Longo",
+		"fr":    "Long",
+	} {
+		generator, err := NewGenerator("beat-*", "beat", beatDir, "7.11.0")
+		require.NoError(t, err)
+		generator.SetFieldAttrs(true)
+		generator.SetLocale(locale)
+		patterns, err := generator.GenerateIndexPatterns()
+		require.NoError(t, err, locale)
+
+		var fieldAttrs map[string]interface{}
+		err = json.Unmarshal([]byte(patterns[1].Objects[0].Attributes.FieldAttrs), &fieldAttrs)
+		require.NoError(t, err, locale)
+		assert.Equal(t, map[string]interface{}{
+			"customLabel":       expected,
+			"customDescription": "A long value.",
+		}, fieldAttrs["long"], locale)
+
+		// Fields without a label nor a description are omitted.
+		assert.NotContains(t, fieldAttrs, "multifield_field", locale)
+		assert.NotContains(t, fieldAttrs, "stored_only", locale)
+	}
+}
+
+func TestGenerateFieldAttrsVersion(t *testing.T) {
+	beatDir := tmpPath()
+	defer teardown(beatDir)
+
+	// fieldAttrs are only supported since Kibana 7.11.0.
+	for _, version := range []string{"7.0.0", "7.0.0-alpha1", "7.10.2"} {
+		generator, err := NewGenerator("beat-*", "beat", beatDir, version)
+		require.NoError(t, err)
+		generator.SetFieldAttrs(true)
+		patterns, err := generator.GenerateIndexPatterns()
+		require.NoError(t, err, version)
+		assert.Empty(t, patterns[1].Objects[0].Attributes.FieldAttrs, version)
+	}
+}
+
+func TestGenerateFormats(t *testing.T) {
+	beatDir := tmpPath()
+	defer teardown(beatDir)
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "7.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, common.MakeStringSet("5.x", "default"), generator.Formats)
+
+	generator.Formats = common.MakeStringSet("default")
+	files, err := generator.Generate()
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(beatDir, "_meta/kibana/default/index-pattern/beat.json")}, files)
+
+	_, err = os.Stat(filepath.Join(beatDir, "_meta/kibana/5.x"))
+	assert.True(t, os.IsNotExist(err))
+
+	patterns, err := generator.GenerateIndexPatterns()
+	require.NoError(t, err)
+	require.Len(t, patterns, 1)
+	assert.Equal(t, "7.0.0", patterns[0].Version)
+}
+
+func TestGenerateFormatsInvalid(t *testing.T) {
+	beatDir := tmpPath()
+	defer teardown(beatDir)
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "7.0.0")
+	require.NoError(t, err)
+
+	for formats, expected := range map[string]string{
+		"":    "no index pattern format to generate",
+		"6.x": "unknown index pattern format '6.x'",
+		"8.x": "data views for Kibana 8.x are not generated for version 7.0.0",
+	} {
+		generator.Formats = common.MakeStringSet(formats)
+		if formats == "" {
+			generator.Formats = nil
+		}
+		_, err = generator.Generate()
+		if assert.Error(t, err, formats) {
+			assert.Contains(t, err.Error(), expected, formats)
+		}
+	}
+
+	_, err = os.Stat(filepath.Join(beatDir, "_meta"))
+	assert.True(t, os.IsNotExist(err))
+}
+
 func TestGenerateFieldsYaml(t *testing.T) {
 	beatDir := tmpPath()
 	defer teardown(beatDir)
@@ -65,7 +397,7 @@ func TestGenerateFieldsYaml(t *testing.T) {
 	_, err = generator.Generate()
 	assert.NoError(t, err)
 
-	generator.fieldsYaml = ""
+	generator.fieldsYamls = []string{""}
 	_, err = generator.Generate()
 	assert.Error(t, err)
 }
@@ -77,7 +409,8 @@ func TestDumpToFile5x(t *testing.T) {
 	_, err = generator.Generate()
 	assert.NoError(t, err)
 
-	generator.targetDir5x = "./non-existing/something"
+	// the directory can not be created below a file
+	generator.targetDir5x = filepath.Join(beatDir, "fields.yml", "something")
 	_, err = generator.Generate()
 	assert.Error(t, err)
 }
@@ -89,11 +422,54 @@ func TestDumpToFileDefault(t *testing.T) {
 	_, err = generator.Generate()
 	assert.NoError(t, err)
 
-	generator.targetDirDefault = "./non-existing/something"
+	// the directory can not be created below a file
+	generator.targetDirDefault = filepath.Join(beatDir, "fields.yml", "something")
 	_, err = generator.Generate()
 	assert.Error(t, err)
 }
 
+func TestSetTargetDir(t *testing.T) {
+	beatDir := tmpPath()
+	defer teardown(beatDir)
+	tmpDir, err := ioutil.TempDir("", "index-pattern")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "8.0.0")
+	require.NoError(t, err)
+	flatDir := filepath.Join(tmpDir, "flat")
+	require.NoError(t, generator.SetTargetDir("default", flatDir))
+
+	paths, err := generator.Generate()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(beatDir, "_meta/kibana/5.x/index-pattern/beat.json"),
+		filepath.Join(flatDir, "beat.json"),
+		filepath.Join(beatDir, "_meta/kibana/8.x/data-view/beat.json"),
+	}, paths)
+
+	// the default index pattern is only written to the overridden directory
+	_, err = os.Stat(filepath.Join(flatDir, "beat.json"))
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(beatDir, "_meta/kibana/default"))
+	assert.True(t, os.IsNotExist(err))
+
+	ok, _, err := generator.Check()
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// formats writing to the same directory would overwrite each other
+	require.NoError(t, generator.SetTargetDir("8.x", flatDir+"/"))
+	_, err = generator.Generate()
+	assert.Error(t, err)
+
+	assert.Error(t, generator.SetTargetDir("6.x", tmpDir))
+
+	generator, err = NewGenerator("beat-*", "beat", beatDir, "7.0.0")
+	require.NoError(t, err)
+	assert.Error(t, generator.SetTargetDir("8.x", tmpDir))
+}
+
 func TestGenerate(t *testing.T) {
 	beatDir := tmpPath()
 	defer teardown(beatDir)
@@ -107,6 +483,193 @@ func TestGenerate(t *testing.T) {
 		{"existing": "beat-default.json", "created": "_meta/kibana/default/index-pattern/beat.json"},
 	}
 	testGenerate(t, beatDir, tests)
+
+	// searchable and aggregatable set in fields.yml override the defaults of
+	// the type
+	patterns, err := generator.GenerateIndexPatterns()
+	assert.NoError(t, err)
+	fields, err := patterns[1].Objects[0].Attributes.DecodeFields()
+	assert.NoError(t, err)
+	idx := find(fields, "stored_only")
+	if assert.NotEqual(t, -1, idx) {
+		assert.Equal(t, "string", fields[idx]["type"])
+		assert.Equal(t, false, fields[idx]["searchable"])
+		assert.Equal(t, false, fields[idx]["aggregatable"])
+	}
+
+	// numeric subtypes are numbers, aliases keep the Elasticsearch type
+	for _, name := range []string{"scaled", "half", "unsigned", "unsigned_alias"} {
+		idx := find(fields, name)
+		if assert.NotEqual(t, -1, idx, name) {
+			assert.Equal(t, "number", fields[idx]["type"], name)
+		}
+	}
+	if idx := find(fields, "unsigned_alias"); idx != -1 {
+		assert.Equal(t, []interface{}{"unsigned_long"}, fields[idx]["esTypes"])
+	}
+
+	// disabled fields and the fields of disabled groups are omitted
+	for _, name := range []string{"object_disabled", "group_disabled.message"} {
+		assert.Equal(t, -1, find(fields, name), name)
+	}
+
+	// geo fields keep their type for Kibana maps and are not aggregatable
+	if idx := find(fields, "location"); assert.NotEqual(t, -1, idx) {
+		assert.Equal(t, map[string]interface{}{
+			"name":         "location",
+			"type":         "geo_point",
+			"esTypes":      []interface{}{"geo_point"},
+			"count":        float64(0),
+			"scripted":     false,
+			"indexed":      true,
+			"analyzed":     false,
+			"doc_values":   true,
+			"searchable":   true,
+			"aggregatable": false,
+		}, fields[idx])
+	}
+	if idx := find(fields, "area"); assert.NotEqual(t, -1, idx) {
+		assert.Equal(t, map[string]interface{}{
+			"name":         "area",
+			"type":         "geo_shape",
+			"esTypes":      []interface{}{"geo_shape"},
+			"count":        float64(0),
+			"scripted":     false,
+			"indexed":      true,
+			"analyzed":     false,
+			"doc_values":   false,
+			"searchable":   true,
+			"aggregatable": false,
+		}, fields[idx])
+	}
+}
+
+func TestGenerate8x(t *testing.T) {
+	beatDir := tmpPath()
+	defer teardown(beatDir)
+	generator, err := NewGenerator("beat-*", "b eat ?!", beatDir, "8.0.0-alpha1")
+	assert.NoError(t, err)
+	pattern, err := generator.Generate()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(beatDir, "_meta/kibana/5.x/index-pattern/beat.json"),
+		filepath.Join(beatDir, "_meta/kibana/default/index-pattern/beat.json"),
+		filepath.Join(beatDir, "_meta/kibana/8.x/data-view/beat.json"),
+	}, pattern)
+
+	tests := []map[string]string{
+		{"existing": "beat-8x.json", "created": "_meta/kibana/8.x/data-view/beat.json"},
+	}
+	testGenerate(t, beatDir, tests)
+
+	created, err := readJson(pattern[2])
+	assert.NoError(t, err)
+	assert.Equal(t, "data-view", created["type"])
+	assert.Equal(t, "beat-*", created["id"])
+	assert.Equal(t, []interface{}{}, created["references"])
+	assert.NotContains(t, created, "objects")
+
+	ok, diff, err := generator.Check()
+	assert.NoError(t, err)
+	assert.True(t, ok, diff)
+}
+
+func TestGenerateWithStats(t *testing.T) {
+	beatDir := tmpPath()
+	defer teardown(beatDir)
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "8.0.0")
+	require.NoError(t, err)
+
+	paths, stats, err := generator.GenerateWithStats()
+	require.NoError(t, err)
+	require.Len(t, stats.Formats, 3)
+
+	// the counts match the written index patterns
+	formats := []string{"5.x", "default", "8.x"}
+	for idx, path := range paths {
+		format := formats[idx]
+		formatStats, ok := stats.Formats[format]
+		require.True(t, ok, format)
+
+		content, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		pattern, err := patternFile{path: path, content: content}.indexPattern()
+		require.NoError(t, err)
+		fields, err := pattern.Objects[0].Attributes.DecodeFields()
+		require.NoError(t, err)
+		var fieldFormatMap map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(pattern.Objects[0].Attributes.FieldFormatMap), &fieldFormatMap))
+
+		assert.Equal(t, len(fields), formatStats.Fields, format)
+		assert.Equal(t, len(fieldFormatMap), formatStats.FieldFormats, format)
+		assert.NotZero(t, formatStats.FieldFormats, format)
+
+		// object_disabled and group_disabled.message
+		assert.Equal(t, 2, formatStats.Skipped, format)
+	}
+}
+
+func TestGenerateWithStatsRuntimeFields(t *testing.T) {
+	beatDir, err := filepath.Abs("./testdata/runtime")
+	require.NoError(t, err)
+	defer teardown(beatDir)
+
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "7.11.0")
+	require.NoError(t, err)
+	_, stats, err := generator.GenerateWithStats()
+	require.NoError(t, err)
+
+	// the runtime fields are not added to the 5.x index pattern
+	assert.Equal(t, 2, stats.Formats["5.x"].Skipped)
+	assert.Equal(t, 0, stats.Formats["default"].Skipped)
+	assert.Equal(t, stats.Formats["5.x"].Fields, stats.Formats["default"].Fields)
+}
+
+func TestGenerateInMemory(t *testing.T) {
+	beatDir := tmpPath()
+	defer teardown(beatDir)
+	generator, err := NewGenerator("beat-*", "b eat ?!", beatDir, "7.0.0-alpha1")
+	assert.NoError(t, err)
+
+	patterns, err := generator.GenerateInMemory()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(patterns))
+	assert.Equal(t, "beat-*", patterns[0]["title"])
+	assert.Equal(t, "7.0.0-alpha1", patterns[1]["version"])
+
+	// nothing is written to the beat directory
+	_, err = os.Stat(filepath.Join(beatDir, "_meta"))
+	assert.True(t, os.IsNotExist(err))
+
+	// the patterns are the ones written by Generate
+	files, err := generator.Generate()
+	assert.NoError(t, err)
+	for idx, path := range files {
+		expected, err := json.MarshalIndent(patterns[idx], "", "  ")
+		assert.NoError(t, err)
+		content, err := ioutil.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, string(expected), string(content))
+	}
+
+	generator.fieldsYamls = []string{""}
+	_, err = generator.GenerateInMemory()
+	assert.Error(t, err)
+}
+
+func TestGenerateInMemory8x(t *testing.T) {
+	beatDir := tmpPath()
+	defer teardown(beatDir)
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "8.0.0-alpha1")
+	assert.NoError(t, err)
+
+	patterns, err := generator.GenerateInMemory()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(patterns))
+	assert.Equal(t, "data-view", patterns[2]["type"])
+
+	_, err = os.Stat(filepath.Join(beatDir, "_meta"))
+	assert.True(t, os.IsNotExist(err))
 }
 
 func TestGenerateExtensive(t *testing.T) {
@@ -127,6 +690,570 @@ func TestGenerateExtensive(t *testing.T) {
 	testGenerate(t, beatDir, tests)
 }
 
+// cancelingContext cancels the context on the given call of Err, so the
+// generation is canceled while it is in progress.
+type cancelingContext struct {
+	context.Context
+	cancel   context.CancelFunc
+	calls    int
+	cancelAt int
+}
+
+func (c *cancelingContext) Err() error {
+	c.calls++
+	if c.calls == c.cancelAt {
+		c.cancel()
+	}
+	return c.Context.Err()
+}
+
+func TestGenerateContextCanceled(t *testing.T) {
+	beatDir, err := filepath.Abs("./testdata/extensive")
+	require.NoError(t, err)
+	defer teardown(beatDir)
+	generator, err := NewGenerator("metricbeat-*", "metricbeat", beatDir, "7.0.0")
+	require.NoError(t, err)
+
+	// canceled between the field groups of the first index pattern
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	canceling := &cancelingContext{Context: ctx, cancel: cancel, cancelAt: 10}
+	_, err = generator.GenerateContext(canceling)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 10, canceling.calls, "generation continued after the cancellation")
+	_, err = os.Stat(filepath.Join(beatDir, "_meta"))
+	assert.True(t, os.IsNotExist(err))
+
+	// canceled before writing the last file, the last check of the context
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	counting := &cancelingContext{Context: ctx}
+	_, err = generator.GenerateContext(counting)
+	require.NoError(t, err)
+	canceling = &cancelingContext{Context: ctx, cancel: cancel, cancelAt: counting.calls}
+	_, err = generator.GenerateContext(canceling)
+	assert.Equal(t, context.Canceled, err)
+
+	// already canceled
+	_, err = generator.GenerateContext(ctx)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestGenerateDuplicateFields(t *testing.T) {
+	beatDir, err := filepath.Abs("./testdata/duplicate")
+	if err != nil {
+		panic(err)
+	}
+	defer teardown(beatDir)
+
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "7.0.0")
+	assert.NoError(t, err)
+
+	_, err = generator.Generate()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "<message> in groups (top level), (top level)")
+		assert.Contains(t, err.Error(), "<system.cpu.pct> in groups system, system.cpu")
+	}
+
+	// nothing is written on errors
+	_, err = os.Stat(filepath.Join(beatDir, "_meta"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGenerateMalformedFieldsYaml(t *testing.T) {
+	beatDir, err := filepath.Abs("./testdata/malformed")
+	if err != nil {
+		panic(err)
+	}
+	defer teardown(beatDir)
+
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "7.0.0")
+	require.NoError(t, err)
+
+	_, err = generator.Generate()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), filepath.Join(beatDir, "fields.yml"))
+		assert.Contains(t, err.Error(), "line 6")
+	}
+
+	// missing files still report the error of the file system
+	generator.fieldsYamls = []string{""}
+	_, err = generator.Generate()
+	assert.True(t, os.IsNotExist(err), "%v", err)
+}
+
+func TestGenerateRuntimeFields(t *testing.T) {
+	beatDir, err := filepath.Abs("./testdata/runtime")
+	if err != nil {
+		panic(err)
+	}
+	defer teardown(beatDir)
+
+	expected := map[string]interface{}{
+		"http.ok": map[string]interface{}{
+			"type":   "boolean",
+			"script": map[string]interface{}{"source": "emit(doc['http.status'].value < 400)"},
+		},
+		"http.duration": map[string]interface{}{
+			"type":   "long",
+			"script": map[string]interface{}{"source": "emit(doc['http.end'].value - doc['http.start'].value)"},
+		},
+	}
+
+	for _, version := range []string{"7.11.0", "7.11.0-SNAPSHOT", "8.0.0"} {
+		generator, err := NewGenerator("beat-*", "beat", beatDir, version)
+		require.NoError(t, err)
+		generator.SetFieldAttrs(true)
+		patterns, err := generator.GenerateIndexPatterns()
+		require.NoError(t, err, version)
+
+		// The 5.x index pattern has no runtime fields.
+		assert.Empty(t, patterns[0].Objects[0].Attributes.RuntimeFieldMap, version)
+		fields, err := patterns[0].Objects[0].Attributes.DecodeFields()
+		require.NoError(t, err)
+		assert.Equal(t, -1, find(fields, "http.ok"), version)
+
+		for _, pattern := range patterns[1:] {
+			attributes := pattern.Objects[0].Attributes
+
+			var runtimeFieldMap map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(attributes.RuntimeFieldMap), &runtimeFieldMap), version)
+			assert.Equal(t, expected, runtimeFieldMap, version)
+
+			fields, err := attributes.DecodeFields()
+			require.NoError(t, err)
+			assert.Equal(t, -1, find(fields, "http.ok"), version)
+			assert.NotEqual(t, -1, find(fields, "http.status"), version)
+
+			// formats and attributes of runtime fields are kept
+			assert.Contains(t, attributes.FieldFormatMap, `"http.duration":{"id":"duration"}`, version)
+			assert.Contains(t, attributes.FieldAttrs, `"http.duration":{"customLabel":"Request duration"}`, version)
+		}
+	}
+
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "7.10.2")
+	require.NoError(t, err)
+	_, err = generator.Generate()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Runtime fields <http.ok, http.duration> require Kibana 7.11.0 or newer")
+	}
+	_, err = os.Stat(filepath.Join(beatDir, "_meta"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGenerateObjectFields(t *testing.T) {
+	beatDir, err := filepath.Abs("./testdata/nested")
+	if err != nil {
+		panic(err)
+	}
+	defer teardown(beatDir)
+
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "8.0.0")
+	require.NoError(t, err)
+	patterns, err := generator.GenerateIndexPatterns()
+	require.NoError(t, err)
+
+	expected := []string{
+		"@timestamp",
+		"orders.id",
+		"orders.item.price",
+		"orders.item.title",
+		"orders.item.title.raw",
+		"user.address.city",
+		"user.address.zip",
+		"user.name",
+		"_id",
+		"_type",
+		"_index",
+		"_score",
+	}
+	esTypes := map[string]interface{}{
+		"orders.id":             []interface{}{"keyword"},
+		"orders.item.price":     []interface{}{"double"},
+		"orders.item.title":     []interface{}{"text"},
+		"orders.item.title.raw": []interface{}{"keyword"},
+	}
+
+	for _, pattern := range patterns {
+		fields, err := pattern.Objects[0].Attributes.DecodeFields()
+		require.NoError(t, err)
+
+		names := make([]string, len(fields))
+		for i, f := range fields {
+			names[i] = f["name"].(string)
+			// Only the sub-fields of nested fields report their type.
+			assert.Equal(t, esTypes[names[i]], f["esTypes"], names[i])
+		}
+		assert.Equal(t, expected, names, pattern.Path)
+		assert.Contains(t, pattern.Objects[0].Attributes.FieldFormatMap, `"orders.item.price":{"id":"number"}`)
+	}
+}
+
+func TestGenerateMultiFields(t *testing.T) {
+	beatDir, err := filepath.Abs("./testdata/multi_fields")
+	if err != nil {
+		panic(err)
+	}
+	defer teardown(beatDir)
+
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "7.0.0")
+	require.NoError(t, err)
+	patterns, err := generator.GenerateIndexPatterns()
+	require.NoError(t, err)
+	require.Len(t, patterns, 2)
+
+	for _, pattern := range patterns {
+		fields, err := pattern.Objects[0].Attributes.DecodeFields()
+		require.NoError(t, err)
+
+		// The parent field is added as before, without esTypes.
+		if idx := find(fields, "message"); assert.NotEqual(t, -1, idx, pattern.Path) {
+			assert.Equal(t, "string", fields[idx]["type"])
+			assert.NotContains(t, fields[idx], "esTypes")
+			assert.Equal(t, true, fields[idx]["searchable"])
+			assert.Equal(t, false, fields[idx]["aggregatable"])
+		}
+
+		// Multi fields are siblings with their own type and flags, and keep
+		// the popularity of their parent.
+		if idx := find(fields, "message.keyword"); assert.NotEqual(t, -1, idx, pattern.Path) {
+			assert.Equal(t, "string", fields[idx]["type"])
+			assert.Equal(t, []interface{}{"keyword"}, fields[idx]["esTypes"])
+			assert.Equal(t, true, fields[idx]["searchable"])
+			assert.Equal(t, true, fields[idx]["aggregatable"])
+			assert.Equal(t, float64(5), fields[idx]["count"])
+		}
+		if idx := find(fields, "log.file.path.text"); assert.NotEqual(t, -1, idx, pattern.Path) {
+			assert.Equal(t, "string", fields[idx]["type"])
+			assert.Equal(t, []interface{}{"text"}, fields[idx]["esTypes"])
+			assert.Equal(t, true, fields[idx]["searchable"])
+			assert.Equal(t, false, fields[idx]["aggregatable"])
+		}
+		if idx := find(fields, "log.file.path"); assert.NotEqual(t, -1, idx, pattern.Path) {
+			assert.Equal(t, false, fields[idx]["searchable"])
+			assert.Equal(t, false, fields[idx]["aggregatable"])
+		}
+	}
+}
+
+func TestFilterModules(t *testing.T) {
+	beatDir, err := filepath.Abs("./testdata/modules")
+	if err != nil {
+		panic(err)
+	}
+	defer teardown(beatDir)
+
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "8.0.0")
+	require.NoError(t, err)
+	generator.SetFieldAttrs(true)
+	untagged, err := generator.GenerateBytes()
+	require.NoError(t, err)
+
+	// Without module tags, index patterns can not be filtered.
+	patterns, err := generator.GenerateIndexPatterns()
+	require.NoError(t, err)
+	assert.Nil(t, patterns[0].Modules)
+	_, err = patterns[0].FilterModules("system")
+	assert.Error(t, err)
+
+	generator.SetModuleTags(true)
+	patterns, err = generator.GenerateIndexPatterns()
+	require.NoError(t, err)
+	require.Len(t, patterns, 3)
+
+	// The generated index patterns are not changed by the tags.
+	tagged, err := generator.GenerateBytes()
+	require.NoError(t, err)
+	assert.Equal(t, untagged, tagged)
+
+	for _, pattern := range patterns {
+		assert.Equal(t, "system", pattern.Modules["system.cpu.user.pct"], pattern.Path)
+		assert.NotContains(t, pattern.Modules, "@timestamp", pattern.Path)
+
+		filtered, err := pattern.FilterModules("system")
+		require.NoError(t, err, pattern.Path)
+		assert.Equal(t, map[string]string{
+			"system.cpu.user.pct": "system",
+			"system.process.name": "system",
+		}, filtered.Modules)
+
+		attributes := filtered.Objects[0].Attributes
+		fields, err := attributes.DecodeFields()
+		require.NoError(t, err)
+		names := make([]string, len(fields))
+		for i, f := range fields {
+			names[i] = f["name"].(string)
+		}
+		assert.Equal(t, []string{
+			"@timestamp",
+			"system.cpu.user.pct",
+			"system.process.name",
+			"_id",
+			"_type",
+			"_index",
+			"_score",
+		}, names, pattern.Path)
+		assert.Equal(t, `{"system.cpu.user.pct":{"id":"percent"}}`, attributes.FieldFormatMap, pattern.Path)
+		assert.Empty(t, attributes.RuntimeFieldMap, pattern.Path)
+
+		// The original index pattern is kept.
+		original := pattern.Objects[0].Attributes
+		assert.Contains(t, original.Fields, `"apache.status.bytes"`)
+		assert.Contains(t, original.FieldFormatMap, `"apache.status.bytes"`)
+	}
+
+	// fieldAttrs and runtime fields are filtered by module, too.
+	filtered, err := patterns[2].FilterModules("apache")
+	require.NoError(t, err)
+	attributes := filtered.Objects[0].Attributes
+	assert.Equal(t, `{"apache.status.bytes":{"customLabel":"Bytes"}}`, attributes.FieldAttrs)
+	assert.Contains(t, attributes.RuntimeFieldMap, `"apache.status.busy"`)
+	assert.NotContains(t, attributes.Fields, `"system.`)
+
+	_, err = patterns[1].FilterModules("system", "nginx")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "no fields found for modules nginx")
+	}
+}
+
+func TestGeneratePopularity(t *testing.T) {
+	beatDir, err := filepath.Abs("./testdata/popularity")
+	if err != nil {
+		panic(err)
+	}
+	defer teardown(beatDir)
+
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "7.0.0")
+	require.NoError(t, err)
+	patterns, err := generator.GenerateIndexPatterns()
+	require.NoError(t, err)
+	require.Len(t, patterns, 2)
+
+	expected := map[string]float64{
+		"message":    10,
+		"host.name":  3,
+		"event.kind": 0,
+	}
+	for _, pattern := range patterns {
+		fields, err := pattern.Objects[0].Attributes.DecodeFields()
+		require.NoError(t, err)
+
+		for name, count := range expected {
+			idx := find(fields, name)
+			if assert.NotEqual(t, -1, idx, name) {
+				assert.Equal(t, count, fields[idx]["count"], "%s in %s", name, pattern.Path)
+			}
+		}
+	}
+}
+
+func TestGenerateFormatParams(t *testing.T) {
+	beatDir, err := filepath.Abs("./testdata/formats")
+	if err != nil {
+		panic(err)
+	}
+	defer teardown(beatDir)
+
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "7.0.0")
+	require.NoError(t, err)
+	patterns, err := generator.GenerateIndexPatterns()
+	require.NoError(t, err)
+	require.Len(t, patterns, 2)
+
+	expected := map[string]interface{}{
+		"system.cpu.pct": map[string]interface{}{
+			"id":     "percent",
+			"params": map[string]interface{}{"pattern": "0.[00]%"},
+		},
+		"http.status": map[string]interface{}{
+			"id": "color",
+			"params": map[string]interface{}{
+				"fieldType": "number",
+				"colors": []interface{}{
+					map[string]interface{}{
+						"range":      "-Infinity:400",
+						"regex":      "<insert regex>",
+						"text":       "#000000",
+						"background": "#54B399",
+					},
+					map[string]interface{}{
+						"range":      "400:Infinity",
+						"regex":      "<insert regex>",
+						"text":       "#FFFFFF",
+						"background": "#E7664C",
+					},
+				},
+			},
+		},
+	}
+	for _, pattern := range patterns {
+		var fieldFormatMap map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(pattern.Objects[0].Attributes.FieldFormatMap), &fieldFormatMap))
+		assert.Equal(t, expected, fieldFormatMap, pattern.Path)
+	}
+}
+
+func TestNewGeneratorFromFiles(t *testing.T) {
+	beatDir := tmpPath()
+	defer teardown(beatDir)
+	files := []string{
+		filepath.Join(beatDir, "merge/base.yml"),
+		filepath.Join(beatDir, "merge/system.yml"),
+	}
+
+	_, err := NewGeneratorFromFiles("beat-*", "beat", beatDir, "7.0.0", nil)
+	assert.Error(t, err)
+	_, err = NewGeneratorFromFiles("beat-*", "beat", beatDir, "7.0.0", append(files, filepath.Join(beatDir, "merge/notexistent.yml")))
+	assert.Error(t, err)
+
+	generator, err := NewGeneratorFromFiles("beat-*", "beat", beatDir, "7.0.0", files)
+	assert.NoError(t, err)
+
+	// the fields follow the order of the files, then the order in the files
+	fields, err := loadFieldsFiles(generator.fieldsYamls)
+	assert.NoError(t, err)
+	var names []string
+	for _, f := range fields {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"@timestamp", "message", "system", "system"}, names)
+
+	patterns, err := generator.GenerateIndexPatterns()
+	assert.NoError(t, err)
+	created, err := patterns[1].Objects[0].Attributes.DecodeFields()
+	assert.NoError(t, err)
+	for _, name := range []string{"@timestamp", "message", "system.hostname", "system.cpu.pct", "system.load.1"} {
+		assert.NotEqual(t, -1, find(created, name), "field %v", name)
+	}
+	assert.Equal(t, 9, len(created))
+}
+
+func TestNewGeneratorFromFilesDuplicateFields(t *testing.T) {
+	beatDir := tmpPath()
+	defer teardown(beatDir)
+	base := filepath.Join(beatDir, "merge/base.yml")
+	system := filepath.Join(beatDir, "merge/system.yml")
+	duplicate := filepath.Join(beatDir, "merge/duplicate.yml")
+
+	generator, err := NewGeneratorFromFiles("beat-*", "beat", beatDir, "7.0.0", []string{base, system, duplicate})
+	assert.NoError(t, err)
+
+	_, err = generator.Generate()
+	if assert.Error(t, err) {
+		assert.Equal(t, fmt.Sprintf("ERROR: Fields are duplicated: <message> in files %s, %s; "+
+			"<system.cpu.pct> in files %s, %s. Please update and try again.",
+			base, duplicate, system, duplicate), err.Error())
+	}
+
+	// nothing is written on errors
+	_, err = os.Stat(filepath.Join(beatDir, "_meta"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGenerateNamespace(t *testing.T) {
+	beatDir, err := filepath.Abs("./testdata/extensive")
+	if err != nil {
+		panic(err)
+	}
+	defer teardown(beatDir)
+	generator, err := NewGenerator("metricbeat-*", "metricbeat", beatDir, "7.0.0-alpha1")
+	assert.NoError(t, err)
+	// the id set only applies to the index pattern of all namespaces,
+	// namespaced index patterns keep their derived ids
+	generator.SetID("metricbeat")
+	pattern, err := generator.GenerateNamespace("docker")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(beatDir, "_meta/kibana/5.x/index-pattern/metricbeat-docker.json"),
+		filepath.Join(beatDir, "_meta/kibana/default/index-pattern/metricbeat-docker.json"),
+	}, pattern)
+
+	created, err := readJson(pattern[1])
+	assert.NoError(t, err)
+	obj := created["objects"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "metricbeat-docker-*", obj["id"])
+	attributes := obj["attributes"].(map[string]interface{})
+	assert.Equal(t, "metricbeat-docker-*", attributes["title"])
+
+	var fields []map[string]interface{}
+	err = json.Unmarshal([]byte(attributes["fields"].(string)), &fields)
+	assert.NoError(t, err)
+	assert.NotEqual(t, -1, find(fields, "@timestamp"))
+	assert.NotEqual(t, -1, find(fields, "docker.container.id"))
+	for _, f := range fields {
+		name := f["name"].(string)
+		if name == "@timestamp" || strings.HasPrefix(name, "_") {
+			continue
+		}
+		assert.True(t, strings.HasPrefix(name, "docker."), "unexpected field %s", name)
+	}
+
+	_, err = generator.GenerateNamespace("notexistent")
+	assert.Error(t, err)
+}
+
+func TestGenerateKeepSafeChars(t *testing.T) {
+	beatDir, err := filepath.Abs("./testdata/extensive")
+	require.NoError(t, err)
+	generator, err := NewGenerator("metricbeat-*", "my-custom-beat", beatDir, "7.0.0-alpha1")
+	require.NoError(t, err)
+
+	// Hyphens are removed by default.
+	patterns, err := generator.GenerateNamespaceBytes("docker")
+	require.NoError(t, err)
+	assert.Contains(t, patterns, filepath.Join(beatDir, "_meta/kibana/default/index-pattern/mycustombeat-docker.json"))
+
+	generator.SetKeepSafeChars(true)
+	patterns, err = generator.GenerateBytes()
+	require.NoError(t, err)
+	assert.Contains(t, patterns, filepath.Join(beatDir, "_meta/kibana/default/index-pattern/my-custom-beat.json"))
+
+	patterns, err = generator.GenerateNamespaceBytes("docker")
+	require.NoError(t, err)
+	assert.Contains(t, patterns, filepath.Join(beatDir, "_meta/kibana/5.x/index-pattern/my-custom-beat-docker.json"))
+	assert.Contains(t, patterns, filepath.Join(beatDir, "_meta/kibana/default/index-pattern/my-custom-beat-docker.json"))
+
+	generator.SetKeepSafeChars(false)
+	assert.Equal(t, "mycustombeat.json", generator.targetFilename)
+}
+
+func TestFilterNamespace(t *testing.T) {
+	fields := common.Fields{
+		{Name: "@timestamp", Type: "date"},
+		{Name: "system", Type: "group", Fields: common.Fields{
+			{Name: "cpu", Type: "group", Fields: common.Fields{
+				{Name: "user.pct", Type: "scaled_float"},
+			}},
+			{Name: "memory.total", Type: "long"},
+		}},
+		{Name: "system.process.name", Type: "keyword"},
+		{Name: "systemd", Type: "keyword"},
+	}
+
+	assert.Equal(t, common.Fields{fields[1], fields[2]}, filterNamespace(fields, "system", ""))
+	assert.Equal(t, common.Fields{
+		{Name: "system", Type: "group", Fields: common.Fields{fields[1].Fields[0]}},
+	}, filterNamespace(fields, "system.cpu", ""))
+	assert.Empty(t, filterNamespace(fields, "docker", ""))
+}
+
+func TestNamespacedIndexName(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "metricbeat-*", expected: "metricbeat-system-*"},
+		{input: "metricbeat", expected: "metricbeat-system-*"},
+		{input: "remote:metricbeat-*", expected: "remote:metricbeat-system-*"},
+		{input: "r1:beat-*,r2:beat-*", expected: "r1:beat-system-*,r2:beat-system-*"},
+	}
+	for idx, test := range tests {
+		output := namespacedIndexName(test.input, "system")
+		msg := fmt.Sprintf("(%v): Expected <%s> Received: <%s>", idx, test.expected, output)
+		assert.Equal(t, test.expected, output, msg)
+	}
+}
+
 func testGenerate(t *testing.T, beatDir string, tests []map[string]string) {
 	for _, test := range tests {
 		// compare default
@@ -137,7 +1264,14 @@ func testGenerate(t *testing.T, beatDir string, tests []map[string]string) {
 
 		var attrExisting, attrCreated common.MapStr
 
-		if strings.Contains(test["existing"], "default") {
+		if strings.Contains(test["existing"], "8x") {
+			for _, key := range []string{"type", "id", "coreMigrationVersion", "references"} {
+				assert.Equal(t, existing[key], created[key])
+			}
+
+			attrExisting = existing["attributes"].(map[string]interface{})
+			attrCreated = created["attributes"].(map[string]interface{})
+		} else if strings.Contains(test["existing"], "default") {
 			assert.Equal(t, existing["version"], created["version"])
 
 			objExisting := existing["objects"].([]interface{})[0].(map[string]interface{})
@@ -169,10 +1303,13 @@ func testGenerate(t *testing.T, beatDir string, tests []map[string]string) {
 		err = json.Unmarshal([]byte(attrCreated["fields"].(string)), &fieldsCreated)
 		assert.NoError(t, err)
 		assert.Equal(t, len(fieldsExisting), len(fieldsCreated))
-		for _, e := range fieldsExisting {
+		for i, e := range fieldsExisting {
 			idx := find(fieldsCreated, e["name"].(string))
 			assert.NotEqual(t, -1, idx)
 			assert.Equal(t, e, fieldsCreated[idx])
+
+			// fields are generated in a stable order
+			assert.Equal(t, i, idx, "position of field %v", e["name"])
 		}
 	}
 }
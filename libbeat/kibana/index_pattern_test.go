@@ -0,0 +1,89 @@
+package kibana
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateIndexPatterns(t *testing.T) {
+	beatDir := tmpPath()
+	defer teardown(beatDir)
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "8.0.0-alpha1")
+	require.NoError(t, err)
+
+	patterns, err := generator.GenerateIndexPatterns()
+	require.NoError(t, err)
+	require.Len(t, patterns, 3)
+
+	_, err = os.Stat(filepath.Join(beatDir, "_meta"))
+	assert.True(t, os.IsNotExist(err))
+
+	index5x, index6x, index8x := patterns[0], patterns[1], patterns[2]
+	assert.Equal(t, filepath.Join(beatDir, "_meta/kibana/5.x/index-pattern/beat.json"), index5x.Path)
+	assert.Equal(t, "", index5x.Version)
+	require.Len(t, index5x.Objects, 1)
+	assert.Equal(t, "", index5x.Objects[0].Type)
+
+	assert.Equal(t, filepath.Join(beatDir, "_meta/kibana/default/index-pattern/beat.json"), index6x.Path)
+	assert.Equal(t, "8.0.0-alpha1", index6x.Version)
+	require.Len(t, index6x.Objects, 1)
+	assert.Equal(t, "index-pattern", index6x.Objects[0].Type)
+	assert.Equal(t, "beat-*", index6x.Objects[0].ID)
+	assert.Equal(t, 1, index6x.Objects[0].Version)
+
+	assert.Equal(t, filepath.Join(beatDir, "_meta/kibana/8.x/data-view/beat.json"), index8x.Path)
+	require.Len(t, index8x.Objects, 1)
+	assert.Equal(t, "data-view", index8x.Objects[0].Type)
+	assert.Equal(t, "8.0.0-alpha1", index8x.Objects[0].CoreMigrationVersion)
+
+	for _, pattern := range patterns {
+		attributes := pattern.Objects[0].Attributes
+		assert.Equal(t, "beat-*", attributes.Title)
+		assert.Equal(t, "@timestamp", attributes.TimeFieldName)
+		assert.Contains(t, attributes.FieldFormatMap, `"long":{"id":"url"`)
+		assert.Empty(t, attributes.FieldAttrs)
+
+		fields, err := attributes.DecodeFields()
+		require.NoError(t, err)
+		assert.NotEqual(t, -1, find(fields, "long"), "fields of %v", pattern.Path)
+	}
+}
+
+func TestGenerateIndexPatternsMatchesGenerateInMemory(t *testing.T) {
+	beatDir := tmpPath()
+	defer teardown(beatDir)
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "7.11.0")
+	require.NoError(t, err)
+	generator.SetFieldAttrs(true)
+
+	patterns, err := generator.GenerateIndexPatterns()
+	require.NoError(t, err)
+	maps, err := generator.GenerateInMemory()
+	require.NoError(t, err)
+	require.Len(t, patterns, len(maps))
+
+	// The default index pattern encodes like the generated file.
+	expected, err := json.Marshal(maps[1])
+	require.NoError(t, err)
+	actual, err := json.Marshal(patterns[1])
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(actual))
+	assert.NotEmpty(t, patterns[1].Objects[0].Attributes.FieldAttrs)
+
+	// The 5.x index pattern only has the attributes.
+	expected, err = json.Marshal(maps[0])
+	require.NoError(t, err)
+	actual, err = json.Marshal(patterns[0].Objects[0].Attributes)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(actual))
+}
+
+func TestDecodeFieldsInvalid(t *testing.T) {
+	_, err := IndexPatternAttributes{Fields: "not json"}.DecodeFields()
+	assert.Error(t, err)
+}
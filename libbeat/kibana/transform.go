@@ -1,20 +1,52 @@
 package kibana
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/elastic/beats/libbeat/common"
 )
 
 type transformer struct {
+	// ctx stops the transformation with its error once it is done.
+	ctx                       context.Context
 	fields                    common.Fields
 	transformedFields         []common.MapStr
 	transformedFieldFormatMap common.MapStr
+	transformedFieldAttrs     common.MapStr
+	transformedRuntimeFields  common.MapStr
 	timeFieldName             string
 	title                     string
 	version                   *common.Version
-	keys                      common.MapStr
+	// keys holds the groups defining each field, to report duplicated fields.
+	keys map[string][]string
+
+	// esTypes holds the Elasticsearch type of each field, aliases the alias
+	// fields by their name. Aliases are resolved once all fields are
+	// transformed, as an alias might point to a field defined later.
+	esTypes map[string]string
+	aliases map[string]common.Field
+
+	// fieldAttrs enables adding field labels and descriptions to fieldAttrs
+	fieldAttrs bool
+	// locale selects the localized labels added to fieldAttrs.
+	locale string
+
+	// runtimeFields enables adding runtime fields to the runtimeFieldMap,
+	// otherwise they are skipped.
+	runtimeFields bool
+
+	// skipped counts the fields not added, disabled fields and the runtime
+	// fields skipped.
+	skipped int
+
+	// module is the top-level group of the fields being transformed, modules
+	// the module of each field added.
+	module  string
+	modules map[string]string
 }
 
 func newTransformer(timeFieldName, title string, version *common.Version, fields common.Fields) (*transformer, error) {
@@ -22,13 +54,19 @@ func newTransformer(timeFieldName, title string, version *common.Version, fields
 		return nil, errors.New("Version must be given")
 	}
 	return &transformer{
+		ctx:                       context.Background(),
 		timeFieldName:             timeFieldName,
 		title:                     title,
 		fields:                    fields,
 		version:                   version,
 		transformedFields:         []common.MapStr{},
 		transformedFieldFormatMap: common.MapStr{},
-		keys: common.MapStr{},
+		transformedFieldAttrs:     common.MapStr{},
+		transformedRuntimeFields:  common.MapStr{},
+		keys:                      map[string][]string{},
+		esTypes:                   map[string]string{},
+		aliases:                   map[string]common.Field{},
+		modules:                   map[string]string{},
 	}, nil
 }
 
@@ -42,7 +80,21 @@ func (t *transformer) transformFields() (transformed common.MapStr, err error) {
 		}
 	}()
 
-	t.transform(t.fields, "")
+	t.transform(t.fields, "", false)
+	t.module = ""
+	if err := t.validateDuplicates(); err != nil {
+		return nil, err
+	}
+	if err := t.resolveAliases(); err != nil {
+		return nil, err
+	}
+
+	// Sort the fields by name, so the generated index pattern is stable. The
+	// meta fields are added last, the keys of the fieldFormatMap are sorted
+	// when encoded.
+	sort.SliceStable(t.transformedFields, func(i, j int) bool {
+		return t.transformedFields[i]["name"].(string) < t.transformedFields[j]["name"].(string)
+	})
 
 	// add some meta fields
 	truthy := true
@@ -52,48 +104,133 @@ func (t *transformer) transformFields() (transformed common.MapStr, err error) {
 	t.add(common.Field{Path: "_index", Type: "keyword", Index: &falsy, Analyzed: &falsy, DocValues: &falsy, Searchable: &falsy, Aggregatable: &falsy})
 	t.add(common.Field{Path: "_score", Type: "integer", Index: &falsy, Analyzed: &falsy, DocValues: &falsy, Searchable: &falsy, Aggregatable: &falsy})
 
+	if err := t.validateFieldFormatMap(); err != nil {
+		return nil, err
+	}
+
 	transformed = common.MapStr{
 		"timeFieldName":  t.timeFieldName,
 		"title":          t.title,
 		"fields":         t.transformedFields,
 		"fieldFormatMap": t.transformedFieldFormatMap,
 	}
+	if t.fieldAttrs {
+		transformed["fieldAttrs"] = t.transformedFieldAttrs
+	}
+	if len(t.transformedRuntimeFields) > 0 {
+		transformed["runtimeFieldMap"] = t.transformedRuntimeFields
+	}
 	return
 }
 
-func (t *transformer) transform(commonFields common.Fields, path string) {
+// transform adds the fields below path. Fields below a `nested` field are
+// flagged by nested, they report their Elasticsearch type in `esTypes`.
+func (t *transformer) transform(commonFields common.Fields, path string, nested bool) {
 	for _, f := range commonFields {
 		f.Path = f.Name
 		if path != "" {
 			f.Path = path + "." + f.Name
 		}
 
-		if t.keys[f.Path] != nil {
-			msg := fmt.Sprintf("ERROR: Field <%s> is duplicated. Please update and try again.", f.Path)
-			panic(errors.New(msg))
+		if !fieldEnabled(f) {
+			t.skipped += countFields(f)
+			continue
+		}
+
+		// The fields of a top-level group belong to its module, top-level
+		// fields out of groups to none.
+		if path == "" {
+			t.module = ""
+			if isContainer(f) {
+				t.module = f.Name
+			}
 		}
 
-		if f.Type == "group" {
-			if f.Enabled == nil || *f.Enabled {
-				t.transform(f.Fields, f.Path)
+		if isContainer(f) {
+			if err := t.ctx.Err(); err != nil {
+				panic(err)
 			}
+
+			// Like groups, objects and nested fields with sub-fields are not
+			// added themselves, only their sub-fields are.
+			t.transform(f.Fields, f.Path, nested || f.Type == "nested")
 		} else {
-			t.keys[f.Path] = true
+			defined := t.keys[f.Path]
+			t.keys[f.Path] = append(defined, path)
+			if len(defined) > 0 {
+				// Only the first definition is added, duplicates are
+				// reported once all fields are collected.
+				continue
+			}
+
+			if count := fieldCount(f); count < 0 {
+				panic(fmt.Errorf("ERROR: Field <%s> has negative popularity <%d>. Please update and try again.", f.Path, count))
+			}
+
+			if f.Runtime {
+				t.addRuntimeField(f)
+				continue
+			}
+
 			t.add(f)
+			t.addFieldAttrs(f)
+			if nested {
+				t.addESTypes()
+			}
 
-			if f.MultiFields != nil {
-				path := f.Path
-				for _, mf := range f.MultiFields {
-					f.Type = mf.Type
-					f.Path = path + "." + mf.Name
-					t.add(f)
-				}
+			for _, mf := range f.MultiFields {
+				t.addMultiField(f, mf)
 			}
 		}
 	}
 }
 
+// isContainer returns true for groups, and for objects and nested fields
+// defining sub-fields. Objects without sub-fields, like objects with an
+// `object_type` for dynamic keys, are added as fields.
+func isContainer(f common.Field) bool {
+	switch f.Type {
+	case "group":
+		return true
+	case "object", "nested":
+		return len(f.Fields) > 0
+	default:
+		return false
+	}
+}
+
+// countFields returns the number of fields a field adds to the index pattern,
+// including its multi-fields, or the number of fields of a group.
+func countFields(f common.Field) int {
+	if !isContainer(f) {
+		return 1 + len(f.MultiFields)
+	}
+	count := 0
+	for _, child := range f.Fields {
+		count += countFields(child)
+	}
+	return count
+}
+
+// fieldEnabled returns false if the field or group is disabled with
+// `enabled: false`. Disabled fields and all fields of disabled groups are not
+// added to the index pattern.
+func fieldEnabled(f common.Field) bool {
+	return f.Enabled == nil || *f.Enabled
+}
+
 func (t *transformer) add(f common.Field) {
+	if f.Type == "alias" {
+		t.aliases[f.Path] = f
+	} else if f.Type == "" {
+		t.esTypes[f.Path] = "keyword"
+	} else {
+		t.esTypes[f.Path] = f.Type
+	}
+	if t.module != "" {
+		t.modules[f.Path] = t.module
+	}
+
 	field, fieldFormat := transformField(t.version, f)
 	t.transformedFields = append(t.transformedFields, field)
 	if fieldFormat != nil {
@@ -102,10 +239,243 @@ func (t *transformer) add(f common.Field) {
 
 }
 
+// addMultiField adds a multi field of parent as the sibling field
+// `<parent>.<name>`. It keeps the popularity and format of its parent, but is
+// indexed with its own type and flags, so its Elasticsearch type is reported
+// in `esTypes`, telling apart the `text` and `keyword` variants of a field.
+func (t *transformer) addMultiField(parent, mf common.Field) {
+	f := parent
+	f.Name = mf.Name
+	f.Path = parent.Path + "." + mf.Name
+	f.Type = mf.Type
+	f.Index = mf.Index
+	f.Analyzed = mf.Analyzed
+	f.DocValues = mf.DocValues
+	f.Searchable = mf.Searchable
+	f.Aggregatable = mf.Aggregatable
+	f.MultiFields = nil
+	if mf.Format != "" {
+		f.Format = mf.Format
+	}
+
+	defined := t.keys[f.Path]
+	t.keys[f.Path] = append(defined, parent.Path)
+	if len(defined) > 0 {
+		return
+	}
+
+	t.add(f)
+	t.addESTypes()
+}
+
+// addESTypes reports the Elasticsearch type of the last added field in
+// `esTypes`, as done by Kibana for the sub-fields of nested fields. The type of
+// aliases is set once they are resolved.
+func (t *transformer) addESTypes() {
+	field := t.transformedFields[len(t.transformedFields)-1]
+	name := field["name"].(string)
+	if esType, ok := t.esTypes[name]; ok {
+		field["esTypes"] = []string{esType}
+	}
+}
+
+// runtimeFieldTypes are the types supported for runtime fields.
+var runtimeFieldTypes = map[string]bool{
+	"keyword":   true,
+	"long":      true,
+	"double":    true,
+	"date":      true,
+	"ip":        true,
+	"boolean":   true,
+	"geo_point": true,
+}
+
+// addRuntimeField adds a runtime field to the runtimeFieldMap, with its
+// Painless script as source. Kibana lists runtime fields from the
+// runtimeFieldMap, so they are not added to the fields. Their formats and
+// attributes are added like for other fields.
+func (t *transformer) addRuntimeField(f common.Field) {
+	if !t.runtimeFields {
+		t.skipped++
+		return
+	}
+
+	if f.Script == "" {
+		panic(fmt.Errorf("ERROR: Runtime field <%s> has no script. Please update and try again.", f.Path))
+	}
+	typ := f.Type
+	if typ == "" {
+		typ = "keyword"
+	}
+	if !runtimeFieldTypes[typ] {
+		panic(fmt.Errorf("ERROR: Runtime field <%s> has unsupported type <%s>. Please update and try again.", f.Path, typ))
+	}
+
+	if t.module != "" {
+		t.modules[f.Path] = t.module
+	}
+	t.transformedRuntimeFields[f.Path] = common.MapStr{
+		"type":   typ,
+		"script": common.MapStr{"source": f.Script},
+	}
+	if _, fieldFormat := transformField(t.version, f); fieldFormat != nil {
+		t.transformedFieldFormatMap[f.Path] = fieldFormat
+	}
+	t.addFieldAttrs(f)
+}
+
+// validateDuplicates returns an error listing every field defined more than
+// once and the groups defining it, as Kibana fails to import index patterns
+// with duplicated fields.
+func (t *transformer) validateDuplicates() error {
+	var duplicates []string
+	for name, groups := range t.keys {
+		if len(groups) < 2 {
+			continue
+		}
+
+		names := make([]string, len(groups))
+		for i, group := range groups {
+			if group == "" {
+				group = "(top level)"
+			}
+			names[i] = group
+		}
+		duplicates = append(duplicates, fmt.Sprintf("<%s> in groups %s", name, strings.Join(names, ", ")))
+	}
+	if len(duplicates) > 0 {
+		sort.Strings(duplicates)
+		return fmt.Errorf("ERROR: Fields are duplicated: %s. Please update and try again.", strings.Join(duplicates, "; "))
+	}
+	return nil
+}
+
+// resolveAliases sets the type of alias fields to the type of the field they
+// point to, following aliases of aliases. Like Elasticsearch field
+// capabilities, the Elasticsearch type of the target is reported in
+// `esTypes`, and the target decides if the alias is searchable and
+// aggregatable, unless set explicitly for the alias.
+func (t *transformer) resolveAliases() error {
+	if len(t.aliases) == 0 {
+		return nil
+	}
+
+	fields := map[string]common.MapStr{}
+	for _, f := range t.transformedFields {
+		fields[f["name"].(string)] = f
+	}
+
+	for name, alias := range t.aliases {
+		target, err := t.aliasTarget(name, alias.AliasPath)
+		if err != nil {
+			return err
+		}
+
+		field, targetField := fields[name], fields[target]
+		if typ, ok := targetField["type"]; ok {
+			field["type"] = typ
+		}
+		field["esTypes"] = []string{t.esTypes[target]}
+		if alias.Searchable == nil {
+			field["searchable"] = targetField["searchable"]
+		}
+		if alias.Aggregatable == nil {
+			field["aggregatable"] = targetField["aggregatable"]
+		}
+	}
+	return nil
+}
+
+// aliasTarget returns the path of the concrete field an alias resolves to.
+func (t *transformer) aliasTarget(name, path string) (string, error) {
+	seen := map[string]bool{name: true}
+	for {
+		if path == "" {
+			return "", fmt.Errorf("ERROR: Alias <%s> has no path. Please update and try again.", name)
+		}
+		if seen[path] {
+			return "", fmt.Errorf("ERROR: Alias <%s> points to itself through <%s>. Please update and try again.", name, path)
+		}
+		seen[path] = true
+
+		if next, isAlias := t.aliases[path]; isAlias {
+			path = next.AliasPath
+			continue
+		}
+		if _, exists := t.esTypes[path]; !exists {
+			return "", fmt.Errorf("ERROR: Alias <%s> points to unknown field <%s>. Please update and try again.", name, path)
+		}
+		return path, nil
+	}
+}
+
+// validateFieldFormatMap ensures all formats of the fieldFormatMap belong to a
+// field of the index pattern, as Kibana silently ignores formats of unknown
+// fields.
+func (t *transformer) validateFieldFormatMap() error {
+	names := map[string]bool{}
+	for _, f := range t.transformedFields {
+		names[f["name"].(string)] = true
+	}
+	for name := range t.transformedRuntimeFields {
+		names[name] = true
+	}
+
+	var unknown []string
+	for name := range t.transformedFieldFormatMap {
+		if !names[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("ERROR: fieldFormatMap references unknown fields <%s>. Please update and try again.", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// addFieldAttrs adds the label of a field as custom label and its description
+// as custom description, shown as tooltip by Kibana. Multi fields are not
+// annotated, as they share the definition of their parent field.
+func (t *transformer) addFieldAttrs(f common.Field) {
+	if !t.fieldAttrs {
+		return
+	}
+
+	attrs := common.MapStr{}
+	if label := strings.TrimSpace(fieldLabel(f, t.locale)); label != "" {
+		attrs["customLabel"] = label
+	}
+	if description := strings.TrimSpace(f.Description); description != "" {
+		attrs["customDescription"] = description
+	}
+	if len(attrs) > 0 {
+		t.transformedFieldAttrs[f.Path] = attrs
+	}
+}
+
+// fieldLabel returns the label of the field for the locale. The label of the
+// locale, like `pt-BR`, is preferred to the label of its language, like `pt`.
+// The default label is used if the field has none of them.
+func fieldLabel(f common.Field, locale string) string {
+	if locale == "" || len(f.Labels) == 0 {
+		return f.Label
+	}
+	if label, ok := f.Labels[locale]; ok {
+		return label
+	}
+	if idx := strings.IndexAny(locale, "-_"); idx > 0 {
+		if label, ok := f.Labels[locale[:idx]]; ok {
+			return label
+		}
+	}
+	return f.Label
+}
+
 func transformField(version *common.Version, f common.Field) (common.MapStr, common.MapStr) {
 	field := common.MapStr{
 		"name":         f.Path,
-		"count":        f.Count,
+		"count":        fieldCount(f),
 		"scripted":     false,
 		"indexed":      getVal(f.Index, true),
 		"analyzed":     getVal(f.Analyzed, false),
@@ -118,10 +488,30 @@ func transformField(version *common.Version, f common.Field) (common.MapStr, com
 		field["type"] = t
 	}
 
-	if f.Type == "text" {
+	// Text fields are not aggregatable, unless set explicitly.
+	if f.Type == "text" && f.Aggregatable == nil {
 		field["aggregatable"] = false
 	}
 
+	// Geo fields report their Elasticsearch type, Kibana maps use it to find
+	// the fields they can display. They are not aggregatable, unless set
+	// explicitly, and geo shapes have no doc values.
+	if f.Type == "geo_point" || f.Type == "geo_shape" {
+		field["esTypes"] = []string{f.Type}
+		if f.Aggregatable == nil {
+			field["aggregatable"] = false
+		}
+		if f.Type == "geo_shape" && f.DocValues == nil {
+			field["doc_values"] = false
+		}
+	}
+
+	// Dates with nanosecond resolution are dates for Kibana, the Elasticsearch
+	// type tells them apart from dates with millisecond resolution.
+	if f.Type == "date_nanos" {
+		field["esTypes"] = []string{f.Type}
+	}
+
 	if f.Script != "" {
 		field["scripted"] = true
 		field["script"] = f.Script
@@ -130,18 +520,30 @@ func transformField(version *common.Version, f common.Field) (common.MapStr, com
 	}
 
 	var format common.MapStr
-	if f.Format != "" || f.Pattern != "" {
+	if f.Format != "" || f.Pattern != "" || len(f.Params) > 0 {
 		format = common.MapStr{}
 
 		if f.Format != "" {
 			format["id"] = f.Format
 		}
 		addParams(&format, version, f)
+	} else if f.Type == "date_nanos" && dateNanosFormatVersion.LessThanOrEqual(true, version) {
+		// Kibana formats dates with millisecond resolution by default.
+		format = common.MapStr{"id": "date_nanos"}
 	}
 
 	return field, format
 }
 
+// fieldCount returns the popularity of the field, Kibana lists popular fields
+// first. It is set with `count`, or `popularity` if `count` is not set.
+func fieldCount(f common.Field) int {
+	if f.Count != 0 {
+		return f.Count
+	}
+	return f.Popularity
+}
+
 func getVal(valP *bool, def bool) bool {
 	if valP != nil {
 		return *valP
@@ -149,7 +551,14 @@ func getVal(valP *bool, def bool) bool {
 	return def
 }
 
+// addParams adds the params of the format. The params set with `params` are
+// copied verbatim, so formats and params unknown to the generator can be used,
+// the params with a dedicated setting take precedence.
 func addParams(format *common.MapStr, version *common.Version, f common.Field) {
+	for key, val := range f.Params {
+		createParam(format)
+		(*format)["params"].(common.MapStr)[key] = val
+	}
 	addFormatParam(format, "pattern", f.Pattern)
 	addFormatParam(format, "inputFormat", f.InputFormat)
 	addFormatParam(format, "outputFormat", f.OutputFormat)
@@ -204,17 +613,25 @@ func createParam(f *common.MapStr) {
 
 var (
 	typeMapping = map[string]string{
-		"half_float":   "number",
-		"scaled_float": "number",
-		"float":        "number",
-		"integer":      "number",
-		"long":         "number",
-		"short":        "number",
-		"byte":         "number",
-		"text":         "string",
-		"keyword":      "string",
-		"":             "string",
-		"geo_point":    "geo_point",
-		"date":         "date",
+		"half_float":    "number",
+		"scaled_float":  "number",
+		"float":         "number",
+		"double":        "number",
+		"integer":       "number",
+		"long":          "number",
+		"unsigned_long": "number",
+		"short":         "number",
+		"byte":          "number",
+		"text":          "string",
+		"keyword":       "string",
+		"":              "string",
+		"geo_point":     "geo_point",
+		"geo_shape":     "geo_shape",
+		"date":          "date",
+		"date_nanos":    "date",
 	}
+
+	// dateNanosFormatVersion is the first version of Kibana with the
+	// `date_nanos` field format.
+	dateNanosFormatVersion, _ = common.NewVersion("7.3.0")
 )
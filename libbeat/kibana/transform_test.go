@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/elastic/beats/libbeat/common"
 )
@@ -92,6 +93,139 @@ func TestDuplicateField(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestDuplicateFieldsInGroups(t *testing.T) {
+	commonFields := common.Fields{
+		common.Field{Name: "a", Type: "group", Fields: common.Fields{
+			common.Field{Name: "b.c", Type: "keyword"},
+			common.Field{Name: "d", Type: "keyword"},
+		}},
+		common.Field{Name: "a.b", Type: "group", Fields: common.Fields{
+			common.Field{Name: "c", Type: "long"},
+		}},
+		common.Field{Name: "a.d", Type: "keyword"},
+	}
+	trans, err := newTransformer("name", "title", version, commonFields)
+	assert.NoError(t, err)
+	_, err = trans.transformFields()
+	if assert.Error(t, err) {
+		assert.Equal(t, "ERROR: Fields are duplicated: <a.b.c> in groups a, a.b; <a.d> in groups a, (top level). Please update and try again.", err.Error())
+	}
+}
+
+func TestTransformAlias(t *testing.T) {
+	commonFields := common.Fields{
+		// aliases defined before their targets are resolved in a second pass
+		common.Field{Name: "host", Type: "alias", AliasPath: "beat.hostname"},
+		common.Field{Name: "hostname", Type: "alias", AliasPath: "host"},
+		common.Field{Name: "duration", Type: "alias", AliasPath: "event.duration"},
+		common.Field{Name: "text", Type: "alias", AliasPath: "message"},
+		common.Field{Name: "host_stored", Type: "alias", AliasPath: "beat.hostname", Aggregatable: &falsy},
+		common.Field{Name: "beat", Type: "group", Fields: common.Fields{
+			common.Field{Name: "hostname"},
+		}},
+		common.Field{Name: "event.duration", Type: "long"},
+		common.Field{Name: "message", Type: "text"},
+	}
+	trans, err := newTransformer("name", "title", version, commonFields)
+	assert.NoError(t, err)
+	out, err := trans.transformFields()
+	assert.NoError(t, err)
+
+	fields := map[string]common.MapStr{}
+	for _, f := range out["fields"].([]common.MapStr) {
+		fields[f["name"].(string)] = f
+	}
+
+	for _, test := range []struct {
+		name         string
+		kibanaType   string
+		esType       string
+		aggregatable bool
+	}{
+		{name: "host", kibanaType: "string", esType: "keyword", aggregatable: true},
+		{name: "hostname", kibanaType: "string", esType: "keyword", aggregatable: true},
+		{name: "duration", kibanaType: "number", esType: "long", aggregatable: true},
+		{name: "text", kibanaType: "string", esType: "text", aggregatable: false},
+		// explicit settings of the alias override the ones of the target
+		{name: "host_stored", kibanaType: "string", esType: "keyword", aggregatable: false},
+	} {
+		f := fields[test.name]
+		assert.Equal(t, test.kibanaType, f["type"], test.name)
+		assert.Equal(t, []string{test.esType}, f["esTypes"], test.name)
+		assert.Equal(t, true, f["searchable"], test.name)
+		assert.Equal(t, test.aggregatable, f["aggregatable"], test.name)
+	}
+
+	// only alias and geo fields report the Elasticsearch types
+	assert.NotContains(t, fields["message"], "esTypes")
+}
+
+func TestTransformAliasInvalid(t *testing.T) {
+	tests := []struct {
+		fields common.Fields
+		err    string
+	}{
+		{
+			fields: common.Fields{common.Field{Name: "a", Type: "alias"}},
+			err:    "ERROR: Alias <a> has no path. Please update and try again.",
+		},
+		{
+			fields: common.Fields{common.Field{Name: "a", Type: "alias", AliasPath: "b"}},
+			err:    "ERROR: Alias <a> points to unknown field <b>. Please update and try again.",
+		},
+		{
+			fields: common.Fields{
+				common.Field{Name: "a", Type: "alias", AliasPath: "b"},
+				common.Field{Name: "b", Type: "alias", AliasPath: "a"},
+			},
+			err: "points to itself",
+		},
+	}
+	for _, test := range tests {
+		trans, err := newTransformer("name", "title", version, test.fields)
+		assert.NoError(t, err)
+		_, err = trans.transformFields()
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), test.err)
+		}
+	}
+}
+
+func TestFieldFormatMapUnknownField(t *testing.T) {
+	commonFields := common.Fields{
+		common.Field{Name: "bytes", Type: "long", Format: "bytes"},
+	}
+	trans, err := newTransformer("name", "title", version, commonFields)
+	assert.NoError(t, err)
+
+	// A format left over from a renamed field.
+	trans.transformedFieldFormatMap["renamed.bytes"] = common.MapStr{"id": "bytes"}
+
+	_, err = trans.transformFields()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "renamed.bytes")
+		assert.NotContains(t, err.Error(), "<bytes")
+	}
+}
+
+func TestFieldFormatMapMultiField(t *testing.T) {
+	commonFields := common.Fields{
+		common.Field{
+			Name:        "url",
+			Type:        "keyword",
+			Format:      "url",
+			MultiFields: common.Fields{common.Field{Name: "text", Type: "text"}},
+		},
+	}
+	trans, err := newTransformer("name", "title", version, commonFields)
+	assert.NoError(t, err)
+
+	out, err := trans.transformFields()
+	assert.NoError(t, err)
+	assert.Contains(t, out["fieldFormatMap"], "url")
+	assert.Contains(t, out["fieldFormatMap"], "url.text")
+}
+
 func TestInvalidVersion(t *testing.T) {
 	commonFields := common.Fields{
 		common.Field{
@@ -116,6 +250,9 @@ func TestTransformTypes(t *testing.T) {
 		{commonField: common.Field{}, expected: "string"},
 		{commonField: common.Field{Type: "half_float"}, expected: "number"},
 		{commonField: common.Field{Type: "scaled_float"}, expected: "number"},
+		{commonField: common.Field{Type: "scaled_float", ScalingFactor: 100}, expected: "number"},
+		{commonField: common.Field{Type: "double"}, expected: "number"},
+		{commonField: common.Field{Type: "unsigned_long"}, expected: "number"},
 		{commonField: common.Field{Type: "float"}, expected: "number"},
 		{commonField: common.Field{Type: "integer"}, expected: "number"},
 		{commonField: common.Field{Type: "long"}, expected: "number"},
@@ -125,7 +262,9 @@ func TestTransformTypes(t *testing.T) {
 		{commonField: common.Field{Type: "text"}, expected: "string"},
 		{commonField: common.Field{Type: "string"}, expected: nil},
 		{commonField: common.Field{Type: "date"}, expected: "date"},
+		{commonField: common.Field{Type: "date_nanos"}, expected: "date"},
 		{commonField: common.Field{Type: "geo_point"}, expected: "geo_point"},
+		{commonField: common.Field{Type: "geo_shape"}, expected: "geo_shape"},
 		{commonField: common.Field{Type: "invalid"}, expected: nil},
 	}
 	for idx, test := range tests {
@@ -137,6 +276,157 @@ func TestTransformTypes(t *testing.T) {
 	}
 }
 
+func TestTransformDateNanos(t *testing.T) {
+	for _, test := range []struct {
+		version string
+		field   common.Field
+		esTypes interface{}
+		format  interface{}
+	}{
+		{version: "8.0.0", field: common.Field{Name: "created", Type: "date"}},
+		{
+			version: "8.0.0",
+			field:   common.Field{Name: "created", Type: "date_nanos"},
+			esTypes: []string{"date_nanos"},
+			format:  common.MapStr{"id": "date_nanos"},
+		},
+		{
+			version: "8.0.0",
+			field:   common.Field{Name: "created", Type: "date_nanos", Format: "date"},
+			esTypes: []string{"date_nanos"},
+			format:  common.MapStr{"id": "date"},
+		},
+		{
+			// Kibana 6.x has no date_nanos format.
+			version: "6.0.0",
+			field:   common.Field{Name: "created", Type: "date_nanos"},
+			esTypes: []string{"date_nanos"},
+		},
+	} {
+		v, _ := common.NewVersion(test.version)
+		trans, err := newTransformer("name", "title", v, common.Fields{test.field})
+		require.NoError(t, err)
+		out, err := trans.transformFields()
+		require.NoError(t, err)
+
+		field := out["fields"].([]common.MapStr)[0]
+		assert.Equal(t, "date", field["type"])
+		assert.Equal(t, test.esTypes, field["esTypes"], "%v in %s", test.field, test.version)
+		assert.Equal(t, test.format, out["fieldFormatMap"].(common.MapStr)["created"], "%v in %s", test.field, test.version)
+	}
+}
+
+func TestTransformGeoTypes(t *testing.T) {
+	truthy := true
+	trans, err := newTransformer("name", "title", version, common.Fields{
+		common.Field{Name: "location", Type: "geo_point"},
+		common.Field{Name: "area", Type: "geo_shape"},
+		common.Field{Name: "grid", Type: "geo_point", Aggregatable: &truthy},
+		common.Field{Name: "location_alias", Type: "alias", AliasPath: "location"},
+	})
+	assert.NoError(t, err)
+	out, err := trans.transformFields()
+	assert.NoError(t, err)
+
+	fields := map[string]common.MapStr{}
+	for _, f := range out["fields"].([]common.MapStr) {
+		fields[f["name"].(string)] = f
+	}
+
+	for _, test := range []struct {
+		name         string
+		kibanaType   string
+		esType       string
+		aggregatable bool
+		docValues    bool
+	}{
+		{name: "location", kibanaType: "geo_point", esType: "geo_point", aggregatable: false, docValues: true},
+		{name: "area", kibanaType: "geo_shape", esType: "geo_shape", aggregatable: false, docValues: false},
+		// explicit settings override the defaults of geo fields
+		{name: "grid", kibanaType: "geo_point", esType: "geo_point", aggregatable: true, docValues: true},
+		{name: "location_alias", kibanaType: "geo_point", esType: "geo_point", aggregatable: false, docValues: true},
+	} {
+		f := fields[test.name]
+		assert.Equal(t, test.kibanaType, f["type"], test.name)
+		assert.Equal(t, []string{test.esType}, f["esTypes"], test.name)
+		assert.Equal(t, true, f["searchable"], test.name)
+		assert.Equal(t, test.aggregatable, f["aggregatable"], test.name)
+		assert.Equal(t, test.docValues, f["doc_values"], test.name)
+	}
+}
+
+func TestTransformRuntimeFields(t *testing.T) {
+	commonFields := common.Fields{
+		common.Field{Name: "status", Type: "long"},
+		common.Field{Name: "ok", Type: "boolean", Runtime: true, Script: "emit(doc['status'].value < 400)"},
+		common.Field{Name: "host", Runtime: true, Script: "emit(params._source.host)", Format: "url"},
+	}
+
+	trans, err := newTransformer("name", "title", version, commonFields)
+	assert.NoError(t, err)
+	trans.runtimeFields = true
+	out, err := trans.transformFields()
+	assert.NoError(t, err)
+
+	assert.Equal(t, common.MapStr{
+		"ok":   common.MapStr{"type": "boolean", "script": common.MapStr{"source": "emit(doc['status'].value < 400)"}},
+		"host": common.MapStr{"type": "keyword", "script": common.MapStr{"source": "emit(params._source.host)"}},
+	}, out["runtimeFieldMap"])
+	assert.Equal(t, common.MapStr{"host": common.MapStr{"id": "url"}}, out["fieldFormatMap"])
+
+	// runtime fields are listed from the runtimeFieldMap only
+	var names []string
+	for _, f := range out["fields"].([]common.MapStr) {
+		names = append(names, f["name"].(string))
+	}
+	assert.Equal(t, []string{"status", "_id", "_type", "_index", "_score"}, names)
+
+	// runtime fields are skipped if not enabled
+	trans, err = newTransformer("name", "title", version, commonFields)
+	assert.NoError(t, err)
+	out, err = trans.transformFields()
+	assert.NoError(t, err)
+	assert.NotContains(t, out, "runtimeFieldMap")
+	assert.Len(t, out["fields"], 5)
+}
+
+func TestTransformRuntimeFieldsInvalid(t *testing.T) {
+	tests := []struct {
+		field common.Field
+		err   string
+	}{
+		{
+			field: common.Field{Name: "ok", Type: "boolean", Runtime: true},
+			err:   "Runtime field <ok> has no script",
+		},
+		{
+			field: common.Field{Name: "message", Type: "text", Runtime: true, Script: "emit('')"},
+			err:   "Runtime field <message> has unsupported type <text>",
+		},
+	}
+
+	for _, test := range tests {
+		trans, err := newTransformer("name", "title", version, common.Fields{test.field})
+		assert.NoError(t, err)
+		trans.runtimeFields = true
+		_, err = trans.transformFields()
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), test.err)
+		}
+	}
+}
+
+func TestTransformNegativePopularity(t *testing.T) {
+	trans, err := newTransformer("name", "title", version, common.Fields{
+		common.Field{Name: "message", Type: "text", Popularity: -1},
+	})
+	assert.NoError(t, err)
+	_, err = trans.transformFields()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Field <message> has negative popularity <-1>")
+	}
+}
+
 func TestTransformGroup(t *testing.T) {
 	tests := []struct {
 		commonFields common.Fields
@@ -170,7 +460,27 @@ func TestTransformGroup(t *testing.T) {
 					},
 				},
 			},
-			expected: []string{"context.another", "context.type", "context.metric.object"},
+			expected: []string{"context.another", "context.metric.object", "context.type"},
+		},
+		{
+			commonFields: common.Fields{
+				common.Field{Name: "labels", Type: "object", ObjectType: "keyword"},
+				common.Field{
+					Name: "user",
+					Type: "object",
+					Fields: common.Fields{
+						common.Field{Name: "name", Type: "keyword"},
+					},
+				},
+				common.Field{
+					Name: "orders",
+					Type: "nested",
+					Fields: common.Fields{
+						common.Field{Name: "id", Type: "keyword"},
+					},
+				},
+			},
+			expected: []string{"labels", "orders.id", "user.name"},
 		},
 	}
 	for idx, test := range tests {
@@ -193,6 +503,8 @@ func TestTransformMisc(t *testing.T) {
 	}{
 		{commonField: common.Field{}, expected: 0, attr: "count"},
 		{commonField: common.Field{Count: 4}, expected: 4, attr: "count"},
+		{commonField: common.Field{Popularity: 7}, expected: 7, attr: "count"},
+		{commonField: common.Field{Count: 4, Popularity: 7}, expected: 4, attr: "count"},
 
 		// searchable
 		{commonField: common.Field{}, expected: true, attr: "searchable"},
@@ -204,7 +516,9 @@ func TestTransformMisc(t *testing.T) {
 		{commonField: common.Field{Aggregatable: &truthy}, expected: true, attr: "aggregatable"},
 		{commonField: common.Field{Aggregatable: &falsy}, expected: false, attr: "aggregatable"},
 		{commonField: common.Field{Type: "keyword"}, expected: true, attr: "aggregatable"},
-		{commonField: common.Field{Aggregatable: &truthy, Type: "text"}, expected: false, attr: "aggregatable"},
+		{commonField: common.Field{Aggregatable: &truthy, Type: "text"}, expected: true, attr: "aggregatable"},
+		{commonField: common.Field{Aggregatable: &falsy, Type: "keyword"}, expected: false, attr: "aggregatable"},
+		{commonField: common.Field{Searchable: &falsy, Type: "keyword"}, expected: false, attr: "searchable"},
 		{commonField: common.Field{Type: "text"}, expected: false, attr: "aggregatable"},
 
 		// analyzed
@@ -258,6 +572,54 @@ func TestTransformFieldFormatMap(t *testing.T) {
 			expected:    common.MapStr{"c": common.MapStr{"id": "url"}},
 			version:     version,
 		},
+		{
+			commonField: common.Field{Name: "c", Type: "long", Format: "bytes"},
+			expected:    common.MapStr{"c": common.MapStr{"id": "bytes"}},
+			version:     version,
+		},
+		{
+			commonField: common.Field{Name: "c", Type: "scaled_float", Format: "percent"},
+			expected:    common.MapStr{"c": common.MapStr{"id": "percent"}},
+			version:     version,
+		},
+		{
+			commonField: common.Field{
+				Name:    "c",
+				Type:    "scaled_float",
+				Format:  "percent",
+				Pattern: "0.[0]%",
+				Params:  map[string]interface{}{"pattern": "0%", "fractional": true},
+			},
+			expected: common.MapStr{
+				"c": common.MapStr{
+					"id": "percent",
+					"params": common.MapStr{
+						"pattern":    "0.[0]%",
+						"fractional": true,
+					},
+				},
+			},
+			version: version,
+		},
+		{
+			commonField: common.Field{
+				Name:         "c",
+				Type:         "long",
+				Format:       "duration",
+				InputFormat:  "nanoseconds",
+				OutputFormat: "asMilliseconds",
+			},
+			expected: common.MapStr{
+				"c": common.MapStr{
+					"id": "duration",
+					"params": common.MapStr{
+						"inputFormat":  "nanoseconds",
+						"outputFormat": "asMilliseconds",
+					},
+				},
+			},
+			version: version,
+		},
 		{
 			commonField: common.Field{Name: "c", Pattern: "p"},
 			expected:    common.MapStr{"c": common.MapStr{"params": common.MapStr{"pattern": "p"}}},
@@ -457,12 +819,13 @@ func TestTransformGroupAndEnabled(t *testing.T) {
 					},
 				},
 			},
-			expected: []string{"context.type", "context.metric.object"},
+			expected: []string{"context.metric.object", "context.type"},
 		},
 		{
 			commonFields: common.Fields{
 				common.Field{Name: "enabledField"},
-				common.Field{Name: "disabledField", Enabled: &falsy}, //enabled is ignored for Type!=group
+				common.Field{Name: "disabledField", Enabled: &falsy},
+				common.Field{Name: "disabledObject", Type: "object", Enabled: &falsy},
 				common.Field{
 					Name:    "enabledGroup",
 					Type:    "group",
@@ -487,7 +850,7 @@ func TestTransformGroupAndEnabled(t *testing.T) {
 					},
 				},
 			},
-			expected: []string{"enabledField", "disabledField", "enabledGroup.type"},
+			expected: []string{"enabledField", "enabledGroup.type"},
 		},
 	}
 	for idx, test := range tests {
@@ -522,3 +885,46 @@ func TestTransformMultiField(t *testing.T) {
 	assert.Equal(t, "string", out[1]["type"])
 	assert.Equal(t, "string", out[2]["type"])
 }
+
+func TestTransformMultiFieldDuplicate(t *testing.T) {
+	fields := common.Fields{
+		common.Field{Name: "message", Type: "text",
+			MultiFields: common.Fields{common.Field{Name: "raw", Type: "keyword"}}},
+		common.Field{Name: "message.raw", Type: "keyword"},
+	}
+	trans, _ := newTransformer("name", "title", version, fields)
+	_, err := trans.transformFields()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "<message.raw>")
+	}
+}
+
+func TestTransformFieldAttrs(t *testing.T) {
+	fields := common.Fields{
+		common.Field{Name: "message", Type: "text", Label: "Message", Description: "The log message.\n",
+			MultiFields: common.Fields{common.Field{Name: "raw", Type: "keyword"}}},
+		common.Field{Name: "http", Type: "group", Description: "HTTP fields.", Fields: common.Fields{
+			common.Field{Name: "status", Type: "long", Label: "Status"},
+			common.Field{Name: "method", Type: "keyword"},
+		}},
+	}
+
+	trans, _ := newTransformer("name", "title", version, fields)
+	transformed, err := trans.transformFields()
+	assert.NoError(t, err)
+	assert.NotContains(t, transformed, "fieldAttrs")
+
+	trans, _ = newTransformer("name", "title", version, fields)
+	trans.fieldAttrs = true
+	transformed, err = trans.transformFields()
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"message": common.MapStr{
+			"customLabel":       "Message",
+			"customDescription": "The log message.",
+		},
+		"http.status": common.MapStr{
+			"customLabel": "Status",
+		},
+	}, transformed["fieldAttrs"])
+}
@@ -0,0 +1,189 @@
+package kibana
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/joeshaw/multierror"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// Validate checks the structure of an index pattern against what Kibana
+// requires to import it as a saved object. The format is told apart like for
+// the generated files: default index patterns have a list of `objects`, data
+// views are a single object with a `type`, 5.x index patterns only have the
+// attributes. Saved objects must have a known `type` and an `id`, the
+// attributes a `title`, the `fields` must be a JSON encoded array of fields
+// with a name and a type, and the `fieldFormatMap` a JSON encoded object. All
+// the problems found are returned in a single error.
+func Validate(pattern common.MapStr) error {
+	v := &validator{}
+
+	switch {
+	case pattern["objects"] != nil:
+		objects, ok := asList(pattern["objects"])
+		if !ok {
+			v.fail("objects", "must be a list of saved objects, got %T", pattern["objects"])
+			break
+		}
+		if len(objects) == 0 {
+			v.fail("objects", "must contain at least one saved object")
+		}
+		for n, object := range objects {
+			v.validateObject(fmt.Sprintf("objects[%d]", n), object, "index-pattern")
+		}
+
+	case pattern["type"] != nil:
+		v.validateObject("", pattern, "index-pattern", "data-view")
+
+	default:
+		v.validateAttributes("", pattern)
+	}
+
+	return v.errs.Err()
+}
+
+// validator collects the problems found in an index pattern.
+type validator struct {
+	errs multierror.Errors
+}
+
+func (v *validator) fail(key, format string, args ...interface{}) {
+	v.errs = append(v.errs, fmt.Errorf("%s %s", key, fmt.Sprintf(format, args...)))
+}
+
+func (v *validator) validateObject(path string, object interface{}, types ...string) {
+	o, ok := asMapStr(object)
+	if !ok {
+		v.fail(path, "must be a saved object, got %T", object)
+		return
+	}
+
+	typ, ok := o["type"].(string)
+	switch {
+	case !ok:
+		v.fail(joinPath(path, "type"), "must be a string, got %T", o["type"])
+	case !contains(types, typ):
+		v.fail(joinPath(path, "type"), "must be one of %v, got '%s'", types, typ)
+	}
+
+	if id, ok := o["id"].(string); !ok || id == "" {
+		v.fail(joinPath(path, "id"), "must be a non-empty string, got %#v", o["id"])
+	}
+
+	attributes, ok := asMapStr(o["attributes"])
+	if !ok {
+		v.fail(joinPath(path, "attributes"), "must be an object, got %T", o["attributes"])
+		return
+	}
+	v.validateAttributes(joinPath(path, "attributes"), attributes)
+}
+
+func (v *validator) validateAttributes(path string, attributes common.MapStr) {
+	if title, ok := attributes["title"].(string); !ok || title == "" {
+		v.fail(joinPath(path, "title"), "must be a non-empty string, got %#v", attributes["title"])
+	}
+
+	if timeFieldName, found := attributes["timeFieldName"]; found {
+		if _, ok := timeFieldName.(string); !ok {
+			v.fail(joinPath(path, "timeFieldName"), "must be a string, got %T", timeFieldName)
+		}
+	}
+
+	if fields, ok := v.decode(joinPath(path, "fields"), attributes["fields"], "array").([]interface{}); ok {
+		for n, field := range fields {
+			fieldPath := joinPath(path, fmt.Sprintf("fields[%d]", n))
+			f, ok := field.(map[string]interface{})
+			if !ok {
+				v.fail(fieldPath, "must be an object, got %T", field)
+				continue
+			}
+			if name, ok := f["name"].(string); !ok || name == "" {
+				v.fail(fieldPath+".name", "must be a non-empty string, got %#v", f["name"])
+			}
+			if _, ok := f["type"].(string); !ok {
+				v.fail(fieldPath+".type", "must be a string, got %#v", f["type"])
+			}
+		}
+	}
+
+	v.decode(joinPath(path, "fieldFormatMap"), attributes["fieldFormatMap"], "object")
+
+	// These attributes are only set if enabled or needed.
+	for _, key := range []string{"fieldAttrs", "runtimeFieldMap"} {
+		if value, found := attributes[key]; found {
+			v.decode(joinPath(path, key), value, "object")
+		}
+	}
+}
+
+// decode decodes a JSON encoded string value, which must hold a JSON value of
+// the given kind, an array or an object. It returns nil if the value is
+// invalid.
+func (v *validator) decode(path string, value interface{}, kind string) interface{} {
+	s, ok := value.(string)
+	if !ok {
+		v.fail(path, "must be a JSON encoded %s string, got %T", kind, value)
+		return nil
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(s), &decoded); err != nil {
+		v.fail(path, "must be a JSON encoded %s: %v", kind, err)
+		return nil
+	}
+
+	switch decoded.(type) {
+	case []interface{}:
+		if kind == "array" {
+			return decoded
+		}
+	case map[string]interface{}:
+		if kind == "object" {
+			return decoded
+		}
+	}
+	v.fail(path, "must be a JSON encoded %s, got %s", kind, s)
+	return nil
+}
+
+func asMapStr(v interface{}) (common.MapStr, bool) {
+	switch m := v.(type) {
+	case common.MapStr:
+		return m, true
+	case map[string]interface{}:
+		return common.MapStr(m), true
+	}
+	return nil, false
+}
+
+func asList(v interface{}) ([]interface{}, bool) {
+	switch l := v.(type) {
+	case []interface{}:
+		return l, true
+	case []common.MapStr:
+		list := make([]interface{}, len(l))
+		for n, m := range l {
+			list[n] = m
+		}
+		return list, true
+	}
+	return nil, false
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
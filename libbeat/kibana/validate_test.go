@@ -0,0 +1,182 @@
+package kibana
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/joeshaw/multierror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// decodePattern returns a copy of the pattern as decoded from JSON, like an
+// index pattern read from a file.
+func decodePattern(t *testing.T, pattern common.MapStr) common.MapStr {
+	content, err := json.Marshal(pattern)
+	require.NoError(t, err)
+	var decoded common.MapStr
+	require.NoError(t, json.Unmarshal(content, &decoded))
+	return decoded
+}
+
+func generatedPatterns(t *testing.T) []common.MapStr {
+	generator, err := NewGenerator("beat-*", "beat", tmpPath(), "8.0.0-alpha1")
+	require.NoError(t, err)
+	generator.SetFieldAttrs(true)
+	patterns, err := generator.GenerateInMemory()
+	require.NoError(t, err)
+	require.Len(t, patterns, 3)
+	return patterns
+}
+
+func TestValidateGenerated(t *testing.T) {
+	for _, pattern := range generatedPatterns(t) {
+		assert.NoError(t, Validate(pattern))
+		assert.NoError(t, Validate(decodePattern(t, pattern)))
+	}
+}
+
+func TestValidateCorrupted(t *testing.T) {
+	patterns := generatedPatterns(t)
+	index5x, index6x, index8x := patterns[0], patterns[1], patterns[2]
+
+	tests := []struct {
+		name    string
+		pattern common.MapStr
+		corrupt func(p common.MapStr)
+		errors  []string
+	}{
+		{
+			name:    "missing title",
+			pattern: index5x,
+			corrupt: func(p common.MapStr) { delete(p, "title") },
+			errors:  []string{"title must be a non-empty string, got <nil>"},
+		},
+		{
+			name:    "fields not encoded",
+			pattern: index5x,
+			corrupt: func(p common.MapStr) { p["fields"] = []interface{}{} },
+			errors:  []string{"fields must be a JSON encoded array string, got []interface {}"},
+		},
+		{
+			name:    "fields invalid JSON",
+			pattern: index6x,
+			corrupt: func(p common.MapStr) {
+				p.Put("objects", []interface{}{objectWith(t, p, "attributes.fields", `[{"name":`)})
+			},
+			errors: []string{"objects[0].attributes.fields must be a JSON encoded array: unexpected end of JSON input"},
+		},
+		{
+			name:    "fields not an array",
+			pattern: index8x,
+			corrupt: func(p common.MapStr) { p.Put("attributes.fields", `{"name":"message"}`) },
+			errors:  []string{`attributes.fields must be a JSON encoded array, got {"name":"message"}`},
+		},
+		{
+			name:    "field without name",
+			pattern: index8x,
+			corrupt: func(p common.MapStr) {
+				p.Put("attributes.fields", `[{"name":"message","type":"string"},{"type":"number"}]`)
+			},
+			errors: []string{"attributes.fields[1].name must be a non-empty string, got <nil>"},
+		},
+		{
+			name:    "fieldFormatMap not an object",
+			pattern: index8x,
+			corrupt: func(p common.MapStr) { p.Put("attributes.fieldFormatMap", "[]") },
+			errors:  []string{"attributes.fieldFormatMap must be a JSON encoded object, got []"},
+		},
+		{
+			name:    "fieldAttrs invalid JSON",
+			pattern: index8x,
+			corrupt: func(p common.MapStr) { p.Put("attributes.fieldAttrs", "{") },
+			errors:  []string{"attributes.fieldAttrs must be a JSON encoded object: unexpected end of JSON input"},
+		},
+		{
+			name:    "unknown type",
+			pattern: index8x,
+			corrupt: func(p common.MapStr) { p["type"] = "dataview" },
+			errors:  []string{"type must be one of [index-pattern data-view], got 'dataview'"},
+		},
+		{
+			name:    "data view in default format",
+			pattern: index6x,
+			corrupt: func(p common.MapStr) { p.Put("objects", []interface{}{objectWith(t, p, "type", "data-view")}) },
+			errors:  []string{"objects[0].type must be one of [index-pattern], got 'data-view'"},
+		},
+		{
+			name:    "missing id",
+			pattern: index8x,
+			corrupt: func(p common.MapStr) { p["id"] = "" },
+			errors:  []string{`id must be a non-empty string, got ""`},
+		},
+		{
+			name:    "no objects",
+			pattern: index6x,
+			corrupt: func(p common.MapStr) { p["objects"] = []interface{}{} },
+			errors:  []string{"objects must contain at least one saved object"},
+		},
+		{
+			name:    "attributes not an object",
+			pattern: index6x,
+			corrupt: func(p common.MapStr) { p.Put("objects", []interface{}{objectWith(t, p, "attributes", "beat-*")}) },
+			errors:  []string{"objects[0].attributes must be an object, got string"},
+		},
+		{
+			name:    "all problems",
+			pattern: index8x,
+			corrupt: func(p common.MapStr) {
+				delete(p, "id")
+				p.Put("attributes.title", 1)
+				p.Put("attributes.fieldFormatMap", nil)
+			},
+			errors: []string{
+				"id must be a non-empty string, got <nil>",
+				"attributes.title must be a non-empty string, got 1",
+				"attributes.fieldFormatMap must be a JSON encoded object string, got <nil>",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pattern := decodePattern(t, test.pattern)
+			test.corrupt(pattern)
+
+			err := Validate(pattern)
+			require.Error(t, err)
+			errs, ok := err.(*multierror.MultiError)
+			require.True(t, ok, "multi-error expected, got %T", err)
+
+			var messages []string
+			for _, e := range errs.Errors {
+				messages = append(messages, e.Error())
+			}
+			assert.Equal(t, test.errors, messages)
+		})
+	}
+}
+
+// objectWith returns the first object of a default index pattern, with the
+// value at key replaced.
+func objectWith(t *testing.T, pattern common.MapStr, key string, value interface{}) common.MapStr {
+	objects, ok := pattern["objects"].([]interface{})
+	require.True(t, ok)
+	object := common.MapStr(objects[0].(map[string]interface{}))
+	object.Put(key, value)
+	return object
+}
+
+func TestGenerateValidate(t *testing.T) {
+	beatDir := tmpPath()
+	defer teardown(beatDir)
+
+	generator, err := NewGenerator("beat-*", "beat", beatDir, "8.0.0-alpha1")
+	require.NoError(t, err)
+	generator.SetValidate(true)
+	files, err := generator.Generate()
+	assert.NoError(t, err)
+	assert.Len(t, files, 3)
+}
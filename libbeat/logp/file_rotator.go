@@ -73,6 +73,13 @@ func (rotator *FileRotator) CheckIfConfigSane() error {
 }
 
 func (rotator *FileRotator) WriteLine(line []byte) error {
+	return rotator.Write(append(line, '\n'))
+}
+
+// Write writes data to the current file as is, without adding a newline, so
+// binary data like gzip members can be written. The file is rotated before
+// writing, like for WriteLine.
+func (rotator *FileRotator) Write(data []byte) error {
 	if rotator.shouldRotate() {
 		err := rotator.Rotate()
 		if err != nil {
@@ -80,10 +87,8 @@ func (rotator *FileRotator) WriteLine(line []byte) error {
 		}
 	}
 
-	line = append(line, '\n')
-
 	rotator.currentLock.RLock()
-	_, err := rotator.current.Write(line)
+	_, err := rotator.current.Write(data)
 	rotator.currentLock.RUnlock()
 
 	if err != nil {
@@ -91,7 +96,7 @@ func (rotator *FileRotator) WriteLine(line []byte) error {
 	}
 
 	rotator.currentLock.Lock()
-	rotator.currentSize += uint64(len(line))
+	rotator.currentSize += uint64(len(data))
 	rotator.currentLock.Unlock()
 
 	return nil
@@ -168,9 +173,10 @@ func (rotator *FileRotator) Rotate() error {
 		}
 	}
 
-	// create the new file
+	// create the new file, writes are appended so they continue at the start
+	// of the file if it is truncated by another process
 	path := rotator.FilePath(0)
-	current, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.FileMode(*rotator.Permissions))
+	current, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND, os.FileMode(*rotator.Permissions))
 	if err != nil {
 		return err
 	}
@@ -183,3 +189,38 @@ func (rotator *FileRotator) Rotate() error {
 
 	return nil
 }
+
+// Reopen syncs and closes the current file, and opens the file at the path of
+// the current file again without rotating the files. A file renamed by
+// another process, like logrotate, is replaced by a new file, while the writes
+// to an existing file continue at its end.
+func (rotator *FileRotator) Reopen() error {
+	rotator.currentLock.Lock()
+	defer rotator.currentLock.Unlock()
+
+	if rotator.current != nil {
+		err := rotator.current.Sync()
+		if cerr := rotator.current.Close(); err == nil {
+			err = cerr
+		}
+		rotator.current = nil
+		if err != nil {
+			return err
+		}
+	}
+
+	path := rotator.FilePath(0)
+	current, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, os.FileMode(*rotator.Permissions))
+	if err != nil {
+		return err
+	}
+	info, err := current.Stat()
+	if err != nil {
+		current.Close()
+		return err
+	}
+
+	rotator.current = current
+	rotator.currentSize = uint64(info.Size())
+	return nil
+}
@@ -206,3 +206,38 @@ func TestRaceConditions(t *testing.T) {
 		go rotator.WriteLine([]byte(string(i)))
 	}
 }
+
+func TestReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test_rotator_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rotator := FileRotator{Path: dir, Name: "testbeat"}
+	assert.NoError(t, rotator.CheckIfConfigSane())
+	assert.NoError(t, rotator.WriteLine([]byte("1")))
+
+	// A renamed file is replaced by a new file.
+	assert.NoError(t, os.Rename(rotator.FilePath(0), filepath.Join(dir, "renamed")))
+	assert.NoError(t, rotator.Reopen())
+	assert.NoError(t, rotator.WriteLine([]byte("2")))
+
+	renamed, err := ioutil.ReadFile(filepath.Join(dir, "renamed"))
+	assert.NoError(t, err)
+	assert.Equal(t, "1\n", string(renamed))
+	file0, err := ioutil.ReadFile(rotator.FilePath(0))
+	assert.NoError(t, err)
+	assert.Equal(t, "2\n", string(file0))
+
+	// Writes continue at the end of an existing file, at its start once it
+	// is truncated.
+	assert.NoError(t, rotator.Reopen())
+	assert.NoError(t, rotator.WriteLine([]byte("3")))
+	assert.NoError(t, os.Truncate(rotator.FilePath(0), 0))
+	assert.NoError(t, rotator.WriteLine([]byte("4")))
+	file0, err = ioutil.ReadFile(rotator.FilePath(0))
+	assert.NoError(t, err)
+	assert.Equal(t, "4\n", string(file0))
+	assert.False(t, rotator.FileExists(1))
+}
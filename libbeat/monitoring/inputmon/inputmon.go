@@ -0,0 +1,89 @@
+// Package inputmon provides the metrics of a single input. The metrics of an
+// input are registered in the `input.<id>` namespace of the monitoring
+// registry, with the same names in all beats.
+package inputmon
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/elastic/beats/libbeat/monitoring"
+)
+
+// mutex serializes the registration of inputs, as checking for and adding the
+// input namespace is not atomic.
+var mutex sync.Mutex
+
+// Metrics are the metrics of an input.
+type Metrics struct {
+	ID string
+
+	Events  *monitoring.Int // events.in: events read by the input
+	Dropped *monitoring.Int // events.dropped: events lost before being read or published
+	Bytes   *monitoring.Int // bytes.in: bytes read by the input
+	Errors  *monitoring.Int // errors: errors reading the input
+
+	registry *monitoring.Registry
+}
+
+// NewMetrics creates the metrics of the input with the given id. The metrics
+// are not available in the monitoring registry until they are registered.
+func NewMetrics(id string) *Metrics {
+	return &Metrics{
+		ID:      id,
+		Events:  &monitoring.Int{},
+		Dropped: &monitoring.Int{},
+		Bytes:   &monitoring.Int{},
+		Errors:  &monitoring.Int{},
+	}
+}
+
+// Register adds the metrics to the `input.<id>` namespace of the registry. If
+// the registry is nil, the default registry is used. The id must be unique
+// among the registered inputs.
+func (m *Metrics) Register(r *monitoring.Registry) error {
+	if m.ID == "" {
+		return errors.New("input id must be set")
+	}
+	if strings.Contains(m.ID, ".") {
+		return fmt.Errorf("input id '%v' must not contain '.'", m.ID)
+	}
+	if r == nil {
+		r = monitoring.Default
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if m.registry != nil {
+		return fmt.Errorf("metrics of input '%v' already registered", m.ID)
+	}
+
+	name := "input." + m.ID
+	if r.Get(name) != nil {
+		return fmt.Errorf("metrics of input '%v' already registered", m.ID)
+	}
+
+	reg := r.NewRegistry(name)
+	reg.Add("events.in", m.Events, monitoring.Full)
+	reg.Add("events.dropped", m.Dropped, monitoring.Full)
+	reg.Add("bytes.in", m.Bytes, monitoring.Full)
+	reg.Add("errors", m.Errors, monitoring.Full)
+	m.registry = r
+	return nil
+}
+
+// Unregister removes the metrics from the registry they have been registered
+// in. The counters keep their values.
+func (m *Metrics) Unregister() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if m.registry == nil {
+		return
+	}
+	m.registry.Remove("input." + m.ID)
+	m.registry = nil
+}
@@ -0,0 +1,78 @@
+// +build !integration
+
+package inputmon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/monitoring"
+)
+
+func getInt(t *testing.T, r *monitoring.Registry, name string) int64 {
+	v, ok := r.Get(name).(*monitoring.Int)
+	require.True(t, ok, "missing metric %v", name)
+	return v.Get()
+}
+
+func TestMetricsIndependentPerInput(t *testing.T) {
+	r := monitoring.NewRegistry()
+
+	a := NewMetrics("a")
+	require.NoError(t, a.Register(r))
+	b := NewMetrics("b")
+	require.NoError(t, b.Register(r))
+
+	a.Events.Add(3)
+	a.Bytes.Add(100)
+	b.Events.Inc()
+	b.Dropped.Add(2)
+	b.Errors.Inc()
+
+	assert.Equal(t, int64(3), getInt(t, r, "input.a.events.in"))
+	assert.Equal(t, int64(100), getInt(t, r, "input.a.bytes.in"))
+	assert.Equal(t, int64(0), getInt(t, r, "input.a.errors"))
+
+	assert.Equal(t, int64(0), getInt(t, r, "input.a.events.dropped"))
+
+	assert.Equal(t, int64(1), getInt(t, r, "input.b.events.in"))
+	assert.Equal(t, int64(2), getInt(t, r, "input.b.events.dropped"))
+	assert.Equal(t, int64(0), getInt(t, r, "input.b.bytes.in"))
+	assert.Equal(t, int64(1), getInt(t, r, "input.b.errors"))
+}
+
+func TestMetricsUnregister(t *testing.T) {
+	r := monitoring.NewRegistry()
+
+	a := NewMetrics("a")
+	require.NoError(t, a.Register(r))
+	b := NewMetrics("b")
+	require.NoError(t, b.Register(r))
+
+	a.Unregister()
+	assert.Nil(t, r.Get("input.a"))
+	assert.NotNil(t, r.Get("input.b"))
+
+	// The id can be registered again once unregistered.
+	restarted := NewMetrics("a")
+	require.NoError(t, restarted.Register(r))
+	assert.Equal(t, int64(0), getInt(t, r, "input.a.events.in"))
+
+	restarted.Unregister()
+	b.Unregister()
+	assert.Nil(t, r.Get("input"))
+}
+
+func TestMetricsRegisterErrors(t *testing.T) {
+	r := monitoring.NewRegistry()
+
+	a := NewMetrics("a")
+	require.NoError(t, a.Register(r))
+	assert.Error(t, a.Register(r))
+	assert.Error(t, NewMetrics("a").Register(r))
+
+	assert.Error(t, NewMetrics("").Register(r))
+	assert.Error(t, NewMetrics("a.b").Register(r))
+}
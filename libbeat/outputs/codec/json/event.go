@@ -5,6 +5,7 @@ import (
 
 	"github.com/elastic/beats/libbeat/beat"
 	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/jsontransform"
 )
 
 // Event describes the event structure for events
@@ -30,8 +31,25 @@ func makeEvent(index, version string, in *beat.Event) event {
 			Beat:    index,
 			Version: version,
 			Type:    "doc",
-			Fields:  in.Meta,
+			Fields:  eventMeta(in.Meta),
 		},
 		Fields: in.Fields,
 	}
 }
+
+// eventMeta returns the metadata of the event to be encoded. The field order
+// only configures the encoding of the event, so it is not encoded.
+func eventMeta(in common.MapStr) common.MapStr {
+	if _, exists := in[jsontransform.FieldOrderKey]; !exists {
+		return in
+	}
+
+	// The metadata of the event is shared by the outputs, it is not modified.
+	out := make(common.MapStr, len(in)-1)
+	for k, v := range in {
+		if k != jsontransform.FieldOrderKey {
+			out[k] = v
+		}
+	}
+	return out
+}
@@ -3,29 +3,46 @@ package json
 import (
 	"bytes"
 	stdjson "encoding/json"
+	"errors"
+	"sort"
+	"strconv"
 
 	"github.com/urso/go-structform/gotype"
 	"github.com/urso/go-structform/json"
 
 	"github.com/elastic/beats/libbeat/beat"
 	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/jsontransform"
 	"github.com/elastic/beats/libbeat/outputs/codec"
 )
 
 // Encoder for serializing a beat.Event to json.
 type Encoder struct {
-	buf     bytes.Buffer
-	folder  *gotype.Iterator
-	pretty  bool
-	version string
+	buf           bytes.Buffer
+	folder        *gotype.Iterator
+	pretty        bool
+	canonical     bool
+	preserveOrder bool
+	version       string
 }
 
 type config struct {
-	Pretty bool
+	Pretty        bool
+	Canonical     bool
+	PreserveOrder bool `config:"preserve_order"`
 }
 
 var defaultConfig = config{
-	Pretty: false,
+	Pretty:        false,
+	Canonical:     false,
+	PreserveOrder: false,
+}
+
+func (c *config) Validate() error {
+	if c.Canonical && c.PreserveOrder {
+		return errors.New("canonical and preserve_order can not be used together")
+	}
+	return nil
 }
 
 func init() {
@@ -37,6 +54,12 @@ func init() {
 			}
 		}
 
+		if config.Canonical {
+			return NewCanonical(config.Pretty, info.Version), nil
+		}
+		if config.PreserveOrder {
+			return NewOrdered(config.Pretty, info.Version), nil
+		}
 		return New(config.Pretty, info.Version), nil
 	})
 }
@@ -48,6 +71,25 @@ func New(pretty bool, version string) *Encoder {
 	return e
 }
 
+// NewCanonical creates a new json Encoder, that sorts all object keys
+// recursively. The output is stable for equal events, at the cost of
+// re-encoding every event.
+func NewCanonical(pretty bool, version string) *Encoder {
+	e := New(pretty, version)
+	e.canonical = true
+	return e
+}
+
+// NewOrdered creates a new json Encoder, that writes the fields of events in
+// the order given by `@metadata.field_order`, e.g. the order of the keys of a
+// decoded JSON document. Fields not listed are written in sorted order after
+// the listed fields. Events without a field order are encoded as by New.
+func NewOrdered(pretty bool, version string) *Encoder {
+	e := New(pretty, version)
+	e.preserveOrder = true
+	return e
+}
+
 func (e *Encoder) reset() {
 	visitor := json.NewVisitor(&e.buf)
 
@@ -76,6 +118,14 @@ func (e *Encoder) Encode(index string, event *beat.Event) ([]byte, error) {
 	}
 
 	json := e.buf.Bytes()
+	if e.canonical {
+		return canonicalize(json, e.pretty)
+	}
+	if e.preserveOrder {
+		if order := fieldOrder(event.Meta); len(order) > 0 {
+			return reorder(json, order, e.pretty)
+		}
+	}
 	if !e.pretty {
 		return json, nil
 	}
@@ -87,3 +137,160 @@ func (e *Encoder) Encode(index string, event *beat.Event) ([]byte, error) {
 
 	return buf.Bytes(), nil
 }
+
+// canonicalize re-encodes a JSON document with all object keys sorted.
+// Numbers are passed through as is.
+func canonicalize(in []byte, pretty bool) ([]byte, error) {
+	var doc interface{}
+	dec := stdjson.NewDecoder(bytes.NewReader(in))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := stdjson.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if pretty {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+
+	// strip newline added by the encoder
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// fieldOrder returns the field order stored in the event metadata. The order
+// is a list of strings, or a list of interfaces if the metadata has been
+// decoded from JSON.
+func fieldOrder(meta common.MapStr) []string {
+	switch v := meta[jsontransform.FieldOrderKey].(type) {
+	case []string:
+		return v
+	case []interface{}:
+		order := make([]string, 0, len(v))
+		for _, key := range v {
+			if s, ok := key.(string); ok {
+				order = append(order, s)
+			}
+		}
+		return order
+	}
+	return nil
+}
+
+// reorder re-encodes a JSON document with the object keys written in the
+// given order of dotted key paths. `@timestamp` and `@metadata` are kept
+// first. Numbers are passed through as is.
+func reorder(in []byte, order []string, pretty bool) ([]byte, error) {
+	var doc interface{}
+	dec := stdjson.NewDecoder(bytes.NewReader(in))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	rank := map[string]int{
+		"@timestamp": -2,
+		"@metadata":  -1,
+	}
+	for i, key := range order {
+		if _, exists := rank[key]; !exists {
+			rank[key] = i
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := writeOrdered(&buf, doc, "", rank); err != nil {
+		return nil, err
+	}
+	if !pretty {
+		return buf.Bytes(), nil
+	}
+
+	var indented bytes.Buffer
+	if err := stdjson.Indent(&indented, buf.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	return indented.Bytes(), nil
+}
+
+func writeOrdered(buf *bytes.Buffer, v interface{}, path string, rank map[string]int) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sortKeys(keys, path, rank)
+
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeValue(buf, k); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeOrdered(buf, v[k], keyPath(path, k), rank); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeOrdered(buf, elem, keyPath(path, strconv.Itoa(i)), rank); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	}
+
+	return writeValue(buf, v)
+}
+
+// sortKeys sorts the keys of an object by their rank. Keys without a rank are
+// sorted by name after the ranked keys.
+func sortKeys(keys []string, path string, rank map[string]int) {
+	sort.Slice(keys, func(i, j int) bool {
+		ri, iRanked := rank[keyPath(path, keys[i])]
+		rj, jRanked := rank[keyPath(path, keys[j])]
+		switch {
+		case iRanked && jRanked:
+			return ri < rj
+		case iRanked != jRanked:
+			return iRanked
+		}
+		return keys[i] < keys[j]
+	})
+}
+
+func keyPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func writeValue(buf *bytes.Buffer, v interface{}) error {
+	var tmp bytes.Buffer
+	enc := stdjson.NewEncoder(&tmp)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+
+	// strip newline added by the encoder
+	buf.Write(bytes.TrimSuffix(tmp.Bytes(), []byte("\n")))
+	return nil
+}
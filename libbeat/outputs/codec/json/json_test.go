@@ -1,10 +1,14 @@
 package json
 
 import (
+	stdjson "encoding/json"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/elastic/beats/libbeat/beat"
 	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/jsontransform"
 )
 
 func TestJsonCodec(t *testing.T) {
@@ -44,3 +48,170 @@ func TestJsonWriterPrettyPrint(t *testing.T) {
 		}
 	}
 }
+
+func TestJsonCodecCanonical(t *testing.T) {
+	expectedValue := `{"@metadata":{"beat":"test","type":"doc","version":"1.2.3"},"@timestamp":"0001-01-01T00:00:00.000Z","a":{"x":1,"y":[{"b":2.5,"c":"<html>"}],"z":true},"b":"message","c":12345678901234567890}`
+
+	event := &beat.Event{Fields: common.MapStr{
+		"c": uint64(12345678901234567890),
+		"b": "message",
+		"a": common.MapStr{
+			"z": true,
+			"y": []common.MapStr{{"c": "<html>", "b": 2.5}},
+			"x": 1,
+		},
+	}}
+
+	codec := NewCanonical(false, "1.2.3")
+	for i := 0; i < 20; i++ {
+		output, err := codec.Encode("test", event)
+		if err != nil {
+			t.Fatalf("Error during event write %v", err)
+		}
+		if string(output) != expectedValue {
+			t.Fatalf("Expected value (%s) does not equal with output (%s)", expectedValue, output)
+		}
+	}
+}
+
+func TestJsonCodecCanonicalPrettyPrint(t *testing.T) {
+	expectedValue := `{
+  "@metadata": {
+    "beat": "test",
+    "type": "doc",
+    "version": "1.2.3"
+  },
+  "@timestamp": "0001-01-01T00:00:00.000Z",
+  "a": "1",
+  "b": "2"
+}`
+
+	codec := NewCanonical(true, "1.2.3")
+	output, err := codec.Encode("test", &beat.Event{Fields: common.MapStr{"b": "2", "a": "1"}})
+
+	if err != nil {
+		t.Errorf("Error during event write %v", err)
+	} else {
+		if string(output) != expectedValue {
+			t.Errorf("Expected value (%s) does not equal with output (%s)", expectedValue, output)
+		}
+	}
+}
+
+func TestJsonCodecPreserveOrder(t *testing.T) {
+	source := `{"zeta":"last?","alpha":{"y":[{"b":1,"a":2}],"x":12345678901234567890},"mid":null,"beta":2.5}`
+
+	order, err := jsontransform.FieldOrder([]byte(source))
+	if err != nil {
+		t.Fatalf("Error reading field order %v", err)
+	}
+
+	var fields common.MapStr
+	dec := stdjson.NewDecoder(strings.NewReader(source))
+	dec.UseNumber()
+	if err := dec.Decode(&fields); err != nil {
+		t.Fatalf("Error decoding source %v", err)
+	}
+	jsontransform.TransformNumbers(fields)
+
+	event := &beat.Event{
+		Meta:   common.MapStr{jsontransform.FieldOrderKey: order},
+		Fields: fields,
+	}
+	event.Fields["added"] = "not in source"
+
+	codec := NewOrdered(false, "1.2.3")
+	for i := 0; i < 20; i++ {
+		output, err := codec.Encode("test", event)
+		if err != nil {
+			t.Fatalf("Error during event write %v", err)
+		}
+
+		var doc struct {
+			Timestamp stdjson.RawMessage `json:"@timestamp"`
+			Meta      stdjson.RawMessage `json:"@metadata"`
+		}
+		if err := stdjson.Unmarshal(output, &doc); err != nil {
+			t.Fatalf("Error decoding output %v", err)
+		}
+
+		expectedValue := `{"@timestamp":` + string(doc.Timestamp) + `,"@metadata":` + string(doc.Meta) + `,` + source[1:len(source)-1] + `,"added":"not in source"}`
+		if string(output) != expectedValue {
+			t.Fatalf("Expected value (%s) does not equal with output (%s)", expectedValue, output)
+		}
+
+		// the order is kept after decoding the encoded event again
+		outputOrder, err := jsontransform.FieldOrder(output)
+		if err != nil {
+			t.Fatalf("Error reading field order %v", err)
+		}
+		if !strings.HasPrefix(strings.Join(outputOrder, ","), "@timestamp,@metadata,") {
+			t.Fatalf("Unexpected field order %v", outputOrder)
+		}
+	}
+}
+
+func TestJsonCodecOmitsFieldOrder(t *testing.T) {
+	expectedMeta := map[string]interface{}{"beat": "test", "pipeline": "logs", "type": "doc", "version": "1.2.3"}
+
+	codecs := map[string]*Encoder{
+		"default":        New(false, "1.2.3"),
+		"preserve_order": NewOrdered(false, "1.2.3"),
+		"canonical":      NewCanonical(false, "1.2.3"),
+	}
+	for name, codec := range codecs {
+		meta := common.MapStr{
+			"pipeline":                  "logs",
+			jsontransform.FieldOrderKey: []string{"msg"},
+		}
+		output, err := codec.Encode("test", &beat.Event{Meta: meta, Fields: common.MapStr{"msg": "message"}})
+		if err != nil {
+			t.Fatalf("Error during event write with %v codec: %v", name, err)
+		}
+
+		// The field order is internal to the codec, it is not written to the
+		// outputs serializing the metadata.
+		var doc struct {
+			Meta map[string]interface{} `json:"@metadata"`
+		}
+		if err := stdjson.Unmarshal(output, &doc); err != nil {
+			t.Fatalf("Error decoding output of %v codec: %v", name, err)
+		}
+		if !reflect.DeepEqual(expectedMeta, doc.Meta) || strings.Contains(string(output), jsontransform.FieldOrderKey) {
+			t.Errorf("Unexpected metadata in output (%s) of %v codec", output, name)
+		}
+		if _, exists := meta[jsontransform.FieldOrderKey]; !exists {
+			t.Errorf("Metadata of the event modified by %v codec", name)
+		}
+	}
+}
+
+func TestJsonCodecPreserveOrderWithoutOrder(t *testing.T) {
+	expectedValue := `{"@timestamp":"0001-01-01T00:00:00.000Z","@metadata":{"beat":"test","type":"doc","version":"1.2.3"},"msg":"message"}`
+
+	codec := NewOrdered(false, "1.2.3")
+	output, err := codec.Encode("test", &beat.Event{Fields: common.MapStr{"msg": "message"}})
+
+	if err != nil {
+		t.Errorf("Error during event write %v", err)
+	} else {
+		if string(output) != expectedValue {
+			t.Errorf("Expected value (%s) does not equal with output (%s)", expectedValue, output)
+		}
+	}
+}
+
+func TestJsonCodecPreserveOrderCanonical(t *testing.T) {
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"canonical":      true,
+		"preserve_order": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := defaultConfig
+	if err := cfg.Unpack(&config); err == nil {
+		t.Errorf("Expected error for canonical and preserve_order")
+	}
+}
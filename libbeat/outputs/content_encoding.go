@@ -0,0 +1,38 @@
+package outputs
+
+import (
+	"github.com/elastic/beats/libbeat/beat"
+)
+
+// ContentEncodingKey is the `@metadata` key hinting that the `message` of an
+// event is already compressed, like `Content-Encoding` in HTTP. The only
+// encoding supported is `gzip`.
+const ContentEncodingKey = "content_encoding"
+
+// gzipMagic are the first bytes of a gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// GzipPayload returns the `message` of the event, if the event is marked as
+// gzip compressed by `@metadata.content_encoding`. Outputs compressing their
+// data can write the payload as is instead of compressing it again. Messages
+// not starting with a gzip header are not considered compressed.
+func GzipPayload(event *beat.Event) ([]byte, bool) {
+	if encoding, _ := event.Meta[ContentEncodingKey].(string); encoding != "gzip" {
+		return nil, false
+	}
+
+	var payload []byte
+	switch message := event.Fields["message"].(type) {
+	case []byte:
+		payload = message
+	case string:
+		payload = []byte(message)
+	default:
+		return nil, false
+	}
+
+	if len(payload) < len(gzipMagic) || payload[0] != gzipMagic[0] || payload[1] != gzipMagic[1] {
+		return nil, false
+	}
+	return payload, true
+}
@@ -0,0 +1,95 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common/aws"
+)
+
+// awsSigningConfig configures the signing of requests to Elasticsearch
+// clusters hosted by AWS.
+type awsSigningConfig struct {
+	Enabled               bool   `config:"enabled"`
+	Region                string `config:"region"`
+	Service               string `config:"service"`
+	aws.CredentialsConfig `config:",inline"`
+}
+
+const defaultAWSService = "es"
+
+// AWSSigner signs the requests to Elasticsearch with AWS Signature Version 4.
+type AWSSigner struct {
+	Credentials aws.CredentialsProvider
+	Region      string
+	Service     string
+}
+
+// newAWSSigner creates the signer of the configured AWS signing settings.
+// Returns nil if signing is disabled.
+func newAWSSigner(config awsSigningConfig) (*AWSSigner, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+
+	creds, err := aws.NewCredentialsProvider(config.CredentialsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	service := config.Service
+	if service == "" {
+		service = defaultAWSService
+	}
+
+	return &AWSSigner{
+		Credentials: creds,
+		Region:      config.Region,
+		Service:     service,
+	}, nil
+}
+
+// awsSigningTransport signs each request before passing it to the wrapped
+// transport. The request body is read for computing the payload hash.
+type awsSigningTransport struct {
+	next   http.RoundTripper
+	signer *AWSSigner
+	now    func() time.Time
+}
+
+func newAWSSigningTransport(next http.RoundTripper, signer *AWSSigner) *awsSigningTransport {
+	return &awsSigningTransport{next: next, signer: signer, now: time.Now}
+}
+
+func (t *awsSigningTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	creds, err := t.signer.Credentials.Credentials()
+	if err != nil {
+		return nil, err
+	}
+
+	var body []byte
+	if req.Body != nil {
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// A RoundTripper must not modify the request, sign a copy of it.
+	signed := new(http.Request)
+	*signed = *req
+	signed.Header = make(http.Header, len(req.Header))
+	for name, values := range req.Header {
+		signed.Header[name] = append([]string(nil), values...)
+	}
+	if req.Body != nil {
+		signed.Body = ioutil.NopCloser(bytes.NewReader(body))
+		signed.ContentLength = int64(len(body))
+	}
+
+	aws.SignRequest(signed, body, creds, t.signer.Region, t.signer.Service, t.now())
+	return t.next.RoundTrip(signed)
+}
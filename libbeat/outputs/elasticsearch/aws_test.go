@@ -0,0 +1,147 @@
+// +build !integration
+
+package elasticsearch
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/aws"
+)
+
+type testCredentials aws.Credentials
+
+func (c testCredentials) Credentials() (aws.Credentials, error) { return aws.Credentials(c), nil }
+
+var testAWSSigner = &AWSSigner{
+	Credentials: testCredentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	},
+	Region:  "us-east-1",
+	Service: "es",
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestAWSSigningTransport(t *testing.T) {
+	body := "{\"index\":{}}\n{\"message\":\"test\"}\n"
+
+	var signed *http.Request
+	var signedBody []byte
+	transport := newAWSSigningTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		signed = req
+		signedBody, _ = ioutil.ReadAll(req.Body)
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(&bytes.Buffer{})}, nil
+	}), testAWSSigner)
+	transport.now = func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) }
+
+	req, err := http.NewRequest("POST", "https://search-logs.us-east-1.es.amazonaws.com/_bulk", strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = transport.RoundTrip(req)
+	require.NoError(t, err)
+
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/es/aws4_request, "+
+		"SignedHeaders=content-type;host;x-amz-date, "+
+		"Signature=5873f3ad5041bbf3145c3c868382b2afbedf6e995076df16445ab221cf7a8eae",
+		signed.Header.Get("Authorization"))
+	assert.Equal(t, "20150830T123600Z", signed.Header.Get("X-Amz-Date"))
+	assert.Equal(t, body, string(signedBody))
+
+	// The original request is not modified.
+	assert.Empty(t, req.Header.Get("Authorization"))
+}
+
+func TestClientSignsRequests(t *testing.T) {
+	var authorization []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = append(authorization, r.Header.Get("Authorization"))
+		w.Write([]byte(`{"version":{"number":"6.0.0"}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(ClientSettings{
+		URL:       server.URL,
+		Timeout:   time.Second,
+		AWSSigner: testAWSSigner,
+	}, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, client.Connect())
+	require.NotEmpty(t, authorization)
+	for _, value := range authorization {
+		assert.True(t, strings.HasPrefix(value, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"), value)
+	}
+
+	// Clones sign their requests as well.
+	authorization = nil
+	require.NoError(t, client.Clone().Connect())
+	require.NotEmpty(t, authorization)
+	assert.True(t, strings.HasPrefix(authorization[0], "AWS4-HMAC-SHA256 "))
+}
+
+func TestAWSSigningConfig(t *testing.T) {
+	tests := map[string]struct {
+		settings map[string]interface{}
+		err      bool
+	}{
+		"disabled": {
+			settings: map[string]interface{}{"aws.region": ""},
+		},
+		"enabled": {
+			settings: map[string]interface{}{
+				"aws.enabled":           true,
+				"aws.region":            "us-east-1",
+				"aws.access_key_id":     "AKIDEXAMPLE",
+				"aws.secret_access_key": "secret",
+			},
+		},
+		"missing region": {
+			settings: map[string]interface{}{"aws.enabled": true},
+			err:      true,
+		},
+		"with basic auth": {
+			settings: map[string]interface{}{
+				"aws.enabled": true,
+				"aws.region":  "us-east-1",
+				"username":    "elastic",
+			},
+			err: true,
+		},
+	}
+
+	for name, test := range tests {
+		cfg, err := common.NewConfigFrom(test.settings)
+		require.NoError(t, err)
+
+		config := defaultConfig
+		err = cfg.Unpack(&config)
+		if test.err {
+			assert.Error(t, err, name)
+			continue
+		}
+		require.NoError(t, err, name)
+
+		signer, err := newAWSSigner(config.AWS)
+		require.NoError(t, err, name)
+		if config.AWS.Enabled {
+			require.NotNil(t, signer, name)
+			assert.Equal(t, "es", signer.Service, name)
+		} else {
+			assert.Nil(t, signer, name)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/publisher"
+)
+
+// bulkTraceConfig configures the tracing of failed bulk requests.
+type bulkTraceConfig struct {
+	Enabled          bool          `config:"enabled"`
+	Interval         time.Duration `config:"interval" validate:"min=0"`
+	MaxDocuments     int           `config:"max_documents" validate:"min=0"`
+	UnredactedFields []string      `config:"unredacted_fields"`
+}
+
+var defaultBulkTraceConfig = bulkTraceConfig{
+	Enabled:      false,
+	Interval:     time.Minute,
+	MaxDocuments: 5,
+}
+
+// traceHeaders are the request headers logged with their value, the values
+// of all other headers, like the credentials, are redacted.
+var traceHeaders = map[string]bool{
+	"Accept":           true,
+	"Content-Type":     true,
+	"Content-Encoding": true,
+}
+
+// errorsTrue is the flag of bulk responses with failed items.
+var errorsTrue = []byte(`"errors":true`)
+
+const redacted = "[redacted]"
+
+// BulkTracer logs the bulk requests failed with an error, or with failed
+// items: the metadata of the request, a sample of the documents with their
+// values redacted, and the complete response of Elasticsearch. At most one
+// request is logged per interval, so a persistent failure does not flood the
+// logs.
+type BulkTracer struct {
+	interval     time.Duration
+	maxDocuments int
+	unredacted   map[string]bool
+
+	mutex sync.Mutex
+	last  time.Time
+
+	now func() time.Time
+	log func(format string, v ...interface{})
+}
+
+// bulkTrace is the trace of a failed bulk request, logged as JSON.
+type bulkTrace struct {
+	Request   bulkTraceRequest    `json:"request"`
+	Status    int                 `json:"status,omitempty"`
+	Error     string              `json:"error,omitempty"`
+	Documents []bulkTraceDocument `json:"documents"`
+	Response  interface{}         `json:"response,omitempty"`
+}
+
+type bulkTraceRequest struct {
+	Method        string            `json:"method"`
+	URL           string            `json:"url"`
+	Headers       map[string]string `json:"headers"`
+	ContentLength int64             `json:"content_length"`
+	Documents     int               `json:"documents"`
+}
+
+type bulkTraceDocument struct {
+	Position int           `json:"position"`
+	Meta     interface{}   `json:"meta"`
+	Document common.MapStr `json:"document"`
+}
+
+// newBulkTracer returns the tracer of failed bulk requests, or nil if tracing
+// is disabled.
+func newBulkTracer(config bulkTraceConfig) *BulkTracer {
+	if !config.Enabled {
+		return nil
+	}
+
+	unredacted := map[string]bool{}
+	for _, field := range config.UnredactedFields {
+		unredacted[field] = true
+	}
+
+	return &BulkTracer{
+		interval:     config.Interval,
+		maxDocuments: config.MaxDocuments,
+		unredacted:   unredacted,
+		now:          time.Now,
+		log:          logp.Warn,
+	}
+}
+
+// Trace logs the request, if it failed and no request has been logged during
+// the interval. The events are the events encoded in the request, in their
+// order. The response is the body returned by Elasticsearch, if any.
+func (t *BulkTracer) Trace(
+	client *Client,
+	requ *http.Request,
+	data []publisher.Event,
+	status int,
+	response []byte,
+	err error,
+) {
+	if t == nil {
+		return
+	}
+
+	// Requests without error can still contain failed items.
+	var failed []int
+	if err == nil {
+		if !bytes.Contains(response, errorsTrue) {
+			return
+		}
+		failed = failedItems(response)
+	}
+
+	if !t.allow() {
+		return
+	}
+
+	trace := bulkTrace{
+		Request: bulkTraceRequest{
+			Method:        requ.Method,
+			URL:           requ.URL.String(),
+			Headers:       redactHeaders(requ.Header),
+			ContentLength: requ.ContentLength,
+			Documents:     len(data),
+		},
+		Status:   status,
+		Response: traceResponse(response),
+	}
+	// Errors of requests with a response contain the response.
+	if err != nil && len(response) == 0 {
+		trace.Error = err.Error()
+	}
+
+	// The failed items are sampled, or the first documents if the complete
+	// request failed.
+	if failed == nil {
+		for i := range data {
+			failed = append(failed, i)
+		}
+	}
+	documents := []bulkTraceDocument{}
+	for _, i := range failed {
+		if len(documents) == t.maxDocuments || i >= len(data) {
+			break
+		}
+
+		event := &data[i].Content
+		meta, _ := createEventBulkMeta(client.index, client.indexNames, client.pipeline, event)
+		// The timestamp is not redacted, to correlate the trace with the
+		// events.
+		document := t.redact(event.Fields, "")
+		document["@timestamp"] = event.Timestamp
+		documents = append(documents, bulkTraceDocument{Position: i, Meta: meta, Document: document})
+	}
+	trace.Documents = documents
+
+	encoded, jsonErr := json.Marshal(trace)
+	if jsonErr != nil {
+		logp.Err("Failed to encode the trace of the bulk request: %v", jsonErr)
+		return
+	}
+	t.log("Failed bulk request trace: %s", encoded)
+}
+
+// allow returns true, if the interval elapsed since the last logged request.
+func (t *BulkTracer) allow() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := t.now()
+	if !t.last.IsZero() && now.Sub(t.last) < t.interval {
+		return false
+	}
+	t.last = now
+	return true
+}
+
+// redact returns a copy of the fields, with all values replaced by their
+// type except the values of the unredacted fields.
+func (t *BulkTracer) redact(fields common.MapStr, prefix string) common.MapStr {
+	out := common.MapStr{}
+	for k, v := range fields {
+		out[k] = t.redactValue(prefix+k, v)
+	}
+	return out
+}
+
+func (t *BulkTracer) redactValue(key string, value interface{}) interface{} {
+	if t.unredacted[key] {
+		return value
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case common.MapStr:
+		return t.redact(v, key+".")
+	case map[string]interface{}:
+		return t.redact(v, key+".")
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if _, ok := value.([]byte); ok {
+			break
+		}
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			list[i] = t.redactValue(key, rv.Index(i).Interface())
+		}
+		return list
+	case reflect.Bool:
+		return redacted + " boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return redacted + " number"
+	case reflect.String:
+		return redacted + " string"
+	}
+	return redacted
+}
+
+func redactHeaders(header http.Header) map[string]string {
+	headers := map[string]string{}
+	for name := range header {
+		if traceHeaders[name] {
+			headers[name] = header.Get(name)
+		} else {
+			headers[name] = redacted
+		}
+	}
+	return headers
+}
+
+// traceResponse returns the response to be embedded in the trace, as JSON if
+// it is valid JSON or as string otherwise.
+func traceResponse(response []byte) interface{} {
+	if len(response) == 0 {
+		return nil
+	}
+	if json.Valid(response) {
+		return json.RawMessage(response)
+	}
+	return string(response)
+}
+
+// failedItems returns the positions of the failed items of a bulk response.
+func failedItems(response []byte) []int {
+	var result struct {
+		Items []map[string]struct {
+			Status int `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(response, &result); err != nil {
+		return nil
+	}
+
+	failed := []int{}
+	for i, item := range result.Items {
+		for _, op := range item {
+			if op.Status >= 300 {
+				failed = append(failed, i)
+			}
+		}
+	}
+	return failed
+}
@@ -0,0 +1,217 @@
+// +build !integration
+
+package elasticsearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/outputs/outest"
+	"github.com/elastic/beats/libbeat/outputs/outil"
+)
+
+const (
+	bulkErrorResponse = `{"error":{"root_cause":[{"type":"illegal_argument_exception","reason":"Malformed action/metadata line [1]"}],"type":"illegal_argument_exception","reason":"Malformed action/metadata line [1]"},"status":400}`
+	bulkItemsResponse = `{"took":3,"errors":true,"items":[` +
+		`{"index":{"_index":"test","_type":"doc","status":201}},` +
+		`{"index":{"_index":"test","_type":"doc","status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse [http.status]"}}},` +
+		`{"index":{"_index":"test","_type":"doc","status":201}}]}`
+)
+
+// testTracer records the traces of a tracer.
+type testTracer struct {
+	*BulkTracer
+	now    time.Time
+	traces []map[string]interface{}
+}
+
+func newTestTracer(t *testing.T, config map[string]interface{}) *testTracer {
+	settings := defaultBulkTraceConfig
+	cfg, err := common.NewConfigFrom(config)
+	require.NoError(t, err)
+	require.NoError(t, cfg.Unpack(&settings))
+
+	tracer := &testTracer{
+		BulkTracer: newBulkTracer(settings),
+		now:        time.Date(2018, 10, 14, 7, 30, 0, 0, time.UTC),
+	}
+	require.NotNil(t, tracer.BulkTracer)
+
+	tracer.BulkTracer.now = func() time.Time { return tracer.now }
+	tracer.BulkTracer.log = func(format string, v ...interface{}) {
+		msg := fmt.Sprintf(format, v...)
+		trace := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(msg, "Failed bulk request trace: ")), &trace))
+		tracer.traces = append(tracer.traces, trace)
+	}
+	return tracer
+}
+
+func newTraceTestServer(status int, response string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, response)
+	}))
+}
+
+func newTraceTestClient(t *testing.T, url string, tracer *testTracer) *Client {
+	client, err := NewClient(ClientSettings{
+		URL:        url,
+		Username:   "elastic",
+		Password:   "secret",
+		Headers:    map[string]string{"X-Api-Key": "key"},
+		Index:      outil.MakeSelector(outil.ConstSelectorExpr("test")),
+		BulkTracer: tracer.BulkTracer,
+	}, nil)
+	require.NoError(t, err)
+	return client
+}
+
+func traceTestEvents() []beat.Event {
+	ts := time.Date(2018, 10, 14, 7, 29, 0, 0, time.UTC)
+	return []beat.Event{
+		{Timestamp: ts, Fields: common.MapStr{"message": "first", "http": common.MapStr{"status": 200}}},
+		{Timestamp: ts, Fields: common.MapStr{
+			"message": "password=secret",
+			"http":    common.MapStr{"status": "OK", "ok": true},
+			"tags":    []string{"a", "b"},
+		}},
+		{Timestamp: ts, Fields: common.MapStr{"message": "third"}},
+	}
+}
+
+func TestBulkTraceRequestError(t *testing.T) {
+	server := newTraceTestServer(400, bulkErrorResponse)
+	defer server.Close()
+
+	tracer := newTestTracer(t, map[string]interface{}{"enabled": true, "max_documents": 2})
+	client := newTraceTestClient(t, server.URL, tracer)
+
+	batch := outest.NewBatch(traceTestEvents()...)
+	assert.Error(t, client.Publish(batch))
+	require.Len(t, tracer.traces, 1)
+	trace := common.MapStr(tracer.traces[0])
+
+	// The metadata of the request, without credentials.
+	request, _ := trace.GetValue("request")
+	assert.Equal(t, map[string]interface{}{
+		"method": "POST",
+		"url":    server.URL + "/_bulk",
+		"headers": map[string]interface{}{
+			"Accept":        "application/json",
+			"Authorization": "[redacted]",
+			"Content-Type":  "application/json; charset=UTF-8",
+			"X-Api-Key":     "[redacted]",
+		},
+		"content_length": request.(map[string]interface{})["content_length"],
+		"documents":      float64(3),
+	}, request)
+	assert.NotContains(t, fmt.Sprint(trace), "secret")
+
+	// The complete error of Elasticsearch.
+	assert.Equal(t, float64(400), trace["status"])
+	var expected interface{}
+	require.NoError(t, json.Unmarshal([]byte(bulkErrorResponse), &expected))
+	assert.Equal(t, expected, trace["response"])
+	assert.NotContains(t, trace, "error")
+
+	// The first documents are sampled, with their values redacted.
+	documents := trace["documents"].([]interface{})
+	require.Len(t, documents, 2)
+	assert.Equal(t, map[string]interface{}{
+		"position": float64(0),
+		"meta":     map[string]interface{}{"index": map[string]interface{}{"_index": "test", "_type": "doc"}},
+		"document": map[string]interface{}{
+			"@timestamp": "2018-10-14T07:29:00Z",
+			"message":    "[redacted] string",
+			"http":       map[string]interface{}{"status": "[redacted] number"},
+		},
+	}, documents[0])
+}
+
+func TestBulkTraceFailedItems(t *testing.T) {
+	server := newTraceTestServer(200, bulkItemsResponse)
+	defer server.Close()
+
+	tracer := newTestTracer(t, map[string]interface{}{
+		"enabled":           true,
+		"unredacted_fields": []string{"http.status"},
+	})
+	client := newTraceTestClient(t, server.URL, tracer)
+
+	batch := outest.NewBatch(traceTestEvents()...)
+	assert.NoError(t, client.Publish(batch))
+	require.Len(t, tracer.traces, 1)
+	trace := tracer.traces[0]
+
+	assert.Equal(t, float64(200), trace["status"])
+	assert.Contains(t, fmt.Sprint(trace["response"]), "mapper_parsing_exception")
+
+	// Only the failed item is sampled.
+	documents := trace["documents"].([]interface{})
+	require.Len(t, documents, 1)
+	assert.Equal(t, map[string]interface{}{
+		"position": float64(1),
+		"meta":     map[string]interface{}{"index": map[string]interface{}{"_index": "test", "_type": "doc"}},
+		"document": map[string]interface{}{
+			"@timestamp": "2018-10-14T07:29:00Z",
+			"message":    "[redacted] string",
+			"http":       map[string]interface{}{"status": "OK", "ok": "[redacted] boolean"},
+			"tags":       []interface{}{"[redacted] string", "[redacted] string"},
+		},
+	}, documents[0])
+}
+
+func TestBulkTraceRateLimited(t *testing.T) {
+	server := newTraceTestServer(503, `{"error":"unavailable"}`)
+	defer server.Close()
+
+	tracer := newTestTracer(t, map[string]interface{}{"enabled": true, "interval": "1m"})
+	client := newTraceTestClient(t, server.URL, tracer)
+
+	publish := func() {
+		client.Publish(outest.NewBatch(traceTestEvents()...))
+	}
+
+	publish()
+	publish()
+	assert.Len(t, tracer.traces, 1)
+
+	tracer.now = tracer.now.Add(59 * time.Second)
+	publish()
+	assert.Len(t, tracer.traces, 1)
+
+	tracer.now = tracer.now.Add(time.Second)
+	publish()
+	assert.Len(t, tracer.traces, 2)
+}
+
+func TestBulkTraceSuccess(t *testing.T) {
+	server := newTraceTestServer(200, `{"took":3,"errors":false,"items":[{"index":{"status":201}}]}`)
+	defer server.Close()
+
+	tracer := newTestTracer(t, map[string]interface{}{"enabled": true})
+	client := newTraceTestClient(t, server.URL, tracer)
+
+	assert.NoError(t, client.Publish(outest.NewBatch(traceTestEvents()[0])))
+	assert.Empty(t, tracer.traces)
+}
+
+func TestBulkTraceDisabledByDefault(t *testing.T) {
+	assert.Nil(t, newBulkTracer(defaultBulkTraceConfig))
+
+	// A disabled tracer is nil, and traces nothing.
+	var tracer *BulkTracer
+	tracer.Trace(nil, nil, nil, 400, []byte(bulkErrorResponse), nil)
+}
@@ -118,7 +118,8 @@ func (r *bulkRequest) Reset(body bodyEncoder) {
 func (conn *Connection) sendBulkRequest(requ *bulkRequest) (int, bulkResult, error) {
 	status, resp, err := conn.execHTTPRequest(requ.requ)
 	if err != nil {
-		return status, bulkResult{}, err
+		// The response is returned with the error, to be traced.
+		return status, bulkResult{resp}, err
 	}
 
 	result, err := readBulkResult(resp)
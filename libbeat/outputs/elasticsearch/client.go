@@ -25,10 +25,12 @@ type Client struct {
 	Connection
 	tlsConfig *transport.TLSConfig
 
-	index    outil.Selector
-	pipeline *outil.Selector
-	params   map[string]string
-	timeout  time.Duration
+	index      outil.Selector
+	indexNames *IndexNames
+	bulkTracer *BulkTracer
+	pipeline   *outil.Selector
+	params     map[string]string
+	timeout    time.Duration
 
 	// buffered bulk requests
 	bulkRequ *bulkRequest
@@ -39,6 +41,7 @@ type Client struct {
 	// additional configs
 	compressionLevel int
 	proxyURL         *url.URL
+	awsSigner        *AWSSigner
 
 	stats *outputs.Stats
 }
@@ -56,6 +59,21 @@ type ClientSettings struct {
 	Timeout            time.Duration
 	CompressionLevel   int
 	Stats              *outputs.Stats
+
+	// ECSTemplate is the name of the index template whose ECS version is
+	// compared to the ECS version of the events on connect. The check is
+	// disabled if empty.
+	ECSTemplate string
+
+	// AWSSigner signs all requests with AWS Signature Version 4, if set.
+	AWSSigner *AWSSigner
+
+	// IndexNames validates the index names of the events, if set. Events
+	// with invalid index names are not indexed, and reported as failed.
+	IndexNames *IndexNames
+
+	// BulkTracer logs the failed bulk requests, if set.
+	BulkTracer *BulkTracer
 }
 
 type connectCallback func(client *Client) error
@@ -153,6 +171,15 @@ func NewClient(
 		}
 	}
 
+	var roundTripper http.RoundTripper = &http.Transport{
+		Dial:    dialer.Dial,
+		DialTLS: tlsDialer.Dial,
+		Proxy:   proxy,
+	}
+	if s.AWSSigner != nil {
+		roundTripper = newAWSSigningTransport(roundTripper, s.AWSSigner)
+	}
+
 	client := &Client{
 		Connection: Connection{
 			URL:      s.URL,
@@ -160,25 +187,24 @@ func NewClient(
 			Password: s.Password,
 			Headers:  s.Headers,
 			http: &http.Client{
-				Transport: &http.Transport{
-					Dial:    dialer.Dial,
-					DialTLS: tlsDialer.Dial,
-					Proxy:   proxy,
-				},
-				Timeout: s.Timeout,
+				Transport: roundTripper,
+				Timeout:   s.Timeout,
 			},
 			encoder: encoder,
 		},
-		tlsConfig: s.TLS,
-		index:     s.Index,
-		pipeline:  pipeline,
-		params:    params,
-		timeout:   s.Timeout,
+		tlsConfig:  s.TLS,
+		index:      s.Index,
+		indexNames: s.IndexNames,
+		bulkTracer: s.BulkTracer,
+		pipeline:   pipeline,
+		params:     params,
+		timeout:    s.Timeout,
 
 		bulkRequ: bulkRequ,
 
 		compressionLevel: compression,
 		proxyURL:         s.Proxy,
+		awsSigner:        s.AWSSigner,
 	}
 
 	client.Connection.onConnectCallback = func() error {
@@ -193,6 +219,11 @@ func NewClient(
 				}
 			}
 		}
+
+		// The template is checked after the callbacks, which might load it.
+		if s.ECSTemplate != "" {
+			client.checkECSVersion(s.ECSTemplate)
+		}
 		return nil
 	}
 
@@ -219,6 +250,9 @@ func (client *Client) Clone() *Client {
 			Headers:          client.Headers,
 			Timeout:          client.http.Timeout,
 			CompressionLevel: client.compressionLevel,
+			AWSSigner:        client.awsSigner,
+			IndexNames:       client.indexNames,
+			BulkTracer:       client.bulkTracer,
 		},
 		nil, // XXX: do not pass connection callback?
 	)
@@ -260,7 +294,7 @@ func (client *Client) publishEvents(
 	// events slice
 
 	origCount := len(data)
-	data = bulkEncodePublishRequest(body, client.index, client.pipeline, data)
+	data = bulkEncodePublishRequest(body, client.index, client.indexNames, client.pipeline, data)
 	newCount := len(data)
 	if st != nil && origCount > newCount {
 		st.Dropped(origCount - newCount)
@@ -274,6 +308,7 @@ func (client *Client) publishEvents(
 	status, result, sendErr := client.sendBulkRequest(requ)
 	if sendErr != nil {
 		logp.Err("Failed to perform any bulk index operations: %s", sendErr)
+		client.bulkTracer.Trace(client, requ.requ, data, status, result.raw, sendErr)
 		return data, sendErr
 	}
 
@@ -286,6 +321,9 @@ func (client *Client) publishEvents(
 	if status != 200 {
 		failedEvents = data
 	} else {
+		// The events are traced before collecting the failed events, reusing
+		// the events slice.
+		client.bulkTracer.Trace(client, requ.requ, data, status, result.raw, nil)
 		client.json.init(result.raw)
 		failedEvents = bulkCollectPublishFails(&client.json, data)
 	}
@@ -308,17 +346,24 @@ func (client *Client) publishEvents(
 }
 
 // fillBulkRequest encodes all bulk requests and returns slice of events
-// successfully added to bulk request.
+// successfully added to bulk request. Events with an invalid index name are
+// marked as failed, so they are added to the dead-letter spool if enabled.
 func bulkEncodePublishRequest(
 	body bulkWriter,
 	index outil.Selector,
+	indexNames *IndexNames,
 	pipeline *outil.Selector,
 	data []publisher.Event,
 ) []publisher.Event {
 	okEvents := data[:0]
 	for i := range data {
 		event := &data[i].Content
-		meta := createEventBulkMeta(index, pipeline, event)
+		meta, err := createEventBulkMeta(index, indexNames, pipeline, event)
+		if err != nil {
+			logp.Err("Dropping event with invalid index name: %v", err)
+			data[i].Fail()
+			continue
+		}
 		if err := body.Add(meta, event); err != nil {
 			logp.Err("Failed to encode event: %s", err)
 			continue
@@ -330,9 +375,15 @@ func bulkEncodePublishRequest(
 
 func createEventBulkMeta(
 	index outil.Selector,
+	indexNames *IndexNames,
 	pipelineSel *outil.Selector,
 	event *beat.Event,
-) interface{} {
+) (interface{}, error) {
+	indexName, err := indexNames.Check(getIndex(event, index))
+	if err != nil {
+		return nil, err
+	}
+
 	pipeline, err := getPipeline(event, pipelineSel)
 	if err != nil {
 		logp.Err("Failed to select pipeline: %v", err)
@@ -349,10 +400,10 @@ func createEventBulkMeta(
 
 		return bulkMeta{
 			Index: bulkMetaIndex{
-				Index:   getIndex(event, index),
+				Index:   indexName,
 				DocType: eventType,
 			},
-		}
+		}, nil
 	}
 
 	type bulkMetaIndex struct {
@@ -366,11 +417,11 @@ func createEventBulkMeta(
 
 	return bulkMeta{
 		Index: bulkMetaIndex{
-			Index:    getIndex(event, index),
+			Index:    indexName,
 			Pipeline: pipeline,
 			DocType:  eventType,
 		},
-	}
+	}, nil
 }
 
 func getPipeline(event *beat.Event, pipelineSel *outil.Selector) (string, error) {
@@ -463,6 +514,7 @@ func bulkCollectPublishFails(
 		if status < 500 && status != 429 {
 			// hard failure, don't collect
 			logp.Warn("Can not index event (status=%v): %s", status, msg)
+			data[i].Fail()
 			continue
 		}
 
@@ -19,6 +19,7 @@ import (
 	"github.com/elastic/beats/libbeat/outputs/outest"
 	"github.com/elastic/beats/libbeat/outputs/outil"
 	"github.com/elastic/beats/libbeat/publisher"
+	"github.com/elastic/beats/libbeat/template"
 )
 
 func readStatusItem(in []byte) (int, string, error) {
@@ -318,3 +319,62 @@ func TestClientWithHeaders(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 2, requestCount)
 }
+
+func TestClientConnectTemplateForbidden(t *testing.T) {
+	bulkRequests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/":
+			fmt.Fprintln(w, `{"version":{"number":"6.2.0"}}`)
+		case strings.HasPrefix(r.URL.Path, "/_template/"):
+			// The user of the beat is only allowed to publish events.
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprintln(w, `{"error":{"type":"security_exception"},"status":403}`)
+		case r.URL.Path == "/_bulk":
+			bulkRequests++
+			fmt.Fprintln(w, `{"items":[{"index":{"status":201}}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	newClient := func(settings map[string]interface{}) *Client {
+		settings["fields"] = "../../template/testdata/fields.yml"
+		cfg, err := common.NewConfigFrom(settings)
+		assert.NoError(t, err)
+
+		onConnect := &callbacksRegistry{}
+		onConnect.callbacks = append(onConnect.callbacks, func(client *Client) error {
+			loader, err := template.NewLoader(cfg, client, beat.Info{Beat: "testbeat", IndexPrefix: "testbeat", Version: "6.2.0"})
+			if err != nil {
+				return err
+			}
+			return loader.Load()
+		})
+
+		client, err := NewClient(ClientSettings{
+			URL:   ts.URL,
+			Index: outil.MakeSelector(outil.ConstSelectorExpr("test")),
+		}, onConnect)
+		assert.NoError(t, err)
+		return client
+	}
+
+	// Without skip_unauthorized, the denied template fails the connection.
+	assert.Error(t, newClient(map[string]interface{}{}).Connect())
+
+	client := newClient(map[string]interface{}{"skip_unauthorized": true})
+	assert.NoError(t, client.Connect())
+
+	event := beat.Event{Fields: common.MapStr{
+		"@timestamp": common.Time(time.Now()),
+		"message":    "Test message from libbeat",
+	}}
+	batch := outest.NewBatch(event)
+	assert.NoError(t, client.Publish(batch))
+	assert.Equal(t, 1, bulkRequests)
+	if assert.Len(t, batch.Signals, 1) {
+		assert.Equal(t, outest.BatchACK, batch.Signals[0].Tag)
+	}
+}
@@ -1,6 +1,7 @@
 package elasticsearch
 
 import (
+	"errors"
 	"time"
 
 	"github.com/elastic/beats/libbeat/outputs"
@@ -21,6 +22,10 @@ type elasticsearchConfig struct {
 	MaxRetries       int                `config:"max_retries"`
 	Timeout          time.Duration      `config:"timeout"`
 	Backoff          Backoff            `config:"backoff"`
+	ECSVersionCheck  ecsVersionCheck    `config:"ecs_version_check"`
+	AWS              awsSigningConfig   `config:"aws"`
+	IndexName        indexNameConfig    `config:"index_name"`
+	BulkTrace        bulkTraceConfig    `config:"bulk_trace"`
 }
 
 type Backoff struct {
@@ -28,6 +33,13 @@ type Backoff struct {
 	Max  time.Duration
 }
 
+// ecsVersionCheck configures the comparison of the ECS version of the events
+// with the ECS version of the index template installed in Elasticsearch.
+type ecsVersionCheck struct {
+	Enabled  bool   `config:"enabled"`
+	Template string `config:"template"`
+}
+
 const (
 	defaultBulkSize = 50
 )
@@ -49,6 +61,11 @@ var (
 			Init: 1 * time.Second,
 			Max:  60 * time.Second,
 		},
+		IndexName: indexNameConfig{
+			Normalize:   true,
+			Replacement: "_",
+		},
+		BulkTrace: defaultBulkTraceConfig,
 	}
 )
 
@@ -59,5 +76,14 @@ func (c *elasticsearchConfig) Validate() error {
 		}
 	}
 
+	if c.AWS.Enabled {
+		if c.AWS.Region == "" {
+			return errors.New("aws.region must be set if AWS request signing is enabled")
+		}
+		if c.Username != "" || c.Password != "" {
+			return errors.New("username and password can not be used with AWS request signing")
+		}
+	}
+
 	return nil
 }
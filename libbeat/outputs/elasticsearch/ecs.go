@@ -0,0 +1,80 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/version"
+)
+
+// ecsWarnf reports ECS version mismatches. Overwritten by tests.
+var ecsWarnf = logp.Warn
+
+// checkECSVersion compares the ECS version stored in the `_meta` of the index
+// template with the ECS version of the published events, logging a warning if
+// they differ. Events not matching the ECS version of the template might be
+// indexed with unexpected mappings. The check never fails the connection.
+func (client *Client) checkECSVersion(templateName string) {
+	status, body, err := client.Request("GET", "/_template/"+templateName, "", nil, nil)
+	if status == http.StatusNotFound {
+		debugf("Template %v not found, skipping ECS version check", templateName)
+		return
+	}
+	if err != nil {
+		logp.Err("Failed to read template %v for the ECS version check: %v", templateName, err)
+		return
+	}
+
+	templateVersion, err := parseTemplateECSVersion(templateName, body)
+	if err != nil {
+		logp.Err("Failed to parse template %v for the ECS version check: %v", templateName, err)
+		return
+	}
+
+	switch templateVersion {
+	case version.ECSVersion:
+		debugf("ECS version %v of template %v matches the events", templateVersion, templateName)
+	case "":
+		ecsWarnf("Template %v has no ECS version, events use ECS version %v. "+
+			"Consider overwriting the template.", templateName, version.ECSVersion)
+	default:
+		ecsWarnf("Template %v uses ECS version %v, but events use ECS version %v. "+
+			"Consider overwriting the template.", templateName, templateVersion, version.ECSVersion)
+	}
+}
+
+// parseTemplateECSVersion returns the `ecs_version` of the mappings `_meta` of
+// a GET _template response. Mappings with and without a type are supported.
+func parseTemplateECSVersion(templateName string, body []byte) (string, error) {
+	type meta struct {
+		ECSVersion string `json:"ecs_version"`
+	}
+
+	var response map[string]struct {
+		Mappings map[string]json.RawMessage `json:"mappings"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", err
+	}
+
+	mappings := response[templateName].Mappings
+	if raw, exists := mappings["_meta"]; exists {
+		var m meta
+		err := json.Unmarshal(raw, &m)
+		return m.ECSVersion, err
+	}
+
+	for _, raw := range mappings {
+		var mapping struct {
+			Meta *meta `json:"_meta"`
+		}
+		if err := json.Unmarshal(raw, &mapping); err != nil {
+			return "", err
+		}
+		if mapping.Meta != nil && mapping.Meta.ECSVersion != "" {
+			return mapping.Meta.ECSVersion, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,126 @@
+// +build !integration
+
+package elasticsearch
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/outputs/outil"
+	"github.com/elastic/beats/libbeat/version"
+)
+
+// templateServer mocks an Elasticsearch node with the given index templates.
+func templateServer(templates map[string]string, requests *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r.URL.Path)
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `{"version": {"number": "6.0.0"}}`)
+			return
+		}
+
+		template, exists := templates[r.URL.Path]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{}`)
+			return
+		}
+		fmt.Fprint(w, template)
+	}))
+}
+
+func templateWithECSVersion(name, ecsVersion string) string {
+	return fmt.Sprintf(`{"%v": {"order": 1, "mappings": {"doc": {"_meta": {"version": "7.0.0-alpha1", "ecs_version": "%v"}}}}}`,
+		name, ecsVersion)
+}
+
+func connectWithECSCheck(t *testing.T, templates map[string]string, ecsTemplate string) ([]string, []string) {
+	var warnings, requests []string
+	warnf := ecsWarnf
+	defer func() { ecsWarnf = warnf }()
+	ecsWarnf = func(format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+
+	server := templateServer(templates, &requests)
+	defer server.Close()
+
+	client, err := NewClient(ClientSettings{
+		URL:         server.URL,
+		Index:       outil.MakeSelector(outil.ConstSelectorExpr("test")),
+		ECSTemplate: ecsTemplate,
+	}, nil)
+	require.NoError(t, err)
+
+	// The ECS version check never fails the connection.
+	require.NoError(t, client.Connect())
+	return warnings, requests
+}
+
+func TestECSVersionCheckMismatch(t *testing.T) {
+	warnings, _ := connectWithECSCheck(t, map[string]string{
+		"/_template/testbeat-7.0.0": templateWithECSVersion("testbeat-7.0.0", "0.1.0"),
+	}, "testbeat-7.0.0")
+
+	if assert.Len(t, warnings, 1) {
+		assert.Contains(t, warnings[0], "uses ECS version 0.1.0")
+		assert.Contains(t, warnings[0], version.ECSVersion)
+	}
+}
+
+func TestECSVersionCheckMissingVersion(t *testing.T) {
+	warnings, _ := connectWithECSCheck(t, map[string]string{
+		"/_template/testbeat-7.0.0": `{"testbeat-7.0.0": {"mappings": {"doc": {"_meta": {"version": "6.2.0"}}}}}`,
+	}, "testbeat-7.0.0")
+
+	if assert.Len(t, warnings, 1) {
+		assert.Contains(t, warnings[0], "has no ECS version")
+	}
+}
+
+func TestECSVersionCheckMatch(t *testing.T) {
+	warnings, requests := connectWithECSCheck(t, map[string]string{
+		"/_template/testbeat-7.0.0": templateWithECSVersion("testbeat-7.0.0", version.ECSVersion),
+	}, "testbeat-7.0.0")
+
+	assert.Empty(t, warnings)
+	assert.Equal(t, []string{"/", "/_template/testbeat-7.0.0"}, requests)
+}
+
+func TestECSVersionCheckTemplateNotFound(t *testing.T) {
+	warnings, requests := connectWithECSCheck(t, nil, "testbeat-7.0.0")
+
+	assert.Empty(t, warnings)
+	assert.Equal(t, []string{"/", "/_template/testbeat-7.0.0"}, requests)
+}
+
+func TestECSVersionCheckDisabled(t *testing.T) {
+	warnings, requests := connectWithECSCheck(t, nil, "")
+
+	assert.Empty(t, warnings)
+	assert.Equal(t, []string{"/"}, requests)
+}
+
+func TestParseTemplateECSVersion(t *testing.T) {
+	tests := map[string]string{
+		`{"t": {"mappings": {"doc": {"_meta": {"ecs_version": "1.0.0"}}}}}`:       "1.0.0",
+		`{"t": {"mappings": {"_default_": {"_meta": {"ecs_version": "1.0.0"}}}}}`: "1.0.0",
+		`{"t": {"mappings": {"_meta": {"ecs_version": "1.0.0"}}}}`:                "1.0.0",
+		`{"t": {"mappings": {"doc": {"properties": {}}}}}`:                        "",
+		`{"other": {"mappings": {"doc": {"_meta": {"ecs_version": "1.0.0"}}}}}`:   "",
+	}
+
+	for body, expected := range tests {
+		ecsVersion, err := parseTemplateECSVersion("t", []byte(body))
+		require.NoError(t, err, body)
+		assert.Equal(t, expected, ecsVersion, body)
+	}
+
+	_, err := parseTemplateECSVersion("t", []byte("not json"))
+	assert.Error(t, err)
+}
@@ -109,11 +109,31 @@ func makeES(
 		logp.Info("Using proxy URL: %s", proxyURL)
 	}
 
+	awsSigner, err := newAWSSigner(config.AWS)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	indexNames, err := newIndexNames(config.IndexName)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	bulkTracer := newBulkTracer(config.BulkTrace)
+
 	params := config.Params
 	if len(params) == 0 {
 		params = nil
 	}
 
+	var ecsTemplate string
+	if config.ECSVersionCheck.Enabled {
+		ecsTemplate = config.ECSVersionCheck.Template
+		if ecsTemplate == "" {
+			ecsTemplate = fmt.Sprintf("%v-%v", beat.IndexPrefix, beat.Version)
+		}
+	}
+
 	clients := make([]outputs.NetworkClient, len(hosts))
 	for i, host := range hosts {
 		esURL, err := common.MakeURL(config.Protocol, config.Path, host, 9200)
@@ -136,6 +156,10 @@ func makeES(
 			Timeout:          config.Timeout,
 			CompressionLevel: config.CompressionLevel,
 			Stats:            stats,
+			ECSTemplate:      ecsTemplate,
+			AWSSigner:        awsSigner,
+			IndexNames:       indexNames,
+			BulkTracer:       bulkTracer,
 		}, &connectCallbackRegistry)
 		if err != nil {
 			return outputs.Fail(err)
@@ -201,6 +225,11 @@ func NewElasticsearchClients(cfg *common.Config) ([]Client, error) {
 		logp.Info("Using proxy URL: %s", proxyURL)
 	}
 
+	awsSigner, err := newAWSSigner(config.AWS)
+	if err != nil {
+		return nil, err
+	}
+
 	params := config.Params
 	if len(params) == 0 {
 		params = nil
@@ -224,6 +253,7 @@ func NewElasticsearchClients(cfg *common.Config) ([]Client, error) {
 			Headers:          config.Headers,
 			Timeout:          config.Timeout,
 			CompressionLevel: config.CompressionLevel,
+			AWSSigner:        awsSigner,
 		}, nil)
 		if err != nil {
 			return clients, err
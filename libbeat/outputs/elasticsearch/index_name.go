@@ -0,0 +1,101 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// indexNameConfig configures the validation of the index names of events.
+type indexNameConfig struct {
+	Normalize   bool   `config:"normalize"`
+	Replacement string `config:"replacement"`
+}
+
+// maxIndexNameBytes is the maximum length of index names in Elasticsearch.
+const maxIndexNameBytes = 255
+
+// invalidIndexNameChars are the characters not allowed in index names.
+const invalidIndexNameChars = `\/*?"<>| ,#`
+
+var errEmptyIndexName = errors.New("index name is empty")
+
+// IndexNames validates the index names of events against the naming rules of
+// Elasticsearch. Invalid names are lowercased and illegal characters are
+// replaced if Normalize is set. Events with names that are still invalid are
+// not indexed.
+type IndexNames struct {
+	Normalize   bool
+	Replacement string
+}
+
+func newIndexNames(config indexNameConfig) (*IndexNames, error) {
+	if strings.ContainsAny(config.Replacement, invalidIndexNameChars) ||
+		config.Replacement != strings.ToLower(config.Replacement) {
+		return nil, fmt.Errorf("index_name.replacement '%v' is not valid in index names", config.Replacement)
+	}
+
+	return &IndexNames{
+		Normalize:   config.Normalize,
+		Replacement: config.Replacement,
+	}, nil
+}
+
+// Check returns the index name to be used, normalizing it if enabled. An error
+// is returned if the name is invalid. Names exceeding the maximum length are
+// not truncated, as this might remove the date of daily indices.
+func (n *IndexNames) Check(name string) (string, error) {
+	if n == nil {
+		return name, nil
+	}
+
+	if n.Normalize {
+		name = n.normalize(name)
+	}
+	if err := validateIndexName(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+func (n *IndexNames) normalize(name string) string {
+	name = replaceInvalid(strings.ToLower(name), n.Replacement)
+	return strings.TrimLeft(name, "-_+")
+}
+
+// replaceInvalid replaces each illegal character with the replacement. Illegal
+// characters are removed, if the replacement is empty.
+func replaceInvalid(name, replacement string) string {
+	if !strings.ContainsAny(name, invalidIndexNameChars) {
+		return name
+	}
+
+	var b bytes.Buffer
+	for _, r := range name {
+		if strings.ContainsRune(invalidIndexNameChars, r) {
+			b.WriteString(replacement)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func validateIndexName(name string) error {
+	switch {
+	case name == "":
+		return errEmptyIndexName
+	case name == "." || name == "..":
+		return fmt.Errorf("index name '%v' is not allowed", name)
+	case len(name) > maxIndexNameBytes:
+		return fmt.Errorf("index name '%v' is longer than %v bytes", name, maxIndexNameBytes)
+	case name != strings.ToLower(name):
+		return fmt.Errorf("index name '%v' must be lowercase", name)
+	case strings.ContainsAny(name, invalidIndexNameChars):
+		return fmt.Errorf("index name '%v' must not contain any of '%v'", name, invalidIndexNameChars)
+	case strings.IndexAny(name, "-_+") == 0:
+		return fmt.Errorf("index name '%v' must not start with '-', '_' or '+'", name)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+// +build !integration
+
+package elasticsearch
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/fmtstr"
+	"github.com/elastic/beats/libbeat/outputs/outil"
+	"github.com/elastic/beats/libbeat/publisher"
+)
+
+func TestIndexNamesCheck(t *testing.T) {
+	long := strings.Repeat("a", maxIndexNameBytes)
+
+	tests := []struct {
+		name     string
+		config   indexNameConfig
+		input    string
+		expected string
+		err      bool
+	}{
+		{name: "valid", config: defaultConfig.IndexName, input: "beat-2018.01.02", expected: "beat-2018.01.02"},
+		{name: "uppercase", config: defaultConfig.IndexName, input: "Beat-Service", expected: "beat-service"},
+		{name: "illegal chars", config: defaultConfig.IndexName, input: `a b\c/d*e?f"g<h>i|j,k#l`, expected: "a_b_c_d_e_f_g_h_i_j_k_l"},
+		{name: "strip illegal chars", config: indexNameConfig{Normalize: true}, input: "my index#1", expected: "myindex1"},
+		{name: "leading chars", config: defaultConfig.IndexName, input: "_-+beat", expected: "beat"},
+		{name: "leading illegal char", config: defaultConfig.IndexName, input: " beat", expected: "beat"},
+		{name: "cross cluster colon", config: defaultConfig.IndexName, input: "beat:x", expected: "beat:x"},
+		{name: "max length", config: defaultConfig.IndexName, input: long, expected: long},
+		{name: "over-length", config: defaultConfig.IndexName, input: long + "a", err: true},
+		{name: "over-length multi-byte", config: defaultConfig.IndexName, input: long[1:] + "ä", err: true},
+		{name: "empty", config: defaultConfig.IndexName, input: "", err: true},
+		{name: "only illegal chars", config: indexNameConfig{Normalize: true}, input: "##", err: true},
+		{name: "dot", config: defaultConfig.IndexName, input: ".", err: true},
+		{name: "dot dot", config: defaultConfig.IndexName, input: "..", err: true},
+		{name: "uppercase without normalize", config: indexNameConfig{}, input: "Beat", err: true},
+		{name: "illegal chars without normalize", config: indexNameConfig{}, input: "be at", err: true},
+		{name: "leading chars without normalize", config: indexNameConfig{}, input: "_beat", err: true},
+		{name: "valid without normalize", config: indexNameConfig{}, input: "beat", expected: "beat"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			names, err := newIndexNames(test.config)
+			require.NoError(t, err)
+
+			name, err := names.Check(test.input)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, name)
+		})
+	}
+}
+
+func TestIndexNamesDisabled(t *testing.T) {
+	var names *IndexNames
+	name, err := names.Check("Not Validated")
+	assert.NoError(t, err)
+	assert.Equal(t, "Not Validated", name)
+}
+
+func TestIndexNamesInvalidReplacement(t *testing.T) {
+	for _, replacement := range []string{"#", " ", "A"} {
+		_, err := newIndexNames(indexNameConfig{Normalize: true, Replacement: replacement})
+		assert.Error(t, err, "replacement %q", replacement)
+	}
+}
+
+func TestBulkEncodeInvalidIndexNames(t *testing.T) {
+	sel := outil.MakeSelector(outil.FmtSelectorExpr(fmtstr.MustCompileEvent("%{[service]}"), ""))
+	names, err := newIndexNames(indexNameConfig{})
+	require.NoError(t, err)
+
+	var statuses []beat.EventStatus
+	makeEvent := func(service string) publisher.Event {
+		return publisher.Event{
+			Content: beat.Event{
+				Timestamp: time.Now(),
+				Fields:    common.MapStr{"service": service},
+			},
+			Delivery: publisher.NewDelivery(func(status beat.EventStatus) {
+				statuses = append(statuses, status)
+			}),
+		}
+	}
+
+	events := []publisher.Event{makeEvent("valid"), makeEvent("In Valid"), makeEvent("other")}
+	all := append([]publisher.Event{}, events...)
+
+	body := newJSONEncoder(nil)
+	ok := bulkEncodePublishRequest(body, sel, names, nil, events)
+	require.Len(t, ok, 2)
+	assert.Equal(t, "valid", ok[0].Content.Fields["service"])
+	assert.Equal(t, "other", ok[1].Content.Fields["service"])
+	assert.NotContains(t, body.buf.String(), "In Valid")
+
+	// events with invalid index names are reported as failed, so they are
+	// added to the dead-letter spool
+	for _, event := range all {
+		event.Delivery.Complete()
+	}
+	assert.Equal(t, []beat.EventStatus{beat.EventACKed, beat.EventFailed, beat.EventACKed}, statuses)
+}
@@ -0,0 +1,175 @@
+package eventhub
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenProvider returns the Authorization header of the requests. Tokens are
+// cached until shortly before they expire, or until they are invalidated after
+// being rejected.
+type tokenProvider interface {
+	token() (string, error)
+	invalidate()
+}
+
+// cachedToken is a token and its expiration time.
+type cachedToken struct {
+	mutex   sync.Mutex
+	value   string
+	expires time.Time
+}
+
+// sasProvider creates shared access signature tokens from the shared access
+// key of a connection string.
+type sasProvider struct {
+	cachedToken
+	resource string
+	keyName  string
+	key      string
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+// aadProvider requests OAuth2 tokens of a service principal from Azure Active
+// Directory, with the client credentials flow.
+type aadProvider struct {
+	cachedToken
+	http         *http.Client
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	now          func() time.Time
+}
+
+const (
+	sasTokenTTL = time.Hour
+
+	// tokenRenewal is the time before expiration a token is renewed, such
+	// that tokens do not expire while a request is in progress.
+	tokenRenewal = 5 * time.Minute
+
+	eventHubsScope = "https://eventhubs.azure.net/.default"
+)
+
+func (c *cachedToken) get(now time.Time, create func() (string, time.Time, error)) (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.value != "" && now.Add(tokenRenewal).Before(c.expires) {
+		return c.value, nil
+	}
+
+	value, expires, err := create()
+	if err != nil {
+		return "", err
+	}
+	c.value, c.expires = value, expires
+	return value, nil
+}
+
+func (c *cachedToken) invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.value = ""
+}
+
+func newSASProvider(resource, keyName, key string) *sasProvider {
+	return &sasProvider{
+		resource: resource,
+		keyName:  keyName,
+		key:      key,
+		ttl:      sasTokenTTL,
+		now:      time.Now,
+	}
+}
+
+func (p *sasProvider) token() (string, error) {
+	now := p.now()
+	return p.get(now, func() (string, time.Time, error) {
+		expires := now.Add(p.ttl)
+		return p.sign(expires), expires, nil
+	})
+}
+
+// sign creates a token for the resource, valid until expires.
+func (p *sasProvider) sign(expires time.Time) string {
+	resource := url.QueryEscape(p.resource)
+	expiry := expires.Unix()
+
+	mac := hmac.New(sha256.New, []byte(p.key))
+	fmt.Fprintf(mac, "%s\n%d", resource, expiry)
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	return fmt.Sprintf("SharedAccessSignature sr=%s&sig=%s&se=%d&skn=%s",
+		resource, url.QueryEscape(signature), expiry, url.QueryEscape(p.keyName))
+}
+
+func newAADProvider(client *http.Client, config aadConfig) *aadProvider {
+	authority := config.Authority
+	if authority == "" {
+		authority = defaultAuthority
+	}
+	return &aadProvider{
+		http:         client,
+		tokenURL:     strings.TrimSuffix(authority, "/") + "/" + config.TenantID + "/oauth2/v2.0/token",
+		clientID:     config.ClientID,
+		clientSecret: config.ClientSecret,
+		now:          time.Now,
+	}
+}
+
+func (p *aadProvider) token() (string, error) {
+	now := p.now()
+	return p.get(now, func() (string, time.Time, error) {
+		accessToken, expiresIn, err := p.request()
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		return "Bearer " + accessToken, now.Add(expiresIn), nil
+	})
+}
+
+func (p *aadProvider) request() (string, time.Duration, error) {
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {p.clientID},
+		"client_secret": {p.clientSecret},
+		"scope":         {eventHubsScope},
+	}
+	resp, err := p.http.PostForm(p.tokenURL, form)
+	if err != nil {
+		return "", 0, err
+	}
+	defer closing(resp.Body)
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("failed to get an Azure AD token, status %v: %s", resp.StatusCode, body)
+	}
+
+	var token struct {
+		AccessToken string      `json:"access_token"`
+		ExpiresIn   json.Number `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", 0, fmt.Errorf("failed to decode the Azure AD token: %v", err)
+	}
+	seconds, err := token.ExpiresIn.Int64()
+	if err != nil || token.AccessToken == "" {
+		return "", 0, fmt.Errorf("invalid Azure AD token response: %s", body)
+	}
+	return token.AccessToken, time.Duration(seconds) * time.Second, nil
+}
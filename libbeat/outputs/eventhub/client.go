@@ -0,0 +1,213 @@
+package eventhub
+
+import (
+	"encoding/json"
+
+	"github.com/elastic/beats/libbeat/common/fmtstr"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/codec"
+	"github.com/elastic/beats/libbeat/publisher"
+)
+
+// sender sends batches of messages to an event hub.
+type sender interface {
+	// send publishes the messages in one request. All messages of a request
+	// have the same partition key, such that Event Hubs stores them in the
+	// same partition. Without partition key the partition is chosen by Event
+	// Hubs. The messages are the JSON encoded event data of the batch.
+	send(partitionKey string, messages [][]byte) error
+
+	close() error
+}
+
+type client struct {
+	sender        sender
+	index         string
+	codec         codec.Codec
+	partitionKey  *fmtstr.EventFormatString
+	maxBatchBytes int
+	stats         *outputs.Stats
+	description   string
+}
+
+type clientSettings struct {
+	Sender        sender
+	Index         string
+	Codec         codec.Codec
+	PartitionKey  *fmtstr.EventFormatString
+	MaxBatchBytes int
+	Stats         *outputs.Stats
+	Description   string
+}
+
+// eventData is a message of a batch send request.
+type eventData struct {
+	Body string `json:"Body"`
+}
+
+// partition is a sequence of messages with the same partition key, in the
+// order of the events in the batch.
+type partition struct {
+	key      string
+	messages [][]byte
+	events   []publisher.Event
+}
+
+func newClient(s clientSettings) *client {
+	return &client{
+		sender:        s.Sender,
+		index:         s.Index,
+		codec:         s.Codec,
+		partitionKey:  s.PartitionKey,
+		maxBatchBytes: s.MaxBatchBytes,
+		stats:         s.Stats,
+		description:   s.Description,
+	}
+}
+
+// Connect is a no-op, as requests are authenticated one by one. Connection
+// errors are reported when publishing.
+func (c *client) Connect() error {
+	return nil
+}
+
+func (c *client) Close() error {
+	return c.sender.close()
+}
+
+func (c *client) Publish(batch publisher.Batch) error {
+	events := batch.Events()
+	st := c.stats
+	st.NewBatch(len(events))
+
+	partitions, dropped := c.partition(events)
+	st.Dropped(dropped)
+
+	acked := 0
+	var retry []publisher.Event
+	var err error
+	for _, p := range partitions {
+		for _, chunk := range c.split(p) {
+			if err != nil {
+				retry = append(retry, chunk.events...)
+				continue
+			}
+
+			sendErr := c.sender.send(chunk.key, chunk.messages)
+			switch {
+			case sendErr == nil:
+				acked += len(chunk.events)
+			case isRetryable(sendErr):
+				// Stop sending on the first temporary failure and retry the
+				// remaining events, the following requests would most likely
+				// fail as well.
+				err = sendErr
+				retry = append(retry, chunk.events...)
+			default:
+				// The request has been rejected by Event Hubs, so retrying it
+				// is pointless.
+				logp.Err("Azure Event Hubs rejected %v events: %v", len(chunk.events), sendErr)
+				for i := range chunk.events {
+					chunk.events[i].Fail()
+				}
+				st.Dropped(len(chunk.events))
+			}
+		}
+	}
+
+	st.Acked(acked)
+	if len(retry) > 0 {
+		logp.Err("Failed to send %v events to Azure Event Hubs: %v", len(retry), err)
+		st.Failed(len(retry))
+		batch.RetryEvents(retry)
+		return err
+	}
+	batch.ACK()
+	return nil
+}
+
+// partition encodes the events and groups them by partition key. Events
+// failing to encode or too large for a single request are dropped.
+func (c *client) partition(events []publisher.Event) ([]*partition, int) {
+	var partitions []*partition
+	byKey := map[string]*partition{}
+	dropped := 0
+	for i := range events {
+		event := &events[i]
+
+		msg, err := c.encode(event)
+		if err != nil {
+			logp.Err("Failed to encode event for Azure Event Hubs: %v", err)
+			event.Fail()
+			dropped++
+			continue
+		}
+		if batchSize(len(msg), 1) > c.maxBatchBytes {
+			logp.Err("Dropping event of %v bytes, exceeding the max_batch_bytes of %v", len(msg), c.maxBatchBytes)
+			event.Fail()
+			dropped++
+			continue
+		}
+
+		key := c.key(event)
+		p := byKey[key]
+		if p == nil {
+			p = &partition{key: key}
+			byKey[key] = p
+			partitions = append(partitions, p)
+		}
+		p.messages = append(p.messages, msg)
+		p.events = append(p.events, *event)
+	}
+	return partitions, dropped
+}
+
+// split splits the messages of a partition into requests of at most
+// max_batch_bytes.
+func (c *client) split(p *partition) []*partition {
+	var chunks []*partition
+	var chunk *partition
+	size := 0
+	for i, msg := range p.messages {
+		if chunk == nil || batchSize(size+len(msg), len(chunk.messages)+1) > c.maxBatchBytes {
+			chunk = &partition{key: p.key}
+			chunks = append(chunks, chunk)
+			size = 0
+		}
+		chunk.messages = append(chunk.messages, msg)
+		chunk.events = append(chunk.events, p.events[i])
+		size += len(msg)
+	}
+	return chunks
+}
+
+func (c *client) encode(event *publisher.Event) ([]byte, error) {
+	body, err := c.codec.Encode(c.index, &event.Content)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(eventData{Body: string(body)})
+}
+
+func (c *client) key(event *publisher.Event) string {
+	if c.partitionKey == nil {
+		return ""
+	}
+	key, err := c.partitionKey.Run(&event.Content)
+	if err != nil {
+		debugf("Failed to get the partition key, sending without key: %v", err)
+		return ""
+	}
+	return key
+}
+
+func (c *client) String() string {
+	return c.description
+}
+
+// batchSize returns the size of the JSON array of count messages of size
+// bytes in total.
+func batchSize(size, count int) int {
+	return size + count + 1
+}
@@ -0,0 +1,140 @@
+package eventhub
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common/fmtstr"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/codec"
+)
+
+type eventHubConfig struct {
+	ConnectionString string                    `config:"connection_string"`
+	Namespace        string                    `config:"namespace"`
+	EventHub         string                    `config:"eventhub"`
+	AAD              *aadConfig                `config:"aad"`
+	PartitionKey     *fmtstr.EventFormatString `config:"partition_key"`
+	MaxBatchBytes    int                       `config:"max_batch_bytes" validate:"min=1"`
+	TLS              *outputs.TLSConfig        `config:"ssl"`
+	BulkMaxSize      int                       `config:"bulk_max_size"`
+	MaxRetries       int                       `config:"max_retries"`
+	Timeout          time.Duration             `config:"timeout"`
+	Backoff          backoff                   `config:"backoff"`
+	Codec            codec.Config              `config:"codec"`
+}
+
+// aadConfig configures the Azure Active Directory client credentials of a
+// service principal, used instead of a connection string.
+type aadConfig struct {
+	TenantID     string `config:"tenant_id" validate:"required"`
+	ClientID     string `config:"client_id" validate:"required"`
+	ClientSecret string `config:"client_secret" validate:"required"`
+	Authority    string `config:"authority"`
+}
+
+type backoff struct {
+	Init time.Duration
+	Max  time.Duration
+}
+
+// connectionString is the parsed Event Hubs connection string, like
+// `Endpoint=sb://<namespace>.servicebus.windows.net/;SharedAccessKeyName=<name>;SharedAccessKey=<key>;EntityPath=<eventhub>`.
+type connectionString struct {
+	Namespace string
+	KeyName   string
+	Key       string
+	EventHub  string
+}
+
+const (
+	defaultBulkSize = 500
+
+	// defaultMaxBatchBytes is the 1MB send limit of Event Hubs, minus some
+	// headroom for the message headers.
+	defaultMaxBatchBytes = 1046528
+
+	defaultAuthority = "https://login.microsoftonline.com"
+	namespaceDomain  = ".servicebus.windows.net"
+)
+
+var defaultConfig = eventHubConfig{
+	MaxBatchBytes: defaultMaxBatchBytes,
+	Timeout:       90 * time.Second,
+	MaxRetries:    3,
+	Backoff: backoff{
+		Init: 1 * time.Second,
+		Max:  60 * time.Second,
+	},
+}
+
+func (c *eventHubConfig) Validate() error {
+	switch {
+	case c.ConnectionString != "" && c.AAD != nil:
+		return errors.New("either connection_string or aad can be configured, not both")
+	case c.ConnectionString != "":
+		cs, err := parseConnectionString(c.ConnectionString)
+		if err != nil {
+			return err
+		}
+		if cs.EventHub == "" && c.EventHub == "" {
+			return errors.New("eventhub must be set if the connection string has no EntityPath")
+		}
+	case c.AAD != nil:
+		if c.Namespace == "" || c.EventHub == "" {
+			return errors.New("namespace and eventhub must be set for aad authentication")
+		}
+	default:
+		return errors.New("connection_string or aad must be configured")
+	}
+	return nil
+}
+
+// endpoint returns the namespace host and the event hub name of the config.
+func (c *eventHubConfig) endpoint() (namespace, eventHub string) {
+	namespace, eventHub = c.Namespace, c.EventHub
+	if c.ConnectionString != "" {
+		cs, _ := parseConnectionString(c.ConnectionString)
+		if namespace == "" {
+			namespace = cs.Namespace
+		}
+		if eventHub == "" {
+			eventHub = cs.EventHub
+		}
+	}
+	if !strings.Contains(namespace, ".") {
+		namespace += namespaceDomain
+	}
+	return namespace, eventHub
+}
+
+func parseConnectionString(s string) (connectionString, error) {
+	var cs connectionString
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return cs, fmt.Errorf("invalid connection string part '%v'", part)
+		}
+		switch strings.ToLower(kv[0]) {
+		case "endpoint":
+			endpoint := strings.TrimPrefix(kv[1], "sb://")
+			cs.Namespace = strings.TrimSuffix(endpoint, "/")
+		case "sharedaccesskeyname":
+			cs.KeyName = kv[1]
+		case "sharedaccesskey":
+			cs.Key = kv[1]
+		case "entitypath":
+			cs.EventHub = kv[1]
+		}
+	}
+
+	if cs.Namespace == "" || cs.KeyName == "" || cs.Key == "" {
+		return cs, errors.New("connection string must contain Endpoint, SharedAccessKeyName and SharedAccessKey")
+	}
+	return cs, nil
+}
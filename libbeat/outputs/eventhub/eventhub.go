@@ -0,0 +1,72 @@
+package eventhub
+
+import (
+	"fmt"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/codec"
+)
+
+func init() {
+	outputs.RegisterType("azure-eventhub", makeEventHub)
+}
+
+var debugf = logp.MakeDebug("eventhub")
+
+func makeEventHub(
+	beat beat.Info,
+	stats *outputs.Stats,
+	cfg *common.Config,
+) (outputs.Group, error) {
+	if !cfg.HasField("bulk_max_size") {
+		cfg.SetInt("bulk_max_size", -1, defaultBulkSize)
+	}
+
+	config := defaultConfig
+	if err := cfg.Unpack(&config); err != nil {
+		return outputs.Fail(err)
+	}
+
+	tlsConfig, err := outputs.LoadTLSConfig(config.TLS)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	enc, err := codec.CreateEncoder(beat, config.Codec)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	namespace, eventHub := config.endpoint()
+	httpClient, err := newHTTPClient(tlsConfig, config.Timeout, stats)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	url := fmt.Sprintf("https://%s/%s", namespace, eventHub)
+	var tokens tokenProvider
+	if config.AAD != nil {
+		tokens = newAADProvider(httpClient, *config.AAD)
+	} else {
+		cs, _ := parseConnectionString(config.ConnectionString)
+		tokens = newSASProvider(url, cs.KeyName, cs.Key)
+	}
+
+	logp.Info("Azure Event Hubs url: %s", url)
+
+	var client outputs.NetworkClient = newClient(clientSettings{
+		Sender:        newRESTSender(url, httpClient, tokens),
+		Index:         beat.Beat,
+		Codec:         enc,
+		PartitionKey:  config.PartitionKey,
+		MaxBatchBytes: config.MaxBatchBytes,
+		Stats:         stats,
+		Description:   "azure-eventhub(" + url + ")",
+	})
+	client = outputs.WithBackoff(client, config.Backoff.Init, config.Backoff.Max)
+
+	return outputs.Success(config.BulkMaxSize, config.MaxRetries, client)
+}
@@ -0,0 +1,291 @@
+package eventhub
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/fmtstr"
+	"github.com/elastic/beats/libbeat/outputs/codec"
+	_ "github.com/elastic/beats/libbeat/outputs/codec/format"
+	_ "github.com/elastic/beats/libbeat/outputs/codec/json"
+	"github.com/elastic/beats/libbeat/outputs/outest"
+)
+
+const testConnectionString = "Endpoint=sb://beats.servicebus.windows.net/;SharedAccessKeyName=send;SharedAccessKey=c2VjcmV0;EntityPath=logs"
+
+// mockSender records the requests sent, failing the requests with the
+// configured errors in order.
+type mockSender struct {
+	requests []mockRequest
+	errors   []error
+}
+
+type mockRequest struct {
+	key    string
+	bodies []string
+	size   int
+}
+
+func (s *mockSender) send(partitionKey string, messages [][]byte) error {
+	if len(s.errors) > 0 {
+		err := s.errors[0]
+		s.errors = s.errors[1:]
+		if err != nil {
+			return err
+		}
+	}
+
+	req := mockRequest{key: partitionKey, size: batchSize(0, len(messages))}
+	for _, msg := range messages {
+		var data eventData
+		if err := json.Unmarshal(msg, &data); err != nil {
+			return err
+		}
+		req.bodies = append(req.bodies, data.Body)
+		req.size += len(msg)
+	}
+	s.requests = append(s.requests, req)
+	return nil
+}
+
+func (s *mockSender) close() error {
+	return nil
+}
+
+func newTestClient(t *testing.T, sender sender, partitionKey string, maxBatchBytes int) *client {
+	cfg, err := common.NewConfigFrom(map[string]interface{}{"format.string": "%{[message]}"})
+	require.NoError(t, err)
+	var codecConfig codec.Config
+	require.NoError(t, cfg.Unpack(&codecConfig))
+	enc, err := codec.CreateEncoder(beat.Info{Beat: "test"}, codecConfig)
+	require.NoError(t, err)
+
+	var key *fmtstr.EventFormatString
+	if partitionKey != "" {
+		key = fmtstr.MustCompileEvent(partitionKey)
+	}
+	return newClient(clientSettings{
+		Sender:        sender,
+		Index:         "test",
+		Codec:         enc,
+		PartitionKey:  key,
+		MaxBatchBytes: maxBatchBytes,
+	})
+}
+
+func messageEvents(fields ...common.MapStr) []beat.Event {
+	events := make([]beat.Event, len(fields))
+	for i, f := range fields {
+		events[i] = beat.Event{Fields: f}
+	}
+	return events
+}
+
+func TestPublishPartitionKeyRouting(t *testing.T) {
+	sender := &mockSender{}
+	client := newTestClient(t, sender, "%{[host]}", defaultMaxBatchBytes)
+
+	batch := outest.NewBatch(messageEvents(
+		common.MapStr{"host": "a", "message": "1"},
+		common.MapStr{"host": "b", "message": "2"},
+		common.MapStr{"host": "a", "message": "3"},
+		common.MapStr{"message": "4"},
+		common.MapStr{"host": "b", "message": "5"},
+	)...)
+	require.NoError(t, client.Publish(batch))
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchACK, batch.Signals[0].Tag)
+
+	// events are grouped by partition key in the order of the first event of
+	// the partition, events without key are sent without partition key
+	require.Len(t, sender.requests, 3)
+	assert.Equal(t, mockRequest{key: "a", bodies: []string{"1", "3"}, size: 27}, sender.requests[0])
+	assert.Equal(t, mockRequest{key: "b", bodies: []string{"2", "5"}, size: 27}, sender.requests[1])
+	assert.Equal(t, mockRequest{key: "", bodies: []string{"4"}, size: 14}, sender.requests[2])
+}
+
+func TestPublishBatching(t *testing.T) {
+	sender := &mockSender{}
+	// each message of 10 characters is encoded as 21 bytes, so 3 messages fit
+	// into a request of 70 bytes
+	client := newTestClient(t, sender, "", 70)
+
+	var fields []common.MapStr
+	for i := 0; i < 7; i++ {
+		fields = append(fields, common.MapStr{"message": strings.Repeat(string('a'+rune(i)), 10)})
+	}
+	fields = append(fields[:2], append([]common.MapStr{{"message": strings.Repeat("x", 100)}}, fields[2:]...)...)
+
+	batch := outest.NewBatch(messageEvents(fields...)...)
+	require.NoError(t, client.Publish(batch))
+	assert.Equal(t, outest.BatchACK, batch.Signals[0].Tag)
+
+	// the event exceeding max_batch_bytes is dropped
+	require.Len(t, sender.requests, 3)
+	assert.Equal(t, []string{"aaaaaaaaaa", "bbbbbbbbbb", "cccccccccc"}, sender.requests[0].bodies)
+	assert.Equal(t, []string{"dddddddddd", "eeeeeeeeee", "ffffffffff"}, sender.requests[1].bodies)
+	assert.Equal(t, []string{"gggggggggg"}, sender.requests[2].bodies)
+	for _, req := range sender.requests {
+		assert.True(t, req.size <= 70, "request of %v bytes", req.size)
+	}
+	assert.Equal(t, 67, sender.requests[0].size)
+}
+
+func TestPublishRetry(t *testing.T) {
+	sender := &mockSender{errors: []error{nil, &sendError{status: http.StatusServiceUnavailable}}}
+	client := newTestClient(t, sender, "%{[host]}", 30)
+
+	batch := outest.NewBatch(messageEvents(
+		common.MapStr{"host": "a", "message": "1"},
+		common.MapStr{"host": "a", "message": "2"},
+		common.MapStr{"host": "a", "message": "3"},
+		common.MapStr{"host": "b", "message": "4"},
+	)...)
+	assert.Error(t, client.Publish(batch))
+
+	// the first request succeeded, the events of the failed and the following
+	// requests are retried
+	require.Len(t, sender.requests, 1)
+	assert.Equal(t, []string{"1", "2"}, sender.requests[0].bodies)
+
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchRetryEvents, batch.Signals[0].Tag)
+	var retried []interface{}
+	for _, event := range batch.Signals[0].Events {
+		retried = append(retried, event.Content.Fields["message"])
+	}
+	assert.Equal(t, []interface{}{"3", "4"}, retried)
+
+	// connection errors are retried too
+	sender = &mockSender{errors: []error{&sendError{status: http.StatusUnauthorized}}}
+	client = newTestClient(t, sender, "", defaultMaxBatchBytes)
+	batch = outest.NewBatch(messageEvents(common.MapStr{"message": "1"})...)
+	assert.Error(t, client.Publish(batch))
+	assert.Equal(t, outest.BatchRetryEvents, batch.Signals[0].Tag)
+	assert.Len(t, batch.Signals[0].Events, 1)
+}
+
+func TestPublishRejected(t *testing.T) {
+	sender := &mockSender{errors: []error{&sendError{status: http.StatusBadRequest}}}
+	client := newTestClient(t, sender, "%{[host]}", defaultMaxBatchBytes)
+
+	batch := outest.NewBatch(messageEvents(
+		common.MapStr{"host": "a", "message": "1"},
+		common.MapStr{"host": "b", "message": "2"},
+	)...)
+	assert.NoError(t, client.Publish(batch))
+
+	// the rejected request is dropped, the others are sent
+	require.Len(t, sender.requests, 1)
+	assert.Equal(t, "b", sender.requests[0].key)
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchACK, batch.Signals[0].Tag)
+}
+
+func TestMakeEventHub(t *testing.T) {
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"connection_string": testConnectionString,
+		"partition_key":     "%{[beat.hostname]}",
+	})
+	require.NoError(t, err)
+
+	group, err := makeEventHub(beat.Info{Beat: "filebeat"}, nil, cfg)
+	require.NoError(t, err)
+	require.Len(t, group.Clients, 1)
+	assert.Equal(t, defaultBulkSize, group.BatchSize)
+}
+
+func TestConfigValidate(t *testing.T) {
+	aad := map[string]interface{}{"tenant_id": "tenant", "client_id": "client", "client_secret": "secret"}
+
+	tests := []struct {
+		name     string
+		settings map[string]interface{}
+		valid    bool
+	}{
+		{
+			name:     "connection string",
+			settings: map[string]interface{}{"connection_string": testConnectionString},
+			valid:    true,
+		},
+		{
+			name: "connection string without entity path",
+			settings: map[string]interface{}{
+				"connection_string": "Endpoint=sb://beats.servicebus.windows.net/;SharedAccessKeyName=send;SharedAccessKey=c2VjcmV0",
+				"eventhub":          "logs",
+			},
+			valid: true,
+		},
+		{
+			name:     "aad",
+			settings: map[string]interface{}{"namespace": "beats", "eventhub": "logs", "aad": aad},
+			valid:    true,
+		},
+		{
+			name:     "no authentication",
+			settings: map[string]interface{}{"namespace": "beats", "eventhub": "logs"},
+		},
+		{
+			name:     "both authentications",
+			settings: map[string]interface{}{"connection_string": testConnectionString, "aad": aad},
+		},
+		{
+			name: "missing eventhub",
+			settings: map[string]interface{}{
+				"connection_string": "Endpoint=sb://beats.servicebus.windows.net/;SharedAccessKeyName=send;SharedAccessKey=c2VjcmV0",
+			},
+		},
+		{
+			name:     "missing key",
+			settings: map[string]interface{}{"connection_string": "Endpoint=sb://beats.servicebus.windows.net/;EntityPath=logs"},
+		},
+		{
+			name:     "aad without namespace",
+			settings: map[string]interface{}{"eventhub": "logs", "aad": aad},
+		},
+		{
+			name: "aad without secret",
+			settings: map[string]interface{}{
+				"namespace": "beats",
+				"eventhub":  "logs",
+				"aad":       map[string]interface{}{"tenant_id": "tenant", "client_id": "client"},
+			},
+		},
+		{
+			name:     "invalid max_batch_bytes",
+			settings: map[string]interface{}{"connection_string": testConnectionString, "max_batch_bytes": 0},
+		},
+	}
+
+	for _, test := range tests {
+		cfg, err := common.NewConfigFrom(test.settings)
+		require.NoError(t, err)
+
+		config := defaultConfig
+		err = cfg.Unpack(&config)
+		if test.valid {
+			assert.NoError(t, err, test.name)
+		} else {
+			assert.Error(t, err, test.name)
+		}
+	}
+}
+
+func TestConfigEndpoint(t *testing.T) {
+	config := eventHubConfig{ConnectionString: testConnectionString}
+	namespace, eventHub := config.endpoint()
+	assert.Equal(t, "beats.servicebus.windows.net", namespace)
+	assert.Equal(t, "logs", eventHub)
+
+	config = eventHubConfig{Namespace: "beats", EventHub: "metrics"}
+	namespace, eventHub = config.endpoint()
+	assert.Equal(t, "beats.servicebus.windows.net", namespace)
+	assert.Equal(t, "metrics", eventHub)
+}
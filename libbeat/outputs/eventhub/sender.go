@@ -0,0 +1,134 @@
+package eventhub
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/transport"
+)
+
+// restSender sends batches using the send batch events operation of the Event
+// Hubs REST API.
+type restSender struct {
+	url    string
+	http   *http.Client
+	tokens tokenProvider
+}
+
+// sendError is the error of a request rejected by Event Hubs.
+type sendError struct {
+	status int
+	msg    string
+}
+
+const batchContentType = "application/vnd.microsoft.servicebus.json"
+
+func newHTTPClient(tls *transport.TLSConfig, timeout time.Duration, stats *outputs.Stats) (*http.Client, error) {
+	dialer := transport.NetDialer(timeout)
+	tlsDialer, err := transport.TLSDialer(dialer, tls, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if stats != nil {
+		dialer = transport.StatsDialer(dialer, stats)
+		tlsDialer = transport.StatsDialer(tlsDialer, stats)
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			Dial:    dialer.Dial,
+			DialTLS: tlsDialer.Dial,
+			Proxy:   http.ProxyFromEnvironment,
+		},
+		Timeout: timeout,
+	}, nil
+}
+
+func newRESTSender(url string, client *http.Client, tokens tokenProvider) *restSender {
+	return &restSender{url: url, http: client, tokens: tokens}
+}
+
+func (s *restSender) send(partitionKey string, messages [][]byte) error {
+	token, err := s.tokens.token()
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	body.WriteByte('[')
+	body.Write(bytes.Join(messages, []byte{','}))
+	body.WriteByte(']')
+
+	req, err := http.NewRequest("POST", s.url+"/messages", &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", batchContentType)
+	req.Header.Set("Authorization", token)
+	if partitionKey != "" {
+		properties, err := json.Marshal(map[string]string{"PartitionKey": partitionKey})
+		if err != nil {
+			return err
+		}
+		req.Header.Set("BrokerProperties", string(properties))
+	}
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer closing(resp.Body)
+
+	// Drain the body so connections can be reused.
+	msg, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		if resp.StatusCode == http.StatusUnauthorized {
+			s.tokens.invalidate()
+		}
+		return &sendError{status: resp.StatusCode, msg: string(msg)}
+	}
+	return nil
+}
+
+func (s *restSender) close() error {
+	if t, ok := s.http.Transport.(*http.Transport); ok {
+		t.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (e *sendError) Error() string {
+	return fmt.Sprintf("event hubs send failed with status %v: %s", e.status, e.msg)
+}
+
+// isRetryable reports whether sending a batch again can succeed. Only requests
+// rejected as invalid are not retried. Authorization failures are retried, as
+// they are reported for expired tokens too.
+func isRetryable(err error) bool {
+	sendErr, ok := err.(*sendError)
+	if !ok {
+		return true
+	}
+	switch {
+	case sendErr.status == http.StatusUnauthorized,
+		sendErr.status == http.StatusTooManyRequests,
+		sendErr.status >= 500:
+		return true
+	default:
+		return false
+	}
+}
+
+func closing(c io.Closer) {
+	if err := c.Close(); err != nil {
+		logp.Warn("Close failed with: %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package eventhub
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticTokens struct {
+	invalidated int
+}
+
+func (s *staticTokens) token() (string, error) {
+	return "SharedAccessSignature test", nil
+}
+
+func (s *staticTokens) invalidate() {
+	s.invalidated++
+}
+
+func TestRESTSender(t *testing.T) {
+	status := http.StatusCreated
+	var request *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request = r
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	tokens := &staticTokens{}
+	sender := newRESTSender(server.URL+"/logs", http.DefaultClient, tokens)
+
+	err := sender.send("host-1", [][]byte{[]byte(`{"Body":"a"}`), []byte(`{"Body":"b"}`)})
+	require.NoError(t, err)
+	assert.Equal(t, "/logs/messages", request.URL.Path)
+	assert.Equal(t, "POST", request.Method)
+	assert.Equal(t, batchContentType, request.Header.Get("Content-Type"))
+	assert.Equal(t, "SharedAccessSignature test", request.Header.Get("Authorization"))
+	assert.Equal(t, `{"PartitionKey":"host-1"}`, request.Header.Get("BrokerProperties"))
+	assert.Equal(t, `[{"Body":"a"},{"Body":"b"}]`, string(body))
+
+	require.NoError(t, sender.send("", [][]byte{[]byte(`{"Body":"a"}`)}))
+	assert.Equal(t, "", request.Header.Get("BrokerProperties"))
+
+	status = http.StatusUnauthorized
+	err = sender.send("", [][]byte{[]byte(`{"Body":"a"}`)})
+	assert.Error(t, err)
+	assert.True(t, isRetryable(err))
+	assert.Equal(t, 1, tokens.invalidated)
+
+	status = http.StatusBadRequest
+	err = sender.send("", [][]byte{[]byte(`{"Body":"a"}`)})
+	assert.Error(t, err)
+	assert.False(t, isRetryable(err))
+}
+
+func TestSASProvider(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	provider := newSASProvider("https://beats.servicebus.windows.net/logs", "send", "c2VjcmV0")
+	provider.now = func() time.Time { return now }
+
+	token, err := provider.token()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(token, "SharedAccessSignature "))
+
+	params, err := url.ParseQuery(strings.TrimPrefix(token, "SharedAccessSignature "))
+	require.NoError(t, err)
+	assert.Equal(t, "https://beats.servicebus.windows.net/logs", params.Get("sr"))
+	assert.Equal(t, "send", params.Get("skn"))
+	assert.Equal(t, "1500003600", params.Get("se"))
+
+	mac := hmac.New(sha256.New, []byte("c2VjcmV0"))
+	mac.Write([]byte(url.QueryEscape("https://beats.servicebus.windows.net/logs") + "\n1500003600"))
+	assert.Equal(t, base64.StdEncoding.EncodeToString(mac.Sum(nil)), params.Get("sig"))
+
+	// the token is cached until shortly before it expires
+	now = now.Add(50 * time.Minute)
+	cached, err := provider.token()
+	require.NoError(t, err)
+	assert.Equal(t, token, cached)
+
+	now = now.Add(6 * time.Minute)
+	renewed, err := provider.token()
+	require.NoError(t, err)
+	assert.NotEqual(t, token, renewed)
+
+	provider.invalidate()
+	now = now.Add(time.Second)
+	invalidated, err := provider.token()
+	require.NoError(t, err)
+	assert.NotEqual(t, renewed, invalidated)
+}
+
+func TestAADProvider(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/tenant/oauth2/v2.0/token", r.URL.Path)
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "client", r.PostForm.Get("client_id"))
+		assert.Equal(t, eventHubsScope, r.PostForm.Get("scope"))
+
+		if r.PostForm.Get("client_secret") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"token_type":"Bearer","expires_in":3599,"access_token":"aad-token"}`))
+	}))
+	defer server.Close()
+
+	provider := newAADProvider(http.DefaultClient, aadConfig{
+		TenantID:     "tenant",
+		ClientID:     "client",
+		ClientSecret: "secret",
+		Authority:    server.URL + "/",
+	})
+
+	token, err := provider.token()
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer aad-token", token)
+
+	_, err = provider.token()
+	require.NoError(t, err)
+	assert.Equal(t, 1, requests)
+
+	provider.invalidate()
+	_, err = provider.token()
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests)
+
+	provider = newAADProvider(http.DefaultClient, aadConfig{
+		TenantID:     "tenant",
+		ClientID:     "client",
+		ClientSecret: "wrong",
+		Authority:    server.URL,
+	})
+	_, err = provider.token()
+	assert.Error(t, err)
+}
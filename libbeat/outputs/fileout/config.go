@@ -14,6 +14,9 @@ type config struct {
 	NumberOfFiles int          `config:"number_of_files"`
 	Codec         codec.Config `config:"codec"`
 	Permissions   uint32       `config:"permissions"`
+
+	// CompressionLevel enables writing each event as a gzip member, if set.
+	CompressionLevel int `config:"compression_level" validate:"min=0, max=9"`
 }
 
 var (
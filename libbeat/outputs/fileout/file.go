@@ -1,6 +1,13 @@
 package fileout
 
 import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
 	"github.com/elastic/beats/libbeat/beat"
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/logp"
@@ -9,6 +16,10 @@ import (
 	"github.com/elastic/beats/libbeat/publisher"
 )
 
+// reopenSignal asks the file output to reopen its file, like logrotate does
+// after renaming or truncating it.
+var reopenSignal os.Signal = syscall.SIGHUP
+
 func init() {
 	outputs.RegisterType("file", makeFileout)
 }
@@ -18,6 +29,15 @@ type fileOutput struct {
 	stats   *outputs.Stats
 	rotator logp.FileRotator
 	codec   codec.Codec
+
+	// The events are gzip compressed if compressionLevel is set.
+	compressionLevel int
+	buf              bytes.Buffer
+	gzip             *gzip.Writer
+
+	signals   chan os.Signal
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
 // New instantiates a new file output instance.
@@ -38,6 +58,7 @@ func makeFileout(
 	if err := fo.init(beat, config); err != nil {
 		return outputs.Fail(err)
 	}
+	fo.handleSignals()
 
 	return outputs.Success(-1, 0, fo)
 }
@@ -58,6 +79,15 @@ func (out *fileOutput) init(beat beat.Info, config config) error {
 
 	out.codec = enc
 
+	if config.CompressionLevel > 0 {
+		out.compressionLevel = config.CompressionLevel
+		out.gzip, err = gzip.NewWriterLevel(&out.buf, config.CompressionLevel)
+		if err != nil {
+			return err
+		}
+		logp.Info("File output compression level set to: %v", config.CompressionLevel)
+	}
+
 	logp.Info("File output path set to: %v", out.rotator.Path)
 	logp.Info("File output base filename set to: %v", out.rotator.Name)
 
@@ -85,8 +115,46 @@ func (out *fileOutput) init(beat beat.Info, config config) error {
 	return nil
 }
 
+// handleSignals reopens the file on SIGHUP, until the output is closed.
+func (out *fileOutput) handleSignals() {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	out.signals = signals
+	out.done = done
+	signal.Notify(signals, reopenSignal)
+
+	go out.reopenOnSignal(signals, done)
+}
+
+func (out *fileOutput) reopenOnSignal(signals <-chan os.Signal, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case <-signals:
+			out.reopen()
+		}
+	}
+}
+
+// reopen flushes the events written to the current file and opens the file
+// again. Events published while reopening are written once the new file is
+// open, so no event is lost when the file is rotated by another process.
+func (out *fileOutput) reopen() {
+	logp.Info("Reopening file output %v", out.rotator.FilePath(0))
+	if err := out.rotator.Reopen(); err != nil {
+		logp.Err("Failed to reopen file output: %v", err)
+	}
+}
+
 // Implement Outputer
 func (out *fileOutput) Close() error {
+	out.closeOnce.Do(func() {
+		if out.done != nil {
+			signal.Stop(out.signals)
+			close(out.done)
+		}
+	})
 	return nil
 }
 
@@ -103,7 +171,7 @@ func (out *fileOutput) Publish(
 	for i := range events {
 		event := &events[i]
 
-		serializedEvent, err := out.codec.Encode(out.beat.Beat, &event.Content)
+		serializedEvent, err := out.encode(&event.Content)
 		if err != nil {
 			if event.Guaranteed() {
 				logp.Critical("Failed to serialize the event: %v", err)
@@ -115,7 +183,11 @@ func (out *fileOutput) Publish(
 			continue
 		}
 
-		err = out.rotator.WriteLine(serializedEvent)
+		if out.compressionLevel > 0 {
+			err = out.rotator.Write(serializedEvent)
+		} else {
+			err = out.rotator.WriteLine(serializedEvent)
+		}
 		if err != nil {
 			st.WriteError()
 
@@ -129,7 +201,11 @@ func (out *fileOutput) Publish(
 			continue
 		}
 
-		st.WriteBytes(len(serializedEvent) + 1)
+		if out.compressionLevel > 0 {
+			st.WriteBytes(len(serializedEvent))
+		} else {
+			st.WriteBytes(len(serializedEvent) + 1)
+		}
 	}
 
 	st.Dropped(dropped)
@@ -137,3 +213,36 @@ func (out *fileOutput) Publish(
 
 	return nil
 }
+
+// encode serializes the event. If compression is enabled, the event line is
+// compressed as a gzip member, so the file is a gzip stream of all the event
+// lines. Events hinting an already gzip compressed message with
+// `@metadata.content_encoding` are not compressed again, their message is
+// written as is instead.
+func (out *fileOutput) encode(event *beat.Event) ([]byte, error) {
+	if out.compressionLevel == 0 {
+		return out.codec.Encode(out.beat.Beat, event)
+	}
+
+	if payload, ok := outputs.GzipPayload(event); ok {
+		return payload, nil
+	}
+
+	serializedEvent, err := out.codec.Encode(out.beat.Beat, event)
+	if err != nil {
+		return nil, err
+	}
+
+	out.buf.Reset()
+	out.gzip.Reset(&out.buf)
+	if _, err := out.gzip.Write(serializedEvent); err != nil {
+		return nil, err
+	}
+	if _, err := out.gzip.Write([]byte{'\n'}); err != nil {
+		return nil, err
+	}
+	if err := out.gzip.Close(); err != nil {
+		return nil, err
+	}
+	return out.buf.Bytes(), nil
+}
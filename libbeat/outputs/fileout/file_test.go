@@ -1,3 +1,221 @@
 // +build !integration
 
 package fileout
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	_ "github.com/elastic/beats/libbeat/outputs/codec/json"
+	"github.com/elastic/beats/libbeat/outputs/outest"
+)
+
+func newFileOutput(t *testing.T, dir string, settings map[string]interface{}) *fileOutput {
+	settings["path"] = dir
+	settings["filename"] = "out"
+	cfg, err := common.NewConfigFrom(settings)
+	require.NoError(t, err)
+
+	group, err := makeFileout(beat.Info{Beat: "test"}, nil, cfg)
+	require.NoError(t, err)
+	require.Len(t, group.Clients, 1)
+	return group.Clients[0].(*fileOutput)
+}
+
+func gzipped(t *testing.T, content string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func testEvents(payload []byte) []beat.Event {
+	ts := time.Date(2018, 3, 1, 10, 0, 0, 0, time.UTC)
+	return []beat.Event{
+		{
+			Timestamp: ts,
+			Fields:    common.MapStr{"message": "plain line"},
+		},
+		{
+			Timestamp: ts,
+			Meta:      common.MapStr{"content_encoding": "gzip"},
+			Fields:    common.MapStr{"message": payload},
+		},
+		{
+			// Not a gzip payload, compressed like other events.
+			Timestamp: ts,
+			Meta:      common.MapStr{"content_encoding": "gzip"},
+			Fields:    common.MapStr{"message": "not compressed"},
+		},
+	}
+}
+
+func decodeMessages(t *testing.T, content []byte) []interface{} {
+	var messages []interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		var event map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			messages = append(messages, scanner.Text())
+			continue
+		}
+		messages = append(messages, event["message"])
+	}
+	require.NoError(t, scanner.Err())
+	return messages
+}
+
+func TestPublishCompressed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileout")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	out := newFileOutput(t, dir, map[string]interface{}{"compression_level": 5})
+	payload := gzipped(t, "precompressed line\n")
+	require.NoError(t, out.Publish(outest.NewBatch(testEvents(payload)...)))
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "out"))
+	require.NoError(t, err)
+
+	// The compressed payload is written as is, between the members of the
+	// other events.
+	assert.True(t, bytes.Contains(content, payload), "payload recompressed")
+	assert.NotEqual(t, 0, bytes.Index(content, payload))
+
+	r, err := gzip.NewReader(bytes.NewReader(content))
+	require.NoError(t, err)
+	decompressed, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"plain line", "precompressed line", "not compressed"}, decodeMessages(t, decompressed))
+}
+
+func TestPublishUncompressed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileout")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	out := newFileOutput(t, dir, map[string]interface{}{})
+	payload := gzipped(t, "precompressed line\n")
+	require.NoError(t, out.Publish(outest.NewBatch(testEvents(payload)...)))
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "out"))
+	require.NoError(t, err)
+
+	// Without compression, the hint is ignored and all events are encoded,
+	// the payload like any other field.
+	messages := decodeMessages(t, content)
+	require.Len(t, messages, 3)
+	assert.Equal(t, "plain line", messages[0])
+	assert.Len(t, messages[1], len(payload))
+	assert.Equal(t, "not compressed", messages[2])
+}
+
+func TestPublishOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileout")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	out := newFileOutput(t, dir, map[string]interface{}{})
+	seq := outest.NewSequencer()
+	for i := 0; i < 3; i++ {
+		events := seq.Tag(testEvents(nil)...)
+		require.NoError(t, out.Publish(outest.NewBatch(events...)))
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "out"))
+	require.NoError(t, err)
+
+	// The events are written in the order they have been published.
+	verifier := outest.NewOrderVerifier(nil)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		var event common.MapStr
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		verifier.Observe(event)
+	}
+	require.NoError(t, scanner.Err())
+	assert.Equal(t, 9, verifier.Events())
+	assert.NoError(t, verifier.Err())
+}
+
+func TestReopenOnSignal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileout")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	out := newFileOutput(t, dir, map[string]interface{}{})
+	defer out.Close()
+	seq := outest.NewSequencer()
+	publish := func() {
+		events := seq.Tag(testEvents(nil)...)
+		require.NoError(t, out.Publish(outest.NewBatch(events...)))
+	}
+
+	// Events published after the file has been renamed are still written to
+	// it, until the output is asked to reopen its file.
+	path := filepath.Join(dir, "out")
+	rotated := filepath.Join(dir, "out.rotated")
+	publish()
+	require.NoError(t, os.Rename(path, rotated))
+	publish()
+
+	out.signals <- syscall.SIGHUP
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		require.True(t, time.Since(start) < 5*time.Second, "file not reopened")
+	}
+	publish()
+
+	verifier := outest.NewOrderVerifier(nil)
+	count := func(path string) int {
+		content, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+
+		n := 0
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for scanner.Scan() {
+			var event common.MapStr
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+			verifier.Observe(event)
+			n++
+		}
+		require.NoError(t, scanner.Err())
+		return n
+	}
+
+	// No event is lost, the events published after the signal are written
+	// to a fresh file.
+	assert.Equal(t, 6, count(rotated))
+	assert.Equal(t, 3, count(path))
+	assert.NoError(t, verifier.Err())
+
+	// The output can be closed more than once.
+	assert.NoError(t, out.Close())
+}
+
+func TestCompressionLevelValidate(t *testing.T) {
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"path":              "/tmp",
+		"compression_level": 10,
+	})
+	require.NoError(t, err)
+	_, err = makeFileout(beat.Info{Beat: "test"}, nil, cfg)
+	assert.Error(t, err)
+}
@@ -0,0 +1,275 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/http2"
+
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/transport"
+	"github.com/elastic/beats/libbeat/publisher"
+)
+
+// Status codes of gRPC, see
+// https://github.com/grpc/grpc/blob/master/doc/statuscodes.md
+const (
+	codeOK                = 0
+	codeCanceled          = 1
+	codeDeadlineExceeded  = 4
+	codeResourceExhausted = 8
+	codeAborted           = 10
+	codeInternal          = 13
+	codeUnavailable       = 14
+)
+
+// maxResponseSize limits the size of the response read from the server. The
+// response message is not decoded, the status of the RPC acknowledges the
+// events.
+const maxResponseSize = 1 << 20
+
+var errNotConnected = errors.New("gRPC client is not connected")
+
+type client struct {
+	host    string
+	url     string
+	headers map[string]string
+	timeout time.Duration
+	encode  encoder
+	stats   *outputs.Stats
+
+	dialer    transport.Dialer
+	transport *http2.Transport
+	conn      net.Conn
+	cc        *http2.ClientConn
+}
+
+type clientSettings struct {
+	Host    string
+	Method  string
+	Headers map[string]string
+	TLS     *transport.TLSConfig
+	Timeout time.Duration
+	Encode  encoder
+	Stats   *outputs.Stats
+}
+
+// rpcStatus is the status of a completed RPC.
+type rpcStatus struct {
+	code    int
+	message string
+}
+
+func newClient(s clientSettings) (*client, error) {
+	dialer, err := transport.MakeDialer(&transport.Config{
+		TLS:     s.TLS,
+		Timeout: s.Timeout,
+		Stats:   s.Stats,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := "http"
+	if s.TLS != nil {
+		scheme = "https"
+	}
+
+	return &client{
+		host:    s.Host,
+		url:     scheme + "://" + s.Host + s.Method,
+		headers: s.Headers,
+		timeout: s.Timeout,
+		encode:  s.Encode,
+		stats:   s.Stats,
+		dialer:  dialer,
+		// The connection is created by the client, TLS is handled by the
+		// dialer, plain text connections use HTTP/2 with prior knowledge.
+		transport: &http2.Transport{AllowHTTP: true},
+	}, nil
+}
+
+// Connect opens the HTTP/2 connection the RPCs are multiplexed on.
+func (c *client) Connect() error {
+	conn, err := c.dialer.Dial("tcp", c.host)
+	if err != nil {
+		return err
+	}
+
+	cc, err := c.transport.NewClientConn(conn)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	debugf("Connected to gRPC server %v", c.host)
+	c.conn, c.cc = conn, cc
+	return nil
+}
+
+// Close closes the connection, failing the RPC in progress.
+func (c *client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn, c.cc = nil, nil
+	return err
+}
+
+// Publish sends the events of the batch with a client-streaming RPC, one
+// message per event. The batch is ACKed once the server completed the RPC
+// successfully. The next batch is not sent before, so the server controls
+// the flow of events by delaying its response.
+func (c *client) Publish(batch publisher.Batch) error {
+	events := batch.Events()
+	st := c.stats
+	st.NewBatch(len(events))
+
+	var body bytes.Buffer
+	dropped := 0
+	for i := range events {
+		event := &events[i]
+
+		msg, err := c.encode(&event.Content)
+		if err != nil {
+			logp.Err("Failed to encode event for gRPC: %v", err)
+			event.Fail()
+			dropped++
+			continue
+		}
+		writeMessage(&body, msg)
+	}
+
+	st.Dropped(dropped)
+	count := len(events) - dropped
+	if count == 0 {
+		batch.ACK()
+		return nil
+	}
+
+	status, err := c.call(body.Bytes())
+	if err != nil {
+		logp.Err("Failed to send events to gRPC server: %v", err)
+		st.Failed(count)
+		batch.Retry()
+		return err
+	}
+
+	switch {
+	case status.code == codeOK:
+		st.Acked(count)
+		batch.ACK()
+		return nil
+
+	case retryable(status.code):
+		st.Failed(count)
+		batch.Retry()
+		return fmt.Errorf("gRPC call failed with status %v: %v", status.code, status.message)
+
+	default:
+		// The events have been rejected by the server, so retrying them is
+		// pointless.
+		logp.Err("gRPC server rejected %v events with status %v: %v", count, status.code, status.message)
+		for i := range events {
+			events[i].Fail()
+		}
+		st.Failed(count)
+		batch.Drop()
+		return nil
+	}
+}
+
+// call runs the RPC with the encoded messages as request stream, and returns
+// its status.
+func (c *client) call(body []byte) (rpcStatus, error) {
+	if c.cc == nil {
+		return rpcStatus{}, errNotConnected
+	}
+
+	req, err := http.NewRequest("POST", c.url, bytes.NewReader(body))
+	if err != nil {
+		return rpcStatus{}, err
+	}
+	req.Header.Set("Content-Type", "application/grpc+proto")
+	req.Header.Set("TE", "trailers")
+	for name, value := range c.headers {
+		req.Header.Add(name, value)
+	}
+	if c.timeout > 0 {
+		ctx, cancel := context.WithTimeout(req.Context(), c.timeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+		req.Header.Set("Grpc-Timeout", strconv.FormatInt(int64(c.timeout/time.Millisecond), 10)+"m")
+	}
+
+	resp, err := c.cc.RoundTrip(req)
+	if err != nil {
+		return rpcStatus{}, err
+	}
+	defer resp.Body.Close()
+
+	// The trailers are only available once the body has been read.
+	_, err = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return rpcStatus{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return rpcStatus{}, fmt.Errorf("gRPC call failed with HTTP status %v", resp.StatusCode)
+	}
+
+	// Servers failing the RPC before any message report the status in the
+	// headers.
+	code := resp.Trailer.Get("Grpc-Status")
+	message := resp.Trailer.Get("Grpc-Message")
+	if code == "" {
+		code = resp.Header.Get("Grpc-Status")
+		message = resp.Header.Get("Grpc-Message")
+	}
+	if code == "" {
+		return rpcStatus{}, errors.New("gRPC response without status")
+	}
+
+	n, err := strconv.Atoi(code)
+	if err != nil {
+		return rpcStatus{}, fmt.Errorf("invalid gRPC status '%v'", code)
+	}
+	// The message is percent encoded.
+	if decoded, err := url.PathUnescape(message); err == nil {
+		message = decoded
+	}
+	return rpcStatus{code: n, message: message}, nil
+}
+
+func (c *client) String() string {
+	return "grpc(" + c.url + ")"
+}
+
+// writeMessage writes a message of the request stream, prefixed by its
+// compression flag and length.
+func writeMessage(w *bytes.Buffer, msg []byte) {
+	var prefix [5]byte
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(msg)))
+	w.Write(prefix[:])
+	w.Write(msg)
+}
+
+// retryable returns true for the status codes of transient failures.
+func retryable(code int) bool {
+	switch code {
+	case codeCanceled, codeDeadlineExceeded, codeResourceExhausted, codeAborted, codeInternal, codeUnavailable:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package grpc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/outputs"
+)
+
+type grpcConfig struct {
+	Method      string             `config:"method" validate:"required"`
+	Message     messageConfig      `config:"message"`
+	Headers     map[string]string  `config:"headers"`
+	LoadBalance bool               `config:"loadbalance"`
+	TLS         *outputs.TLSConfig `config:"ssl"`
+	BulkMaxSize int                `config:"bulk_max_size"`
+	MaxRetries  int                `config:"max_retries"`
+	Timeout     time.Duration      `config:"timeout"`
+	Backoff     backoff            `config:"backoff"`
+}
+
+// messageConfig selects the message type events are encoded into. Events are
+// encoded into google.protobuf.Struct messages if no descriptor set is
+// configured.
+type messageConfig struct {
+	DescriptorSet string `config:"descriptor_set"`
+	MessageType   string `config:"message_type"`
+}
+
+type backoff struct {
+	Init time.Duration
+	Max  time.Duration
+}
+
+const defaultBulkSize = 50
+
+var defaultConfig = grpcConfig{
+	Timeout:     90 * time.Second,
+	MaxRetries:  3,
+	LoadBalance: true,
+	Backoff: backoff{
+		Init: 1 * time.Second,
+		Max:  60 * time.Second,
+	},
+}
+
+func (c *grpcConfig) Validate() error {
+	// The method is the path of the RPC, like /package.Service/Method.
+	parts := strings.Split(c.Method, "/")
+	if len(parts) != 3 || parts[0] != "" || parts[1] == "" || parts[2] == "" {
+		return fmt.Errorf("invalid method '%v', use /package.Service/Method", c.Method)
+	}
+
+	if (c.Message.DescriptorSet == "") != (c.Message.MessageType == "") {
+		return fmt.Errorf("message.descriptor_set and message.message_type must be set together")
+	}
+
+	for name := range c.Headers {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "grpc-") || strings.HasPrefix(name, ":") {
+			return fmt.Errorf("header '%v' is reserved for gRPC", name)
+		}
+		switch lower {
+		case "content-type", "te":
+			return fmt.Errorf("header '%v' is reserved for gRPC", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package grpc
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/protobuf"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/paths"
+)
+
+func init() {
+	outputs.RegisterType("grpc", makeGRPC)
+}
+
+var debugf = logp.MakeDebug("grpc")
+
+func makeGRPC(
+	beat beat.Info,
+	stats *outputs.Stats,
+	cfg *common.Config,
+) (outputs.Group, error) {
+	if !cfg.HasField("bulk_max_size") {
+		cfg.SetInt("bulk_max_size", -1, defaultBulkSize)
+	}
+
+	config := defaultConfig
+	if err := cfg.Unpack(&config); err != nil {
+		return outputs.Fail(err)
+	}
+
+	hosts, err := outputs.ReadHostList(cfg)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	tlsConfig, err := outputs.LoadTLSConfig(config.TLS)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+	if tlsConfig != nil {
+		// gRPC servers only accept HTTP/2 negotiated during the handshake.
+		tlsConfig.NextProtos = []string{"h2"}
+	}
+
+	encode, err := newMessageEncoder(config.Message)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	clients := make([]outputs.NetworkClient, len(hosts))
+	for i, host := range hosts {
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			return outputs.Fail(fmt.Errorf("invalid gRPC host '%v', the port must be set: %v", host, err))
+		}
+
+		var client outputs.NetworkClient
+		client, err = newClient(clientSettings{
+			Host:    host,
+			Method:  config.Method,
+			Headers: config.Headers,
+			TLS:     tlsConfig,
+			Timeout: config.Timeout,
+			Encode:  encode,
+			Stats:   stats,
+		})
+		if err != nil {
+			return outputs.Fail(err)
+		}
+
+		client = outputs.WithBackoff(client, config.Backoff.Init, config.Backoff.Max)
+		clients[i] = client
+	}
+
+	return outputs.SuccessNet(config.LoadBalance, config.BulkMaxSize, config.MaxRetries, clients)
+}
+
+// encoder encodes an event into a protobuf message.
+type encoder func(event *beat.Event) ([]byte, error)
+
+// newMessageEncoder returns the encoder of the configured message type, or of
+// google.protobuf.Struct messages if no message type is configured.
+func newMessageEncoder(config messageConfig) (encoder, error) {
+	if config.DescriptorSet == "" {
+		return encodeStruct, nil
+	}
+
+	set, err := protobuf.LoadDescriptorSet(paths.Resolve(paths.Config, config.DescriptorSet))
+	if err != nil {
+		return nil, err
+	}
+	enc, err := set.NewEncoder(config.MessageType)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(event *beat.Event) ([]byte, error) {
+		// The timestamp is set to the timestamp field of the message, if the
+		// event has no field of this name.
+		fields := event.Fields
+		if _, exists := fields["timestamp"]; !exists {
+			fields = common.MapStr{"timestamp": event.Timestamp}
+			for k, v := range event.Fields {
+				fields[k] = v
+			}
+		}
+		return enc.Encode(fields)
+	}, nil
+}
+
+// encodeStruct encodes the fields of an event and its @timestamp into a
+// google.protobuf.Struct message.
+func encodeStruct(event *beat.Event) ([]byte, error) {
+	fields := common.MapStr{"@timestamp": common.Time(event.Timestamp)}
+	for k, v := range event.Fields {
+		fields[k] = v
+	}
+	if len(event.Meta) > 0 {
+		fields["@metadata"] = event.Meta
+	}
+	return protobuf.EncodeStruct(fields)
+}
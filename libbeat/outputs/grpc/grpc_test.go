@@ -0,0 +1,343 @@
+package grpc
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/protobuf"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/outest"
+)
+
+const testMethod = "/collector.Collector/Stream"
+
+// fakeCollector is an in-process gRPC server recording the messages of the
+// client-streaming RPCs.
+type fakeCollector struct {
+	listener net.Listener
+
+	mutex    sync.Mutex
+	status   int
+	release  chan struct{}
+	conns    []net.Conn
+	requests []*http.Request
+	calls    [][][]byte
+}
+
+func newFakeCollector(t *testing.T) *fakeCollector {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	f := &fakeCollector{listener: l}
+	server := &http2.Server{}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			f.mutex.Lock()
+			f.conns = append(f.conns, conn)
+			f.mutex.Unlock()
+
+			go server.ServeConn(conn, &http2.ServeConnOpts{Handler: http.HandlerFunc(f.serve)})
+		}
+	}()
+	return f
+}
+
+func (f *fakeCollector) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	var messages [][]byte
+	for len(body) >= 5 {
+		n := binary.BigEndian.Uint32(body[1:5])
+		messages = append(messages, body[5:5+n])
+		body = body[5+n:]
+	}
+
+	f.mutex.Lock()
+	f.requests = append(f.requests, r)
+	f.calls = append(f.calls, messages)
+	status, release := f.status, f.release
+	f.mutex.Unlock()
+
+	// The response is held back until the test releases it.
+	if release != nil {
+		<-release
+	}
+
+	w.Header().Set("Content-Type", "application/grpc")
+	if status != codeOK {
+		// Failed RPCs are answered by a response without messages, reporting
+		// the status in the headers.
+		w.Header().Set("Grpc-Status", strconv.Itoa(status))
+		w.Header().Set("Grpc-Message", "failed%20here")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set(http2.TrailerPrefix+"Grpc-Status", "0")
+	w.WriteHeader(http.StatusOK)
+	// An empty response message.
+	w.Write(make([]byte, 5))
+}
+
+func (f *fakeCollector) setStatus(status int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.status = status
+}
+
+func (f *fakeCollector) holdResponses() chan struct{} {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.release = make(chan struct{})
+	return f.release
+}
+
+func (f *fakeCollector) getCalls() [][][]byte {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.calls
+}
+
+// dropConnections closes the connections of the server, breaking the RPCs in
+// progress.
+func (f *fakeCollector) dropConnections() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for _, conn := range f.conns {
+		conn.Close()
+	}
+}
+
+func (f *fakeCollector) connections() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return len(f.conns)
+}
+
+func (f *fakeCollector) Close() {
+	f.listener.Close()
+	f.dropConnections()
+}
+
+func newTestClient(t *testing.T, f *fakeCollector, settings map[string]interface{}) outputs.NetworkClient {
+	config := map[string]interface{}{
+		"hosts":         []string{f.listener.Addr().String()},
+		"method":        testMethod,
+		"timeout":       "5s",
+		"backoff.init":  "10ms",
+		"backoff.max":   "10ms",
+		"headers.token": "secret",
+	}
+	for k, v := range settings {
+		config[k] = v
+	}
+	cfg, err := common.NewConfigFrom(config)
+	require.NoError(t, err)
+
+	group, err := makeGRPC(beat.Info{Beat: "filebeat"}, nil, cfg)
+	require.NoError(t, err)
+	require.Len(t, group.Clients, 1)
+
+	client := group.Clients[0].(outputs.NetworkClient)
+	require.NoError(t, client.Connect())
+	return client
+}
+
+var testTimestamp = time.Date(2017, 10, 14, 7, 30, 0, 123456789, time.UTC)
+
+func testEvents() []beat.Event {
+	return []beat.Event{
+		{Timestamp: testTimestamp, Fields: common.MapStr{"message": "first", "count": 1}},
+		{Timestamp: testTimestamp, Fields: common.MapStr{"message": "second", "count": 2}},
+	}
+}
+
+func TestPublishStruct(t *testing.T) {
+	f := newFakeCollector(t)
+	defer f.Close()
+
+	client := newTestClient(t, f, nil)
+	defer client.Close()
+
+	batch := outest.NewBatch(testEvents()...)
+	require.NoError(t, client.Publish(batch))
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchACK, batch.Signals[0].Tag)
+
+	// All events of the batch are sent with a single RPC.
+	calls := f.getCalls()
+	require.Len(t, calls, 1)
+	require.Len(t, calls[0], 2)
+
+	expected, err := protobuf.EncodeStruct(common.MapStr{
+		"@timestamp": common.Time(testTimestamp),
+		"message":    "first",
+		"count":      1,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, expected, calls[0][0])
+
+	r := f.requests[0]
+	assert.Equal(t, testMethod, r.URL.Path)
+	assert.Equal(t, "application/grpc+proto", r.Header.Get("Content-Type"))
+	assert.Equal(t, "trailers", r.Header.Get("TE"))
+	assert.Equal(t, "5000m", r.Header.Get("Grpc-Timeout"))
+	assert.Equal(t, "secret", r.Header.Get("Token"))
+}
+
+func TestPublishDescriptor(t *testing.T) {
+	f := newFakeCollector(t)
+	defer f.Close()
+
+	path, err := filepath.Abs("../../common/protobuf/testdata/sample.desc")
+	require.NoError(t, err)
+
+	client := newTestClient(t, f, map[string]interface{}{
+		"message.descriptor_set": path,
+		"message.message_type":   "sample.Event",
+	})
+	defer client.Close()
+
+	batch := outest.NewBatch(testEvents()...)
+	require.NoError(t, client.Publish(batch))
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchACK, batch.Signals[0].Tag)
+
+	set, err := protobuf.LoadDescriptorSet(path)
+	require.NoError(t, err)
+	decoder, err := set.NewDecoder("sample.Event")
+	require.NoError(t, err)
+
+	calls := f.getCalls()
+	require.Len(t, calls, 1)
+	require.Len(t, calls[0], 2)
+	decoded, err := decoder.Decode(calls[0][1])
+	require.NoError(t, err)
+	assert.Equal(t, common.MapStr{"message": "second", "count": int64(2)}, decoded)
+}
+
+func TestPublishAckGated(t *testing.T) {
+	f := newFakeCollector(t)
+	defer f.Close()
+
+	client := newTestClient(t, f, nil)
+	defer client.Close()
+
+	release := f.holdResponses()
+	batch := outest.NewBatch(testEvents()...)
+	done := make(chan error)
+	go func() {
+		done <- client.Publish(batch)
+	}()
+
+	// The events have been received, but the batch is not ACKed before the
+	// server completed the RPC.
+	for len(f.getCalls()) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case <-done:
+		t.Fatal("batch published before the server acknowledged it")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-done)
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchACK, batch.Signals[0].Tag)
+}
+
+func TestPublishReconnect(t *testing.T) {
+	f := newFakeCollector(t)
+	defer f.Close()
+
+	client := newTestClient(t, f, nil)
+	defer client.Close()
+
+	batch := outest.NewBatch(testEvents()...)
+	require.NoError(t, client.Publish(batch))
+
+	// The stream breaks, the batch is retried and the client reconnected by
+	// the pipeline.
+	f.dropConnections()
+	batch = outest.NewBatch(testEvents()...)
+	assert.Error(t, client.Publish(batch))
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchRetry, batch.Signals[0].Tag)
+
+	require.NoError(t, client.Connect())
+	batch = outest.NewBatch(testEvents()...)
+	require.NoError(t, client.Publish(batch))
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchACK, batch.Signals[0].Tag)
+	assert.Equal(t, 2, f.connections())
+	assert.Len(t, f.getCalls(), 2)
+}
+
+func TestPublishStatus(t *testing.T) {
+	f := newFakeCollector(t)
+	defer f.Close()
+
+	client := newTestClient(t, f, nil)
+	defer client.Close()
+
+	// Transient failures are retried.
+	f.setStatus(codeUnavailable)
+	batch := outest.NewBatch(testEvents()...)
+	err := client.Publish(batch)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed here")
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchRetry, batch.Signals[0].Tag)
+
+	// Rejected events are dropped.
+	require.NoError(t, client.Connect())
+	f.setStatus(3) // INVALID_ARGUMENT
+	batch = outest.NewBatch(testEvents()...)
+	require.NoError(t, client.Publish(batch))
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchDrop, batch.Signals[0].Tag)
+}
+
+func TestConfigValidation(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"missing method":    {},
+		"invalid method":    {"method": "collector.Collector/Stream"},
+		"message type only": {"method": testMethod, "message.message_type": "sample.Event"},
+		"reserved header":   {"method": testMethod, "headers.grpc-timeout": "1s"},
+		"content type":      {"method": testMethod, "headers.Content-Type": "application/json"},
+	}
+
+	for name, settings := range tests {
+		cfg, err := common.NewConfigFrom(settings)
+		require.NoError(t, err)
+
+		config := defaultConfig
+		assert.Error(t, cfg.Unpack(&config), name)
+	}
+
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"hosts":  []string{"localhost"},
+		"method": testMethod,
+	})
+	require.NoError(t, err)
+	_, err = makeGRPC(beat.Info{Beat: "filebeat"}, nil, cfg)
+	assert.Error(t, err, "host without port")
+}
@@ -7,29 +7,47 @@ import "github.com/elastic/beats/libbeat/common"
 // host list by the number of `workers`.
 func ReadHostList(cfg *common.Config) ([]string, error) {
 	config := struct {
-		Hosts  []string `config:"hosts"  validate:"required"`
-		Worker int      `config:"worker" validate:"min=1"`
-	}{
-		Worker: 1,
-	}
+		Hosts []string `config:"hosts"  validate:"required"`
+	}{}
 
 	err := cfg.Unpack(&config)
 	if err != nil {
 		return nil, err
 	}
 
+	worker, err := ReadWorkers(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	lst := config.Hosts
-	if len(lst) == 0 || config.Worker <= 1 {
+	if len(lst) == 0 || worker <= 1 {
 		return lst, nil
 	}
 
-	// duplicate entries config.Workers times
-	hosts := make([]string, 0, len(lst)*config.Worker)
+	// duplicate entries worker times
+	hosts := make([]string, 0, len(lst)*worker)
 	for _, entry := range lst {
-		for i := 0; i < config.Worker; i++ {
+		for i := 0; i < worker; i++ {
 			hosts = append(hosts, entry)
 		}
 	}
 
 	return hosts, nil
 }
+
+// ReadWorkers reads the number of workers from the `worker` setting of an
+// output configuration. Outputs start a client per worker, and the pipeline
+// publishes to all clients concurrently. The default is 1.
+func ReadWorkers(cfg *common.Config) (int, error) {
+	config := struct {
+		Worker int `config:"worker" validate:"min=1"`
+	}{
+		Worker: 1,
+	}
+
+	if err := cfg.Unpack(&config); err != nil {
+		return 0, err
+	}
+	return config.Worker, nil
+}
@@ -0,0 +1,43 @@
+// +build !integration
+
+package outputs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func TestReadWorkers(t *testing.T) {
+	cfg, err := common.NewConfigFrom(map[string]interface{}{})
+	require.NoError(t, err)
+	worker, err := ReadWorkers(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, 1, worker)
+
+	cfg, err = common.NewConfigFrom(map[string]interface{}{"worker": 3})
+	require.NoError(t, err)
+	worker, err = ReadWorkers(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, 3, worker)
+
+	cfg, err = common.NewConfigFrom(map[string]interface{}{"worker": 0})
+	require.NoError(t, err)
+	_, err = ReadWorkers(cfg)
+	assert.Error(t, err)
+}
+
+func TestReadHostListWorkers(t *testing.T) {
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"hosts":  []string{"a", "b"},
+		"worker": 2,
+	})
+	require.NoError(t, err)
+
+	hosts, err := ReadHostList(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "a", "b", "b"}, hosts)
+}
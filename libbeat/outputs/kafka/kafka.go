@@ -138,26 +138,31 @@ func makeKafka(
 		return outputs.Fail(err)
 	}
 
-	hosts, err := outputs.ReadHostList(cfg)
+	worker, err := outputs.ReadWorkers(cfg)
 	if err != nil {
 		return outputs.Fail(err)
 	}
 
-	codec, err := codec.CreateEncoder(beat, config.Codec)
-	if err != nil {
-		return outputs.Fail(err)
-	}
-
-	client, err := newKafkaClient(stats, hosts, beat.Beat, config.Key, topic, codec, libCfg)
-	if err != nil {
-		return outputs.Fail(err)
+	// Each worker has its own producer connected to all brokers.
+	clients := make([]outputs.NetworkClient, worker)
+	for i := range clients {
+		codec, err := codec.CreateEncoder(beat, config.Codec)
+		if err != nil {
+			return outputs.Fail(err)
+		}
+
+		client, err := newKafkaClient(stats, config.Hosts, beat.Beat, config.Key, topic, codec, libCfg)
+		if err != nil {
+			return outputs.Fail(err)
+		}
+		clients[i] = client
 	}
 
 	retry := 0
 	if config.MaxRetries < 0 {
 		retry = -1
 	}
-	return outputs.Success(config.BulkMaxSize, retry, client)
+	return outputs.Success(config.BulkMaxSize, retry, outputs.NetworkClients(clients)...)
 }
 
 func newKafkaConfig(config *kafkaConfig) (*sarama.Config, error) {
@@ -0,0 +1,204 @@
+package loki
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/codec"
+	"github.com/elastic/beats/libbeat/outputs/transport"
+	"github.com/elastic/beats/libbeat/publisher"
+)
+
+type client struct {
+	url      string
+	username string
+	password string
+	headers  map[string]string
+	encoding string
+
+	http    *http.Client
+	index   string
+	codec   codec.Codec
+	labeler *labeler
+	stats   *outputs.Stats
+}
+
+type clientSettings struct {
+	URL                string
+	Username, Password string
+	Headers            map[string]string
+	Encoding           string
+	TLS                *transport.TLSConfig
+	Timeout            time.Duration
+	Index              string
+	Codec              codec.Codec
+	Labeler            *labeler
+	Stats              *outputs.Stats
+}
+
+func newClient(s clientSettings) (*client, error) {
+	dialer := transport.NetDialer(s.Timeout)
+	tlsDialer, err := transport.TLSDialer(dialer, s.TLS, s.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if st := s.Stats; st != nil {
+		dialer = transport.StatsDialer(dialer, st)
+		tlsDialer = transport.StatsDialer(tlsDialer, st)
+	}
+
+	logp.Info("Loki url: %s", s.URL)
+
+	return &client{
+		url:      s.URL,
+		username: s.Username,
+		password: s.Password,
+		headers:  s.Headers,
+		encoding: s.Encoding,
+		http: &http.Client{
+			Transport: &http.Transport{
+				Dial:    dialer.Dial,
+				DialTLS: tlsDialer.Dial,
+				Proxy:   http.ProxyFromEnvironment,
+			},
+			Timeout: s.Timeout,
+		},
+		index:   s.Index,
+		codec:   s.Codec,
+		labeler: s.Labeler,
+		stats:   s.Stats,
+	}, nil
+}
+
+// Connect is a no-op, as the push API does not require a handshake. Connection
+// errors are reported when publishing.
+func (c *client) Connect() error {
+	return nil
+}
+
+func (c *client) Close() error {
+	if t, ok := c.http.Transport.(*http.Transport); ok {
+		t.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (c *client) Publish(batch publisher.Batch) error {
+	events := batch.Events()
+	st := c.stats
+	st.NewBatch(len(events))
+
+	builder := newStreamBuilder()
+	dropped := 0
+	for i := range events {
+		event := &events[i]
+
+		line, err := c.codec.Encode(c.index, &event.Content)
+		if err != nil {
+			logp.Err("Failed to encode event for Loki: %v", err)
+			event.Fail()
+			dropped++
+			continue
+		}
+
+		labels := c.labeler.labels(event.Content.Fields)
+		builder.add(labels, event.Content.Timestamp, string(line))
+	}
+
+	st.Dropped(dropped)
+	count := len(events) - dropped
+	if count == 0 {
+		batch.ACK()
+		return nil
+	}
+
+	status, err := c.push(builder.build())
+	if err != nil {
+		logp.Err("Failed to push events to Loki: %v", err)
+		st.Failed(count)
+		batch.Retry()
+		return err
+	}
+
+	switch {
+	case status < 300:
+		st.Acked(count)
+		batch.ACK()
+		return nil
+
+	case status == http.StatusTooManyRequests || status >= 500:
+		st.Failed(count)
+		batch.Retry()
+		return fmt.Errorf("loki push failed with status %v", status)
+
+	default:
+		// The request has been rejected by Loki, so retrying it is pointless.
+		logp.Err("Loki rejected %v events with status %v", count, status)
+		for i := range events {
+			events[i].Fail()
+		}
+		st.Failed(count)
+		batch.Drop()
+		return nil
+	}
+}
+
+func (c *client) push(streams []*stream) (int, error) {
+	var (
+		body        []byte
+		contentType string
+		err         error
+	)
+	if c.encoding == encodingJSON {
+		body, err = encodeJSON(streams)
+		contentType = "application/json"
+	} else {
+		body, err = encodeProtobuf(streams)
+		contentType = "application/x-protobuf"
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest("POST", c.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for name, value := range c.headers {
+		req.Header.Add(name, value)
+	}
+	if c.username != "" || c.password != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer closing(resp.Body)
+
+	// Drain the body so connections can be reused.
+	msg, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		debugf("Loki push response (status=%v): %s", resp.StatusCode, msg)
+	}
+	return resp.StatusCode, nil
+}
+
+func (c *client) String() string {
+	return "loki(" + c.url + ")"
+}
+
+func closing(c io.Closer) {
+	if err := c.Close(); err != nil {
+		logp.Warn("Close failed with: %v", err)
+	}
+}
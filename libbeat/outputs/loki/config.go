@@ -0,0 +1,111 @@
+package loki
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/codec"
+)
+
+type lokiConfig struct {
+	Protocol       string             `config:"protocol"`
+	Path           string             `config:"path"`
+	Headers        map[string]string  `config:"headers"`
+	Username       string             `config:"username"`
+	Password       string             `config:"password"`
+	LoadBalance    bool               `config:"loadbalance"`
+	TLS            *outputs.TLSConfig `config:"ssl"`
+	BulkMaxSize    int                `config:"bulk_max_size"`
+	MaxRetries     int                `config:"max_retries"`
+	Timeout        time.Duration      `config:"timeout"`
+	Backoff        backoff            `config:"backoff"`
+	Encoding       string             `config:"encoding"`
+	Labels         map[string]string  `config:"labels"`
+	StaticLabels   map[string]string  `config:"static_labels"`
+	MaxLabelValues int                `config:"max_label_values" validate:"min=1"`
+	Codec          codec.Config       `config:"codec"`
+}
+
+type backoff struct {
+	Init time.Duration
+	Max  time.Duration
+}
+
+const (
+	defaultBulkSize = 100
+	defaultPort     = 3100
+
+	encodingProtobuf = "protobuf"
+	encodingJSON     = "json"
+)
+
+var (
+	defaultConfig = lokiConfig{
+		Path:           "/loki/api/v1/push",
+		Timeout:        90 * time.Second,
+		MaxRetries:     3,
+		LoadBalance:    true,
+		Encoding:       encodingProtobuf,
+		MaxLabelValues: 100,
+		Backoff: backoff{
+			Init: 1 * time.Second,
+			Max:  60 * time.Second,
+		},
+	}
+
+	labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+	// highCardinalityFields are fields which have a different value for
+	// almost every event and must not be used as labels.
+	highCardinalityFields = map[string]bool{
+		"@timestamp": true,
+		"message":    true,
+		"offset":     true,
+	}
+)
+
+func (c *lokiConfig) Validate() error {
+	switch c.Encoding {
+	case encodingProtobuf, encodingJSON:
+	default:
+		return fmt.Errorf("unsupported encoding '%v', use protobuf or json", c.Encoding)
+	}
+
+	if len(c.Labels) == 0 && len(c.StaticLabels) == 0 {
+		return fmt.Errorf("at least one label must be configured in labels or static_labels")
+	}
+
+	for name, field := range c.Labels {
+		if err := validateLabelName(name); err != nil {
+			return err
+		}
+		if highCardinalityFields[field] {
+			return fmt.Errorf("field '%v' can not be used for label '%v', because "+
+				"labels must have a low cardinality", field, name)
+		}
+	}
+
+	for name := range c.StaticLabels {
+		if _, exists := c.Labels[name]; exists {
+			return fmt.Errorf("label '%v' is configured in labels and static_labels", name)
+		}
+		if err := validateLabelName(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateLabelName(name string) error {
+	if !labelNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid label name '%v'", name)
+	}
+	if strings.HasPrefix(name, "__") {
+		return fmt.Errorf("label name '%v' is reserved for internal use", name)
+	}
+	return nil
+}
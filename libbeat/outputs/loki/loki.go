@@ -0,0 +1,81 @@
+package loki
+
+import (
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/codec"
+)
+
+func init() {
+	outputs.RegisterType("loki", makeLoki)
+}
+
+var debugf = logp.MakeDebug("loki")
+
+func makeLoki(
+	beat beat.Info,
+	stats *outputs.Stats,
+	cfg *common.Config,
+) (outputs.Group, error) {
+	if !cfg.HasField("bulk_max_size") {
+		cfg.SetInt("bulk_max_size", -1, defaultBulkSize)
+	}
+
+	config := defaultConfig
+	if err := cfg.Unpack(&config); err != nil {
+		return outputs.Fail(err)
+	}
+
+	hosts, err := outputs.ReadHostList(cfg)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	tlsConfig, err := outputs.LoadTLSConfig(config.TLS)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	// The labeler is shared by all clients, such that the label cardinality
+	// is limited for the output as a whole.
+	labeler := newLabeler(config.Labels, config.StaticLabels, config.MaxLabelValues)
+
+	clients := make([]outputs.NetworkClient, len(hosts))
+	for i, host := range hosts {
+		url, err := common.MakeURL(config.Protocol, config.Path, host, defaultPort)
+		if err != nil {
+			logp.Err("Invalid host param set: %s, Error: %v", host, err)
+			return outputs.Fail(err)
+		}
+
+		enc, err := codec.CreateEncoder(beat, config.Codec)
+		if err != nil {
+			return outputs.Fail(err)
+		}
+
+		var client outputs.NetworkClient
+		client, err = newClient(clientSettings{
+			URL:      url,
+			Username: config.Username,
+			Password: config.Password,
+			Headers:  config.Headers,
+			Encoding: config.Encoding,
+			TLS:      tlsConfig,
+			Timeout:  config.Timeout,
+			Index:    beat.Beat,
+			Codec:    enc,
+			Labeler:  labeler,
+			Stats:    stats,
+		})
+		if err != nil {
+			return outputs.Fail(err)
+		}
+
+		client = outputs.WithBackoff(client, config.Backoff.Init, config.Backoff.Max)
+		clients[i] = client
+	}
+
+	return outputs.SuccessNet(config.LoadBalance, config.BulkMaxSize, config.MaxRetries, clients)
+}
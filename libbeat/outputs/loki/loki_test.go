@@ -0,0 +1,319 @@
+package loki
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/outputs"
+	_ "github.com/elastic/beats/libbeat/outputs/codec/format"
+	_ "github.com/elastic/beats/libbeat/outputs/codec/json"
+	"github.com/elastic/beats/libbeat/outputs/outest"
+)
+
+// fakeLoki records the requests sent to the push API.
+type fakeLoki struct {
+	*httptest.Server
+
+	mutex    sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newFakeLoki() *fakeLoki {
+	f := &fakeLoki{status: http.StatusNoContent}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+		f.requests = append(f.requests, r)
+		f.bodies = append(f.bodies, body)
+		w.WriteHeader(f.status)
+	}))
+	return f
+}
+
+func (f *fakeLoki) setStatus(status int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.status = status
+}
+
+func newTestClient(t *testing.T, settings map[string]interface{}) outputs.NetworkClient {
+	cfg, err := common.NewConfigFrom(settings)
+	require.NoError(t, err)
+
+	group, err := makeLoki(beat.Info{Beat: "filebeat"}, nil, cfg)
+	require.NoError(t, err)
+	require.Len(t, group.Clients, 1)
+
+	client := group.Clients[0].(outputs.NetworkClient)
+	require.NoError(t, client.Connect())
+	return client
+}
+
+func testEvents() []beat.Event {
+	ts := time.Date(2017, 10, 14, 7, 30, 0, 123456789, time.UTC)
+	return []beat.Event{
+		{
+			Timestamp: ts.Add(time.Second),
+			Fields:    common.MapStr{"message": "second", "source": "/var/log/a.log"},
+		},
+		{
+			Timestamp: ts,
+			Fields:    common.MapStr{"message": "first", "source": "/var/log/a.log"},
+		},
+		{
+			Timestamp: ts,
+			Fields:    common.MapStr{"message": "other", "source": "/var/log/b.log"},
+		},
+	}
+}
+
+func TestPublishJSON(t *testing.T) {
+	loki := newFakeLoki()
+	defer loki.Close()
+
+	client := newTestClient(t, map[string]interface{}{
+		"hosts":                 loki.URL,
+		"encoding":              "json",
+		"labels.filename":       "source",
+		"static_labels.job":     "filebeat",
+		"codec.format.string":   "%{[message]}",
+		"backoff.init":          "1ms",
+		"backoff.max":           "1ms",
+		"max_label_values":      10,
+		"headers.X-Scope-OrgID": "tenant",
+	})
+	defer client.Close()
+
+	batch := outest.NewBatch(testEvents()...)
+	require.NoError(t, client.Publish(batch))
+	assert.Equal(t, outest.BatchACK, batch.Signals[0].Tag)
+
+	require.Len(t, loki.requests, 1)
+	assert.Equal(t, "/loki/api/v1/push", loki.requests[0].URL.Path)
+	assert.Equal(t, "application/json", loki.requests[0].Header.Get("Content-Type"))
+	assert.Equal(t, "tenant", loki.requests[0].Header.Get("X-Scope-OrgID"))
+
+	var req struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	require.NoError(t, json.Unmarshal(loki.bodies[0], &req))
+	require.Len(t, req.Streams, 2)
+
+	assert.Equal(t, map[string]string{"job": "filebeat", "filename": "/var/log/a.log"}, req.Streams[0].Stream)
+	assert.Equal(t, [][2]string{
+		{"1507966200123456789", "first"},
+		{"1507966201123456789", "second"},
+	}, req.Streams[0].Values)
+
+	assert.Equal(t, map[string]string{"job": "filebeat", "filename": "/var/log/b.log"}, req.Streams[1].Stream)
+	assert.Equal(t, [][2]string{
+		{"1507966200123456789", "other"},
+	}, req.Streams[1].Values)
+}
+
+type protoEntry struct {
+	secs, nanos uint64
+	line        string
+}
+
+type protoStream struct {
+	labels  string
+	entries []protoEntry
+}
+
+// decodeFields decodes a protobuf message into its length delimited and varint
+// fields, calling fn for every field.
+func decodeFields(t *testing.T, msg []byte, fn func(field int, raw []byte, v uint64)) {
+	for len(msg) > 0 {
+		tag, n := proto.DecodeVarint(msg)
+		require.NotZero(t, n)
+		msg = msg[n:]
+
+		field, wire := int(tag>>3), int(tag&7)
+		v, n := proto.DecodeVarint(msg)
+		require.NotZero(t, n)
+		msg = msg[n:]
+
+		switch wire {
+		case wireVarint:
+			fn(field, nil, v)
+		case wireBytes:
+			require.True(t, uint64(len(msg)) >= v)
+			fn(field, msg[:v], 0)
+			msg = msg[v:]
+		default:
+			t.Fatalf("unexpected wire type %v", wire)
+		}
+	}
+}
+
+func decodePushRequest(t *testing.T, body []byte) []protoStream {
+	raw, err := snappy.Decode(nil, body)
+	require.NoError(t, err)
+
+	var streams []protoStream
+	decodeFields(t, raw, func(field int, msg []byte, _ uint64) {
+		require.Equal(t, fieldPushStreams, field)
+
+		var s protoStream
+		decodeFields(t, msg, func(field int, msg []byte, _ uint64) {
+			switch field {
+			case fieldStreamLabels:
+				s.labels = string(msg)
+			case fieldStreamEntries:
+				var e protoEntry
+				decodeFields(t, msg, func(field int, msg []byte, _ uint64) {
+					switch field {
+					case fieldEntryTimestamp:
+						decodeFields(t, msg, func(field int, _ []byte, v uint64) {
+							if field == fieldTimestampSecs {
+								e.secs = v
+							} else {
+								e.nanos = v
+							}
+						})
+					case fieldEntryLine:
+						e.line = string(msg)
+					}
+				})
+				s.entries = append(s.entries, e)
+			}
+		})
+		streams = append(streams, s)
+	})
+	return streams
+}
+
+func TestPublishProtobuf(t *testing.T) {
+	loki := newFakeLoki()
+	defer loki.Close()
+
+	client := newTestClient(t, map[string]interface{}{
+		"hosts":               loki.URL,
+		"labels.filename":     "source",
+		"static_labels.job":   "filebeat",
+		"codec.format.string": "%{[message]}",
+	})
+	defer client.Close()
+
+	batch := outest.NewBatch(testEvents()...)
+	require.NoError(t, client.Publish(batch))
+	assert.Equal(t, outest.BatchACK, batch.Signals[0].Tag)
+
+	require.Len(t, loki.requests, 1)
+	assert.Equal(t, "application/x-protobuf", loki.requests[0].Header.Get("Content-Type"))
+
+	streams := decodePushRequest(t, loki.bodies[0])
+	assert.Equal(t, []protoStream{
+		{
+			labels: `{filename="/var/log/a.log", job="filebeat"}`,
+			entries: []protoEntry{
+				{secs: 1507966200, nanos: 123456789, line: "first"},
+				{secs: 1507966201, nanos: 123456789, line: "second"},
+			},
+		},
+		{
+			labels: `{filename="/var/log/b.log", job="filebeat"}`,
+			entries: []protoEntry{
+				{secs: 1507966200, nanos: 123456789, line: "other"},
+			},
+		},
+	}, streams)
+}
+
+func TestPublishFailures(t *testing.T) {
+	loki := newFakeLoki()
+	defer loki.Close()
+
+	client := newTestClient(t, map[string]interface{}{
+		"hosts":             loki.URL,
+		"static_labels.job": "filebeat",
+		"backoff.init":      "1ms",
+		"backoff.max":       "1ms",
+	})
+	defer client.Close()
+
+	tests := []struct {
+		status int
+		signal outest.BatchSignalTag
+		err    bool
+	}{
+		{http.StatusInternalServerError, outest.BatchRetry, true},
+		{http.StatusTooManyRequests, outest.BatchRetry, true},
+		{http.StatusBadRequest, outest.BatchDrop, false},
+	}
+
+	for _, test := range tests {
+		loki.setStatus(test.status)
+
+		batch := outest.NewBatch(testEvents()...)
+		err := client.Publish(batch)
+		assert.Equal(t, test.err, err != nil, "status %v", test.status)
+		if assert.Len(t, batch.Signals, 1) {
+			assert.Equal(t, test.signal, batch.Signals[0].Tag, "status %v", test.status)
+		}
+	}
+}
+
+func TestLabelCardinalityLimit(t *testing.T) {
+	l := newLabeler(map[string]string{"user": "user.name"}, nil, 2)
+
+	labels := func(name string) map[string]string {
+		return l.labels(common.MapStr{"user": common.MapStr{"name": name}})
+	}
+
+	assert.Equal(t, map[string]string{"user": "a"}, labels("a"))
+	assert.Equal(t, map[string]string{"user": "b"}, labels("b"))
+	assert.Equal(t, map[string]string{"user": overflowLabelValue}, labels("c"))
+	assert.Equal(t, map[string]string{"user": "a"}, labels("a"))
+
+	// Missing fields are omitted.
+	assert.Equal(t, map[string]string{}, l.labels(common.MapStr{}))
+}
+
+func TestConfigValidation(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"no labels": {},
+		"high cardinality field": {
+			"labels.msg": "message",
+		},
+		"invalid label name": {
+			"labels.file-name": "source",
+		},
+		"reserved label name": {
+			"static_labels.__name__": "logs",
+		},
+		"invalid encoding": {
+			"static_labels.job": "filebeat",
+			"encoding":          "xml",
+		},
+	}
+
+	for name, settings := range tests {
+		settings["hosts"] = "localhost"
+		cfg, err := common.NewConfigFrom(settings)
+		require.NoError(t, err)
+
+		_, err = makeLoki(beat.Info{Beat: "filebeat"}, nil, cfg)
+		assert.Error(t, err, name)
+	}
+}
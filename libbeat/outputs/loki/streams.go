@@ -0,0 +1,258 @@
+package loki
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// overflowLabelValue replaces label values once a label has reached the
+// configured number of distinct values.
+const overflowLabelValue = "_other"
+
+// stream is a set of log lines sharing the same labels.
+type stream struct {
+	key     string // labels in Prometheus text format, e.g. {job="filebeat"}
+	labels  map[string]string
+	entries []entry
+}
+
+type entry struct {
+	ts   time.Time
+	line string
+}
+
+// labeler computes the label sets of events. It limits the number of
+// distinct values per label, such that a high cardinality field can not
+// create an unbounded number of streams in Loki.
+type labeler struct {
+	fields    map[string]string // label name -> event field
+	static    map[string]string // label name -> constant value
+	maxValues int
+
+	mutex    sync.Mutex
+	seen     map[string]map[string]struct{}
+	overflow map[string]bool
+}
+
+func newLabeler(fields, static map[string]string, maxValues int) *labeler {
+	return &labeler{
+		fields:    fields,
+		static:    static,
+		maxValues: maxValues,
+		seen:      map[string]map[string]struct{}{},
+		overflow:  map[string]bool{},
+	}
+}
+
+// labels returns the label set of an event. Labels whose field is missing or
+// not a primitive value are omitted.
+func (l *labeler) labels(fields common.MapStr) map[string]string {
+	labels := make(map[string]string, len(l.fields)+len(l.static))
+	for name, value := range l.static {
+		labels[name] = value
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for name, field := range l.fields {
+		v, err := fields.GetValue(field)
+		if err != nil {
+			continue
+		}
+
+		var value string
+		switch v := v.(type) {
+		case string:
+			value = v
+		case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64,
+			float32, float64, common.Float:
+			value = fmt.Sprint(v)
+		default:
+			continue
+		}
+
+		labels[name] = l.limit(name, value)
+	}
+	return labels
+}
+
+func (l *labeler) limit(name, value string) string {
+	values := l.seen[name]
+	if values == nil {
+		values = map[string]struct{}{}
+		l.seen[name] = values
+	}
+
+	if _, exists := values[value]; exists {
+		return value
+	}
+	if len(values) >= l.maxValues {
+		if !l.overflow[name] {
+			logp.Warn("Loki label '%v' exceeds %v distinct values, using '%v' for new values",
+				name, l.maxValues, overflowLabelValue)
+			l.overflow[name] = true
+		}
+		return overflowLabelValue
+	}
+
+	values[value] = struct{}{}
+	return value
+}
+
+// streamBuilder groups log lines into streams by their label sets.
+type streamBuilder struct {
+	streams []*stream
+	index   map[string]*stream
+}
+
+func newStreamBuilder() *streamBuilder {
+	return &streamBuilder{index: map[string]*stream{}}
+}
+
+func (b *streamBuilder) add(labels map[string]string, ts time.Time, line string) {
+	key := formatLabels(labels)
+	s := b.index[key]
+	if s == nil {
+		s = &stream{key: key, labels: labels}
+		b.index[key] = s
+		b.streams = append(b.streams, s)
+	}
+	s.entries = append(s.entries, entry{ts: ts, line: line})
+}
+
+// build returns all streams, with the entries of each stream sorted by
+// timestamp as required by Loki.
+func (b *streamBuilder) build() []*stream {
+	for _, s := range b.streams {
+		entries := s.entries
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].ts.Before(entries[j].ts)
+		})
+	}
+	return b.streams
+}
+
+// formatLabels formats a label set in the Prometheus text format used by the
+// Loki push API, with labels sorted by name.
+func formatLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(name)
+		buf.WriteByte('=')
+		buf.WriteString(strconv.Quote(labels[name]))
+	}
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// encodeJSON encodes streams into the JSON body of the Loki push API. The
+// timestamps are encoded as strings of nanoseconds since the Unix epoch.
+func encodeJSON(streams []*stream) ([]byte, error) {
+	type jsonStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+
+	req := struct {
+		Streams []jsonStream `json:"streams"`
+	}{
+		Streams: make([]jsonStream, len(streams)),
+	}
+	for i, s := range streams {
+		values := make([][2]string, len(s.entries))
+		for j, e := range s.entries {
+			values[j] = [2]string{strconv.FormatInt(e.ts.UnixNano(), 10), e.line}
+		}
+		req.Streams[i] = jsonStream{Stream: s.labels, Values: values}
+	}
+	return json.Marshal(req)
+}
+
+// Field numbers of the Loki push API protobuf messages:
+//
+//	message PushRequest { repeated Stream streams = 1; }
+//	message Stream { string labels = 1; repeated Entry entries = 2; }
+//	message Entry { google.protobuf.Timestamp timestamp = 1; string line = 2; }
+//	message Timestamp { int64 seconds = 1; int32 nanos = 2; }
+const (
+	fieldPushStreams    = 1
+	fieldStreamLabels   = 1
+	fieldStreamEntries  = 2
+	fieldEntryTimestamp = 1
+	fieldEntryLine      = 2
+	fieldTimestampSecs  = 1
+	fieldTimestampNanos = 2
+
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+// encodeProtobuf encodes streams into the snappy compressed protobuf body of
+// the Loki push API.
+func encodeProtobuf(streams []*stream) ([]byte, error) {
+	req := proto.NewBuffer(nil)
+	msg := proto.NewBuffer(nil)
+	sub := proto.NewBuffer(nil)
+	ts := proto.NewBuffer(nil)
+
+	for _, s := range streams {
+		msg.Reset()
+		encodeString(msg, fieldStreamLabels, s.key)
+
+		for _, e := range s.entries {
+			ts.Reset()
+			encodeVarint(ts, fieldTimestampSecs, uint64(e.ts.Unix()))
+			encodeVarint(ts, fieldTimestampNanos, uint64(e.ts.Nanosecond()))
+
+			sub.Reset()
+			encodeBytes(sub, fieldEntryTimestamp, ts.Bytes())
+			encodeString(sub, fieldEntryLine, e.line)
+
+			encodeBytes(msg, fieldStreamEntries, sub.Bytes())
+		}
+
+		encodeBytes(req, fieldPushStreams, msg.Bytes())
+	}
+
+	return snappy.Encode(nil, req.Bytes()), nil
+}
+
+func encodeVarint(b *proto.Buffer, field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	b.EncodeVarint(uint64(field<<3 | wireVarint))
+	b.EncodeVarint(v)
+}
+
+func encodeBytes(b *proto.Buffer, field int, v []byte) {
+	b.EncodeVarint(uint64(field<<3 | wireBytes))
+	b.EncodeRawBytes(v)
+}
+
+func encodeString(b *proto.Buffer, field int, v string) {
+	b.EncodeVarint(uint64(field<<3 | wireBytes))
+	b.EncodeStringBytes(v)
+}
@@ -0,0 +1,202 @@
+package outest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/publisher"
+)
+
+// SequenceField is the field a Sequencer tags events with. It is a regular
+// field, so it is published by all outputs and can be verified on the events
+// received by their servers.
+const SequenceField = "ingest_sequence"
+
+// maxViolations limits the number of order violations reported by a verifier.
+const maxViolations = 10
+
+// Sequencer tags events with an ingest sequence, increasing with each event.
+// It is a processor, so it can be added to the pipeline client or to the
+// processors of an output, to tag the events in the order they enter.
+type Sequencer struct {
+	mutex sync.Mutex
+	next  uint64
+}
+
+// NewSequencer creates a Sequencer, tagging the first event with 1.
+func NewSequencer() *Sequencer {
+	return &Sequencer{next: 1}
+}
+
+// Run tags the event with the next sequence.
+func (s *Sequencer) Run(event *beat.Event) (*beat.Event, error) {
+	s.mutex.Lock()
+	seq := s.next
+	s.next++
+	s.mutex.Unlock()
+
+	if event.Fields == nil {
+		event.Fields = common.MapStr{}
+	}
+	event.Fields[SequenceField] = seq
+	return event, nil
+}
+
+// Tag tags the events in their order and returns them.
+func (s *Sequencer) Tag(events ...beat.Event) []beat.Event {
+	for i := range events {
+		s.Run(&events[i])
+	}
+	return events
+}
+
+func (s *Sequencer) String() string {
+	return "sequencer"
+}
+
+// KeyFunc returns the key of an event for an OrderVerifier, the order of
+// events is verified separately for each key.
+type KeyFunc func(fields common.MapStr) string
+
+// FieldKey returns a KeyFunc using the value of a field as key. Events
+// without the field share the empty key.
+func FieldKey(name string) KeyFunc {
+	return func(fields common.MapStr) string {
+		v, err := fields.GetValue(name)
+		if err != nil {
+			return ""
+		}
+		return fmt.Sprint(v)
+	}
+}
+
+// OrderVerifier verifies that events are delivered in the order of the
+// sequence they were tagged with by a Sequencer, for all events or per key.
+// It is a test sink, it implements outputs.Client to be used as an output,
+// or observes the events received by the server of an output.
+type OrderVerifier struct {
+	key KeyFunc
+
+	mutex      sync.Mutex
+	last       map[string]uint64
+	events     int
+	violations []string
+	dropped    int
+}
+
+// NewOrderVerifier creates a verifier checking the order of the events per
+// key. If key is nil, the order of all events is verified.
+func NewOrderVerifier(key KeyFunc) *OrderVerifier {
+	return &OrderVerifier{key: key, last: map[string]uint64{}}
+}
+
+// Observe records the delivery of an event. Events delivered again, like
+// after a retry, are reported as reordered, as they follow events with a
+// higher sequence.
+func (v *OrderVerifier) Observe(fields common.MapStr) {
+	key := ""
+	if v.key != nil {
+		key = v.key(fields)
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	v.events++
+	seq, err := sequence(fields)
+	if err != nil {
+		v.violate("event %d: %v", v.events, err)
+		return
+	}
+
+	if last, ok := v.last[key]; ok && seq <= last {
+		v.violate("event %d: sequence %d delivered after %d%s", v.events, seq, last, keyInfo(key, v.key))
+		return
+	}
+	v.last[key] = seq
+}
+
+// Publish observes the events of the batch in their order and ACKs it.
+func (v *OrderVerifier) Publish(batch publisher.Batch) error {
+	for _, event := range batch.Events() {
+		v.Observe(event.Content.Fields)
+	}
+	batch.ACK()
+	return nil
+}
+
+// Close does nothing, the verifier keeps its results once closed.
+func (v *OrderVerifier) Close() error {
+	return nil
+}
+
+func (v *OrderVerifier) String() string {
+	return "order-verifier"
+}
+
+// Events returns the number of events observed.
+func (v *OrderVerifier) Events() int {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	return v.events
+}
+
+// Err returns an error listing the order violations observed, or nil if all
+// events have been delivered in order.
+func (v *OrderVerifier) Err() error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if len(v.violations) == 0 {
+		return nil
+	}
+	msg := strings.Join(v.violations, "; ")
+	if v.dropped > 0 {
+		msg += fmt.Sprintf("; and %d more", v.dropped)
+	}
+	return fmt.Errorf("%d events out of order: %s", len(v.violations)+v.dropped, msg)
+}
+
+func (v *OrderVerifier) violate(format string, args ...interface{}) {
+	if len(v.violations) == maxViolations {
+		v.dropped++
+		return
+	}
+	v.violations = append(v.violations, fmt.Sprintf(format, args...))
+}
+
+func keyInfo(key string, f KeyFunc) string {
+	if f == nil {
+		return ""
+	}
+	return fmt.Sprintf(" for key '%s'", key)
+}
+
+// sequence returns the sequence of an event, as set by a Sequencer or
+// decoded from JSON.
+func sequence(fields common.MapStr) (uint64, error) {
+	v, ok := fields[SequenceField]
+	if !ok {
+		return 0, fmt.Errorf("missing %v", SequenceField)
+	}
+
+	switch n := v.(type) {
+	case uint64:
+		return n, nil
+	case int:
+		return uint64(n), nil
+	case int64:
+		return uint64(n), nil
+	case float64:
+		return uint64(n), nil
+	case json.Number:
+		i, err := n.Int64()
+		return uint64(i), err
+	default:
+		return 0, fmt.Errorf("invalid %v of type %T", SequenceField, v)
+	}
+}
@@ -0,0 +1,138 @@
+package outest
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/publisher"
+)
+
+func orderEvents(keys ...string) []beat.Event {
+	events := make([]beat.Event, len(keys))
+	for i, key := range keys {
+		events[i] = beat.Event{Fields: common.MapStr{"host": common.MapStr{"name": key}}}
+	}
+	return NewSequencer().Tag(events...)
+}
+
+// reorderingClient induces a reorder, swapping the first two events of each
+// batch before passing it to the next client.
+type reorderingClient struct {
+	next *OrderVerifier
+}
+
+func (c *reorderingClient) Publish(batch publisher.Batch) error {
+	events := batch.Events()
+	if len(events) > 1 {
+		events[0], events[1] = events[1], events[0]
+	}
+	return c.next.Publish(batch)
+}
+
+func TestSequencer(t *testing.T) {
+	s := NewSequencer()
+	events := s.Tag(beat.Event{}, beat.Event{Fields: common.MapStr{"message": "x"}})
+	assert.Equal(t, uint64(1), events[0].Fields[SequenceField])
+	assert.Equal(t, uint64(2), events[1].Fields[SequenceField])
+
+	event, err := s.Run(&beat.Event{Fields: common.MapStr{}})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), event.Fields[SequenceField])
+}
+
+func TestOrderVerifierInOrder(t *testing.T) {
+	v := NewOrderVerifier(nil)
+	batch := NewBatch(orderEvents("a", "b", "a", "c")...)
+	require.NoError(t, v.Publish(batch))
+
+	assert.NoError(t, v.Err())
+	assert.Equal(t, 4, v.Events())
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, BatchACK, batch.Signals[0].Tag)
+}
+
+func TestOrderVerifierDetectsReorder(t *testing.T) {
+	v := NewOrderVerifier(nil)
+	client := &reorderingClient{next: v}
+	require.NoError(t, client.Publish(NewBatch(orderEvents("a", "a", "b")...)))
+
+	err := v.Err()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "sequence 1 delivered after 2")
+	}
+}
+
+func TestOrderVerifierPerKey(t *testing.T) {
+	events := orderEvents("a", "b", "a", "b")
+
+	// Events of different keys may be reordered.
+	v := NewOrderVerifier(FieldKey("host.name"))
+	events[0], events[1] = events[1], events[0]
+	require.NoError(t, v.Publish(NewBatch(events...)))
+	assert.NoError(t, v.Err())
+
+	// Events of the same key may not.
+	v = NewOrderVerifier(FieldKey("host.name"))
+	events[0], events[2] = events[2], events[0]
+	require.NoError(t, v.Publish(NewBatch(events...)))
+	err := v.Err()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "sequence 1 delivered after 3 for key 'a'")
+	}
+}
+
+func TestOrderVerifierDecodedEvents(t *testing.T) {
+	v := NewOrderVerifier(nil)
+	for _, event := range orderEvents("a", "b") {
+		data, err := json.Marshal(event.Fields)
+		require.NoError(t, err)
+
+		var decoded common.MapStr
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		v.Observe(decoded)
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		decoded = nil
+		require.NoError(t, decoder.Decode(&decoded))
+		v.Observe(decoded)
+	}
+
+	// Each event is delivered twice.
+	err := v.Err()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "2 events out of order")
+		assert.Contains(t, err.Error(), "event 2: sequence 1 delivered after 1")
+	}
+}
+
+func TestOrderVerifierInvalidSequence(t *testing.T) {
+	v := NewOrderVerifier(nil)
+	v.Observe(common.MapStr{"message": "untagged"})
+	v.Observe(common.MapStr{SequenceField: "1"})
+
+	err := v.Err()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "event 1: missing "+SequenceField)
+		assert.Contains(t, err.Error(), "event 2: invalid "+SequenceField+" of type string")
+	}
+}
+
+func TestOrderVerifierLimitsViolations(t *testing.T) {
+	v := NewOrderVerifier(nil)
+	for i := 0; i < 15; i++ {
+		v.Observe(common.MapStr{SequenceField: uint64(1)})
+	}
+
+	err := v.Err()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "14 events out of order")
+		assert.Contains(t, err.Error(), "; and 4 more")
+	}
+}
@@ -48,5 +48,9 @@ func Load(info beat.Info, stats *Stats, name string, config *common.Config) (Gro
 		return Fail(err)
 	}
 
-	return factory(info, stats, config)
+	group, err := factory(info, stats, config)
+	if err != nil {
+		return Fail(err)
+	}
+	return withProcessors(group, config)
 }
@@ -0,0 +1,154 @@
+package outputs
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/processors"
+	"github.com/elastic/beats/libbeat/publisher"
+)
+
+// processingClient applies output local processors to a copy of each event,
+// right before the events are passed to the actual output client. Events
+// shared with other outputs are never modified.
+type processingClient struct {
+	client     Client
+	processors *processors.Processors
+}
+
+// processingNetClient is a processingClient for reconnectable network clients.
+type processingNetClient struct {
+	*processingClient
+	conn Connectable
+}
+
+// processingBatch holds the processed copies of the events in the original
+// batch. Events returned to the pipeline for retry are mapped back to their
+// original, unprocessed, events, so processors are not applied twice.
+type processingBatch struct {
+	publisher.Batch
+	events    []publisher.Event
+	originals []publisher.Event
+}
+
+// originalIndex is stored as the private data of the processed events, to
+// find the original of processed events passed back by the output. Copies of
+// the processed events made by the output keep it.
+type originalIndex int
+
+type outputProcessorsConfig struct {
+	Processors processors.PluginConfig `config:"processors"`
+}
+
+// withProcessors wraps all clients in the group with the output local
+// processors configured in cfg. The group is returned unchanged if no
+// processors are configured.
+func withProcessors(group Group, cfg *common.Config) (Group, error) {
+	config := outputProcessorsConfig{}
+	if err := cfg.Unpack(&config); err != nil {
+		return Fail(err)
+	}
+	if len(config.Processors) == 0 {
+		return group, nil
+	}
+
+	// Each client owns its processors, as the processors are closed with the
+	// client.
+	clients := make([]Client, len(group.Clients))
+	created := make([]*processors.Processors, 0, len(group.Clients))
+	for i, client := range group.Clients {
+		procs, err := processors.New(config.Processors)
+		if err != nil {
+			for _, procs := range created {
+				procs.Close()
+			}
+			return Fail(err)
+		}
+		created = append(created, procs)
+		clients[i] = newProcessingClient(client, procs)
+	}
+	group.Clients = clients
+	return group, nil
+}
+
+func newProcessingClient(client Client, procs *processors.Processors) Client {
+	c := &processingClient{client: client, processors: procs}
+	if nc, ok := client.(NetworkClient); ok {
+		return &processingNetClient{processingClient: c, conn: nc}
+	}
+	return c
+}
+
+func (c *processingClient) Close() error {
+	err := c.client.Close()
+	if perr := c.processors.Close(); perr != nil {
+		logp.Err("Failed to close output processors: %v", perr)
+	}
+	return err
+}
+
+func (c *processingClient) Publish(batch publisher.Batch) error {
+	events := batch.Events()
+	processed := &processingBatch{
+		Batch:     batch,
+		events:    make([]publisher.Event, 0, len(events)),
+		originals: make([]publisher.Event, 0, len(events)),
+	}
+
+	for _, event := range events {
+		content := event.Content
+		content.Fields = event.Content.Fields.Clone()
+		if event.Content.Meta != nil {
+			content.Meta = event.Content.Meta.Clone()
+		}
+
+		out := c.processors.Run(&content)
+		if out == nil {
+			// event dropped by output local processors
+			event.Delivery.Drop()
+			continue
+		}
+		if out.Fields == nil {
+			out.Fields = common.MapStr{}
+		}
+		out.Private = originalIndex(len(processed.events))
+
+		processed.events = append(processed.events, publisher.Event{
+			Content:  *out,
+			Flags:    event.Flags,
+			Delivery: event.Delivery,
+		})
+		processed.originals = append(processed.originals, event)
+	}
+
+	if len(processed.events) == 0 {
+		batch.ACK()
+		return nil
+	}
+	return c.client.Publish(processed)
+}
+
+func (c *processingNetClient) Connect() error {
+	return c.conn.Connect()
+}
+
+func (b *processingBatch) Events() []publisher.Event {
+	return b.events
+}
+
+func (b *processingBatch) RetryEvents(events []publisher.Event) {
+	b.Batch.RetryEvents(b.originalEvents(events))
+}
+
+func (b *processingBatch) CancelledEvents(events []publisher.Event) {
+	b.Batch.CancelledEvents(b.originalEvents(events))
+}
+
+func (b *processingBatch) originalEvents(events []publisher.Event) []publisher.Event {
+	originals := make([]publisher.Event, 0, len(events))
+	for _, event := range events {
+		if i, ok := event.Content.Private.(originalIndex); ok {
+			originals = append(originals, b.originals[i])
+		}
+	}
+	return originals
+}
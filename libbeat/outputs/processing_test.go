@@ -0,0 +1,299 @@
+package outputs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/mask"
+	"github.com/elastic/beats/libbeat/outputs/outest"
+	"github.com/elastic/beats/libbeat/publisher"
+
+	"github.com/elastic/beats/libbeat/processors"
+	_ "github.com/elastic/beats/libbeat/processors/actions"
+)
+
+func init() {
+	processors.RegisterPlugin("test_replace_fields", newTestReplaceFields)
+}
+
+// testReplaceFields returns new events only holding the field n, and counts
+// how often it has been closed.
+type testReplaceFields struct{}
+
+var testReplaceFieldsClosed int
+
+func newTestReplaceFields(*common.Config) (processors.Processor, error) {
+	return testReplaceFields{}, nil
+}
+
+func (testReplaceFields) Run(event *beat.Event) (*beat.Event, error) {
+	return &beat.Event{Fields: common.MapStr{"n": event.Fields["n"]}}, nil
+}
+
+func (testReplaceFields) String() string { return "test_replace_fields" }
+
+func (testReplaceFields) Close() error {
+	testReplaceFieldsClosed++
+	return nil
+}
+
+type recordingClient struct {
+	published [][]publisher.Event
+	onPublish func(publisher.Batch)
+}
+
+type recordingNetClient struct {
+	recordingClient
+	connected bool
+}
+
+func (c *recordingClient) Close() error { return nil }
+
+func (c *recordingClient) Publish(batch publisher.Batch) error {
+	c.published = append(c.published, batch.Events())
+	if c.onPublish != nil {
+		c.onPublish(batch)
+	} else {
+		batch.ACK()
+	}
+	return nil
+}
+
+func (c *recordingNetClient) Connect() error {
+	c.connected = true
+	return nil
+}
+
+func TestWithProcessorsNoConfig(t *testing.T) {
+	client := &recordingClient{}
+	group, err := withProcessors(Group{Clients: []Client{client}}, common.NewConfig())
+	require.NoError(t, err)
+	assert.True(t, group.Clients[0] == client)
+}
+
+func TestWithProcessorsIsolation(t *testing.T) {
+	flat := &recordingClient{}
+	flatGroup, err := withProcessors(Group{Clients: []Client{flat}}, mustConfig(t, map[string]interface{}{
+		"processors": []map[string]interface{}{
+			{"drop_fields": map[string]interface{}{"fields": []string{"nested"}}},
+		},
+	}))
+	require.NoError(t, err)
+
+	nested := &recordingClient{}
+	nestedGroup, err := withProcessors(Group{Clients: []Client{nested}}, common.NewConfig())
+	require.NoError(t, err)
+
+	event := beat.Event{Fields: common.MapStr{
+		"message": "hello",
+		"nested":  common.MapStr{"a": 1},
+	}}
+	expected := event.Fields.Clone()
+
+	batch := outest.NewBatch(event)
+	require.NoError(t, flatGroup.Clients[0].Publish(batch))
+	require.NoError(t, nestedGroup.Clients[0].Publish(batch))
+
+	// only the first output sees the transformed event
+	assert.Equal(t, common.MapStr{"message": "hello"}, flat.published[0][0].Content.Fields)
+	assert.Equal(t, expected, nested.published[0][0].Content.Fields)
+
+	// the shared event is not modified
+	assert.Equal(t, expected, batch.Events()[0].Content.Fields)
+
+	assert.Equal(t, outest.BatchACK, batch.Signals[0].Tag)
+	assert.Equal(t, outest.BatchACK, batch.Signals[1].Tag)
+}
+
+func TestWithProcessorsDropAll(t *testing.T) {
+	client := &recordingClient{}
+	group, err := withProcessors(Group{Clients: []Client{client}}, mustConfig(t, map[string]interface{}{
+		"processors": []map[string]interface{}{
+			{"drop_event": map[string]interface{}{}},
+		},
+	}))
+	require.NoError(t, err)
+
+	batch := outest.NewBatch(beat.Event{Fields: common.MapStr{"a": 1}})
+	require.NoError(t, group.Clients[0].Publish(batch))
+
+	assert.Len(t, client.published, 0)
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchACK, batch.Signals[0].Tag)
+}
+
+func TestWithProcessorsRetryOriginalEvents(t *testing.T) {
+	client := &recordingNetClient{}
+	client.onPublish = func(batch publisher.Batch) {
+		// retry all but the first event
+		batch.RetryEvents(batch.Events()[1:])
+	}
+
+	group, err := withProcessors(Group{Clients: []Client{client}}, mustConfig(t, map[string]interface{}{
+		"processors": []map[string]interface{}{
+			{"drop_fields": map[string]interface{}{"fields": []string{"secret"}}},
+		},
+	}))
+	require.NoError(t, err)
+
+	// network clients stay connectable
+	nc, ok := group.Clients[0].(NetworkClient)
+	require.True(t, ok)
+	require.NoError(t, nc.Connect())
+	assert.True(t, client.connected)
+
+	batch := outest.NewBatch(
+		beat.Event{Fields: common.MapStr{"n": 1, "secret": "x"}},
+		beat.Event{Fields: common.MapStr{"n": 2, "secret": "y"}},
+		beat.Event{Fields: common.MapStr{"n": 3, "secret": "z"}},
+	)
+	require.NoError(t, nc.Publish(batch))
+
+	require.Len(t, batch.Signals, 1)
+	signal := batch.Signals[0]
+	assert.Equal(t, outest.BatchRetryEvents, signal.Tag)
+	require.Len(t, signal.Events, 2)
+	assert.Equal(t, common.MapStr{"n": 2, "secret": "y"}, signal.Events[0].Content.Fields)
+	assert.Equal(t, common.MapStr{"n": 3, "secret": "z"}, signal.Events[1].Content.Fields)
+}
+
+func TestWithProcessorsRetryCopies(t *testing.T) {
+	client := &recordingClient{}
+	client.onPublish = func(batch publisher.Batch) {
+		// return copies of the last two events, in reverse order
+		var retry []publisher.Event
+		for i := len(batch.Events()) - 1; i > 0; i-- {
+			event := batch.Events()[i]
+			event.Content.Fields = event.Content.Fields.Clone()
+			retry = append(retry, event)
+		}
+		batch.RetryEvents(retry)
+	}
+
+	group, err := withProcessors(Group{Clients: []Client{client, client}}, mustConfig(t, map[string]interface{}{
+		"processors": []map[string]interface{}{
+			{"test_replace_fields": map[string]interface{}{}},
+		},
+	}))
+	require.NoError(t, err)
+
+	batch := outest.NewBatch(
+		beat.Event{Fields: common.MapStr{"n": 1, "secret": "x"}},
+		beat.Event{Fields: common.MapStr{"n": 2, "secret": "y"}},
+		beat.Event{Fields: common.MapStr{"n": 3, "secret": "z"}},
+	)
+	require.NoError(t, group.Clients[0].Publish(batch))
+
+	require.Len(t, batch.Signals, 1)
+	signal := batch.Signals[0]
+	assert.Equal(t, outest.BatchRetryEvents, signal.Tag)
+	require.Len(t, signal.Events, 2)
+	assert.Equal(t, common.MapStr{"n": 3, "secret": "z"}, signal.Events[0].Content.Fields)
+	assert.Equal(t, common.MapStr{"n": 2, "secret": "y"}, signal.Events[1].Content.Fields)
+
+	// the processors of each client are closed with the client
+	testReplaceFieldsClosed = 0
+	require.NoError(t, group.Clients[0].Close())
+	assert.Equal(t, 1, testReplaceFieldsClosed)
+	require.NoError(t, group.Clients[1].Close())
+	assert.Equal(t, 2, testReplaceFieldsClosed)
+}
+
+func TestWithProcessorsDelivery(t *testing.T) {
+	client := &recordingClient{}
+	client.onPublish = func(batch publisher.Batch) {
+		// the output fails the first event it receives
+		batch.Events()[0].Fail()
+		batch.ACK()
+	}
+
+	group, err := withProcessors(Group{Clients: []Client{client}}, mustConfig(t, map[string]interface{}{
+		"processors": []map[string]interface{}{
+			{"drop_event": map[string]interface{}{
+				"when": map[string]interface{}{"equals": map[string]interface{}{"n": 2}},
+			}},
+		},
+	}))
+	require.NoError(t, err)
+
+	batch := outest.NewBatch(
+		beat.Event{Fields: common.MapStr{"n": 1}},
+		beat.Event{Fields: common.MapStr{"n": 2}},
+		beat.Event{Fields: common.MapStr{"n": 3}},
+	)
+	status := make([]beat.EventStatus, len(batch.Events()))
+	for i := range batch.Events() {
+		i := i
+		batch.Events()[i].Delivery = publisher.NewDelivery(func(s beat.EventStatus) {
+			status[i] = s
+		})
+	}
+	batch.OnSignal = func(sig outest.BatchSignal) {
+		if sig.Tag == outest.BatchACK {
+			for _, event := range batch.Events() {
+				event.Delivery.Complete()
+			}
+		}
+	}
+	require.NoError(t, group.Clients[0].Publish(batch))
+
+	assert.Equal(t, []beat.EventStatus{beat.EventFailed, beat.EventDropped, beat.EventACKed}, status)
+}
+
+func TestWithProcessorsEncryptFields(t *testing.T) {
+	const key = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+
+	encrypted := &recordingClient{}
+	encryptedGroup, err := withProcessors(Group{Clients: []Client{encrypted}}, mustConfig(t, map[string]interface{}{
+		"processors": []map[string]interface{}{
+			{"encrypt_fields": map[string]interface{}{"fields": []string{"user.email"}, "key": key}},
+		},
+	}))
+	require.NoError(t, err)
+
+	plain := &recordingClient{}
+	plainGroup, err := withProcessors(Group{Clients: []Client{plain}}, common.NewConfig())
+	require.NoError(t, err)
+
+	batch := outest.NewBatch(beat.Event{Fields: common.MapStr{
+		"message": "login",
+		"user":    common.MapStr{"email": "alice@example.com"},
+	}})
+	require.NoError(t, encryptedGroup.Clients[0].Publish(batch))
+	require.NoError(t, plainGroup.Clients[0].Publish(batch))
+
+	// the configured output only receives the ciphertext
+	value, err := encrypted.published[0][0].Content.Fields.GetValue("user.email")
+	require.NoError(t, err)
+	ciphertext, ok := value.(string)
+	require.True(t, ok)
+	assert.True(t, strings.HasPrefix(ciphertext, mask.EncryptedPrefix), ciphertext)
+	assert.NotContains(t, ciphertext, "alice")
+
+	rawKey, err := mask.DecodeKey(key)
+	require.NoError(t, err)
+	cipher, err := mask.NewCipher(rawKey)
+	require.NoError(t, err)
+	decrypted, err := cipher.Decrypt(ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", decrypted)
+
+	// other outputs and the shared event are untouched
+	value, err = plain.published[0][0].Content.Fields.GetValue("user.email")
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", value)
+	value, err = batch.Events()[0].Content.Fields.GetValue("user.email")
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", value)
+}
+
+func mustConfig(t *testing.T, m map[string]interface{}) *common.Config {
+	cfg, err := common.NewConfigFrom(m)
+	require.NoError(t, err)
+	return cfg
+}
@@ -0,0 +1,267 @@
+package route
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/processors"
+	"github.com/elastic/beats/libbeat/publisher"
+)
+
+// pendingKey is the meta key used to remember the routes an event still has
+// to be published to, when it is returned to the pipeline for retry. The
+// key is removed before the event is passed to the routed outputs.
+const pendingKey = "_route_pending"
+
+type route struct {
+	name      string
+	condition *processors.Condition
+	isDefault bool
+	client    outputs.Client
+
+	// connected is false for network clients that have to be (re)connected.
+	// It is only accessed by the goroutine owning the routing client.
+	connected bool
+}
+
+// client splits each batch between the routes with a matching condition.
+// Events not matching any route are published to the default routes. The
+// original batch is ACKed once the events have been ACKed by all routes.
+type client struct {
+	routes []*route
+}
+
+// routedBatch tracks the sub-batches published to the routes, collecting the
+// events to be retried per route.
+type routedBatch struct {
+	parent publisher.Batch
+
+	mutex   sync.Mutex
+	active  int
+	retry   bool
+	pending map[uintptr][]int
+}
+
+// subBatch is the part of a batch published to a single route.
+type subBatch struct {
+	batch  *routedBatch
+	route  int
+	events []publisher.Event
+}
+
+func newClient(routes []*route) *client {
+	return &client{routes: routes}
+}
+
+func (c *client) String() string {
+	names := make([]string, len(c.routes))
+	for i, r := range c.routes {
+		names[i] = r.name
+	}
+	return fmt.Sprintf("route%v", names)
+}
+
+// Connect connects the routed network clients not connected yet.
+func (c *client) Connect() error {
+	for _, r := range c.routes {
+		if r.connected {
+			continue
+		}
+
+		if nc, ok := r.client.(outputs.Connectable); ok {
+			if err := nc.Connect(); err != nil {
+				return fmt.Errorf("failed to connect %v output: %v", r.name, err)
+			}
+		}
+		r.connected = true
+	}
+	return nil
+}
+
+func (c *client) Close() error {
+	var firstErr error
+	for _, r := range c.routes {
+		r.connected = false
+		if err := r.client.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Publish publishes the events of the batch to the matching routes
+// concurrently. If publishing to a route fails, the route is reconnected
+// on the next call to Connect.
+func (c *client) Publish(batch publisher.Batch) error {
+	events := batch.Events()
+	routed := make([][]publisher.Event, len(c.routes))
+	for _, event := range events {
+		routes, event := c.match(event)
+		for _, i := range routes {
+			routed[i] = append(routed[i], event)
+		}
+	}
+
+	b := &routedBatch{parent: batch, pending: map[uintptr][]int{}}
+	for _, events := range routed {
+		if len(events) > 0 {
+			b.active++
+		}
+	}
+	if b.active == 0 {
+		debugf("No route for %v events", len(events))
+		batch.ACK()
+		return nil
+	}
+
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, len(c.routes))
+	)
+	for i, events := range routed {
+		if len(events) == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, events []publisher.Event) {
+			defer wg.Done()
+			errs[i] = c.routes[i].client.Publish(&subBatch{batch: b, route: i, events: events})
+		}(i, events)
+	}
+	wg.Wait()
+
+	var firstErr error
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		c.routes[i].connected = false
+		if firstErr == nil {
+			firstErr = fmt.Errorf("failed to publish to %v output: %v", c.routes[i].name, err)
+		}
+	}
+	return firstErr
+}
+
+// match returns the indices of the routes the event is published to. Events
+// returned for retry are only published to the routes that failed them.
+func (c *client) match(event publisher.Event) ([]int, publisher.Event) {
+	if pending, ok := event.Content.Meta[pendingKey].([]int); ok {
+		meta := event.Content.Meta.Clone()
+		delete(meta, pendingKey)
+		if len(meta) == 0 {
+			meta = nil
+		}
+		event.Content.Meta = meta
+		return pending, event
+	}
+
+	var matched []int
+	for i, r := range c.routes {
+		if !r.isDefault && (r.condition == nil || r.condition.Check(&event.Content)) {
+			matched = append(matched, i)
+		}
+	}
+	if len(matched) > 0 {
+		return matched, event
+	}
+
+	for i, r := range c.routes {
+		if r.isDefault {
+			matched = append(matched, i)
+		}
+	}
+	return matched, event
+}
+
+// done is called once a route has processed its sub-batch, passing the events
+// the route has not processed. Once all routes are done, the events not
+// processed are returned to the pipeline, remembering the routes they still
+// have to be published to. All other events are ACKed.
+func (b *routedBatch) done(route int, events []publisher.Event, retry bool) {
+	b.mutex.Lock()
+	for _, event := range events {
+		id := eventID(event)
+		b.pending[id] = append(b.pending[id], route)
+	}
+	if len(events) > 0 && retry {
+		b.retry = true
+	}
+
+	b.active--
+	if b.active > 0 {
+		b.mutex.Unlock()
+		return
+	}
+	b.mutex.Unlock()
+
+	if len(b.pending) == 0 {
+		b.parent.ACK()
+		return
+	}
+
+	var remaining []publisher.Event
+	for _, event := range b.parent.Events() {
+		routes, ok := b.pending[eventID(event)]
+		if !ok {
+			continue
+		}
+
+		meta := common.MapStr{}
+		if event.Content.Meta != nil {
+			meta = event.Content.Meta.Clone()
+		}
+		meta[pendingKey] = routes
+		event.Content.Meta = meta
+		remaining = append(remaining, event)
+	}
+
+	if b.retry {
+		b.parent.RetryEvents(remaining)
+	} else {
+		b.parent.CancelledEvents(remaining)
+	}
+}
+
+func (s *subBatch) Events() []publisher.Event {
+	return s.events
+}
+
+func (s *subBatch) ACK() {
+	s.batch.done(s.route, nil, false)
+}
+
+// Drop marks the events as failed. The failure is reported once all routes
+// are done with the batch.
+func (s *subBatch) Drop() {
+	for i := range s.events {
+		s.events[i].Fail()
+	}
+	s.batch.done(s.route, nil, false)
+}
+
+func (s *subBatch) Retry() {
+	s.batch.done(s.route, s.events, true)
+}
+
+func (s *subBatch) RetryEvents(events []publisher.Event) {
+	s.batch.done(s.route, events, true)
+}
+
+func (s *subBatch) Cancelled() {
+	s.batch.done(s.route, s.events, false)
+}
+
+func (s *subBatch) CancelledEvents(events []publisher.Event) {
+	s.batch.done(s.route, events, false)
+}
+
+// eventID identifies an event by its fields, as the fields are shared by all
+// copies of an event.
+func eventID(event publisher.Event) uintptr {
+	return reflect.ValueOf(event.Content.Fields).Pointer()
+}
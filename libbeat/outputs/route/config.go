@@ -0,0 +1,53 @@
+package route
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+type routeConfig struct {
+	Outputs     []*common.Config `config:"outputs" validate:"required"`
+	BulkMaxSize int              `config:"bulk_max_size"`
+	MaxRetries  int              `config:"max_retries" validate:"min=-1"`
+}
+
+// outputConfig holds the routing settings of an output. The output itself is
+// configured under its type name, next to the routing settings.
+type outputConfig struct {
+	When    *processors.ConditionConfig `config:"when"`
+	Default bool                        `config:"default"`
+}
+
+const (
+	defaultBulkMaxSize = 2048
+)
+
+var defaultConfig = routeConfig{
+	BulkMaxSize: defaultBulkMaxSize,
+	MaxRetries:  3,
+}
+
+var errDefaultCondition = errors.New("an output can not have both a 'when' condition and 'default' set")
+
+// outputType returns the type name of a routed output, the only setting that
+// is not a routing setting.
+func outputType(cfg *common.Config) (string, error) {
+	var name string
+	for _, field := range cfg.GetFields() {
+		if field == "when" || field == "default" {
+			continue
+		}
+		if name != "" {
+			return "", fmt.Errorf("each routed output needs to have exactly one output type, but found '%v' and '%v'", name, field)
+		}
+		name = field
+	}
+
+	if name == "" {
+		return "", errors.New("routed output has no output type configured")
+	}
+	return name, nil
+}
@@ -0,0 +1,126 @@
+// Package route implements the route output, publishing each event to the
+// outputs whose condition matches the event.
+package route
+
+import (
+	"fmt"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+func init() {
+	outputs.RegisterType("route", makeRoute)
+}
+
+var debugf = logp.MakeDebug("route")
+
+// makeRoute loads the routed outputs. Each output is combined into a single
+// client, as the events of a batch are split between the outputs. Outputs
+// configured with multiple hosts use the hosts in failover mode. The batch
+// size is limited to the smallest batch size of the routed outputs.
+func makeRoute(
+	beat beat.Info,
+	stats *outputs.Stats,
+	cfg *common.Config,
+) (outputs.Group, error) {
+	config := defaultConfig
+	if err := cfg.Unpack(&config); err != nil {
+		return outputs.Fail(err)
+	}
+
+	batchSize := config.BulkMaxSize
+	routes := make([]*route, 0, len(config.Outputs))
+	for i, outCfg := range config.Outputs {
+		r, size, err := loadRoute(beat, stats, outCfg)
+		if err != nil {
+			closeRoutes(routes)
+			return outputs.Fail(fmt.Errorf("error loading routed output %v: %v", i, err))
+		}
+		if size > 0 && (batchSize <= 0 || size < batchSize) {
+			batchSize = size
+		}
+		routes = append(routes, r)
+	}
+
+	return outputs.Success(batchSize, config.MaxRetries, newClient(routes))
+}
+
+// loadRoute loads a routed output, returning the route and the batch size of
+// the output.
+func loadRoute(beat beat.Info, stats *outputs.Stats, cfg *common.Config) (*route, int, error) {
+	name, err := outputType(cfg)
+	if err != nil {
+		return nil, 0, err
+	}
+	if name == "route" {
+		return nil, 0, fmt.Errorf("route outputs can not be nested")
+	}
+
+	config := outputConfig{}
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, 0, err
+	}
+	if config.Default && config.When != nil {
+		return nil, 0, errDefaultCondition
+	}
+
+	r := &route{name: name, isDefault: config.Default}
+	if config.When != nil {
+		r.condition, err = processors.NewCondition(config.When)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	outCfg, err := cfg.Child(name, -1)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	group, err := outputs.Load(beat, stats, name, outCfg)
+	if err != nil {
+		return nil, 0, err
+	}
+	r.client, err = combineClients(group.Clients)
+	if err != nil {
+		closeClients(group.Clients)
+		return nil, 0, fmt.Errorf("%v output: %v", name, err)
+	}
+	return r, group.BatchSize, nil
+}
+
+// combineClients combines the clients of an output into a single client.
+func combineClients(clients []outputs.Client) (outputs.Client, error) {
+	switch len(clients) {
+	case 0:
+		return nil, outputs.ErrNoConnectionConfigured
+	case 1:
+		return clients[0], nil
+	}
+
+	netClients := make([]outputs.NetworkClient, len(clients))
+	for i, client := range clients {
+		nc, ok := client.(outputs.NetworkClient)
+		if !ok {
+			return nil, fmt.Errorf("multiple clients are only supported by network outputs")
+		}
+		netClients[i] = nc
+	}
+	return outputs.NewFailoverClient(netClients), nil
+}
+
+func closeRoutes(routes []*route) {
+	for _, r := range routes {
+		r.client.Close()
+	}
+}
+
+func closeClients(clients []outputs.Client) {
+	for _, c := range clients {
+		c.Close()
+	}
+}
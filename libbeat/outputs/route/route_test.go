@@ -0,0 +1,307 @@
+package route
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/outest"
+	"github.com/elastic/beats/libbeat/publisher"
+)
+
+// fakeClient records the batches published to a routed output. Batches are
+// ACKed, unless publish is set.
+type fakeClient struct {
+	name    string
+	publish func(batch publisher.Batch) error
+
+	mutex    sync.Mutex
+	events   [][]beat.Event
+	connects int
+	closed   bool
+}
+
+var (
+	fakeMutex   sync.Mutex
+	fakeClients = map[string]*fakeClient{}
+)
+
+func init() {
+	outputs.RegisterType("fake", makeFake)
+}
+
+func makeFake(beat beat.Info, stats *outputs.Stats, cfg *common.Config) (outputs.Group, error) {
+	config := struct {
+		Name      string `config:"name"`
+		BatchSize int    `config:"batch_size"`
+	}{}
+	if err := cfg.Unpack(&config); err != nil {
+		return outputs.Fail(err)
+	}
+
+	client := &fakeClient{name: config.Name}
+	fakeMutex.Lock()
+	fakeClients[config.Name] = client
+	fakeMutex.Unlock()
+	return outputs.Success(config.BatchSize, 0, client)
+}
+
+func getFake(name string) *fakeClient {
+	fakeMutex.Lock()
+	defer fakeMutex.Unlock()
+	return fakeClients[name]
+}
+
+func (c *fakeClient) Connect() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.connects++
+	return nil
+}
+
+func (c *fakeClient) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *fakeClient) Publish(batch publisher.Batch) error {
+	var events []beat.Event
+	for _, event := range batch.Events() {
+		events = append(events, event.Content)
+	}
+
+	c.mutex.Lock()
+	c.events = append(c.events, events)
+	publish := c.publish
+	c.mutex.Unlock()
+
+	if publish != nil {
+		return publish(batch)
+	}
+	batch.ACK()
+	return nil
+}
+
+// ids returns the ids of the events published to the client, per batch.
+func (c *fakeClient) ids() [][]int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var ids [][]int
+	for _, events := range c.events {
+		var batch []int
+		for _, event := range events {
+			batch = append(batch, event.Fields["id"].(int))
+		}
+		ids = append(ids, batch)
+	}
+	return ids
+}
+
+func fakeOutput(name string, settings map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{"fake.name": name}
+	for k, v := range settings {
+		out[k] = v
+	}
+	return out
+}
+
+func whenType(typ string) map[string]interface{} {
+	return map[string]interface{}{"when.equals.type": typ}
+}
+
+func newTestRoute(t *testing.T, outputs ...map[string]interface{}) (*client, int) {
+	cfg, err := common.NewConfigFrom(map[string]interface{}{"outputs": outputs})
+	require.NoError(t, err)
+
+	group, err := makeRoute(beat.Info{}, nil, cfg)
+	require.NoError(t, err)
+	require.Len(t, group.Clients, 1)
+
+	client := group.Clients[0].(*client)
+	require.NoError(t, client.Connect())
+	return client, group.BatchSize
+}
+
+func testEvent(id int, typ string) beat.Event {
+	return beat.Event{Fields: common.MapStr{"id": id, "type": typ}}
+}
+
+func TestRouteByCondition(t *testing.T) {
+	client, _ := newTestRoute(t,
+		fakeOutput("a", whenType("a")),
+		fakeOutput("b", whenType("b")),
+		fakeOutput("other", map[string]interface{}{"default": true}),
+	)
+
+	batch := outest.NewBatch(
+		testEvent(0, "a"),
+		testEvent(1, "b"),
+		testEvent(2, "c"),
+		testEvent(3, "a"),
+	)
+	require.NoError(t, client.Publish(batch))
+
+	assert.Equal(t, [][]int{{0, 3}}, getFake("a").ids())
+	assert.Equal(t, [][]int{{1}}, getFake("b").ids())
+	assert.Equal(t, [][]int{{2}}, getFake("other").ids())
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchACK}}, batch.Signals)
+}
+
+func TestRouteUnconditional(t *testing.T) {
+	client, _ := newTestRoute(t,
+		fakeOutput("a", whenType("a")),
+		fakeOutput("all", nil),
+		fakeOutput("other", map[string]interface{}{"default": true}),
+	)
+
+	batch := outest.NewBatch(testEvent(0, "a"), testEvent(1, "b"))
+	require.NoError(t, client.Publish(batch))
+
+	// Events matching an output without condition are not published to the
+	// default output.
+	assert.Equal(t, [][]int{{0}}, getFake("a").ids())
+	assert.Equal(t, [][]int{{0, 1}}, getFake("all").ids())
+	assert.Empty(t, getFake("other").ids())
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchACK}}, batch.Signals)
+}
+
+func TestRouteNoMatch(t *testing.T) {
+	client, _ := newTestRoute(t, fakeOutput("a", whenType("a")))
+
+	batch := outest.NewBatch(testEvent(0, "b"))
+	require.NoError(t, client.Publish(batch))
+
+	assert.Empty(t, getFake("a").ids())
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchACK}}, batch.Signals)
+}
+
+func TestRouteACKAfterAllOutputs(t *testing.T) {
+	client, _ := newTestRoute(t, fakeOutput("a", nil), fakeOutput("b", nil))
+
+	var pending publisher.Batch
+	getFake("a").publish = func(batch publisher.Batch) error {
+		pending = batch
+		return nil
+	}
+
+	batch := outest.NewBatch(testEvent(0, "a"))
+	require.NoError(t, client.Publish(batch))
+	assert.Empty(t, batch.Signals)
+
+	pending.ACK()
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchACK}}, batch.Signals)
+}
+
+func TestRouteRetryFailedOutput(t *testing.T) {
+	client, _ := newTestRoute(t, fakeOutput("a", nil), fakeOutput("b", nil))
+
+	b := getFake("b")
+	b.publish = func(batch publisher.Batch) error {
+		events := batch.Events()
+		batch.RetryEvents(events[1:])
+		return errors.New("connection lost")
+	}
+
+	event := testEvent(1, "a")
+	event.Meta = common.MapStr{"id": "x"}
+	batch := outest.NewBatch(testEvent(0, "a"), event)
+	assert.Error(t, client.Publish(batch))
+
+	require.Len(t, batch.Signals, 1)
+	signal := batch.Signals[0]
+	assert.Equal(t, outest.BatchRetryEvents, signal.Tag)
+	require.Len(t, signal.Events, 1)
+	assert.Equal(t, common.MapStr{"id": "x", pendingKey: []int{1}}, signal.Events[0].Content.Meta)
+
+	// Only the failed output is reconnected.
+	require.NoError(t, client.Connect())
+	assert.Equal(t, 1, getFake("a").connects)
+	assert.Equal(t, 2, b.connects)
+
+	// Retried events are only published to the failed output, without the
+	// routing meta data.
+	b.publish = nil
+	retry := outest.NewBatch(signal.Events[0].Content)
+	require.NoError(t, client.Publish(retry))
+
+	assert.Equal(t, [][]int{{0, 1}}, getFake("a").ids())
+	assert.Equal(t, [][]int{{0, 1}, {1}}, b.ids())
+	assert.Equal(t, common.MapStr{"id": "x"}, b.events[1][0].Meta)
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchACK}}, retry.Signals)
+}
+
+func TestRouteCancelled(t *testing.T) {
+	client, _ := newTestRoute(t, fakeOutput("a", nil))
+	getFake("a").publish = func(batch publisher.Batch) error {
+		batch.Cancelled()
+		return nil
+	}
+
+	batch := outest.NewBatch(testEvent(0, "a"))
+	require.NoError(t, client.Publish(batch))
+
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchCancelledEvents, batch.Signals[0].Tag)
+	assert.Len(t, batch.Signals[0].Events, 1)
+}
+
+func TestRouteBatchSize(t *testing.T) {
+	_, batchSize := newTestRoute(t,
+		fakeOutput("a", map[string]interface{}{"fake.batch_size": 100}),
+		fakeOutput("b", map[string]interface{}{"fake.batch_size": 50}),
+	)
+	assert.Equal(t, 50, batchSize)
+
+	_, batchSize = newTestRoute(t, fakeOutput("a", nil))
+	assert.Equal(t, defaultBulkMaxSize, batchSize)
+}
+
+func TestRouteClose(t *testing.T) {
+	client, _ := newTestRoute(t, fakeOutput("a", nil), fakeOutput("b", nil))
+	require.NoError(t, client.Close())
+
+	assert.True(t, getFake("a").closed)
+	assert.True(t, getFake("b").closed)
+}
+
+func TestRouteConfigErrors(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"no outputs": {},
+		"no output type": {
+			"outputs": []map[string]interface{}{{"default": true}},
+		},
+		"multiple output types": {
+			"outputs": []map[string]interface{}{{"fake.name": "a", "console.pretty": true}},
+		},
+		"condition and default": {
+			"outputs": []map[string]interface{}{
+				fakeOutput("a", map[string]interface{}{"default": true, "when.equals.type": "a"}),
+			},
+		},
+		"nested route": {
+			"outputs": []map[string]interface{}{
+				{"route.outputs": []map[string]interface{}{fakeOutput("a", nil)}},
+			},
+		},
+		"unknown output": {
+			"outputs": []map[string]interface{}{{"unknown.hosts": []string{"localhost"}}},
+		},
+	}
+
+	for name, settings := range tests {
+		cfg, err := common.NewConfigFrom(settings)
+		require.NoError(t, err)
+
+		_, err = makeRoute(beat.Info{}, nil, cfg)
+		assert.Error(t, err, name)
+	}
+}
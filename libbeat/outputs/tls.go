@@ -2,8 +2,10 @@ package outputs
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/pem"
 	"errors"
 	"fmt"
@@ -32,11 +34,13 @@ type TLSConfig struct {
 	Enabled          *bool                         `config:"enabled"`
 	VerificationMode transport.TLSVerificationMode `config:"verification_mode"` // one of 'none', 'full'
 	Versions         []transport.TLSVersion        `config:"supported_protocols"`
+	MinVersion       *transport.TLSVersion         `config:"min_version"`
 	CipherSuites     []tlsCipherSuite              `config:"cipher_suites"`
 	CAs              []string                      `config:"certificate_authorities"`
 	Certificate      CertificateConfig             `config:",inline"`
 	CurveTypes       []tlsCurveType                `config:"curve_types"`
 	Renegotiation    tlsRenegotiationSupport       `config:"renegotiation"`
+	CASha256         []string                      `config:"ca_sha256"`
 }
 
 type CertificateConfig struct {
@@ -69,12 +73,35 @@ var tlsCipherSuites = map[string]tlsCipherSuite{
 	"RSA-AES-256-CBC-SHA":            tlsCipherSuite(tls.TLS_RSA_WITH_AES_256_CBC_SHA),
 	"RSA-AES-256-GCM-SHA384":         tlsCipherSuite(tls.TLS_RSA_WITH_AES_256_GCM_SHA384),
 	"RSA-RC4-128-SHA":                tlsCipherSuite(tls.TLS_RSA_WITH_RC4_128_SHA),
+
+	// TLS 1.3 cipher suites, they can only be used with TLS 1.3.
+	"TLS-AES-128-GCM-SHA256":       tlsCipherSuite(tls.TLS_AES_128_GCM_SHA256),
+	"TLS-AES-256-GCM-SHA384":       tlsCipherSuite(tls.TLS_AES_256_GCM_SHA384),
+	"TLS-CHACHA20-POLY1305-SHA256": tlsCipherSuite(tls.TLS_CHACHA20_POLY1305_SHA256),
+}
+
+// tls13CipherSuites are the cipher suites of TLS 1.3, which can not be used
+// with earlier versions, and no other cipher suites can be used with TLS 1.3.
+var tls13CipherSuites = map[tlsCipherSuite]bool{
+	tlsCipherSuite(tls.TLS_AES_128_GCM_SHA256):       true,
+	tlsCipherSuite(tls.TLS_AES_256_GCM_SHA384):       true,
+	tlsCipherSuite(tls.TLS_CHACHA20_POLY1305_SHA256): true,
+}
+
+// tlsMinVersions are the versions enabled by min_version, if no
+// supported_protocols are configured.
+var tlsMinVersions = []transport.TLSVersion{
+	transport.TLSVersion10,
+	transport.TLSVersion11,
+	transport.TLSVersion12,
+	transport.TLSVersion13,
 }
 
 var tlsCurveTypes = map[string]tlsCurveType{
-	"P-256": tlsCurveType(tls.CurveP256),
-	"P-384": tlsCurveType(tls.CurveP384),
-	"P-521": tlsCurveType(tls.CurveP521),
+	"P-256":  tlsCurveType(tls.CurveP256),
+	"P-384":  tlsCurveType(tls.CurveP384),
+	"P-521":  tlsCurveType(tls.CurveP521),
+	"X25519": tlsCurveType(tls.X25519),
 }
 
 var tlsRenegotiationSupportTypes = map[string]tlsRenegotiationSupport{
@@ -94,9 +121,84 @@ func (c *TLSConfig) Validate() error {
 		return ErrKeyNoCertificate
 	}
 
+	for _, pin := range c.CASha256 {
+		fingerprint, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil || len(fingerprint) != sha256.Size {
+			return fmt.Errorf("invalid ca_sha256 pin '%v', must be a base64 encoded SHA-256 fingerprint", pin)
+		}
+	}
+
+	if c.MinVersion != nil {
+		for _, version := range c.Versions {
+			if version < *c.MinVersion {
+				return fmt.Errorf("supported protocol %v is below the min_version %v", version, *c.MinVersion)
+			}
+		}
+	}
+
+	return c.validateCipherSuites()
+}
+
+// validateCipherSuites checks that each cipher suite can be used with one of
+// the versions, and that connections of all versions have a cipher suite.
+// Otherwise the versions or cipher suites would fail all handshakes, which
+// is reported when loading the config instead.
+func (c *TLSConfig) validateCipherSuites() error {
+	if len(c.CipherSuites) == 0 {
+		return nil
+	}
+
+	// the default versions are all before TLSv1.3
+	versions := c.versions()
+	hasTLS13, hasLegacy := false, len(versions) == 0
+	for _, version := range versions {
+		if version >= transport.TLSVersion13 {
+			hasTLS13 = true
+		} else {
+			hasLegacy = true
+		}
+	}
+
+	suitesTLS13, suitesLegacy := 0, 0
+	for _, suite := range c.CipherSuites {
+		if tls13CipherSuites[suite] {
+			if !hasTLS13 {
+				return fmt.Errorf("cipher suite %v requires TLSv1.3", tls.CipherSuiteName(uint16(suite)))
+			}
+			suitesTLS13++
+		} else {
+			if !hasLegacy {
+				return fmt.Errorf("cipher suite %v can not be used with TLSv1.3", tls.CipherSuiteName(uint16(suite)))
+			}
+			suitesLegacy++
+		}
+	}
+
+	switch {
+	case hasTLS13 && suitesTLS13 == 0:
+		return errors.New("cipher_suites contain no TLSv1.3 cipher suite")
+	case hasLegacy && suitesLegacy == 0:
+		return errors.New("cipher_suites contain no cipher suite for versions before TLSv1.3")
+	}
 	return nil
 }
 
+// versions returns the enabled versions. Without supported_protocols, all
+// versions from min_version are enabled if it is set.
+func (c *TLSConfig) versions() []transport.TLSVersion {
+	if len(c.Versions) > 0 || c.MinVersion == nil {
+		return c.Versions
+	}
+
+	var versions []transport.TLSVersion
+	for _, version := range tlsMinVersions {
+		if version >= *c.MinVersion {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
+
 func (c *TLSConfig) IsEnabled() bool {
 	return c != nil && (c.Enabled == nil || *c.Enabled)
 }
@@ -147,13 +249,14 @@ func LoadTLSConfig(config *TLSConfig) (*transport.TLSConfig, error) {
 
 	// return config if no error occurred
 	return &transport.TLSConfig{
-		Versions:         config.Versions,
+		Versions:         config.versions(),
 		Verification:     config.VerificationMode,
 		Certificates:     certs,
 		RootCAs:          cas,
 		CipherSuites:     cipherSuites,
 		CurvePreferences: curves,
 		Renegotiation:    tls.RenegotiationSupport(config.Renegotiation),
+		CASha256:         config.CASha256,
 	}, nil
 }
 
@@ -4,9 +4,12 @@ package outputs
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/outputs/transport"
@@ -177,6 +180,42 @@ func TestCertificateFails(t *testing.T) {
 			"unknown renegotiation type",
 			"renegotiation: always",
 		},
+		{
+			"invalid ca_sha256 pin",
+			"ca_sha256: ['not-base64!']",
+		},
+		{
+			"ca_sha256 pin of wrong length",
+			"ca_sha256: ['c2hvcnQ=']",
+		},
+		{
+			"unknown min version",
+			"min_version: TLSv2",
+		},
+		{
+			"supported protocol below min_version",
+			"{min_version: TLSv1.3, supported_protocols: [TLSv1.2, TLSv1.3]}",
+		},
+		{
+			"TLS 1.2 cipher suite with min_version TLSv1.3",
+			"{min_version: TLSv1.3, cipher_suites: [ECDHE-RSA-AES-128-GCM-SHA256]}",
+		},
+		{
+			"TLS 1.3 cipher suite without TLSv1.3",
+			"{supported_protocols: [TLSv1.2], cipher_suites: [TLS-AES-128-GCM-SHA256]}",
+		},
+		{
+			"TLS 1.3 cipher suite with the default versions",
+			"cipher_suites: [TLS-AES-128-GCM-SHA256]",
+		},
+		{
+			"no TLS 1.3 cipher suite",
+			"{supported_protocols: [TLSv1.2, TLSv1.3], cipher_suites: [ECDHE-RSA-AES-128-GCM-SHA256]}",
+		},
+		{
+			"no cipher suite before TLS 1.3",
+			"{min_version: TLSv1.2, cipher_suites: [TLS-AES-128-GCM-SHA256]}",
+		},
 	}
 
 	for i, test := range tests {
@@ -199,3 +238,164 @@ func TestCertificateFails(t *testing.T) {
 		assert.Error(t, err)
 	}
 }
+
+// startPinTestServer starts a TLS server presenting the test certificate,
+// completing the handshake of each connection.
+func startPinTestServer(t *testing.T) (string, *x509.Certificate, func()) {
+	cert, err := tls.LoadX509KeyPair("logstash/ca_test.pem", "logstash/ca_test.key")
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	})
+	require.NoError(t, err)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+	return listener.Addr().String(), leaf, func() { listener.Close() }
+}
+
+func dialWithPins(t *testing.T, addr string, pins ...string) error {
+	tmp, err := LoadTLSConfig(&TLSConfig{
+		VerificationMode: transport.VerifyNone,
+		CASha256:         pins,
+	})
+	require.NoError(t, err)
+
+	conn, err := tls.Dial("tcp", addr, tmp.BuildModuleConfig(""))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func TestCASha256PinMatching(t *testing.T) {
+	addr, leaf, stop := startPinTestServer(t)
+	defer stop()
+
+	other := "pF0SGmrNR+IHUqxzOgLdpnkz3avwgOvHkxXVkACVFDY="
+	assert.NoError(t, dialWithPins(t, addr, transport.SPKIFingerprint(leaf)))
+	assert.NoError(t, dialWithPins(t, addr, other, transport.SPKIFingerprint(leaf)))
+}
+
+func TestCASha256PinMismatch(t *testing.T) {
+	addr, _, stop := startPinTestServer(t)
+	defer stop()
+
+	err := dialWithPins(t, addr, "pF0SGmrNR+IHUqxzOgLdpnkz3avwgOvHkxXVkACVFDY=")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), transport.ErrPinMismatch.Error())
+	}
+}
+
+func TestCASha256NotSetAcceptsAnyCertificate(t *testing.T) {
+	addr, _, stop := startPinTestServer(t)
+	defer stop()
+
+	assert.NoError(t, dialWithPins(t, addr))
+}
+
+func TestMinVersion(t *testing.T) {
+	cfg := mustLoad(t, `
+    min_version: TLSv1.3
+    cipher_suites: [TLS-AES-256-GCM-SHA384, TLS-CHACHA20-POLY1305-SHA256]
+    curve_types: [X25519, P-256]
+  `)
+	tmp, err := LoadTLSConfig(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []transport.TLSVersion{transport.TLSVersion13}, tmp.Versions)
+	assert.Equal(t, []tls.CurveID{tls.X25519, tls.CurveP256}, tmp.CurvePreferences)
+
+	tlsCfg := tmp.BuildModuleConfig("localhost")
+	assert.Equal(t, uint16(tls.VersionTLS13), tlsCfg.MinVersion)
+	assert.Equal(t, uint16(tls.VersionTLS13), tlsCfg.MaxVersion)
+
+	cfg = mustLoad(t, `
+    min_version: TLSv1.2
+    cipher_suites: [ECDHE-RSA-AES-128-GCM-SHA256, TLS-AES-128-GCM-SHA256]
+  `)
+	tmp, err = LoadTLSConfig(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []transport.TLSVersion{transport.TLSVersion12, transport.TLSVersion13}, tmp.Versions)
+}
+
+// startVersionTestServer starts a TLS server presenting the test certificate,
+// supporting TLS versions up to maxVersion.
+func startVersionTestServer(t *testing.T, maxVersion uint16) (string, func()) {
+	cert, err := tls.LoadX509KeyPair("logstash/ca_test.pem", "logstash/ca_test.key")
+	require.NoError(t, err)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MaxVersion:   maxVersion,
+	})
+	require.NoError(t, err)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+	return listener.Addr().String(), func() { listener.Close() }
+}
+
+func dialWithConfig(t *testing.T, addr, yamlStr string) (tls.ConnectionState, error) {
+	tmp, err := LoadTLSConfig(mustLoad(t, yamlStr))
+	require.NoError(t, err)
+
+	timeout := 5 * time.Second
+	dialer, err := transport.TLSDialer(transport.NetDialer(timeout), tmp, timeout)
+	require.NoError(t, err)
+
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		return tls.ConnectionState{}, err
+	}
+	defer conn.Close()
+	return conn.(*tls.Conn).ConnectionState(), nil
+}
+
+func TestMinVersionTLS13RejectsTLS12Peer(t *testing.T) {
+	addr, stop := startVersionTestServer(t, tls.VersionTLS12)
+	defer stop()
+
+	_, err := dialWithConfig(t, addr, "{verification_mode: none, min_version: TLSv1.3}")
+	assert.Error(t, err)
+
+	// the peer is accepted without the TLS 1.3 floor
+	st, err := dialWithConfig(t, addr, "{verification_mode: none, min_version: TLSv1.2}")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), st.Version)
+}
+
+func TestMinVersionTLS13AcceptsTLS13Peer(t *testing.T) {
+	addr, stop := startVersionTestServer(t, tls.VersionTLS13)
+	defer stop()
+
+	st, err := dialWithConfig(t, addr, `
+    verification_mode: none
+    min_version: TLSv1.3
+    cipher_suites: [TLS-AES-128-GCM-SHA256, TLS-AES-256-GCM-SHA384, TLS-CHACHA20-POLY1305-SHA256]
+  `)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), st.Version)
+}
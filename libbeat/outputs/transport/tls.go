@@ -1,8 +1,10 @@
 package transport
 
 import (
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"net"
@@ -33,7 +35,9 @@ type TLSConfig struct {
 	RootCAs *x509.CertPool
 
 	// List of supported cipher suites. If nil, a default list provided by the
-	// implementation will be used.
+	// implementation will be used. The cipher suite negotiated is checked
+	// after the handshake, as TLS 1.3 cipher suites can not be restricted
+	// during the handshake.
 	CipherSuites []uint16
 
 	// Types of elliptic curves that will be used in an ECDHE handshake. If empty,
@@ -43,6 +47,16 @@ type TLSConfig struct {
 	// Renegotiation controls what types of renegotiation are supported.
 	// The default, never, is correct for the vast majority of applications.
 	Renegotiation tls.RenegotiationSupport
+
+	// List of base64 encoded SHA-256 fingerprints of the subject public key
+	// info (SPKI) of accepted server certificates. If set, the server
+	// certificate must match one of the pins, in addition to the verification
+	// configured by Verification.
+	CASha256 []string
+
+	// NextProtos are the application protocols offered during the handshake,
+	// like h2 for outputs using HTTP/2.
+	NextProtos []string
 }
 
 type TLSVersion uint16
@@ -52,6 +66,7 @@ const (
 	TLSVersion10    TLSVersion = tls.VersionTLS10
 	TLSVersion11    TLSVersion = tls.VersionTLS11
 	TLSVersion12    TLSVersion = tls.VersionTLS12
+	TLSVersion13    TLSVersion = tls.VersionTLS13
 )
 
 type TLSVerificationMode uint8
@@ -196,9 +211,29 @@ func postVerifyTLSConnection(d testing.Driver, conn *tls.Conn, config *TLSConfig
 		return err
 	}
 
+	if err := checkCipherSuite(st, config.CipherSuites); err != nil {
+		d.Fatal("TLS cipher suite", err)
+		return err
+	}
+
 	return nil
 }
 
+// checkCipherSuite returns an error if the cipher suite of the connection is
+// not one of the configured cipher suites. All cipher suites are accepted if
+// none are configured.
+func checkCipherSuite(st tls.ConnectionState, suites []uint16) error {
+	if len(suites) == 0 {
+		return nil
+	}
+	for _, suite := range suites {
+		if st.CipherSuite == suite {
+			return nil
+		}
+	}
+	return fmt.Errorf("tls cipher suite %v not configured", tls.CipherSuiteName(st.CipherSuite))
+}
+
 func (c *TLSConfig) BuildModuleConfig(host string) *tls.Config {
 	if c == nil {
 		// use default TLS settings, if config is empty.
@@ -227,7 +262,7 @@ func (c *TLSConfig) BuildModuleConfig(host string) *tls.Config {
 		logp.Warn("SSL/TLS verifications disabled.")
 	}
 
-	return &tls.Config{
+	tlsConfig := &tls.Config{
 		ServerName:         host,
 		MinVersion:         minVersion,
 		MaxVersion:         maxVersion,
@@ -236,6 +271,47 @@ func (c *TLSConfig) BuildModuleConfig(host string) *tls.Config {
 		InsecureSkipVerify: insecure,
 		CipherSuites:       c.CipherSuites,
 		CurvePreferences:   c.CurvePreferences,
+		NextProtos:         c.NextProtos,
+	}
+	if len(c.CASha256) > 0 {
+		// VerifyPeerCertificate is called after the chain has been verified,
+		// or without verification if verification is disabled.
+		tlsConfig.VerifyPeerCertificate = verifyPins(c.CASha256)
+	}
+	return tlsConfig
+}
+
+// ErrPinMismatch indicates the server certificate not matching any of the
+// configured pins.
+var ErrPinMismatch = errors.New("server certificate does not match any of the ca_sha256 pins")
+
+// SPKIFingerprint returns the base64 encoded SHA-256 fingerprint of the
+// subject public key info of the certificate, as used by CASha256.
+func SPKIFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// verifyPins creates the callback requiring the server certificate to match
+// one of the pins.
+func verifyPins(pins []string) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("no server certificate to verify the ca_sha256 pins")
+		}
+
+		cert, err := x509.ParseCertificate(rawCerts[0])
+		if err != nil {
+			return err
+		}
+
+		fingerprint := SPKIFingerprint(cert)
+		for _, pin := range pins {
+			if pin == fingerprint {
+				return nil
+			}
+		}
+		return ErrPinMismatch
 	}
 }
 
@@ -246,6 +322,7 @@ var tlsProtocolVersions = map[string]TLSVersion{
 	"TLSv1.0": TLSVersion10,
 	"TLSv1.1": TLSVersion11,
 	"TLSv1.2": TLSVersion12,
+	"TLSv1.3": TLSVersion13,
 }
 
 func (v TLSVersion) String() string {
@@ -254,6 +331,7 @@ func (v TLSVersion) String() string {
 		TLSVersion10:    "TLSv1.0",
 		TLSVersion11:    "TLSv1.1",
 		TLSVersion12:    "TLSv1.2",
+		TLSVersion13:    "TLSv1.3",
 	}
 	if s, ok := versions[v]; ok {
 		return s
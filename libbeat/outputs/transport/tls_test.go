@@ -0,0 +1,18 @@
+// +build !integration
+
+package transport
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckCipherSuite(t *testing.T) {
+	st := tls.ConnectionState{CipherSuite: tls.TLS_AES_256_GCM_SHA384}
+
+	assert.NoError(t, checkCipherSuite(st, nil))
+	assert.NoError(t, checkCipherSuite(st, []uint16{tls.TLS_AES_128_GCM_SHA256, tls.TLS_AES_256_GCM_SHA384}))
+	assert.Error(t, checkCipherSuite(st, []uint16{tls.TLS_AES_128_GCM_SHA256}))
+}
@@ -0,0 +1,46 @@
+package actions
+
+import (
+	"fmt"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+type addFields struct {
+	fields common.MapStr
+	config processors.FieldsConfig
+}
+
+type addFieldsConfig struct {
+	Fields                  common.MapStr `config:"fields" validate:"required"`
+	processors.FieldsConfig `config:",inline"`
+}
+
+func init() {
+	processors.RegisterPlugin("add_fields",
+		configChecked(newAddFields,
+			requireFields("fields"),
+			allowedFields("fields", "fields_under_root", "when")))
+}
+
+func newAddFields(c *common.Config) (processors.Processor, error) {
+	config := addFieldsConfig{}
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("fail to unpack the add_fields configuration: %s", err)
+	}
+
+	return &addFields{fields: config.Fields, config: config.FieldsConfig}, nil
+}
+
+// Run adds the configured fields under the `fields` key, or to the root of
+// the event if fields_under_root is set.
+func (f *addFields) Run(event *beat.Event) (*beat.Event, error) {
+	err := f.config.AddFields(event, common.FieldsKey, f.fields.Clone())
+	return event, err
+}
+
+func (f *addFields) String() string {
+	return fmt.Sprintf("add_fields=[fields=%v, fields_under_root=%v]", f.fields.String(), f.config.FieldsUnderRoot)
+}
@@ -0,0 +1,65 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func TestAddFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		expected common.MapStr
+	}{
+		{
+			name:   "nested under fields",
+			config: map[string]interface{}{"fields": map[string]interface{}{"env": "prod", "team": "web"}},
+			expected: common.MapStr{
+				"message": "hello",
+				"fields":  common.MapStr{"env": "prod", "team": "web", "existing": true},
+			},
+		},
+		{
+			name: "under root",
+			config: map[string]interface{}{
+				"fields":            map[string]interface{}{"env": "prod", "service": map[string]interface{}{"name": "nginx"}},
+				"fields_under_root": true,
+			},
+			expected: common.MapStr{
+				"message": "hello",
+				"env":     "prod",
+				"service": common.MapStr{"name": "nginx"},
+				"fields":  common.MapStr{"existing": true},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		config, err := common.NewConfigFrom(test.config)
+		require.NoError(t, err)
+
+		p, err := newAddFields(config)
+		require.NoError(t, err, test.name)
+
+		event := &beat.Event{Fields: common.MapStr{
+			"message": "hello",
+			"fields":  common.MapStr{"existing": true},
+		}}
+		event, err = p.Run(event)
+		require.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, event.Fields, test.name)
+	}
+}
+
+func TestAddFieldsRequiresFields(t *testing.T) {
+	config, err := common.NewConfigFrom(map[string]interface{}{"fields_under_root": true})
+	require.NoError(t, err)
+
+	_, err = newAddFields(config)
+	assert.Error(t, err)
+}
@@ -0,0 +1,152 @@
+package actions
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+type anonymizeFields struct {
+	fields         []string
+	key            []byte
+	hash           func() hash.Hash
+	hashName       string
+	preserveFormat bool
+}
+
+type anonymizeFieldsConfig struct {
+	Fields         []string `config:"fields" validate:"required"`
+	Key            string   `config:"key" validate:"required"`
+	Hash           string   `config:"hash"`
+	PreserveFormat bool     `config:"preserve_format"`
+}
+
+var anonymizeHashes = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+func init() {
+	processors.RegisterPlugin("anonymize_fields",
+		configChecked(newAnonymizeFields,
+			requireFields("fields", "key"),
+			allowedFields("fields", "key", "hash", "preserve_format", "when")))
+}
+
+func newAnonymizeFields(c *common.Config) (processors.Processor, error) {
+	config := anonymizeFieldsConfig{
+		Hash: "sha256",
+	}
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("fail to unpack the anonymize_fields configuration: %s", err)
+	}
+
+	for _, field := range config.Fields {
+		for _, readOnly := range processors.MandatoryExportedFields {
+			if field == readOnly {
+				return nil, fmt.Errorf("%s is a read only field, cannot override", readOnly)
+			}
+		}
+	}
+
+	hashName := strings.ToLower(config.Hash)
+	h, found := anonymizeHashes[hashName]
+	if !found {
+		return nil, fmt.Errorf("'%s' is not a valid hash for the anonymize_fields "+
+			"processor. Valid options are 'sha256' and 'sha512'", config.Hash)
+	}
+
+	return &anonymizeFields{
+		fields:         config.Fields,
+		key:            []byte(config.Key),
+		hash:           h,
+		hashName:       hashName,
+		preserveFormat: config.PreserveFormat,
+	}, nil
+}
+
+func (f *anonymizeFields) Run(event *beat.Event) (*beat.Event, error) {
+	var errs []string
+
+	for _, field := range f.fields {
+		err := f.anonymizeField(event, field)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return event, errors.New(strings.Join(errs, ", "))
+	}
+	return event, nil
+}
+
+func (f *anonymizeFields) anonymizeField(event *beat.Event, field string) error {
+	fieldValue, err := event.GetValue(field)
+	if err != nil {
+		if errors.Cause(err) == common.ErrKeyNotFound {
+			return nil
+		}
+		return err
+	}
+
+	var value interface{}
+	switch v := fieldValue.(type) {
+	case string:
+		value = f.tokenize(v)
+	case []string:
+		tokens := make([]string, len(v))
+		for i, s := range v {
+			tokens[i] = f.tokenize(s)
+		}
+		value = tokens
+	case []interface{}:
+		tokens := make([]string, len(v))
+		for i, elem := range v {
+			s, ok := elem.(string)
+			if !ok {
+				return fmt.Errorf("could not get a string from field '%s'", field)
+			}
+			tokens[i] = f.tokenize(s)
+		}
+		value = tokens
+	default:
+		return fmt.Errorf("could not get a string from field '%s'", field)
+	}
+
+	_, err = event.PutValue(field, value)
+	return err
+}
+
+// tokenize replaces a value with the hex encoded HMAC of the value. The same
+// value always yields the same token. If format preservation is enabled, only
+// the local part of email addresses is replaced, keeping the domain.
+func (f *anonymizeFields) tokenize(value string) string {
+	if f.preserveFormat {
+		if at := strings.LastIndex(value, "@"); at > 0 && at < len(value)-1 {
+			return f.hmac(value[:at]) + value[at:]
+		}
+	}
+	return f.hmac(value)
+}
+
+func (f *anonymizeFields) hmac(value string) string {
+	mac := hmac.New(f.hash, f.key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (f *anonymizeFields) String() string {
+	return fmt.Sprintf("anonymize_fields=[fields=%s, hash=%s, preserve_format=%v]",
+		strings.Join(f.fields, ", "), f.hashName, f.preserveFormat)
+}
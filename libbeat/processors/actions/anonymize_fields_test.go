@@ -0,0 +1,134 @@
+package actions
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func TestAnonymizeFieldsDeterministic(t *testing.T) {
+	config, _ := common.NewConfigFrom(map[string]interface{}{
+		"fields": []string{"user", "client.ip"},
+		"key":    "secret",
+	})
+
+	run := func(user, ip string) common.MapStr {
+		actual, err := runAnonymizeFields(t, config, common.MapStr{
+			"user":   user,
+			"client": common.MapStr{"ip": ip},
+		})
+		require.NoError(t, err)
+		return actual
+	}
+
+	first := run("alice", "10.0.0.1")
+	second := run("alice", "10.0.0.1")
+	other := run("bob", "10.0.0.1")
+
+	assert.Equal(t, first, second)
+	assert.NotEqual(t, "alice", first["user"])
+	assert.Len(t, first["user"], 64)
+	assert.NotEqual(t, first["user"], other["user"])
+	assert.Equal(t, first["client"], other["client"])
+
+	// Another key yields other tokens for the same input.
+	otherConfig, _ := common.NewConfigFrom(map[string]interface{}{
+		"fields": []string{"user"},
+		"key":    "other secret",
+	})
+	actual, err := runAnonymizeFields(t, otherConfig, common.MapStr{"user": "alice"})
+	require.NoError(t, err)
+	assert.NotEqual(t, first["user"], actual["user"])
+}
+
+func TestAnonymizeFieldsPreserveFormat(t *testing.T) {
+	config, _ := common.NewConfigFrom(map[string]interface{}{
+		"fields":          []string{"email", "user"},
+		"key":             "secret",
+		"preserve_format": true,
+	})
+
+	actual, err := runAnonymizeFields(t, config, common.MapStr{
+		"email": "alice@example.com",
+		"user":  "alice",
+	})
+	require.NoError(t, err)
+
+	email := actual["email"].(string)
+	assert.True(t, strings.HasSuffix(email, "@example.com"), email)
+	assert.NotContains(t, email, "alice")
+
+	// The local part is tokenized the same way as a plain value.
+	assert.Equal(t, actual["user"].(string)+"@example.com", email)
+
+	actual, err = runAnonymizeFields(t, config, common.MapStr{"email": "alice@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, email, actual["email"])
+}
+
+func TestAnonymizeFieldsArrays(t *testing.T) {
+	config, _ := common.NewConfigFrom(map[string]interface{}{
+		"fields": []string{"users"},
+		"key":    "secret",
+		"hash":   "sha512",
+	})
+
+	actual, err := runAnonymizeFields(t, config, common.MapStr{
+		"users": []interface{}{"alice", "bob", "alice"},
+	})
+	require.NoError(t, err)
+
+	users := actual["users"].([]string)
+	require.Len(t, users, 3)
+	assert.Len(t, users[0], 128)
+	assert.Equal(t, users[0], users[2])
+	assert.NotEqual(t, users[0], users[1])
+}
+
+func TestAnonymizeFieldsMissingOrInvalid(t *testing.T) {
+	config, _ := common.NewConfigFrom(map[string]interface{}{
+		"fields": []string{"user"},
+		"key":    "secret",
+	})
+
+	actual, err := runAnonymizeFields(t, config, common.MapStr{"message": "hello"})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{"message": "hello"}, actual)
+
+	actual, err = runAnonymizeFields(t, config, common.MapStr{"user": 42})
+	assert.Error(t, err)
+	assert.Equal(t, common.MapStr{"user": 42}, actual)
+}
+
+func TestAnonymizeFieldsInvalidConfig(t *testing.T) {
+	tests := []map[string]interface{}{
+		{"fields": []string{"user"}},
+		{"key": "secret"},
+		{"fields": []string{"user"}, "key": ""},
+		{"fields": []string{"user"}, "key": "secret", "hash": "md5"},
+		{"fields": []string{"type"}, "key": "secret"},
+	}
+
+	for _, test := range tests {
+		cfg, err := common.NewConfigFrom(test)
+		require.NoError(t, err)
+
+		_, err = configChecked(newAnonymizeFields, requireFields("fields", "key"))(cfg)
+		assert.Error(t, err, "config: %v", test)
+	}
+}
+
+func runAnonymizeFields(t *testing.T, config *common.Config, input common.MapStr) (common.MapStr, error) {
+	p, err := newAnonymizeFields(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := p.Run(&beat.Event{Fields: input})
+	return actual.Fields, err
+}
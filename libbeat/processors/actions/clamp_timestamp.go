@@ -0,0 +1,124 @@
+package actions
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+type clampTimestamp struct {
+	maxFuture     time.Duration
+	maxPast       time.Duration
+	drop          bool
+	tag           string
+	originalField string
+
+	now func() time.Time
+}
+
+type clampTimestampConfig struct {
+	MaxFuture     time.Duration `config:"max_future" validate:"min=0"`
+	MaxPast       time.Duration `config:"max_past" validate:"min=0"`
+	Action        string        `config:"action"`
+	Tag           string        `config:"tag"`
+	OriginalField string        `config:"original_field"`
+}
+
+const (
+	clampActionClamp = "clamp"
+	clampActionDrop  = "drop"
+)
+
+func init() {
+	processors.RegisterPlugin("clamp_timestamp",
+		configChecked(newClampTimestamp,
+			allowedFields("max_future", "max_past", "action", "tag", "original_field", "when")))
+}
+
+func newClampTimestamp(c *common.Config) (processors.Processor, error) {
+	config := clampTimestampConfig{
+		MaxFuture: time.Minute,
+		Action:    clampActionClamp,
+		Tag:       "_timestamp_clamped",
+	}
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("fail to unpack the clamp_timestamp configuration: %s", err)
+	}
+
+	var drop bool
+	switch strings.ToLower(config.Action) {
+	case clampActionClamp:
+		drop = false
+	case clampActionDrop:
+		drop = true
+	default:
+		return nil, fmt.Errorf("'%s' is not a valid action for the clamp_timestamp "+
+			"processor. Valid actions are 'clamp' and 'drop'", config.Action)
+	}
+
+	for _, readOnly := range processors.MandatoryExportedFields {
+		if config.OriginalField == readOnly {
+			return nil, fmt.Errorf("%s is a read only field, cannot override", readOnly)
+		}
+	}
+
+	return &clampTimestamp{
+		maxFuture:     config.MaxFuture,
+		maxPast:       config.MaxPast,
+		drop:          drop,
+		tag:           config.Tag,
+		originalField: config.OriginalField,
+		now:           time.Now,
+	}, nil
+}
+
+// Run moves the timestamp of events into the window of max_future after and
+// max_past before the current time. Timestamps in the future beyond the
+// allowed skew are set to the current time, timestamps older than max_past to
+// the start of the window. A max_past of 0 accepts any past timestamp.
+func (c *clampTimestamp) Run(event *beat.Event) (*beat.Event, error) {
+	now := c.now()
+
+	var clamped time.Time
+	switch ts := event.Timestamp; {
+	case ts.After(now.Add(c.maxFuture)):
+		clamped = now
+	case c.maxPast > 0 && ts.Before(now.Add(-c.maxPast)):
+		clamped = now.Add(-c.maxPast)
+	default:
+		return event, nil
+	}
+
+	if c.drop {
+		return nil, nil
+	}
+
+	if c.originalField != "" {
+		if _, err := event.PutValue(c.originalField, common.Time(event.Timestamp)); err != nil {
+			return event, err
+		}
+	}
+	event.Timestamp = clamped
+
+	if c.tag != "" {
+		if event.Fields == nil {
+			event.Fields = common.MapStr{}
+		}
+		if err := common.AddTags(event.Fields, []string{c.tag}); err != nil {
+			return event, err
+		}
+	}
+	return event, nil
+}
+
+func (c *clampTimestamp) String() string {
+	action := clampActionClamp
+	if c.drop {
+		action = clampActionDrop
+	}
+	return fmt.Sprintf("clamp_timestamp=[max_future=%v, max_past=%v, action=%s]", c.maxFuture, c.maxPast, action)
+}
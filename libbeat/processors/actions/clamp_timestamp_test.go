@@ -0,0 +1,150 @@
+package actions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func TestClampTimestamp(t *testing.T) {
+	now := time.Date(2018, 1, 10, 12, 0, 0, 0, time.UTC)
+	config, _ := common.NewConfigFrom(map[string]interface{}{
+		"max_future": "5m",
+		"max_past":   "168h",
+	})
+
+	tests := []struct {
+		name      string
+		timestamp time.Time
+		expected  time.Time
+		corrected bool
+	}{
+		{
+			name:      "in window",
+			timestamp: now.Add(-time.Hour),
+			expected:  now.Add(-time.Hour),
+		},
+		{
+			name:      "future within skew",
+			timestamp: now.Add(4 * time.Minute),
+			expected:  now.Add(4 * time.Minute),
+		},
+		{
+			name:      "future beyond skew",
+			timestamp: now.Add(24 * time.Hour),
+			expected:  now,
+			corrected: true,
+		},
+		{
+			name:      "ancient",
+			timestamp: time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC),
+			expected:  now.Add(-168 * time.Hour),
+			corrected: true,
+		},
+	}
+
+	for _, test := range tests {
+		event := runClampTimestamp(t, config, now, &beat.Event{
+			Timestamp: test.timestamp,
+			Fields:    common.MapStr{"message": "test"},
+		})
+		require.NotNil(t, event, test.name)
+
+		assert.Equal(t, test.expected, event.Timestamp, test.name)
+		if test.corrected {
+			assert.Equal(t, []string{"_timestamp_clamped"}, event.Fields["tags"], test.name)
+		} else {
+			assert.NotContains(t, event.Fields, "tags", test.name)
+		}
+	}
+}
+
+func TestClampTimestampNoMaxPast(t *testing.T) {
+	now := time.Date(2018, 1, 10, 12, 0, 0, 0, time.UTC)
+	config := common.NewConfig()
+
+	ancient := time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+	event := runClampTimestamp(t, config, now, &beat.Event{Timestamp: ancient, Fields: common.MapStr{}})
+	assert.Equal(t, ancient, event.Timestamp)
+
+	// The default max_future is 1m.
+	event = runClampTimestamp(t, config, now, &beat.Event{Timestamp: now.Add(2 * time.Minute), Fields: common.MapStr{}})
+	assert.Equal(t, now, event.Timestamp)
+}
+
+func TestClampTimestampOriginalFieldAndTag(t *testing.T) {
+	now := time.Date(2018, 1, 10, 12, 0, 0, 0, time.UTC)
+	config, _ := common.NewConfigFrom(map[string]interface{}{
+		"tag":            "clock_skew",
+		"original_field": "event.original_timestamp",
+	})
+
+	future := now.Add(time.Hour)
+	event := runClampTimestamp(t, config, now, &beat.Event{
+		Timestamp: future,
+		Fields:    common.MapStr{"tags": []string{"web"}},
+	})
+	assert.Equal(t, now, event.Timestamp)
+	assert.Equal(t, []string{"web", "clock_skew"}, event.Fields["tags"])
+
+	original, err := event.GetValue("event.original_timestamp")
+	require.NoError(t, err)
+	assert.Equal(t, common.Time(future), original)
+}
+
+func TestClampTimestampDrop(t *testing.T) {
+	now := time.Date(2018, 1, 10, 12, 0, 0, 0, time.UTC)
+	config, _ := common.NewConfigFrom(map[string]interface{}{
+		"action":   "drop",
+		"max_past": "24h",
+	})
+
+	tests := []struct {
+		name      string
+		timestamp time.Time
+		dropped   bool
+	}{
+		{name: "future", timestamp: now.Add(time.Hour), dropped: true},
+		{name: "past", timestamp: now.Add(-48 * time.Hour), dropped: true},
+		{name: "in window", timestamp: now},
+	}
+
+	for _, test := range tests {
+		event := runClampTimestamp(t, config, now, &beat.Event{Timestamp: test.timestamp, Fields: common.MapStr{}})
+		assert.Equal(t, test.dropped, event == nil, test.name)
+	}
+}
+
+func TestClampTimestampInvalidConfig(t *testing.T) {
+	for _, config := range []map[string]interface{}{
+		{"action": "reject"},
+		{"max_future": "-1m"},
+	} {
+		c, err := common.NewConfigFrom(config)
+		require.NoError(t, err)
+
+		_, err = newClampTimestamp(c)
+		assert.Error(t, err, "%v", config)
+	}
+}
+
+// runClampTimestamp runs a new clamp_timestamp processor on the event, with
+// now being the current time.
+func runClampTimestamp(t *testing.T, config *common.Config, now time.Time, event *beat.Event) *beat.Event {
+	p, err := newClampTimestamp(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.(*clampTimestamp).now = func() time.Time { return now }
+
+	actual, err := p.Run(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return actual
+}
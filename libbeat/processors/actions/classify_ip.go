@@ -0,0 +1,202 @@
+package actions
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+type classifyIP struct {
+	fields        []string
+	ignoreMissing bool
+}
+
+type classifyIPConfig struct {
+	Fields        []string `config:"fields" validate:"required"`
+	IgnoreMissing bool     `config:"ignore_missing"`
+}
+
+// IP address types reported by the classify_ip processor.
+const (
+	ipTypePublic    = "public"
+	ipTypePrivate   = "private"
+	ipTypeLoopback  = "loopback"
+	ipTypeLinkLocal = "link-local"
+	ipTypeMulticast = "multicast"
+	ipTypeReserved  = "reserved"
+)
+
+// ipTypeSuffix is added to the name of a field to get the field the type of
+// the address is written to, e.g. `source.ip` -> `source.ip_type`.
+const ipTypeSuffix = "_type"
+
+type ipNetType struct {
+	net    *net.IPNet
+	ipType string
+}
+
+// ipv4Types and ipv6Types classify the blocks of the IANA IPv4 and IPv6
+// Special-Purpose Address Registries (RFC 6890). Addresses not contained in
+// any block are public, except for IPv6 addresses outside of the global
+// unicast range 2000::/3, which are reserved by the IETF.
+var (
+	ipv4Types = mustIPNetTypes([][2]string{
+		{"0.0.0.0/8", ipTypeReserved},       // "this" network
+		{"10.0.0.0/8", ipTypePrivate},       // private-use
+		{"100.64.0.0/10", ipTypePrivate},    // shared address space
+		{"127.0.0.0/8", ipTypeLoopback},     // loopback
+		{"169.254.0.0/16", ipTypeLinkLocal}, // link local
+		{"172.16.0.0/12", ipTypePrivate},    // private-use
+		{"192.0.0.0/24", ipTypeReserved},    // IETF protocol assignments
+		{"192.0.2.0/24", ipTypeReserved},    // documentation (TEST-NET-1)
+		{"192.88.99.0/24", ipTypeReserved},  // 6to4 relay anycast
+		{"192.168.0.0/16", ipTypePrivate},   // private-use
+		{"198.18.0.0/15", ipTypeReserved},   // benchmarking
+		{"198.51.100.0/24", ipTypeReserved}, // documentation (TEST-NET-2)
+		{"203.0.113.0/24", ipTypeReserved},  // documentation (TEST-NET-3)
+		{"224.0.0.0/4", ipTypeMulticast},    // multicast
+		{"240.0.0.0/4", ipTypeReserved},     // reserved, limited broadcast
+	})
+	ipv6Types = mustIPNetTypes([][2]string{
+		{"::/128", ipTypeReserved},        // unspecified address
+		{"::1/128", ipTypeLoopback},       // loopback
+		{"64:ff9b::/96", ipTypePublic},    // IPv4-IPv6 translation
+		{"64:ff9b:1::/48", ipTypePrivate}, // IPv4-IPv6 translation, local use
+		{"100::/64", ipTypeReserved},      // discard-only
+		{"2001::/23", ipTypeReserved},     // IETF protocol assignments
+		{"2001:db8::/32", ipTypeReserved}, // documentation
+		{"2002::/16", ipTypePublic},       // 6to4
+		{"2000::/3", ipTypePublic},        // global unicast
+		{"fc00::/7", ipTypePrivate},       // unique local
+		{"fe80::/10", ipTypeLinkLocal},    // link-local unicast
+		{"ff00::/8", ipTypeMulticast},     // multicast
+	})
+)
+
+func init() {
+	processors.RegisterPlugin("classify_ip",
+		configChecked(newClassifyIP,
+			requireFields("fields"),
+			allowedFields("fields", "ignore_missing", "when")))
+}
+
+func newClassifyIP(c *common.Config) (processors.Processor, error) {
+	config := classifyIPConfig{}
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("fail to unpack the classify_ip configuration: %s", err)
+	}
+
+	for _, field := range config.Fields {
+		for _, readOnly := range processors.MandatoryExportedFields {
+			if field+ipTypeSuffix == readOnly {
+				return nil, fmt.Errorf("%s is a read only field, cannot override", readOnly)
+			}
+		}
+	}
+
+	return &classifyIP{
+		fields:        config.Fields,
+		ignoreMissing: config.IgnoreMissing,
+	}, nil
+}
+
+// Run writes the type of the address in each field to the field with the
+// `_type` suffix. Missing fields are an error, unless ignore_missing is set.
+func (f *classifyIP) Run(event *beat.Event) (*beat.Event, error) {
+	var errs []string
+
+	for _, field := range f.fields {
+		if err := f.classifyField(event, field); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return event, errors.New(strings.Join(errs, ", "))
+	}
+	return event, nil
+}
+
+func (f *classifyIP) classifyField(event *beat.Event, field string) error {
+	value, err := event.GetValue(field)
+	if err != nil {
+		if f.ignoreMissing && errors.Cause(err) == common.ErrKeyNotFound {
+			return nil
+		}
+		return fmt.Errorf("could not classify field '%s': %v", field, err)
+	}
+
+	var ip net.IP
+	switch v := value.(type) {
+	case string:
+		ip = net.ParseIP(v)
+	case net.IP:
+		ip = v
+	default:
+		return fmt.Errorf("could not classify field '%s': unsupported value type %T", field, value)
+	}
+	if ip == nil {
+		return fmt.Errorf("could not classify field '%s': '%v' is not an IP address", field, value)
+	}
+
+	if _, err := event.PutValue(field+ipTypeSuffix, classifyIPAddress(ip)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// classifyIPAddress returns the type of the address. IPv4-mapped IPv6
+// addresses are classified as IPv4 addresses.
+func classifyIPAddress(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		if t, found := lookupIPType(ipv4Types, ip4); found {
+			return t
+		}
+		return ipTypePublic
+	}
+
+	if t, found := lookupIPType(ipv6Types, ip); found {
+		return t
+	}
+	return ipTypeReserved
+}
+
+// lookupIPType returns the type of the most specific block containing the
+// address.
+func lookupIPType(types []ipNetType, ip net.IP) (string, bool) {
+	var (
+		ipType string
+		bits   = -1
+	)
+	for _, t := range types {
+		if !t.net.Contains(ip) {
+			continue
+		}
+		if ones, _ := t.net.Mask.Size(); ones > bits {
+			ipType, bits = t.ipType, ones
+		}
+	}
+	return ipType, bits >= 0
+}
+
+func mustIPNetTypes(blocks [][2]string) []ipNetType {
+	types := make([]ipNetType, 0, len(blocks))
+	for _, b := range blocks {
+		_, ipNet, err := net.ParseCIDR(b[0])
+		if err != nil {
+			panic(err)
+		}
+		types = append(types, ipNetType{net: ipNet, ipType: b[1]})
+	}
+	return types
+}
+
+func (f *classifyIP) String() string {
+	return fmt.Sprintf("classify_ip=[fields=%s]", strings.Join(f.fields, ","))
+}
@@ -0,0 +1,149 @@
+package actions
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func TestClassifyIPAddress(t *testing.T) {
+	tests := map[string]string{
+		// IPv4
+		"8.8.8.8":         ipTypePublic,
+		"203.0.114.1":     ipTypePublic,
+		"10.1.2.3":        ipTypePrivate,
+		"172.16.0.1":      ipTypePrivate,
+		"172.31.255.255":  ipTypePrivate,
+		"172.32.0.1":      ipTypePublic,
+		"192.168.1.1":     ipTypePrivate,
+		"100.64.0.1":      ipTypePrivate,
+		"127.0.0.1":       ipTypeLoopback,
+		"127.255.0.1":     ipTypeLoopback,
+		"169.254.10.20":   ipTypeLinkLocal,
+		"224.0.0.251":     ipTypeMulticast,
+		"239.255.255.250": ipTypeMulticast,
+		"0.0.0.0":         ipTypeReserved,
+		"192.0.2.1":       ipTypeReserved,
+		"198.51.100.7":    ipTypeReserved,
+		"203.0.113.9":     ipTypeReserved,
+		"198.18.0.1":      ipTypeReserved,
+		"240.0.0.1":       ipTypeReserved,
+		"255.255.255.255": ipTypeReserved,
+
+		// IPv6
+		"2a00:1450:4001:80b::200e": ipTypePublic,
+		"2002:c000:0204::1":        ipTypePublic,
+		"64:ff9b::808:808":         ipTypePublic,
+		"fd12:3456:789a::1":        ipTypePrivate,
+		"fc00::1":                  ipTypePrivate,
+		"64:ff9b:1::1":             ipTypePrivate,
+		"::1":                      ipTypeLoopback,
+		"fe80::1ff:fe23:4567:890a": ipTypeLinkLocal,
+		"ff02::1":                  ipTypeMulticast,
+		"ff05::1:3":                ipTypeMulticast,
+		"::":                       ipTypeReserved,
+		"2001:db8::1":              ipTypeReserved,
+		"2001::1":                  ipTypeReserved,
+		"100::1":                   ipTypeReserved,
+		"4000::1":                  ipTypeReserved,
+
+		// IPv4-mapped IPv6
+		"::ffff:10.0.0.1": ipTypePrivate,
+		"::ffff:8.8.4.4":  ipTypePublic,
+	}
+
+	for addr, expected := range tests {
+		ip := net.ParseIP(addr)
+		require.NotNil(t, ip, addr)
+		assert.Equal(t, expected, classifyIPAddress(ip), addr)
+	}
+}
+
+func TestClassifyIP(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		fields   common.MapStr
+		expected common.MapStr
+		err      bool
+	}{
+		{
+			name:   "all fields",
+			config: map[string]interface{}{"fields": []string{"source.ip", "destination.ip"}},
+			fields: common.MapStr{
+				"source":      common.MapStr{"ip": "192.168.0.10"},
+				"destination": common.MapStr{"ip": "2a00:1450:4001:80b::200e"},
+			},
+			expected: common.MapStr{
+				"source":      common.MapStr{"ip": "192.168.0.10", "ip_type": "private"},
+				"destination": common.MapStr{"ip": "2a00:1450:4001:80b::200e", "ip_type": "public"},
+			},
+		},
+		{
+			name:   "net.IP value",
+			config: map[string]interface{}{"fields": []string{"ip"}},
+			fields: common.MapStr{"ip": net.ParseIP("127.0.0.1")},
+			expected: common.MapStr{
+				"ip":      net.ParseIP("127.0.0.1"),
+				"ip_type": "loopback",
+			},
+		},
+		{
+			name:   "missing field",
+			config: map[string]interface{}{"fields": []string{"source.ip", "destination.ip"}},
+			fields: common.MapStr{"source": common.MapStr{"ip": "10.0.0.1"}},
+			expected: common.MapStr{
+				"source": common.MapStr{"ip": "10.0.0.1", "ip_type": "private"},
+			},
+			err: true,
+		},
+		{
+			name: "missing field ignored",
+			config: map[string]interface{}{
+				"fields":         []string{"source.ip", "destination.ip"},
+				"ignore_missing": true,
+			},
+			fields: common.MapStr{"source": common.MapStr{"ip": "10.0.0.1"}},
+			expected: common.MapStr{
+				"source": common.MapStr{"ip": "10.0.0.1", "ip_type": "private"},
+			},
+		},
+		{
+			name:     "invalid address",
+			config:   map[string]interface{}{"fields": []string{"ip"}, "ignore_missing": true},
+			fields:   common.MapStr{"ip": "not an ip"},
+			expected: common.MapStr{"ip": "not an ip"},
+			err:      true,
+		},
+		{
+			name:     "unsupported type",
+			config:   map[string]interface{}{"fields": []string{"ip"}},
+			fields:   common.MapStr{"ip": 42},
+			expected: common.MapStr{"ip": 42},
+			err:      true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg, err := common.NewConfigFrom(test.config)
+			require.NoError(t, err)
+
+			p, err := newClassifyIP(cfg)
+			require.NoError(t, err)
+
+			event, err := p.Run(&beat.Event{Fields: test.fields})
+			if test.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.expected, event.Fields)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package actions
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/cbor"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+type decodeCBORField struct {
+	field         string
+	target        string
+	base64        bool
+	maxDepth      int
+	ignoreMissing bool
+	failOnError   bool
+}
+
+type decodeCBORFieldConfig struct {
+	Field         string  `config:"field"`
+	Target        *string `config:"target"`
+	Encoding      string  `config:"encoding"`
+	MaxDepth      int     `config:"max_depth" validate:"min=1"`
+	IgnoreMissing bool    `config:"ignore_missing"`
+	FailOnError   bool    `config:"fail_on_error"`
+}
+
+func init() {
+	processors.RegisterPlugin("decode_cbor_field",
+		configChecked(newDecodeCBORField,
+			requireFields("field"),
+			allowedFields("field", "target", "encoding", "max_depth", "ignore_missing", "fail_on_error", "when")))
+}
+
+func newDecodeCBORField(c *common.Config) (processors.Processor, error) {
+	config := decodeCBORFieldConfig{
+		Encoding:    "base64",
+		MaxDepth:    cbor.DefaultMaxDepth,
+		FailOnError: true,
+	}
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("fail to unpack the decode_cbor_field configuration: %s", err)
+	}
+
+	if config.Encoding != "base64" && config.Encoding != "binary" {
+		return nil, fmt.Errorf("encoding of decode_cbor_field must be base64 or binary, found '%s'", config.Encoding)
+	}
+
+	// The decoded value replaces the field by default, an empty target writes
+	// the keys of a decoded map to the root of the event.
+	target := config.Field
+	if config.Target != nil {
+		target = *config.Target
+	}
+	for _, readOnly := range processors.MandatoryExportedFields {
+		if target == readOnly {
+			return nil, fmt.Errorf("%s is a read only field, cannot override", readOnly)
+		}
+	}
+
+	return &decodeCBORField{
+		field:         config.Field,
+		target:        target,
+		base64:        config.Encoding == "base64",
+		maxDepth:      config.MaxDepth,
+		ignoreMissing: config.IgnoreMissing,
+		failOnError:   config.FailOnError,
+	}, nil
+}
+
+func (f *decodeCBORField) Run(event *beat.Event) (*beat.Event, error) {
+	fieldValue, err := event.GetValue(f.field)
+	if err != nil {
+		if f.ignoreMissing && errors.Cause(err) == common.ErrKeyNotFound {
+			return event, nil
+		}
+		return f.fail(event, fmt.Errorf("could not get field '%s': %s", f.field, err))
+	}
+
+	// Binary values are decoded as is, strings are base64 encoded unless the
+	// encoding is binary.
+	var data []byte
+	switch v := fieldValue.(type) {
+	case []byte:
+		data = v
+	case string:
+		if !f.base64 {
+			data = []byte(v)
+			break
+		}
+		data, err = base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return f.fail(event, fmt.Errorf("fail to decode base64 from field '%s': %s", f.field, err))
+		}
+	default:
+		return f.fail(event, fmt.Errorf("could not get a string or bytes from field '%s'", f.field))
+	}
+
+	decoded, err := cbor.Decode(data, f.maxDepth)
+	if err != nil {
+		return f.fail(event, fmt.Errorf("fail to decode CBOR from field '%s': %s", f.field, err))
+	}
+
+	if f.target == "" {
+		fields, ok := decoded.(common.MapStr)
+		if !ok {
+			return f.fail(event, fmt.Errorf("CBOR of field '%s' is not a map, it cannot be written to the root of the event", f.field))
+		}
+		for _, readOnly := range processors.MandatoryExportedFields {
+			if _, found := fields[readOnly]; found {
+				return f.fail(event, fmt.Errorf("%s is a read only field, cannot override", readOnly))
+			}
+		}
+		event.Fields.DeepUpdate(fields)
+		return event, nil
+	}
+	if _, err := event.PutValue(f.target, decoded); err != nil {
+		return f.fail(event, err)
+	}
+	return event, nil
+}
+
+// fail returns the error if fail_on_error is enabled, otherwise the error is
+// logged and the event is passed on unchanged.
+func (f *decodeCBORField) fail(event *beat.Event, err error) (*beat.Event, error) {
+	if f.failOnError {
+		return event, err
+	}
+	debug("%s", err)
+	return event, nil
+}
+
+func (f *decodeCBORField) String() string {
+	return fmt.Sprintf("decode_cbor_field=[field=%s, target=%s]", f.field, f.target)
+}
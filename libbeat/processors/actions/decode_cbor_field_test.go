@@ -0,0 +1,149 @@
+package actions
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// {"device": "sensor-1", "readings": [21.5, {"unit": "C"}], "at": 1(1363896240)}
+const sensorCBOR = "a3666465766963656873656e736f722d316872656164696e677382f94d60a164756e69746143626174c11a514b67b0"
+
+func cborBytes(t *testing.T, h string) []byte {
+	data, err := hex.DecodeString(h)
+	require.NoError(t, err)
+	return data
+}
+
+func TestDecodeCBORField(t *testing.T) {
+	expected := common.MapStr{
+		"device":   "sensor-1",
+		"readings": []interface{}{21.5, common.MapStr{"unit": "C"}},
+		"at":       common.Time(time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)),
+	}
+	data := cborBytes(t, sensorCBOR)
+
+	// base64 encoded string
+	config, _ := common.NewConfigFrom(map[string]interface{}{"field": "payload", "target": "sensor"})
+	encoded := base64.StdEncoding.EncodeToString(data)
+	actual, err := runDecodeCBORField(t, config, common.MapStr{"payload": encoded})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{"payload": encoded, "sensor": expected}, actual)
+
+	// binary value
+	actual, err = runDecodeCBORField(t, config, common.MapStr{"payload": data})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual["sensor"])
+
+	// binary string
+	config, _ = common.NewConfigFrom(map[string]interface{}{"field": "payload", "encoding": "binary"})
+	actual, err = runDecodeCBORField(t, config, common.MapStr{"payload": string(data)})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{"payload": expected}, actual)
+}
+
+func TestDecodeCBORFieldTarget(t *testing.T) {
+	data := cborBytes(t, "a26161016162a1616302") // {"a": 1, "b": {"c": 2}}
+
+	config, _ := common.NewConfigFrom(map[string]interface{}{"field": "payload", "target": ""})
+	actual, err := runDecodeCBORField(t, config, common.MapStr{
+		"payload": data,
+		"b":       common.MapStr{"d": 3},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"payload": data,
+		"a":       int64(1),
+		"b":       common.MapStr{"c": int64(2), "d": 3},
+	}, actual)
+
+	// Only maps can be written to the root.
+	_, err = runDecodeCBORField(t, config, common.MapStr{"payload": cborBytes(t, "820102")})
+	assert.Error(t, err)
+
+	// Other values are written to the target.
+	config, _ = common.NewConfigFrom(map[string]interface{}{"field": "payload", "target": "values"})
+	actual, err = runDecodeCBORField(t, config, common.MapStr{"payload": cborBytes(t, "820102")})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{int64(1), int64(2)}, actual["values"])
+}
+
+func TestDecodeCBORFieldMaxDepth(t *testing.T) {
+	data := cborBytes(t, "a161618181a1616201") // {"a": [[{"b": 1}]]}
+
+	config, _ := common.NewConfigFrom(map[string]interface{}{"field": "payload", "target": "decoded", "max_depth": 4})
+	actual, err := runDecodeCBORField(t, config, common.MapStr{"payload": data})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"a": []interface{}{[]interface{}{common.MapStr{"b": int64(1)}}},
+	}, actual["decoded"])
+
+	config, _ = common.NewConfigFrom(map[string]interface{}{"field": "payload", "target": "decoded", "max_depth": 3})
+	actual, err = runDecodeCBORField(t, config, common.MapStr{"payload": data})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "CBOR nesting exceeds 3 levels")
+	}
+	assert.Equal(t, common.MapStr{"payload": data}, actual)
+}
+
+func TestDecodeCBORFieldErrors(t *testing.T) {
+	tests := map[string]interface{}{
+		"invalid base64": "not base64!",
+		"malformed CBOR": base64.StdEncoding.EncodeToString([]byte{0xa2, 0x61, 0x61}),
+		"not a string":   42,
+	}
+
+	for name, value := range tests {
+		config, _ := common.NewConfigFrom(map[string]interface{}{"field": "payload"})
+		actual, err := runDecodeCBORField(t, config, common.MapStr{"payload": value})
+		assert.Error(t, err, name)
+		assert.Equal(t, common.MapStr{"payload": value}, actual, name)
+
+		// The event is passed on unchanged without fail_on_error.
+		config, _ = common.NewConfigFrom(map[string]interface{}{"field": "payload", "fail_on_error": false})
+		actual, err = runDecodeCBORField(t, config, common.MapStr{"payload": value})
+		assert.NoError(t, err, name)
+		assert.Equal(t, common.MapStr{"payload": value}, actual, name)
+	}
+
+	config, _ := common.NewConfigFrom(map[string]interface{}{"field": "payload"})
+	_, err := runDecodeCBORField(t, config, common.MapStr{})
+	assert.Error(t, err)
+
+	config, _ = common.NewConfigFrom(map[string]interface{}{"field": "payload", "ignore_missing": true})
+	_, err = runDecodeCBORField(t, config, common.MapStr{})
+	assert.NoError(t, err)
+}
+
+func TestDecodeCBORFieldInvalidConfig(t *testing.T) {
+	tests := []map[string]interface{}{
+		{"field": "payload", "target": "type"},
+		{"field": "payload", "max_depth": 0},
+		{"field": "payload", "encoding": "hex"},
+	}
+
+	for _, test := range tests {
+		cfg, err := common.NewConfigFrom(test)
+		require.NoError(t, err)
+
+		_, err = newDecodeCBORField(cfg)
+		assert.Error(t, err, "config: %v", test)
+	}
+}
+
+func runDecodeCBORField(t *testing.T, config *common.Config, input common.MapStr) (common.MapStr, error) {
+	p, err := newDecodeCBORField(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := p.Run(&beat.Event{Fields: input})
+	return actual.Fields, err
+}
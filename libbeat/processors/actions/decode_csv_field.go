@@ -0,0 +1,161 @@
+package actions
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+type decodeCSVField struct {
+	field       string
+	separator   rune
+	columns     []string
+	target      string
+	failOnError bool
+}
+
+type decodeCSVFieldConfig struct {
+	Field       string   `config:"field"`
+	Separator   string   `config:"separator"`
+	ColumnNames []string `config:"column_names"`
+	Header      string   `config:"header"`
+	Target      *string  `config:"target"`
+	FailOnError bool     `config:"fail_on_error"`
+}
+
+func init() {
+	processors.RegisterPlugin("decode_csv_field",
+		configChecked(newDecodeCSVField,
+			requireFields("field"),
+			allowedFields("field", "separator", "column_names", "header", "target", "fail_on_error", "when")))
+}
+
+func newDecodeCSVField(c *common.Config) (processors.Processor, error) {
+	config := decodeCSVFieldConfig{
+		Separator:   ",",
+		FailOnError: true,
+	}
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("fail to unpack the decode_csv_field configuration: %s", err)
+	}
+
+	separator, size := utf8.DecodeRuneInString(config.Separator)
+	if size == 0 || size != len(config.Separator) {
+		return nil, fmt.Errorf("separator of decode_csv_field must be a single character, found '%s'", config.Separator)
+	}
+
+	columns := config.ColumnNames
+	switch {
+	case len(columns) > 0 && config.Header != "":
+		return nil, errors.New("decode_csv_field accepts either column_names or header, not both")
+	case config.Header != "":
+		var err error
+		columns, err = common.ParseCSVLine(config.Header, separator)
+		if err != nil {
+			return nil, fmt.Errorf("fail to parse the header of decode_csv_field: %s", err)
+		}
+	case len(columns) == 0:
+		return nil, errors.New("decode_csv_field requires column_names or header")
+	}
+
+	seen := map[string]bool{}
+	for _, column := range columns {
+		if column == "" {
+			return nil, errors.New("column names of decode_csv_field must not be empty")
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("duplicate column name '%s' in decode_csv_field", column)
+		}
+		seen[column] = true
+	}
+
+	// The columns replace the field by default, an empty target writes them
+	// to the root of the event.
+	target := config.Field
+	if config.Target != nil {
+		target = *config.Target
+	}
+	for _, readOnly := range processors.MandatoryExportedFields {
+		if target == readOnly || (target == "" && seen[readOnly]) {
+			return nil, fmt.Errorf("%s is a read only field, cannot override", readOnly)
+		}
+	}
+
+	return &decodeCSVField{
+		field:       config.Field,
+		separator:   separator,
+		columns:     columns,
+		target:      target,
+		failOnError: config.FailOnError,
+	}, nil
+}
+
+func (f *decodeCSVField) Run(event *beat.Event) (*beat.Event, error) {
+	fieldValue, err := event.GetValue(f.field)
+	if err != nil {
+		if errors.Cause(err) == common.ErrKeyNotFound {
+			return event, nil
+		}
+		return event, err
+	}
+
+	value, ok := fieldValue.(string)
+	if !ok {
+		return event, fmt.Errorf("could not get a string from field '%s'", f.field)
+	}
+
+	record, err := common.ParseCSVLine(value, f.separator)
+	if err != nil {
+		return f.fail(event, fmt.Errorf("fail to parse CSV from field '%s': %s", f.field, err))
+	}
+	if len(record) != len(f.columns) {
+		err := fmt.Errorf("field '%s' has %d CSV columns, expected %d", f.field, len(record), len(f.columns))
+		if f.failOnError {
+			return event, err
+		}
+		debug("%s, decoding the available columns", err)
+	}
+
+	// Without fail_on_error, missing columns are not set and extra values
+	// are discarded.
+	if len(record) > len(f.columns) {
+		record = record[:len(f.columns)]
+	}
+
+	if f.target == "" {
+		for i, v := range record {
+			if _, err := event.PutValue(f.columns[i], v); err != nil {
+				return event, err
+			}
+		}
+		return event, nil
+	}
+
+	columns := common.MapStr{}
+	for i, v := range record {
+		columns.Put(f.columns[i], v)
+	}
+	if _, err := event.PutValue(f.target, columns); err != nil {
+		return event, err
+	}
+	return event, nil
+}
+
+// fail returns the error if fail_on_error is enabled, otherwise the error is
+// logged and the event is passed on unchanged.
+func (f *decodeCSVField) fail(event *beat.Event, err error) (*beat.Event, error) {
+	if f.failOnError {
+		return event, err
+	}
+	debug("%s", err)
+	return event, nil
+}
+
+func (f *decodeCSVField) String() string {
+	return fmt.Sprintf("decode_csv_field=[field=%s, separator=%q, target=%s]", f.field, f.separator, f.target)
+}
@@ -0,0 +1,198 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func TestDecodeCSVField(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		value    string
+		expected common.MapStr
+	}{
+		{
+			name: "plain",
+			config: map[string]interface{}{
+				"field":        "message",
+				"target":       "request",
+				"column_names": []string{"method", "path", "status"},
+			},
+			value:    "GET,/index.html,200",
+			expected: common.MapStr{"method": "GET", "path": "/index.html", "status": "200"},
+		},
+		{
+			name: "quoted fields",
+			config: map[string]interface{}{
+				"field":        "message",
+				"target":       "request",
+				"column_names": []string{"method", "path", "status"},
+			},
+			value:    `"GET","/search?q=""beats""",200`,
+			expected: common.MapStr{"method": "GET", "path": `/search?q="beats"`, "status": "200"},
+		},
+		{
+			name: "embedded separators",
+			config: map[string]interface{}{
+				"field":        "message",
+				"target":       "request",
+				"column_names": []string{"method", "path", "status"},
+			},
+			value:    `POST,"/a,b",201`,
+			expected: common.MapStr{"method": "POST", "path": "/a,b", "status": "201"},
+		},
+		{
+			name: "empty values",
+			config: map[string]interface{}{
+				"field":        "message",
+				"target":       "request",
+				"column_names": []string{"method", "path", "status"},
+			},
+			value:    "GET,,",
+			expected: common.MapStr{"method": "GET", "path": "", "status": ""},
+		},
+		{
+			name: "custom separator",
+			config: map[string]interface{}{
+				"field":        "message",
+				"target":       "request",
+				"column_names": []string{"method", "path", "status"},
+				"separator":    ";",
+			},
+			value:    `GET;"/a;b";200`,
+			expected: common.MapStr{"method": "GET", "path": "/a;b", "status": "200"},
+		},
+		{
+			name: "header",
+			config: map[string]interface{}{
+				"field":  "message",
+				"target": "request",
+				"header": `method,"path",status`,
+			},
+			value:    "GET,/index.html,200",
+			expected: common.MapStr{"method": "GET", "path": "/index.html", "status": "200"},
+		},
+	}
+
+	for _, test := range tests {
+		config, _ := common.NewConfigFrom(test.config)
+
+		actual, err := runDecodeCSVField(t, config, common.MapStr{"message": test.value})
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, common.MapStr{"message": test.value, "request": test.expected}, actual, test.name)
+	}
+}
+
+func TestDecodeCSVFieldTarget(t *testing.T) {
+	value := "GET,/index.html"
+
+	config, _ := common.NewConfigFrom(map[string]interface{}{
+		"field":        "message",
+		"column_names": []string{"method", "url.path"},
+	})
+	actual, err := runDecodeCSVField(t, config, common.MapStr{"message": value})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"message": common.MapStr{"method": "GET", "url": common.MapStr{"path": "/index.html"}},
+	}, actual)
+
+	config, _ = common.NewConfigFrom(map[string]interface{}{
+		"field":        "message",
+		"column_names": []string{"method", "url.path"},
+		"target":       "",
+	})
+	actual, err = runDecodeCSVField(t, config, common.MapStr{"message": value})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"message": value,
+		"method":  "GET",
+		"url":     common.MapStr{"path": "/index.html"},
+	}, actual)
+}
+
+func TestDecodeCSVFieldColumnCountMismatch(t *testing.T) {
+	config, _ := common.NewConfigFrom(map[string]interface{}{
+		"field":        "message",
+		"target":       "request",
+		"column_names": []string{"method", "path", "status"},
+	})
+	for _, value := range []string{"GET,/index.html", "GET,/index.html,200,extra", `"GET,/index.html",200`} {
+		actual, err := runDecodeCSVField(t, config, common.MapStr{"message": value})
+		assert.Error(t, err, value)
+		assert.Equal(t, common.MapStr{"message": value}, actual, value)
+	}
+
+	config, _ = common.NewConfigFrom(map[string]interface{}{
+		"field":         "message",
+		"target":        "request",
+		"column_names":  []string{"method", "path", "status"},
+		"fail_on_error": false,
+	})
+
+	actual, err := runDecodeCSVField(t, config, common.MapStr{"message": "GET,/index.html"})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{"method": "GET", "path": "/index.html"}, actual["request"])
+
+	actual, err = runDecodeCSVField(t, config, common.MapStr{"message": "GET,/index.html,200,extra"})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{"method": "GET", "path": "/index.html", "status": "200"}, actual["request"])
+
+	// Invalid CSV leaves the event unchanged.
+	actual, err = runDecodeCSVField(t, config, common.MapStr{"message": `"GET,/index.html`})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{"message": `"GET,/index.html`}, actual)
+}
+
+func TestDecodeCSVFieldMissingOrInvalid(t *testing.T) {
+	config, _ := common.NewConfigFrom(map[string]interface{}{
+		"field":        "message",
+		"column_names": []string{"a"},
+	})
+
+	actual, err := runDecodeCSVField(t, config, common.MapStr{"other": "hello"})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{"other": "hello"}, actual)
+
+	actual, err = runDecodeCSVField(t, config, common.MapStr{"message": 42})
+	assert.Error(t, err)
+	assert.Equal(t, common.MapStr{"message": 42}, actual)
+}
+
+func TestDecodeCSVFieldInvalidConfig(t *testing.T) {
+	tests := []map[string]interface{}{
+		{"column_names": []string{"a"}},
+		{"field": "message"},
+		{"field": "message", "column_names": []string{"a"}, "header": "a"},
+		{"field": "message", "column_names": []string{"a", "a"}},
+		{"field": "message", "column_names": []string{"a", ""}},
+		{"field": "message", "header": `"a,b`},
+		{"field": "message", "column_names": []string{"a"}, "separator": ""},
+		{"field": "message", "column_names": []string{"a"}, "separator": "||"},
+		{"field": "message", "column_names": []string{"a"}, "target": "type"},
+		{"field": "message", "column_names": []string{"type"}, "target": ""},
+	}
+
+	for _, test := range tests {
+		cfg, err := common.NewConfigFrom(test)
+		require.NoError(t, err)
+
+		_, err = configChecked(newDecodeCSVField, requireFields("field"))(cfg)
+		assert.Error(t, err, "config: %v", test)
+	}
+}
+
+func runDecodeCSVField(t *testing.T, config *common.Config, input common.MapStr) (common.MapStr, error) {
+	p, err := newDecodeCSVField(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := p.Run(&beat.Event{Fields: input})
+	return actual.Fields, err
+}
@@ -177,6 +177,8 @@ func decodeJSON(text string, to *interface{}) error {
 	switch O := interface{}(*to).(type) {
 	case map[string]interface{}:
 		jsontransform.TransformNumbers(O)
+	case []interface{}:
+		jsontransform.TransformNumbersArray(O)
 	}
 	return nil
 }
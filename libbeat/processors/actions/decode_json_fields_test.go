@@ -182,6 +182,33 @@ func TestTargetRootOption(t *testing.T) {
 	assert.Equal(t, expected.String(), actual.String())
 }
 
+func TestLargeIntegers(t *testing.T) {
+	input := common.MapStr{
+		"msg": `{"id":1152921504606846977,"ids":[1152921504606846977,18446744073709551615],"ratio":0.5}`,
+	}
+
+	testConfig, _ = common.NewConfigFrom(map[string]interface{}{
+		"fields":        fields,
+		"process_array": false,
+		"max_depth":     1,
+		"target":        "doc",
+	})
+
+	actual := getActualValue(t, testConfig, input)
+
+	doc, err := actual.GetValue("doc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"id":    int64(1152921504606846977),
+		"ids":   []interface{}{int64(1152921504606846977), uint64(18446744073709551615)},
+		"ratio": float64(0.5),
+	}
+	assert.Equal(t, expected, doc)
+}
+
 func getActualValue(t *testing.T, config *common.Config, input common.MapStr) common.MapStr {
 	if testing.Verbose() {
 		logp.LogInit(logp.LOG_DEBUG, "", false, true, []string{"*"})
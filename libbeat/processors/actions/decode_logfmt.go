@@ -0,0 +1,123 @@
+package actions
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+type decodeLogfmt struct {
+	field         string
+	target        string
+	ignoreMissing bool
+	failOnError   bool
+}
+
+type decodeLogfmtConfig struct {
+	Field         string  `config:"field"`
+	Target        *string `config:"target"`
+	IgnoreMissing bool    `config:"ignore_missing"`
+	FailOnError   bool    `config:"fail_on_error"`
+}
+
+func init() {
+	processors.RegisterPlugin("decode_logfmt",
+		configChecked(newDecodeLogfmt,
+			requireFields("field"),
+			allowedFields("field", "target", "ignore_missing", "fail_on_error", "when")))
+}
+
+func newDecodeLogfmt(c *common.Config) (processors.Processor, error) {
+	config := decodeLogfmtConfig{
+		FailOnError: true,
+	}
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("fail to unpack the decode_logfmt configuration: %s", err)
+	}
+
+	// The decoded keys replace the field by default, an empty target writes
+	// them to the root of the event.
+	target := config.Field
+	if config.Target != nil {
+		target = *config.Target
+	}
+	for _, readOnly := range processors.MandatoryExportedFields {
+		if target == readOnly {
+			return nil, fmt.Errorf("%s is a read only field, cannot override", readOnly)
+		}
+	}
+
+	return &decodeLogfmt{
+		field:         config.Field,
+		target:        target,
+		ignoreMissing: config.IgnoreMissing,
+		failOnError:   config.FailOnError,
+	}, nil
+}
+
+func (f *decodeLogfmt) Run(event *beat.Event) (*beat.Event, error) {
+	fieldValue, err := event.GetValue(f.field)
+	if err != nil {
+		if f.ignoreMissing && errors.Cause(err) == common.ErrKeyNotFound {
+			return event, nil
+		}
+		return f.fail(event, fmt.Errorf("could not get field '%s': %s", f.field, err))
+	}
+
+	value, ok := fieldValue.(string)
+	if !ok {
+		return f.fail(event, fmt.Errorf("could not get a string from field '%s'", f.field))
+	}
+
+	pairs, err := common.ParseLogfmt(value)
+	if err != nil {
+		return f.fail(event, fmt.Errorf("fail to parse logfmt from field '%s': %s", f.field, err))
+	}
+
+	// Bare keys are flags and decoded as true. Later values of duplicate keys
+	// replace earlier ones.
+	decoded := common.MapStr{}
+	for _, pair := range pairs {
+		var v interface{} = pair.Value
+		if pair.Bare {
+			v = true
+		}
+		if f.target == "" {
+			for _, readOnly := range processors.MandatoryExportedFields {
+				if pair.Key == readOnly {
+					return f.fail(event, fmt.Errorf("%s is a read only field, cannot override", readOnly))
+				}
+			}
+		}
+		if _, err := decoded.Put(pair.Key, v); err != nil {
+			return f.fail(event, fmt.Errorf("fail to decode key '%s' of field '%s': %s", pair.Key, f.field, err))
+		}
+	}
+
+	if f.target == "" {
+		event.Fields.DeepUpdate(decoded)
+		return event, nil
+	}
+	if _, err := event.PutValue(f.target, decoded); err != nil {
+		return f.fail(event, err)
+	}
+	return event, nil
+}
+
+// fail returns the error if fail_on_error is enabled, otherwise the error is
+// logged and the event is passed on unchanged.
+func (f *decodeLogfmt) fail(event *beat.Event, err error) (*beat.Event, error) {
+	if f.failOnError {
+		return event, err
+	}
+	debug("%s", err)
+	return event, nil
+}
+
+func (f *decodeLogfmt) String() string {
+	return fmt.Sprintf("decode_logfmt=[field=%s, target=%s]", f.field, f.target)
+}
@@ -0,0 +1,164 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func TestDecodeLogfmt(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected common.MapStr
+	}{
+		{
+			name:     "plain",
+			value:    "level=info status=200",
+			expected: common.MapStr{"level": "info", "status": "200"},
+		},
+		{
+			name:     "quoted values",
+			value:    `level=warn msg="disk almost full" path="/var/log"`,
+			expected: common.MapStr{"level": "warn", "msg": "disk almost full", "path": "/var/log"},
+		},
+		{
+			name:     "escaped quotes",
+			value:    `msg="user \"admin\" logged in" dir="C:\\logs"`,
+			expected: common.MapStr{"msg": `user "admin" logged in`, "dir": `C:\logs`},
+		},
+		{
+			name:     "bare keys",
+			value:    "debug level=info retry",
+			expected: common.MapStr{"debug": true, "level": "info", "retry": true},
+		},
+		{
+			name:     "empty values",
+			value:    `a= b=""`,
+			expected: common.MapStr{"a": "", "b": ""},
+		},
+		{
+			name:     "dotted and duplicate keys",
+			value:    "url.path=/index.html url.port=80 level=info level=error",
+			expected: common.MapStr{"url": common.MapStr{"path": "/index.html", "port": "80"}, "level": "error"},
+		},
+		{
+			name:     "empty line",
+			value:    "",
+			expected: common.MapStr{},
+		},
+	}
+
+	config, _ := common.NewConfigFrom(map[string]interface{}{"field": "message", "target": "log"})
+	for _, test := range tests {
+		actual, err := runDecodeLogfmt(t, config, common.MapStr{"message": test.value})
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, common.MapStr{"message": test.value, "log": test.expected}, actual, test.name)
+	}
+}
+
+func TestDecodeLogfmtTarget(t *testing.T) {
+	value := "level=info msg=started"
+
+	config, _ := common.NewConfigFrom(map[string]interface{}{"field": "message"})
+	actual, err := runDecodeLogfmt(t, config, common.MapStr{"message": value})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"message": common.MapStr{"level": "info", "msg": "started"},
+	}, actual)
+
+	config, _ = common.NewConfigFrom(map[string]interface{}{"field": "message", "target": ""})
+	actual, err = runDecodeLogfmt(t, config, common.MapStr{
+		"message": value,
+		"log":     common.MapStr{"offset": 10},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"message": value,
+		"level":   "info",
+		"msg":     "started",
+		"log":     common.MapStr{"offset": 10},
+	}, actual)
+
+	// read only fields are not overwritten from the decoded line
+	actual, err = runDecodeLogfmt(t, config, common.MapStr{"message": "type=fake"})
+	assert.Error(t, err)
+	assert.Equal(t, common.MapStr{"message": "type=fake"}, actual)
+}
+
+func TestDecodeLogfmtMalformed(t *testing.T) {
+	lines := []string{
+		`msg="unterminated`,
+		`msg="closed"trailing`,
+		`a=b=c`,
+		`=value`,
+		`a=1 a.b=2`,
+	}
+
+	config, _ := common.NewConfigFrom(map[string]interface{}{"field": "message", "target": "log"})
+	for _, line := range lines {
+		actual, err := runDecodeLogfmt(t, config, common.MapStr{"message": line})
+		assert.Error(t, err, line)
+		assert.Equal(t, common.MapStr{"message": line}, actual, line)
+	}
+
+	config, _ = common.NewConfigFrom(map[string]interface{}{"field": "message", "target": "log", "fail_on_error": false})
+	for _, line := range lines {
+		actual, err := runDecodeLogfmt(t, config, common.MapStr{"message": line})
+		assert.NoError(t, err, line)
+		assert.Equal(t, common.MapStr{"message": line}, actual, line)
+	}
+}
+
+func TestDecodeLogfmtMissingOrInvalid(t *testing.T) {
+	config, _ := common.NewConfigFrom(map[string]interface{}{"field": "message"})
+
+	actual, err := runDecodeLogfmt(t, config, common.MapStr{"other": "hello"})
+	assert.Error(t, err)
+	assert.Equal(t, common.MapStr{"other": "hello"}, actual)
+
+	actual, err = runDecodeLogfmt(t, config, common.MapStr{"message": 42})
+	assert.Error(t, err)
+	assert.Equal(t, common.MapStr{"message": 42}, actual)
+
+	config, _ = common.NewConfigFrom(map[string]interface{}{"field": "message", "ignore_missing": true})
+
+	actual, err = runDecodeLogfmt(t, config, common.MapStr{"other": "hello"})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{"other": "hello"}, actual)
+
+	actual, err = runDecodeLogfmt(t, config, common.MapStr{"message": 42})
+	assert.Error(t, err)
+}
+
+func TestDecodeLogfmtInvalidConfig(t *testing.T) {
+	tests := []map[string]interface{}{
+		{"target": "log"},
+		{"field": "message", "target": "type"},
+		{"field": "message", "separator": " "},
+	}
+
+	for _, test := range tests {
+		cfg, err := common.NewConfigFrom(test)
+		require.NoError(t, err)
+
+		_, err = configChecked(newDecodeLogfmt,
+			requireFields("field"),
+			allowedFields("field", "target", "ignore_missing", "fail_on_error", "when"))(cfg)
+		assert.Error(t, err, "config: %v", test)
+	}
+}
+
+func runDecodeLogfmt(t *testing.T, config *common.Config, input common.MapStr) (common.MapStr, error) {
+	p, err := newDecodeLogfmt(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := p.Run(&beat.Event{Fields: input})
+	return actual.Fields, err
+}
@@ -1,27 +1,126 @@
 package actions
 
 import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
 	"github.com/elastic/beats/libbeat/beat"
 	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/atomic"
+	"github.com/elastic/beats/libbeat/monitoring"
 	"github.com/elastic/beats/libbeat/processors"
 )
 
-type dropEvent struct{}
+type dropEvent struct {
+	reason   string
+	keepRate float64
+	metrics  *dropEventMetrics
+
+	// number of events seen by the processor, used for sampling
+	seen atomic.Uint64
+}
+
+type dropEventConfig struct {
+	Reason   string  `config:"reason"`
+	KeepRate float64 `config:"keep_rate" validate:"min=0,max=1"`
+}
+
+// dropEventMetrics holds the counters of a `reason`. Metrics are shared by all
+// drop_event processors using the same reason.
+type dropEventMetrics struct {
+	dropped *monitoring.Int
+	kept    *monitoring.Int
+}
+
+var (
+	droppedEvents = monitoring.NewInt(nil, "libbeat.processors.drop_event.dropped")
+
+	dropEventReasonsMutex sync.Mutex
+	dropEventReasons      = map[string]*dropEventMetrics{}
+)
 
 func init() {
 	processors.RegisterPlugin("drop_event",
-		configChecked(newDropEvent, allowedFields("when")))
+		configChecked(newDropEvent, allowedFields("reason", "keep_rate", "when")))
 }
 
-var dropEventsSingleton = (*dropEvent)(nil)
+var dropEventsSingleton = &dropEvent{}
 
 func newDropEvent(c *common.Config) (processors.Processor, error) {
-	return dropEventsSingleton, nil
+	config := dropEventConfig{}
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("fail to unpack the drop_event configuration: %s", err)
+	}
+
+	if config.Reason == "" && config.KeepRate == 0 {
+		return dropEventsSingleton, nil
+	}
+
+	if strings.Contains(config.Reason, ".") {
+		return nil, fmt.Errorf("drop_event reason '%v' must not contain dots", config.Reason)
+	}
+
+	p := &dropEvent{
+		reason:   config.Reason,
+		keepRate: config.KeepRate,
+	}
+	if config.Reason != "" {
+		p.metrics = reasonMetrics(config.Reason)
+	}
+	return p, nil
+}
+
+func reasonMetrics(reason string) *dropEventMetrics {
+	dropEventReasonsMutex.Lock()
+	defer dropEventReasonsMutex.Unlock()
+
+	if m, exists := dropEventReasons[reason]; exists {
+		return m
+	}
+
+	prefix := "libbeat.processors.drop_event.reasons." + reason
+	m := &dropEventMetrics{
+		dropped: monitoring.NewInt(nil, prefix+".dropped"),
+		kept:    monitoring.NewInt(nil, prefix+".kept"),
+	}
+	dropEventReasons[reason] = m
+	return m
 }
 
-func (*dropEvent) Run(_ *beat.Event) (*beat.Event, error) {
+func (p *dropEvent) Run(event *beat.Event) (*beat.Event, error) {
+	if p.keep() {
+		if p.metrics != nil {
+			p.metrics.kept.Inc()
+		}
+		return event, nil
+	}
+
+	droppedEvents.Inc()
+	if p.metrics != nil {
+		p.metrics.dropped.Inc()
+	}
+
 	// return event=nil to delete the entire event
 	return nil, nil
 }
 
-func (*dropEvent) String() string { return "drop_event" }
+// keep reports if the current event is kept for debugging purposes. Events are
+// kept deterministically, such that the share of kept events approximates
+// keep_rate.
+func (p *dropEvent) keep() bool {
+	if p.keepRate <= 0 {
+		return false
+	}
+
+	n := p.seen.Inc()
+	return math.Floor(float64(n)*p.keepRate) != math.Floor(float64(n-1)*p.keepRate)
+}
+
+func (p *dropEvent) String() string {
+	if p.reason == "" && p.keepRate == 0 {
+		return "drop_event"
+	}
+	return fmt.Sprintf("drop_event=[reason=%v, keep_rate=%v]", p.reason, p.keepRate)
+}
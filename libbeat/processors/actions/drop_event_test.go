@@ -0,0 +1,106 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/monitoring"
+)
+
+func TestDropEvent(t *testing.T) {
+	config := common.NewConfig()
+
+	before := droppedEvents.Get()
+	actual := runDropEvent(t, config, common.MapStr{"a": 1})
+	assert.Nil(t, actual[0])
+	assert.Equal(t, before+1, droppedEvents.Get())
+
+	p, err := newDropEvent(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "drop_event", p.String())
+}
+
+func TestDropEventReasonMetrics(t *testing.T) {
+	debugConfig, _ := common.NewConfigFrom(map[string]interface{}{"reason": "debug_logs"})
+	healthcheckConfig, _ := common.NewConfigFrom(map[string]interface{}{"reason": "healthcheck"})
+
+	// Processors with the same reason share its metrics.
+	runDropEvent(t, debugConfig, common.MapStr{}, common.MapStr{}, common.MapStr{})
+	runDropEvent(t, debugConfig, common.MapStr{})
+	runDropEvent(t, healthcheckConfig, common.MapStr{})
+
+	assert.Equal(t, int64(4), metricValue(t, "libbeat.processors.drop_event.reasons.debug_logs.dropped"))
+	assert.Equal(t, int64(0), metricValue(t, "libbeat.processors.drop_event.reasons.debug_logs.kept"))
+	assert.Equal(t, int64(1), metricValue(t, "libbeat.processors.drop_event.reasons.healthcheck.dropped"))
+}
+
+func TestDropEventKeepRate(t *testing.T) {
+	config, _ := common.NewConfigFrom(map[string]interface{}{
+		"reason":    "sampled",
+		"keep_rate": 0.1,
+	})
+
+	inputs := make([]common.MapStr, 1000)
+	for i := range inputs {
+		inputs[i] = common.MapStr{"i": i}
+	}
+
+	kept := 0
+	for i, event := range runDropEvent(t, config, inputs...) {
+		if event != nil {
+			kept++
+			assert.Equal(t, common.MapStr{"i": i}, event.Fields)
+		}
+	}
+
+	assert.Equal(t, 100, kept)
+	assert.Equal(t, int64(100), metricValue(t, "libbeat.processors.drop_event.reasons.sampled.kept"))
+	assert.Equal(t, int64(900), metricValue(t, "libbeat.processors.drop_event.reasons.sampled.dropped"))
+}
+
+func TestDropEventInvalidConfig(t *testing.T) {
+	tests := []map[string]interface{}{
+		{"keep_rate": 1.5},
+		{"keep_rate": -0.1},
+		{"reason": "with.dots"},
+		{"unknown": true},
+	}
+
+	for _, test := range tests {
+		cfg, err := common.NewConfigFrom(test)
+		require.NoError(t, err)
+
+		_, err = configChecked(newDropEvent, allowedFields("reason", "keep_rate", "when"))(cfg)
+		assert.Error(t, err, "config: %v", test)
+	}
+}
+
+// runDropEvent runs a new drop_event processor on all inputs, returning the
+// events not dropped, or nil for dropped events.
+func runDropEvent(t *testing.T, config *common.Config, inputs ...common.MapStr) []*beat.Event {
+	p, err := newDropEvent(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := make([]*beat.Event, len(inputs))
+	for i, input := range inputs {
+		actual[i], err = p.Run(&beat.Event{Fields: input})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return actual
+}
+
+func metricValue(t *testing.T, name string) int64 {
+	v, ok := monitoring.Default.Get(name).(*monitoring.Int)
+	require.True(t, ok, "metric %v not found", name)
+	return v.Get()
+}
@@ -0,0 +1,101 @@
+package actions
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/mask"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+type encryptFields struct {
+	fields []string
+	cipher *mask.Cipher
+}
+
+type encryptFieldsConfig struct {
+	Fields []string `config:"fields" validate:"required"`
+	Key    string   `config:"key" validate:"required"`
+}
+
+func init() {
+	processors.RegisterPlugin("encrypt_fields",
+		configChecked(newEncryptFields,
+			requireFields("fields", "key"),
+			allowedFields("fields", "key", "when")))
+}
+
+// newEncryptFields creates the encrypt_fields processor. In contrast to
+// reversible_mask, values of any type are encrypted, and the encrypted values
+// are marked by mask.EncryptedPrefix. It is meant to be configured in the
+// processors of an output, so the fields are only encrypted for this output.
+func newEncryptFields(c *common.Config) (processors.Processor, error) {
+	var config encryptFieldsConfig
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("fail to unpack the encrypt_fields configuration: %s", err)
+	}
+
+	for _, field := range config.Fields {
+		for _, readOnly := range processors.MandatoryExportedFields {
+			if field == readOnly {
+				return nil, fmt.Errorf("%s is a read only field, cannot override", readOnly)
+			}
+		}
+	}
+
+	key, err := mask.DecodeKey(config.Key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypt_fields key: %v", err)
+	}
+	cipher, err := mask.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &encryptFields{
+		fields: config.Fields,
+		cipher: cipher,
+	}, nil
+}
+
+func (f *encryptFields) Run(event *beat.Event) (*beat.Event, error) {
+	var errs []string
+
+	for _, field := range f.fields {
+		err := f.encryptField(event, field)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return event, errors.New(strings.Join(errs, ", "))
+	}
+	return event, nil
+}
+
+func (f *encryptFields) encryptField(event *beat.Event, field string) error {
+	value, err := event.GetValue(field)
+	if err != nil {
+		if errors.Cause(err) == common.ErrKeyNotFound {
+			return nil
+		}
+		return err
+	}
+
+	encrypted, err := f.cipher.Encrypt(value)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt field '%s': %v", field, err)
+	}
+
+	_, err = event.PutValue(field, encrypted)
+	return err
+}
+
+func (f *encryptFields) String() string {
+	return fmt.Sprintf("encrypt_fields=[fields=%s]", strings.Join(f.fields, ", "))
+}
@@ -0,0 +1,93 @@
+package actions
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/mask"
+)
+
+func decryptTestValue(t *testing.T, encrypted interface{}) interface{} {
+	key, err := mask.DecodeKey(testMaskKey)
+	require.NoError(t, err)
+	c, err := mask.NewCipher(key)
+	require.NoError(t, err)
+
+	s, ok := encrypted.(string)
+	require.True(t, ok, "encrypted value must be a string, got %T", encrypted)
+	assert.True(t, strings.HasPrefix(s, mask.EncryptedPrefix), s)
+
+	value, err := c.Decrypt(s)
+	require.NoError(t, err)
+	return value
+}
+
+func TestEncryptFieldsRoundTrip(t *testing.T) {
+	config, _ := common.NewConfigFrom(map[string]interface{}{
+		"fields": []string{"user.name", "card", "amount"},
+		"key":    testMaskKey,
+	})
+
+	actual, err := runEncryptFields(t, config, common.MapStr{
+		"user":    common.MapStr{"name": "alice"},
+		"card":    common.MapStr{"number": "4111111111111111", "expiry": "12/30"},
+		"amount":  42,
+		"message": "hello",
+	})
+	require.NoError(t, err)
+
+	name, _ := actual.GetValue("user.name")
+	assert.NotEqual(t, "alice", name)
+	assert.Equal(t, "alice", decryptTestValue(t, name))
+
+	// objects are encrypted as a whole, numbers keep their value
+	assert.Equal(t, map[string]interface{}{"number": "4111111111111111", "expiry": "12/30"},
+		decryptTestValue(t, actual["card"]))
+	assert.Equal(t, float64(42), decryptTestValue(t, actual["amount"]))
+
+	assert.Equal(t, "hello", actual["message"])
+}
+
+func TestEncryptFieldsMissing(t *testing.T) {
+	config, _ := common.NewConfigFrom(map[string]interface{}{
+		"fields": []string{"user"},
+		"key":    testMaskKey,
+	})
+
+	actual, err := runEncryptFields(t, config, common.MapStr{"message": "hello"})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{"message": "hello"}, actual)
+}
+
+func TestEncryptFieldsInvalidConfig(t *testing.T) {
+	tests := []map[string]interface{}{
+		{"fields": []string{"user"}},
+		{"key": testMaskKey},
+		{"fields": []string{"user"}, "key": "secret"},
+		{"fields": []string{"user"}, "key": "c2hvcnQ="},
+		{"fields": []string{"type"}, "key": testMaskKey},
+	}
+
+	for _, test := range tests {
+		cfg, err := common.NewConfigFrom(test)
+		require.NoError(t, err)
+
+		_, err = configChecked(newEncryptFields, requireFields("fields", "key"))(cfg)
+		assert.Error(t, err, "config: %v", test)
+	}
+}
+
+func runEncryptFields(t *testing.T, config *common.Config, input common.MapStr) (common.MapStr, error) {
+	p, err := newEncryptFields(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := p.Run(&beat.Event{Fields: input})
+	return actual.Fields, err
+}
@@ -0,0 +1,136 @@
+package actions
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+type joinFields struct {
+	fields         []string
+	separator      string
+	target         string
+	ignoreMissing  bool
+	usePlaceholder bool
+	placeholder    string
+}
+
+type joinFieldsConfig struct {
+	Fields        []string `config:"fields" validate:"required"`
+	Separator     string   `config:"separator"`
+	Target        string   `config:"target" validate:"required"`
+	IgnoreMissing bool     `config:"ignore_missing"`
+	MissingFields string   `config:"missing_fields"`
+	Placeholder   string   `config:"placeholder"`
+}
+
+const (
+	missingFieldsSkip        = "skip"
+	missingFieldsPlaceholder = "placeholder"
+)
+
+func init() {
+	processors.RegisterPlugin("join_fields",
+		configChecked(newJoinFields,
+			requireFields("fields", "target"),
+			allowedFields("fields", "separator", "target", "ignore_missing", "missing_fields", "placeholder", "when")))
+}
+
+func newJoinFields(c *common.Config) (processors.Processor, error) {
+	config := joinFieldsConfig{
+		Separator:     ",",
+		MissingFields: missingFieldsSkip,
+	}
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("fail to unpack the join_fields configuration: %s", err)
+	}
+
+	for _, readOnly := range processors.MandatoryExportedFields {
+		if config.Target == readOnly {
+			return nil, fmt.Errorf("%s is a read only field, cannot override", readOnly)
+		}
+	}
+
+	var usePlaceholder bool
+	switch strings.ToLower(config.MissingFields) {
+	case missingFieldsSkip:
+		usePlaceholder = false
+	case missingFieldsPlaceholder:
+		usePlaceholder = true
+	default:
+		return nil, fmt.Errorf("'%s' is not a valid missing_fields option for the "+
+			"join_fields processor. Valid options are 'skip' and 'placeholder'", config.MissingFields)
+	}
+
+	return &joinFields{
+		fields:         config.Fields,
+		separator:      config.Separator,
+		target:         config.Target,
+		ignoreMissing:  config.IgnoreMissing,
+		usePlaceholder: usePlaceholder,
+		placeholder:    config.Placeholder,
+	}, nil
+}
+
+// Run joins the values of the fields, in the configured order, and writes the
+// result to the target field. Missing fields are an error, unless
+// ignore_missing is set, in which case they are skipped or replaced by the
+// placeholder. If none of the fields exist, the event is not modified.
+func (f *joinFields) Run(event *beat.Event) (*beat.Event, error) {
+	parts := make([]string, 0, len(f.fields))
+	found := false
+	for _, field := range f.fields {
+		value, err := event.GetValue(field)
+		if err != nil {
+			if errors.Cause(err) != common.ErrKeyNotFound {
+				return event, err
+			}
+			if !f.ignoreMissing {
+				return event, fmt.Errorf("could not join fields, field '%s' is missing", field)
+			}
+			if f.usePlaceholder {
+				parts = append(parts, f.placeholder)
+			}
+			continue
+		}
+
+		s, err := joinValueString(value)
+		if err != nil {
+			return event, fmt.Errorf("could not join field '%s': %v", field, err)
+		}
+		parts = append(parts, s)
+		found = true
+	}
+
+	if !found {
+		return event, nil
+	}
+
+	if _, err := event.PutValue(f.target, strings.Join(parts, f.separator)); err != nil {
+		return event, err
+	}
+	return event, nil
+}
+
+// joinValueString formats string, number and boolean values. Objects and
+// arrays can not be joined.
+func joinValueString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+func (f *joinFields) String() string {
+	return fmt.Sprintf("join_fields=[fields=%s, separator=%q, target=%s]",
+		strings.Join(f.fields, ","), f.separator, f.target)
+}
@@ -0,0 +1,110 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func TestJoinFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		fields   common.MapStr
+		expected interface{}
+		err      bool
+	}{
+		{
+			name:     "all present",
+			config:   map[string]interface{}{"separator": "|"},
+			fields:   common.MapStr{"host": "web-1", "service": common.MapStr{"name": "nginx"}, "env": "prod"},
+			expected: "web-1|nginx|prod",
+		},
+		{
+			name:     "default separator and numbers",
+			config:   map[string]interface{}{},
+			fields:   common.MapStr{"host": "web-1", "service": common.MapStr{"name": 80}, "env": true},
+			expected: "web-1,80,true",
+		},
+		{
+			name:   "some missing without ignore_missing",
+			config: map[string]interface{}{},
+			fields: common.MapStr{"host": "web-1", "env": "prod"},
+			err:    true,
+		},
+		{
+			name:     "some missing skipped",
+			config:   map[string]interface{}{"separator": "|", "ignore_missing": true},
+			fields:   common.MapStr{"host": "web-1", "env": "prod"},
+			expected: "web-1|prod",
+		},
+		{
+			name: "some missing with placeholder",
+			config: map[string]interface{}{
+				"separator":      "|",
+				"ignore_missing": true,
+				"missing_fields": "placeholder",
+				"placeholder":    "-",
+			},
+			fields:   common.MapStr{"host": "web-1", "env": "prod"},
+			expected: "web-1|-|prod",
+		},
+		{
+			name:   "all missing",
+			config: map[string]interface{}{"ignore_missing": true, "missing_fields": "placeholder"},
+			fields: common.MapStr{"message": "test"},
+		},
+		{
+			name:   "object value",
+			config: map[string]interface{}{},
+			fields: common.MapStr{"host": "web-1", "service": common.MapStr{"name": common.MapStr{}}, "env": "prod"},
+			err:    true,
+		},
+	}
+
+	for _, test := range tests {
+		config := map[string]interface{}{
+			"fields": []string{"host", "service.name", "env"},
+			"target": "key",
+		}
+		for k, v := range test.config {
+			config[k] = v
+		}
+
+		c, err := common.NewConfigFrom(config)
+		require.NoError(t, err, test.name)
+
+		p, err := newJoinFields(c)
+		require.NoError(t, err, test.name)
+
+		event, err := p.Run(&beat.Event{Fields: test.fields})
+		if test.err {
+			assert.Error(t, err, test.name)
+			assert.NotContains(t, event.Fields, "key", test.name)
+			continue
+		}
+		require.NoError(t, err, test.name)
+
+		if test.expected == nil {
+			assert.NotContains(t, event.Fields, "key", test.name)
+		} else {
+			assert.Equal(t, test.expected, event.Fields["key"], test.name)
+		}
+	}
+}
+
+func TestJoinFieldsInvalidConfig(t *testing.T) {
+	c, err := common.NewConfigFrom(map[string]interface{}{
+		"fields":         []string{"host"},
+		"target":         "key",
+		"missing_fields": "fail",
+	})
+	require.NoError(t, err)
+
+	_, err = newJoinFields(c)
+	assert.Error(t, err)
+}
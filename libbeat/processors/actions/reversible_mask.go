@@ -0,0 +1,126 @@
+package actions
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/mask"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+type reversibleMask struct {
+	fields []string
+	cipher *mask.Cipher
+}
+
+type reversibleMaskConfig struct {
+	Fields []string `config:"fields" validate:"required"`
+	Key    string   `config:"key" validate:"required"`
+}
+
+func init() {
+	processors.RegisterPlugin("reversible_mask",
+		configChecked(newReversibleMask,
+			requireFields("fields", "key"),
+			allowedFields("fields", "key", "when")))
+}
+
+func newReversibleMask(c *common.Config) (processors.Processor, error) {
+	var config reversibleMaskConfig
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("fail to unpack the reversible_mask configuration: %s", err)
+	}
+
+	for _, field := range config.Fields {
+		for _, readOnly := range processors.MandatoryExportedFields {
+			if field == readOnly {
+				return nil, fmt.Errorf("%s is a read only field, cannot override", readOnly)
+			}
+		}
+	}
+
+	key, err := mask.DecodeKey(config.Key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reversible_mask key: %v", err)
+	}
+	cipher, err := mask.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reversibleMask{
+		fields: config.Fields,
+		cipher: cipher,
+	}, nil
+}
+
+func (f *reversibleMask) Run(event *beat.Event) (*beat.Event, error) {
+	var errs []string
+
+	for _, field := range f.fields {
+		err := f.maskField(event, field)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return event, errors.New(strings.Join(errs, ", "))
+	}
+	return event, nil
+}
+
+func (f *reversibleMask) maskField(event *beat.Event, field string) error {
+	fieldValue, err := event.GetValue(field)
+	if err != nil {
+		if errors.Cause(err) == common.ErrKeyNotFound {
+			return nil
+		}
+		return err
+	}
+
+	var value interface{}
+	switch v := fieldValue.(type) {
+	case string:
+		value, err = f.cipher.Mask(v)
+	case []string:
+		value, err = f.maskStrings(v)
+	case []interface{}:
+		strs := make([]string, len(v))
+		for i, elem := range v {
+			s, ok := elem.(string)
+			if !ok {
+				return fmt.Errorf("could not get a string from field '%s'", field)
+			}
+			strs[i] = s
+		}
+		value, err = f.maskStrings(strs)
+	default:
+		return fmt.Errorf("could not get a string from field '%s'", field)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to mask field '%s': %v", field, err)
+	}
+
+	_, err = event.PutValue(field, value)
+	return err
+}
+
+func (f *reversibleMask) maskStrings(values []string) ([]string, error) {
+	masked := make([]string, len(values))
+	for i, s := range values {
+		var err error
+		if masked[i], err = f.cipher.Mask(s); err != nil {
+			return nil, err
+		}
+	}
+	return masked, nil
+}
+
+func (f *reversibleMask) String() string {
+	return fmt.Sprintf("reversible_mask=[fields=%s]", strings.Join(f.fields, ", "))
+}
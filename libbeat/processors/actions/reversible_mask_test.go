@@ -0,0 +1,123 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/mask"
+)
+
+const testMaskKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+
+func unmaskTestValue(t *testing.T, masked interface{}) string {
+	key, err := mask.DecodeKey(testMaskKey)
+	require.NoError(t, err)
+	c, err := mask.NewCipher(key)
+	require.NoError(t, err)
+
+	value, err := c.Unmask(masked.(string))
+	require.NoError(t, err)
+	return value
+}
+
+func TestReversibleMaskRoundTrip(t *testing.T) {
+	config, _ := common.NewConfigFrom(map[string]interface{}{
+		"fields": []string{"user", "client.ip"},
+		"key":    testMaskKey,
+	})
+
+	actual, err := runReversibleMask(t, config, common.MapStr{
+		"user":    "alice",
+		"client":  common.MapStr{"ip": "10.0.0.1"},
+		"message": "hello",
+	})
+	require.NoError(t, err)
+
+	assert.NotEqual(t, "alice", actual["user"])
+	assert.Equal(t, "alice", unmaskTestValue(t, actual["user"]))
+
+	ip, _ := actual.GetValue("client.ip")
+	assert.Equal(t, "10.0.0.1", unmaskTestValue(t, ip))
+	assert.Equal(t, "hello", actual["message"])
+}
+
+func TestReversibleMaskNoncePerEvent(t *testing.T) {
+	config, _ := common.NewConfigFrom(map[string]interface{}{
+		"fields": []string{"user"},
+		"key":    testMaskKey,
+	})
+
+	run := func() interface{} {
+		actual, err := runReversibleMask(t, config, common.MapStr{"user": "alice"})
+		require.NoError(t, err)
+		return actual["user"]
+	}
+
+	first, second := run(), run()
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, unmaskTestValue(t, first), unmaskTestValue(t, second))
+}
+
+func TestReversibleMaskArrays(t *testing.T) {
+	config, _ := common.NewConfigFrom(map[string]interface{}{
+		"fields": []string{"users"},
+		"key":    testMaskKey,
+	})
+
+	actual, err := runReversibleMask(t, config, common.MapStr{
+		"users": []interface{}{"alice", "bob"},
+	})
+	require.NoError(t, err)
+
+	users := actual["users"].([]string)
+	require.Len(t, users, 2)
+	assert.Equal(t, "alice", unmaskTestValue(t, users[0]))
+	assert.Equal(t, "bob", unmaskTestValue(t, users[1]))
+}
+
+func TestReversibleMaskMissingOrInvalid(t *testing.T) {
+	config, _ := common.NewConfigFrom(map[string]interface{}{
+		"fields": []string{"user"},
+		"key":    testMaskKey,
+	})
+
+	actual, err := runReversibleMask(t, config, common.MapStr{"message": "hello"})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{"message": "hello"}, actual)
+
+	actual, err = runReversibleMask(t, config, common.MapStr{"user": 42})
+	assert.Error(t, err)
+	assert.Equal(t, common.MapStr{"user": 42}, actual)
+}
+
+func TestReversibleMaskInvalidConfig(t *testing.T) {
+	tests := []map[string]interface{}{
+		{"fields": []string{"user"}},
+		{"key": testMaskKey},
+		{"fields": []string{"user"}, "key": "secret"},
+		{"fields": []string{"user"}, "key": "c2hvcnQ="},
+		{"fields": []string{"type"}, "key": testMaskKey},
+	}
+
+	for _, test := range tests {
+		cfg, err := common.NewConfigFrom(test)
+		require.NoError(t, err)
+
+		_, err = configChecked(newReversibleMask, requireFields("fields", "key"))(cfg)
+		assert.Error(t, err, "config: %v", test)
+	}
+}
+
+func runReversibleMask(t *testing.T, config *common.Config, input common.MapStr) (common.MapStr, error) {
+	p, err := newReversibleMask(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := p.Run(&beat.Event{Fields: input})
+	return actual.Fields, err
+}
@@ -0,0 +1,126 @@
+package actions
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+type splitField struct {
+	field     string
+	separator string
+	target    string
+	trim      bool
+	max       int
+	keepEmpty bool
+}
+
+type splitFieldConfig struct {
+	Field         string `config:"field"`
+	Separator     string `config:"separator" validate:"nonzero"`
+	Target        string `config:"target"`
+	Trim          bool   `config:"trim"`
+	Max           int    `config:"max" validate:"min=0"`
+	EmptySegments string `config:"empty_segments"`
+}
+
+const (
+	emptySegmentsKeep = "keep"
+	emptySegmentsDrop = "drop"
+)
+
+func init() {
+	processors.RegisterPlugin("split_field",
+		configChecked(newSplitField,
+			requireFields("field"),
+			allowedFields("field", "separator", "target", "trim", "max", "empty_segments", "when")))
+}
+
+func newSplitField(c *common.Config) (processors.Processor, error) {
+	config := splitFieldConfig{
+		Separator:     ",",
+		EmptySegments: emptySegmentsDrop,
+	}
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("fail to unpack the split_field configuration: %s", err)
+	}
+
+	for _, readOnly := range processors.MandatoryExportedFields {
+		if config.Target == readOnly || (config.Target == "" && config.Field == readOnly) {
+			return nil, fmt.Errorf("%s is a read only field, cannot override", readOnly)
+		}
+	}
+
+	var keepEmpty bool
+	switch strings.ToLower(config.EmptySegments) {
+	case emptySegmentsKeep:
+		keepEmpty = true
+	case emptySegmentsDrop:
+		keepEmpty = false
+	default:
+		return nil, fmt.Errorf("'%s' is not a valid empty_segments option for the "+
+			"split_field processor. Valid options are 'keep' and 'drop'", config.EmptySegments)
+	}
+
+	target := config.Target
+	if target == "" {
+		target = config.Field
+	}
+
+	return &splitField{
+		field:     config.Field,
+		separator: config.Separator,
+		target:    target,
+		trim:      config.Trim,
+		max:       config.Max,
+		keepEmpty: keepEmpty,
+	}, nil
+}
+
+func (f *splitField) Run(event *beat.Event) (*beat.Event, error) {
+	fieldValue, err := event.GetValue(f.field)
+	if err != nil {
+		if errors.Cause(err) == common.ErrKeyNotFound {
+			return event, nil
+		}
+		return event, err
+	}
+
+	value, ok := fieldValue.(string)
+	if !ok {
+		return event, fmt.Errorf("could not get a string from field '%s'", f.field)
+	}
+
+	if _, err := event.PutValue(f.target, f.split(value)); err != nil {
+		return event, err
+	}
+	return event, nil
+}
+
+func (f *splitField) split(value string) []string {
+	parts := strings.Split(value, f.separator)
+	out := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if f.trim {
+			part = strings.TrimSpace(part)
+		}
+		if part == "" && !f.keepEmpty {
+			continue
+		}
+
+		out = append(out, part)
+		if f.max > 0 && len(out) == f.max {
+			break
+		}
+	}
+	return out
+}
+
+func (f *splitField) String() string {
+	return fmt.Sprintf("split_field=[field=%s, separator=%q, target=%s]", f.field, f.separator, f.target)
+}
@@ -0,0 +1,137 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func TestSplitField(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		value    string
+		expected []string
+	}{
+		{
+			name:     "defaults",
+			config:   map[string]interface{}{"field": "tags"},
+			value:    "a,b,,c",
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name:     "no trim",
+			config:   map[string]interface{}{"field": "tags"},
+			value:    "a, b ,c",
+			expected: []string{"a", " b ", "c"},
+		},
+		{
+			name:     "trim",
+			config:   map[string]interface{}{"field": "tags", "trim": true},
+			value:    " a, b ,  ,c ",
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name:     "keep empty segments",
+			config:   map[string]interface{}{"field": "tags", "empty_segments": "keep"},
+			value:    "a,,b,",
+			expected: []string{"a", "", "b", ""},
+		},
+		{
+			name:     "keep empty segments after trim",
+			config:   map[string]interface{}{"field": "tags", "empty_segments": "keep", "trim": true},
+			value:    "a, ,b",
+			expected: []string{"a", "", "b"},
+		},
+		{
+			name:     "custom separator",
+			config:   map[string]interface{}{"field": "tags", "separator": " | "},
+			value:    "GET | /index.html | 200",
+			expected: []string{"GET", "/index.html", "200"},
+		},
+		{
+			name:     "max",
+			config:   map[string]interface{}{"field": "tags", "max": 2},
+			value:    "a,b,c,d",
+			expected: []string{"a", "b"},
+		},
+		{
+			name:     "max counts kept segments only",
+			config:   map[string]interface{}{"field": "tags", "max": 2},
+			value:    ",,a,,b,c",
+			expected: []string{"a", "b"},
+		},
+		{
+			name:     "empty string",
+			config:   map[string]interface{}{"field": "tags"},
+			value:    "",
+			expected: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		config, _ := common.NewConfigFrom(test.config)
+
+		actual, err := runSplitField(t, config, common.MapStr{"tags": test.value})
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, actual["tags"], test.name)
+	}
+}
+
+func TestSplitFieldTarget(t *testing.T) {
+	config, _ := common.NewConfigFrom(map[string]interface{}{
+		"field":  "raw.tags",
+		"target": "tags",
+	})
+
+	actual, err := runSplitField(t, config, common.MapStr{"raw": common.MapStr{"tags": "a,b"}})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"raw":  common.MapStr{"tags": "a,b"},
+		"tags": []string{"a", "b"},
+	}, actual)
+}
+
+func TestSplitFieldMissingOrInvalid(t *testing.T) {
+	config, _ := common.NewConfigFrom(map[string]interface{}{"field": "tags"})
+
+	actual, err := runSplitField(t, config, common.MapStr{"message": "hello"})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{"message": "hello"}, actual)
+
+	actual, err = runSplitField(t, config, common.MapStr{"tags": 42})
+	assert.Error(t, err)
+	assert.Equal(t, common.MapStr{"tags": 42}, actual)
+}
+
+func TestSplitFieldInvalidConfig(t *testing.T) {
+	tests := []map[string]interface{}{
+		{},
+		{"field": "tags", "empty_segments": "ignore"},
+		{"field": "tags", "max": -1},
+		{"field": "tags", "separator": ""},
+		{"field": "type"},
+	}
+
+	for _, test := range tests {
+		cfg, err := common.NewConfigFrom(test)
+		require.NoError(t, err)
+
+		_, err = configChecked(newSplitField, requireFields("field"))(cfg)
+		assert.Error(t, err, "config: %v", test)
+	}
+}
+
+func runSplitField(t *testing.T, config *common.Config, input common.MapStr) (common.MapStr, error) {
+	p, err := newSplitField(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := p.Run(&beat.Event{Fields: input})
+	return actual.Fields, err
+}
@@ -278,7 +278,8 @@ func setupFetchers(c *common.Config) ([]*metadataFetcher, error) {
 
 func newCloudMetadata(c *common.Config) (processors.Processor, error) {
 	config := struct {
-		Timeout time.Duration `config:"timeout"` // Amount of time to wait for responses from the metadata services.
+		Timeout                 time.Duration `config:"timeout"` // Amount of time to wait for responses from the metadata services.
+		processors.FieldsConfig `config:",inline"`
 	}{
 		Timeout: defaultTimeOut,
 	}
@@ -295,17 +296,18 @@ func newCloudMetadata(c *common.Config) (processors.Processor, error) {
 	result := fetchMetadata(fetchers, config.Timeout)
 	if result == nil {
 		logp.Info("add_cloud_metadata: hosting provider type not detected.")
-		return &addCloudMetadata{}, nil
+		return &addCloudMetadata{fields: config.FieldsConfig}, nil
 	}
 
 	logp.Info("add_cloud_metadata: hosting provider type detected as %v, metadata=%v",
 		result.provider, result.metadata.String())
 
-	return &addCloudMetadata{metadata: result.metadata}, nil
+	return &addCloudMetadata{metadata: result.metadata, fields: config.FieldsConfig}, nil
 }
 
 type addCloudMetadata struct {
 	metadata common.MapStr
+	fields   processors.FieldsConfig
 }
 
 func (p addCloudMetadata) Run(event *beat.Event) (*beat.Event, error) {
@@ -314,8 +316,9 @@ func (p addCloudMetadata) Run(event *beat.Event) (*beat.Event, error) {
 	}
 
 	// This overwrites the meta.cloud if it exists. But the cloud key should be
-	// reserved for this processor so this should happen.
-	_, err := event.PutValue("meta.cloud", p.metadata)
+	// reserved for this processor so this should happen. The metadata is
+	// shared by all events, so it is copied before being merged.
+	err := p.fields.AddFields(event, "meta", common.MapStr{"cloud": p.metadata.Clone()})
 
 	return event, err
 }
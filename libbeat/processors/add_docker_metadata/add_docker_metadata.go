@@ -20,6 +20,7 @@ type addDockerMetadata struct {
 	watcher         Watcher
 	fields          []string
 	sourceProcessor processors.Processor
+	target          processors.FieldsConfig
 }
 
 func newDockerMetadataProcessor(cfg *common.Config) (processors.Processor, error) {
@@ -67,6 +68,7 @@ func buildDockerMetadataProcessor(cfg *common.Config, watcherConstructor Watcher
 		watcher:         watcher,
 		fields:          config.Fields,
 		sourceProcessor: sourceProcessor,
+		target:          config.FieldsConfig,
 	}, nil
 }
 
@@ -103,11 +105,6 @@ func (d *addDockerMetadata) Run(event *beat.Event) (*beat.Event, error) {
 	container := d.watcher.Container(cid)
 	if container != nil {
 		meta := common.MapStr{}
-		metaIface, ok := event.Fields["docker"]
-		if ok {
-			meta = metaIface.(common.MapStr)
-		}
-
 		if len(container.Labels) > 0 {
 			labels := common.MapStr{}
 			for k, v := range container.Labels {
@@ -119,7 +116,9 @@ func (d *addDockerMetadata) Run(event *beat.Event) (*beat.Event, error) {
 		meta.Put("container.id", container.ID)
 		meta.Put("container.image", container.Image)
 		meta.Put("container.name", container.Name)
-		event.Fields["docker"] = meta
+		if err := d.target.AddFields(event, "docker", meta); err != nil {
+			logp.Debug("docker", "Error adding container metadata: %v", err)
+		}
 	} else {
 		logp.Debug("docker", "Container not found: %s", cid)
 	}
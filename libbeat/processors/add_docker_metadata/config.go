@@ -1,6 +1,10 @@
 package add_docker_metadata
 
-import "time"
+import (
+	"time"
+
+	"github.com/elastic/beats/libbeat/processors"
+)
 
 // Config for docker processor
 type Config struct {
@@ -13,6 +17,10 @@ type Config struct {
 	// Annotations are kept after container is killled, until they haven't been accessed
 	// for a full `cleanup_timeout`:
 	CleanupTimeout time.Duration `config:"cleanup_timeout"`
+
+	// Add the container metadata to the root of the event, instead of
+	// under `docker`.
+	processors.FieldsConfig `config:",inline"`
 }
 
 // TLSConfig for docker socket connection
@@ -0,0 +1,186 @@
+package add_geoip
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+var debugf = logp.MakeDebug("add_geoip")
+
+func init() {
+	processors.RegisterPlugin("add_geoip", newGeoIPProcessor)
+}
+
+type addGeoIP struct {
+	field  string
+	target string
+	db     *database
+
+	closeOnce sync.Once
+}
+
+func newGeoIPProcessor(cfg *common.Config) (processors.Processor, error) {
+	cfgwarn.Experimental("The add_geoip processor is experimental")
+
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, errors.Wrap(err, "fail to unpack the add_geoip configuration")
+	}
+
+	var period time.Duration
+	if config.Reload.Enabled {
+		period = config.Reload.Period
+	}
+	db, err := acquireDatabase(config.Database, period)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &addGeoIP{
+		field:  config.Field,
+		target: config.Target,
+		db:     db,
+	}
+	if p.target == "" {
+		p.target = defaultTarget(p.field)
+	}
+	return p, nil
+}
+
+// Close releases the database, stopping its watcher once no other processor
+// uses it.
+func (p *addGeoIP) Close() error {
+	p.closeOnce.Do(p.db.release)
+	return nil
+}
+
+// defaultTarget derives the ECS geo object from the IP field name, e.g.
+// `source.ip` -> `source.geo`.
+func defaultTarget(field string) string {
+	if idx := strings.LastIndex(field, "."); idx >= 0 {
+		return field[:idx] + ".geo"
+	}
+	return "geo"
+}
+
+func (p *addGeoIP) Run(event *beat.Event) (*beat.Event, error) {
+	v, err := event.GetValue(p.field)
+	if err != nil {
+		// no IP to enrich
+		return event, nil
+	}
+
+	ip := toIP(v)
+	if ip == nil {
+		debugf("Ignoring invalid IP %v in field %v", v, p.field)
+		return event, nil
+	}
+	if isPrivate(ip) {
+		return event, nil
+	}
+
+	record, found, err := p.db.lookup(ip)
+	if err != nil {
+		return event, errors.Wrapf(err, "GeoIP lookup of %v failed", ip)
+	}
+	if !found {
+		return event, nil
+	}
+
+	geo := record.toMapStr()
+	if len(geo) == 0 {
+		return event, nil
+	}
+	if _, err := event.PutValue(p.target, geo); err != nil {
+		return event, err
+	}
+	return event, nil
+}
+
+func (p *addGeoIP) String() string {
+	return fmt.Sprintf("add_geoip=[field=%v, target=%v, database=%v]", p.field, p.target, p.db.path)
+}
+
+func (r *cityRecord) toMapStr() common.MapStr {
+	geo := common.MapStr{}
+	putString(geo, "city_name", r.City.Names["en"])
+	putString(geo, "continent_name", r.Continent.Names["en"])
+	putString(geo, "country_iso_code", r.Country.IsoCode)
+	putString(geo, "country_name", r.Country.Names["en"])
+	if len(r.Subdivisions) > 0 {
+		putString(geo, "region_iso_code", r.Subdivisions[0].IsoCode)
+		putString(geo, "region_name", r.Subdivisions[0].Names["en"])
+	}
+	if r.Location.Latitude != nil && r.Location.Longitude != nil {
+		geo["location"] = common.MapStr{
+			"lat": *r.Location.Latitude,
+			"lon": *r.Location.Longitude,
+		}
+	}
+	return geo
+}
+
+func putString(m common.MapStr, key, value string) {
+	if value != "" {
+		m[key] = value
+	}
+}
+
+func toIP(v interface{}) net.IP {
+	switch ip := v.(type) {
+	case net.IP:
+		return ip
+	case string:
+		return net.ParseIP(ip)
+	}
+	return nil
+}
+
+var privateNetworks = parseCIDRs(
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+)
+
+// isPrivate reports whether ip is a loopback, link-local or private address,
+// for which no GeoIP information exists.
+func isPrivate(ip net.IP) bool {
+	if ip.IsUnspecified() {
+		return true
+	}
+	for _, n := range privateNetworks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = n
+	}
+	return nets
+}
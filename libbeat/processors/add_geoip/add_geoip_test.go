@@ -0,0 +1,402 @@
+package add_geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+var (
+	london = map[string]interface{}{
+		"city":      map[string]interface{}{"names": map[string]interface{}{"en": "London"}},
+		"continent": map[string]interface{}{"code": "EU", "names": map[string]interface{}{"en": "Europe"}},
+		"country":   map[string]interface{}{"iso_code": "GB", "names": map[string]interface{}{"en": "United Kingdom"}},
+		"location":  map[string]interface{}{"latitude": 51.5142, "longitude": -0.0931},
+	}
+	paris = map[string]interface{}{
+		"city":      map[string]interface{}{"names": map[string]interface{}{"en": "Paris"}},
+		"continent": map[string]interface{}{"code": "EU", "names": map[string]interface{}{"en": "Europe"}},
+		"country":   map[string]interface{}{"iso_code": "FR", "names": map[string]interface{}{"en": "France"}},
+		"location":  map[string]interface{}{"latitude": 48.8566, "longitude": 2.3522},
+	}
+)
+
+func TestPublicIP(t *testing.T) {
+	path := writeTestDatabase(t, map[string]map[string]interface{}{"81.2.69.0/24": london})
+	defer os.RemoveAll(filepath.Dir(path))
+
+	geo := common.MapStr{
+		"city_name":        "London",
+		"continent_name":   "Europe",
+		"country_iso_code": "GB",
+		"country_name":     "United Kingdom",
+		"location":         common.MapStr{"lat": 51.5142, "lon": -0.0931},
+	}
+
+	tests := []struct {
+		name     string
+		settings map[string]interface{}
+		field    string
+	}{
+		{
+			name: "default field",
+			settings: map[string]interface{}{
+				"database": path,
+				"reload":   map[string]interface{}{"enabled": false},
+			},
+			field: "client",
+		},
+		{
+			name: "custom field",
+			settings: map[string]interface{}{
+				"database": path,
+				"field":    "source.ip",
+				"reload":   map[string]interface{}{"enabled": false},
+			},
+			field: "source",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := common.NewConfigFrom(test.settings)
+			require.NoError(t, err)
+			p := newGeoIP(t, config)
+			defer p.Close()
+
+			event, err := p.Run(&beat.Event{Fields: common.MapStr{test.field: common.MapStr{"ip": "81.2.69.142"}}})
+			require.NoError(t, err)
+			assert.Equal(t, common.MapStr{"ip": "81.2.69.142", "geo": geo}, event.Fields[test.field])
+		})
+	}
+}
+
+func TestPrivateAndMissingIP(t *testing.T) {
+	path := writeTestDatabase(t, map[string]map[string]interface{}{
+		"81.2.69.0/24": london,
+		// private networks are never looked up, even if present in the database
+		"192.168.0.0/16": paris,
+	})
+	defer os.RemoveAll(filepath.Dir(path))
+
+	config, err := common.NewConfigFrom(map[string]interface{}{
+		"database": path,
+		"reload":   map[string]interface{}{"enabled": false},
+	})
+	require.NoError(t, err)
+	p := newGeoIP(t, config)
+	defer p.Close()
+
+	tests := []common.MapStr{
+		{"client": common.MapStr{"ip": "192.168.1.10"}},
+		{"client": common.MapStr{"ip": "127.0.0.1"}},
+		{"client": common.MapStr{"ip": "8.8.8.8"}},
+		{"client": common.MapStr{"ip": "not an ip"}},
+		{"message": "no ip field"},
+	}
+	for _, fields := range tests {
+		expected := fields.Clone()
+		event, err := p.Run(&beat.Event{Fields: fields})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, event.Fields)
+	}
+}
+
+func TestDatabaseReload(t *testing.T) {
+	path := writeTestDatabase(t, map[string]map[string]interface{}{"81.2.69.0/24": london})
+	defer os.RemoveAll(filepath.Dir(path))
+
+	config, err := common.NewConfigFrom(map[string]interface{}{
+		"database": path,
+		"reload":   map[string]interface{}{"enabled": true, "period": "10ms"},
+	})
+	require.NoError(t, err)
+	p := newGeoIP(t, config)
+	defer p.Close()
+
+	cityName := func() interface{} {
+		event, err := p.Run(&beat.Event{Fields: common.MapStr{"client": common.MapStr{"ip": "81.2.69.142"}}})
+		require.NoError(t, err)
+		v, _ := event.GetValue("client.geo.city_name")
+		return v
+	}
+	assert.Equal(t, "London", cityName())
+
+	// swap the database file
+	data := buildTestDatabase(t, map[string]map[string]interface{}{"81.2.69.0/24": paris})
+	tmp := path + ".tmp"
+	require.NoError(t, ioutil.WriteFile(tmp, data, 0644))
+	require.NoError(t, os.Chtimes(tmp, time.Now(), time.Now().Add(time.Minute)))
+	require.NoError(t, os.Rename(tmp, path))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for cityName() != "Paris" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, "Paris", cityName())
+
+	// an invalid database file keeps the last good one
+	require.NoError(t, ioutil.WriteFile(path, []byte("garbage"), 0644))
+	reloaded, err := p.db.reload()
+	assert.Error(t, err)
+	assert.False(t, reloaded)
+	assert.Equal(t, "Paris", cityName())
+}
+
+func TestSharedDatabase(t *testing.T) {
+	path := writeTestDatabase(t, map[string]map[string]interface{}{"81.2.69.0/24": london})
+	defer os.RemoveAll(filepath.Dir(path))
+
+	// The processors created again on each config reload share the database
+	// and its watcher.
+	config, err := common.NewConfigFrom(map[string]interface{}{
+		"database": path,
+		"reload":   map[string]interface{}{"enabled": true, "period": "10ms"},
+	})
+	require.NoError(t, err)
+	first := newGeoIP(t, config)
+	second := newGeoIP(t, config)
+	assert.True(t, first.db == second.db)
+	db := first.db
+
+	require.NoError(t, first.Close())
+	require.NoError(t, first.Close())
+	select {
+	case <-db.done:
+		t.Fatal("watcher stopped while the database is still used")
+	default:
+	}
+
+	// The watcher is stopped once the database is not used anymore.
+	require.NoError(t, second.Close())
+	select {
+	case <-db.done:
+	default:
+		t.Fatal("watcher not stopped")
+	}
+
+	third := newGeoIP(t, config)
+	defer third.Close()
+	assert.False(t, third.db == db)
+}
+
+func TestReleaseOnInvalidProcessors(t *testing.T) {
+	path := writeTestDatabase(t, map[string]map[string]interface{}{"81.2.69.0/24": london})
+	defer os.RemoveAll(filepath.Dir(path))
+
+	geoip, err := common.NewConfigFrom(map[string]interface{}{"database": path})
+	require.NoError(t, err)
+	invalid, err := common.NewConfigFrom(map[string]interface{}{"database": ""})
+	require.NoError(t, err)
+
+	// The database of the processors created before the invalid processor is
+	// released.
+	_, err = processors.New(processors.PluginConfig{
+		{"add_geoip": geoip},
+		{"add_geoip": invalid},
+	})
+	assert.Error(t, err)
+
+	databases.Lock()
+	defer databases.Unlock()
+	for key := range databases.open {
+		assert.NotEqual(t, path, key.path)
+	}
+}
+
+func TestMissingDatabase(t *testing.T) {
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"database": "/does/not/exist.mmdb",
+	})
+	require.NoError(t, err)
+
+	_, err = newGeoIPProcessor(cfg)
+	assert.Error(t, err)
+}
+
+func TestDefaultTarget(t *testing.T) {
+	assert.Equal(t, "client.geo", defaultTarget("client.ip"))
+	assert.Equal(t, "destination.geo", defaultTarget("destination.ip"))
+	assert.Equal(t, "geo", defaultTarget("ip"))
+}
+
+func newGeoIP(t *testing.T, config *common.Config) *addGeoIP {
+	p, err := newGeoIPProcessor(config)
+	if err != nil {
+		t.Fatalf("error initializing add_geoip: %s", err)
+	}
+	return p.(*addGeoIP)
+}
+
+func writeTestDatabase(t *testing.T, networks map[string]map[string]interface{}) string {
+	dir, err := ioutil.TempDir("", "add_geoip")
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "GeoIP2-City-Test.mmdb")
+	require.NoError(t, ioutil.WriteFile(path, buildTestDatabase(t, networks), 0644))
+	return path
+}
+
+// buildTestDatabase creates an IPv4 only MaxMind DB file (record size 24)
+// containing the given networks.
+func buildTestDatabase(t *testing.T, networks map[string]map[string]interface{}) []byte {
+	const (
+		empty = -1
+		data  = -2
+	)
+
+	type node struct {
+		children [2]int
+		offsets  [2]int
+	}
+
+	var (
+		nodes   = []node{{children: [2]int{empty, empty}}}
+		section bytes.Buffer
+	)
+
+	cidrs := make([]string, 0, len(networks))
+	for cidr := range networks {
+		cidrs = append(cidrs, cidr)
+	}
+	sort.Strings(cidrs)
+
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		require.NoError(t, err)
+		ip := network.IP.To4()
+		require.NotNil(t, ip, "only IPv4 networks are supported")
+		prefix, _ := network.Mask.Size()
+
+		offset := section.Len()
+		encodeMMDBValue(&section, networks[cidr])
+
+		current := 0
+		for i := 0; i < prefix; i++ {
+			bit := (ip[i/8] >> uint(7-i%8)) & 1
+			if i == prefix-1 {
+				nodes[current].children[bit] = data
+				nodes[current].offsets[bit] = offset
+				break
+			}
+
+			next := nodes[current].children[bit]
+			if next < 0 {
+				nodes = append(nodes, node{children: [2]int{empty, empty}})
+				next = len(nodes) - 1
+				nodes[current].children[bit] = next
+			}
+			current = next
+		}
+	}
+
+	var buf bytes.Buffer
+	nodeCount := len(nodes)
+	for _, n := range nodes {
+		for i, child := range n.children {
+			var record int
+			switch child {
+			case empty:
+				record = nodeCount
+			case data:
+				record = nodeCount + 16 + n.offsets[i]
+			default:
+				record = child
+			}
+			buf.Write([]byte{byte(record >> 16), byte(record >> 8), byte(record)})
+		}
+	}
+	buf.Write(make([]byte, 16))
+	buf.Write(section.Bytes())
+	buf.WriteString("\xAB\xCD\xEFMaxMind.com")
+	encodeMMDBValue(&buf, map[string]interface{}{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(time.Now().Unix()),
+		"database_type":               "GeoIP2-City",
+		"description":                 map[string]interface{}{"en": "add_geoip test database"},
+		"ip_version":                  uint16(4),
+		"languages":                   []interface{}{"en"},
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(24),
+	})
+	return buf.Bytes()
+}
+
+func encodeMMDBValue(buf *bytes.Buffer, v interface{}) {
+	const (
+		typeString = 2
+		typeDouble = 3
+		typeUint16 = 5
+		typeUint32 = 6
+		typeMap    = 7
+		typeUint64 = 9
+		typeArray  = 11
+	)
+
+	control := func(typ, size int) {
+		if typ > 7 {
+			buf.WriteByte(byte(size))
+			buf.WriteByte(byte(typ - 7))
+		} else {
+			buf.WriteByte(byte(typ<<5 | size))
+		}
+	}
+	uint := func(typ int, v uint64, size int) {
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, v)
+		control(typ, size)
+		buf.Write(b[8-size:])
+	}
+
+	switch val := v.(type) {
+	case string:
+		if len(val) >= 29 {
+			panic("long strings are not supported")
+		}
+		control(typeString, len(val))
+		buf.WriteString(val)
+	case float64:
+		control(typeDouble, 8)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(val))
+	case uint16:
+		uint(typeUint16, uint64(val), 2)
+	case uint32:
+		uint(typeUint32, uint64(val), 4)
+	case uint64:
+		uint(typeUint64, val, 8)
+	case []interface{}:
+		control(typeArray, len(val))
+		for _, elem := range val {
+			encodeMMDBValue(buf, elem)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		control(typeMap, len(keys))
+		for _, k := range keys {
+			encodeMMDBValue(buf, k)
+			encodeMMDBValue(buf, val[k])
+		}
+	default:
+		panic("unsupported type")
+	}
+}
@@ -0,0 +1,36 @@
+package add_geoip
+
+import "time"
+
+// Config for the add_geoip processor.
+type Config struct {
+	// Database is the path to the MaxMind MMDB file used for lookups.
+	Database string `config:"database" validate:"required"`
+
+	// Field holds the IP address to look up.
+	Field string `config:"field"`
+
+	// Target is the key the geo information is written to. If empty the geo
+	// information is written next to the source field (`client.ip` ->
+	// `client.geo`).
+	Target string `config:"target"`
+
+	// Reload configures how often the database file is checked for updates.
+	Reload ReloadConfig `config:"reload"`
+}
+
+// ReloadConfig for the database file watcher.
+type ReloadConfig struct {
+	Enabled bool          `config:"enabled"`
+	Period  time.Duration `config:"period" validate:"positive,nonzero"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		Field: "client.ip",
+		Reload: ReloadConfig{
+			Enabled: true,
+			Period:  60 * time.Second,
+		},
+	}
+}
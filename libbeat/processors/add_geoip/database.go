@@ -0,0 +1,215 @@
+package add_geoip
+
+import (
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// cityRecord contains the subset of a GeoIP2/GeoLite2 City record that is
+// copied into events.
+type cityRecord struct {
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Continent struct {
+		Code  string            `maxminddb:"code"`
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"continent"`
+	Country struct {
+		IsoCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		IsoCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	Location struct {
+		Latitude  *float64 `maxminddb:"latitude"`
+		Longitude *float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+}
+
+// database wraps a maxminddb.Reader, that is swapped out whenever the
+// underlying file changes.
+type database struct {
+	path string
+
+	mu      sync.RWMutex
+	reader  *maxminddb.Reader
+	modTime time.Time
+	size    int64
+
+	// key, refs and done are protected by the mutex of databases.
+	key  databaseKey
+	refs int
+	done chan struct{}
+}
+
+// databaseKey identifies a shared database by its file and reload period, a
+// period of 0 disables reloading.
+type databaseKey struct {
+	path   string
+	period time.Duration
+}
+
+// databases are the open databases, shared by the processors using the same
+// file, so processors created again on each config reload do not start
+// another watcher.
+var databases = struct {
+	sync.Mutex
+	open map[databaseKey]*database
+}{open: map[databaseKey]*database{}}
+
+func openDatabase(path string) (*database, error) {
+	db := &database{path: path}
+	if _, err := db.reload(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// acquireDatabase returns the shared database of the file, opening it and
+// starting its watcher if it is not open yet. The database must be released
+// once it is not used anymore.
+func acquireDatabase(path string, period time.Duration) (*database, error) {
+	databases.Lock()
+	defer databases.Unlock()
+
+	key := databaseKey{path: path, period: period}
+	if db, ok := databases.open[key]; ok {
+		db.refs++
+		return db, nil
+	}
+
+	db, err := openDatabase(path)
+	if err != nil {
+		return nil, err
+	}
+	db.key = key
+	db.refs = 1
+	if period > 0 {
+		db.done = make(chan struct{})
+		go db.watch(period, db.done)
+	}
+	databases.open[key] = db
+	return db, nil
+}
+
+// release drops a reference to the database, stopping its watcher once it is
+// not used anymore. The reader stays valid for lookups still running.
+func (db *database) release() {
+	databases.Lock()
+	defer databases.Unlock()
+
+	db.refs--
+	if db.refs > 0 {
+		return
+	}
+	delete(databases.open, db.key)
+	if db.done != nil {
+		close(db.done)
+	}
+}
+
+// lookup returns the city record for the given IP. The returned bool is false
+// if the database has no entry for the address.
+func (db *database) lookup(ip net.IP) (*cityRecord, bool, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var record cityRecord
+	offset, err := db.reader.LookupOffset(ip)
+	if err != nil {
+		return nil, false, err
+	}
+	if offset == maxminddb.NotFound {
+		return nil, false, nil
+	}
+	if err := db.reader.Decode(offset, &record); err != nil {
+		return nil, false, err
+	}
+	return &record, true, nil
+}
+
+// reload opens the database file again if it has been modified since it was
+// last loaded. It returns true if a new database has been loaded. On error the
+// currently loaded database is kept.
+func (db *database) reload() (bool, error) {
+	info, err := os.Stat(db.path)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to stat GeoIP database %v", db.path)
+	}
+
+	db.mu.RLock()
+	unchanged := db.reader != nil && info.ModTime().Equal(db.modTime) && info.Size() == db.size
+	db.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	// The reader memory maps the file. Load the file contents into memory
+	// instead, so the file can be replaced in place without affecting running
+	// lookups.
+	reader, err := openReader(db.path)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to open GeoIP database %v", db.path)
+	}
+
+	db.mu.Lock()
+	db.reader = reader
+	db.modTime = info.ModTime()
+	db.size = info.Size()
+	db.mu.Unlock()
+	return true, nil
+}
+
+// watch periodically checks the database file for updates until done is
+// closed.
+func (db *database) watch(period time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		reloaded, err := db.reload()
+		if err != nil {
+			logp.Err("add_geoip: %v", err)
+			continue
+		}
+		if reloaded {
+			logp.Info("add_geoip: reloaded GeoIP database %v", db.path)
+		}
+	}
+}
+
+func openReader(path string) (*maxminddb.Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, info.Size())
+	if _, err := io.ReadFull(f, buf); err != nil {
+		return nil, err
+	}
+	return maxminddb.FromBytes(buf)
+}
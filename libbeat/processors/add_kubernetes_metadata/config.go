@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/processors"
 )
 
 type kubeAnnotatorConfig struct {
@@ -22,6 +23,10 @@ type kubeAnnotatorConfig struct {
 	IncludeLabels      []string      `config:"include_labels"`
 	ExcludeLabels      []string      `config:"exclude_labels"`
 	IncludeAnnotations []string      `config:"include_annotations"`
+
+	// Add the pod metadata to the root of the event, instead of under
+	// `kubernetes`.
+	processors.FieldsConfig `config:",inline"`
 }
 
 type Enabled struct {
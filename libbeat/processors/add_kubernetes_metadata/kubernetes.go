@@ -29,6 +29,7 @@ var (
 type kubernetesAnnotator struct {
 	podWatcher *PodWatcher
 	matchers   *Matchers
+	fields     processors.FieldsConfig
 }
 
 func init() {
@@ -178,7 +179,7 @@ func newKubernetesAnnotator(cfg *common.Config) (processors.Processor, error) {
 		watcher := NewPodWatcher(client, &indexers, config.SyncPeriod, config.CleanupTimeout, config.Host)
 
 		if watcher.Run() {
-			return &kubernetesAnnotator{podWatcher: watcher, matchers: &matchers}, nil
+			return &kubernetesAnnotator{podWatcher: watcher, matchers: &matchers, fields: config.FieldsConfig}, nil
 		}
 
 		return nil, fatalError
@@ -198,18 +199,8 @@ func (k *kubernetesAnnotator) Run(event *beat.Event) (*beat.Event, error) {
 		return event, nil
 	}
 
-	meta := common.MapStr{}
-	metaIface, ok := event.Fields["kubernetes"]
-	if !ok {
-		event.Fields["kubernetes"] = common.MapStr{}
-	} else {
-		meta = metaIface.(common.MapStr)
-	}
-
-	meta.Update(metadata)
-	event.Fields["kubernetes"] = meta
-
-	return event, nil
+	err := k.fields.AddFields(event, "kubernetes", metadata.Clone())
+	return event, err
 }
 
 func (*kubernetesAnnotator) String() string { return "add_kubernetes_metadata" }
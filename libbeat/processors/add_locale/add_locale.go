@@ -14,6 +14,12 @@ import (
 
 type addLocale struct {
 	TimezoneFormat TimezoneFormat
+	Field          string
+
+	// now and location are used to determine the time zone of each event, so
+	// changes from and to daylight saving time are reflected immediately.
+	now      func() time.Time
+	location *time.Location
 }
 
 // TimezoneFormat type
@@ -41,8 +47,10 @@ func init() {
 func newAddLocale(c *common.Config) (processors.Processor, error) {
 	config := struct {
 		Format string `config:"format"`
+		Field  string `config:"field"`
 	}{
 		Format: "offset",
+		Field:  "beat.timezone",
 	}
 
 	err := c.Unpack(&config)
@@ -50,7 +58,15 @@ func newAddLocale(c *common.Config) (processors.Processor, error) {
 		return nil, errors.Wrap(err, "fail to unpack the add_locale configuration")
 	}
 
-	var loc addLocale
+	if config.Field == "" {
+		return nil, errors.New("the field of the add_locale processor must not be empty")
+	}
+
+	loc := addLocale{
+		Field:    config.Field,
+		now:      time.Now,
+		location: time.Local,
+	}
 
 	switch strings.ToLower(config.Format) {
 	case "abbreviation":
@@ -67,9 +83,9 @@ func newAddLocale(c *common.Config) (processors.Processor, error) {
 }
 
 func (l addLocale) Run(event *beat.Event) (*beat.Event, error) {
-	zone, offset := time.Now().Zone()
+	zone, offset := l.now().In(l.location).Zone()
 	format := l.Format(zone, offset)
-	event.PutValue("beat.timezone", format)
+	event.PutValue(l.Field, format)
 	return event, nil
 }
 
@@ -99,5 +115,5 @@ func (l addLocale) Format(zone string, offset int) string {
 }
 
 func (l addLocale) String() string {
-	return "add_locale=[format=" + l.TimezoneFormat.String() + "]"
+	return "add_locale=[format=" + l.TimezoneFormat.String() + ", field=" + l.Field + "]"
 }
@@ -67,6 +67,100 @@ func TestTimezoneFormat(t *testing.T) {
 	assert.Regexp(t, regexp.MustCompile(`\-[\d]{2}\:[\d]{2}`), negVal)
 }
 
+func newTestLocale(t *testing.T, settings map[string]interface{}, zone string, now *time.Time) addLocale {
+	config, err := common.NewConfigFrom(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := newAddLocale(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		t.Skipf("time zone %v not available: %v", zone, err)
+	}
+
+	l := p.(addLocale)
+	l.location = loc
+	l.now = func() time.Time { return *now }
+	return l
+}
+
+func TestDaylightSavingTime(t *testing.T) {
+	// Clocks are set forward from 02:00 CET to 03:00 CEST on 2018-03-25, and
+	// back from 03:00 CEST to 02:00 CET on 2018-10-28.
+	tests := []struct {
+		time         string
+		offset, abbr string
+	}{
+		{"2018-03-25T00:59:59Z", "+01:00", "CET"},
+		{"2018-03-25T01:00:00Z", "+02:00", "CEST"},
+		{"2018-10-28T00:59:59Z", "+02:00", "CEST"},
+		{"2018-10-28T01:00:00Z", "+01:00", "CET"},
+	}
+
+	var now time.Time
+	offset := newTestLocale(t, map[string]interface{}{"field": "event.timezone"}, "Europe/Berlin", &now)
+	abbr := newTestLocale(t, map[string]interface{}{"field": "event.timezone", "format": "abbreviation"}, "Europe/Berlin", &now)
+
+	for _, test := range tests {
+		var err error
+		now, err = time.Parse(time.RFC3339, test.time)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, l := range []struct {
+			processor addLocale
+			expected  string
+		}{
+			{offset, test.offset},
+			{abbr, test.abbr},
+		} {
+			event, err := l.processor.Run(&beat.Event{Fields: common.MapStr{}})
+			assert.NoError(t, err)
+			value, err := event.GetValue("event.timezone")
+			assert.NoError(t, err)
+			assert.Equal(t, l.expected, value, "%v at %v", l.processor, test.time)
+		}
+	}
+}
+
+func TestOffsetFormat(t *testing.T) {
+	now := time.Date(2018, 1, 15, 12, 0, 0, 0, time.UTC)
+	tests := map[string]string{
+		"UTC":              "+00:00",
+		"Asia/Kolkata":     "+05:30",
+		"America/St_Johns": "-03:30",
+		"America/Caracas":  "-04:00",
+	}
+
+	for zone, expected := range tests {
+		l := newTestLocale(t, map[string]interface{}{}, zone, &now)
+		event, err := l.Run(&beat.Event{Fields: common.MapStr{}})
+		assert.NoError(t, err)
+		assert.Equal(t, common.MapStr{"beat": common.MapStr{"timezone": expected}}, event.Fields, zone)
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for _, settings := range []map[string]interface{}{
+		{"format": "name"},
+		{"field": ""},
+	} {
+		config, err := common.NewConfigFrom(settings)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = newAddLocale(config)
+		assert.Error(t, err, "%v", settings)
+	}
+}
+
 func getActualValue(t *testing.T, config *common.Config, input common.MapStr) common.MapStr {
 	if testing.Verbose() {
 		logp.LogInit(logp.LOG_DEBUG, "", false, true, []string{"*"})
@@ -3,6 +3,7 @@ package processors
 import (
 	"errors"
 	"fmt"
+	"math"
 	"reflect"
 	"strconv"
 	"strings"
@@ -308,6 +309,9 @@ func (c *Condition) checkMatches(event *beat.Event) bool {
 func (c *Condition) checkRange(event *beat.Event) bool {
 	checkValue := func(value float64, rangeValue RangeValue) bool {
 
+		if math.IsNaN(value) {
+			return false
+		}
 		if rangeValue.gte != nil {
 			if value < *rangeValue.gte {
 				return false
@@ -338,7 +342,7 @@ func (c *Condition) checkRange(event *beat.Event) bool {
 			return false
 		}
 
-		switch value.(type) {
+		switch v := value.(type) {
 		case int, int8, int16, int32, int64:
 			intValue := reflect.ValueOf(value).Int()
 
@@ -360,6 +364,17 @@ func (c *Condition) checkRange(event *beat.Event) bool {
 				return false
 			}
 
+		case string:
+			floatValue, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				logp.Debug("processors", "non-numeric value '%s' of field %s in range condition", v, field)
+				return false
+			}
+
+			if !checkValue(floatValue, rangeValue) {
+				return false
+			}
+
 		default:
 			logp.Warn("unexpected type %T in range condition. ", value)
 			return false
@@ -462,6 +477,11 @@ func (r *WhenProcessor) String() string {
 	return fmt.Sprintf("%v, condition=%v", r.p.String(), r.condition.String())
 }
 
+// Close closes the conditional processor, if it holds resources.
+func (r *WhenProcessor) Close() error {
+	return closeProcessor(r.p)
+}
+
 func addCondition(
 	cfg *common.Config,
 	p Processor,
@@ -334,6 +334,53 @@ func TestRangeCondition(t *testing.T) {
 	assert.False(t, conds[3].Check(event))
 }
 
+func TestRangeConditionTypes(t *testing.T) {
+	conds := GetConditions(t, []ConditionConfig{
+		{
+			Range: &ConditionFields{fields: map[string]interface{}{
+				"value.gte": 400,
+				"value.lt":  500,
+			}},
+		},
+		{
+			Range: &ConditionFields{fields: map[string]interface{}{
+				"value.gt":  0.5,
+				"value.lte": 0.8,
+			}},
+		},
+	})
+
+	tests := []struct {
+		value      interface{}
+		int, float bool
+	}{
+		{value: 404, int: true},
+		{value: int64(500)},
+		{value: uint16(400), int: true},
+		{value: 0.8, float: true},
+		{value: float32(0.5)},
+		{value: common.Float(450.5), int: true},
+		{value: "404", int: true},
+		{value: " 0.6 ", float: true},
+		{value: "1e3"},
+		{value: "not a number"},
+		{value: "NaN"},
+		{value: true},
+		{value: []int{404}},
+	}
+
+	for _, test := range tests {
+		event := &beat.Event{Fields: common.MapStr{"value": test.value}}
+		assert.Equal(t, test.int, conds[0].Check(event), "%#v", test.value)
+		assert.Equal(t, test.float, conds[1].Check(event), "%#v", test.value)
+	}
+
+	// Missing fields never match.
+	event := &beat.Event{Fields: common.MapStr{"other": 404}}
+	assert.False(t, conds[0].Check(event))
+	assert.False(t, conds[1].Check(event))
+}
+
 func TestORCondition(t *testing.T) {
 	if testing.Verbose() {
 		logp.LogInit(logp.LOG_DEBUG, "", false, true, []string{"*"})
@@ -0,0 +1,94 @@
+package delta
+
+import (
+	"sync"
+	"time"
+)
+
+// sample is the last value of a field of an entity, and the timestamp of its
+// event.
+type sample struct {
+	value     number
+	timestamp time.Time
+}
+
+// cache holds the last samples of the entities. In contrast to common.Cache,
+// it is bounded in size, the least recently updated entity is evicted when a
+// new entity is added to a full cache.
+type cache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	entries map[string]*cacheEntry
+	now     func() time.Time
+}
+
+type cacheEntry struct {
+	samples map[string]sample // by field
+	updated time.Time
+}
+
+func newCache(ttl time.Duration, size int) *cache {
+	return &cache{
+		ttl:     ttl,
+		size:    size,
+		entries: map[string]*cacheEntry{},
+		now:     time.Now,
+	}
+}
+
+// update replaces the samples of the entity by the result of fn, called with
+// the previous samples, which are nil for a new or expired entity. The cache
+// is locked while fn runs.
+func (c *cache) update(key string, fn func(previous map[string]sample) map[string]sample) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	entry, found := c.entries[key]
+	if found && now.Sub(entry.updated) > c.ttl {
+		delete(c.entries, key)
+		entry, found = nil, false
+	}
+
+	var previous map[string]sample
+	if found {
+		previous = entry.samples
+	}
+	samples := fn(previous)
+
+	if !found {
+		if len(c.entries) >= c.size {
+			c.evict(now)
+		}
+		entry = &cacheEntry{}
+		c.entries[key] = entry
+	}
+	entry.samples = samples
+	entry.updated = now
+}
+
+// evict removes the expired entities, or the least recently updated one if
+// none has expired.
+func (c *cache) evict(now time.Time) {
+	var oldest string
+	var oldestUpdated time.Time
+	for key, entry := range c.entries {
+		if now.Sub(entry.updated) > c.ttl {
+			delete(c.entries, key)
+			continue
+		}
+		if oldest == "" || entry.updated.Before(oldestUpdated) {
+			oldest, oldestUpdated = key, entry.updated
+		}
+	}
+	if len(c.entries) >= c.size {
+		delete(c.entries, oldest)
+	}
+}
+
+func (c *cache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
@@ -0,0 +1,68 @@
+package delta
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func put(c *cache, key string, v int64) {
+	c.update(key, func(map[string]sample) map[string]sample {
+		return map[string]sample{"v": {value: number{i: v}}}
+	})
+}
+
+func get(c *cache, key string) (map[string]sample, bool) {
+	var samples map[string]sample
+	c.update(key, func(previous map[string]sample) map[string]sample {
+		samples = previous
+		return previous
+	})
+	return samples, samples != nil
+}
+
+func TestCacheTTL(t *testing.T) {
+	c := newCache(time.Minute, 10)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	put(c, "a", 1)
+	now = now.Add(30 * time.Second)
+	samples, found := get(c, "a")
+	assert.True(t, found)
+	assert.Equal(t, int64(1), samples["v"].value.i)
+
+	// Updates keep the entity, idle entities expire.
+	now = now.Add(45 * time.Second)
+	_, found = get(c, "a")
+	assert.True(t, found)
+	now = now.Add(2 * time.Minute)
+	_, found = get(c, "a")
+	assert.False(t, found)
+}
+
+func TestCacheSize(t *testing.T) {
+	c := newCache(time.Minute, 2)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	put(c, "a", 1)
+	now = now.Add(time.Second)
+	put(c, "b", 2)
+	now = now.Add(time.Second)
+	put(c, "a", 3)
+
+	// The least recently updated entity is evicted.
+	now = now.Add(time.Second)
+	put(c, "c", 4)
+	assert.Equal(t, 2, c.len())
+	assert.NotContains(t, c.entries, "b")
+	assert.Contains(t, c.entries, "a")
+
+	// Expired entities are all removed to make room.
+	now = now.Add(2 * time.Minute)
+	put(c, "d", 5)
+	assert.Equal(t, 1, c.len())
+	assert.Contains(t, c.entries, "d")
+}
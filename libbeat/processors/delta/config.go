@@ -0,0 +1,69 @@
+package delta
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Config for the delta processor.
+type Config struct {
+	// Fields are the numeric fields the delta and rate are computed for.
+	Fields []string `config:"fields" validate:"required"`
+
+	// Key are the fields identifying the entity of an event, like a host or
+	// process. The values of each entity are compared separately. Without
+	// key, all events are compared.
+	Key []string `config:"key"`
+
+	// Target is the field the results are written to, as
+	// `<target>.<field>.delta` and `<target>.<field>.rate`.
+	Target string `config:"target"`
+
+	// Type is `counter` for values only increasing until they are reset, or
+	// `gauge` for values that can also decrease.
+	Type string `config:"type"`
+
+	// Cache configures how long the last values of an entity are kept.
+	Cache CacheConfig `config:"cache"`
+}
+
+// CacheConfig for the last values of the entities.
+type CacheConfig struct {
+	// TTL is how long the last values of an entity are kept without new
+	// events of the entity.
+	TTL time.Duration `config:"ttl" validate:"positive"`
+
+	// Size is the maximum number of entities kept. The least recently seen
+	// entity is evicted to make room for a new one.
+	Size int `config:"size" validate:"min=1"`
+}
+
+const (
+	typeCounter = "counter"
+	typeGauge   = "gauge"
+)
+
+func defaultConfig() Config {
+	return Config{
+		Target: "change",
+		Type:   typeCounter,
+		Cache: CacheConfig{
+			TTL:  10 * time.Minute,
+			Size: 10000,
+		},
+	}
+}
+
+// Validate checks the target and the type of the values.
+func (c *Config) Validate() error {
+	if c.Target == "" {
+		return errors.New("target must not be empty")
+	}
+	switch c.Type {
+	case typeCounter, typeGauge:
+		return nil
+	default:
+		return fmt.Errorf("invalid type '%v', use counter or gauge", c.Type)
+	}
+}
@@ -0,0 +1,204 @@
+package delta
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+var debugf = logp.MakeDebug("delta")
+
+func init() {
+	processors.RegisterPlugin("delta", newDeltaProcessor)
+}
+
+type delta struct {
+	fields  []string
+	key     []string
+	target  string
+	counter bool
+	cache   *cache
+}
+
+// number is a numeric value, integers are kept as integers so their deltas
+// are exact.
+type number struct {
+	isFloat bool
+	i       int64
+	f       float64
+}
+
+func newDeltaProcessor(cfg *common.Config) (processors.Processor, error) {
+	cfgwarn.Experimental("The delta processor is experimental")
+
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, errors.Wrap(err, "fail to unpack the delta configuration")
+	}
+
+	return &delta{
+		fields:  config.Fields,
+		key:     config.Key,
+		target:  config.Target,
+		counter: config.Type == typeCounter,
+		cache:   newCache(config.Cache.TTL, config.Cache.Size),
+	}, nil
+}
+
+// Run writes the delta and the rate per second of the fields since the
+// previous event of the same entity, by the timestamps of the events. Events
+// older than the previous event of their entity are not compared.
+func (p *delta) Run(event *beat.Event) (*beat.Event, error) {
+	key, ok := p.entity(event)
+	if !ok {
+		return event, nil
+	}
+
+	values := map[string]number{}
+	for _, field := range p.fields {
+		v, err := event.GetValue(field)
+		if err != nil {
+			continue
+		}
+		n, ok := toNumber(v)
+		if !ok {
+			debugf("Ignoring field %v with non-numeric value of type %T", field, v)
+			continue
+		}
+		values[field] = n
+	}
+	if len(values) == 0 {
+		return event, nil
+	}
+
+	results := common.MapStr{}
+	p.cache.update(key, func(previous map[string]sample) map[string]sample {
+		samples := make(map[string]sample, len(previous)+len(values))
+		for field, s := range previous {
+			samples[field] = s
+		}
+
+		for field, value := range values {
+			current := sample{value: value, timestamp: event.Timestamp}
+			last, found := previous[field]
+			if !found {
+				samples[field] = current
+				continue
+			}
+			if current.timestamp.Before(last.timestamp) {
+				debugf("Ignoring field %v of an event older than the previous event of its entity", field)
+				continue
+			}
+			samples[field] = current
+			results[field] = p.compare(last, current)
+		}
+		return samples
+	})
+
+	for field, result := range results {
+		if _, err := event.PutValue(p.target+"."+field, result); err != nil {
+			return event, errors.Wrapf(err, "failed to write the delta of %v", field)
+		}
+	}
+	return event, nil
+}
+
+// entity returns the key of the entity of the event, or false if the event
+// misses a key field.
+func (p *delta) entity(event *beat.Event) (string, bool) {
+	values := make([]string, len(p.key))
+	for i, field := range p.key {
+		v, err := event.GetValue(field)
+		if err != nil {
+			debugf("Ignoring event without key field %v", field)
+			return "", false
+		}
+		values[i] = fmt.Sprint(v)
+	}
+	return strings.Join(values, "\x00"), true
+}
+
+// compare returns the delta and the rate of a field between two samples. A
+// counter lower than its previous value has been reset, so its delta is its
+// current value.
+func (p *delta) compare(last, current sample) common.MapStr {
+	result := common.MapStr{}
+
+	reset := p.counter && current.value.less(last.value)
+	var d number
+	if reset {
+		d = current.value
+		result["reset"] = true
+	} else {
+		d = current.value.sub(last.value)
+	}
+	result["delta"] = d.value()
+
+	// Without elapsed time there is no rate.
+	if elapsed := current.timestamp.Sub(last.timestamp).Seconds(); elapsed > 0 {
+		result["rate"] = d.float() / elapsed
+	}
+	return result
+}
+
+func (p *delta) String() string {
+	return fmt.Sprintf("delta=[fields=%v, key=%v, target=%v, counter=%v]",
+		p.fields, p.key, p.target, p.counter)
+}
+
+func toNumber(v interface{}) (number, bool) {
+	if f, ok := v.(common.Float); ok {
+		return number{isFloat: true, f: float64(f)}, true
+	}
+
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return number{i: rv.Int()}, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return number{isFloat: true, f: float64(u)}, true
+		}
+		return number{i: int64(u)}, true
+	case reflect.Float32, reflect.Float64:
+		return number{isFloat: true, f: rv.Float()}, true
+	}
+	return number{}, false
+}
+
+func (n number) float() float64 {
+	if n.isFloat {
+		return n.f
+	}
+	return float64(n.i)
+}
+
+func (n number) value() interface{} {
+	if n.isFloat {
+		return n.f
+	}
+	return n.i
+}
+
+func (n number) less(o number) bool {
+	if n.isFloat || o.isFloat {
+		return n.float() < o.float()
+	}
+	return n.i < o.i
+}
+
+func (n number) sub(o number) number {
+	if n.isFloat || o.isFloat {
+		return number{isFloat: true, f: n.float() - o.float()}
+	}
+	return number{i: n.i - o.i}
+}
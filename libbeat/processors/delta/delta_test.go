@@ -0,0 +1,178 @@
+package delta
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+var testTime = time.Date(2018, 10, 14, 7, 30, 0, 0, time.UTC)
+
+var testConfig, _ = common.NewConfigFrom(map[string]interface{}{
+	"fields": []string{"network.in.bytes", "load"},
+	"key":    []string{"host.name"},
+})
+
+func newDelta(t *testing.T, config *common.Config) *delta {
+	p, err := newDeltaProcessor(config)
+	if err != nil {
+		t.Fatalf("error initializing delta: %s", err)
+	}
+	return p.(*delta)
+}
+
+func run(t *testing.T, p *delta, host string, offset time.Duration, fields common.MapStr) common.MapStr {
+	event := &beat.Event{
+		Timestamp: testTime.Add(offset),
+		Fields:    common.MapStr{"host": common.MapStr{"name": host}},
+	}
+	for k, v := range fields {
+		event.Fields.Put(k, v)
+	}
+
+	event, err := p.Run(event)
+	require.NoError(t, err)
+	change, _ := event.Fields.GetValue("change")
+	if change == nil {
+		return nil
+	}
+	return change.(common.MapStr)
+}
+
+func TestDeltaIncrements(t *testing.T) {
+	p := newDelta(t, testConfig)
+
+	// The first event of an entity has nothing to be compared with.
+	change := run(t, p, "a", 0, common.MapStr{"network.in.bytes": 100, "load": 0.5})
+	assert.Nil(t, change)
+
+	change = run(t, p, "a", 10*time.Second, common.MapStr{"network.in.bytes": 150, "load": 1.5})
+	assert.Equal(t, common.MapStr{
+		"network": common.MapStr{"in": common.MapStr{"bytes": common.MapStr{
+			"delta": int64(50),
+			"rate":  5.0,
+		}}},
+		"load": common.MapStr{
+			"delta": 1.0,
+			"rate":  0.1,
+		},
+	}, change)
+
+	// Without elapsed time, only the delta is written.
+	change = run(t, p, "a", 10*time.Second, common.MapStr{"network.in.bytes": 160})
+	assert.Equal(t, common.MapStr{"delta": int64(10)}, change["network"].(common.MapStr)["in"].(common.MapStr)["bytes"])
+
+	// Events older than the previous one are not compared.
+	change = run(t, p, "a", 5*time.Second, common.MapStr{"network.in.bytes": 120})
+	assert.Nil(t, change)
+	change = run(t, p, "a", 20*time.Second, common.MapStr{"network.in.bytes": 180})
+	v, _ := change.GetValue("network.in.bytes.delta")
+	assert.Equal(t, int64(20), v)
+}
+
+func TestDeltaReset(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]interface{}
+		field    string
+		values   []interface{}
+		expected common.MapStr
+	}{
+		{
+			name: "counters are reset",
+			settings: map[string]interface{}{
+				"fields": []string{"network.in.bytes"},
+				"key":    []string{"host.name"},
+			},
+			field:    "network.in.bytes",
+			values:   []interface{}{1000, 40},
+			expected: common.MapStr{"delta": int64(40), "rate": 20.0, "reset": true},
+		},
+		{
+			name: "gauges decrease without being reset",
+			settings: map[string]interface{}{
+				"fields": []string{"load"},
+				"key":    []string{"host.name"},
+				"type":   "gauge",
+			},
+			field:    "load",
+			values:   []interface{}{2.0, 1.0},
+			expected: common.MapStr{"delta": -1.0, "rate": -0.5},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := common.NewConfigFrom(test.settings)
+			require.NoError(t, err)
+			p := newDelta(t, config)
+
+			run(t, p, "a", 0, common.MapStr{test.field: test.values[0]})
+			change := run(t, p, "a", 2*time.Second, common.MapStr{test.field: test.values[1]})
+			v, _ := change.GetValue(test.field)
+			assert.Equal(t, test.expected, v)
+		})
+	}
+}
+
+func TestDeltaEntities(t *testing.T) {
+	p := newDelta(t, testConfig)
+
+	run(t, p, "a", 0, common.MapStr{"network.in.bytes": 100})
+	run(t, p, "b", 0, common.MapStr{"network.in.bytes": 1000})
+	change := run(t, p, "a", time.Second, common.MapStr{"network.in.bytes": 110})
+	v, _ := change.GetValue("network.in.bytes.delta")
+	assert.Equal(t, int64(10), v)
+	change = run(t, p, "b", time.Second, common.MapStr{"network.in.bytes": 1100})
+	v, _ = change.GetValue("network.in.bytes.delta")
+	assert.Equal(t, int64(100), v)
+
+	// Events without key are passed unchanged.
+	event := &beat.Event{Timestamp: testTime, Fields: common.MapStr{"network": common.MapStr{"in": common.MapStr{"bytes": 5}}}}
+	event, err := p.Run(event)
+	require.NoError(t, err)
+	assert.Equal(t, common.MapStr{"network": common.MapStr{"in": common.MapStr{"bytes": 5}}}, event.Fields)
+}
+
+func TestDeltaEviction(t *testing.T) {
+	config, err := common.NewConfigFrom(map[string]interface{}{
+		"fields":     []string{"network.in.bytes"},
+		"key":        []string{"host.name"},
+		"cache.ttl":  "1m",
+		"cache.size": 1,
+	})
+	require.NoError(t, err)
+	p := newDelta(t, config)
+	now := time.Now()
+	p.cache.now = func() time.Time { return now }
+
+	run(t, p, "a", 0, common.MapStr{"network.in.bytes": 100})
+
+	// The state of an idle entity expires.
+	now = now.Add(2 * time.Minute)
+	change := run(t, p, "a", time.Minute, common.MapStr{"network.in.bytes": 200})
+	assert.Nil(t, change)
+
+	// The state of a is evicted by b, as the cache is full.
+	run(t, p, "b", time.Minute, common.MapStr{"network.in.bytes": 100})
+	change = run(t, p, "a", 2*time.Minute, common.MapStr{"network.in.bytes": 300})
+	assert.Nil(t, change)
+}
+
+func TestDeltaConfig(t *testing.T) {
+	for name, settings := range map[string]map[string]interface{}{
+		"missing fields": {"key": "host.name"},
+		"invalid type":   {"fields": []string{"load"}, "type": "histogram"},
+		"invalid size":   {"fields": []string{"load"}, "cache.size": 0},
+	} {
+		cfg, err := common.NewConfigFrom(settings)
+		require.NoError(t, err)
+		_, err = newDeltaProcessor(cfg)
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,74 @@
+package es_lookup
+
+import (
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// cache holds the results of lookups for a fixed time after they have been
+// looked up. In contrast to common.Cache, accessing a result does not extend
+// its lifetime, so changes to the lookup index are picked up after the TTL.
+type cache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	entries map[string]cacheEntry
+	now     func() time.Time
+}
+
+type cacheEntry struct {
+	fields  common.MapStr // fields of the matching document, nil for a miss
+	expires time.Time
+}
+
+func newCache(ttl time.Duration, size int) *cache {
+	return &cache{
+		ttl:     ttl,
+		size:    size,
+		entries: map[string]cacheEntry{},
+		now:     time.Now,
+	}
+}
+
+// get returns the cached result for key. found is false if no unexpired result
+// is cached.
+func (c *cache) get(key string) (fields common.MapStr, found bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, found := c.entries[key]
+	if !found {
+		return nil, false
+	}
+	if c.now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.fields, true
+}
+
+// put caches the result for key. Expired results are removed if the cache is
+// full. If it stays full, the result is not cached.
+func (c *cache) put(key string, fields common.MapStr) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.size {
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= c.size {
+			return
+		}
+	}
+	c.entries[key] = cacheEntry{fields: fields, expires: now.Add(c.ttl)}
+}
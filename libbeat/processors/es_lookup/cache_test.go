@@ -0,0 +1,47 @@
+package es_lookup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func TestCacheSize(t *testing.T) {
+	c := newCache(time.Minute, 2)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.put("a", common.MapStr{"v": 1})
+	c.put("b", nil)
+	c.put("c", common.MapStr{"v": 3})
+
+	// The cache is full, c is not cached.
+	_, found := c.get("c")
+	assert.False(t, found)
+	fields, found := c.get("b")
+	assert.True(t, found)
+	assert.Nil(t, fields)
+
+	// Cached keys are updated when the cache is full.
+	c.put("a", common.MapStr{"v": 2})
+	fields, _ = c.get("a")
+	assert.Equal(t, common.MapStr{"v": 2}, fields)
+
+	// Expired results are removed to make room.
+	now = now.Add(2 * time.Minute)
+	c.put("c", common.MapStr{"v": 3})
+	fields, found = c.get("c")
+	assert.True(t, found)
+	assert.Equal(t, common.MapStr{"v": 3}, fields)
+	assert.Len(t, c.entries, 1)
+}
+
+func TestCacheDisabled(t *testing.T) {
+	c := newCache(0, 10)
+	c.put("a", common.MapStr{"v": 1})
+	_, found := c.get("a")
+	assert.False(t, found)
+}
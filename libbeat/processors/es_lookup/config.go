@@ -0,0 +1,61 @@
+package es_lookup
+
+import (
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// Config for the es_lookup processor. The Elasticsearch connection settings,
+// like `hosts`, `username` or `ssl`, are read from the same configuration as
+// for the Elasticsearch output.
+type Config struct {
+	// Hosts are the Elasticsearch hosts the lookup index is queried on.
+	Hosts []string `config:"hosts" validate:"required"`
+
+	// Field holds the value looked up.
+	Field string `config:"field" validate:"required"`
+
+	// Index is the name of the lookup index.
+	Index string `config:"index" validate:"required"`
+
+	// LookupField is the field of the documents matched against the value. It
+	// defaults to Field.
+	LookupField string `config:"lookup_field"`
+
+	// Target is the key the fields of the matching document are merged into.
+	Target string `config:"target" validate:"required"`
+
+	// Fields restricts the fields of the matching document that are merged.
+	// All fields are merged by default.
+	Fields []string `config:"fields"`
+
+	// Default holds the fields merged into Target if no document matches.
+	Default common.MapStr `config:"default"`
+
+	// Cache configures how long lookups are cached.
+	Cache CacheConfig `config:"cache"`
+
+	// Timeout of the lookup requests.
+	Timeout time.Duration `config:"timeout" validate:"positive"`
+}
+
+// CacheConfig for the lookup results.
+type CacheConfig struct {
+	// TTL is how long the result of a lookup is cached, for matches and
+	// misses. Lookups are not cached if set to 0.
+	TTL time.Duration `config:"ttl" validate:"min=0"`
+
+	// Size is the maximum number of cached results.
+	Size int `config:"size" validate:"min=1"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		Cache: CacheConfig{
+			TTL:  5 * time.Minute,
+			Size: 10000,
+		},
+		Timeout: 5 * time.Second,
+	}
+}
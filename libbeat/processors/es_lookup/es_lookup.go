@@ -0,0 +1,196 @@
+package es_lookup
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs/elasticsearch"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+var debugf = logp.MakeDebug("es_lookup")
+
+// reconnectBackoff is the time to wait before connecting to Elasticsearch
+// again after a failure, so events are not delayed by connection attempts
+// while Elasticsearch is unavailable.
+const reconnectBackoff = 10 * time.Second
+
+func init() {
+	processors.RegisterPlugin("es_lookup", newLookupProcessor)
+}
+
+type esLookup struct {
+	field       string
+	index       string
+	lookupField string
+	target      string
+	fields      []string
+	defaults    common.MapStr
+	cache       *cache
+
+	// mu serializes the requests, as the client is not safe for concurrent
+	// use.
+	mu       sync.Mutex
+	client   *elasticsearch.Client
+	connect  func() (*elasticsearch.Client, error)
+	failedAt time.Time
+	backoff  time.Duration
+	now      func() time.Time
+}
+
+func newLookupProcessor(cfg *common.Config) (processors.Processor, error) {
+	cfgwarn.Experimental("The es_lookup processor is experimental")
+
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, errors.Wrap(err, "fail to unpack the es_lookup configuration")
+	}
+
+	esConfig := common.NewConfig()
+	if err := esConfig.Merge(cfg); err != nil {
+		return nil, err
+	}
+	if err := esConfig.SetString("timeout", -1, config.Timeout.String()); err != nil {
+		return nil, err
+	}
+	// Check the connection settings, the connection is only opened on the
+	// first lookup.
+	if _, err := elasticsearch.NewElasticsearchClients(esConfig); err != nil {
+		return nil, errors.Wrap(err, "invalid es_lookup Elasticsearch settings")
+	}
+
+	p := &esLookup{
+		field:       config.Field,
+		index:       config.Index,
+		lookupField: config.LookupField,
+		target:      config.Target,
+		fields:      config.Fields,
+		defaults:    config.Default,
+		cache:       newCache(config.Cache.TTL, config.Cache.Size),
+		connect: func() (*elasticsearch.Client, error) {
+			return elasticsearch.NewConnectedClient(esConfig)
+		},
+		backoff: reconnectBackoff,
+		now:     time.Now,
+	}
+	if p.lookupField == "" {
+		p.lookupField = p.field
+	}
+	return p, nil
+}
+
+// Run merges the fields of the document matching the value of the field into
+// the target. The event is not modified if the field is missing or the
+// lookup fails.
+func (p *esLookup) Run(event *beat.Event) (*beat.Event, error) {
+	v, err := event.GetValue(p.field)
+	if err != nil {
+		// no value to look up
+		return event, nil
+	}
+
+	switch v.(type) {
+	case string, bool, int, int32, int64, uint, uint32, uint64, float32, float64:
+	default:
+		debugf("Ignoring value %v of type %T in field %v", v, v, p.field)
+		return event, nil
+	}
+
+	key := fmt.Sprint(v)
+	fields, found := p.cache.get(key)
+	if !found {
+		fields, err = p.lookup(v)
+		if err != nil {
+			return event, errors.Wrapf(err, "lookup of %v in index %v failed", key, p.index)
+		}
+		p.cache.put(key, fields)
+	}
+
+	if fields == nil {
+		fields = p.defaults
+	}
+	if len(fields) == 0 {
+		return event, nil
+	}
+
+	update := common.MapStr{}
+	if _, err := update.Put(p.target, fields.Clone()); err != nil {
+		return event, err
+	}
+	event.Fields.DeepUpdate(update)
+	return event, nil
+}
+
+// lookup queries the lookup index for a document whose lookup field matches
+// the value. It returns the fields of the document, or nil if no document
+// matches.
+func (p *esLookup) lookup(value interface{}) (common.MapStr, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.client == nil {
+		if !p.failedAt.IsZero() && p.now().Sub(p.failedAt) < p.backoff {
+			return nil, errors.New("Elasticsearch is unavailable")
+		}
+		client, err := p.connect()
+		if err != nil {
+			p.failedAt = p.now()
+			return nil, err
+		}
+		p.client = client
+		p.failedAt = time.Time{}
+	}
+
+	query := common.MapStr{
+		"size": 1,
+		"query": common.MapStr{
+			"term": common.MapStr{p.lookupField: value},
+		},
+	}
+	if len(p.fields) > 0 {
+		query["_source"] = p.fields
+	}
+
+	status, body, err := p.client.Request("POST", "/"+p.index+"/_search", "", nil, query)
+	if err != nil {
+		if status == 0 {
+			// Connect again on the next lookup.
+			p.client = nil
+			p.failedAt = p.now()
+		}
+		return nil, err
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				Source common.MapStr `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the search response")
+	}
+	if len(result.Hits.Hits) == 0 {
+		return nil, nil
+	}
+
+	fields := result.Hits.Hits[0].Source
+	if fields == nil {
+		fields = common.MapStr{}
+	}
+	return fields, nil
+}
+
+func (p *esLookup) String() string {
+	return fmt.Sprintf("es_lookup=[field=%v, index=%v, lookup_field=%v, target=%v]",
+		p.field, p.index, p.lookupField, p.target)
+}
@@ -0,0 +1,294 @@
+package es_lookup
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/outputs/elasticsearch"
+)
+
+// mockES answers the ping and the search requests on the lookup index with
+// the documents by the value of their `hostname` field.
+type mockES struct {
+	mu       sync.Mutex
+	docs     map[string]common.MapStr
+	down     bool // fail the ping
+	status   int  // status of the search responses
+	searches int
+	queries  []common.MapStr
+}
+
+func (m *mockES) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if r.URL.Path == "/" {
+		if m.down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"version": {"number": "6.5.0"}}`))
+		return
+	}
+	if r.URL.Path != "/assets/_search" {
+		http.NotFound(w, r)
+		return
+	}
+
+	m.searches++
+	if m.status != 0 {
+		w.WriteHeader(m.status)
+		w.Write([]byte(`{"error": "search failed"}`))
+		return
+	}
+
+	var query common.MapStr
+	json.NewDecoder(r.Body).Decode(&query)
+	m.queries = append(m.queries, query)
+
+	value, _ := query.GetValue("query.term.hostname")
+	hits := []common.MapStr{}
+	if doc, found := m.docs[value.(string)]; found {
+		hits = append(hits, common.MapStr{"_index": "assets", "_source": doc})
+	}
+	json.NewEncoder(w).Encode(common.MapStr{"hits": common.MapStr{"total": len(hits), "hits": hits}})
+}
+
+func (m *mockES) searchCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.searches
+}
+
+func newMockES() (*mockES, *httptest.Server) {
+	es := &mockES{docs: map[string]common.MapStr{
+		"web-1": {"owner": "web-team", "env": "production"},
+		"db-1":  {"owner": "db-team", "env": "staging"},
+	}}
+	return es, httptest.NewServer(es)
+}
+
+// lookupSettings returns new settings looking up the hosts of the events in
+// the assets index of url.
+func lookupSettings(url string) map[string]interface{} {
+	return map[string]interface{}{
+		"hosts":        []string{url},
+		"field":        "host.name",
+		"index":        "assets",
+		"lookup_field": "hostname",
+		"target":       "asset",
+	}
+}
+
+func newLookup(t *testing.T, settings map[string]interface{}) *esLookup {
+	config, err := common.NewConfigFrom(settings)
+	if err != nil {
+		t.Fatalf("error creating config: %s", err)
+	}
+
+	p, err := newLookupProcessor(config)
+	if err != nil {
+		t.Fatalf("error initializing es_lookup: %s", err)
+	}
+	return p.(*esLookup)
+}
+
+func hostEvent(name string) *beat.Event {
+	return &beat.Event{Fields: common.MapStr{
+		"host":  common.MapStr{"name": name},
+		"asset": common.MapStr{"id": "a1"},
+	}}
+}
+
+func TestLookupHit(t *testing.T) {
+	es, server := newMockES()
+	defer server.Close()
+	p := newLookup(t, lookupSettings(server.URL))
+
+	event, err := p.Run(hostEvent("web-1"))
+	require.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"id":    "a1",
+		"owner": "web-team",
+		"env":   "production",
+	}, event.Fields["asset"])
+
+	require.Len(t, es.queries, 1)
+	assert.Equal(t, common.MapStr{
+		"size":  float64(1),
+		"query": map[string]interface{}{"term": map[string]interface{}{"hostname": "web-1"}},
+	}, es.queries[0])
+}
+
+func TestLookupMiss(t *testing.T) {
+	_, server := newMockES()
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		defaults map[string]interface{}
+		expected common.MapStr
+	}{
+		{
+			name:     "without default",
+			expected: hostEvent("unknown").Fields,
+		},
+		{
+			name:     "with default",
+			defaults: map[string]interface{}{"owner": "unassigned"},
+			expected: common.MapStr{
+				"host":  common.MapStr{"name": "unknown"},
+				"asset": common.MapStr{"id": "a1", "owner": "unassigned"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := lookupSettings(server.URL)
+			if test.defaults != nil {
+				settings["default"] = test.defaults
+			}
+			p := newLookup(t, settings)
+
+			event, err := p.Run(hostEvent("unknown"))
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, event.Fields)
+		})
+	}
+}
+
+func TestLookupCachedHit(t *testing.T) {
+	es, server := newMockES()
+	defer server.Close()
+	settings := lookupSettings(server.URL)
+	settings["cache.ttl"] = "1m"
+	p := newLookup(t, settings)
+	now := time.Now()
+	p.cache.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		event, err := p.Run(hostEvent("web-1"))
+		require.NoError(t, err)
+		assert.Equal(t, "web-team", event.Fields["asset"].(common.MapStr)["owner"])
+
+		// Misses are cached too.
+		_, err = p.Run(hostEvent("unknown"))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, es.searchCount())
+
+	// Cached documents are not modified by later processors.
+	event, err := p.Run(hostEvent("web-1"))
+	require.NoError(t, err)
+	event.Fields.Put("asset.owner", "changed")
+	event, err = p.Run(hostEvent("web-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "web-team", event.Fields["asset"].(common.MapStr)["owner"])
+
+	// Results are looked up again after the TTL, even if accessed in between.
+	now = now.Add(2 * time.Minute)
+	_, err = p.Run(hostEvent("web-1"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, es.searchCount())
+}
+
+func TestLookupQueryFailure(t *testing.T) {
+	es, server := newMockES()
+	defer server.Close()
+	es.status = http.StatusInternalServerError
+	settings := lookupSettings(server.URL)
+	settings["default"] = map[string]interface{}{"owner": "unassigned"}
+	p := newLookup(t, settings)
+
+	// The event is kept unmodified and the failure is not cached.
+	for i := 1; i <= 2; i++ {
+		event, err := p.Run(hostEvent("web-1"))
+		assert.Error(t, err)
+		require.NotNil(t, event)
+		assert.Equal(t, hostEvent("web-1").Fields, event.Fields)
+		assert.Equal(t, i, es.searchCount())
+	}
+
+	es.mu.Lock()
+	es.status = 0
+	es.mu.Unlock()
+	event, err := p.Run(hostEvent("web-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "web-team", event.Fields["asset"].(common.MapStr)["owner"])
+}
+
+func TestLookupUnavailable(t *testing.T) {
+	es, server := newMockES()
+	defer server.Close()
+	es.down = true
+
+	p := newLookup(t, lookupSettings(server.URL))
+	now := time.Now()
+	p.now = func() time.Time { return now }
+	connects := 0
+	connect := p.connect
+	p.connect = func() (*elasticsearch.Client, error) {
+		connects++
+		return connect()
+	}
+
+	event, err := p.Run(hostEvent("web-1"))
+	assert.Error(t, err)
+	require.NotNil(t, event)
+	assert.Equal(t, hostEvent("web-1").Fields, event.Fields)
+
+	// No connection is attempted during the backoff.
+	_, err = p.Run(hostEvent("web-1"))
+	assert.Error(t, err)
+	assert.Equal(t, 1, connects)
+	assert.Equal(t, 0, es.searchCount())
+
+	// Connect again after the backoff.
+	es.mu.Lock()
+	es.down = false
+	es.mu.Unlock()
+	now = now.Add(reconnectBackoff)
+
+	event, err = p.Run(hostEvent("web-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "web-team", event.Fields["asset"].(common.MapStr)["owner"])
+	assert.Equal(t, 2, connects)
+}
+
+func TestLookupIgnoresMissingField(t *testing.T) {
+	es, server := newMockES()
+	defer server.Close()
+	p := newLookup(t, lookupSettings(server.URL))
+
+	event, err := p.Run(&beat.Event{Fields: common.MapStr{"message": "hello"}})
+	require.NoError(t, err)
+	assert.Equal(t, common.MapStr{"message": "hello"}, event.Fields)
+
+	event, err = p.Run(&beat.Event{Fields: common.MapStr{"host": common.MapStr{"name": []string{"a", "b"}}}})
+	require.NoError(t, err)
+	assert.Equal(t, 0, es.searchCount())
+}
+
+func TestLookupConfig(t *testing.T) {
+	for _, config := range []map[string]interface{}{
+		{"index": "assets", "target": "asset", "hosts": []string{"localhost:9200"}},
+		{"field": "host.name", "target": "asset", "hosts": []string{"localhost:9200"}},
+		{"field": "host.name", "index": "assets", "hosts": []string{"localhost:9200"}},
+		{"field": "host.name", "index": "assets", "target": "asset"},
+	} {
+		cfg, err := common.NewConfigFrom(config)
+		require.NoError(t, err)
+		_, err = newLookupProcessor(cfg)
+		assert.Error(t, err, "config %v", config)
+	}
+}
@@ -0,0 +1,50 @@
+package processors
+
+import (
+	"fmt"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// FieldsConfig configures where a processor adds its fields to the event. It
+// is meant to be inlined into the configuration of processors adding fields,
+// so all processors support the same `fields_under_root` setting.
+type FieldsConfig struct {
+	// Add the fields to the root of the event, instead of under the
+	// processor's own key.
+	FieldsUnderRoot bool `config:"fields_under_root"`
+}
+
+// AddFields adds fields to the event under key, or to the root of the event
+// if FieldsUnderRoot is set. Fields are merged recursively into existing
+// objects, values already present in the event are overwritten.
+func (c FieldsConfig) AddFields(event *beat.Event, key string, fields common.MapStr) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	if event.Fields == nil {
+		event.Fields = common.MapStr{}
+	}
+
+	if c.FieldsUnderRoot {
+		event.Fields.DeepUpdate(fields)
+		return nil
+	}
+
+	if existing, err := event.GetValue(key); err == nil && existing != nil {
+		switch m := existing.(type) {
+		case common.MapStr:
+			m.DeepUpdate(fields)
+		case map[string]interface{}:
+			common.MapStr(m).DeepUpdate(fields)
+		default:
+			return fmt.Errorf("can not add fields to '%v', the field is not an object", key)
+		}
+		return nil
+	}
+
+	_, err := event.PutValue(key, fields)
+	return err
+}
@@ -0,0 +1,68 @@
+package processors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func TestFieldsConfigAddFields(t *testing.T) {
+	tests := []struct {
+		name      string
+		underRoot bool
+		event     common.MapStr
+		expected  common.MapStr
+		err       bool
+	}{
+		{
+			name:     "nested",
+			event:    common.MapStr{"message": "hello"},
+			expected: common.MapStr{"message": "hello", "meta": common.MapStr{"cloud": common.MapStr{"provider": "ec2"}}},
+		},
+		{
+			name:      "under root",
+			underRoot: true,
+			event:     common.MapStr{"message": "hello"},
+			expected:  common.MapStr{"message": "hello", "cloud": common.MapStr{"provider": "ec2"}},
+		},
+		{
+			name:     "nested merges existing object",
+			event:    common.MapStr{"meta": common.MapStr{"cloud": common.MapStr{"region": "eu"}, "other": 1}},
+			expected: common.MapStr{"meta": common.MapStr{"cloud": common.MapStr{"provider": "ec2", "region": "eu"}, "other": 1}},
+		},
+		{
+			name:      "under root merges existing object",
+			underRoot: true,
+			event:     common.MapStr{"cloud": common.MapStr{"provider": "gce", "region": "eu"}},
+			expected:  common.MapStr{"cloud": common.MapStr{"provider": "ec2", "region": "eu"}},
+		},
+		{
+			name:  "nested into value",
+			event: common.MapStr{"meta": "value"},
+			err:   true,
+		},
+	}
+
+	for _, test := range tests {
+		event := &beat.Event{Fields: test.event}
+		config := FieldsConfig{FieldsUnderRoot: test.underRoot}
+		err := config.AddFields(event, "meta", common.MapStr{"cloud": common.MapStr{"provider": "ec2"}})
+		if test.err {
+			assert.Error(t, err, test.name)
+			continue
+		}
+		if assert.NoError(t, err, test.name) {
+			assert.Equal(t, test.expected, event.Fields, test.name)
+		}
+	}
+}
+
+func TestFieldsConfigAddFieldsEmptyEvent(t *testing.T) {
+	event := &beat.Event{}
+	err := FieldsConfig{}.AddFields(event, "docker", common.MapStr{"container": common.MapStr{"id": "abc"}})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{"docker": common.MapStr{"container": common.MapStr{"id": "abc"}}}, event.Fields)
+}
@@ -0,0 +1,44 @@
+package map_fields
+
+import "fmt"
+
+// Config for the map_fields processor.
+type Config struct {
+	// File is the mapping file. Relative paths are resolved against the
+	// configuration directory.
+	File string `config:"file" validate:"required"`
+
+	// Unmapped is `keep` to keep the fields not mapped by the file, or `drop`
+	// to remove them from the events.
+	Unmapped string `config:"unmapped"`
+
+	// KeepFields are kept in the events even if unmapped fields are dropped.
+	KeepFields []string `config:"keep_fields"`
+
+	// FailOnError returns an error if a value can not be converted. Otherwise
+	// the mapping is skipped.
+	FailOnError bool `config:"fail_on_error"`
+}
+
+const (
+	unmappedKeep = "keep"
+	unmappedDrop = "drop"
+)
+
+func defaultConfig() Config {
+	return Config{
+		Unmapped:    unmappedKeep,
+		FailOnError: true,
+	}
+}
+
+// Validate checks the unmapped mode.
+func (c *Config) Validate() error {
+	switch c.Unmapped {
+	case unmappedKeep, unmappedDrop:
+		return nil
+	default:
+		return fmt.Errorf("invalid unmapped mode '%s', must be '%s' or '%s'",
+			c.Unmapped, unmappedKeep, unmappedDrop)
+	}
+}
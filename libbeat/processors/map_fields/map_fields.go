@@ -0,0 +1,126 @@
+package map_fields
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/paths"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+var debug = logp.MakeDebug("map_fields")
+
+func init() {
+	processors.RegisterPlugin("map_fields", newMapFields)
+}
+
+type mapFields struct {
+	file        string
+	mappings    []mapping
+	drop        bool
+	keepFields  []string
+	failOnError bool
+}
+
+// mapping is a Mapping with its resolved converter.
+type mapping struct {
+	from, to string
+	convert  converter
+}
+
+// mappedValue is the converted value of a field found in an event.
+type mappedValue struct {
+	from, to string
+	value    interface{}
+}
+
+func newMapFields(cfg *common.Config) (processors.Processor, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, errors.Wrap(err, "fail to unpack the map_fields configuration")
+	}
+
+	file := paths.Resolve(paths.Config, config.File)
+	rules, err := loadMappings(file)
+	if err != nil {
+		return nil, err
+	}
+
+	mappings := make([]mapping, len(rules))
+	for i, rule := range rules {
+		mappings[i] = mapping{
+			from:    rule.From,
+			to:      rule.To,
+			convert: converters[rule.Type],
+		}
+	}
+
+	return &mapFields{
+		file:        file,
+		mappings:    mappings,
+		drop:        config.Unmapped == unmappedDrop,
+		keepFields:  config.KeepFields,
+		failOnError: config.FailOnError,
+	}, nil
+}
+
+// Run moves the mapped fields to their ECS names. All values are read and
+// converted before the event is modified, so mappings can not overwrite the
+// source of another mapping, and the event is returned unchanged if a value
+// can not be converted and fail_on_error is enabled.
+func (p *mapFields) Run(event *beat.Event) (*beat.Event, error) {
+	values := make([]mappedValue, 0, len(p.mappings))
+	for _, m := range p.mappings {
+		value, err := event.GetValue(m.from)
+		if err != nil {
+			continue
+		}
+		if m.convert != nil {
+			converted, err := m.convert(value)
+			if err != nil {
+				err = errors.Wrapf(err, "failed to map field '%s' to '%s'", m.from, m.to)
+				if p.failOnError {
+					return event, err
+				}
+				debug("%s", err)
+				continue
+			}
+			value = converted
+		}
+		values = append(values, mappedValue{from: m.from, to: m.to, value: value})
+	}
+
+	if p.drop {
+		fields := common.MapStr{}
+		for _, name := range p.keepFields {
+			if value, err := event.GetValue(name); err == nil {
+				fields.Put(name, value)
+			}
+		}
+		event.Fields = fields
+	} else {
+		for _, v := range values {
+			event.Delete(v.from)
+		}
+	}
+
+	for _, v := range values {
+		if _, err := event.PutValue(v.to, v.value); err != nil {
+			return event, errors.Wrapf(err, "failed to map field '%s' to '%s'", v.from, v.to)
+		}
+	}
+	return event, nil
+}
+
+func (p *mapFields) String() string {
+	unmapped := unmappedKeep
+	if p.drop {
+		unmapped = unmappedDrop
+	}
+	return fmt.Sprintf("map_fields=[file=%s, mappings=%d, unmapped=%s]",
+		p.file, len(p.mappings), unmapped)
+}
@@ -0,0 +1,238 @@
+package map_fields
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func runMapFields(t *testing.T, config *common.Config, fields common.MapStr) (*beat.Event, error) {
+	p, err := newMapFields(config)
+	if err != nil {
+		t.Fatalf("error initializing map_fields: %s", err)
+	}
+
+	return p.Run(&beat.Event{Fields: fields})
+}
+
+func testFields() common.MapStr {
+	return common.MapStr{
+		"src_ip":   " 10.0.0.1",
+		"src_port": "5432",
+		"bytes":    uint64(1024),
+		"duration": 12,
+		"blocked":  "true",
+		"user":     "alice",
+		"status":   float64(200),
+		"severity": 3,
+		"message":  "hello",
+		"beat":     common.MapStr{"name": "web-1"},
+	}
+}
+
+func TestMapFields(t *testing.T) {
+	mapped := common.MapStr{
+		"source":  common.MapStr{"ip": "10.0.0.1", "port": int64(5432)},
+		"network": common.MapStr{"bytes": int64(1024)},
+		"event":   common.MapStr{"duration": float64(12), "blocked": true},
+		"user":    common.MapStr{"name": "alice"},
+		"http":    common.MapStr{"response": common.MapStr{"status_code": int64(200)}},
+		"log":     common.MapStr{"level": "3"},
+	}
+
+	tests := []struct {
+		name     string
+		settings map[string]interface{}
+		expected common.MapStr
+	}{
+		{
+			name:     "keep unmapped fields",
+			settings: map[string]interface{}{"file": "testdata/mapping.yml"},
+			expected: common.MapStr{
+				"message": "hello",
+				"beat":    common.MapStr{"name": "web-1"},
+			},
+		},
+		{
+			name: "drop unmapped fields",
+			settings: map[string]interface{}{
+				"file":     "testdata/mapping.yml",
+				"unmapped": "drop",
+			},
+			expected: common.MapStr{},
+		},
+		{
+			name: "drop unmapped fields but keep some",
+			settings: map[string]interface{}{
+				"file":        "testdata/mapping.yml",
+				"unmapped":    "drop",
+				"keep_fields": []string{"beat.name", "missing"},
+			},
+			expected: common.MapStr{
+				"beat": common.MapStr{"name": "web-1"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := common.NewConfigFrom(test.settings)
+			require.NoError(t, err)
+
+			event, err := runMapFields(t, config, testFields())
+			require.NoError(t, err)
+
+			expected := mapped.Clone()
+			expected.DeepUpdate(test.expected)
+			assert.Equal(t, expected, event.Fields)
+		})
+	}
+}
+
+func TestMapFieldsMissingFields(t *testing.T) {
+	config, err := common.NewConfigFrom(map[string]interface{}{"file": "testdata/mapping.yml"})
+	require.NoError(t, err)
+
+	event, err := runMapFields(t, config, common.MapStr{"user": "bob", "message": "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"user":    common.MapStr{"name": "bob"},
+		"message": "hello",
+	}, event.Fields)
+}
+
+func TestMapFieldsConversionError(t *testing.T) {
+	fields := common.MapStr{
+		"src_ip":  "not an ip",
+		"bytes":   "1.5k",
+		"user":    "alice",
+		"message": "hello",
+	}
+
+	tests := []struct {
+		name     string
+		settings map[string]interface{}
+		expected common.MapStr
+		err      bool
+	}{
+		{
+			name:     "fail on error",
+			settings: map[string]interface{}{"file": "testdata/mapping.yml"},
+			expected: fields,
+			err:      true,
+		},
+		{
+			name: "skip invalid values",
+			settings: map[string]interface{}{
+				"file":          "testdata/mapping.yml",
+				"fail_on_error": false,
+			},
+			expected: common.MapStr{
+				"src_ip":  "not an ip",
+				"bytes":   "1.5k",
+				"user":    common.MapStr{"name": "alice"},
+				"message": "hello",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := common.NewConfigFrom(test.settings)
+			require.NoError(t, err)
+
+			event, err := runMapFields(t, config, fields.Clone())
+			if test.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.expected, event.Fields)
+		})
+	}
+}
+
+func TestMapFieldsInvalidConfig(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"missing file":          {},
+		"file not found":        {"file": "testdata/missing.yml"},
+		"invalid unmapped mode": {"file": "testdata/mapping.yml", "unmapped": "rename"},
+	}
+
+	for name, settings := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := common.NewConfigFrom(settings)
+			require.NoError(t, err)
+
+			_, err = newMapFields(cfg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadMappingsInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "map_fields")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"unknown type": "mappings:\n- {from: a, to: b, type: date}\n",
+		"duplicate":    "mappings:\n- {from: a, to: b}\n- {from: a, to: c}\n",
+		"missing to":   "mappings:\n- {from: a}\n",
+		"no mappings":  "fields: []\n",
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, "mapping.yml")
+			require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+
+			_, err := loadMappings(path)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestConverters(t *testing.T) {
+	tests := []struct {
+		typ      string
+		value    interface{}
+		expected interface{}
+		err      bool
+	}{
+		{typ: "string", value: 42, expected: "42"},
+		{typ: "string", value: common.MapStr{}, err: true},
+		{typ: "long", value: int32(7), expected: int64(7)},
+		{typ: "long", value: " 42 ", expected: int64(42)},
+		{typ: "long", value: 4.0, expected: int64(4)},
+		{typ: "long", value: 4.5, err: true},
+		{typ: "long", value: uint64(1 << 63), err: true},
+		{typ: "long", value: true, err: true},
+		{typ: "double", value: "1.5", expected: 1.5},
+		{typ: "double", value: uint8(3), expected: float64(3)},
+		{typ: "double", value: "fast", err: true},
+		{typ: "boolean", value: "false", expected: false},
+		{typ: "boolean", value: 1, err: true},
+		{typ: "ip", value: "::ffff:10.0.0.1", expected: "10.0.0.1"},
+		{typ: "ip", value: "2001:DB8::1", expected: "2001:db8::1"},
+		{typ: "ip", value: 10, err: true},
+	}
+
+	for _, test := range tests {
+		value, err := converters[test.typ](test.value)
+		if test.err {
+			assert.Error(t, err, "%s(%#v)", test.typ, test.value)
+			continue
+		}
+		if assert.NoError(t, err, "%s(%#v)", test.typ, test.value) {
+			assert.Equal(t, test.expected, value, "%s(%#v)", test.typ, test.value)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package map_fields
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// mappingFile is the content of a mapping file.
+type mappingFile struct {
+	Mappings []Mapping `config:"mappings" validate:"required"`
+}
+
+// Mapping moves a field of the source schema to its ECS name, optionally
+// converting its value.
+type Mapping struct {
+	// From is the name of the field in the source schema.
+	From string `config:"from" validate:"required"`
+
+	// To is the ECS name of the field.
+	To string `config:"to" validate:"required"`
+
+	// Type the value is converted to. The value is not converted if empty.
+	Type string `config:"type"`
+}
+
+// converter converts a value to the type of a mapping.
+type converter func(value interface{}) (interface{}, error)
+
+var converters = map[string]converter{
+	"string":  toString,
+	"long":    toLong,
+	"double":  toDouble,
+	"boolean": toBoolean,
+	"ip":      toIP,
+}
+
+// loadMappings reads and validates the mappings of a file.
+func loadMappings(path string) ([]Mapping, error) {
+	cfg, err := common.LoadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load the mapping file %s", path)
+	}
+
+	file := mappingFile{}
+	if err := cfg.Unpack(&file); err != nil {
+		return nil, errors.Wrapf(err, "failed to read the mapping file %s", path)
+	}
+
+	seen := map[string]bool{}
+	for _, m := range file.Mappings {
+		if seen[m.From] {
+			return nil, fmt.Errorf("field '%s' is mapped more than once in %s", m.From, path)
+		}
+		seen[m.From] = true
+
+		if _, found := converters[m.Type]; m.Type != "" && !found {
+			return nil, fmt.Errorf("unknown type '%s' of the mapping of '%s' in %s", m.Type, m.From, path)
+		}
+	}
+	return file.Mappings, nil
+}
+
+func toString(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case common.MapStr, map[string]interface{}, []interface{}:
+		return nil, fmt.Errorf("can not convert %T to string", value)
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+func toLong(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint:
+		return int64(v), nil
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return nil, fmt.Errorf("%d overflows a long", v)
+		}
+		return int64(v), nil
+	case float32:
+		return floatToLong(float64(v))
+	case float64:
+		return floatToLong(v)
+	case string:
+		return strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	default:
+		return nil, fmt.Errorf("can not convert %T to long", value)
+	}
+}
+
+func floatToLong(f float64) (interface{}, error) {
+	if f != math.Trunc(f) || f > math.MaxInt64 || f < math.MinInt64 {
+		return nil, fmt.Errorf("%v is not a long", f)
+	}
+	return int64(f), nil
+}
+
+func toDouble(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case float32:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	}
+
+	l, err := toLong(value)
+	if err != nil {
+		return nil, fmt.Errorf("can not convert %T to double", value)
+	}
+	return float64(l.(int64)), nil
+}
+
+func toBoolean(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(strings.TrimSpace(v))
+	default:
+		return nil, fmt.Errorf("can not convert %T to boolean", value)
+	}
+}
+
+func toIP(value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("can not convert %T to ip", value)
+	}
+	ip := net.ParseIP(strings.TrimSpace(s))
+	if ip == nil {
+		return nil, fmt.Errorf("'%s' is not an ip address", s)
+	}
+	return ip.String(), nil
+}
@@ -0,0 +1,16 @@
+package migrate_fields
+
+// Config for the migrate_fields processor.
+type Config struct {
+	// Tables are the names of the registered rule tables to apply.
+	Tables []string `config:"tables"`
+
+	// Rules are applied in addition to the rules of the tables.
+	Rules []Rule `config:"rules"`
+
+	// Compatibility is the version the events stay compatible with. Fields
+	// renamed after this version are copied to the new name, instead of being
+	// moved, so dashboards of this version still find them. Fields are always
+	// moved if not set.
+	Compatibility string `config:"compatibility"`
+}
@@ -0,0 +1,122 @@
+package migrate_fields
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+func init() {
+	processors.RegisterPlugin("migrate_fields", newMigrateFields)
+}
+
+type migrateFields struct {
+	migrations    []migration
+	compatibility string
+}
+
+// migration is a rule compiled for the compatibility version.
+type migration struct {
+	from, to string
+	version  *common.Version
+
+	// keep is set if the old field is kept, because the new name was
+	// introduced after the compatibility version.
+	keep bool
+}
+
+func newMigrateFields(cfg *common.Config) (processors.Processor, error) {
+	config := Config{}
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, errors.Wrap(err, "fail to unpack the migrate_fields configuration")
+	}
+
+	var compatibility *common.Version
+	if config.Compatibility != "" {
+		var err error
+		compatibility, err = common.NewVersion(config.Compatibility)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid migrate_fields compatibility version")
+		}
+	}
+
+	var rules []Rule
+	for _, table := range config.Tables {
+		tableRules, found := lookupRules(table)
+		if !found {
+			return nil, fmt.Errorf("unknown migration rules '%s'", table)
+		}
+		rules = append(rules, tableRules...)
+	}
+	for _, rule := range config.Rules {
+		if err := rule.validate(); err != nil {
+			return nil, err
+		}
+	}
+	rules = append(rules, config.Rules...)
+	if len(rules) == 0 {
+		return nil, errors.New("no migration rules configured in migrate_fields")
+	}
+
+	migrations := make([]migration, len(rules))
+	for i, rule := range rules {
+		// The versions have been validated when registering or unpacking the
+		// rules.
+		version, _ := common.NewVersion(rule.Version)
+		migrations[i] = migration{
+			from:    rule.From,
+			to:      rule.To,
+			version: version,
+			keep:    compatibility != nil && compatibility.LessThan(version),
+		}
+	}
+
+	// Apply the migrations in the order of the versions, so fields renamed
+	// multiple times end up with the latest name.
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return migrations[i].version.LessThan(migrations[j].version)
+	})
+
+	return &migrateFields{
+		migrations:    migrations,
+		compatibility: config.Compatibility,
+	}, nil
+}
+
+// Run moves the fields to their new names, or copies them if they are kept
+// for compatibility. Missing fields are skipped, and fields already present
+// under their new name are not overwritten.
+func (p *migrateFields) Run(event *beat.Event) (*beat.Event, error) {
+	for _, m := range p.migrations {
+		value, err := event.GetValue(m.from)
+		if err != nil {
+			continue
+		}
+		if _, err := event.GetValue(m.to); err == nil {
+			continue
+		}
+
+		if fields, ok := value.(common.MapStr); ok && m.keep {
+			value = fields.Clone()
+		}
+		if _, err := event.PutValue(m.to, value); err != nil {
+			return event, errors.Wrapf(err, "failed to migrate field '%s' to '%s'", m.from, m.to)
+		}
+		if !m.keep {
+			if err := event.Delete(m.from); err != nil {
+				return event, errors.Wrapf(err, "failed to remove migrated field '%s'", m.from)
+			}
+		}
+	}
+	return event, nil
+}
+
+func (p *migrateFields) String() string {
+	return fmt.Sprintf("migrate_fields=[rules=%d, compatibility=%s]",
+		len(p.migrations), p.compatibility)
+}
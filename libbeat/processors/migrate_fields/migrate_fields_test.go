@@ -0,0 +1,165 @@
+package migrate_fields
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func init() {
+	RegisterRules("test", []Rule{
+		{From: "client_ip", To: "client.ip", Version: "6.3.0"},
+		{From: "beat.hostname", To: "host.hostname", Version: "6.3.0"},
+		{From: "host.hostname", To: "host.name", Version: "7.0.0"},
+	})
+}
+
+func runMigrateFields(t *testing.T, settings map[string]interface{}, fields common.MapStr) common.MapStr {
+	config, err := common.NewConfigFrom(settings)
+	if err != nil {
+		t.Fatalf("error creating config: %s", err)
+	}
+
+	p, err := newMigrateFields(config)
+	if err != nil {
+		t.Fatalf("error initializing migrate_fields: %s", err)
+	}
+
+	actual, err := p.Run(&beat.Event{Fields: fields})
+	if err != nil {
+		t.Fatalf("error running migrate_fields: %s", err)
+	}
+
+	return actual.Fields
+}
+
+func testFields() common.MapStr {
+	return common.MapStr{
+		"client_ip": "10.0.0.1",
+		"beat":      common.MapStr{"hostname": "web-1", "version": "6.2.0"},
+		"message":   "hello",
+	}
+}
+
+func TestMigrateFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]interface{}
+		expected common.MapStr
+	}{
+		{
+			name:     "without compatibility",
+			settings: map[string]interface{}{"tables": []string{"test"}},
+			expected: common.MapStr{
+				"client":  common.MapStr{"ip": "10.0.0.1"},
+				"beat":    common.MapStr{"version": "6.2.0"},
+				"host":    common.MapStr{"name": "web-1"},
+				"message": "hello",
+			},
+		},
+		{
+			name: "compatible with all renames",
+			settings: map[string]interface{}{
+				"tables":        []string{"test"},
+				"compatibility": "6.2.0",
+			},
+			expected: common.MapStr{
+				"client_ip": "10.0.0.1",
+				"client":    common.MapStr{"ip": "10.0.0.1"},
+				"beat":      common.MapStr{"hostname": "web-1", "version": "6.2.0"},
+				"host":      common.MapStr{"hostname": "web-1", "name": "web-1"},
+				"message":   "hello",
+			},
+		},
+		{
+			name: "compatible with later renames",
+			settings: map[string]interface{}{
+				"tables":        []string{"test"},
+				"compatibility": "6.3.0",
+			},
+			expected: common.MapStr{
+				"client":  common.MapStr{"ip": "10.0.0.1"},
+				"beat":    common.MapStr{"version": "6.2.0"},
+				"host":    common.MapStr{"hostname": "web-1", "name": "web-1"},
+				"message": "hello",
+			},
+		},
+		{
+			name: "compatible with the latest version",
+			settings: map[string]interface{}{
+				"tables":        []string{"test"},
+				"compatibility": "7.0.0",
+			},
+			expected: common.MapStr{
+				"client":  common.MapStr{"ip": "10.0.0.1"},
+				"beat":    common.MapStr{"version": "6.2.0"},
+				"host":    common.MapStr{"name": "web-1"},
+				"message": "hello",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := runMigrateFields(t, test.settings, testFields())
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestMigrateFieldsCopiesObjects(t *testing.T) {
+	actual := runMigrateFields(t, map[string]interface{}{
+		"rules":         []map[string]interface{}{{"from": "geo", "to": "client.geo", "version": "6.3.0"}},
+		"compatibility": "6.2.0",
+	}, common.MapStr{
+		"geo": common.MapStr{"country_iso_code": "DE"},
+	})
+
+	// Modifying one of the copies does not modify the other one.
+	actual.Put("client.geo.country_iso_code", "FR")
+	assert.Equal(t, common.MapStr{
+		"geo":    common.MapStr{"country_iso_code": "DE"},
+		"client": common.MapStr{"geo": common.MapStr{"country_iso_code": "FR"}},
+	}, actual)
+}
+
+func TestMigrateFieldsKeepsExistingFields(t *testing.T) {
+	// Missing fields are skipped, and new fields are not overwritten.
+	actual := runMigrateFields(t, map[string]interface{}{"tables": []string{"test"}}, common.MapStr{
+		"client_ip": "10.0.0.1",
+		"client":    common.MapStr{"ip": "10.0.0.2"},
+	})
+	assert.Equal(t, common.MapStr{
+		"client_ip": "10.0.0.1",
+		"client":    common.MapStr{"ip": "10.0.0.2"},
+	}, actual)
+}
+
+func TestMigrateFieldsConfig(t *testing.T) {
+	for _, config := range []map[string]interface{}{
+		{},
+		{"tables": []string{"unknown"}},
+		{"tables": []string{"test"}, "compatibility": "6.x"},
+		{"rules": []map[string]interface{}{{"from": "a", "to": "b"}}},
+		{"rules": []map[string]interface{}{{"from": "a", "to": "b", "version": "7"}}},
+		{"rules": []map[string]interface{}{{"from": "a", "to": "a.b", "version": "7.0.0"}}},
+	} {
+		cfg, err := common.NewConfigFrom(config)
+		require.NoError(t, err)
+		_, err = newMigrateFields(cfg)
+		assert.Error(t, err, "config %v", config)
+	}
+}
+
+func TestRegisterRules(t *testing.T) {
+	assert.Panics(t, func() {
+		RegisterRules("test", nil)
+	})
+	assert.Panics(t, func() {
+		RegisterRules("invalid", []Rule{{From: "a", To: "b", Version: "7.0"}})
+	})
+}
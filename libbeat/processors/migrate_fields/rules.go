@@ -0,0 +1,64 @@
+package migrate_fields
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// Rule migrates a field renamed in a version. The value of the field is moved
+// from the old to the new name, or copied if the events must stay compatible
+// with a version still using the old name.
+type Rule struct {
+	// From is the old name of the field.
+	From string `config:"from" validate:"required"`
+
+	// To is the new name of the field.
+	To string `config:"to" validate:"required"`
+
+	// Version is the first version using the new name.
+	Version string `config:"version" validate:"required"`
+}
+
+func (r Rule) validate() error {
+	if r.From == r.To || strings.HasPrefix(r.To, r.From+".") || strings.HasPrefix(r.From, r.To+".") {
+		return fmt.Errorf("field '%s' can not be migrated to '%s'", r.From, r.To)
+	}
+	if _, err := common.NewVersion(r.Version); err != nil {
+		return fmt.Errorf("invalid version of the migration of '%s': %v", r.From, err)
+	}
+	return nil
+}
+
+var (
+	tablesMutex sync.Mutex
+	tables      = map[string][]Rule{}
+)
+
+// RegisterRules registers a table of migration rules, like the fields renamed
+// by a beat, to be enabled by name in the `tables` setting of the processor.
+// It panics if the table is registered already or a rule is invalid.
+func RegisterRules(table string, rules []Rule) {
+	tablesMutex.Lock()
+	defer tablesMutex.Unlock()
+
+	if _, exists := tables[table]; exists {
+		panic(fmt.Sprintf("migration rules '%s' are registered already", table))
+	}
+	for _, rule := range rules {
+		if err := rule.validate(); err != nil {
+			panic(fmt.Sprintf("invalid migration rules '%s': %v", table, err))
+		}
+	}
+	tables[table] = append([]Rule(nil), rules...)
+}
+
+func lookupRules(table string) ([]Rule, bool) {
+	tablesMutex.Lock()
+	defer tablesMutex.Unlock()
+
+	rules, found := tables[table]
+	return rules, found
+}
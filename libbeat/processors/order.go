@@ -0,0 +1,143 @@
+package processors
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// Settings of a processor ordering it in the chain. They are removed from the
+// configuration before the processor is created.
+const (
+	orderID     = "id"
+	orderAfter  = "after"
+	orderBefore = "before"
+)
+
+// orderConfig contains the ordering hints of a processor. A processor is
+// referred to by its id, or by the name of its action if it has no id, like
+// `add_geoip`. All processors found with a name are referred to.
+type orderConfig struct {
+	ID     string   `config:"id"`
+	After  []string `config:"after"`
+	Before []string `config:"before"`
+}
+
+// orderedProcessor is a processor of the chain with its ordering hints.
+type orderedProcessor struct {
+	processor Processor
+	id        string
+	after     []string
+	before    []string
+}
+
+// extractOrder returns the ordering hints of a processor, and its
+// configuration without them. The configuration is returned unchanged if it
+// has no hints.
+func extractOrder(name string, cfg *common.Config) (orderConfig, *common.Config, error) {
+	order := orderConfig{ID: name}
+	if !cfg.HasField(orderID) && !cfg.HasField(orderAfter) && !cfg.HasField(orderBefore) {
+		return order, cfg, nil
+	}
+
+	if err := cfg.Unpack(&order); err != nil {
+		return order, nil, fmt.Errorf("invalid ordering of processor %s: %v", name, err)
+	}
+	if order.ID == "" {
+		order.ID = name
+	}
+
+	fields := map[string]interface{}{}
+	if err := cfg.Unpack(&fields); err != nil {
+		return order, nil, err
+	}
+	delete(fields, orderID)
+	delete(fields, orderAfter)
+	delete(fields, orderBefore)
+
+	stripped, err := common.NewConfigFrom(fields)
+	if err != nil {
+		return order, nil, err
+	}
+	return order, stripped, nil
+}
+
+// sortProcessors orders the processors to satisfy their ordering hints. The
+// configured order is kept for all processors not depending on each other,
+// so the chain is only changed where hints require it. Hints referring to
+// processors not in the chain are ignored, so configuration fragments can
+// refer to optional processors. An error is returned if the hints contain a
+// cycle.
+func sortProcessors(list []orderedProcessor) ([]Processor, error) {
+	byID := map[string][]int{}
+	for i, p := range list {
+		byID[p.id] = append(byID[p.id], i)
+	}
+
+	// deps[i] contains the processors to be run before processor i.
+	deps := make([]map[int]bool, len(list))
+	for i := range deps {
+		deps[i] = map[int]bool{}
+	}
+	refs := func(i int, ids []string, add func(j int)) {
+		for _, id := range ids {
+			matches, found := byID[id]
+			if !found {
+				logp.Debug("processors", "Ignore ordering of processor %s relative to the missing processor %s", list[i].id, id)
+			}
+			for _, j := range matches {
+				if j != i {
+					add(j)
+				}
+			}
+		}
+	}
+	for i, p := range list {
+		refs(i, p.after, func(j int) { deps[i][j] = true })
+		refs(i, p.before, func(j int) { deps[j][i] = true })
+	}
+
+	// Kahn's algorithm, always picking the first processor in the configured
+	// order whose dependencies have all been run.
+	sorted := make([]Processor, 0, len(list))
+	done := make([]bool, len(list))
+	for len(sorted) < len(list) {
+		next := -1
+		for i := range list {
+			if !done[i] && ready(deps[i], done) {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return nil, cycleError(list, done)
+		}
+
+		done[next] = true
+		sorted = append(sorted, list[next].processor)
+	}
+	return sorted, nil
+}
+
+func ready(deps map[int]bool, done []bool) bool {
+	for j := range deps {
+		if !done[j] {
+			return false
+		}
+	}
+	return true
+}
+
+// cycleError reports the processors that could not be ordered, all part of
+// or depending on a cycle.
+func cycleError(list []orderedProcessor, done []bool) error {
+	var ids []string
+	for i, p := range list {
+		if !done[i] {
+			ids = append(ids, p.id)
+		}
+	}
+	return fmt.Errorf("cycle in the ordering of the processors: %s", strings.Join(ids, ", "))
+}
@@ -0,0 +1,176 @@
+package processors_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+func init() {
+	processors.RegisterPlugin("test_append", newTestAppend)
+}
+
+// testAppend appends its name to the chain field of the events, recording
+// the order the processors have been run in.
+type testAppend struct {
+	name string
+}
+
+func newTestAppend(c *common.Config) (processors.Processor, error) {
+	config := struct {
+		Name string `config:"name" validate:"required"`
+	}{}
+	if err := c.Unpack(&config); err != nil {
+		return nil, err
+	}
+	return &testAppend{name: config.Name}, nil
+}
+
+func (p *testAppend) Run(event *beat.Event) (*beat.Event, error) {
+	chain, _ := event.Fields["chain"].([]string)
+	event.Fields["chain"] = append(chain, p.name)
+	return event, nil
+}
+
+func (p *testAppend) String() string { return fmt.Sprintf("test_append=%v", p.name) }
+
+func newOrderedProcessors(yml []map[string]interface{}) (*processors.Processors, error) {
+	config := processors.PluginConfig{}
+	for _, action := range yml {
+		c := map[string]*common.Config{}
+		for name, actionYml := range action {
+			actionConfig, err := common.NewConfigFrom(actionYml)
+			if err != nil {
+				return nil, err
+			}
+			c[name] = actionConfig
+		}
+		config = append(config, c)
+	}
+	return processors.New(config)
+}
+
+// appendName returns the configuration of a test_append processor, with its
+// ordering settings.
+func appendName(name string, order map[string]interface{}) map[string]interface{} {
+	config := map[string]interface{}{"name": name}
+	for k, v := range order {
+		config[k] = v
+	}
+	return map[string]interface{}{"test_append": config}
+}
+
+// chain runs the processors and returns the names of the processors in the
+// order they have been run.
+func chain(procs *processors.Processors) []string {
+	event := procs.Run(&beat.Event{Fields: common.MapStr{}})
+	return event.Fields["chain"].([]string)
+}
+
+func TestOrderReorders(t *testing.T) {
+	// The geoip lookup depends on the ip field extracted by a later
+	// processor.
+	procs, err := newOrderedProcessors([]map[string]interface{}{
+		appendName("a", nil),
+		appendName("geoip", map[string]interface{}{"id": "geoip", "after": "extract_ip"}),
+		appendName("b", nil),
+		appendName("ip", map[string]interface{}{"id": "extract_ip"}),
+		appendName("first", map[string]interface{}{"id": "first", "before": []string{"test_append", "extract_ip"}}),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"first", "a", "b", "ip", "geoip"}, chain(procs))
+}
+
+func TestOrderKeepsConfiguredOrder(t *testing.T) {
+	procs, err := newOrderedProcessors([]map[string]interface{}{
+		appendName("a", map[string]interface{}{"id": "a"}),
+		appendName("b", map[string]interface{}{"after": "a"}),
+		appendName("c", map[string]interface{}{"after": "missing"}),
+		appendName("d", nil),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"a", "b", "c", "d"}, chain(procs))
+}
+
+func TestOrderCycle(t *testing.T) {
+	tests := []struct {
+		name string
+		yml  []map[string]interface{}
+		err  string
+	}{
+		{
+			"after",
+			[]map[string]interface{}{
+				appendName("a", map[string]interface{}{"id": "a", "after": "b"}),
+				appendName("b", map[string]interface{}{"id": "b", "after": "a"}),
+			},
+			"cycle in the ordering of the processors: a, b",
+		},
+		{
+			"before",
+			[]map[string]interface{}{
+				appendName("a", map[string]interface{}{"id": "a", "before": "b"}),
+				appendName("b", map[string]interface{}{"id": "b", "before": "c"}),
+				appendName("c", map[string]interface{}{"id": "c", "before": "a"}),
+			},
+			"cycle in the ordering of the processors: a, b, c",
+		},
+		{
+			// Processors outside of the cycle are not reported.
+			"after and before",
+			[]map[string]interface{}{
+				appendName("a", map[string]interface{}{"id": "a", "before": "b"}),
+				appendName("b", map[string]interface{}{"id": "b", "before": "a"}),
+				appendName("c", map[string]interface{}{"id": "c"}),
+			},
+			"cycle in the ordering of the processors: a, b",
+		},
+	}
+
+	for _, test := range tests {
+		_, err := newOrderedProcessors(test.yml)
+		if assert.Error(t, err, test.name) {
+			assert.Equal(t, test.err, err.Error(), test.name)
+		}
+	}
+}
+
+func TestOrderFragments(t *testing.T) {
+	// Defaults and fragments, like the processors of hints or of the files of
+	// config.processors, are combined into a single chain. The processors of
+	// the fragment are appended, but run where the defaults expect them.
+	defaults := []map[string]interface{}{
+		appendName("extract_ip", map[string]interface{}{"id": "extract_ip"}),
+		appendName("cleanup", map[string]interface{}{"id": "cleanup"}),
+	}
+	fragment := []map[string]interface{}{
+		appendName("geoip", map[string]interface{}{"after": "extract_ip", "before": "cleanup"}),
+		{"drop_fields": map[string]interface{}{
+			"after":  "cleanup",
+			"fields": []string{"debug"},
+		}},
+	}
+
+	procs, err := newOrderedProcessors(append(defaults, fragment...))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"extract_ip", "geoip", "cleanup"}, chain(procs))
+
+	// The ordering settings are removed from the configuration of
+	// drop_fields, rejecting unknown settings.
+	assert.Len(t, procs.List, 4)
+}
+
+func TestOrderInvalidSettings(t *testing.T) {
+	_, err := newOrderedProcessors([]map[string]interface{}{
+		appendName("a", map[string]interface{}{"id": []string{"a", "b"}}),
+	})
+	assert.Error(t, err)
+}
@@ -2,8 +2,11 @@ package processors
 
 import (
 	"fmt"
+	"io"
 	"strings"
 
+	"github.com/joeshaw/multierror"
+
 	"github.com/elastic/beats/libbeat/beat"
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/logp"
@@ -18,9 +21,21 @@ type Processor interface {
 	String() string
 }
 
-func New(config PluginConfig) (*Processors, error) {
-	procs := Processors{}
+// New creates the processors of the configuration. The processors are run in
+// the configured order, unless they are ordered by the `after` and `before`
+// settings, referring to the `id` or the name of other processors.
+func New(config PluginConfig) (procs *Processors, err error) {
+	procs = &Processors{}
 
+	var list []orderedProcessor
+	defer func() {
+		// Release the processors already created, if a processor fails.
+		if err != nil {
+			for _, p := range list {
+				closeProcessor(p.processor)
+			}
+		}
+	}()
 	for _, processor := range config {
 
 		if len(processor) != 1 {
@@ -35,6 +50,11 @@ func New(config PluginConfig) (*Processors, error) {
 				return nil, fmt.Errorf("the processor %s doesn't exist", processorName)
 			}
 
+			order, cfg, err := extractOrder(processorName, cfg)
+			if err != nil {
+				return nil, err
+			}
+
 			cfg.PrintDebugf("Configure processor '%v' with:", processorName)
 			constructor := gen.Plugin()
 			plugin, err := constructor(cfg)
@@ -42,12 +62,48 @@ func New(config PluginConfig) (*Processors, error) {
 				return nil, err
 			}
 
-			procs.add(plugin)
+			list = append(list, orderedProcessor{
+				processor: plugin,
+				id:        order.ID,
+				after:     order.After,
+				before:    order.Before,
+			})
+		}
+	}
+
+	sorted, err := sortProcessors(list)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range sorted {
+		procs.add(p)
+	}
+
+	logp.Debug("processors", "Processors: %v", *procs)
+	return procs, nil
+}
+
+// Close closes the processors holding resources, like file watchers. Events
+// still being processed are completed.
+func (procs *Processors) Close() error {
+	if procs == nil {
+		return nil
+	}
+
+	var errs multierror.Errors
+	for _, p := range procs.List {
+		if err := closeProcessor(p); err != nil {
+			errs = append(errs, err)
 		}
 	}
+	return errs.Err()
+}
 
-	logp.Debug("processors", "Processors: %v", procs)
-	return &procs, nil
+func closeProcessor(p Processor) error {
+	if c, ok := p.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
 }
 
 func (procs *Processors) add(p Processor) {
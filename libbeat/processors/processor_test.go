@@ -1,6 +1,7 @@
 package processors_test
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -619,3 +620,54 @@ func TestDropMissingFields(t *testing.T) {
 
 	assert.Equal(t, expectedEvent, processedEvent.Fields)
 }
+
+// testClose counts how often it has been closed.
+type testClose struct {
+	closed int
+	err    error
+}
+
+func (p *testClose) Run(event *beat.Event) (*beat.Event, error) { return event, nil }
+func (p *testClose) String() string                             { return "test_close" }
+func (p *testClose) Close() error {
+	p.closed++
+	return p.err
+}
+
+func TestProcessorsClose(t *testing.T) {
+	first := &testClose{}
+	failing := &testClose{err: errors.New("close failed")}
+	last := &testClose{}
+	procs := &processors.Processors{List: []processors.Processor{first, failing, last}}
+
+	// All processors are closed, even if one of them fails.
+	assert.Error(t, procs.Close())
+	assert.Equal(t, 1, first.closed)
+	assert.Equal(t, 1, failing.closed)
+	assert.Equal(t, 1, last.closed)
+
+	var empty *processors.Processors
+	assert.NoError(t, empty.Close())
+}
+
+func TestProcessorsCloseConditional(t *testing.T) {
+	p := &testClose{}
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"when.equals.type": "process",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conditional, err := processors.NewConditional(func(*common.Config) (processors.Processor, error) {
+		return p, nil
+	})(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.IsType(t, &processors.WhenProcessor{}, conditional)
+
+	procs := &processors.Processors{List: []processors.Processor{conditional}}
+	assert.NoError(t, procs.Close())
+	assert.Equal(t, 1, p.closed)
+}
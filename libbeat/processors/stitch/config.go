@@ -0,0 +1,46 @@
+package stitch
+
+import (
+	"time"
+
+	"github.com/elastic/beats/libbeat/common/match"
+)
+
+// Config for the stitch processor.
+type Config struct {
+	// Key are the fields correlating the events to stitch, like a request or
+	// a thread ID.
+	Key []string `config:"key" validate:"required"`
+
+	// Field is the string field merged into the stitched event.
+	Field string `config:"field"`
+
+	// Separator is written between the values of the merged field.
+	Separator string `config:"separator"`
+
+	// Terminator matches the value of the field in the last event of a
+	// group. Without terminator, groups are stitched once their timeout
+	// elapsed.
+	Terminator *match.Matcher `config:"terminator"`
+
+	// Timeout is how long the events of a group are buffered, since the
+	// first event of the group.
+	Timeout time.Duration `config:"timeout" validate:"positive"`
+
+	// MaxEvents is the maximum number of events stitched into an event.
+	MaxEvents int `config:"max_events" validate:"min=1"`
+
+	// MaxGroups is the maximum number of groups buffered. The oldest group is
+	// stitched to make room for a new group.
+	MaxGroups int `config:"max_groups" validate:"min=1"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		Field:     "message",
+		Separator: "\n",
+		Timeout:   5 * time.Second,
+		MaxEvents: 500,
+		MaxGroups: 1000,
+	}
+}
@@ -0,0 +1,172 @@
+package stitch
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/libbeat/common/match"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+var debugf = logp.MakeDebug("stitch")
+
+func init() {
+	processors.RegisterPlugin("stitch", newStitchProcessor)
+}
+
+type stitch struct {
+	key        []string
+	field      string
+	separator  string
+	terminator *match.Matcher
+	timeout    time.Duration
+	maxEvents  int
+	maxGroups  int
+
+	mu     sync.Mutex
+	groups map[string]*list.Element
+	order  *list.List    // groups by deadline, oldest first
+	ready  []*beat.Event // stitched events not published yet
+	now    func() time.Time
+}
+
+// group is the events of a key buffered to be stitched.
+type group struct {
+	key      string
+	first    *beat.Event
+	values   []string
+	deadline time.Time
+}
+
+func newStitchProcessor(cfg *common.Config) (processors.Processor, error) {
+	cfgwarn.Experimental("The stitch processor is experimental")
+
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, errors.Wrap(err, "fail to unpack the stitch configuration")
+	}
+
+	return &stitch{
+		key:        config.Key,
+		field:      config.Field,
+		separator:  config.Separator,
+		terminator: config.Terminator,
+		timeout:    config.Timeout,
+		maxEvents:  config.MaxEvents,
+		maxGroups:  config.MaxGroups,
+		groups:     map[string]*list.Element{},
+		order:      list.New(),
+		now:        time.Now,
+	}, nil
+}
+
+// Run buffers the event into the group of its key, and returns the stitched
+// event once the terminator matches or the group is full. Otherwise, it
+// returns an event stitched before and not published yet, like a group whose
+// timeout elapsed, or nil. Events without the key or the field are returned
+// unchanged.
+func (p *stitch) Run(event *beat.Event) (*beat.Event, error) {
+	key, ok := p.groupKey(event)
+	if !ok {
+		return event, nil
+	}
+	v, err := event.GetValue(p.field)
+	if err != nil {
+		return event, nil
+	}
+	value, ok := v.(string)
+	if !ok {
+		debugf("Not stitching event with field %v of type %T", p.field, v)
+		return event, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	p.flushExpired(now)
+
+	elem, found := p.groups[key]
+	if !found {
+		if len(p.groups) >= p.maxGroups {
+			debugf("Stitching the oldest group to make room for group %v", key)
+			p.flush(p.order.Front())
+		}
+		elem = p.order.PushBack(&group{key: key, first: event, deadline: now.Add(p.timeout)})
+		p.groups[key] = elem
+	}
+	g := elem.Value.(*group)
+	g.values = append(g.values, value)
+
+	if len(g.values) >= p.maxEvents || (p.terminator != nil && p.terminator.MatchString(value)) {
+		p.remove(elem)
+		return p.merge(g), nil
+	}
+
+	if len(p.ready) == 0 {
+		return nil, nil
+	}
+	stitched := p.ready[0]
+	p.ready[0] = nil
+	p.ready = p.ready[1:]
+	return stitched, nil
+}
+
+// flushExpired stitches the groups whose timeout elapsed.
+func (p *stitch) flushExpired(now time.Time) {
+	for elem := p.order.Front(); elem != nil; elem = p.order.Front() {
+		if now.Before(elem.Value.(*group).deadline) {
+			return
+		}
+		p.flush(elem)
+	}
+}
+
+// flush stitches a group, to be published with the next event buffered.
+func (p *stitch) flush(elem *list.Element) {
+	p.remove(elem)
+	p.ready = append(p.ready, p.merge(elem.Value.(*group)))
+}
+
+func (p *stitch) remove(elem *list.Element) {
+	p.order.Remove(elem)
+	delete(p.groups, elem.Value.(*group).key)
+}
+
+// merge returns the first event of the group, with the values of the field
+// of all events joined.
+func (p *stitch) merge(g *group) *beat.Event {
+	event := g.first
+	if len(g.values) > 1 {
+		event.PutValue(p.field, strings.Join(g.values, p.separator))
+	}
+	return event
+}
+
+// groupKey returns the key of the group of the event, or false if the event
+// misses a key field.
+func (p *stitch) groupKey(event *beat.Event) (string, bool) {
+	values := make([]string, len(p.key))
+	for i, field := range p.key {
+		v, err := event.GetValue(field)
+		if err != nil {
+			return "", false
+		}
+		values[i] = fmt.Sprint(v)
+	}
+	return strings.Join(values, "\x00"), true
+}
+
+func (p *stitch) String() string {
+	return fmt.Sprintf("stitch=[key=%v, field=%v, terminator=%v, timeout=%v]",
+		p.key, p.field, p.terminator, p.timeout)
+}
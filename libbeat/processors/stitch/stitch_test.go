@@ -0,0 +1,147 @@
+package stitch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+var testConfig, _ = common.NewConfigFrom(map[string]interface{}{
+	"key":        []string{"trace.id"},
+	"terminator": "^END",
+	"timeout":    "5s",
+})
+
+func newStitch(t *testing.T, config *common.Config) (*stitch, *testClock) {
+	p, err := newStitchProcessor(config)
+	if err != nil {
+		t.Fatalf("error initializing stitch: %s", err)
+	}
+
+	clock := &testClock{now: time.Date(2018, 10, 14, 7, 30, 0, 0, time.UTC)}
+	s := p.(*stitch)
+	s.now = func() time.Time { return clock.now }
+	return s, clock
+}
+
+func run(t *testing.T, p *stitch, id, message string) *beat.Event {
+	fields := common.MapStr{"message": message}
+	if id != "" {
+		fields.Put("trace.id", id)
+	}
+	event, err := p.Run(&beat.Event{Fields: fields})
+	require.NoError(t, err)
+	return event
+}
+
+func message(t *testing.T, event *beat.Event) string {
+	require.NotNil(t, event)
+	v, err := event.GetValue("message")
+	require.NoError(t, err)
+	return v.(string)
+}
+
+func TestStitchInWindow(t *testing.T) {
+	p, clock := newStitch(t, testConfig)
+
+	assert.Nil(t, run(t, p, "a", "java.lang.NullPointerException"))
+	assert.Nil(t, run(t, p, "b", "GET /"))
+	clock.add(time.Second)
+	assert.Nil(t, run(t, p, "a", "  at Foo.bar(Foo.java:12)"))
+
+	// The terminator completes the group of its key only.
+	event := run(t, p, "a", "END of trace")
+	assert.Equal(t, "java.lang.NullPointerException\n  at Foo.bar(Foo.java:12)\nEND of trace", message(t, event))
+	id, _ := event.GetValue("trace.id")
+	assert.Equal(t, "a", id)
+
+	event = run(t, p, "b", "END 200")
+	assert.Equal(t, "GET /\nEND 200", message(t, event))
+	assert.Empty(t, p.groups)
+}
+
+func TestStitchTimeoutFlush(t *testing.T) {
+	p, clock := newStitch(t, testConfig)
+
+	assert.Nil(t, run(t, p, "a", "first"))
+	assert.Nil(t, run(t, p, "a", "second"))
+
+	// Once the timeout elapsed, the group is published with the next event
+	// buffered, which starts a new group.
+	clock.add(6 * time.Second)
+	event := run(t, p, "a", "third")
+	assert.Equal(t, "first\nsecond", message(t, event))
+	assert.Len(t, p.groups, 1)
+
+	// A group completed by its terminator is published first, expired groups
+	// follow with the next events.
+	assert.Nil(t, run(t, p, "b", "other"))
+	clock.add(6 * time.Second)
+	assert.Equal(t, "END", message(t, run(t, p, "c", "END")))
+	event = run(t, p, "d", "next")
+	assert.Equal(t, "third", message(t, event))
+	event = run(t, p, "d", "more")
+	assert.Equal(t, "other", message(t, event))
+	assert.Len(t, p.groups, 1)
+}
+
+func TestStitchBounds(t *testing.T) {
+	config, err := common.NewConfigFrom(map[string]interface{}{
+		"key":        []string{"trace.id"},
+		"max_events": 3,
+		"max_groups": 2,
+	})
+	require.NoError(t, err)
+	p, _ := newStitch(t, config)
+
+	// A full group is stitched.
+	assert.Nil(t, run(t, p, "a", "1"))
+	assert.Nil(t, run(t, p, "a", "2"))
+	assert.Equal(t, "1\n2\n3", message(t, run(t, p, "a", "3")))
+
+	// The oldest group is stitched to make room for a new group.
+	assert.Nil(t, run(t, p, "a", "a1"))
+	assert.Nil(t, run(t, p, "b", "b1"))
+	assert.Equal(t, "a1", message(t, run(t, p, "c", "c1")))
+	assert.Len(t, p.groups, 2)
+	assert.NotContains(t, p.groups, "a")
+}
+
+func TestStitchPassThrough(t *testing.T) {
+	p, _ := newStitch(t, testConfig)
+
+	// Events without key or without a string field are not buffered.
+	event := run(t, p, "", "no key")
+	assert.Equal(t, "no key", message(t, event))
+
+	event, err := p.Run(&beat.Event{Fields: common.MapStr{"trace": common.MapStr{"id": "a"}, "message": 42}})
+	require.NoError(t, err)
+	assert.Equal(t, common.MapStr{"trace": common.MapStr{"id": "a"}, "message": 42}, event.Fields)
+	assert.Empty(t, p.groups)
+}
+
+func TestStitchConfig(t *testing.T) {
+	for name, settings := range map[string]map[string]interface{}{
+		"missing key":        {"field": "message"},
+		"invalid terminator": {"key": []string{"id"}, "terminator": "("},
+		"invalid max events": {"key": []string{"id"}, "max_events": 0},
+	} {
+		cfg, err := common.NewConfigFrom(settings)
+		require.NoError(t, err)
+		_, err = newStitchProcessor(cfg)
+		assert.Error(t, err, name)
+	}
+}
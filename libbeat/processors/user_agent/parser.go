@@ -0,0 +1,59 @@
+package user_agent
+
+import "strings"
+
+// other is reported for every part of the user agent that can not be detected.
+const other = "Other"
+
+type userAgent struct {
+	name, version     string
+	osName, osVersion string
+	device            string
+}
+
+func parse(s string) userAgent {
+	ua := userAgent{name: other, osName: other, device: other}
+
+	if name, version, ok := match(agentPatterns, s); ok {
+		ua.name, ua.version = name, version
+	}
+
+	if name, version, ok := match(osPatterns, s); ok {
+		if name == "Windows" {
+			if release, exists := windowsVersions[version]; exists {
+				version = release
+			}
+		}
+		ua.osName, ua.osVersion = name, version
+	}
+
+	if name, _, ok := match(devicePatterns, s); ok {
+		ua.device = name
+	}
+
+	return ua
+}
+
+// match returns the name and version of the first pattern matching s.
+func match(patterns []pattern, s string) (name, version string, ok bool) {
+	for _, pattern := range patterns {
+		groups := pattern.regex.FindStringSubmatch(s)
+		if groups == nil {
+			continue
+		}
+
+		name = pattern.name
+		for i, group := range pattern.regex.SubexpNames() {
+			switch group {
+			case "name":
+				if name == "" {
+					name = groups[i]
+				}
+			case "version":
+				version = strings.Replace(groups[i], "_", ".", -1)
+			}
+		}
+		return name, version, true
+	}
+	return "", "", false
+}
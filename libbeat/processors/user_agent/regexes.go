@@ -0,0 +1,88 @@
+package user_agent
+
+import "regexp"
+
+// pattern matches a user agent string. The name and version are taken from
+// the `name` and `version` capture groups, unless name is set explicitly.
+type pattern struct {
+	regex *regexp.Regexp
+	name  string
+}
+
+func p(regex, name string) pattern {
+	return pattern{regex: regexp.MustCompile(regex), name: name}
+}
+
+// The bundled pattern sets are modeled after the ua-parser regexes. Patterns
+// are checked in order, so more specific patterns must be listed first.
+
+// agentPatterns detect the browser, library or bot.
+var agentPatterns = []pattern{
+	// bots and crawlers
+	p(`(?P<name>Googlebot(?:-Mobile|-Image|-Video|-News)?)/(?P<version>[\d.]+)`, ""),
+	p(`(?P<name>bingbot|YandexBot|Baiduspider|DuckDuckBot|AhrefsBot|SemrushBot|Applebot|Twitterbot|Slackbot|PetalBot)(?:[/-](?P<version>[\d.]+))?`, ""),
+	p(`(?P<name>facebookexternalhit|LinkedInBot|Pinterestbot)(?:/(?P<version>[\d.]+))?`, ""),
+	p(`(?i)(?P<name>[a-z0-9_-]*(?:bot|spider|crawler))(?:/(?P<version>[\d.]+))?`, ""),
+
+	// command line tools and libraries
+	p(`^(?P<name>curl|Wget|HTTPie|okhttp|Go-http-client|Apache-HttpClient)/(?P<version>[\d.]+)`, ""),
+	p(`^python-requests/(?P<version>[\d.]+)`, "Python Requests"),
+	p(`^Java/(?P<version>[\d._]+)`, "Java"),
+
+	// browsers based on chrome
+	p(`Edg(?:e|A|iOS)?/(?P<version>[\d.]+)`, "Edge"),
+	p(`(?:OPR|OPiOS)/(?P<version>[\d.]+)`, "Opera"),
+	p(`SamsungBrowser/(?P<version>[\d.]+)`, "Samsung Internet"),
+	p(`UCBrowser/(?P<version>[\d.]+)`, "UC Browser"),
+	p(`YaBrowser/(?P<version>[\d.]+)`, "Yandex Browser"),
+	p(`Vivaldi/(?P<version>[\d.]+)`, "Vivaldi"),
+	p(`CriOS/(?P<version>[\d.]+)`, "Chrome Mobile iOS"),
+	p(`FxiOS/(?P<version>[\d.]+)`, "Firefox iOS"),
+	p(`Android.+; wv\).+Chrome/(?P<version>[\d.]+)`, "Chrome Mobile WebView"),
+	p(`Android.+Chrome/(?P<version>[\d.]+) Mobile`, "Chrome Mobile"),
+	p(`Chrome/(?P<version>[\d.]+)`, "Chrome"),
+
+	// other browsers
+	p(`Mobile.+Firefox/(?P<version>[\d.]+)`, "Firefox Mobile"),
+	p(`Firefox/(?P<version>[\d.]+)`, "Firefox"),
+	p(`Opera/.+Version/(?P<version>[\d.]+)`, "Opera"),
+	p(`MSIE (?P<version>[\d.]+)`, "IE"),
+	p(`Trident/.+rv:(?P<version>[\d.]+)`, "IE"),
+	p(`Version/(?P<version>[\d.]+).*Mobile.*Safari/`, "Mobile Safari"),
+	p(`(?:iPhone|iPad|iPod).+AppleWebKit/.+Mobile/`, "Mobile Safari UI/WKWebView"),
+	p(`Version/(?P<version>[\d.]+).*Safari/`, "Safari"),
+}
+
+// osPatterns detect the operating system.
+var osPatterns = []pattern{
+	p(`Windows Phone(?: OS)? (?P<version>[\d.]+)`, "Windows Phone"),
+	p(`Windows NT (?P<version>[\d.]+)`, "Windows"),
+	p(`Windows (?P<version>XP|Vista|98|95)`, "Windows"),
+	p(`(?:iPhone|iPad|iPod)(?:;.*)? (?:CPU )?(?:iPhone )?OS (?P<version>[\d_]+)`, "iOS"),
+	p(`Mac OS X (?P<version>[\d_.]+)`, "Mac OS X"),
+	p(`Android (?P<version>[\d.]+)`, "Android"),
+	p(`CrOS \S+ (?P<version>[\d.]+)`, "Chrome OS"),
+	p(`(?P<name>Ubuntu|Fedora|Debian|CentOS)(?:/(?P<version>[\d.]+))?`, ""),
+	p(`(?P<name>FreeBSD|OpenBSD|NetBSD)`, ""),
+	p(`Linux`, "Linux"),
+}
+
+// devicePatterns detect the device.
+var devicePatterns = []pattern{
+	p(`(?i)bot|spider|crawler|facebookexternalhit`, "Spider"),
+	p(`(?P<name>iPhone|iPad|iPod)`, ""),
+	p(`Macintosh`, "Mac"),
+	p(`Android [\d.]+; (?:[a-zA-Z]{2}[-_][a-zA-Z]{2}; )?(?P<name>[^;)]+?)(?: Build/|\))`, ""),
+}
+
+// windowsVersions maps the Windows NT kernel versions to the release names.
+var windowsVersions = map[string]string{
+	"10.0": "10",
+	"6.3":  "8.1",
+	"6.2":  "8",
+	"6.1":  "7",
+	"6.0":  "Vista",
+	"5.2":  "XP",
+	"5.1":  "XP",
+	"5.0":  "2000",
+}
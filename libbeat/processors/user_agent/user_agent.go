@@ -0,0 +1,90 @@
+package user_agent
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+type userAgentProcessor struct {
+	config config
+}
+
+type config struct {
+	Field         string `config:"field" validate:"required"`
+	Target        string `config:"target"`
+	IgnoreMissing bool   `config:"ignore_missing"`
+}
+
+var defaultConfig = config{
+	Target: "user_agent",
+}
+
+func init() {
+	processors.RegisterPlugin("user_agent", newUserAgent)
+}
+
+func newUserAgent(c *common.Config) (processors.Processor, error) {
+	config := defaultConfig
+	if err := c.Unpack(&config); err != nil {
+		return nil, errors.Wrap(err, "fail to unpack the user_agent configuration")
+	}
+
+	return &userAgentProcessor{config: config}, nil
+}
+
+func (p *userAgentProcessor) Run(event *beat.Event) (*beat.Event, error) {
+	value, err := event.GetValue(p.config.Field)
+	if err != nil {
+		if p.config.IgnoreMissing && errors.Cause(err) == common.ErrKeyNotFound {
+			return event, nil
+		}
+		return event, errors.Wrapf(err, "failed to get user agent field '%v'", p.config.Field)
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return event, errors.Errorf("user agent field '%v' is not a string", p.config.Field)
+	}
+	if s == "" {
+		if p.config.IgnoreMissing {
+			return event, nil
+		}
+		return event, errors.Errorf("user agent field '%v' is empty", p.config.Field)
+	}
+
+	ua := parse(s)
+	fields := common.MapStr{
+		"name":   ua.name,
+		"device": common.MapStr{"name": ua.device},
+		"os": common.MapStr{
+			"name": ua.osName,
+			"full": ua.osName,
+		},
+	}
+	if ua.version != "" {
+		fields["version"] = ua.version
+	}
+	if ua.osVersion != "" {
+		fields.Put("os.version", ua.osVersion)
+		fields.Put("os.full", ua.osName+" "+ua.osVersion)
+	}
+
+	for key, value := range fields {
+		if p.config.Target != "" {
+			key = p.config.Target + "." + key
+		}
+		if _, err := event.PutValue(key, value); err != nil {
+			return event, errors.Wrap(err, "failed to add user agent fields")
+		}
+	}
+	return event, nil
+}
+
+func (p *userAgentProcessor) String() string {
+	return fmt.Sprintf("user_agent=[field=%v, target=%v]", p.config.Field, p.config.Target)
+}
@@ -0,0 +1,215 @@
+package user_agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		ua       string
+		expected userAgent
+	}{
+		// desktop
+		{
+			ua:       "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/61.0.3163.100 Safari/537.36",
+			expected: userAgent{name: "Chrome", version: "61.0.3163.100", osName: "Windows", osVersion: "10", device: "Other"},
+		},
+		{
+			ua:       "Mozilla/5.0 (Windows NT 6.1; WOW64; Trident/7.0; rv:11.0) like Gecko",
+			expected: userAgent{name: "IE", version: "11.0", osName: "Windows", osVersion: "7", device: "Other"},
+		},
+		{
+			ua:       "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/58.0.3029.110 Safari/537.36 Edge/16.16299",
+			expected: userAgent{name: "Edge", version: "16.16299", osName: "Windows", osVersion: "10", device: "Other"},
+		},
+		{
+			ua:       "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_13_1) AppleWebKit/604.3.5 (KHTML, like Gecko) Version/11.0.1 Safari/604.3.5",
+			expected: userAgent{name: "Safari", version: "11.0.1", osName: "Mac OS X", osVersion: "10.13.1", device: "Mac"},
+		},
+		{
+			ua:       "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:57.0) Gecko/20100101 Firefox/57.0",
+			expected: userAgent{name: "Firefox", version: "57.0", osName: "Ubuntu", device: "Other"},
+		},
+		{
+			ua:       "Mozilla/5.0 (Windows NT 6.1; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/62.0.3202.62 Safari/537.36 OPR/49.0.2725.39",
+			expected: userAgent{name: "Opera", version: "49.0.2725.39", osName: "Windows", osVersion: "7", device: "Other"},
+		},
+
+		// mobile
+		{
+			ua:       "Mozilla/5.0 (iPhone; CPU iPhone OS 11_1_1 like Mac OS X) AppleWebKit/604.3.5 (KHTML, like Gecko) Version/11.0 Mobile/15B150 Safari/604.1",
+			expected: userAgent{name: "Mobile Safari", version: "11.0", osName: "iOS", osVersion: "11.1.1", device: "iPhone"},
+		},
+		{
+			ua:       "Mozilla/5.0 (iPad; CPU OS 10_3_3 like Mac OS X) AppleWebKit/603.1.30 (KHTML, like Gecko) CriOS/62.0.3202.70 Mobile/14G60 Safari/602.1",
+			expected: userAgent{name: "Chrome Mobile iOS", version: "62.0.3202.70", osName: "iOS", osVersion: "10.3.3", device: "iPad"},
+		},
+		{
+			ua:       "Mozilla/5.0 (Linux; Android 7.0; SM-G930F Build/NRD90M) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/62.0.3202.84 Mobile Safari/537.36",
+			expected: userAgent{name: "Chrome Mobile", version: "62.0.3202.84", osName: "Android", osVersion: "7.0", device: "SM-G930F"},
+		},
+		{
+			ua:       "Mozilla/5.0 (Linux; Android 8.0.0; Pixel XL) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/6.2 Chrome/56.0.2924.87 Mobile Safari/537.36",
+			expected: userAgent{name: "Samsung Internet", version: "6.2", osName: "Android", osVersion: "8.0.0", device: "Pixel XL"},
+		},
+		{
+			ua:       "Mozilla/5.0 (Android 7.1.1; Mobile; rv:57.0) Gecko/57.0 Firefox/57.0",
+			expected: userAgent{name: "Firefox Mobile", version: "57.0", osName: "Android", osVersion: "7.1.1", device: "Other"},
+		},
+
+		// bots
+		{
+			ua:       "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			expected: userAgent{name: "Googlebot", version: "2.1", osName: "Other", device: "Spider"},
+		},
+		{
+			ua:       "Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)",
+			expected: userAgent{name: "bingbot", version: "2.0", osName: "Other", device: "Spider"},
+		},
+		{
+			ua:       "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)",
+			expected: userAgent{name: "facebookexternalhit", version: "1.1", osName: "Other", device: "Spider"},
+		},
+		{
+			ua:       "Mozilla/5.0 (compatible; MJ12bot/v1.4.7; http://mj12bot.com/)",
+			expected: userAgent{name: "MJ12bot", osName: "Other", device: "Spider"},
+		},
+
+		// tools
+		{
+			ua:       "curl/7.54.0",
+			expected: userAgent{name: "curl", version: "7.54.0", osName: "Other", device: "Other"},
+		},
+		{
+			ua:       "python-requests/2.18.4",
+			expected: userAgent{name: "Python Requests", version: "2.18.4", osName: "Other", device: "Other"},
+		},
+
+		// unknown
+		{
+			ua:       "something completely different",
+			expected: userAgent{name: "Other", osName: "Other", device: "Other"},
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, parse(test.ua), test.ua)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	config, _ := common.NewConfigFrom(map[string]interface{}{
+		"field": "user_agent.original",
+	})
+
+	ua := "Mozilla/5.0 (iPhone; CPU iPhone OS 11_1_1 like Mac OS X) AppleWebKit/604.3.5 (KHTML, like Gecko) Version/11.0 Mobile/15B150 Safari/604.1"
+	actual, err := runUserAgent(t, config, common.MapStr{
+		"user_agent": common.MapStr{"original": ua},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"user_agent": common.MapStr{
+			"original": ua,
+			"name":     "Mobile Safari",
+			"version":  "11.0",
+			"os": common.MapStr{
+				"name":    "iOS",
+				"version": "11.1.1",
+				"full":    "iOS 11.1.1",
+			},
+			"device": common.MapStr{"name": "iPhone"},
+		},
+	}, actual)
+}
+
+func TestUserAgentTarget(t *testing.T) {
+	config, _ := common.NewConfigFrom(map[string]interface{}{
+		"field":  "http.agent",
+		"target": "http.user_agent",
+	})
+
+	actual, err := runUserAgent(t, config, common.MapStr{
+		"http": common.MapStr{"agent": "curl/7.54.0"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"http": common.MapStr{
+			"agent": "curl/7.54.0",
+			"user_agent": common.MapStr{
+				"name":    "curl",
+				"version": "7.54.0",
+				"os":      common.MapStr{"name": "Other", "full": "Other"},
+				"device":  common.MapStr{"name": "Other"},
+			},
+		},
+	}, actual)
+}
+
+func TestUserAgentMissing(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		input  common.MapStr
+		err    bool
+	}{
+		{
+			name:   "missing field",
+			config: map[string]interface{}{"field": "agent"},
+			input:  common.MapStr{"message": "hello"},
+			err:    true,
+		},
+		{
+			name:   "ignore missing field",
+			config: map[string]interface{}{"field": "agent", "ignore_missing": true},
+			input:  common.MapStr{"message": "hello"},
+		},
+		{
+			name:   "ignore empty field",
+			config: map[string]interface{}{"field": "agent", "ignore_missing": true},
+			input:  common.MapStr{"agent": ""},
+		},
+		{
+			// non string values are always reported
+			name:   "invalid field",
+			config: map[string]interface{}{"field": "agent", "ignore_missing": true},
+			input:  common.MapStr{"agent": 1},
+			err:    true,
+		},
+	}
+
+	for _, test := range tests {
+		config, _ := common.NewConfigFrom(test.config)
+
+		actual, err := runUserAgent(t, config, test.input.Clone())
+		if test.err {
+			assert.Error(t, err, test.name)
+		} else {
+			assert.NoError(t, err, test.name)
+		}
+		assert.Equal(t, test.input, actual, test.name)
+	}
+}
+
+func TestUserAgentInvalidConfig(t *testing.T) {
+	cfg, err := common.NewConfigFrom(map[string]interface{}{"target": "ua"})
+	require.NoError(t, err)
+
+	_, err = newUserAgent(cfg)
+	assert.Error(t, err)
+}
+
+func runUserAgent(t *testing.T, config *common.Config, input common.MapStr) (common.MapStr, error) {
+	p, err := newUserAgent(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := p.Run(&beat.Event{Fields: input})
+	return actual.Fields, err
+}
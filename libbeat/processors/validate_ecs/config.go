@@ -0,0 +1,86 @@
+package validate_ecs
+
+import (
+	"fmt"
+
+	"github.com/elastic/beats/libbeat/publisher/deadletter"
+)
+
+// Config for the validate_ecs processor.
+type Config struct {
+	// Fields are the fields required in the events, and their types.
+	Fields []FieldConfig `config:"fields" validate:"required"`
+
+	// OnFailure is the action on events failing the validation, `drop`, `tag`
+	// or `dead_letter`.
+	OnFailure string `config:"on_failure"`
+
+	// Tag is added to the tags of the events failing the validation, if the
+	// action is `tag`.
+	Tag string `config:"tag"`
+
+	// DeadLetter configures the spool file events failing the validation are
+	// written to, if the action is `dead_letter`.
+	DeadLetter DeadLetterConfig `config:"dead_letter"`
+}
+
+// FieldConfig is a required field and its type.
+type FieldConfig struct {
+	Name string `config:"name" validate:"required"`
+	Type string `config:"type" validate:"required"`
+}
+
+// DeadLetterConfig configures the dead-letter spool file of the processor.
+type DeadLetterConfig struct {
+	// Path is the spool file. Relative paths are resolved against the data
+	// path.
+	Path string `config:"path"`
+
+	// MaxBytes is the maximum size of the spool file. Events are dropped once
+	// the spool file has reached this size.
+	MaxBytes int64 `config:"max_bytes" validate:"min=1"`
+}
+
+const (
+	onFailureDrop       = "drop"
+	onFailureTag        = "tag"
+	onFailureDeadLetter = "dead_letter"
+)
+
+func defaultConfig() Config {
+	return Config{
+		OnFailure: onFailureDrop,
+		Tag:       "ecs_validation_failed",
+		DeadLetter: DeadLetterConfig{
+			Path:     "dead_letter/" + deadletter.FileName("validate_ecs"),
+			MaxBytes: deadletter.DefaultConfig.MaxBytes,
+		},
+	}
+}
+
+// Validate checks the action on failure and the types of the fields.
+func (c *Config) Validate() error {
+	switch c.OnFailure {
+	case onFailureDrop, onFailureTag, onFailureDeadLetter:
+	default:
+		return fmt.Errorf("invalid on_failure action '%s', must be '%s', '%s' or '%s'",
+			c.OnFailure, onFailureDrop, onFailureTag, onFailureDeadLetter)
+	}
+
+	if c.OnFailure == onFailureTag && c.Tag == "" {
+		return fmt.Errorf("tag must be set if on_failure is '%s'", onFailureTag)
+	}
+
+	seen := map[string]bool{}
+	for _, f := range c.Fields {
+		if seen[f.Name] {
+			return fmt.Errorf("field '%s' is configured more than once", f.Name)
+		}
+		seen[f.Name] = true
+
+		if _, found := checks[f.Type]; !found {
+			return fmt.Errorf("unknown type '%s' of field '%s'", f.Type, f.Name)
+		}
+	}
+	return nil
+}
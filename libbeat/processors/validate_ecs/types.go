@@ -0,0 +1,126 @@
+package validate_ecs
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// check reports if a value is of the type of a field.
+type check func(value interface{}) error
+
+// checks by the Elasticsearch type of the field. Values must have the type
+// already, numbers and booleans in strings are not accepted, unlike in
+// Elasticsearch.
+var checks = map[string]check{
+	"keyword": isString,
+	"text":    isString,
+	"long":    isLong,
+	"double":  isDouble,
+	"boolean": isBoolean,
+	"ip":      isIP,
+	"date":    isDate,
+	"object":  isObject,
+}
+
+func isString(value interface{}) error {
+	if _, ok := value.(string); !ok {
+		return fmt.Errorf("expected a string, found %T", value)
+	}
+	return nil
+}
+
+// isLong accepts integers, and floats without fraction, like numbers decoded
+// from JSON.
+func isLong(value interface{}) error {
+	switch v := value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32:
+		return nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return fmt.Errorf("%d overflows a long", v)
+		}
+		return nil
+	case float32:
+		return isIntegral(float64(v))
+	case float64:
+		return isIntegral(v)
+	default:
+		return fmt.Errorf("expected a long, found %T", value)
+	}
+}
+
+func isIntegral(f float64) error {
+	if f != math.Trunc(f) || f > math.MaxInt64 || f < math.MinInt64 {
+		return fmt.Errorf("%v is not a long", f)
+	}
+	return nil
+}
+
+func isDouble(value interface{}) error {
+	switch value.(type) {
+	case float32, float64:
+		return nil
+	}
+	if err := isLong(value); err != nil {
+		return fmt.Errorf("expected a double, found %T", value)
+	}
+	return nil
+}
+
+func isBoolean(value interface{}) error {
+	if _, ok := value.(bool); !ok {
+		return fmt.Errorf("expected a boolean, found %T", value)
+	}
+	return nil
+}
+
+func isIP(value interface{}) error {
+	switch v := value.(type) {
+	case net.IP:
+		return nil
+	case string:
+		if net.ParseIP(v) == nil {
+			return fmt.Errorf("'%s' is not an ip address", v)
+		}
+		return nil
+	default:
+		return fmt.Errorf("expected an ip address, found %T", value)
+	}
+}
+
+// isDate accepts times, and strings in the RFC 3339 format of the timestamps
+// of the events.
+func isDate(value interface{}) error {
+	switch v := value.(type) {
+	case time.Time:
+		if v.IsZero() {
+			return fmt.Errorf("time is not set")
+		}
+		return nil
+	case common.Time:
+		if time.Time(v).IsZero() {
+			return fmt.Errorf("time is not set")
+		}
+		return nil
+	case string:
+		if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+			return fmt.Errorf("'%s' is not a date", v)
+		}
+		return nil
+	default:
+		return fmt.Errorf("expected a date, found %T", value)
+	}
+}
+
+func isObject(value interface{}) error {
+	switch value.(type) {
+	case common.MapStr, map[string]interface{}:
+		return nil
+	default:
+		return fmt.Errorf("expected an object, found %T", value)
+	}
+}
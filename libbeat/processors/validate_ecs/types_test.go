@@ -0,0 +1,36 @@
+package validate_ecs
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func TestChecks(t *testing.T) {
+	tests := []struct {
+		typ     string
+		valid   []interface{}
+		invalid []interface{}
+	}{
+		{"keyword", []interface{}{"a", ""}, []interface{}{1, true, []string{"a"}}},
+		{"long", []interface{}{1, int64(-1), uint32(1), float64(200)}, []interface{}{"1", 1.5, uint64(1 << 63), true}},
+		{"double", []interface{}{1.5, float32(1), 2}, []interface{}{"1.5", false}},
+		{"boolean", []interface{}{true}, []interface{}{"true", 1}},
+		{"ip", []interface{}{"10.0.0.1", "::1", net.ParseIP("10.0.0.1")}, []interface{}{"10.0.0", "localhost", 1}},
+		{"date", []interface{}{time.Now(), common.Time(time.Now()), "2018-03-01T12:00:00.123Z"}, []interface{}{time.Time{}, "2018-03-01", 1519905600}},
+		{"object", []interface{}{common.MapStr{}, map[string]interface{}{}}, []interface{}{"{}", []interface{}{}}},
+	}
+	for _, test := range tests {
+		check := checks[test.typ]
+		for _, v := range test.valid {
+			assert.NoError(t, check(v), "%v (%T) as %s", v, v, test.typ)
+		}
+		for _, v := range test.invalid {
+			assert.Error(t, check(v), "%v (%T) as %s", v, v, test.typ)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package validate_ecs
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/monitoring"
+	"github.com/elastic/beats/libbeat/paths"
+	"github.com/elastic/beats/libbeat/processors"
+	"github.com/elastic/beats/libbeat/publisher/deadletter"
+)
+
+var debug = logp.MakeDebug("validate_ecs")
+
+var (
+	droppedEvents      = monitoring.NewInt(nil, "libbeat.processors.validate_ecs.dropped")
+	taggedEvents       = monitoring.NewInt(nil, "libbeat.processors.validate_ecs.tagged")
+	deadLetteredEvents = monitoring.NewInt(nil, "libbeat.processors.validate_ecs.dead_lettered")
+
+	// spools are shared by all processors writing to the same file, like the
+	// processors of the inputs of a beat.
+	spoolsMutex sync.Mutex
+	spools      = map[string]*deadletter.Spool{}
+)
+
+func init() {
+	processors.RegisterPlugin("validate_ecs", newValidateECS)
+}
+
+type validateECS struct {
+	fields    []field
+	onFailure string
+	tag       string
+	spool     *deadletter.Spool
+}
+
+// field is a FieldConfig with its resolved check.
+type field struct {
+	name, typ string
+	check     check
+}
+
+func newValidateECS(cfg *common.Config) (processors.Processor, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, errors.Wrap(err, "fail to unpack the validate_ecs configuration")
+	}
+
+	fields := make([]field, len(config.Fields))
+	for i, f := range config.Fields {
+		fields[i] = field{name: f.Name, typ: f.Type, check: checks[f.Type]}
+	}
+
+	p := &validateECS{
+		fields:    fields,
+		onFailure: config.OnFailure,
+		tag:       config.Tag,
+	}
+	if config.OnFailure == onFailureDeadLetter {
+		spool, err := openSpool(paths.Resolve(paths.Data, config.DeadLetter.Path), config.DeadLetter.MaxBytes)
+		if err != nil {
+			return nil, err
+		}
+		p.spool = spool
+	}
+	return p, nil
+}
+
+// openSpool returns the spool of the file at path, opening it if no other
+// processor uses it yet. The size limit of the processor opening the file
+// applies.
+func openSpool(path string, maxBytes int64) (*deadletter.Spool, error) {
+	spoolsMutex.Lock()
+	defer spoolsMutex.Unlock()
+
+	if spool, exists := spools[path]; exists {
+		return spool, nil
+	}
+	spool, err := deadletter.Open(path, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	spools[path] = spool
+	return spool, nil
+}
+
+// Run checks that the required fields are present and of their types. Events
+// failing the validation are dropped, tagged or written to the dead-letter
+// spool file, then dropped.
+func (p *validateECS) Run(event *beat.Event) (*beat.Event, error) {
+	problems := p.validate(event)
+	if len(problems) == 0 {
+		return event, nil
+	}
+	debug("event failed the validation: %s", strings.Join(problems, ", "))
+
+	switch p.onFailure {
+	case onFailureTag:
+		taggedEvents.Inc()
+		if event.Fields == nil {
+			event.Fields = common.MapStr{}
+		}
+		if err := common.AddTags(event.Fields, []string{p.tag}); err != nil {
+			return event, errors.Wrap(err, "failed to tag the event failing the validation")
+		}
+		event.PutValue("error.message", "ECS validation failed: "+strings.Join(problems, ", "))
+		return event, nil
+
+	case onFailureDeadLetter:
+		if err := p.spool.Add(*event); err != nil {
+			droppedEvents.Inc()
+			if err != deadletter.ErrFull {
+				logp.Err("Failed to spool the event failing the ECS validation: %v", err)
+			}
+			return nil, nil
+		}
+		deadLetteredEvents.Inc()
+		return nil, nil
+
+	default:
+		droppedEvents.Inc()
+		return nil, nil
+	}
+}
+
+// validate returns the problems of the fields of the event.
+func (p *validateECS) validate(event *beat.Event) []string {
+	var problems []string
+	for _, f := range p.fields {
+		value, err := event.GetValue(f.name)
+		if err != nil || value == nil {
+			problems = append(problems, fmt.Sprintf("field '%s' is missing", f.name))
+			continue
+		}
+		if err := f.check(value); err != nil {
+			problems = append(problems, fmt.Sprintf("field '%s' is not of type %s: %s", f.name, f.typ, err))
+		}
+	}
+	return problems
+}
+
+func (p *validateECS) String() string {
+	names := make([]string, len(p.fields))
+	for i, f := range p.fields {
+		names[i] = f.name
+	}
+	return fmt.Sprintf("validate_ecs=[fields=%s, on_failure=%s]", strings.Join(names, ","), p.onFailure)
+}
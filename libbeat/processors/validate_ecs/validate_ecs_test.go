@@ -0,0 +1,213 @@
+package validate_ecs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/publisher/deadletter"
+)
+
+// testFields are the ECS fields validated by the tests.
+var testFields = []map[string]interface{}{
+	{"name": "@timestamp", "type": "date"},
+	{"name": "event.dataset", "type": "keyword"},
+	{"name": "source.ip", "type": "ip"},
+	{"name": "http.response.status_code", "type": "long"},
+}
+
+func newValidator(t *testing.T, settings map[string]interface{}) *validateECS {
+	config, err := common.NewConfigFrom(settings)
+	if err != nil {
+		t.Fatalf("error creating config: %s", err)
+	}
+
+	p, err := newValidateECS(config)
+	if err != nil {
+		t.Fatalf("error initializing validate_ecs: %s", err)
+	}
+	return p.(*validateECS)
+}
+
+func validEvent() *beat.Event {
+	return &beat.Event{
+		Timestamp: time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC),
+		Fields: common.MapStr{
+			"event":   common.MapStr{"dataset": "nginx.access"},
+			"source":  common.MapStr{"ip": "10.0.0.1"},
+			"http":    common.MapStr{"response": common.MapStr{"status_code": float64(200)}},
+			"message": "GET /",
+		},
+	}
+}
+
+// invalidEvents are failing the validation with a missing field, or a field
+// of the wrong type.
+func invalidEvents() map[string]*beat.Event {
+	missing := validEvent()
+	missing.Fields.Delete("event.dataset")
+
+	noTimestamp := validEvent()
+	noTimestamp.Timestamp = time.Time{}
+
+	mismatch := validEvent()
+	mismatch.Fields.Put("http.response.status_code", "200")
+
+	invalidIP := validEvent()
+	invalidIP.Fields.Put("source.ip", "localhost")
+
+	return map[string]*beat.Event{
+		"missing field":      missing,
+		"missing timestamp":  noTimestamp,
+		"type mismatch":      mismatch,
+		"invalid ip address": invalidIP,
+	}
+}
+
+func TestValidEventsArePassed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate_ecs")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		action   string
+		settings map[string]interface{}
+	}{
+		{
+			action:   "drop",
+			settings: map[string]interface{}{"fields": testFields},
+		},
+		{
+			action: "tag",
+			settings: map[string]interface{}{
+				"fields":     testFields,
+				"on_failure": "tag",
+			},
+		},
+		{
+			action: "dead_letter",
+			settings: map[string]interface{}{
+				"fields":           testFields,
+				"on_failure":       "dead_letter",
+				"dead_letter.path": filepath.Join(dir, "invalid.ndjson"),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		p := newValidator(t, test.settings)
+		event, err := p.Run(validEvent())
+		assert.NoError(t, err, test.action)
+		assert.Equal(t, validEvent(), event, test.action)
+	}
+}
+
+func TestDropInvalidEvents(t *testing.T) {
+	p := newValidator(t, map[string]interface{}{"fields": testFields})
+
+	for name, event := range invalidEvents() {
+		out, err := p.Run(event)
+		assert.NoError(t, err, name)
+		assert.Nil(t, out, name)
+	}
+}
+
+func TestTagInvalidEvents(t *testing.T) {
+	p := newValidator(t, map[string]interface{}{
+		"fields":     testFields,
+		"on_failure": "tag",
+		"tag":        "invalid",
+	})
+
+	expected := map[string]string{
+		"missing field":      "field 'event.dataset' is missing",
+		"missing timestamp":  "field '@timestamp' is not of type date: time is not set",
+		"type mismatch":      "field 'http.response.status_code' is not of type long: expected a long, found string",
+		"invalid ip address": "field 'source.ip' is not of type ip: 'localhost' is not an ip address",
+	}
+	for name, event := range invalidEvents() {
+		out, err := p.Run(event)
+		require.NoError(t, err, name)
+		require.NotNil(t, out, name)
+
+		assert.Equal(t, []string{"invalid"}, out.Fields["tags"], name)
+		msg, _ := out.GetValue("error.message")
+		assert.Equal(t, "ECS validation failed: "+expected[name], msg, name)
+	}
+}
+
+func TestDeadLetterInvalidEvents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate_ecs")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "invalid.ndjson")
+
+	settings := map[string]interface{}{
+		"fields":           testFields,
+		"on_failure":       "dead_letter",
+		"dead_letter.path": path,
+	}
+	p := newValidator(t, settings)
+	// Processors using the same file share its spool.
+	assert.Equal(t, p.spool, newValidator(t, settings).spool)
+
+	invalid := invalidEvents()
+	for _, name := range []string{"missing field", "type mismatch"} {
+		out, err := p.Run(invalid[name])
+		assert.NoError(t, err, name)
+		assert.Nil(t, out, name)
+	}
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	reader := deadletter.NewReader(file)
+	event, err := reader.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "GET /", event.Fields["message"])
+	_, err = event.GetValue("event.dataset")
+	assert.Error(t, err)
+
+	event, err = reader.Next()
+	require.NoError(t, err)
+	status, _ := event.GetValue("http.response.status_code")
+	assert.Equal(t, "200", status)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"no fields": {},
+		"unknown type": {
+			"fields": []map[string]interface{}{{"name": "source.port", "type": "port"}},
+		},
+		"duplicate field": {
+			"fields": []map[string]interface{}{
+				{"name": "source.ip", "type": "ip"},
+				{"name": "source.ip", "type": "keyword"},
+			},
+		},
+		"unknown action": {
+			"fields":     []map[string]interface{}{{"name": "source.ip", "type": "ip"}},
+			"on_failure": "ignore",
+		},
+		"empty tag": {
+			"fields":     []map[string]interface{}{{"name": "source.ip", "type": "ip"}},
+			"on_failure": "tag",
+			"tag":        "",
+		},
+	}
+	for name, settings := range tests {
+		cfg, err := common.NewConfigFrom(settings)
+		require.NoError(t, err)
+		_, err = newValidateECS(cfg)
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,130 @@
+// Package capture records a sample of the raw events published to the
+// pipeline, before any processing is applied, for debugging processor
+// configurations. Captured events can be fed back into the pipeline with the
+// replay command, using a different configuration.
+//
+// Capture files use the format of the dead-letter spool files, and are read
+// with deadletter.Reader.
+package capture
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/publisher/deadletter"
+)
+
+// Writer writes a sample of events to a capture file, until the maximum
+// number of events or bytes is reached. It is safe for concurrent use.
+type Writer struct {
+	path       string
+	sampleRate float64
+	maxEvents  int
+	maxBytes   int64
+
+	mutex  sync.Mutex
+	file   *os.File
+	events int
+	size   int64
+	done   bool
+	random func() float64
+}
+
+// Open creates the capture file at path, creating its directory if it does
+// not exist yet. An existing capture file is truncated, so every run of the
+// beat captures a new sample of events.
+func Open(path string, config Config) (*Writer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create capture directory: %v", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open capture file: %v", err)
+	}
+
+	return &Writer{
+		path:       path,
+		sampleRate: config.SampleRate,
+		maxEvents:  config.MaxEvents,
+		maxBytes:   config.MaxBytes,
+		file:       file,
+		random:     rand.New(rand.NewSource(time.Now().UnixNano())).Float64,
+	}, nil
+}
+
+// Path returns the path of the capture file.
+func (w *Writer) Path() string {
+	return w.path
+}
+
+// Add writes the event to the capture file, if it is selected by the sample
+// rate. The event is encoded right away, so it can be modified by processors
+// once Add returns. Capturing stops once the maximum number of events or
+// bytes has been written.
+func (w *Writer) Add(event beat.Event) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.file == nil || w.done {
+		return nil
+	}
+	if w.sampleRate < 1 && w.random() >= w.sampleRate {
+		return nil
+	}
+
+	line, err := deadletter.EncodeEvent(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event for the capture file: %v", err)
+	}
+
+	if w.size+int64(len(line)) > w.maxBytes {
+		w.stop()
+		return nil
+	}
+
+	n, err := w.file.Write(line)
+	w.size += int64(n)
+	if err != nil {
+		w.stop()
+		return fmt.Errorf("failed to write to capture file: %v", err)
+	}
+
+	w.events++
+	if w.events >= w.maxEvents {
+		w.stop()
+	}
+	return nil
+}
+
+// Events returns the number of events captured.
+func (w *Writer) Events() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.events
+}
+
+// stop ends capturing. The mutex must be held.
+func (w *Writer) stop() {
+	w.done = true
+	logp.Info("Event capture complete, %v events written to %v", w.events, w.path)
+}
+
+// Close closes the capture file.
+func (w *Writer) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
@@ -0,0 +1,161 @@
+package capture
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/publisher/deadletter"
+)
+
+func tempCapturePath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "capture")
+	require.NoError(t, err)
+	return filepath.Join(dir, "capture", "events.ndjson"), func() { os.RemoveAll(dir) }
+}
+
+func readIDs(t *testing.T, path string) []int64 {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var ids []int64
+	r := deadletter.NewReader(file)
+	for {
+		event, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		id, _ := event.Fields["id"].(int64)
+		ids = append(ids, id)
+	}
+	assert.Equal(t, 0, r.Skipped())
+	return ids
+}
+
+func testEvent(id int) beat.Event {
+	return beat.Event{
+		Timestamp: time.Date(2017, 10, 14, 8, 0, 0, 0, time.UTC),
+		Meta:      common.MapStr{"index": "test"},
+		Fields:    common.MapStr{"id": id},
+	}
+}
+
+func TestCaptureRoundTrip(t *testing.T) {
+	path, cleanup := tempCapturePath(t)
+	defer cleanup()
+
+	w, err := Open(path, DefaultConfig)
+	require.NoError(t, err)
+	require.NoError(t, w.Add(testEvent(1)))
+	require.NoError(t, w.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	event, err := deadletter.NewReader(file).Next()
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2017, 10, 14, 8, 0, 0, 0, time.UTC), event.Timestamp.UTC())
+	assert.Equal(t, common.MapStr{"index": "test"}, event.Meta)
+	assert.Equal(t, common.MapStr{"id": int64(1)}, event.Fields)
+}
+
+func TestCaptureMaxEvents(t *testing.T) {
+	path, cleanup := tempCapturePath(t)
+	defer cleanup()
+
+	config := DefaultConfig
+	config.MaxEvents = 3
+	w, err := Open(path, config)
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, w.Add(testEvent(i)))
+	}
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, 3, w.Events())
+	assert.Equal(t, []int64{0, 1, 2}, readIDs(t, path))
+}
+
+func TestCaptureMaxBytes(t *testing.T) {
+	path, cleanup := tempCapturePath(t)
+	defer cleanup()
+
+	line, err := deadletter.EncodeEvent(testEvent(0))
+	require.NoError(t, err)
+
+	config := DefaultConfig
+	config.MaxBytes = int64(2*len(line) + 1)
+	w, err := Open(path, config)
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, w.Add(testEvent(i)))
+	}
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, []int64{0, 1}, readIDs(t, path))
+}
+
+func TestCaptureSampleRate(t *testing.T) {
+	path, cleanup := tempCapturePath(t)
+	defer cleanup()
+
+	config := DefaultConfig
+	config.SampleRate = 0.5
+	w, err := Open(path, config)
+	require.NoError(t, err)
+
+	// Alternate between selecting and skipping events.
+	samples := []float64{0.1, 0.9}
+	n := 0
+	w.random = func() float64 {
+		n++
+		return samples[n%2]
+	}
+
+	for i := 0; i < 6; i++ {
+		require.NoError(t, w.Add(testEvent(i)))
+	}
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, []int64{1, 3, 5}, readIDs(t, path))
+}
+
+func TestCaptureTruncatesFile(t *testing.T) {
+	path, cleanup := tempCapturePath(t)
+	defer cleanup()
+
+	for run := 0; run < 2; run++ {
+		w, err := Open(path, DefaultConfig)
+		require.NoError(t, err)
+		require.NoError(t, w.Add(testEvent(run)))
+		require.NoError(t, w.Close())
+	}
+
+	assert.Equal(t, []int64{1}, readIDs(t, path))
+}
+
+func TestConfigSampleRate(t *testing.T) {
+	for _, rate := range []float64{-1, 0, 1.5} {
+		cfg, err := common.NewConfigFrom(map[string]interface{}{"sample_rate": rate})
+		require.NoError(t, err)
+		config := DefaultConfig
+		assert.Error(t, cfg.Unpack(&config), "sample rate %v", rate)
+	}
+
+	cfg, err := common.NewConfigFrom(map[string]interface{}{"sample_rate": 0.1})
+	require.NoError(t, err)
+	config := DefaultConfig
+	require.NoError(t, cfg.Unpack(&config))
+	assert.Equal(t, 0.1, config.SampleRate)
+}
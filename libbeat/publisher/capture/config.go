@@ -0,0 +1,39 @@
+package capture
+
+import "errors"
+
+// Config configures the capture of the events published to the pipeline.
+type Config struct {
+	// Enabled enables writing a sample of the published events to the capture
+	// file.
+	Enabled bool `config:"enabled"`
+
+	// Path is the capture file. Relative paths are resolved against the data
+	// path.
+	Path string `config:"path"`
+
+	// SampleRate is the fraction of events captured, between 0 and 1.
+	SampleRate float64 `config:"sample_rate"`
+
+	// MaxEvents and MaxBytes limit the number of events captured and the size
+	// of the capture file. Capturing stops once either limit is reached.
+	MaxEvents int   `config:"max_events" validate:"min=1"`
+	MaxBytes  int64 `config:"max_bytes" validate:"min=1"`
+}
+
+// DefaultConfig is the default event capture configuration.
+var DefaultConfig = Config{
+	Enabled:    false,
+	Path:       "capture/events.ndjson",
+	SampleRate: 1,
+	MaxEvents:  1000,
+	MaxBytes:   10 * 1024 * 1024,
+}
+
+// Validate checks the sample rate is a valid fraction.
+func (c *Config) Validate() error {
+	if c.SampleRate <= 0 || c.SampleRate > 1 {
+		return errors.New("capture.sample_rate must be greater than 0 and at most 1")
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package deadletter
+
+// Config configures the dead-letter spool of the publisher pipeline.
+type Config struct {
+	// Enabled enables spooling of events the output failed to publish
+	// permanently.
+	Enabled bool `config:"enabled"`
+
+	// Path is the directory the spool files are written to. Relative paths are
+	// resolved against the data path.
+	Path string `config:"path"`
+
+	// MaxBytes is the maximum size of a spool file. Events are dropped once the
+	// spool file has reached this size.
+	MaxBytes int64 `config:"max_bytes" validate:"min=1"`
+}
+
+// DefaultConfig is the default dead-letter spool configuration.
+var DefaultConfig = Config{
+	Enabled:  false,
+	Path:     "dead_letter",
+	MaxBytes: 100 * 1024 * 1024,
+}
@@ -0,0 +1,132 @@
+package deadletter
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func tempSpoolPath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "deadletter")
+	require.NoError(t, err)
+	return filepath.Join(dir, "spool", FileName("test")), func() { os.RemoveAll(dir) }
+}
+
+func readAll(t *testing.T, path string) ([]beat.Event, int) {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var events []beat.Event
+	reader := NewReader(file)
+	for {
+		event, err := reader.Next()
+		if err == io.EOF {
+			return events, reader.Skipped()
+		}
+		require.NoError(t, err)
+		events = append(events, event)
+	}
+}
+
+func TestSpoolRoundtrip(t *testing.T) {
+	path, cleanup := tempSpoolPath(t)
+	defer cleanup()
+
+	ts := time.Date(2017, 10, 14, 8, 0, 0, 123456789, time.UTC)
+	events := []beat.Event{
+		{
+			Timestamp: ts,
+			Meta:      common.MapStr{"pipeline": "test"},
+			Fields: common.MapStr{
+				"message": "hello",
+				"id":      uint64(18446744073709551615),
+				"nested":  common.MapStr{"count": 5, "ratio": 0.5},
+			},
+		},
+		{
+			Timestamp: ts.Add(time.Second),
+			Fields:    common.MapStr{"message": "world"},
+		},
+	}
+
+	spool, err := Open(path, DefaultConfig.MaxBytes)
+	require.NoError(t, err)
+	for _, event := range events {
+		require.NoError(t, spool.Add(event))
+	}
+	require.NoError(t, spool.Close())
+
+	// Reopening the spool appends to the existing file.
+	spool, err = Open(path, DefaultConfig.MaxBytes)
+	require.NoError(t, err)
+	require.NoError(t, spool.Add(events[1]))
+	require.NoError(t, spool.Close())
+
+	actual, skipped := readAll(t, path)
+	assert.Equal(t, 0, skipped)
+	require.Len(t, actual, 3)
+
+	assert.True(t, ts.Equal(actual[0].Timestamp))
+	assert.Equal(t, common.MapStr{"pipeline": "test"}, actual[0].Meta)
+	assert.Equal(t, common.MapStr{
+		"message": "hello",
+		"id":      uint64(18446744073709551615),
+		"nested":  map[string]interface{}{"count": int64(5), "ratio": 0.5},
+	}, actual[0].Fields)
+
+	assert.Nil(t, actual[1].Meta)
+	assert.Equal(t, common.MapStr{"message": "world"}, actual[1].Fields)
+	assert.Equal(t, actual[1], actual[2])
+}
+
+func TestSpoolMaxBytes(t *testing.T) {
+	path, cleanup := tempSpoolPath(t)
+	defer cleanup()
+
+	spool, err := Open(path, 100)
+	require.NoError(t, err)
+	defer spool.Close()
+
+	event := beat.Event{Timestamp: time.Now(), Fields: common.MapStr{"message": "hello"}}
+	require.NoError(t, spool.Add(event))
+	assert.Equal(t, ErrFull, spool.Add(event))
+
+	actual, _ := readAll(t, path)
+	assert.Len(t, actual, 1)
+}
+
+func TestReaderSkipsInvalidEntries(t *testing.T) {
+	input := strings.Join([]string{
+		`{"@timestamp":"2017-10-14T08:00:00Z","fields":{"id":1}}`,
+		`not json`,
+		``,
+		`{"@timestamp":"2017-10-14T08:00:01Z","fields":{"id":2}}`,
+		`{"@timestamp":"2017-10-14T08:00:02Z","fie`,
+	}, "\n")
+
+	reader := NewReader(strings.NewReader(input))
+
+	var ids []interface{}
+	for {
+		event, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		ids = append(ids, event.Fields["id"])
+	}
+
+	assert.Equal(t, []interface{}{int64(1), int64(2)}, ids)
+	assert.Equal(t, 2, reader.Skipped())
+}
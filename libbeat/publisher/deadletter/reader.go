@@ -0,0 +1,85 @@
+package deadletter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/jsontransform"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// Reader reads the events of a dead-letter spool file, or of any other file
+// written with EncodeEvent.
+type Reader struct {
+	reader  *bufio.Reader
+	line    int
+	skipped int
+}
+
+// NewReader creates a Reader reading spooled events from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{reader: bufio.NewReader(r)}
+}
+
+// Next returns the next event. Lines that can not be decoded, e.g. a partial
+// line written on a crash, are logged and skipped. Next returns io.EOF when
+// all events have been read.
+func (r *Reader) Next() (beat.Event, error) {
+	for {
+		line, err := r.reader.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			return beat.Event{}, err
+		}
+		if err != nil && err != io.EOF {
+			return beat.Event{}, err
+		}
+		r.line++
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		event, decodeErr := decodeEntry(line)
+		if decodeErr != nil {
+			logp.Warn("Skipping invalid event entry on line %v: %v", r.line, decodeErr)
+			r.skipped++
+			continue
+		}
+		return event, nil
+	}
+}
+
+// Skipped returns the number of lines skipped because they could not be
+// decoded.
+func (r *Reader) Skipped() int {
+	return r.skipped
+}
+
+func decodeEntry(line []byte) (beat.Event, error) {
+	var e entry
+
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if err := dec.Decode(&e); err != nil {
+		return beat.Event{}, err
+	}
+
+	if e.Fields == nil {
+		e.Fields = common.MapStr{}
+	}
+	jsontransform.TransformNumbers(e.Fields)
+	if e.Meta != nil {
+		jsontransform.TransformNumbers(e.Meta)
+	}
+
+	return beat.Event{
+		Timestamp: e.Timestamp,
+		Meta:      e.Meta,
+		Fields:    e.Fields,
+	}, nil
+}
@@ -0,0 +1,70 @@
+package deadletter
+
+import (
+	"io"
+	"sync"
+
+	"github.com/elastic/beats/libbeat/beat"
+)
+
+// ReplayStats reports the publishing status of replayed events.
+type ReplayStats struct {
+	Published int // events read and published to the pipeline
+	ACKed     int // events ACKed by the output
+	Failed    int // events the output failed to publish again
+	Dropped   int // events dropped by the pipeline
+	Skipped   int // invalid entries skipped
+}
+
+// Replay publishes all events read from r to the pipeline. It blocks until
+// the final publishing status of every published event has been reported.
+// Events failing again are spooled by the pipeline, if the dead-letter spool
+// is enabled.
+func Replay(pipeline beat.Pipeline, r *Reader) (ReplayStats, error) {
+	var (
+		stats ReplayStats
+		mutex sync.Mutex
+		wg    sync.WaitGroup
+	)
+
+	client, err := pipeline.Connect()
+	if err != nil {
+		return stats, err
+	}
+	defer client.Close()
+
+	onComplete := func(status beat.EventStatus) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		switch status {
+		case beat.EventACKed:
+			stats.ACKed++
+		case beat.EventFailed:
+			stats.Failed++
+		default:
+			stats.Dropped++
+		}
+		wg.Done()
+	}
+
+	for {
+		event, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			wg.Wait()
+			return stats, err
+		}
+
+		event.OnComplete = onComplete
+		wg.Add(1)
+		stats.Published++
+		client.Publish(event)
+	}
+
+	wg.Wait()
+	stats.Skipped = r.Skipped()
+	return stats, nil
+}
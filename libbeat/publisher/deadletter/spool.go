@@ -0,0 +1,144 @@
+// Package deadletter provides a spool file for events the outputs failed to
+// publish permanently, e.g. because they have been rejected or the retry limit
+// has been reached. Spooled events can be replayed into the publisher pipeline
+// later on.
+//
+// A spool file contains one JSON encoded event per line:
+//
+//	{"@timestamp":"2017-10-14T08:00:00Z","@metadata":{...},"fields":{...}}
+package deadletter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// ReplaySuffix is appended to the name of a spool file while its events are
+// being replayed.
+const ReplaySuffix = ".replaying"
+
+// ErrFull is returned by Add if the spool file has reached its maximum size.
+var ErrFull = errors.New("dead-letter spool is full")
+
+// entry is the serialized form of a spooled event.
+type entry struct {
+	Timestamp time.Time     `json:"@timestamp"`
+	Meta      common.MapStr `json:"@metadata,omitempty"`
+	Fields    common.MapStr `json:"fields"`
+}
+
+// Spool appends events to a dead-letter spool file. It is safe for
+// concurrent use.
+type Spool struct {
+	path     string
+	maxBytes int64
+
+	mutex  sync.Mutex
+	file   *os.File
+	size   int64
+	warned bool
+}
+
+// FileName returns the name of the spool file for the given output.
+func FileName(output string) string {
+	return output + ".ndjson"
+}
+
+// Open opens the spool file at path for appending, creating the file and its
+// directory if they do not exist yet.
+func Open(path string, maxBytes int64) (*Spool, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create dead-letter directory: %v", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead-letter spool: %v", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open dead-letter spool: %v", err)
+	}
+
+	return &Spool{
+		path:     path,
+		maxBytes: maxBytes,
+		file:     file,
+		size:     info.Size(),
+	}, nil
+}
+
+// Path returns the path of the spool file.
+func (s *Spool) Path() string {
+	return s.path
+}
+
+// Add appends an event to the spool file. Events are dropped with ErrFull if
+// the spool file has reached its maximum size. A warning is logged the first
+// time an event is dropped.
+func (s *Spool) Add(event beat.Event) error {
+	line, err := EncodeEvent(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event for the dead-letter spool: %v", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.file == nil {
+		return fmt.Errorf("dead-letter spool %v is closed", s.path)
+	}
+
+	if s.size+int64(len(line)) > s.maxBytes {
+		if !s.warned {
+			logp.Warn("Dead-letter spool %v is full, further failed events are dropped", s.path)
+			s.warned = true
+		}
+		return ErrFull
+	}
+
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write to dead-letter spool: %v", err)
+	}
+	return nil
+}
+
+// EncodeEvent encodes an event as a line of a spool file, including the
+// trailing newline. Files written with EncodeEvent can be read with Reader.
+func EncodeEvent(event beat.Event) ([]byte, error) {
+	line, err := json.Marshal(entry{
+		Timestamp: event.Timestamp,
+		Meta:      event.Meta,
+		Fields:    event.Fields,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// Close closes the spool file.
+func (s *Spool) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
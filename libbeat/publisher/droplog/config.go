@@ -0,0 +1,33 @@
+package droplog
+
+import "errors"
+
+// Config configures the log of the last dropped events, read from the
+// `/events/dropped` endpoint of the HTTP metrics endpoint.
+type Config struct {
+	// Enabled enables logging dropped events and the endpoint.
+	Enabled bool `config:"enabled"`
+
+	// Token is the bearer token clients must send to read the dropped events.
+	Token string `config:"token"`
+
+	// Size is the number of dropped events kept. Older events are discarded.
+	Size int `config:"size" validate:"min=1"`
+
+	// RedactFields are the fields masked in the logged events.
+	RedactFields []string `config:"redact_fields"`
+}
+
+// DefaultConfig is the default configuration of the log of dropped events.
+var DefaultConfig = Config{
+	Enabled: false,
+	Size:    100,
+}
+
+// Validate checks a token is set if the log is enabled.
+func (c *Config) Validate() error {
+	if c.Enabled && c.Token == "" {
+		return errors.New("dropped_events.token is required if the log of dropped events is enabled")
+	}
+	return nil
+}
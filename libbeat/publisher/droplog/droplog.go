@@ -0,0 +1,126 @@
+// Package droplog keeps the last events dropped by the publishing pipeline in
+// memory, together with the reason they were dropped, so operators can inspect
+// recent examples through the `/events/dropped` endpoint of the HTTP metrics
+// endpoint.
+package droplog
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/publisher/inspect"
+)
+
+// Reason tells why an event was dropped.
+type Reason string
+
+const (
+	// Filtered events were dropped by a processor, like drop_event.
+	Filtered Reason = "filtered"
+
+	// QueueFull events were dropped, because the queue was full and the
+	// client publishes with DropIfFull.
+	QueueFull Reason = "queue_full"
+
+	// ClientClosed events were published on a closed or closing client.
+	ClientClosed Reason = "client_closed"
+
+	// OutputFailed events were rejected permanently by the output, and no
+	// dead-letter spool is configured.
+	OutputFailed Reason = "output_failed"
+
+	// DeadLetterFailed events were rejected permanently by the output, but
+	// could not be added to the dead-letter spool, because it is full or
+	// failed.
+	DeadLetterFailed Reason = "dead_letter_failed"
+)
+
+// Entry is a dropped event.
+type Entry struct {
+	// Timestamp is the time the event was dropped.
+	Timestamp time.Time `json:"timestamp"`
+	Reason    Reason    `json:"reason"`
+
+	// Event is the event encoded as JSON, with the redacted fields masked.
+	Event json.RawMessage `json:"event"`
+}
+
+// Log keeps the last dropped events in a ring buffer of fixed size. It is safe
+// for concurrent use.
+type Log struct {
+	config Config
+
+	mutex   sync.Mutex
+	entries []Entry
+	next    int    // index of the entry overwritten by the next event
+	total   uint64 // number of events added, including the discarded ones
+	encoder *inspect.Encoder
+	now     func() time.Time
+}
+
+// New creates a Log for the events of the beat.
+func New(info beat.Info, config Config) *Log {
+	return &Log{
+		config:  config,
+		entries: make([]Entry, 0, config.Size),
+		encoder: inspect.NewEncoder(info, config.RedactFields),
+		now:     time.Now,
+	}
+}
+
+// Authorized checks the token matches the configured token.
+func (l *Log) Authorized(token string) bool {
+	return inspect.Authorized(token, l.config.Token)
+}
+
+// Add logs the dropped event. The event is encoded right away, so it can be
+// modified once Add returns. If the log is full, the oldest event is
+// discarded.
+func (l *Log) Add(event beat.Event, reason Reason) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	content, err := l.encoder.Encode(event)
+	if err != nil {
+		logp.Debug("droplog", "failed to encode dropped event: %v", err)
+		return
+	}
+
+	entry := Entry{Timestamp: l.now(), Reason: reason, Event: content}
+	if len(l.entries) < cap(l.entries) {
+		l.entries = append(l.entries, entry)
+	} else {
+		l.entries[l.next] = entry
+	}
+	l.next = (l.next + 1) % cap(l.entries)
+	l.total++
+}
+
+// Entries returns the logged events, oldest first.
+func (l *Log) Entries() []Entry {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	entries := make([]Entry, 0, len(l.entries))
+	if len(l.entries) == cap(l.entries) {
+		entries = append(entries, l.entries[l.next:]...)
+		return append(entries, l.entries[:l.next]...)
+	}
+	return append(entries, l.entries...)
+}
+
+// Total returns the number of events added to the log, including the events
+// discarded since.
+func (l *Log) Total() uint64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.total
+}
+
+// Size returns the maximum number of events kept.
+func (l *Log) Size() int {
+	return cap(l.entries)
+}
@@ -0,0 +1,119 @@
+package droplog
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/publisher/inspect/inspecttest"
+)
+
+func newTestLog(size int, redact ...string) *Log {
+	config := DefaultConfig
+	config.Enabled = true
+	config.Token = "secret"
+	config.Size = size
+	config.RedactFields = redact
+
+	l := New(beat.Info{Beat: "testbeat", Version: "6.3.0"}, config)
+	now := time.Date(2017, 10, 14, 8, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+	return l
+}
+
+func decode(t *testing.T, entries []Entry) []map[string]interface{} {
+	var events []map[string]interface{}
+	for _, entry := range entries {
+		var event map[string]interface{}
+		require.NoError(t, json.Unmarshal(entry.Event, &event))
+		events = append(events, event)
+	}
+	return events
+}
+
+func ids(events []map[string]interface{}) []float64 {
+	var ids []float64
+	for _, event := range events {
+		ids = append(ids, event["id"].(float64))
+	}
+	return ids
+}
+
+func TestLogCapacity(t *testing.T) {
+	l := newTestLog(3)
+	assert.Empty(t, l.Entries())
+
+	l.Add(inspecttest.Event(0), Filtered)
+	l.Add(inspecttest.Event(1), Filtered)
+	assert.Equal(t, []float64{0, 1}, ids(decode(t, l.Entries())))
+
+	for i := 2; i < 8; i++ {
+		l.Add(inspecttest.Event(i), Filtered)
+	}
+
+	// Only the last events are kept, oldest first.
+	assert.Equal(t, []float64{5, 6, 7}, ids(decode(t, l.Entries())))
+	assert.Equal(t, uint64(8), l.Total())
+	assert.Equal(t, 3, l.Size())
+}
+
+func TestLogReasons(t *testing.T) {
+	l := newTestLog(10)
+	reasons := []Reason{Filtered, QueueFull, ClientClosed, OutputFailed, DeadLetterFailed}
+	for i, reason := range reasons {
+		l.Add(inspecttest.Event(i), reason)
+	}
+
+	entries := l.Entries()
+	require.Len(t, entries, len(reasons))
+	for i, entry := range entries {
+		assert.Equal(t, reasons[i], entry.Reason)
+		assert.Equal(t, time.Date(2017, 10, 14, 8, 0, 0, 0, time.UTC), entry.Timestamp)
+	}
+	assert.Equal(t, []float64{0, 1, 2, 3, 4}, ids(decode(t, entries)))
+}
+
+func TestLogRedact(t *testing.T) {
+	l := newTestLog(10, "user.password", "missing")
+	event := inspecttest.Event(1)
+	l.Add(event, Filtered)
+
+	logged := decode(t, l.Entries())[0]
+	assert.Equal(t, map[string]interface{}{"name": "alice", "password": "xxxxx"}, logged["user"])
+	assert.NotContains(t, logged, "missing")
+
+	// the event itself is not modified
+	assert.Equal(t, "hunter2", event.Fields["user"].(common.MapStr)["password"])
+}
+
+func TestLogEncodesOnAdd(t *testing.T) {
+	l := newTestLog(10)
+	event := inspecttest.Event(1)
+	l.Add(event, Filtered)
+	event.Fields["id"] = 2
+
+	assert.Equal(t, []float64{1}, ids(decode(t, l.Entries())))
+}
+
+func TestLogAuthorized(t *testing.T) {
+	l := newTestLog(10)
+	assert.True(t, l.Authorized("secret"))
+	assert.False(t, l.Authorized("wrong"))
+	assert.False(t, l.Authorized(""))
+}
+
+func TestConfigValidate(t *testing.T) {
+	config := DefaultConfig
+	assert.NoError(t, config.Validate())
+
+	config.Enabled = true
+	assert.Error(t, config.Validate())
+
+	config.Token = "secret"
+	assert.NoError(t, config.Validate())
+}
@@ -2,6 +2,7 @@ package publisher
 
 import (
 	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common/atomic"
 )
 
 // Batch is used to pass a batch of events to the outputs and asynchronously listening
@@ -24,6 +25,19 @@ type Batch interface {
 type Event struct {
 	Content beat.Event
 	Flags   EventFlags
+
+	// Delivery tracks the publishing status of events with an OnComplete
+	// callback. It is nil for all other events.
+	Delivery *Delivery
+}
+
+// Delivery reports the final publishing status of an event to the events
+// OnComplete callback. Copies of an event share the same Delivery, so outputs
+// can mark an event as failed, before the batch is ACKed.
+type Delivery struct {
+	callback func(beat.EventStatus)
+	failed   atomic.Bool
+	done     atomic.Bool
 }
 
 // EventFlags provides additional flags/option types  for used with the outputs.
@@ -40,3 +54,47 @@ const (
 func (e *Event) Guaranteed() bool {
 	return (e.Flags & GuaranteedSend) == GuaranteedSend
 }
+
+// NewDelivery creates a new Delivery for the given callback. If the callback
+// is nil, no Delivery is returned.
+func NewDelivery(callback func(beat.EventStatus)) *Delivery {
+	if callback == nil {
+		return nil
+	}
+	return &Delivery{callback: callback}
+}
+
+// Fail marks the event as failed, without reporting it yet. The failure is
+// reported once the event's batch is completed.
+func (e *Event) Fail() {
+	if e.Delivery != nil {
+		e.Delivery.failed.Store(true)
+	}
+}
+
+// Complete reports the event to be ACKed or failed, if it has been marked as
+// failed before.
+func (d *Delivery) Complete() {
+	if d == nil {
+		return
+	}
+
+	status := beat.EventACKed
+	if d.failed.Load() {
+		status = beat.EventFailed
+	}
+	d.report(status)
+}
+
+// Drop reports the event to have been dropped.
+func (d *Delivery) Drop() {
+	if d != nil {
+		d.report(beat.EventDropped)
+	}
+}
+
+func (d *Delivery) report(status beat.EventStatus) {
+	if d.done.CAS(false, true) {
+		d.callback(status)
+	}
+}
@@ -0,0 +1,57 @@
+// Package inspect encodes published events for inspection, like the events
+// streamed by the tap or kept by the log of dropped events, with sensitive
+// fields redacted.
+package inspect
+
+import (
+	"crypto/subtle"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/outputs/codec/json"
+)
+
+// redactedValue replaces the values of the redacted fields.
+const redactedValue = "xxxxx"
+
+// Encoder encodes events as JSON, with the redacted fields masked. It is not
+// safe for concurrent use.
+type Encoder struct {
+	beat    string
+	redact  []string
+	encoder *json.Encoder
+}
+
+// NewEncoder creates an Encoder for the events of the beat, masking the values
+// of the redact fields.
+func NewEncoder(info beat.Info, redact []string) *Encoder {
+	return &Encoder{
+		beat:    info.Beat,
+		redact:  redact,
+		encoder: json.New(false, info.Version),
+	}
+}
+
+// Encode returns the JSON encoding of the event. The event is not modified,
+// and the encoding stays valid once the next event is encoded.
+func (e *Encoder) Encode(event beat.Event) ([]byte, error) {
+	if len(e.redact) > 0 {
+		event.Fields = event.Fields.Clone()
+		for _, field := range e.redact {
+			if _, err := event.Fields.GetValue(field); err == nil {
+				event.Fields.Put(field, redactedValue)
+			}
+		}
+	}
+
+	b, err := e.encoder.Encode(e.beat, &event)
+	if err != nil {
+		return nil, err
+	}
+	// The buffer of the encoder is reused by the next event.
+	return append([]byte(nil), b...), nil
+}
+
+// Authorized checks the token matches the configured token, in constant time.
+func Authorized(token, configured string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(configured)) == 1
+}
@@ -0,0 +1,67 @@
+package inspect
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/publisher/inspect/inspecttest"
+)
+
+func encode(t *testing.T, e *Encoder, event beat.Event) map[string]interface{} {
+	b, err := e.Encode(event)
+	require.NoError(t, err)
+
+	var encoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &encoded))
+	return encoded
+}
+
+func TestEncodeRedact(t *testing.T) {
+	tests := []struct {
+		redact []string
+		user   map[string]interface{}
+	}{
+		{nil, map[string]interface{}{"name": "alice", "password": "hunter2"}},
+		{[]string{"user.password", "missing"}, map[string]interface{}{"name": "alice", "password": "xxxxx"}},
+		{[]string{"user"}, nil},
+	}
+
+	for _, test := range tests {
+		e := NewEncoder(beat.Info{Beat: "testbeat", Version: "6.3.0"}, test.redact)
+		event := inspecttest.Event(1)
+		encoded := encode(t, e, event)
+
+		if test.user != nil {
+			assert.Equal(t, test.user, encoded["user"], "%v", test.redact)
+		} else {
+			assert.Equal(t, "xxxxx", encoded["user"], "%v", test.redact)
+		}
+		assert.NotContains(t, encoded, "missing")
+		assert.Equal(t, map[string]interface{}{"beat": "testbeat", "type": "doc", "version": "6.3.0"}, encoded["@metadata"])
+
+		// the event itself is not modified
+		assert.Equal(t, "hunter2", event.Fields["user"].(common.MapStr)["password"])
+	}
+}
+
+func TestEncodeKeepsEncoding(t *testing.T) {
+	e := NewEncoder(beat.Info{Beat: "testbeat"}, nil)
+	first, err := e.Encode(inspecttest.Event(1))
+	require.NoError(t, err)
+	encoded := string(first)
+
+	_, err = e.Encode(inspecttest.Event(2))
+	require.NoError(t, err)
+	assert.Equal(t, encoded, string(first))
+}
+
+func TestAuthorized(t *testing.T) {
+	assert.True(t, Authorized("secret", "secret"))
+	assert.False(t, Authorized("wrong", "secret"))
+	assert.False(t, Authorized("", "secret"))
+}
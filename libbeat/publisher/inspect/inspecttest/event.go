@@ -0,0 +1,25 @@
+// Package inspecttest provides the events used to test the inspection of
+// published events.
+package inspecttest
+
+import (
+	"time"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// Timestamp is the timestamp of the test events.
+var Timestamp = time.Date(2017, 10, 14, 8, 0, 0, 0, time.UTC)
+
+// Event returns a test event with the given id, holding a secret in
+// `user.password`.
+func Event(id int) beat.Event {
+	return beat.Event{
+		Timestamp: Timestamp,
+		Fields: common.MapStr{
+			"id":   id,
+			"user": common.MapStr{"name": "alice", "password": "hunter2"},
+		},
+	}
+}
@@ -2,6 +2,7 @@ package pipeline
 
 import (
 	"sync"
+	"time"
 
 	"github.com/elastic/beats/libbeat/publisher"
 	"github.com/elastic/beats/libbeat/publisher/queue"
@@ -12,11 +13,19 @@ type Batch struct {
 	ctx      *batchContext
 	ttl      int
 	events   []publisher.Event
+
+	// worker is set while the batch is published by an output worker, with
+	// the number of events sent and the time the batch was passed to the
+	// output client.
+	worker     *workerMetrics
+	workerSent int
+	workerTime time.Time
 }
 
 type batchContext struct {
 	observer outputObserver
 	retryer  *retryer
+	tracker  *batchTracker // tracker is set if the queue is flushed on shutdown
 }
 
 var batchPool = sync.Pool{
@@ -37,6 +46,7 @@ func newBatch(ctx *batchContext, original queue.Batch, ttl int) *Batch {
 		ttl:      ttl,
 		events:   original.Events(),
 	}
+	ctx.tracker.add(b)
 	return b
 }
 
@@ -50,22 +60,69 @@ func (b *Batch) Events() []publisher.Event {
 }
 
 func (b *Batch) ACK() {
+	b.releaseWorker()
+	if !b.ctx.tracker.done(b) {
+		return
+	}
 	b.ctx.observer.outBatchACKed(len(b.events))
+	b.complete()
 	b.original.ACK()
 	releaseBatch(b)
 }
 
 func (b *Batch) Drop() {
+	b.releaseWorker()
+	if !b.ctx.tracker.done(b) {
+		return
+	}
+	b.complete()
 	b.original.ACK()
 	releaseBatch(b)
 }
 
+// sentBy records the batch being passed to the output client of a worker.
+func (b *Batch) sentBy(metrics *workerMetrics) {
+	if b == nil || metrics == nil {
+		return
+	}
+	b.worker = metrics
+	b.workerSent = len(b.events)
+	b.workerTime = time.Now()
+	metrics.sent(b.workerSent)
+}
+
+// releaseWorker reports the batch as completed to the metrics of the worker
+// that has sent it. Retried batches are reported again by the worker sending
+// them next.
+func (b *Batch) releaseWorker() {
+	if b.worker == nil {
+		return
+	}
+	b.worker.done(b.workerSent, time.Since(b.workerTime))
+	b.worker = nil
+}
+
+// complete reports the final status of all events in the original batch.
+// Events removed from the batch without being marked as failed have been
+// ACKed by the output.
+func (b *Batch) complete() {
+	for _, event := range b.original.Events() {
+		event.Delivery.Complete()
+	}
+}
+
 func (b *Batch) Retry() {
-	b.ctx.retryer.retry(b)
+	b.releaseWorker()
+	if b.ctx.tracker.owned() {
+		b.ctx.retryer.retry(b)
+	}
 }
 
 func (b *Batch) Cancelled() {
-	b.ctx.retryer.cancelled(b)
+	b.releaseWorker()
+	if b.ctx.tracker.owned() {
+		b.ctx.retryer.cancelled(b)
+	}
 }
 
 func (b *Batch) RetryEvents(events []publisher.Event) {
@@ -0,0 +1,157 @@
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/publisher"
+	"github.com/elastic/beats/libbeat/publisher/capture"
+	"github.com/elastic/beats/libbeat/publisher/deadletter"
+	"github.com/elastic/beats/libbeat/publisher/queue"
+	"github.com/elastic/beats/libbeat/publisher/queue/memqueue"
+	"github.com/elastic/beats/libbeat/version"
+)
+
+type eventCollector struct {
+	mutex  sync.Mutex
+	events []beat.Event
+}
+
+func (c *eventCollector) publish(batch publisher.Batch) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, event := range batch.Events() {
+		c.events = append(c.events, event.Content)
+	}
+	batch.ACK()
+}
+
+func (c *eventCollector) sorted() []beat.Event {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	events := append([]beat.Event(nil), c.events...)
+	sort.Slice(events, func(i, j int) bool {
+		a, _ := events[i].Fields.GetValue("id")
+		b, _ := events[j].Fields.GetValue("id")
+		return toInt(a) < toInt(b)
+	})
+	return events
+}
+
+func toInt(v interface{}) int {
+	switch i := v.(type) {
+	case int:
+		return i
+	case int64:
+		return int(i)
+	}
+	return -1
+}
+
+func newCaptureTestPipeline(t *testing.T, settings Settings, publish func(publisher.Batch)) *Pipeline {
+	queueFactory := func(e queue.Eventer) (queue.Queue, error) {
+		return memqueue.NewBroker(memqueue.Settings{Eventer: e, Events: 64}), nil
+	}
+	p, err := New(beat.Info{}, nil, queueFactory, testOutputGroup(3, publish), settings)
+	require.NoError(t, err)
+	return p
+}
+
+func TestCaptureAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "capture")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events.ndjson")
+
+	w, err := capture.Open(path, capture.DefaultConfig)
+	require.NoError(t, err)
+
+	// First run: events are captured before the pipeline fields are added.
+	var first eventCollector
+	p := newCaptureTestPipeline(t, Settings{
+		Capture: w,
+		Annotations: Annotations{
+			Event: common.EventMetadata{Fields: common.MapStr{"env": "first"}},
+		},
+	}, first.publish)
+
+	r := newStatusRecorder(3)
+	publishTestEvents(t, p, beat.ClientConfig{}, r, 3)
+	r.wait(t)
+	p.Close()
+
+	for _, event := range first.sorted() {
+		env, _ := event.Fields.GetValue("fields.env")
+		assert.Equal(t, "first", env)
+	}
+
+	captured, err := os.Open(path)
+	require.NoError(t, err)
+	defer captured.Close()
+	reader := deadletter.NewReader(captured)
+	for i := 0; i < 3; i++ {
+		event, err := reader.Next()
+		require.NoError(t, err)
+		assert.Equal(t, common.MapStr{"id": int64(i)}, event.Fields)
+	}
+
+	// Later run: the captured events are processed with the fields of the new
+	// configuration.
+	var replayed eventCollector
+	p = newCaptureTestPipeline(t, Settings{
+		Annotations: Annotations{
+			Event: common.EventMetadata{Fields: common.MapStr{"env": "second"}},
+		},
+	}, replayed.publish)
+	defer p.Close()
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	stats, err := deadletter.Replay(p, deadletter.NewReader(file))
+	require.NoError(t, err)
+	assert.Equal(t, deadletter.ReplayStats{Published: 3, ACKed: 3}, stats)
+
+	events := replayed.sorted()
+	require.Len(t, events, 3)
+	for i, event := range events {
+		assert.Equal(t, common.MapStr{
+			"id":     int64(i),
+			"ecs":    common.MapStr{"version": version.ECSVersion},
+			"fields": common.MapStr{"env": "second"},
+		}, event.Fields)
+	}
+}
+
+func TestCaptureDroppedEvents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "capture")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events.ndjson")
+
+	w, err := capture.Open(path, capture.DefaultConfig)
+	require.NoError(t, err)
+
+	// Events dropped by processors are captured, so the processors can be
+	// debugged on replay.
+	p := newCaptureTestPipeline(t, Settings{Capture: w, Disabled: true}, func(batch publisher.Batch) {
+		batch.ACK()
+	})
+
+	r := newStatusRecorder(2)
+	publishTestEvents(t, p, beat.ClientConfig{}, r, 2)
+	r.wait(t)
+	p.Close()
+
+	assert.Equal(t, 2, w.Events())
+}
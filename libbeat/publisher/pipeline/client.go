@@ -1,11 +1,14 @@
 package pipeline
 
 import (
+	"io"
 	"sync"
 
 	"github.com/elastic/beats/libbeat/beat"
 	"github.com/elastic/beats/libbeat/common/atomic"
 	"github.com/elastic/beats/libbeat/publisher"
+	"github.com/elastic/beats/libbeat/publisher/deadletter"
+	"github.com/elastic/beats/libbeat/publisher/droplog"
 	"github.com/elastic/beats/libbeat/publisher/queue"
 )
 
@@ -21,6 +24,10 @@ type client struct {
 	mutex      sync.Mutex
 	acker      acker
 
+	// clientProcessors are the processors configured by the beat for this
+	// client. They are closed with the client.
+	clientProcessors beat.ProcessorList
+
 	eventFlags   publisher.EventFlags
 	canDrop      bool
 	reportEvents bool
@@ -57,7 +64,7 @@ func (c *client) publish(e beat.Event) {
 
 	if !c.isOpen.Load() {
 		// client is closing down -> report event as dropped and return
-		c.onDroppedOnPublish(e)
+		c.onDroppedOnPublish(e, droplog.ClientClosed)
 		return
 	}
 
@@ -80,7 +87,7 @@ func (c *client) publish(e beat.Event) {
 	open := c.acker.addEvent(e, publish)
 	if !open {
 		// client is closing down -> report event as dropped and return
-		c.onDroppedOnPublish(e)
+		c.onDroppedOnPublish(e, droplog.ClientClosed)
 		return
 	}
 
@@ -91,8 +98,9 @@ func (c *client) publish(e beat.Event) {
 
 	e = *event
 	pubEvent := publisher.Event{
-		Content: e,
-		Flags:   c.eventFlags,
+		Content:  e,
+		Flags:    c.eventFlags,
+		Delivery: c.newDelivery(e),
 	}
 
 	if c.reportEvents {
@@ -109,13 +117,46 @@ func (c *client) publish(e beat.Event) {
 	if published {
 		c.onPublished()
 	} else {
-		c.onDroppedOnPublish(e)
+		reason := droplog.ClientClosed
+		if c.canDrop {
+			reason = droplog.QueueFull
+		}
+		c.onDroppedOnPublish(e, reason)
 		if c.reportEvents {
 			c.pipeline.waitCloser.dec(1)
 		}
 	}
 }
 
+// newDelivery creates the Delivery tracking the publishing status of an event.
+// If the dead-letter spool is enabled, events failing permanently are added
+// to the spool, before the events OnComplete callback is run. Failed events
+// not spooled are logged as dropped, if the drop log is enabled.
+func (c *client) newDelivery(e beat.Event) *publisher.Delivery {
+	spool, dropLog := c.pipeline.deadLetter, c.pipeline.dropLog
+	if spool == nil && dropLog == nil {
+		return publisher.NewDelivery(e.OnComplete)
+	}
+
+	return publisher.NewDelivery(func(status beat.EventStatus) {
+		if status == beat.EventFailed {
+			if spool == nil {
+				dropLog.Add(e, droplog.OutputFailed)
+			} else if err := spool.Add(e); err != nil {
+				if err != deadletter.ErrFull {
+					c.pipeline.logger.Errf("Failed to spool dead-letter event: %v", err)
+				}
+				if dropLog != nil {
+					dropLog.Add(e, droplog.DeadLetterFailed)
+				}
+			}
+		}
+		if e.OnComplete != nil {
+			e.OnComplete(status)
+		}
+	})
+}
+
 func (c *client) Close() error {
 	// first stop ack handling. ACK handler might block (with timeout), waiting
 	// for pending events to be ACKed.
@@ -143,10 +184,27 @@ func (c *client) Close() error {
 		}
 	}
 
+	c.closeProcessors()
+
 	c.onClosed()
 	return nil
 }
 
+// closeProcessors releases the resources of the client processors, once no
+// event is being processed anymore.
+func (c *client) closeProcessors() {
+	closer, ok := c.clientProcessors.(io.Closer)
+	if !ok {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if err := closer.Close(); err != nil {
+		c.pipeline.logger.Errf("Failed to close client processors: %v", err)
+	}
+}
+
 func (c *client) onClosing() {
 	c.pipeline.observer.clientClosing()
 	if c.eventer != nil {
@@ -173,15 +231,30 @@ func (c *client) onPublished() {
 }
 
 func (c *client) onFilteredOut(e beat.Event) {
+	c.logDropped(e, droplog.Filtered)
 	c.pipeline.observer.filteredEvent()
 	if c.eventer != nil {
 		c.eventer.FilteredOut(e)
 	}
+	if e.OnComplete != nil {
+		e.OnComplete(beat.EventDropped)
+	}
 }
 
-func (c *client) onDroppedOnPublish(e beat.Event) {
+func (c *client) onDroppedOnPublish(e beat.Event, reason droplog.Reason) {
+	c.logDropped(e, reason)
 	c.pipeline.observer.failedPublishEvent()
 	if c.eventer != nil {
 		c.eventer.DroppedOnPublish(e)
 	}
+	if e.OnComplete != nil {
+		e.OnComplete(beat.EventDropped)
+	}
+}
+
+// logDropped adds the event to the drop log, if enabled.
+func (c *client) logDropped(e beat.Event, reason droplog.Reason) {
+	if l := c.pipeline.dropLog; l != nil {
+		l.Add(e, reason)
+	}
 }
@@ -15,8 +15,35 @@ type Config struct {
 	common.EventMetadata `config:",inline"`      // Fields and tags to add to each event.
 	Processors           processors.PluginConfig `config:"processors"`
 
+	// Processors loaded from files, reloadable at runtime
+	ProcessorsReload *common.Config `config:"config.processors"`
+
+	// Named processing pipelines selected by inputs
+	ProcessingPipelines []NamedPipelineConfig `config:"processing_pipelines"`
+
 	// Event queue
 	Queue common.ConfigNamespace `config:"queue"`
+
+	// Labels and tags stamped into each event
+	Global GlobalConfig `config:"global"`
+
+	// Limits on the depth and length of field names
+	FieldLimits FieldLimitsConfig `config:"field_limits"`
+
+	// Sequence numbers stamped into each event
+	Sequence SequenceConfig `config:"sequence"`
+
+	// Provenance entries appended to the metadata of each event
+	Provenance ProvenanceConfig `config:"provenance"`
+
+	// Spool for events the output failed to publish permanently
+	DeadLetter *common.Config `config:"dead_letter"`
+
+	// Capture of a sample of the raw events published
+	Capture *common.Config `config:"capture"`
+
+	// Flushing of the outputs on shutdown
+	Shutdown ShutdownConfig `config:"shutdown"`
 }
 
 // validateClientConfig checks a ClientConfig can be used with (*Pipeline).ConnectWith.
@@ -11,8 +11,9 @@ import (
 // from the retryer in case of too many events failing to be send or if retryer
 // is receiving cancelled batches from outputs to be closed on output reloading.
 type eventConsumer struct {
-	logger *logp.Logger
-	done   chan struct{}
+	logger  *logp.Logger
+	done    chan struct{}
+	stopped chan struct{}
 
 	ctx *batchContext
 
@@ -46,10 +47,11 @@ func newEventConsumer(
 	ctx *batchContext,
 ) *eventConsumer {
 	c := &eventConsumer{
-		logger: log,
-		done:   make(chan struct{}),
-		sig:    make(chan consumerSignal, 3),
-		out:    nil,
+		logger:  log,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		sig:     make(chan consumerSignal, 3),
+		out:     nil,
 
 		queue:    queue,
 		consumer: queue.Consumer(),
@@ -61,9 +63,13 @@ func newEventConsumer(
 	return c
 }
 
+// close stops the consumer, and waits for the consumer to return. A batch
+// read from the queue, but not forwarded to the outputs yet, is kept by the
+// consumer.
 func (c *eventConsumer) close() {
 	c.consumer.Close()
 	close(c.done)
+	<-c.stopped
 }
 
 func (c *eventConsumer) sigWait() {
@@ -118,6 +124,7 @@ func (c *eventConsumer) loop(consumer queue.Consumer) {
 	log := c.logger
 
 	log.Debug("start pipeline event consumer")
+	defer close(c.stopped)
 
 	var (
 		out    workQueue
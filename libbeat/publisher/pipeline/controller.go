@@ -1,6 +1,8 @@
 package pipeline
 
 import (
+	"sync"
+
 	"github.com/elastic/beats/libbeat/logp"
 	"github.com/elastic/beats/libbeat/outputs"
 	"github.com/elastic/beats/libbeat/publisher/queue"
@@ -19,12 +21,17 @@ type outputController struct {
 	retryer  *retryer
 	consumer *eventConsumer
 	out      *outputGroup
+
+	// tracker keeps track of the batches not ACKed yet, if the queue is
+	// flushed on shutdown.
+	tracker *batchTracker
 }
 
 // outputGroup configures a group of load balanced outputs with shared work queue.
 type outputGroup struct {
 	workQueue workQueue
 	outputs   []outputWorker
+	workers   sync.WaitGroup
 
 	batchSize  int
 	timeToLive int // event lifetime
@@ -42,14 +49,18 @@ func newOutputController(
 	log *logp.Logger,
 	observer outputObserver,
 	b queue.Queue,
+	flush bool,
 ) *outputController {
 	c := &outputController{
 		logger:   log,
 		observer: observer,
 		queue:    b,
 	}
+	if flush {
+		c.tracker = newBatchTracker()
+	}
 
-	ctx := &batchContext{}
+	ctx := &batchContext{tracker: c.tracker}
 	c.consumer = newEventConsumer(log, b, ctx)
 	c.retryer = newRetryer(log, observer, nil, c.consumer)
 	ctx.observer = observer
@@ -60,8 +71,12 @@ func newOutputController(
 	return c
 }
 
+// Close stops forwarding batches to the outputs, and closes the outputs one
+// after another. Batches in progress are left to the outputs, see flush.
 func (c *outputController) Close() error {
 	c.consumer.sigPause()
+	c.consumer.close()
+	c.retryer.close()
 
 	if c.out != nil {
 		for _, out := range c.out.outputs {
@@ -70,9 +85,6 @@ func (c *outputController) Close() error {
 		close(c.out.workQueue)
 	}
 
-	c.consumer.close()
-	c.retryer.close()
-
 	return nil
 }
 
@@ -80,16 +92,16 @@ func (c *outputController) Set(outGrp outputs.Group) {
 	// create new outputGroup with shared work queue
 	clients := outGrp.Clients
 	queue := makeWorkQueue()
-	worker := make([]outputWorker, len(clients))
-	for i, client := range clients {
-		worker[i] = makeClientWorker(c.observer, queue, client)
-	}
 	grp := &outputGroup{
 		workQueue:  queue,
-		outputs:    worker,
+		outputs:    make([]outputWorker, len(clients)),
 		timeToLive: outGrp.Retry + 1,
 		batchSize:  outGrp.BatchSize,
 	}
+	metrics := c.observer.outWorkers(len(clients))
+	for i, client := range clients {
+		grp.outputs[i] = makeClientWorker(c.observer, metrics[i], queue, client, &grp.workers)
+	}
 
 	// update consumer and retryer
 	c.consumer.sigPause()
@@ -0,0 +1,92 @@
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/publisher"
+	"github.com/elastic/beats/libbeat/publisher/deadletter"
+	"github.com/elastic/beats/libbeat/publisher/queue"
+	"github.com/elastic/beats/libbeat/publisher/queue/memqueue"
+)
+
+func newDeadLetterTestPipeline(t *testing.T, path string, publish func(publisher.Batch)) *Pipeline {
+	spool, err := deadletter.Open(path, deadletter.DefaultConfig.MaxBytes)
+	require.NoError(t, err)
+
+	queueFactory := func(e queue.Eventer) (queue.Queue, error) {
+		return memqueue.NewBroker(memqueue.Settings{Eventer: e, Events: 64}), nil
+	}
+	p, err := New(beat.Info{}, nil, queueFactory, testOutputGroup(3, publish), Settings{
+		DeadLetter: spool,
+	})
+	require.NoError(t, err)
+	return p
+}
+
+func TestDeadLetterSpoolAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deadletter")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, deadletter.FileName("test"))
+
+	// First run: the output rejects odd events.
+	p := newDeadLetterTestPipeline(t, path, func(batch publisher.Batch) {
+		for _, event := range batch.Events() {
+			if id, _ := event.Content.Fields["id"].(int); id%2 == 1 {
+				event.Fail()
+			}
+		}
+		batch.ACK()
+	})
+
+	r := newStatusRecorder(4)
+	publishTestEvents(t, p, beat.ClientConfig{}, r, 4)
+	status := r.wait(t)
+	p.Close()
+
+	assert.Equal(t, []beat.EventStatus{beat.EventACKed}, status[0])
+	assert.Equal(t, []beat.EventStatus{beat.EventFailed}, status[1])
+
+	// Later run: replay the spooled events to an output ACKing all events.
+	require.NoError(t, os.Rename(path, path+deadletter.ReplaySuffix))
+
+	var mutex sync.Mutex
+	var replayed []int
+	p = newDeadLetterTestPipeline(t, path, func(batch publisher.Batch) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		for _, event := range batch.Events() {
+			id, _ := event.Content.Fields["id"].(int64)
+			replayed = append(replayed, int(id))
+		}
+		batch.ACK()
+	})
+	defer p.Close()
+
+	file, err := os.Open(path + deadletter.ReplaySuffix)
+	require.NoError(t, err)
+	defer file.Close()
+
+	stats, err := deadletter.Replay(p, deadletter.NewReader(file))
+	require.NoError(t, err)
+	assert.Equal(t, deadletter.ReplayStats{Published: 2, ACKed: 2}, stats)
+
+	mutex.Lock()
+	sort.Ints(replayed)
+	assert.Equal(t, []int{1, 3}, replayed)
+	mutex.Unlock()
+
+	// No events failed again, so the new spool file is empty.
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), info.Size())
+}
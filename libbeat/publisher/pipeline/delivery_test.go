@@ -0,0 +1,219 @@
+package pipeline
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/publisher"
+	"github.com/elastic/beats/libbeat/publisher/queue"
+	"github.com/elastic/beats/libbeat/publisher/queue/memqueue"
+)
+
+type mockClient struct {
+	publish func(publisher.Batch)
+}
+
+func (c *mockClient) Close() error { return nil }
+func (c *mockClient) Publish(batch publisher.Batch) error {
+	c.publish(batch)
+	return nil
+}
+
+type dropProcessor struct{}
+
+type processorList []beat.Processor
+
+func (dropProcessor) String() string                         { return "drop" }
+func (dropProcessor) Run(_ *beat.Event) (*beat.Event, error) { return nil, nil }
+func (l processorList) All() []beat.Processor                { return l }
+
+// statusRecorder collects the status reported per event.
+type statusRecorder struct {
+	mutex  sync.Mutex
+	status map[int][]beat.EventStatus
+	wg     sync.WaitGroup
+}
+
+func newStatusRecorder(events int) *statusRecorder {
+	r := &statusRecorder{status: map[int][]beat.EventStatus{}}
+	r.wg.Add(events)
+	return r
+}
+
+func (r *statusRecorder) callback(id int) func(beat.EventStatus) {
+	return func(status beat.EventStatus) {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		r.status[id] = append(r.status[id], status)
+		r.wg.Done()
+	}
+}
+
+func (r *statusRecorder) wait(t *testing.T) map[int][]beat.EventStatus {
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for event callbacks")
+	}
+
+	// give late duplicate callbacks a chance to show up
+	time.Sleep(50 * time.Millisecond)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.status
+}
+
+func newTestPipeline(t *testing.T, retry int, publish func(publisher.Batch)) *Pipeline {
+	queueFactory := func(e queue.Eventer) (queue.Queue, error) {
+		return memqueue.NewBroker(memqueue.Settings{
+			Eventer: e,
+			Events:  64,
+		}), nil
+	}
+
+	p, err := New(beat.Info{}, nil, queueFactory, testOutputGroup(retry, publish), Settings{})
+	require.NoError(t, err)
+	return p
+}
+
+func testOutputGroup(retry int, publish func(publisher.Batch)) outputs.Group {
+	return outputs.Group{
+		Clients:   []outputs.Client{&mockClient{publish: publish}},
+		BatchSize: 10,
+		Retry:     retry,
+	}
+}
+
+func publishTestEvents(t *testing.T, p *Pipeline, cfg beat.ClientConfig, r *statusRecorder, n int) {
+	client, err := p.ConnectWith(cfg)
+	require.NoError(t, err)
+	defer client.Close()
+
+	for i := 0; i < n; i++ {
+		client.Publish(beat.Event{
+			Timestamp:  time.Now(),
+			Fields:     common.MapStr{"id": i},
+			OnComplete: r.callback(i),
+		})
+	}
+}
+
+func TestEventCallbackACKedAfterRetry(t *testing.T) {
+	var mutex sync.Mutex
+	attempts := 0
+	p := newTestPipeline(t, 3, func(batch publisher.Batch) {
+		mutex.Lock()
+		attempts++
+		first := attempts == 1
+		mutex.Unlock()
+
+		if first {
+			batch.Retry()
+		} else {
+			batch.ACK()
+		}
+	})
+	defer p.Close()
+
+	r := newStatusRecorder(5)
+	publishTestEvents(t, p, beat.ClientConfig{}, r, 5)
+
+	status := r.wait(t)
+	assert.Len(t, status, 5)
+	for id, s := range status {
+		assert.Equal(t, []beat.EventStatus{beat.EventACKed}, s, "event %v", id)
+	}
+}
+
+func TestEventCallbackFailedAfterRetryLimit(t *testing.T) {
+	p := newTestPipeline(t, 2, func(batch publisher.Batch) {
+		batch.Retry()
+	})
+	defer p.Close()
+
+	r := newStatusRecorder(5)
+	publishTestEvents(t, p, beat.ClientConfig{}, r, 5)
+
+	status := r.wait(t)
+	assert.Len(t, status, 5)
+	for id, s := range status {
+		assert.Equal(t, []beat.EventStatus{beat.EventFailed}, s, "event %v", id)
+	}
+}
+
+func TestEventCallbackPartialRetry(t *testing.T) {
+	// the output rejects odd events and retries the first event of each batch
+	// once, before ACKing the batch.
+	var mutex sync.Mutex
+	retried := map[interface{}]bool{}
+	p := newTestPipeline(t, 3, func(batch publisher.Batch) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		var retry []publisher.Event
+		for _, event := range batch.Events() {
+			id, _ := event.Content.Fields["id"].(int)
+			if id%2 == 1 {
+				event.Fail()
+				continue
+			}
+			if !retried[id] {
+				retried[id] = true
+				retry = append(retry, event)
+			}
+		}
+
+		if len(retry) > 0 {
+			batch.RetryEvents(retry)
+		} else {
+			batch.ACK()
+		}
+	})
+	defer p.Close()
+
+	r := newStatusRecorder(6)
+	publishTestEvents(t, p, beat.ClientConfig{}, r, 6)
+
+	status := r.wait(t)
+	assert.Len(t, status, 6)
+	for id, s := range status {
+		expected := beat.EventACKed
+		if id%2 == 1 {
+			expected = beat.EventFailed
+		}
+		assert.Equal(t, []beat.EventStatus{expected}, s, "event %v", id)
+	}
+}
+
+func TestEventCallbackDropped(t *testing.T) {
+	p := newTestPipeline(t, 3, func(batch publisher.Batch) {
+		batch.ACK()
+	})
+	defer p.Close()
+
+	r := newStatusRecorder(3)
+	cfg := beat.ClientConfig{
+		Processor: processorList{dropProcessor{}},
+	}
+	publishTestEvents(t, p, cfg, r, 3)
+
+	status := r.wait(t)
+	assert.Len(t, status, 3)
+	for id, s := range status {
+		assert.Equal(t, []beat.EventStatus{beat.EventDropped}, s, "event %v", id)
+	}
+}
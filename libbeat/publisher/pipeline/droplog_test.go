@@ -0,0 +1,98 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/publisher"
+	"github.com/elastic/beats/libbeat/publisher/droplog"
+)
+
+func newTestDropLog(size int) *droplog.Log {
+	config := droplog.DefaultConfig
+	config.Enabled = true
+	config.Token = "secret"
+	config.Size = size
+	return droplog.New(beat.Info{}, config)
+}
+
+// droppedIDs returns the ids of the logged events per drop reason.
+func droppedIDs(t *testing.T, l *droplog.Log) map[droplog.Reason][]int {
+	ids := map[droplog.Reason][]int{}
+	for _, entry := range l.Entries() {
+		var event struct {
+			ID int `json:"id"`
+		}
+		require.NoError(t, json.Unmarshal(entry.Event, &event))
+		ids[entry.Reason] = append(ids[entry.Reason], event.ID)
+	}
+	return ids
+}
+
+func TestDropLogFilteredEvents(t *testing.T) {
+	dropLog := newTestDropLog(10)
+	p := newCaptureTestPipeline(t, Settings{DropLog: dropLog}, func(batch publisher.Batch) {
+		batch.ACK()
+	})
+	defer p.Close()
+
+	r := newStatusRecorder(3)
+	publishTestEvents(t, p, beat.ClientConfig{Processor: processorList{dropProcessor{}}}, r, 3)
+	r.wait(t)
+
+	assert.Equal(t, map[droplog.Reason][]int{droplog.Filtered: {0, 1, 2}}, droppedIDs(t, dropLog))
+}
+
+func TestDropLogFailedEvents(t *testing.T) {
+	dropLog := newTestDropLog(10)
+	p := newCaptureTestPipeline(t, Settings{DropLog: dropLog}, func(batch publisher.Batch) {
+		batch.Retry()
+	})
+	defer p.Close()
+
+	r := newStatusRecorder(2)
+	publishTestEvents(t, p, beat.ClientConfig{}, r, 2)
+	r.wait(t)
+
+	ids := droppedIDs(t, dropLog)
+	sort.Ints(ids[droplog.OutputFailed])
+	assert.Equal(t, map[droplog.Reason][]int{droplog.OutputFailed: {0, 1}}, ids)
+}
+
+func TestDropLogClosedClient(t *testing.T) {
+	dropLog := newTestDropLog(10)
+	p := newCaptureTestPipeline(t, Settings{DropLog: dropLog}, func(batch publisher.Batch) {
+		batch.ACK()
+	})
+	defer p.Close()
+
+	client, err := p.Connect()
+	require.NoError(t, err)
+	client.Close()
+	client.Publish(beat.Event{Timestamp: time.Now(), Fields: common.MapStr{"id": 1}})
+
+	assert.Equal(t, map[droplog.Reason][]int{droplog.ClientClosed: {1}}, droppedIDs(t, dropLog))
+}
+
+func TestDropLogCapacity(t *testing.T) {
+	dropLog := newTestDropLog(2)
+	p := newCaptureTestPipeline(t, Settings{DropLog: dropLog}, func(batch publisher.Batch) {
+		batch.ACK()
+	})
+	defer p.Close()
+
+	r := newStatusRecorder(5)
+	publishTestEvents(t, p, beat.ClientConfig{Processor: processorList{dropProcessor{}}}, r, 5)
+	r.wait(t)
+
+	// Only the last dropped events are kept.
+	assert.Equal(t, map[droplog.Reason][]int{droplog.Filtered: {3, 4}}, droppedIDs(t, dropLog))
+	assert.Equal(t, uint64(5), dropLog.Total())
+}
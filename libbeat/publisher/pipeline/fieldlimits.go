@@ -0,0 +1,154 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/atomic"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/monitoring"
+)
+
+// FieldLimitsConfig configures limits on the field names of published events,
+// protecting Elasticsearch from mapping explosions caused by deeply nested or
+// long keys, like in malformed JSON documents.
+type FieldLimitsConfig struct {
+	MaxFieldDepth      int    `config:"max_field_depth" validate:"min=0"`
+	MaxFieldNameLength int    `config:"max_field_name_length" validate:"min=0"`
+	Action             string `config:"action"`
+}
+
+const (
+	fieldLimitsDrop = "drop"
+	fieldLimitsTag  = "tag"
+
+	// fieldLimitsExceededTag is added to events exceeding the field limits,
+	// if the tag action is configured.
+	fieldLimitsExceededTag = "field_limits_exceeded"
+)
+
+func (c *FieldLimitsConfig) Validate() error {
+	switch c.Action {
+	case "", fieldLimitsDrop, fieldLimitsTag:
+		return nil
+	}
+	return fmt.Errorf("invalid field_limits action '%v', must be one of '%v' or '%v'",
+		c.Action, fieldLimitsDrop, fieldLimitsTag)
+}
+
+// fieldLimiter checks the depth and length of the field names of events.
+// Fields exceeding a limit are dropped with all their sub fields, or the
+// event is tagged, keeping the fields.
+type fieldLimiter struct {
+	maxDepth      int
+	maxNameLength int
+	tag           bool
+
+	events  atomic.Uint64 // number of events exceeding the limits
+	dropped atomic.Uint64 // number of fields dropped
+}
+
+// newFieldLimiter creates a fieldLimiter. Returns nil if no limits are
+// configured.
+func newFieldLimiter(config FieldLimitsConfig) *fieldLimiter {
+	if config.MaxFieldDepth <= 0 && config.MaxFieldNameLength <= 0 {
+		return nil
+	}
+
+	return &fieldLimiter{
+		maxDepth:      config.MaxFieldDepth,
+		maxNameLength: config.MaxFieldNameLength,
+		tag:           config.Action == fieldLimitsTag,
+	}
+}
+
+func makeFieldLimitsProcessor(l *fieldLimiter) *processorFn {
+	return newAnnotateProcessor("fieldLimits", func(event *beat.Event) {
+		exceeded := l.limit(event.Fields, 0)
+		if exceeded == 0 {
+			return
+		}
+
+		l.events.Inc()
+		if l.tag {
+			common.AddTags(event.Fields, []string{fieldLimitsExceededTag})
+			return
+		}
+
+		l.dropped.Add(uint64(exceeded))
+		logp.Debug("publish", "dropped %v fields exceeding the field limits", exceeded)
+	})
+}
+
+// limit checks the fields nested at depth, returning the number of fields
+// exceeding the limits. Dotted keys count as one level per dot, like in the
+// Elasticsearch mapping.
+func (l *fieldLimiter) limit(fields common.MapStr, depth int) int {
+	exceeded := 0
+	for key, value := range fields {
+		keyDepth := depth + strings.Count(key, ".") + 1
+		if l.exceeds(key, keyDepth) {
+			exceeded++
+			if !l.tag {
+				delete(fields, key)
+			}
+			continue
+		}
+
+		exceeded += l.limitValue(value, keyDepth)
+	}
+	return exceeded
+}
+
+// limitValue checks the objects contained in a value. Arrays do not add a
+// nesting level.
+func (l *fieldLimiter) limitValue(value interface{}, depth int) int {
+	exceeded := 0
+	switch v := value.(type) {
+	case common.MapStr:
+		exceeded = l.limit(v, depth)
+	case map[string]interface{}:
+		exceeded = l.limit(common.MapStr(v), depth)
+	case []common.MapStr:
+		for _, elem := range v {
+			exceeded += l.limit(elem, depth)
+		}
+	case []interface{}:
+		for _, elem := range v {
+			exceeded += l.limitValue(elem, depth)
+		}
+	}
+	return exceeded
+}
+
+func (l *fieldLimiter) exceeds(key string, depth int) bool {
+	if l.maxDepth > 0 && depth > l.maxDepth {
+		return true
+	}
+	if l.maxNameLength > 0 {
+		for _, name := range strings.Split(key, ".") {
+			if len(name) > l.maxNameLength {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// registerFieldLimitsMetrics reports the number of events exceeding the field
+// limits and the number of fields dropped in the pipeline metrics.
+func registerFieldLimitsMetrics(metrics *monitoring.Registry, l *fieldLimiter) {
+	reg := metrics.GetRegistry("pipeline")
+	if reg == nil {
+		reg = metrics.NewRegistry("pipeline")
+	}
+
+	monitoring.NewFunc(reg, "events.field_limits.exceeded", func(_ monitoring.Mode, v monitoring.Visitor) {
+		v.OnInt(int64(l.events.Load()))
+	})
+	monitoring.NewFunc(reg, "events.field_limits.dropped_fields", func(_ monitoring.Mode, v monitoring.Visitor) {
+		v.OnInt(int64(l.dropped.Load()))
+	})
+}
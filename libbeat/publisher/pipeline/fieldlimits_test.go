@@ -0,0 +1,148 @@
+package pipeline
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/monitoring"
+	"github.com/elastic/beats/libbeat/publisher"
+	"github.com/elastic/beats/libbeat/publisher/queue"
+	"github.com/elastic/beats/libbeat/publisher/queue/memqueue"
+	"github.com/elastic/beats/libbeat/version"
+)
+
+func newFieldLimitsTestPipeline(t *testing.T, config FieldLimitsConfig) (*Pipeline, *monitoring.Registry) {
+	queueFactory := func(e queue.Eventer) (queue.Queue, error) {
+		return memqueue.NewBroker(memqueue.Settings{Eventer: e, Events: 16}), nil
+	}
+
+	reg := monitoring.NewRegistry()
+	p, err := New(beat.Info{}, reg, queueFactory,
+		testOutputGroup(0, func(batch publisher.Batch) { batch.ACK() }),
+		Settings{FieldLimits: config})
+	require.NoError(t, err)
+	return p, reg
+}
+
+// pathologicalEvent returns an event with a subtree nested 100 levels deep
+// and a 1000 characters long key, as created by decoding malformed JSON.
+func pathologicalEvent() *beat.Event {
+	deep := map[string]interface{}{"leaf": "value"}
+	for i := 0; i < 98; i++ {
+		deep = map[string]interface{}{"nested": deep}
+	}
+
+	return &beat.Event{Fields: common.MapStr{
+		"message": "hello",
+		"json": map[string]interface{}{
+			"deep":                    deep,
+			strings.Repeat("k", 1000): "long",
+			"list":                    []interface{}{map[string]interface{}{"a.b.c": 1}},
+			"ok":                      map[string]interface{}{"key": 1},
+		},
+	}}
+}
+
+func fieldLimitsMetrics(reg *monitoring.Registry) map[string]int64 {
+	return monitoring.CollectFlatSnapshot(reg, monitoring.Full, false).Ints
+}
+
+func TestFieldLimitsDrop(t *testing.T) {
+	p, reg := newFieldLimitsTestPipeline(t, FieldLimitsConfig{
+		MaxFieldDepth:      3,
+		MaxFieldNameLength: 256,
+	})
+	defer p.Close()
+	processor := p.newProcessorPipeline(beat.ClientConfig{})
+
+	event, err := processor.Run(pathologicalEvent())
+	require.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"message": "hello",
+		"json": common.MapStr{
+			"deep": common.MapStr{
+				"nested": common.MapStr{},
+			},
+			"list": []interface{}{common.MapStr{}},
+			"ok":   common.MapStr{"key": 1},
+		},
+		"ecs": common.MapStr{"version": version.ECSVersion},
+	}, event.Fields)
+
+	metrics := fieldLimitsMetrics(reg)
+	assert.Equal(t, int64(1), metrics["pipeline.events.field_limits.exceeded"])
+	assert.Equal(t, int64(3), metrics["pipeline.events.field_limits.dropped_fields"])
+
+	// Events within the limits are not modified.
+	event, err = processor.Run(&beat.Event{Fields: common.MapStr{
+		"a": common.MapStr{"b": common.MapStr{"c": 1}},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"a":   common.MapStr{"b": common.MapStr{"c": 1}},
+		"ecs": common.MapStr{"version": version.ECSVersion},
+	}, event.Fields)
+	assert.Equal(t, int64(1), fieldLimitsMetrics(reg)["pipeline.events.field_limits.exceeded"])
+}
+
+func TestFieldLimitsTag(t *testing.T) {
+	p, reg := newFieldLimitsTestPipeline(t, FieldLimitsConfig{
+		MaxFieldDepth:      3,
+		MaxFieldNameLength: 256,
+		Action:             "tag",
+	})
+	defer p.Close()
+	processor := p.newProcessorPipeline(beat.ClientConfig{})
+
+	event, err := processor.Run(pathologicalEvent())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"field_limits_exceeded"}, event.Fields["tags"])
+
+	leaf, err := event.GetValue("json.deep" + strings.Repeat(".nested", 98) + ".leaf")
+	require.NoError(t, err)
+	assert.Equal(t, "value", leaf)
+	assert.Contains(t, event.Fields["json"], strings.Repeat("k", 1000))
+
+	metrics := fieldLimitsMetrics(reg)
+	assert.Equal(t, int64(1), metrics["pipeline.events.field_limits.exceeded"])
+	assert.Equal(t, int64(0), metrics["pipeline.events.field_limits.dropped_fields"])
+}
+
+func TestFieldLimitsNameLength(t *testing.T) {
+	p, _ := newFieldLimitsTestPipeline(t, FieldLimitsConfig{MaxFieldNameLength: 5})
+	defer p.Close()
+	processor := p.newProcessorPipeline(beat.ClientConfig{})
+
+	event, err := processor.Run(&beat.Event{Fields: common.MapStr{
+		"short":       1,
+		"toolong":     2,
+		"a.toolong.b": 3,
+		"outer":       common.MapStr{"deep": common.MapStr{"toolong": 4, "ok": 5}},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"short": 1,
+		"outer": common.MapStr{"deep": common.MapStr{"ok": 5}},
+		"ecs":   common.MapStr{"version": version.ECSVersion},
+	}, event.Fields)
+}
+
+func TestFieldLimitsDisabled(t *testing.T) {
+	assert.Nil(t, newFieldLimiter(FieldLimitsConfig{}))
+	assert.Nil(t, newFieldLimiter(FieldLimitsConfig{Action: "tag"}))
+}
+
+func TestFieldLimitsConfigValidate(t *testing.T) {
+	for _, action := range []string{"", "drop", "tag"} {
+		config := FieldLimitsConfig{Action: action}
+		assert.NoError(t, config.Validate(), action)
+	}
+
+	config := FieldLimitsConfig{Action: "ignore"}
+	assert.Error(t, config.Validate())
+}
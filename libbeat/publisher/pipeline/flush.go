@@ -0,0 +1,257 @@
+package pipeline
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/publisher"
+	"github.com/elastic/beats/libbeat/publisher/deadletter"
+	"github.com/elastic/beats/libbeat/publisher/queue"
+)
+
+// ShutdownConfig configures how the outputs are flushed on shutdown.
+type ShutdownConfig struct {
+	FlushTimeout time.Duration     `config:"flush_timeout" validate:"min=0"`
+	WarmRestart  WarmRestartConfig `config:"warm_restart"`
+}
+
+// flushIdleTimeout is the time to wait for the queue to return more events,
+// when spooling the events left in the queue on shutdown.
+const flushIdleTimeout = 100 * time.Millisecond
+
+// batchTracker keeps track of the batches read from the queue and not ACKed
+// yet, in the order they have been read. On shutdown, the tracker is closed
+// and the batches still active are flushed. Batches flushed are owned by the
+// flush, the outputs can not ACK or retry them anymore.
+type batchTracker struct {
+	mutex  sync.Mutex
+	seq    uint64
+	active map[*Batch]uint64
+	closed bool
+}
+
+func newBatchTracker() *batchTracker {
+	return &batchTracker{active: map[*Batch]uint64{}}
+}
+
+func (t *batchTracker) add(b *Batch) {
+	if t == nil {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.seq++
+	t.active[b] = t.seq
+}
+
+// done removes a batch once it is ACKed or dropped. It returns false if the
+// batch has been flushed on shutdown.
+func (t *batchTracker) done(b *Batch) bool {
+	if t == nil {
+		return true
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.closed {
+		return false
+	}
+	delete(t.active, b)
+	return true
+}
+
+// owned returns false if the batch has been flushed on shutdown.
+func (t *batchTracker) owned() bool {
+	if t == nil {
+		return true
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return !t.closed
+}
+
+// close stops tracking and returns the active batches, in the order they have
+// been read from the queue.
+func (t *batchTracker) close() []*Batch {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.closed = true
+	batches := make([]*Batch, 0, len(t.active))
+	for b := range t.active {
+		batches = append(batches, b)
+	}
+	sort.Slice(batches, func(i, j int) bool {
+		return t.active[batches[i]] < t.active[batches[j]]
+	})
+	return batches
+}
+
+// flush flushes the events not ACKed by the outputs, once the outputs have
+// been closed. It waits up to timeout for the outputs to return the batches
+// in progress. If handoff is set, the events not ACKed are persisted to the
+// warm restart file in queue order, followed by the events left in the queue,
+// and ACKed once persisted. Events failing to be persisted, or all events if
+// handoff is not set, are failed if spool is set, so they are added to the
+// dead-letter spool. All events are either ACKed by an output, persisted or
+// spooled. Otherwise the events are not ACKed, for the beat to publish them
+// again on restart.
+func (c *outputController) flush(timeout time.Duration, spool bool, handoff *warmRestart) {
+	if !c.waitOutputs(timeout) {
+		c.logger.Info("Outputs did not stop within the flush timeout")
+	}
+
+	batches := c.tracker.close()
+
+	f := &flusher{logger: c.logger, spool: spool, handoff: handoff}
+	if !f.spool && f.handoff == nil {
+		pending := 0
+		for _, b := range batches {
+			pending += len(b.events)
+		}
+		if pending > 0 {
+			c.logger.Infof("Shutdown with %v events not ACKed by the outputs", pending)
+		}
+		return
+	}
+
+	for _, b := range batches {
+		if f.release(b.events) {
+			b.complete()
+			b.original.ACK()
+		}
+	}
+
+	for f.spool || f.handoff != nil {
+		batch := getQueued(c.queue, flushIdleTimeout)
+		if batch == nil {
+			break
+		}
+
+		events := batch.Events()
+		if f.release(events) {
+			for i := range events {
+				events[i].Delivery.Complete()
+			}
+			batch.ACK()
+		}
+	}
+
+	if f.handoff != nil {
+		if err := f.close(); err != nil {
+			c.logger.Errf("Failed to close warm restart file: %v", err)
+		} else {
+			handoff.done()
+		}
+	}
+
+	if f.persisted > 0 {
+		c.logger.Infof("Persisted %v events not ACKed by the outputs on shutdown for warm restart", f.persisted)
+	}
+	if f.spooled > 0 {
+		c.logger.Infof("Spooled %v events not ACKed by the outputs on shutdown", f.spooled)
+	}
+	if f.pending > 0 {
+		c.logger.Infof("Shutdown with %v events not ACKed by the outputs", f.pending)
+	}
+}
+
+// flusher persists or spools the events flushed on shutdown.
+type flusher struct {
+	logger  *logp.Logger
+	spool   bool
+	handoff *warmRestart
+	persist *deadletter.Spool // opened on the first event persisted
+
+	persisted, spooled, pending int
+}
+
+// release persists or fails the events, and returns true if the events are to
+// be reported as completed and ACKed to the queue. If persisting fails, the
+// warm restart file is not used anymore, and events are spooled or left in the
+// queue.
+func (f *flusher) release(events []publisher.Event) bool {
+	if f.handoff != nil {
+		err := f.persistEvents(events)
+		if err == nil {
+			f.persisted += len(events)
+			return true
+		}
+		f.logger.Errf("Failed to persist events for warm restart: %v", err)
+		f.close()
+		f.handoff = nil
+	}
+
+	if !f.spool {
+		f.pending += len(events)
+		return false
+	}
+	f.spooled += len(events)
+	for i := range events {
+		events[i].Fail()
+	}
+	return true
+}
+
+func (f *flusher) persistEvents(events []publisher.Event) error {
+	if f.persist == nil {
+		var err error
+		if f.persist, err = f.handoff.open(); err != nil {
+			return err
+		}
+	}
+	for i := range events {
+		if err := f.persist.Add(events[i].Content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *flusher) close() error {
+	if f.persist == nil {
+		return nil
+	}
+	return f.persist.Close()
+}
+
+// waitOutputs waits for the output workers to return, after the outputs have
+// been closed.
+func (c *outputController) waitOutputs(timeout time.Duration) bool {
+	if c.out == nil {
+		return true
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.out.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// getQueued returns the next batch of events left in the queue, or nil if the
+// queue does not return events within timeout.
+func getQueued(q queue.Queue, timeout time.Duration) queue.Batch {
+	consumer := q.Consumer()
+	defer consumer.Close()
+
+	timer := time.AfterFunc(timeout, func() { consumer.Close() })
+	defer timer.Stop()
+
+	batch, err := consumer.Get(0)
+	if err != nil {
+		return nil
+	}
+	return batch
+}
@@ -0,0 +1,191 @@
+package pipeline
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/publisher"
+	"github.com/elastic/beats/libbeat/publisher/deadletter"
+	"github.com/elastic/beats/libbeat/publisher/queue"
+	"github.com/elastic/beats/libbeat/publisher/queue/memqueue"
+)
+
+// stallingClient ACKs the first batches, and blocks publishing any further
+// batch until the client is closed. Blocked batches are cancelled on close,
+// and ACKed late if lateACK is set.
+type stallingClient struct {
+	acks    int
+	lateACK bool
+
+	mutex     sync.Mutex
+	published int
+	acked     map[int]bool
+	closed    chan struct{}
+	late      sync.WaitGroup
+}
+
+func newStallingClient(acks int) *stallingClient {
+	return &stallingClient{acks: acks, acked: map[int]bool{}, closed: make(chan struct{})}
+}
+
+func (c *stallingClient) Close() error {
+	close(c.closed)
+	return nil
+}
+
+func (c *stallingClient) Publish(batch publisher.Batch) error {
+	c.mutex.Lock()
+	c.published++
+	ack := c.published <= c.acks
+	if ack {
+		for _, event := range batch.Events() {
+			c.acked[event.Content.Fields["id"].(int)] = true
+		}
+	}
+	c.mutex.Unlock()
+
+	if ack {
+		batch.ACK()
+		return nil
+	}
+
+	<-c.closed
+	if c.lateACK {
+		c.late.Add(1)
+		go func() {
+			defer c.late.Done()
+			time.Sleep(50 * time.Millisecond)
+			batch.ACK()
+		}()
+	} else {
+		batch.Cancelled()
+	}
+	return errors.New("client closed")
+}
+
+func (c *stallingClient) publishedBatches() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.published
+}
+
+func newFlushTestPipeline(t *testing.T, client outputs.Client, spool *deadletter.Spool) *Pipeline {
+	queueFactory := func(e queue.Eventer) (queue.Queue, error) {
+		return memqueue.NewBroker(memqueue.Settings{Eventer: e, Events: 64}), nil
+	}
+	group := outputs.Group{Clients: []outputs.Client{client}, BatchSize: 10}
+	p, err := New(beat.Info{}, nil, queueFactory, group, Settings{
+		FlushTimeout: 200 * time.Millisecond,
+		DeadLetter:   spool,
+	})
+	require.NoError(t, err)
+	return p
+}
+
+func readSpooledIDs(t *testing.T, path string) []int {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var ids []int
+	reader := deadletter.NewReader(file)
+	for {
+		event, err := reader.Next()
+		if err == io.EOF {
+			return ids
+		}
+		require.NoError(t, err)
+		id, _ := event.Fields["id"].(int64)
+		ids = append(ids, int(id))
+	}
+}
+
+func (r *statusRecorder) snapshot() map[int][]beat.EventStatus {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.status
+}
+
+func TestFlushOnCloseWaitsForOutputs(t *testing.T) {
+	p := newFlushTestPipeline(t, &mockClient{publish: func(batch publisher.Batch) {
+		time.Sleep(10 * time.Millisecond)
+		batch.ACK()
+	}}, nil)
+
+	r := newStatusRecorder(30)
+	publishTestEvents(t, p, beat.ClientConfig{}, r, 30)
+	p.Close()
+
+	// All events have been ACKed by the time Close returns.
+	status := r.snapshot()
+	for id := 0; id < 30; id++ {
+		assert.Equal(t, []beat.EventStatus{beat.EventACKed}, status[id], "event %v", id)
+	}
+}
+
+func TestFlushOnCloseSpoolsUnacked(t *testing.T) {
+	for _, lateACK := range []bool{false, true} {
+		dir, err := ioutil.TempDir("", "flush")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, deadletter.FileName("test"))
+		spool, err := deadletter.Open(path, deadletter.DefaultConfig.MaxBytes)
+		require.NoError(t, err)
+
+		client := newStallingClient(1)
+		client.lateACK = lateACK
+		p := newFlushTestPipeline(t, client, spool)
+
+		// The first batch is ACKed, the second batch is in-flight, the
+		// following events are held by the consumer or queued.
+		r := newStatusRecorder(45)
+		publishTestEvents(t, p, beat.ClientConfig{}, r, 45)
+		waitFor(t, "in-flight batch", func() bool { return client.publishedBatches() == 2 })
+		p.Close()
+		client.late.Wait()
+
+		// Each event is reported once, either ACKed or spooled.
+		status := r.wait(t)
+		var expected []int
+		for id := 0; id < 45; id++ {
+			if client.acked[id] {
+				assert.Equal(t, []beat.EventStatus{beat.EventACKed}, status[id], "event %v, late ACK %v", id, lateACK)
+			} else {
+				assert.Equal(t, []beat.EventStatus{beat.EventFailed}, status[id], "event %v, late ACK %v", id, lateACK)
+				expected = append(expected, id)
+			}
+		}
+
+		// Events are spooled in queue order.
+		assert.Equal(t, expected, readSpooledIDs(t, path), "late ACK %v", lateACK)
+	}
+}
+
+func TestFlushOnCloseWithoutSpool(t *testing.T) {
+	client := newStallingClient(1)
+	p := newFlushTestPipeline(t, client, nil)
+
+	r := newStatusRecorder(30)
+	publishTestEvents(t, p, beat.ClientConfig{}, r, 30)
+	waitFor(t, "in-flight batch", func() bool { return client.publishedBatches() == 2 })
+	p.Close()
+
+	// Events not ACKed are not reported, for the beat to publish them again.
+	status := r.snapshot()
+	assert.Equal(t, len(client.acked), len(status))
+	for id := range client.acked {
+		assert.Equal(t, []beat.EventStatus{beat.EventACKed}, status[id], "event %v", id)
+	}
+}
@@ -0,0 +1,92 @@
+package pipeline
+
+import (
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// GlobalConfig configures the labels and tags stamped into every published
+// event.
+type GlobalConfig struct {
+	Labels map[string]string `config:"labels"`
+	Tags   []string          `config:"tags"`
+}
+
+const labelsKey = "labels"
+
+// makeGlobalProcessor creates the processor adding the global labels and
+// tags to events. Labels already set by an event take precedence over the
+// global labels. Returns nil if no global labels or tags are configured.
+func makeGlobalProcessor(config GlobalConfig) *processorFn {
+	if len(config.Labels) == 0 && len(config.Tags) == 0 {
+		return nil
+	}
+
+	return newAnnotateProcessor("globalLabels", func(event *beat.Event) {
+		addGlobalLabels(event.Fields, config.Labels)
+		addGlobalTags(event.Fields, config.Tags)
+	})
+}
+
+func addGlobalLabels(fields common.MapStr, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+
+	var current common.MapStr
+	switch v := fields[labelsKey].(type) {
+	case nil:
+		current = common.MapStr{}
+		fields[labelsKey] = current
+	case common.MapStr:
+		current = v
+	case map[string]interface{}:
+		current = common.MapStr(v)
+		fields[labelsKey] = current
+	default:
+		// labels set by the event to a non object value are kept
+		return
+	}
+
+	for name, value := range labels {
+		if _, exists := current[name]; !exists {
+			current[name] = value
+		}
+	}
+}
+
+// addGlobalTags appends the global tags not yet present to the events tags.
+// A new slice is created, such that events never share their tags.
+func addGlobalTags(fields common.MapStr, tags []string) {
+	if len(tags) == 0 {
+		return
+	}
+
+	var existing []string
+	switch v := fields[common.TagsKey].(type) {
+	case nil:
+	case []string:
+		existing = v
+	default:
+		// tags set by the event to an unexpected type are kept
+		return
+	}
+
+	merged := make([]string, len(existing), len(existing)+len(tags))
+	copy(merged, existing)
+	for _, tag := range tags {
+		if !containsTag(merged, tag) {
+			merged = append(merged, tag)
+		}
+	}
+	fields[common.TagsKey] = merged
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
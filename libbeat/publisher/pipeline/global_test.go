@@ -0,0 +1,84 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/version"
+)
+
+func newGlobalTestProcessor(config beat.ClientConfig) beat.Processor {
+	p := &Pipeline{
+		processors: makePipelineProcessors(Annotations{
+			Global: GlobalConfig{
+				Labels: map[string]string{"environment": "production", "region": "us-east-1"},
+				Tags:   []string{"fleet", "web"},
+			},
+		}, nil, false),
+	}
+	return p.newProcessorPipeline(config)
+}
+
+func TestGlobalLabelsAndTags(t *testing.T) {
+	processor := newGlobalTestProcessor(beat.ClientConfig{})
+
+	event, err := processor.Run(&beat.Event{Fields: common.MapStr{"message": "hello"}})
+	require.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"message": "hello",
+		"labels":  common.MapStr{"environment": "production", "region": "us-east-1"},
+		"tags":    []string{"fleet", "web"},
+		"ecs":     common.MapStr{"version": version.ECSVersion},
+	}, event.Fields)
+}
+
+func TestGlobalLabelsOverriddenByEvent(t *testing.T) {
+	processor := newGlobalTestProcessor(beat.ClientConfig{})
+
+	event, err := processor.Run(&beat.Event{Fields: common.MapStr{
+		"message": "hello",
+		"labels":  common.MapStr{"region": "eu-west-1", "team": "search"},
+		"tags":    []string{"web", "canary"},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"message": "hello",
+		"labels": common.MapStr{
+			"environment": "production",
+			"region":      "eu-west-1",
+			"team":        "search",
+		},
+		"tags": []string{"web", "canary", "fleet"},
+		"ecs":  common.MapStr{"version": version.ECSVersion},
+	}, event.Fields)
+}
+
+func TestGlobalLabelsOverriddenByClientFields(t *testing.T) {
+	processor := newGlobalTestProcessor(beat.ClientConfig{
+		Fields: common.MapStr{"labels": common.MapStr{"environment": "staging"}},
+	})
+
+	event, err := processor.Run(&beat.Event{Fields: common.MapStr{"message": "hello"}})
+	require.NoError(t, err)
+	labels, err := event.GetValue("labels")
+	require.NoError(t, err)
+	assert.Equal(t, common.MapStr{"environment": "staging", "region": "us-east-1"}, labels)
+}
+
+func TestGlobalLabelsNotShared(t *testing.T) {
+	processor := newGlobalTestProcessor(beat.ClientConfig{})
+
+	first, err := processor.Run(&beat.Event{Fields: common.MapStr{"message": "first"}})
+	require.NoError(t, err)
+	first.Fields["labels"].(common.MapStr)["region"] = "modified"
+	first.Fields["tags"].([]string)[0] = "modified"
+
+	second, err := processor.Run(&beat.Event{Fields: common.MapStr{"message": "second"}})
+	require.NoError(t, err)
+	assert.Equal(t, "us-east-1", second.Fields["labels"].(common.MapStr)["region"])
+	assert.Equal(t, []string{"fleet", "web"}, second.Fields["tags"])
+}
@@ -4,14 +4,20 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"path/filepath"
 
 	"github.com/elastic/beats/libbeat/beat"
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/logp"
 	"github.com/elastic/beats/libbeat/monitoring"
 	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/paths"
 	"github.com/elastic/beats/libbeat/processors"
+	"github.com/elastic/beats/libbeat/publisher/capture"
+	"github.com/elastic/beats/libbeat/publisher/deadletter"
+	"github.com/elastic/beats/libbeat/publisher/droplog"
 	"github.com/elastic/beats/libbeat/publisher/queue"
+	"github.com/elastic/beats/libbeat/publisher/tap"
 )
 
 // Global pipeline module for loading the main pipeline from a configuration object
@@ -26,11 +32,14 @@ func init() {
 }
 
 // Load uses a Config object to create a new complete Pipeline instance with
-// configured queue and outputs.
+// configured queue and outputs. The processed events are streamed to
+// eventTap, and dropped events are logged to dropLog, if set.
 func Load(
 	beatInfo beat.Info,
 	config Config,
 	outcfg common.ConfigNamespace,
+	eventTap *tap.Tap,
+	dropLog *droplog.Log,
 ) (*Pipeline, error) {
 	if publishDisabled {
 		logp.Info("Dry run mode. All output types except the file based one are disabled.")
@@ -41,6 +50,11 @@ func Load(
 		return nil, fmt.Errorf("error initializing processors: %v", err)
 	}
 
+	named, err := loadNamedPipelines(config.ProcessingPipelines)
+	if err != nil {
+		return nil, err
+	}
+
 	reg := monitoring.Default.GetRegistry("libbeat")
 	if reg == nil {
 		reg = monitoring.Default.NewRegistry("libbeat")
@@ -48,12 +62,22 @@ func Load(
 
 	name := beatInfo.Name
 	settings := Settings{
-		WaitClose:     0,
-		WaitCloseMode: NoWaitOnClose,
-		Disabled:      publishDisabled,
-		Processors:    processors,
+		WaitClose:        0,
+		WaitCloseMode:    NoWaitOnClose,
+		Disabled:         publishDisabled,
+		Processors:       processors,
+		ProcessorsReload: config.ProcessorsReload,
+		NamedPipelines:   named,
+		SequenceField:    config.Sequence.field(),
+		Provenance:       config.Provenance.Enabled,
+		FieldLimits:      config.FieldLimits,
+		FlushTimeout:     config.Shutdown.FlushTimeout,
+		WarmRestartPath:  warmRestartPath(config.Shutdown.WarmRestart),
+		Tap:              eventTap,
+		DropLog:          dropLog,
 		Annotations: Annotations{
-			Event: config.EventMetadata,
+			Event:  config.EventMetadata,
+			Global: config.Global,
 			Beat: common.MapStr{
 				"name":     name,
 				"hostname": beatInfo.Hostname,
@@ -72,8 +96,27 @@ func Load(
 		return nil, err
 	}
 
+	settings.DeadLetter, err = loadDeadLetter(config.DeadLetter, outcfg)
+	if err != nil {
+		return nil, err
+	}
+
+	settings.Capture, err = loadCapture(config.Capture)
+	if err != nil {
+		if settings.DeadLetter != nil {
+			settings.DeadLetter.Close()
+		}
+		return nil, err
+	}
+
 	p, err := New(beatInfo, reg, queueBuilder, out, settings)
 	if err != nil {
+		if settings.DeadLetter != nil {
+			settings.DeadLetter.Close()
+		}
+		if settings.Capture != nil {
+			settings.Capture.Close()
+		}
 		return nil, err
 	}
 
@@ -110,6 +153,74 @@ func loadOutput(
 	return out, nil
 }
 
+// loadDeadLetter opens the dead-letter spool file of the configured output, if
+// the dead-letter spool is enabled.
+func loadDeadLetter(
+	cfg *common.Config,
+	outcfg common.ConfigNamespace,
+) (*deadletter.Spool, error) {
+	if cfg == nil || publishDisabled || !outcfg.IsSet() {
+		return nil, nil
+	}
+
+	config := deadletter.DefaultConfig
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("error initializing dead-letter spool: %v", err)
+	}
+	if !config.Enabled {
+		return nil, nil
+	}
+
+	path := filepath.Join(paths.Resolve(paths.Data, config.Path), deadletter.FileName(outcfg.Name()))
+	spool, err := deadletter.Open(path, config.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	logp.Info("Dead-letter spool enabled: %v", path)
+	return spool, nil
+}
+
+// warmRestartPath returns the path of the warm restart file, if warm restarts
+// are enabled.
+func warmRestartPath(config WarmRestartConfig) string {
+	if !config.Enabled || publishDisabled {
+		return ""
+	}
+
+	path := config.Path
+	if path == "" {
+		path = defaultWarmRestartFile
+	}
+	path = paths.Resolve(paths.Data, path)
+	logp.Info("Warm restart enabled: %v", path)
+	return path
+}
+
+// loadCapture opens the capture file, if event capture is enabled.
+func loadCapture(cfg *common.Config) (*capture.Writer, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	config := capture.DefaultConfig
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("error initializing event capture: %v", err)
+	}
+	if !config.Enabled {
+		return nil, nil
+	}
+
+	path := paths.Resolve(paths.Data, config.Path)
+	w, err := capture.Open(path, config)
+	if err != nil {
+		return nil, err
+	}
+
+	logp.Info("Event capture enabled: %v", path)
+	return w, nil
+}
+
 func createQueueBuilder(config common.ConfigNamespace) (func(queue.Eventer) (queue.Queue, error), error) {
 	queueType := defaultQueueType
 	if b := config.Name(); b != "" {
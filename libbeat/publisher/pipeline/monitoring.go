@@ -35,6 +35,9 @@ type outputObserver interface {
 	eventsRetry(int)
 	outBatchSend(int)
 	outBatchACKed(int)
+	outClientConnected()
+	outClientDisconnected()
+	outWorkers(n int) []*workerMetrics
 }
 
 // metricsObserver is used by many component in the publisher pipeline, to report
@@ -45,6 +48,7 @@ type outputObserver interface {
 // event-handlers only (e.g. the client centric events callbacks)
 type metricsObserver struct {
 	metrics *monitoring.Registry
+	reg     *monitoring.Registry // pipeline registry
 
 	// clients metrics
 	clients *monitoring.Uint
@@ -56,6 +60,9 @@ type metricsObserver struct {
 
 	// queue metrics
 	ackedQueue *monitoring.Uint
+
+	// output metrics
+	connectedOutputs *monitoring.Uint
 }
 
 func newMetricsObserver(metrics *monitoring.Registry) *metricsObserver {
@@ -66,6 +73,7 @@ func newMetricsObserver(metrics *monitoring.Registry) *metricsObserver {
 
 	return &metricsObserver{
 		metrics: metrics,
+		reg:     reg,
 		clients: monitoring.NewUint(reg, "clients"),
 
 		events:    monitoring.NewUint(reg, "events.total"),
@@ -78,6 +86,8 @@ func newMetricsObserver(metrics *monitoring.Registry) *metricsObserver {
 		ackedQueue: monitoring.NewUint(reg, "queue.acked"),
 
 		activeEvents: monitoring.NewUint(reg, "events.active"),
+
+		connectedOutputs: monitoring.NewUint(reg, "output.connected"),
 	}
 }
 
@@ -163,22 +173,45 @@ func (o *metricsObserver) outBatchSend(int) {}
 // (output) number of events acked by the output batch
 func (o *metricsObserver) outBatchACKed(int) {}
 
+// (output) output client is connected and ready to publish events
+func (o *metricsObserver) outClientConnected() { o.connectedOutputs.Inc() }
+
+// (output) output client lost its connection or has been closed
+func (o *metricsObserver) outClientDisconnected() { o.connectedOutputs.Dec() }
+
+// (controller) metrics of the n workers of a new output group. The metrics of
+// the workers of the previous output group are removed.
+func (o *metricsObserver) outWorkers(n int) []*workerMetrics {
+	o.reg.Remove(workersRegistry)
+
+	workers := make([]*workerMetrics, n)
+	for i := range workers {
+		workers[i] = newWorkerMetrics(o.reg, i)
+	}
+	return workers
+}
+
 type emptyObserver struct{}
 
 var nilObserver observer = (*emptyObserver)(nil)
 
-func (*emptyObserver) cleanup()            {}
-func (*emptyObserver) clientConnected()    {}
-func (*emptyObserver) clientClosing()      {}
-func (*emptyObserver) clientClosed()       {}
-func (*emptyObserver) newEvent()           {}
-func (*emptyObserver) filteredEvent()      {}
-func (*emptyObserver) publishedEvent()     {}
-func (*emptyObserver) failedPublishEvent() {}
-func (*emptyObserver) queueACKed(n int)    {}
-func (*emptyObserver) updateOutputGroup()  {}
-func (*emptyObserver) eventsFailed(int)    {}
-func (*emptyObserver) eventsDropped(int)   {}
-func (*emptyObserver) eventsRetry(int)     {}
-func (*emptyObserver) outBatchSend(int)    {}
-func (*emptyObserver) outBatchACKed(int)   {}
+func (*emptyObserver) cleanup()               {}
+func (*emptyObserver) clientConnected()       {}
+func (*emptyObserver) clientClosing()         {}
+func (*emptyObserver) clientClosed()          {}
+func (*emptyObserver) newEvent()              {}
+func (*emptyObserver) filteredEvent()         {}
+func (*emptyObserver) publishedEvent()        {}
+func (*emptyObserver) failedPublishEvent()    {}
+func (*emptyObserver) queueACKed(n int)       {}
+func (*emptyObserver) updateOutputGroup()     {}
+func (*emptyObserver) eventsFailed(int)       {}
+func (*emptyObserver) eventsDropped(int)      {}
+func (*emptyObserver) eventsRetry(int)        {}
+func (*emptyObserver) outBatchSend(int)       {}
+func (*emptyObserver) outBatchACKed(int)      {}
+func (*emptyObserver) outClientConnected()    {}
+func (*emptyObserver) outClientDisconnected() {}
+func (*emptyObserver) outWorkers(n int) []*workerMetrics {
+	return make([]*workerMetrics, n)
+}
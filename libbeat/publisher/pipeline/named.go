@@ -0,0 +1,92 @@
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+// NamedPipelineConfig configures a named processing pipeline. Clients select
+// the named pipeline their events are processed by via
+// beat.ClientConfig.ProcessingPipeline. All named pipelines publish to the
+// queue and outputs shared by the pipeline.
+type NamedPipelineConfig struct {
+	Name                 string                  `config:"name" validate:"required"`
+	common.EventMetadata `config:",inline"`      // Fields and tags to add to each event.
+	Processors           processors.PluginConfig `config:"processors"`
+}
+
+// NamedPipeline contains the event processing settings of a named processing
+// pipeline.
+type NamedPipeline struct {
+	Event      common.EventMetadata
+	Processors *processors.Processors
+}
+
+// namedProcessors holds the processing of a named pipeline for constructing
+// the clients processor pipeline on connect.
+type namedProcessors struct {
+	fields     common.MapStr
+	tags       []string
+	processors beat.Processor
+}
+
+// loadNamedPipelines creates the processors of the configured named
+// processing pipelines. Names must be unique.
+func loadNamedPipelines(configs []NamedPipelineConfig) (map[string]NamedPipeline, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+
+	named := make(map[string]NamedPipeline, len(configs))
+	for _, config := range configs {
+		if _, exists := named[config.Name]; exists {
+			return nil, fmt.Errorf("duplicate processing pipeline name '%v'", config.Name)
+		}
+
+		procs, err := processors.New(config.Processors)
+		if err != nil {
+			return nil, fmt.Errorf("error initializing processors of processing pipeline '%v': %v", config.Name, err)
+		}
+
+		named[config.Name] = NamedPipeline{
+			Event:      config.EventMetadata,
+			Processors: procs,
+		}
+	}
+	return named, nil
+}
+
+func makeNamedProcessors(named map[string]NamedPipeline) map[string]*namedProcessors {
+	if len(named) == 0 {
+		return nil
+	}
+
+	m := make(map[string]*namedProcessors, len(named))
+	for name, settings := range named {
+		n := &namedProcessors{}
+
+		if em := settings.Event; len(em.Fields) > 0 {
+			fields := common.MapStr{}
+			common.MergeFields(fields, em.Fields.Clone(), em.FieldsUnderRoot)
+			n.fields = fields
+		}
+
+		if t := settings.Event.Tags; len(t) > 0 {
+			n.tags = t
+		}
+
+		if procs := settings.Processors; procs != nil && len(procs.List) > 0 {
+			tmp := &program{title: "pipeline=" + name}
+			for _, p := range procs.List {
+				tmp.add(p)
+			}
+			n.processors = tmp
+		}
+
+		m[name] = n
+	}
+	return m
+}
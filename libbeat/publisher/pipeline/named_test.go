@@ -0,0 +1,149 @@
+package pipeline
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/processors"
+	"github.com/elastic/beats/libbeat/publisher"
+	"github.com/elastic/beats/libbeat/publisher/queue"
+	"github.com/elastic/beats/libbeat/publisher/queue/memqueue"
+)
+
+func TestNamedPipelinesShareOutput(t *testing.T) {
+	queueFactory := func(e queue.Eventer) (queue.Queue, error) {
+		return memqueue.NewBroker(memqueue.Settings{Eventer: e, Events: 64}), nil
+	}
+
+	var mutex sync.Mutex
+	published := map[string]common.MapStr{}
+	out := testOutputGroup(0, func(batch publisher.Batch) {
+		mutex.Lock()
+		for _, event := range batch.Events() {
+			fields := event.Content.Fields
+			published[fields["message"].(string)] = fields
+		}
+		mutex.Unlock()
+		batch.ACK()
+	})
+
+	p, err := New(beat.Info{}, nil, queueFactory, out, Settings{
+		Annotations: Annotations{
+			Event: common.EventMetadata{Tags: []string{"global"}},
+		},
+		Processors: &processors.Processors{List: []processors.Processor{
+			&testSetFields{fields: common.MapStr{"global": true}},
+		}},
+		NamedPipelines: map[string]NamedPipeline{
+			"security": {
+				Event: common.EventMetadata{Tags: []string{"security"}},
+				Processors: &processors.Processors{List: []processors.Processor{
+					&testSetFields{fields: common.MapStr{"pipeline": "security"}},
+				}},
+			},
+			"ops": {
+				Event: common.EventMetadata{Fields: common.MapStr{"team": "ops"}},
+				Processors: &processors.Processors{List: []processors.Processor{
+					&testSetFields{fields: common.MapStr{"pipeline": "ops"}},
+				}},
+			},
+		},
+	})
+	require.NoError(t, err)
+	defer p.Close()
+
+	for _, name := range []string{"security", "ops", ""} {
+		client, err := p.ConnectWith(beat.ClientConfig{ProcessingPipeline: name})
+		require.NoError(t, err)
+		defer client.Close()
+
+		client.Publish(beat.Event{
+			Timestamp: time.Now(),
+			Fields:    common.MapStr{"message": "from " + name},
+		})
+	}
+
+	waitFor(t, "published events", func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(published) == 3
+	})
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	security := published["from security"]
+	assert.Equal(t, "security", security["pipeline"])
+	assert.Equal(t, []string{"global", "security"}, security["tags"])
+	assert.NotContains(t, security, "fields")
+	assert.Equal(t, true, security["global"])
+
+	ops := published["from ops"]
+	assert.Equal(t, "ops", ops["pipeline"])
+	assert.Equal(t, []string{"global"}, ops["tags"])
+	assert.Equal(t, common.MapStr{"team": "ops"}, ops["fields"])
+	assert.Equal(t, true, ops["global"])
+
+	// Clients not selecting a named pipeline only run the global processing.
+	none := published["from "]
+	assert.NotContains(t, none, "pipeline")
+	assert.Equal(t, []string{"global"}, none["tags"])
+	assert.Equal(t, true, none["global"])
+}
+
+func TestNamedPipelineUnknown(t *testing.T) {
+	p := newTestPipeline(t, 0, func(batch publisher.Batch) { batch.ACK() })
+	defer p.Close()
+
+	_, err := p.ConnectWith(beat.ClientConfig{ProcessingPipeline: "missing"})
+	assert.Error(t, err)
+}
+
+func TestLoadNamedPipelines(t *testing.T) {
+	load := func(settings interface{}) (map[string]NamedPipeline, error) {
+		cfg, err := common.NewConfigFrom(settings)
+		require.NoError(t, err)
+
+		config := Config{}
+		require.NoError(t, cfg.Unpack(&config))
+		return loadNamedPipelines(config.ProcessingPipelines)
+	}
+
+	named, err := load(map[string]interface{}{
+		"processing_pipelines": []map[string]interface{}{
+			{
+				"name": "security",
+				"tags": []string{"security"},
+				"processors": []map[string]interface{}{
+					{"test_set_fields": map[string]interface{}{"fields.pipeline": "security"}},
+				},
+			},
+			{"name": "ops"},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, named, 2)
+	assert.Equal(t, []string{"security"}, named["security"].Event.Tags)
+	assert.Len(t, named["security"].Processors.List, 1)
+
+	_, err = load(map[string]interface{}{
+		"processing_pipelines": []map[string]interface{}{
+			{"name": "ops"},
+			{"name": "ops"},
+		},
+	})
+	assert.Error(t, err)
+
+	_, err = load(map[string]interface{}{
+		"processing_pipelines": []map[string]interface{}{
+			{"name": "ops", "processors": []map[string]interface{}{{"unknown_processor": nil}}},
+		},
+	})
+	assert.Error(t, err)
+}
@@ -1,6 +1,8 @@
 package pipeline
 
 import (
+	"sync"
+
 	"github.com/elastic/beats/libbeat/common/atomic"
 	"github.com/elastic/beats/libbeat/logp"
 	"github.com/elastic/beats/libbeat/outputs"
@@ -9,6 +11,7 @@ import (
 // clientWorker manages output client of type outputs.Client, not supporting reconnect.
 type clientWorker struct {
 	observer outputObserver
+	metrics  *workerMetrics
 	qu       workQueue
 	client   outputs.Client
 	closed   atomic.Bool
@@ -17,6 +20,7 @@ type clientWorker struct {
 // netClientWorker manages reconnectable output clients of type outputs.NetworkClient.
 type netClientWorker struct {
 	observer outputObserver
+	metrics  *workerMetrics
 	qu       workQueue
 	client   outputs.NetworkClient
 	closed   atomic.Bool
@@ -25,14 +29,29 @@ type netClientWorker struct {
 	batchSizer func() int
 }
 
-func makeClientWorker(observer outputObserver, qu workQueue, client outputs.Client) outputWorker {
+// makeClientWorker starts the worker of client. The wait group is done once
+// the worker returns.
+func makeClientWorker(
+	observer outputObserver,
+	metrics *workerMetrics,
+	qu workQueue,
+	client outputs.Client,
+	wg *sync.WaitGroup,
+) outputWorker {
+	wg.Add(1)
 	if nc, ok := client.(outputs.NetworkClient); ok {
-		c := &netClientWorker{observer: observer, qu: qu, client: nc}
-		go c.run()
+		c := &netClientWorker{observer: observer, metrics: metrics, qu: qu, client: nc}
+		go func() {
+			defer wg.Done()
+			c.run()
+		}()
 		return c
 	}
-	c := &clientWorker{observer: observer, qu: qu, client: client}
-	go c.run()
+	c := &clientWorker{observer: observer, metrics: metrics, qu: qu, client: client}
+	go func() {
+		defer wg.Done()
+		c.run()
+	}()
 	return c
 }
 
@@ -42,9 +61,13 @@ func (w *clientWorker) Close() error {
 }
 
 func (w *clientWorker) run() {
+	w.observer.outClientConnected()
+	defer w.observer.outClientDisconnected()
+
 	for !w.closed.Load() {
 		for batch := range w.qu {
 			w.observer.outBatchSend(len(batch.events))
+			batch.sentBy(w.metrics)
 
 			if err := w.client.Publish(batch); err != nil {
 				return
@@ -78,21 +101,33 @@ func (w *netClientWorker) run() {
 			break
 		}
 
-		// send loop
-		for batch := range w.qu {
-			if w.closed.Load() {
-				if batch != nil {
-					batch.Cancelled()
-				}
-				return
-			}
+		if !w.publish() {
+			return
+		}
+	}
+}
 
-			err := w.client.Publish(batch)
-			if err != nil {
-				logp.Err("Failed to publish events: %v", err)
-				// on error return to connect loop
-				break
+// publish runs the send loop of a connected client. It returns false if the
+// worker has been closed, and true if the client must reconnect.
+func (w *netClientWorker) publish() bool {
+	w.observer.outClientConnected()
+	defer w.observer.outClientDisconnected()
+
+	for batch := range w.qu {
+		if w.closed.Load() {
+			if batch != nil {
+				batch.Cancelled()
 			}
+			return false
+		}
+
+		batch.sentBy(w.metrics)
+		err := w.client.Publish(batch)
+		if err != nil {
+			logp.Err("Failed to publish events: %v", err)
+			// on error return to connect loop
+			return true
 		}
 	}
+	return true
 }
@@ -0,0 +1,70 @@
+package pipeline
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/atomic"
+	"github.com/elastic/beats/libbeat/monitoring"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/publisher"
+	"github.com/elastic/beats/libbeat/publisher/queue"
+	"github.com/elastic/beats/libbeat/publisher/queue/memqueue"
+)
+
+type mockNetworkClient struct {
+	mockClient
+	connectable atomic.Bool
+}
+
+func (c *mockNetworkClient) Connect() error {
+	if !c.connectable.Load() {
+		time.Sleep(10 * time.Millisecond)
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func waitFor(t *testing.T, msg string, cond func() bool) {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("timeout waiting for " + msg)
+}
+
+func TestConnectedOutputsMetric(t *testing.T) {
+	reg := monitoring.NewRegistry()
+	client := &mockNetworkClient{
+		mockClient: mockClient{publish: func(batch publisher.Batch) { batch.ACK() }},
+	}
+
+	queueFactory := func(e queue.Eventer) (queue.Queue, error) {
+		return memqueue.NewBroker(memqueue.Settings{Eventer: e, Events: 64}), nil
+	}
+	group := outputs.Group{Clients: []outputs.Client{client}, BatchSize: 10}
+	p, err := New(beat.Info{}, reg, queueFactory, group, Settings{})
+	require.NoError(t, err)
+	defer p.Close()
+
+	connected := reg.Get("pipeline.output.connected").(*monitoring.Uint)
+
+	pc, err := p.Connect()
+	require.NoError(t, err)
+	defer pc.Close()
+	pc.Publish(beat.Event{Timestamp: time.Now(), Fields: common.MapStr{"id": 1}})
+
+	// The output is not connected while connection attempts fail.
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, uint64(0), connected.Get())
+
+	client.connectable.Store(true)
+	waitFor(t, "connected output", func() bool { return connected.Get() == 1 })
+}
@@ -5,6 +5,7 @@ package pipeline
 
 import (
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -16,7 +17,12 @@ import (
 	"github.com/elastic/beats/libbeat/outputs"
 	"github.com/elastic/beats/libbeat/processors"
 	"github.com/elastic/beats/libbeat/publisher"
+	"github.com/elastic/beats/libbeat/publisher/capture"
+	"github.com/elastic/beats/libbeat/publisher/deadletter"
+	"github.com/elastic/beats/libbeat/publisher/droplog"
 	"github.com/elastic/beats/libbeat/publisher/queue"
+	"github.com/elastic/beats/libbeat/publisher/tap"
+	"github.com/elastic/beats/libbeat/version"
 )
 
 // Pipeline implementation providint all beats publisher functionality.
@@ -48,6 +54,14 @@ type Pipeline struct {
 	waitCloseTimeout time.Duration
 	waitCloser       *waitCloser
 
+	// flush the queue on Close, waiting up to flushTimeout
+	flushTimeout time.Duration
+
+	// warmRestart is set if the events not ACKed on Close are persisted for
+	// the next process
+	warmRestart *warmRestart
+	restoreOnce sync.Once
+
 	// pipeline ack
 	ackMode    pipelineACKMode
 	ackActive  atomic.Bool
@@ -56,6 +70,14 @@ type Pipeline struct {
 	eventSema  *sema
 
 	processors pipelineProcessors
+
+	deadLetter *deadletter.Spool
+
+	capture *capture.Writer
+
+	dropLog *droplog.Log
+
+	processorsReloader *processorsReloader
 }
 
 type pipelineProcessors struct {
@@ -68,6 +90,22 @@ type pipelineProcessors struct {
 
 	processors beat.Processor
 
+	reloadable *reloadableProcessors // reloadable is set if processors are loaded from files
+
+	named map[string]*namedProcessors // named processing pipelines by name
+
+	global *processorFn // global adds the global labels and tags, if configured
+
+	fieldLimits *fieldLimiter // fieldLimits is set if field limits are configured
+
+	capture *capture.Writer // capture is set if event capture is enabled
+
+	tap *tap.Tap // tap is set if the event tap is enabled
+
+	sequence *sequencer // sequence is set if sequence numbers are enabled
+
+	provenance *provenance // provenance is set if provenance entries are enabled
+
 	disabled bool // disabled is set if outputs have been disabled via CLI
 }
 
@@ -79,9 +117,64 @@ type Settings struct {
 
 	WaitCloseMode WaitCloseMode
 
+	// FlushTimeout enables flushing the queue on Close, if set. Close waits up
+	// to FlushTimeout for the outputs to ACK all events, with the queue being
+	// drained in order. Events not ACKed by then are added to the dead-letter
+	// spool, if DeadLetter is set. FlushTimeout takes precedence over
+	// WaitClose of the WaitOnPipelineClose mode.
+	FlushTimeout time.Duration
+
+	// WarmRestartPath enables warm restarts, if set. The events not ACKed on
+	// Close are flushed to the file at WarmRestartPath, instead of the
+	// dead-letter spool, and ACKed once persisted. A pipeline created with
+	// the same path publishes the persisted events again, before the events
+	// of its clients.
+	WarmRestartPath string
+
 	Annotations Annotations
 	Processors  *processors.Processors
 
+	// ProcessorsReload configures the files to load additional processors
+	// from, if set. The processors are run after Processors, and are replaced
+	// when the files change if reloading is enabled.
+	ProcessorsReload *common.Config
+
+	// NamedPipelines configures the named processing pipelines clients can
+	// select on connect. The events of all named pipelines are published to
+	// the same queue and outputs.
+	NamedPipelines map[string]NamedPipeline
+
+	// SequenceField enables stamping a monotonic sequence number into every
+	// event, if set. The field is the name of the event field to store the
+	// sequence number in.
+	SequenceField string
+
+	// Provenance enables appending an entry describing the beat, with its
+	// name, hostname and the time the event was processed, to
+	// `@metadata.provenance` of every event.
+	Provenance bool
+
+	// FieldLimits configures limits on the depth and length of field names,
+	// applied when events are normalized.
+	FieldLimits FieldLimitsConfig
+
+	// DeadLetter spools events the outputs failed to publish permanently, if
+	// set. The pipeline takes ownership of the spool and closes it on Close.
+	DeadLetter *deadletter.Spool
+
+	// Capture writes a sample of the events published to the pipeline, before
+	// any processing, if set. The pipeline takes ownership of the writer and
+	// closes it on Close.
+	Capture *capture.Writer
+
+	// Tap streams a sample of the processed events to its subscribers, if
+	// set.
+	Tap *tap.Tap
+
+	// DropLog keeps the last dropped events and why they were dropped, if
+	// set.
+	DropLog *droplog.Log
+
 	Disabled bool
 }
 
@@ -92,6 +185,10 @@ type Settings struct {
 type Annotations struct {
 	Beat  common.MapStr
 	Event common.EventMetadata
+
+	// Global labels and tags are added to every event before any other fields,
+	// so they can be overwritten by the event, clients and processors.
+	Global GlobalConfig
 }
 
 // WaitCloseMode enumerates the possible behaviors of WaitClose in a pipeline.
@@ -150,18 +247,43 @@ func New(
 		observer:         nilObserver,
 		waitCloseMode:    settings.WaitCloseMode,
 		waitCloseTimeout: settings.WaitClose,
+		flushTimeout:     settings.FlushTimeout,
+		warmRestart:      newWarmRestart(log, settings.WarmRestartPath),
 		processors:       makePipelineProcessors(annotations, processors, disabledOutput),
+		deadLetter:       settings.DeadLetter,
+		capture:          settings.Capture,
+		dropLog:          settings.DropLog,
+	}
+	p.processors.capture = settings.Capture
+	p.processors.tap = settings.Tap
+	p.processors.sequence = newSequencer(settings.SequenceField)
+	p.processors.provenance = newProvenance(beat, settings.Provenance)
+	p.processors.fieldLimits = newFieldLimiter(settings.FieldLimits)
+	p.processors.named = makeNamedProcessors(settings.NamedPipelines)
+	if cfg := settings.ProcessorsReload; cfg != nil {
+		p.processorsReloader, err = newProcessorsReloader(cfg)
+		if err != nil {
+			return nil, err
+		}
+		p.processors.reloadable = p.processorsReloader.processors
 	}
 	p.ackBuilder = &pipelineEmptyACK{p}
 	p.ackActive = atomic.MakeBool(true)
 
 	if metrics != nil {
 		p.observer = newMetricsObserver(metrics)
+		if seq := p.processors.sequence; seq != nil {
+			registerSequenceMetrics(metrics, seq)
+		}
+		if l := p.processors.fieldLimits; l != nil {
+			registerFieldLimitsMetrics(metrics, l)
+		}
 	}
 	p.eventer.observer = p.observer
 	p.eventer.modifyable = true
 
-	if settings.WaitCloseMode == WaitOnPipelineClose && settings.WaitClose > 0 {
+	flush := settings.FlushTimeout > 0 || p.warmRestart != nil
+	if (settings.WaitCloseMode == WaitOnPipelineClose && settings.WaitClose > 0) || flush {
 		p.waitCloser = &waitCloser{}
 
 		// waitCloser decrements counter on queue ACK (not per client)
@@ -174,9 +296,13 @@ func New(
 	}
 	p.eventSema = newSema(p.queue.BufferConfig().Events)
 
-	p.output = newOutputController(log, p.observer, p.queue)
+	p.output = newOutputController(log, p.observer, p.queue, flush)
 	p.output.Set(out)
 
+	if p.processorsReloader != nil {
+		p.processorsReloader.Start()
+	}
+
 	return p, nil
 }
 
@@ -222,12 +348,26 @@ func (p *Pipeline) SetACKHandler(handler beat.PipelineACKHandler) error {
 // Close stops the pipeline, outputs and queue.
 // If WaitClose with WaitOnPipelineClose mode is configured, Close will block
 // for a duration of WaitClose, if there are still active events in the pipeline.
+// If FlushTimeout is configured, Close will block for up to FlushTimeout for
+// all events to be published, and flush the remaining events. If
+// WarmRestartPath is configured, the remaining events are flushed to the warm
+// restart file.
 // Note: clients must be closed before calling Close.
 func (p *Pipeline) Close() error {
 	log := p.logger
 
 	log.Debug("close pipeline")
 
+	timeout := p.waitCloseTimeout
+	if p.flushTimeout > 0 {
+		timeout = p.flushTimeout
+	}
+	deadline := time.Now().Add(timeout)
+
+	if p.processorsReloader != nil {
+		p.processorsReloader.Stop()
+	}
+
 	if p.waitCloser != nil {
 		ch := make(chan struct{})
 		go func() {
@@ -239,7 +379,7 @@ func (p *Pipeline) Close() error {
 		case <-ch:
 			// all events have been ACKed
 
-		case <-time.After(p.waitCloseTimeout):
+		case <-time.After(timeout):
 			// timeout -> close pipeline with pending events
 		}
 
@@ -249,6 +389,9 @@ func (p *Pipeline) Close() error {
 
 	// close output before shutting down queue
 	p.output.Close()
+	if p.flushTimeout > 0 || p.warmRestart != nil {
+		p.output.flush(deadline.Sub(time.Now()), p.deadLetter != nil, p.warmRestart)
+	}
 
 	// shutdown queue
 	err := p.queue.Close()
@@ -256,6 +399,18 @@ func (p *Pipeline) Close() error {
 		log.Err("pipeline queue shutdown error: ", err)
 	}
 
+	if p.deadLetter != nil {
+		if err := p.deadLetter.Close(); err != nil {
+			log.Err("dead-letter spool shutdown error: ", err)
+		}
+	}
+
+	if p.capture != nil {
+		if err := p.capture.Close(); err != nil {
+			log.Err("event capture shutdown error: ", err)
+		}
+	}
+
 	p.observer.cleanup()
 	return nil
 }
@@ -268,7 +423,26 @@ func (p *Pipeline) Connect() (beat.Client, error) {
 // ConnectWith create a new Client for publishing events to the pipeline.
 // The client behavior on close and ACK handling can be configured by setting
 // the appropriate fields in the passed ClientConfig.
+// The events persisted by the previous process for a warm restart are
+// published on the first connect, before the events of any client.
 func (p *Pipeline) ConnectWith(cfg beat.ClientConfig) (beat.Client, error) {
+	p.restoreOnce.Do(func() {
+		if err := p.warmRestart.restore(p); err != nil {
+			p.logger.Errf("Failed to restore events for warm restart: %v", err)
+		}
+	})
+
+	client, err := p.connect(cfg, false)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// connect creates a new client. The events of restore clients have been
+// restored for a warm restart, they are published as is, without running
+// any processor, and are kept in the queue when the client is closed.
+func (p *Pipeline) connect(cfg beat.ClientConfig, restore bool) (*client, error) {
 	var (
 		canDrop      bool
 		dropOnCancel bool
@@ -280,6 +454,10 @@ func (p *Pipeline) ConnectWith(cfg beat.ClientConfig) (beat.Client, error) {
 		return nil, err
 	}
 
+	if name := cfg.ProcessingPipeline; name != "" && p.processors.named[name] == nil {
+		return nil, fmt.Errorf("unknown processing pipeline '%v'", name)
+	}
+
 	p.eventer.mutex.Lock()
 	p.eventer.modifyable = false
 	p.eventer.mutex.Unlock()
@@ -304,13 +482,16 @@ func (p *Pipeline) ConnectWith(cfg beat.ClientConfig) (beat.Client, error) {
 		}
 	}
 
-	processors := p.newProcessorPipeline(cfg)
+	var processors beat.Processor
+	if !restore {
+		processors = p.newProcessorPipeline(cfg)
+	}
 
 	acker := p.makeACKer(processors != nil, &cfg, waitClose)
 	producerCfg := queue.ProducerConfig{
 		// Cancel events from queue if acker is configured
 		// and no pipeline-wide ACK handler is registered.
-		DropOnCancel: dropOnCancel && acker != nil && p.eventer.cb == nil,
+		DropOnCancel: dropOnCancel && acker != nil && p.eventer.cb == nil && !restore,
 	}
 
 	if reportEvents || cfg.Events != nil {
@@ -341,6 +522,8 @@ func (p *Pipeline) ConnectWith(cfg beat.ClientConfig) (beat.Client, error) {
 		eventFlags:   eventFlags,
 		canDrop:      canDrop,
 		reportEvents: reportEvents,
+
+		clientProcessors: cfg.Processor,
 	}
 
 	p.observer.clientConnected()
@@ -379,6 +562,7 @@ func makePipelineProcessors(
 ) pipelineProcessors {
 	p := pipelineProcessors{
 		disabled: disabled,
+		global:   makeGlobalProcessor(annotations.Global),
 	}
 
 	hasProcessors := processors != nil && len(processors.List) > 0
@@ -390,8 +574,11 @@ func makePipelineProcessors(
 		p.processors = tmp
 	}
 
+	p.beatsMeta = common.MapStr{
+		"ecs": common.MapStr{"version": version.ECSVersion},
+	}
 	if meta := annotations.Beat; meta != nil {
-		p.beatsMeta = common.MapStr{"beat": meta}
+		p.beatsMeta["beat"] = meta
 	}
 
 	if em := annotations.Event; len(em.Fields) > 0 {
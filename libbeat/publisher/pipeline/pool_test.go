@@ -0,0 +1,87 @@
+package pipeline
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/publisher"
+	"github.com/elastic/beats/libbeat/version"
+)
+
+// TestPooledFieldsReleasedOnACK checks the ownership rules of
+// common.MapStrPool: the pipeline must not reference the fields of an event
+// after it has been acknowledged, so inputs can reuse them.
+func TestPooledFieldsReleasedOnACK(t *testing.T) {
+	const events = 50
+
+	var mutex sync.Mutex
+	var published []common.MapStr
+	p := newTestPipeline(t, 0, func(batch publisher.Batch) {
+		mutex.Lock()
+		for _, event := range batch.Events() {
+			published = append(published, event.Content.Fields)
+		}
+		mutex.Unlock()
+		batch.ACK()
+	})
+	defer p.Close()
+
+	pool := common.NewMapStrPool(2)
+	acked := make(chan struct{}, events)
+	client, err := p.ConnectWith(beat.ClientConfig{
+		ACKEvents: func(data []interface{}) {
+			for _, private := range data {
+				pool.Put(private.(common.MapStr))
+
+				// Overwrite the released maps, as another event would do.
+				reused := pool.Get()
+				reused["id"] = -1
+				reused["nested"] = common.MapStr{"id": -1}
+
+				acked <- struct{}{}
+			}
+		},
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	for i := 0; i < events; i++ {
+		nested := pool.Get()
+		nested["id"] = i
+
+		fields := pool.Get()
+		fields["id"] = i
+		fields["nested"] = nested
+
+		client.Publish(beat.Event{
+			Timestamp: time.Now(),
+			Fields:    fields,
+			Private:   fields,
+		})
+	}
+
+	for i := 0; i < events; i++ {
+		select {
+		case <-acked:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for ACKs, got %v", i)
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	require.Len(t, published, events)
+	for i, fields := range published {
+		assert.Equal(t, common.MapStr{
+			"id":     i,
+			"nested": common.MapStr{"id": i},
+			"ecs":    common.MapStr{"version": version.ECSVersion},
+		}, fields)
+	}
+}
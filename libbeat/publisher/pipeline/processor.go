@@ -10,6 +10,8 @@ import (
 	"github.com/elastic/beats/libbeat/logp"
 	"github.com/elastic/beats/libbeat/outputs/codec/json"
 	"github.com/elastic/beats/libbeat/processors"
+	"github.com/elastic/beats/libbeat/publisher/capture"
+	"github.com/elastic/beats/libbeat/publisher/tap"
 )
 
 type program struct {
@@ -29,16 +31,25 @@ type processorFn struct {
 //
 // Pipeline (C=client, P=pipeline)
 //
+//  0. (P) (if enabled) capture raw event
 //  1. (P) generalize/normalize event
+//     (P) (if configured) enforce field depth and name length limits
+//     (P) add global labels + tags
 //  2. (C) add Meta from client Config to event.Meta
 //  3. (C) add Fields from client config to event.Fields
 //  4. (P) add pipeline fields + tags
+//     (P) (if selected) add named pipeline fields + tags
 //  5. (C) add client fields + tags
 //  6. (C) client processors list
-//  7. (P) add beats metadata
+//  7. (P) add beats metadata and ECS version
+//     (P) (if selected) named pipeline processors list
 //  8. (P) pipeline processors list
-//  9. (P) (if publish/debug enabled) log event
-// 10. (P) (if output disabled) dropEvent
+//     (P) (if configured) reloadable processors list
+//  9. (P) (if enabled) add sequence number
+//     (P) (if enabled) add provenance entry
+// 10. (P) (if enabled) tap processed event
+// 11. (P) (if publish/debug enabled) log event
+// 12. (P) (if output disabled) dropEvent
 func (p *Pipeline) newProcessorPipeline(
 	config beat.ClientConfig,
 ) beat.Processor {
@@ -52,29 +63,54 @@ func (p *Pipeline) newProcessorPipeline(
 
 		// pipeline global
 		global = p.processors
+
+		// named pipeline selected by the client
+		named = global.named[config.ProcessingPipeline]
 	)
+	if named == nil {
+		named = &namedProcessors{}
+	}
+
+	needsCopy := localProcessors != nil || global.processors != nil ||
+		global.reloadable != nil || named.processors != nil
 
-	needsCopy := localProcessors != nil || global.processors != nil
+	// setup 0: capture the raw event, before any processing (P)
+	if c := global.capture; c != nil {
+		processors.add(makeCaptureProcessor(c))
+	}
 
 	// setup 1: generalize/normalize output (P)
 	processors.add(generalizeProcessor)
 
+	// setup 1: drop or tag fields exceeding the field limits (P)
+	if l := global.fieldLimits; l != nil {
+		processors.add(makeFieldLimitsProcessor(l))
+	}
+
+	// setup 1: add global labels and tags, keeping values set by the event (P)
+	if global.global != nil {
+		processors.add(global.global)
+	}
+
 	// setup 2: add Meta from client config (C)
 	if m := clientMeta; len(m) > 0 {
 		processors.add(clientEventMeta(m, needsCopy))
 	}
 
-	// setup 4, 5: pipeline tags + client tags
+	// setup 4, 5: pipeline tags + named pipeline tags + client tags
 	var tags []string
 	tags = append(tags, global.tags...)
+	tags = append(tags, named.tags...)
 	tags = append(tags, config.EventMetadata.Tags...)
 	if len(tags) > 0 {
 		processors.add(makeAddTagsProcessor("tags", tags))
 	}
 
-	// setup 3, 4, 5: client config fields + pipeline fields + client fields
+	// setup 3, 4, 5: client config fields + pipeline fields + named pipeline
+	// fields + client fields
 	fields := config.Fields.Clone()
 	fields.DeepUpdate(global.fields)
+	fields.DeepUpdate(named.fields)
 	if em := config.EventMetadata; len(em.Fields) > 0 {
 		common.MergeFields(fields, em.Fields.Clone(), em.FieldsUnderRoot)
 	}
@@ -85,20 +121,45 @@ func (p *Pipeline) newProcessorPipeline(
 	// setup 5: client processor list
 	processors.add(localProcessors)
 
-	// setup 6: add beats metadata
+	// setup 6: add beats metadata and ECS version
 	if meta := global.beatsMeta; len(meta) > 0 {
 		processors.add(makeAddFieldsProcessor("beatsMeta", meta, needsCopy))
 	}
 
+	// setup 6: named pipeline processors list
+	processors.add(named.processors)
+
 	// setup 7: pipeline processors list
 	processors.add(global.processors)
 
-	// setup 9: debug print final event (P)
+	// setup 8: reloadable pipeline processors list, the current chain is
+	// selected per event
+	if r := global.reloadable; r != nil {
+		processors.add(r)
+	}
+
+	// setup 9: stamp sequence number, after all processors might have dropped
+	// the event (P)
+	if seq := global.sequence; seq != nil {
+		processors.add(makeSequenceProcessor(seq))
+	}
+
+	// setup 9: append the provenance entry of the beat (P)
+	if prov := global.provenance; prov != nil {
+		processors.add(makeProvenanceProcessor(prov))
+	}
+
+	// setup 10: stream a sample of the processed events to the tap (P)
+	if t := global.tap; t != nil {
+		processors.add(makeTapProcessor(t))
+	}
+
+	// setup 11: debug print final event (P)
 	if logp.IsDebug("publish") {
 		processors.add(debugPrintProcessor(p.beatInfo))
 	}
 
-	// setup 10: drop all events if outputs are disabled (P)
+	// setup 12: drop all events if outputs are disabled (P)
 	if global.disabled {
 		processors.add(dropDisabledProcessor)
 	}
@@ -182,6 +243,20 @@ var generalizeProcessor = newProcessor("generalizeEvent", func(event *beat.Event
 	return event, nil
 })
 
+func makeCaptureProcessor(w *capture.Writer) *processorFn {
+	return newAnnotateProcessor("capture", func(event *beat.Event) {
+		if err := w.Add(*event); err != nil {
+			logp.Debug("publish", "failed to capture event: %v", err)
+		}
+	})
+}
+
+func makeTapProcessor(t *tap.Tap) *processorFn {
+	return newAnnotateProcessor("tap", func(event *beat.Event) {
+		t.Add(*event)
+	})
+}
+
 var dropDisabledProcessor = newProcessor("dropDisabled", func(event *beat.Event) (*beat.Event, error) {
 	return nil, nil
 })
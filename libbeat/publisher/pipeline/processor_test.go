@@ -0,0 +1,69 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/publisher/tap"
+	"github.com/elastic/beats/libbeat/version"
+)
+
+func TestProcessorECSVersion(t *testing.T) {
+	tests := map[string]Annotations{
+		"without beat metadata": {},
+		"with beat metadata": {
+			Beat: common.MapStr{"name": "test", "version": "7.0.0"},
+		},
+	}
+
+	for name, annotations := range tests {
+		p := &Pipeline{processors: makePipelineProcessors(annotations, nil, false)}
+		processor := p.newProcessorPipeline(beat.ClientConfig{})
+
+		event, err := processor.Run(&beat.Event{Fields: common.MapStr{
+			"message": "hello",
+			"ecs":     common.MapStr{"version": "0.1.0"},
+		}})
+		require.NoError(t, err, name)
+
+		ecsVersion, err := event.GetValue("ecs.version")
+		require.NoError(t, err, name)
+		assert.Equal(t, version.ECSVersion, ecsVersion, name)
+		if annotations.Beat != nil {
+			assert.Equal(t, annotations.Beat, event.Fields["beat"], name)
+		}
+	}
+}
+
+func TestProcessorTap(t *testing.T) {
+	config := tap.DefaultConfig
+	config.SampleRate = 1
+	eventTap := tap.New(beat.Info{Beat: "test"}, config)
+	s := eventTap.Subscribe()
+	defer s.Close()
+
+	p := &Pipeline{processors: makePipelineProcessors(Annotations{
+		Event: common.EventMetadata{Fields: common.MapStr{"env": "test"}},
+	}, nil, false)}
+	p.processors.tap = eventTap
+	processor := p.newProcessorPipeline(beat.ClientConfig{})
+
+	_, err := processor.Run(&beat.Event{Fields: common.MapStr{"message": "hello"}})
+	require.NoError(t, err)
+
+	// The tap receives the event after the pipeline fields have been added.
+	select {
+	case line := <-s.Events():
+		var event map[string]interface{}
+		require.NoError(t, json.Unmarshal(line, &event))
+		assert.Equal(t, "hello", event["message"])
+		assert.Equal(t, map[string]interface{}{"env": "test"}, event["fields"])
+	default:
+		t.Fatal("no event received from the tap")
+	}
+}
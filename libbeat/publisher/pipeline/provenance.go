@@ -0,0 +1,92 @@
+package pipeline
+
+import (
+	"time"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// ProvenanceConfig configures the provenance entries appended to
+// `@metadata.provenance` of every published event.
+type ProvenanceConfig struct {
+	Enabled bool `config:"enabled"`
+}
+
+const provenanceKey = "provenance"
+
+// provenance appends an entry describing the beat to the provenance of the
+// events, so the beats an event passed through can be traced when events are
+// forwarded from beat to beat, for example via Logstash or Kafka.
+type provenance struct {
+	beat     string
+	name     string
+	hostname string
+	id       string
+}
+
+func newProvenance(info beat.Info, enabled bool) *provenance {
+	if !enabled {
+		return nil
+	}
+	return &provenance{
+		beat:     info.Beat,
+		name:     info.Name,
+		hostname: info.Hostname,
+		id:       info.UUID.String(),
+	}
+}
+
+// stamp appends the entry of the beat to the provenance of the event. The
+// entry is not appended again if the event already passed through the beat.
+// The metadata of the event is copied, as it might be shared with other
+// events.
+func (p *provenance) stamp(event *beat.Event, now time.Time) {
+	var entries []interface{}
+	switch existing := event.Meta[provenanceKey].(type) {
+	case []interface{}:
+		entries = existing
+	case []common.MapStr:
+		for _, entry := range existing {
+			entries = append(entries, entry)
+		}
+	}
+
+	for _, entry := range entries {
+		if p.isEntry(entry) {
+			return
+		}
+	}
+
+	meta := make(common.MapStr, len(event.Meta)+1)
+	for k, v := range event.Meta {
+		meta[k] = v
+	}
+	meta[provenanceKey] = append(append([]interface{}(nil), entries...), common.MapStr{
+		"beat":      p.beat,
+		"name":      p.name,
+		"hostname":  p.hostname,
+		"id":        p.id,
+		"timestamp": now.UTC(),
+	})
+	event.Meta = meta
+}
+
+// isEntry returns true if the provenance entry was added by the beat. Entries
+// of events decoded from JSON are plain maps.
+func (p *provenance) isEntry(entry interface{}) bool {
+	var id interface{}
+	switch e := entry.(type) {
+	case common.MapStr:
+		id = e["id"]
+	case map[string]interface{}:
+		id = e["id"]
+	}
+	return id == p.id
+}
+
+func makeProvenanceProcessor(p *provenance) *processorFn {
+	return newAnnotateProcessor("provenance", func(event *beat.Event) {
+		p.stamp(event, time.Now())
+	})
+}
@@ -0,0 +1,117 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	jsonCodec "github.com/elastic/beats/libbeat/outputs/codec/json"
+)
+
+func newProvenancePipeline(info beat.Info) beat.Processor {
+	p := &Pipeline{
+		beatInfo:   info,
+		processors: makePipelineProcessors(Annotations{}, nil, false),
+	}
+	p.processors.provenance = newProvenance(info, true)
+	return p.newProcessorPipeline(beat.ClientConfig{})
+}
+
+// forward encodes the event like an output and decodes it like a beat
+// receiving events from another beat, for example via Kafka.
+func forward(t *testing.T, event *beat.Event) *beat.Event {
+	encoded, err := jsonCodec.New(false, "7.0.0").Encode("beat", event)
+	require.NoError(t, err)
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+
+	meta, _ := decoded["@metadata"].(map[string]interface{})
+	delete(decoded, "@metadata")
+	delete(decoded, "@timestamp")
+	return &beat.Event{Timestamp: event.Timestamp, Meta: meta, Fields: decoded}
+}
+
+func provenanceIDs(t *testing.T, event *beat.Event) []string {
+	entries, ok := event.Meta["provenance"].([]interface{})
+	require.True(t, ok, "provenance is missing")
+
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		switch e := entry.(type) {
+		case common.MapStr:
+			ids[i] = e["id"].(string)
+			assert.False(t, e["timestamp"].(time.Time).IsZero())
+		case map[string]interface{}:
+			ids[i] = e["id"].(string)
+			assert.NotEmpty(t, e["timestamp"])
+		default:
+			t.Fatalf("invalid provenance entry %#v", entry)
+		}
+	}
+	return ids
+}
+
+func TestProvenanceHops(t *testing.T) {
+	hops := []beat.Info{
+		{Beat: "filebeat", Name: "shipper", Hostname: "web-1", UUID: uuid.NewV4()},
+		{Beat: "filebeat", Name: "relay", Hostname: "relay-1", UUID: uuid.NewV4()},
+		{Beat: "metricbeat", Name: "collector", Hostname: "collector-1", UUID: uuid.NewV4()},
+	}
+
+	event := &beat.Event{Timestamp: time.Now(), Fields: common.MapStr{"message": "hello"}}
+	var expected []string
+	for i, info := range hops {
+		if i > 0 {
+			event = forward(t, event)
+		}
+
+		var err error
+		event, err = newProvenancePipeline(info).Run(event)
+		require.NoError(t, err)
+
+		expected = append(expected, info.UUID.String())
+		assert.Equal(t, expected, provenanceIDs(t, event))
+	}
+
+	last := event.Meta["provenance"].([]interface{})[2].(common.MapStr)
+	assert.Equal(t, "metricbeat", last["beat"])
+	assert.Equal(t, "collector", last["name"])
+	assert.Equal(t, "collector-1", last["hostname"])
+
+	// Events passing through a beat again, like events forwarded in a loop,
+	// are not stamped twice.
+	event, err := newProvenancePipeline(hops[1]).Run(forward(t, event))
+	require.NoError(t, err)
+	assert.Equal(t, expected, provenanceIDs(t, event))
+}
+
+func TestProvenanceCopiesMeta(t *testing.T) {
+	info := beat.Info{Beat: "filebeat", UUID: uuid.NewV4()}
+	processor := newProvenancePipeline(info)
+
+	// Clients might publish events sharing their metadata.
+	meta := common.MapStr{"pipeline": "logs"}
+	for i := 0; i < 2; i++ {
+		event, err := processor.Run(&beat.Event{Meta: meta, Fields: common.MapStr{"i": i}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{info.UUID.String()}, provenanceIDs(t, event))
+		assert.Equal(t, "logs", event.Meta["pipeline"])
+	}
+	assert.Equal(t, common.MapStr{"pipeline": "logs"}, meta)
+}
+
+func TestProvenanceDisabled(t *testing.T) {
+	assert.Nil(t, newProvenance(beat.Info{}, false))
+
+	p := &Pipeline{processors: makePipelineProcessors(Annotations{}, nil, false)}
+	event, err := p.newProcessorPipeline(beat.ClientConfig{}).Run(&beat.Event{Fields: common.MapStr{"message": "hello"}})
+	require.NoError(t, err)
+	assert.NotContains(t, event.Meta, "provenance")
+}
@@ -0,0 +1,192 @@
+package pipeline
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/cfgfile"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/monitoring"
+	"github.com/elastic/beats/libbeat/paths"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+var processorsReloads = monitoring.NewInt(nil, "libbeat.config.processors.reloads")
+
+// reloadableProcessors is the processor chain loaded from the files configured
+// by `config.processors`. It is run after the global processors. The chain is
+// replaced atomically on reload, each event is processed by exactly one
+// version of the chain.
+type reloadableProcessors struct {
+	chain atomic.Value // *program
+}
+
+func newReloadableProcessors() *reloadableProcessors {
+	r := &reloadableProcessors{}
+	r.set(nil)
+	return r
+}
+
+func (r *reloadableProcessors) set(procs *processors.Processors) {
+	chain := &program{title: "reloadable"}
+	if procs != nil {
+		for _, p := range procs.List {
+			chain.add(p)
+		}
+	}
+	r.chain.Store(chain)
+}
+
+func (r *reloadableProcessors) current() *program {
+	return r.chain.Load().(*program)
+}
+
+func (r *reloadableProcessors) Run(event *beat.Event) (*beat.Event, error) {
+	return r.current().Run(event)
+}
+
+func (r *reloadableProcessors) String() string {
+	return r.current().String()
+}
+
+// processorsReloader loads the reloadable processors from the files matched by
+// the configured path, and replaces the chain if the files change. Inputs and
+// connected clients are not affected by a reload. If the files can not be
+// loaded, the current chain is kept.
+type processorsReloader struct {
+	config     cfgfile.DynamicConfig
+	path       string
+	watcher    *cfgfile.GlobWatcher
+	processors *reloadableProcessors
+
+	// current are the processors loaded, closed once replaced.
+	current *processors.Processors
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newProcessorsReloader(cfg *common.Config) (*processorsReloader, error) {
+	config := cfgfile.DefaultDynamicConfig
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, err
+	}
+	if config.Path == "" {
+		return nil, fmt.Errorf("config.processors.path must be set")
+	}
+
+	path := config.Path
+	if !filepath.IsAbs(path) {
+		path = paths.Resolve(paths.Config, path)
+	}
+
+	r := &processorsReloader{
+		config:     config,
+		path:       path,
+		watcher:    cfgfile.NewGlobWatcher(path),
+		processors: newReloadableProcessors(),
+		done:       make(chan struct{}),
+	}
+
+	// If reloading is enabled, errors are ignored, as the files may be fixed
+	// afterwards.
+	if err := r.reload(); err != nil && !config.Reload.Enabled {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Start starts watching the files for changes, if reloading is enabled.
+func (r *processorsReloader) Start() {
+	if !r.config.Reload.Enabled {
+		return
+	}
+
+	logp.Info("Processors reloader started for %v", r.path)
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.run()
+	}()
+}
+
+// Stop stops watching the files, and closes the current processors.
+func (r *processorsReloader) Stop() {
+	close(r.done)
+	r.wg.Wait()
+	r.current.Close()
+}
+
+func (r *processorsReloader) run() {
+	for {
+		select {
+		case <-r.done:
+			logp.Info("Processors reloader stopped")
+			return
+		case <-time.After(r.config.Reload.Period):
+		}
+
+		if err := r.reload(); err != nil {
+			logp.Err("Error reloading processors, keeping the current processors: %v", err)
+		}
+	}
+}
+
+// reload replaces the processor chain if the files changed since the last
+// scan.
+func (r *processorsReloader) reload() error {
+	files, updated, err := r.watcher.Scan()
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return nil
+	}
+
+	procs, err := loadProcessorFiles(files)
+	if err != nil {
+		// Force the files to be loaded again on the next scan.
+		r.watcher = cfgfile.NewGlobWatcher(r.path)
+		return err
+	}
+
+	r.processors.set(procs)
+	// Events still running through the previous processors are completed.
+	r.current.Close()
+	r.current = procs
+	processorsReloads.Inc()
+	logp.Info("Loaded %v processors from %v", len(procs.List), r.path)
+	return nil
+}
+
+// loadProcessorFiles creates the processors configured in the files. Each file
+// contains a list of processors, in the format of the `processors` setting.
+// The processors of all files are combined in the order of the files.
+func loadProcessorFiles(files []string) (*processors.Processors, error) {
+	var config processors.PluginConfig
+	for _, file := range files {
+		list, err := cfgfile.LoadList(file)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range list {
+			var processor map[string]*common.Config
+			if err := c.Unpack(&processor); err != nil {
+				return nil, fmt.Errorf("error reading processor from file %s: %v", file, err)
+			}
+			config = append(config, processor)
+		}
+	}
+
+	procs, err := processors.New(config)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing processors: %v", err)
+	}
+	return procs, nil
+}
@@ -0,0 +1,302 @@
+package pipeline
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/processors"
+	"github.com/elastic/beats/libbeat/publisher"
+	"github.com/elastic/beats/libbeat/publisher/queue"
+	"github.com/elastic/beats/libbeat/publisher/queue/memqueue"
+)
+
+func init() {
+	processors.RegisterPlugin("test_set_fields", newTestSetFields)
+}
+
+// testSetFields sets the configured fields in each event.
+type testSetFields struct {
+	fields common.MapStr
+	closed int32
+}
+
+func newTestSetFields(c *common.Config) (processors.Processor, error) {
+	config := struct {
+		Fields common.MapStr `config:"fields"`
+	}{}
+	if err := c.Unpack(&config); err != nil {
+		return nil, err
+	}
+	return &testSetFields{fields: config.Fields}, nil
+}
+
+func (p *testSetFields) Run(event *beat.Event) (*beat.Event, error) {
+	event.Fields.DeepUpdate(p.fields.Clone())
+	return event, nil
+}
+
+func (p *testSetFields) String() string { return fmt.Sprintf("test_set_fields=%v", p.fields) }
+
+func (p *testSetFields) Close() error {
+	atomic.AddInt32(&p.closed, 1)
+	return nil
+}
+
+func writeProcessorsFile(t *testing.T, path, chain string) {
+	content := fmt.Sprintf("- test_set_fields:\n    fields:\n      chain: %v\n", chain)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+}
+
+// newReloadTestPipeline creates a pipeline with reloadable processors loaded
+// from dir. Published events are send to the returned channel.
+func newReloadTestPipeline(t *testing.T, dir string) (*Pipeline, chan common.MapStr) {
+	queueFactory := func(e queue.Eventer) (queue.Queue, error) {
+		return memqueue.NewBroker(memqueue.Settings{Eventer: e, Events: 16}), nil
+	}
+
+	published := make(chan common.MapStr, 16)
+	out := testOutputGroup(0, func(batch publisher.Batch) {
+		for _, event := range batch.Events() {
+			published <- event.Content.Fields
+		}
+		batch.ACK()
+	})
+
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"path":           filepath.Join(dir, "*.yml"),
+		"reload.enabled": true,
+		"reload.period":  "20ms",
+	})
+	require.NoError(t, err)
+
+	p, err := New(beat.Info{}, nil, queueFactory, out, Settings{ProcessorsReload: cfg})
+	require.NoError(t, err)
+	return p, published
+}
+
+func publishAndWait(t *testing.T, client beat.Client, published chan common.MapStr, message string) common.MapStr {
+	client.Publish(beat.Event{
+		Timestamp: time.Now(),
+		Fields:    common.MapStr{"message": message},
+	})
+
+	select {
+	case fields := <-published:
+		assert.Equal(t, message, fields["message"])
+		return fields
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for published event")
+		return nil
+	}
+}
+
+// waitReloaded waits for the reload counter to reach reloads. The counter is
+// incremented after the chain has been replaced.
+func waitReloaded(t *testing.T, reloads int64) {
+	waitFor(t, "processors reload", func() bool {
+		return processorsReloads.Get() >= reloads
+	})
+}
+
+func TestReloadableProcessorsUpdateChain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "processors")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "enrich.yml")
+	writeProcessorsFile(t, path, "a")
+
+	p, published := newReloadTestPipeline(t, dir)
+	defer p.Close()
+
+	client, err := p.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+
+	fields := publishAndWait(t, client, published, "first")
+	assert.Equal(t, "a", fields["chain"])
+
+	// Events published by the already connected client use the new chain.
+	reloads := processorsReloads.Get()
+	writeProcessorsFile(t, path, "b")
+	waitReloaded(t, reloads+1)
+
+	fields = publishAndWait(t, client, published, "second")
+	assert.Equal(t, "b", fields["chain"])
+
+	// Removing all files removes the reloadable processors.
+	reloads = processorsReloads.Get()
+	require.NoError(t, os.Remove(path))
+	waitReloaded(t, reloads+1)
+
+	fields = publishAndWait(t, client, published, "third")
+	assert.NotContains(t, fields, "chain")
+}
+
+func TestReloadableProcessorsClosedOnReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "processors")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "enrich.yml")
+	writeProcessorsFile(t, path, "a")
+
+	p, _ := newReloadTestPipeline(t, dir)
+	first := p.processorsReloader.current.List[0].(*testSetFields)
+
+	// The processors replaced by a reload are closed.
+	reloads := processorsReloads.Get()
+	writeProcessorsFile(t, path, "b")
+	waitReloaded(t, reloads+1)
+
+	second := p.processorsReloader.current.List[0].(*testSetFields)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&first.closed))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&second.closed))
+
+	// The current processors are closed with the pipeline.
+	require.NoError(t, p.Close())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&first.closed))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&second.closed))
+}
+
+func TestReloadableProcessorsKeepChainOnError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "processors")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "enrich.yml")
+	writeProcessorsFile(t, path, "a")
+
+	p, published := newReloadTestPipeline(t, dir)
+	defer p.Close()
+
+	client, err := p.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+
+	content := "- unknown_processor:\n    field: value\n"
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+
+	// Give the reloader a few periods to fail loading the file.
+	time.Sleep(100 * time.Millisecond)
+
+	fields := publishAndWait(t, client, published, "first")
+	assert.Equal(t, "a", fields["chain"])
+}
+
+func TestReloadableProcessorsOrderAcrossFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "processors")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// The processor of the second file runs before the processor of the
+	// first file, the last one setting the chain.
+	defaults := "- test_set_fields:\n    id: defaults\n    fields:\n      chain: defaults\n"
+	override := "- test_set_fields:\n    before: defaults\n    fields:\n      chain: override\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "10-defaults.yml"), []byte(defaults), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "20-override.yml"), []byte(override), 0600))
+
+	procs, err := loadProcessorFiles([]string{
+		filepath.Join(dir, "10-defaults.yml"),
+		filepath.Join(dir, "20-override.yml"),
+	})
+	require.NoError(t, err)
+
+	event := procs.Run(&beat.Event{Fields: common.MapStr{}})
+	assert.Equal(t, "defaults", event.Fields["chain"])
+}
+
+func TestReloadableProcessorsInvalidWithoutReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "processors")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	content := "- unknown_processor:\n    field: value\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "enrich.yml"), []byte(content), 0600))
+
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"path": filepath.Join(dir, "*.yml"),
+	})
+	require.NoError(t, err)
+
+	_, err = newProcessorsReloader(cfg)
+	assert.Error(t, err)
+}
+
+func TestReloadableProcessorsConsistentChain(t *testing.T) {
+	makeChain := func(value string) *processors.Processors {
+		return &processors.Processors{List: []processors.Processor{
+			&testSetFields{fields: common.MapStr{"first": value}},
+			&testSetFields{fields: common.MapStr{"second": value}},
+		}}
+	}
+
+	reloadable := newReloadableProcessors()
+	reloadable.set(makeChain("a"))
+
+	p := &Pipeline{processors: makePipelineProcessors(Annotations{}, nil, false)}
+	p.processors.reloadable = reloadable
+	processor := p.newProcessorPipeline(beat.ClientConfig{})
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			reloadable.set(makeChain(fmt.Sprintf("chain-%v", i)))
+		}
+	}()
+
+	// Each event is processed by exactly one version of the chain.
+	for i := 0; i < 1000; i++ {
+		event, err := processor.Run(&beat.Event{Fields: common.MapStr{"message": "test"}})
+		require.NoError(t, err)
+		if !assert.Equal(t, event.Fields["first"], event.Fields["second"]) {
+			break
+		}
+	}
+
+	close(done)
+	wg.Wait()
+}
+
+func TestClientProcessorsClosedWithClient(t *testing.T) {
+	queueFactory := func(e queue.Eventer) (queue.Queue, error) {
+		return memqueue.NewBroker(memqueue.Settings{Eventer: e, Events: 16}), nil
+	}
+	out := testOutputGroup(0, func(batch publisher.Batch) { batch.ACK() })
+	p, err := New(beat.Info{}, nil, queueFactory, out, Settings{})
+	require.NoError(t, err)
+	defer p.Close()
+
+	cfg, err := common.NewConfigFrom(map[string]interface{}{"fields": common.MapStr{"a": 1}})
+	require.NoError(t, err)
+	procs, err := processors.New(processors.PluginConfig{{"test_set_fields": cfg}})
+	require.NoError(t, err)
+	processor := procs.List[0].(*testSetFields)
+
+	client, err := p.ConnectWith(beat.ClientConfig{Processor: procs})
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&processor.closed))
+
+	require.NoError(t, client.Close())
+	require.NoError(t, client.Close())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&processor.closed))
+}
@@ -16,7 +16,8 @@ type retryer struct {
 	logger   *logp.Logger
 	observer outputObserver
 
-	done chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
 
 	consumer *eventConsumer
 
@@ -62,6 +63,7 @@ func newRetryer(
 		logger:   log,
 		observer: observer,
 		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
 		sig:      make(chan retryerSignal, 3),
 		in:       retryQueue(make(chan batchEvent, 3)),
 		out:      out,
@@ -71,8 +73,11 @@ func newRetryer(
 	return r
 }
 
+// close stops the retryer, and waits for the retryer to return. Batches
+// retried or cancelled afterwards are not forwarded anymore.
 func (r *retryer) close() {
 	close(r.done)
+	<-r.stopped
 }
 
 func (r *retryer) sigOutputAdded() {
@@ -91,11 +96,17 @@ func (r *retryer) updOutput(ch workQueue) {
 }
 
 func (r *retryer) retry(b *Batch) {
-	r.in <- batchEvent{tag: retryBatch, batch: b}
+	select {
+	case r.in <- batchEvent{tag: retryBatch, batch: b}:
+	case <-r.done:
+	}
 }
 
 func (r *retryer) cancelled(b *Batch) {
-	r.in <- batchEvent{tag: cancelledBatch, batch: b}
+	select {
+	case r.in <- batchEvent{tag: cancelledBatch, batch: b}:
+	case <-r.done:
+	}
 }
 
 func (r *retryer) loop() {
@@ -111,6 +122,7 @@ func (r *retryer) loop() {
 		log = r.logger
 	)
 
+	defer close(r.stopped)
 	for {
 		select {
 		case <-r.done:
@@ -180,6 +192,9 @@ func (r *retryer) loop() {
 			switch sig.tag {
 			case sigRetryerUpdateOutput:
 				r.out = sig.channel
+				if active != nil {
+					out = r.out
+				}
 			case sigRetryerOutputAdded:
 				numOutputs++
 			case sigRetryerOutputRemoved:
@@ -208,6 +223,8 @@ func decBatch(batch *Batch) {
 	for _, event := range batch.events {
 		if (event.Flags & publisher.GuaranteedSend) == publisher.GuaranteedSend {
 			events = append(events, event)
+		} else {
+			event.Fail()
 		}
 	}
 	batch.events = events
@@ -0,0 +1,70 @@
+package pipeline
+
+import (
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common/atomic"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/monitoring"
+)
+
+// SequenceConfig configures the event sequence numbers stamped into every
+// published event.
+type SequenceConfig struct {
+	Enabled bool   `config:"enabled"`
+	Field   string `config:"field"`
+}
+
+const defaultSequenceField = "event.sequence"
+
+// field returns the event field to store sequence numbers in, or an empty
+// string if sequence numbers are disabled.
+func (c SequenceConfig) field() string {
+	if !c.Enabled {
+		return ""
+	}
+	if c.Field == "" {
+		return defaultSequenceField
+	}
+	return c.Field
+}
+
+// sequencer generates monotonic sequence numbers, unique within a pipeline
+// instance. The first event is stamped with 1, so gaps in the numbers observed
+// downstream indicate events lost after processing.
+type sequencer struct {
+	field   string
+	counter atomic.Uint64
+}
+
+func newSequencer(field string) *sequencer {
+	if field == "" {
+		return nil
+	}
+	return &sequencer{field: field}
+}
+
+func (s *sequencer) next() uint64 { return s.counter.Inc() }
+
+// last returns the most recent sequence number handed out.
+func (s *sequencer) last() uint64 { return s.counter.Load() }
+
+func makeSequenceProcessor(s *sequencer) *processorFn {
+	return newAnnotateProcessor("sequence", func(event *beat.Event) {
+		if _, err := event.PutValue(s.field, s.next()); err != nil {
+			logp.Debug("publish", "failed to add event sequence number: %v", err)
+		}
+	})
+}
+
+// registerSequenceMetrics reports the last sequence number in the pipeline
+// metrics, so downstream consumers can compare it against the events received.
+func registerSequenceMetrics(metrics *monitoring.Registry, s *sequencer) {
+	reg := metrics.GetRegistry("pipeline")
+	if reg == nil {
+		reg = metrics.NewRegistry("pipeline")
+	}
+
+	monitoring.NewFunc(reg, "events.sequence", func(_ monitoring.Mode, v monitoring.Visitor) {
+		v.OnInt(int64(s.last()))
+	})
+}
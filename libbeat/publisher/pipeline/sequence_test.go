@@ -0,0 +1,103 @@
+package pipeline
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/monitoring"
+	"github.com/elastic/beats/libbeat/publisher"
+	"github.com/elastic/beats/libbeat/publisher/queue"
+	"github.com/elastic/beats/libbeat/publisher/queue/memqueue"
+)
+
+func TestSequenceConcurrentPublish(t *testing.T) {
+	const (
+		clients   = 8
+		perClient = 500
+	)
+
+	var (
+		mutex    sync.Mutex
+		received []publisher.Event
+		wg       sync.WaitGroup
+	)
+	wg.Add(clients * perClient)
+
+	out := func(batch publisher.Batch) {
+		mutex.Lock()
+		received = append(received, batch.Events()...)
+		mutex.Unlock()
+		for range batch.Events() {
+			wg.Done()
+		}
+		batch.ACK()
+	}
+
+	queueFactory := func(e queue.Eventer) (queue.Queue, error) {
+		return memqueue.NewBroker(memqueue.Settings{Eventer: e, Events: 128}), nil
+	}
+	p, err := New(beat.Info{}, monitoring.NewRegistry(), queueFactory,
+		testOutputGroup(3, out), Settings{SequenceField: "event.sequence"})
+	require.NoError(t, err)
+	defer p.Close()
+
+	var clientsWG sync.WaitGroup
+	for c := 0; c < clients; c++ {
+		clientsWG.Add(1)
+		go func(c int) {
+			defer clientsWG.Done()
+
+			client, err := p.Connect()
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer client.Close()
+
+			for i := 0; i < perClient; i++ {
+				client.Publish(beat.Event{
+					Timestamp: time.Now(),
+					Fields:    common.MapStr{"client": c, "i": i},
+				})
+			}
+		}(c)
+	}
+	clientsWG.Wait()
+	wg.Wait()
+
+	seen := map[uint64]bool{}
+	last := map[int]uint64{}
+	for _, event := range received {
+		fields := event.Content.Fields
+		seq, err := fields.GetValue("event.sequence")
+		require.NoError(t, err)
+
+		n := seq.(uint64)
+		assert.False(t, seen[n], "duplicate sequence number %v", n)
+		seen[n] = true
+
+		// sequence numbers must be monotonic per publishing client
+		c := fields["client"].(int)
+		assert.True(t, n > last[c], "sequence number %v after %v", n, last[c])
+		last[c] = n
+	}
+
+	// all sequence numbers from 1 to N must be used without gaps
+	assert.Len(t, seen, clients*perClient)
+	for i := uint64(1); i <= clients*perClient; i++ {
+		assert.True(t, seen[i], "missing sequence number %v", i)
+	}
+
+	assert.Equal(t, uint64(clients*perClient), p.processors.sequence.last())
+}
+
+func TestSequenceDisabled(t *testing.T) {
+	assert.Nil(t, newSequencer(SequenceConfig{}.field()))
+	assert.Equal(t, "event.sequence", SequenceConfig{Enabled: true}.field())
+	assert.Equal(t, "seq", SequenceConfig{Enabled: true, Field: "seq"}.field())
+}
@@ -0,0 +1,129 @@
+package pipeline
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/beat"
+)
+
+var (
+	// ErrPublishTimeout is returned by PublishSync, if not all events have
+	// been completed before the timeout.
+	ErrPublishTimeout = errors.New("timeout waiting for events to be published")
+
+	// ErrSyncClientClosed is returned by PublishSync, if the client is closed
+	// before all events have been completed.
+	ErrSyncClientClosed = errors.New("client closed before events have been published")
+)
+
+// SyncClient publishes batches of events and waits for the publishing status
+// of each event, based on the events OnComplete callbacks. It can be used to
+// report the outcome of publishing to the sender of the events, like the
+// response to an HTTP request.
+type SyncClient struct {
+	client  beat.Client
+	timeout time.Duration
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// Result is the publishing outcome of an event published by PublishSync.
+type Result struct {
+	// Status is the final publishing status of the event. It is only valid if
+	// Completed is set.
+	Status beat.EventStatus
+
+	// Completed is not set if the event was still pending when PublishSync
+	// returned.
+	Completed bool
+}
+
+// OK returns true if the event has been ACKed by the outputs.
+func (r Result) OK() bool {
+	return r.Completed && r.Status == beat.EventACKed
+}
+
+// NewSyncClient creates a SyncClient publishing the events via client.
+// PublishSync waits at most timeout for the events to be completed. If timeout
+// is 0, PublishSync waits until all events are completed or the client is
+// closed.
+func NewSyncClient(client beat.Client, timeout time.Duration) *SyncClient {
+	return &SyncClient{
+		client:  client,
+		timeout: timeout,
+		done:    make(chan struct{}),
+	}
+}
+
+// PublishSync publishes the events and waits for them to be ACKed, dropped or
+// failed. The results are returned in the order of the events. If not all
+// events have been completed before the timeout or the client is closed, the
+// results are returned with ErrPublishTimeout or ErrSyncClientClosed, and the
+// pending events are reported as not completed.
+// OnComplete callbacks already set on the events are still run.
+func (c *SyncClient) PublishSync(events []beat.Event) ([]Result, error) {
+	results := make([]Result, len(events))
+	if len(events) == 0 {
+		return results, nil
+	}
+
+	var (
+		mutex   sync.Mutex
+		pending = len(events)
+		done    = make(chan struct{})
+	)
+
+	tracked := make([]beat.Event, len(events))
+	for i, event := range events {
+		i, onComplete := i, event.OnComplete
+		event.OnComplete = func(status beat.EventStatus) {
+			if onComplete != nil {
+				onComplete(status)
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			results[i] = Result{Status: status, Completed: true}
+			if pending--; pending == 0 {
+				close(done)
+			}
+		}
+		tracked[i] = event
+	}
+
+	var timeout <-chan time.Time
+	if c.timeout > 0 {
+		timer := time.NewTimer(c.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	c.client.PublishAll(tracked)
+
+	var err error
+	select {
+	case <-done:
+		return results, nil
+	case <-timeout:
+		err = ErrPublishTimeout
+	case <-c.done:
+		err = ErrSyncClientClosed
+	}
+
+	// Events completed after returning must not modify the returned results.
+	mutex.Lock()
+	defer mutex.Unlock()
+	return append([]Result(nil), results...), err
+}
+
+// Close closes the client. Calls to PublishSync waiting for events return
+// ErrSyncClientClosed.
+func (c *SyncClient) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+	return c.client.Close()
+}
@@ -0,0 +1,162 @@
+package pipeline
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/publisher"
+)
+
+func syncTestEvents(n int) []beat.Event {
+	events := make([]beat.Event, n)
+	for i := range events {
+		events[i] = beat.Event{
+			Timestamp: time.Now(),
+			Fields:    common.MapStr{"id": i},
+		}
+	}
+	return events
+}
+
+func newTestSyncClient(t *testing.T, p *Pipeline, cfg beat.ClientConfig, timeout time.Duration) *SyncClient {
+	client, err := p.ConnectWith(cfg)
+	require.NoError(t, err)
+	return NewSyncClient(client, timeout)
+}
+
+func TestPublishSyncACKed(t *testing.T) {
+	p := newTestPipeline(t, 3, func(batch publisher.Batch) {
+		batch.ACK()
+	})
+	defer p.Close()
+	client := newTestSyncClient(t, p, beat.ClientConfig{}, 5*time.Second)
+	defer client.Close()
+
+	results, err := client.PublishSync(syncTestEvents(15))
+	require.NoError(t, err)
+	require.Len(t, results, 15)
+	for i, r := range results {
+		assert.True(t, r.OK(), "event %v", i)
+	}
+
+	results, err = client.PublishSync(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestPublishSyncPartialFailure(t *testing.T) {
+	// the output rejects odd events, events with id 4 are dropped by the
+	// processors.
+	p := newTestPipeline(t, 3, func(batch publisher.Batch) {
+		for _, event := range batch.Events() {
+			if id, _ := event.Content.Fields["id"].(int); id%2 == 1 {
+				event.Fail()
+			}
+		}
+		batch.ACK()
+	})
+	defer p.Close()
+
+	cfg := beat.ClientConfig{
+		Processor: processorList{dropIDProcessor(4)},
+	}
+	client := newTestSyncClient(t, p, cfg, 5*time.Second)
+	defer client.Close()
+
+	// existing callbacks are still run
+	var mutex sync.Mutex
+	var reported []beat.EventStatus
+	events := syncTestEvents(6)
+	events[1].OnComplete = func(status beat.EventStatus) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		reported = append(reported, status)
+	}
+
+	results, err := client.PublishSync(events)
+	require.NoError(t, err)
+	assert.Equal(t, []Result{
+		{Status: beat.EventACKed, Completed: true},
+		{Status: beat.EventFailed, Completed: true},
+		{Status: beat.EventACKed, Completed: true},
+		{Status: beat.EventFailed, Completed: true},
+		{Status: beat.EventDropped, Completed: true},
+		{Status: beat.EventFailed, Completed: true},
+	}, results)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, []beat.EventStatus{beat.EventFailed}, reported)
+}
+
+func TestPublishSyncTimeout(t *testing.T) {
+	// the output ACKs the first batch only and holds the others until the end
+	// of the test.
+	var mutex sync.Mutex
+	var held []publisher.Batch
+	p := newTestPipeline(t, 3, func(batch publisher.Batch) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if id, _ := batch.Events()[0].Content.Fields["id"].(int); id == 0 {
+			batch.ACK()
+			return
+		}
+		held = append(held, batch)
+	})
+	defer p.Close()
+	defer func() {
+		mutex.Lock()
+		defer mutex.Unlock()
+		for _, batch := range held {
+			batch.ACK()
+		}
+	}()
+
+	client := newTestSyncClient(t, p, beat.ClientConfig{}, 200*time.Millisecond)
+	defer client.Close()
+
+	events := syncTestEvents(2)
+	results, err := client.PublishSync(events[:1])
+	require.NoError(t, err)
+	assert.True(t, results[0].OK())
+
+	results, err = client.PublishSync(events[1:])
+	assert.Equal(t, ErrPublishTimeout, err)
+	assert.Equal(t, []Result{{}}, results)
+	assert.False(t, results[0].OK())
+}
+
+func TestPublishSyncClosed(t *testing.T) {
+	published := make(chan struct{})
+	p := newTestPipeline(t, 3, func(batch publisher.Batch) {
+		// never ACK, the batch is cancelled on close
+		close(published)
+	})
+	defer p.Close()
+	client := newTestSyncClient(t, p, beat.ClientConfig{}, 0)
+
+	go func() {
+		<-published
+		client.Close()
+	}()
+
+	results, err := client.PublishSync(syncTestEvents(1))
+	assert.Equal(t, ErrSyncClientClosed, err)
+	assert.Equal(t, []Result{{}}, results)
+}
+
+type dropIDProcessor int
+
+func (p dropIDProcessor) String() string { return "drop_id" }
+func (p dropIDProcessor) Run(event *beat.Event) (*beat.Event, error) {
+	if id, _ := event.Fields["id"].(int); id == int(p) {
+		return nil, nil
+	}
+	return event, nil
+}
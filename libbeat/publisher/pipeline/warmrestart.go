@@ -0,0 +1,178 @@
+package pipeline
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/publisher/deadletter"
+)
+
+// WarmRestartConfig configures handing the events not ACKed on shutdown over
+// to the next process of the beat.
+type WarmRestartConfig struct {
+	Enabled bool   `config:"enabled"`
+	Path    string `config:"path"`
+}
+
+// defaultWarmRestartFile is the name of the warm restart file in the data
+// path, if no path is configured.
+const defaultWarmRestartFile = "warm_restart.ndjson"
+
+// warmRestart persists the events not ACKed by the outputs on shutdown to a
+// file, using the format of the dead-letter spool. The next pipeline created
+// with the same file publishes the events again, before any other event. The
+// file is renamed while its events are restored, and removed once they have
+// all been published, or persisted again on shutdown.
+type warmRestart struct {
+	logger *logp.Logger
+	path   string
+
+	mutex    sync.Mutex
+	restored int // events restored not completed yet
+	replay   string
+}
+
+func newWarmRestart(log *logp.Logger, path string) *warmRestart {
+	if path == "" {
+		return nil
+	}
+	return &warmRestart{logger: log, path: path}
+}
+
+// restore publishes the events persisted by the previous process to the
+// pipeline. A file left from an interrupted restore is restored first, so no
+// event is lost, though some may be published twice.
+func (w *warmRestart) restore(p *Pipeline) error {
+	if w == nil {
+		return nil
+	}
+
+	replay := w.path + deadletter.ReplaySuffix
+	if _, err := os.Stat(replay); err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to open warm restart file: %v", err)
+		}
+		if _, err := os.Stat(w.path); os.IsNotExist(err) {
+			return nil
+		}
+		if err := os.Rename(w.path, replay); err != nil {
+			return fmt.Errorf("failed to prepare warm restart file for restore: %v", err)
+		}
+	} else if _, err := os.Stat(w.path); err == nil {
+		// Both files are restored, by appending the newer one.
+		if err := appendFile(replay, w.path); err != nil {
+			return fmt.Errorf("failed to prepare warm restart file for restore: %v", err)
+		}
+	}
+
+	file, err := os.Open(replay)
+	if err != nil {
+		return fmt.Errorf("failed to open warm restart file: %v", err)
+	}
+	defer file.Close()
+
+	var events []beat.Event
+	reader := deadletter.NewReader(file)
+	for {
+		event, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read warm restart file: %v", err)
+		}
+		event.OnComplete = w.onComplete
+		events = append(events, event)
+	}
+	file.Close()
+
+	if len(events) == 0 {
+		return os.Remove(replay)
+	}
+
+	w.mutex.Lock()
+	w.restored = len(events)
+	w.replay = replay
+	w.mutex.Unlock()
+
+	// Restored events have already been processed, they are published as is.
+	client, err := p.connect(beat.ClientConfig{PublishMode: beat.GuaranteedSend}, true)
+	if err != nil {
+		return err
+	}
+	client.PublishAll(events)
+	client.Close()
+
+	w.logger.Infof("Restored %v events not ACKed on shutdown from %v", len(events), w.path)
+	return nil
+}
+
+// onComplete removes the restored file, once all of its events have been
+// ACKed, spooled or persisted again.
+func (w *warmRestart) onComplete(_ beat.EventStatus) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.restored == 0 {
+		return
+	}
+	w.restored--
+	if w.restored == 0 {
+		w.removeReplay()
+	}
+}
+
+// removeReplay removes the restored file. The mutex must be held.
+func (w *warmRestart) removeReplay() {
+	if w.replay == "" {
+		return
+	}
+	if err := os.Remove(w.replay); err != nil && !os.IsNotExist(err) {
+		w.logger.Errf("Failed to remove warm restart file: %v", err)
+	}
+	w.replay = ""
+}
+
+// open opens the file the events not ACKed on shutdown are persisted to.
+func (w *warmRestart) open() (*deadletter.Spool, error) {
+	return deadletter.Open(w.path, math.MaxInt64)
+}
+
+// done is called once all events not ACKed have been persisted. Restored
+// events not ACKed yet have been persisted again, the restored file is
+// removed.
+func (w *warmRestart) done() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.restored = 0
+	w.removeReplay()
+}
+
+// appendFile appends the content of the file at src to the file at dst, and
+// removes src.
+func appendFile(dst, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	in.Close()
+	return os.Remove(src)
+}
@@ -0,0 +1,199 @@
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/publisher"
+	"github.com/elastic/beats/libbeat/publisher/deadletter"
+	"github.com/elastic/beats/libbeat/publisher/queue"
+	"github.com/elastic/beats/libbeat/publisher/queue/memqueue"
+)
+
+// recordingClient ACKs all batches, recording the ids of the events published.
+type recordingClient struct {
+	mutex sync.Mutex
+	ids   []int
+}
+
+func (c *recordingClient) Close() error { return nil }
+
+func (c *recordingClient) Publish(batch publisher.Batch) error {
+	c.mutex.Lock()
+	for _, event := range batch.Events() {
+		id, _ := event.Content.Fields["id"].(int64)
+		c.ids = append(c.ids, int(id))
+	}
+	c.mutex.Unlock()
+	batch.ACK()
+	return nil
+}
+
+func (c *recordingClient) published() []int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]int(nil), c.ids...)
+}
+
+func newWarmRestartTestPipeline(t *testing.T, client outputs.Client, path string, spool *deadletter.Spool) *Pipeline {
+	queueFactory := func(e queue.Eventer) (queue.Queue, error) {
+		return memqueue.NewBroker(memqueue.Settings{Eventer: e, Events: 64}), nil
+	}
+	group := outputs.Group{Clients: []outputs.Client{client}, BatchSize: 10}
+	p, err := New(beat.Info{}, nil, queueFactory, group, Settings{
+		FlushTimeout:    100 * time.Millisecond,
+		WarmRestartPath: path,
+		DeadLetter:      spool,
+	})
+	require.NoError(t, err)
+	return p
+}
+
+func TestWarmRestartPreservesUnacked(t *testing.T) {
+	dir, err := ioutil.TempDir("", "warmrestart")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, defaultWarmRestartFile)
+
+	// The first batch is ACKed, the second batch is in-flight, the following
+	// events are held by the consumer or queued when the process stops.
+	stalling := newStallingClient(1)
+	p := newWarmRestartTestPipeline(t, stalling, path, nil)
+	r := newStatusRecorder(45)
+	publishTestEvents(t, p, beat.ClientConfig{}, r, 45)
+	waitFor(t, "in-flight batch", func() bool { return stalling.publishedBatches() == 2 })
+	p.Close()
+
+	// Events are persisted in queue order, and ACKed once persisted.
+	var expected []int
+	status := r.wait(t)
+	for id := 0; id < 45; id++ {
+		assert.Equal(t, []beat.EventStatus{beat.EventACKed}, status[id], "event %v", id)
+		if !stalling.acked[id] {
+			expected = append(expected, id)
+		}
+	}
+	require.NotEmpty(t, expected)
+	assert.Equal(t, expected, readSpooledIDs(t, path))
+
+	// The next process publishes the persisted events again, before any new
+	// event.
+	recording := &recordingClient{}
+	p = newWarmRestartTestPipeline(t, recording, path, nil)
+	publishTestEvents(t, p, beat.ClientConfig{}, newStatusRecorder(1), 1)
+	waitFor(t, "restored events", func() bool { return len(recording.published()) == len(expected)+1 })
+	assert.Equal(t, append(expected, 0), recording.published())
+
+	// The file is removed once all restored events have been ACKed.
+	waitFor(t, "restored file removed", func() bool {
+		_, err := os.Stat(path + deadletter.ReplaySuffix)
+		return os.IsNotExist(err)
+	})
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+	p.Close()
+
+	// Nothing is left to restore.
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestWarmRestartPersistsRestoredEventsAgain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "warmrestart")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, defaultWarmRestartFile)
+
+	stalling := newStallingClient(0)
+	p := newWarmRestartTestPipeline(t, stalling, path, nil)
+	publishTestEvents(t, p, beat.ClientConfig{}, newStatusRecorder(20), 20)
+	waitFor(t, "in-flight batch", func() bool { return stalling.publishedBatches() == 1 })
+	p.Close()
+	require.Len(t, readSpooledIDs(t, path), 20)
+
+	// The restored events are not published before the process stops again,
+	// so they are persisted again, followed by the new events.
+	stalling = newStallingClient(0)
+	p = newWarmRestartTestPipeline(t, stalling, path, nil)
+	r := newStatusRecorder(5)
+	client, err := p.Connect()
+	require.NoError(t, err)
+	for i := 20; i < 25; i++ {
+		client.Publish(beat.Event{
+			Timestamp:  time.Now(),
+			Fields:     common.MapStr{"id": i},
+			OnComplete: r.callback(i),
+		})
+	}
+	client.Close()
+	waitFor(t, "in-flight batch", func() bool { return stalling.publishedBatches() == 1 })
+	p.Close()
+
+	var expected []int
+	for id := 0; id < 25; id++ {
+		expected = append(expected, id)
+	}
+	assert.Equal(t, expected, readSpooledIDs(t, path))
+	_, err = os.Stat(path + deadletter.ReplaySuffix)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestWarmRestartRestoresInterruptedRestore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "warmrestart")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, defaultWarmRestartFile)
+
+	// A restore interrupted by a crash left its file, and the process
+	// persisted more events on shutdown.
+	require.NoError(t, ioutil.WriteFile(path+deadletter.ReplaySuffix, []byte(
+		`{"@timestamp":"2017-10-14T08:00:00Z","fields":{"id":1}}`+"\n"+
+			`{"@timestamp":"2017-10-14T08:00:01Z","fields":{"id":2}}`+"\n"), 0600))
+	require.NoError(t, ioutil.WriteFile(path, []byte(
+		`{"@timestamp":"2017-10-14T08:00:02Z","fields":{"id":3}}`+"\n"), 0600))
+
+	// The events are restored once the beat connects to the pipeline.
+	recording := &recordingClient{}
+	p := newWarmRestartTestPipeline(t, recording, path, nil)
+	defer p.Close()
+	assert.Empty(t, recording.published())
+	client, err := p.Connect()
+	require.NoError(t, err)
+	client.Close()
+	waitFor(t, "restored events", func() bool { return len(recording.published()) == 3 })
+	assert.Equal(t, []int{1, 2, 3}, recording.published())
+	waitFor(t, "restored file removed", func() bool {
+		_, err := os.Stat(path + deadletter.ReplaySuffix)
+		return os.IsNotExist(err)
+	})
+}
+
+func TestWarmRestartTakesPrecedenceOverSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "warmrestart")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, defaultWarmRestartFile)
+
+	spoolPath := filepath.Join(dir, deadletter.FileName("test"))
+	spool, err := deadletter.Open(spoolPath, deadletter.DefaultConfig.MaxBytes)
+	require.NoError(t, err)
+
+	stalling := newStallingClient(0)
+	p := newWarmRestartTestPipeline(t, stalling, path, spool)
+	publishTestEvents(t, p, beat.ClientConfig{}, newStatusRecorder(15), 15)
+	waitFor(t, "in-flight batch", func() bool { return stalling.publishedBatches() == 1 })
+	p.Close()
+
+	assert.Len(t, readSpooledIDs(t, path), 15)
+	assert.Empty(t, readSpooledIDs(t, spoolPath))
+}
@@ -0,0 +1,58 @@
+package pipeline
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elastic/beats/libbeat/monitoring"
+)
+
+// workerMetrics reports the events in flight and the publishing latency of a
+// single output worker, so the number of workers can be tuned for the latency
+// of the output.
+type workerMetrics struct {
+	batches *monitoring.Uint // batches passed to the output client
+	events  *monitoring.Uint // events passed to the output client
+	active  *monitoring.Uint // events passed to the output client, not completed yet
+
+	completed    *monitoring.Uint // batches ACKed, dropped, retried or cancelled
+	latencyLast  *monitoring.Uint // latency of the last completed batch in milliseconds
+	latencyTotal *monitoring.Uint // total latency of all completed batches in milliseconds
+}
+
+// workersRegistry is the name of the pipeline registry the metrics of the
+// output workers are reported in, by worker index.
+const workersRegistry = "output.workers"
+
+func newWorkerMetrics(reg *monitoring.Registry, id int) *workerMetrics {
+	prefix := fmt.Sprintf("%v.%v.", workersRegistry, id)
+	return &workerMetrics{
+		batches:      monitoring.NewUint(reg, prefix+"events.batches"),
+		events:       monitoring.NewUint(reg, prefix+"events.total"),
+		active:       monitoring.NewUint(reg, prefix+"events.active"),
+		completed:    monitoring.NewUint(reg, prefix+"latency.count"),
+		latencyLast:  monitoring.NewUint(reg, prefix+"latency.last_ms"),
+		latencyTotal: monitoring.NewUint(reg, prefix+"latency.total_ms"),
+	}
+}
+
+// sent records a batch of n events being passed to the output client.
+func (m *workerMetrics) sent(n int) {
+	if m != nil {
+		m.batches.Inc()
+		m.events.Add(uint64(n))
+		m.active.Add(uint64(n))
+	}
+}
+
+// done records the completion of a batch of n events, latency after it has
+// been passed to the output client.
+func (m *workerMetrics) done(n int, latency time.Duration) {
+	if m != nil {
+		ms := uint64(latency / time.Millisecond)
+		m.active.Sub(uint64(n))
+		m.completed.Inc()
+		m.latencyLast.Set(ms)
+		m.latencyTotal.Add(ms)
+	}
+}
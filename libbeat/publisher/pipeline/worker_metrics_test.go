@@ -0,0 +1,102 @@
+package pipeline
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common/atomic"
+	"github.com/elastic/beats/libbeat/monitoring"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/publisher"
+	"github.com/elastic/beats/libbeat/publisher/queue"
+	"github.com/elastic/beats/libbeat/publisher/queue/memqueue"
+)
+
+func workerMetric(t *testing.T, reg *monitoring.Registry, worker int, name string) uint64 {
+	v, ok := reg.Get(fmt.Sprintf("pipeline.output.workers.%v.%v", worker, name)).(*monitoring.Uint)
+	require.True(t, ok, "metric %v of worker %v", name, worker)
+	return v.Get()
+}
+
+func TestOutputWorkersPublishConcurrently(t *testing.T) {
+	const workers = 3
+
+	var inFlight atomic.Int32
+	release := make(chan struct{})
+	publish := func(batch publisher.Batch) {
+		inFlight.Inc()
+		<-release
+		batch.ACK()
+	}
+
+	clients := make([]outputs.Client, workers)
+	for i := range clients {
+		clients[i] = &mockClient{publish: publish}
+	}
+
+	reg := monitoring.NewRegistry()
+	queueFactory := func(e queue.Eventer) (queue.Queue, error) {
+		return memqueue.NewBroker(memqueue.Settings{Eventer: e, Events: 64}), nil
+	}
+	group := outputs.Group{Clients: clients, BatchSize: 1}
+	p, err := New(beat.Info{}, reg, queueFactory, group, Settings{})
+	require.NoError(t, err)
+	defer p.Close()
+
+	r := newStatusRecorder(workers)
+	publishTestEvents(t, p, beat.ClientConfig{}, r, workers)
+
+	// Every worker is blocked publishing a batch at the same time.
+	waitFor(t, "all workers publishing", func() bool { return inFlight.Load() == workers })
+	for i := 0; i < workers; i++ {
+		assert.Equal(t, uint64(1), workerMetric(t, reg, i, "events.active"))
+		assert.Equal(t, uint64(1), workerMetric(t, reg, i, "events.batches"))
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	r.wait(t)
+
+	for i := 0; i < workers; i++ {
+		assert.Equal(t, uint64(0), workerMetric(t, reg, i, "events.active"))
+		assert.Equal(t, uint64(1), workerMetric(t, reg, i, "events.total"))
+		assert.Equal(t, uint64(1), workerMetric(t, reg, i, "latency.count"))
+		assert.True(t, workerMetric(t, reg, i, "latency.last_ms") >= 10)
+		assert.Equal(t, workerMetric(t, reg, i, "latency.last_ms"), workerMetric(t, reg, i, "latency.total_ms"))
+	}
+	assert.Nil(t, reg.Get(fmt.Sprintf("pipeline.output.workers.%v", workers)))
+}
+
+func TestOutputWorkerMetricsRetriedBatch(t *testing.T) {
+	var attempts atomic.Int32
+	client := &mockClient{publish: func(batch publisher.Batch) {
+		if attempts.Inc() == 1 {
+			batch.Retry()
+			return
+		}
+		batch.ACK()
+	}}
+
+	reg := monitoring.NewRegistry()
+	queueFactory := func(e queue.Eventer) (queue.Queue, error) {
+		return memqueue.NewBroker(memqueue.Settings{Eventer: e, Events: 64}), nil
+	}
+	group := outputs.Group{Clients: []outputs.Client{client}, BatchSize: 10, Retry: 3}
+	p, err := New(beat.Info{}, reg, queueFactory, group, Settings{})
+	require.NoError(t, err)
+	defer p.Close()
+
+	r := newStatusRecorder(1)
+	publishTestEvents(t, p, beat.ClientConfig{}, r, 1)
+	r.wait(t)
+
+	// The retried batch is reported as sent and completed twice.
+	assert.Equal(t, uint64(2), workerMetric(t, reg, 0, "events.batches"))
+	assert.Equal(t, uint64(2), workerMetric(t, reg, 0, "latency.count"))
+	assert.Equal(t, uint64(0), workerMetric(t, reg, 0, "events.active"))
+}
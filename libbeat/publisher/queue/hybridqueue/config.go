@@ -0,0 +1,21 @@
+package hybridqueue
+
+type config struct {
+	Events   int            `config:"events" validate:"min=32"`
+	Overflow overflowConfig `config:"overflow"`
+}
+
+type overflowConfig struct {
+	// Path of the directory the segment file is stored in, relative to the
+	// data path.
+	Path     string `config:"path"`
+	MaxBytes int64  `config:"max_bytes" validate:"min=1"`
+}
+
+var defaultConfig = config{
+	Events: 4 * 1024,
+	Overflow: overflowConfig{
+		Path:     "queue",
+		MaxBytes: 100 * 1024 * 1024,
+	},
+}
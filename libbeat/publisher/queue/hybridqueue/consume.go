@@ -0,0 +1,50 @@
+package hybridqueue
+
+import (
+	"io"
+	"sync"
+
+	"github.com/elastic/beats/libbeat/publisher"
+	"github.com/elastic/beats/libbeat/publisher/queue"
+)
+
+type consumer struct {
+	queue *Queue
+
+	// closed is protected by the queue mutex.
+	closed bool
+}
+
+type batch struct {
+	queue  *Queue
+	seqs   []uint64
+	events []publisher.Event
+	once   sync.Once
+}
+
+func (c *consumer) Get(sz int) (queue.Batch, error) {
+	seqs, events, ok := c.queue.get(c, sz)
+	if !ok {
+		return nil, io.EOF
+	}
+	return &batch{queue: c.queue, seqs: seqs, events: events}, nil
+}
+
+func (c *consumer) Close() error {
+	c.queue.mutex.Lock()
+	defer c.queue.mutex.Unlock()
+
+	c.closed = true
+	c.queue.cond.Broadcast()
+	return nil
+}
+
+func (b *batch) Events() []publisher.Event {
+	return b.events
+}
+
+func (b *batch) ACK() {
+	b.once.Do(func() {
+		b.queue.ack(b.seqs)
+	})
+}
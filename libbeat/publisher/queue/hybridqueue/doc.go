@@ -0,0 +1,15 @@
+// Package hybridqueue provides a queue.Queue implementation keeping events in
+// memory, and overflowing to a segment file on disk once the memory is full,
+// for example while the outputs are stalled.
+// The queue implementation is registered as queue type "hybrid".
+//
+// Events are forwarded to the consumers in the order they have been
+// published. Once events have been written to the segment file, all new events
+// are appended to the segment file, until all events on disk have been read by
+// the consumers. The segment file is truncated once it has been drained, and
+// new events are kept in memory again.
+//
+// The segment file is only used as a temporary buffer. As with the memory
+// queue, producers are only ACKed once the consumers ACKed the events, so events
+// buffered on disk are published again on restart by clients waiting for ACKs.
+package hybridqueue
@@ -0,0 +1,28 @@
+package hybridqueue
+
+import (
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/publisher"
+)
+
+type producer struct {
+	queue        *Queue
+	ack          func(count int)
+	dropCB       func(beat.Event)
+	dropOnCancel bool
+
+	// cancelled is protected by the queue mutex.
+	cancelled bool
+}
+
+func (p *producer) Publish(event publisher.Event) bool {
+	return p.queue.publish(p, event, true)
+}
+
+func (p *producer) TryPublish(event publisher.Event) bool {
+	return p.queue.publish(p, event, false)
+}
+
+func (p *producer) Cancel() int {
+	return p.queue.cancel(p)
+}
@@ -0,0 +1,381 @@
+package hybridqueue
+
+import (
+	"path/filepath"
+	"sync"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/paths"
+	"github.com/elastic/beats/libbeat/publisher"
+	"github.com/elastic/beats/libbeat/publisher/queue"
+)
+
+// Queue buffers up to Events events in memory. Further events are written to
+// the segment file, until all events written to the file have been consumed.
+type Queue struct {
+	eventer queue.Eventer
+	events  int
+
+	mutex  sync.Mutex
+	cond   sync.Cond
+	closed bool
+
+	// mem holds the events in memory not consumed yet. All events in memory
+	// have been published before the events in the segment file.
+	mem []queuedEvent
+
+	// spilled holds the state of the events in the segment file, in the
+	// order they have been written. The event contents are read from the
+	// segment file.
+	segment *segment
+	spilled []queuedEvent
+
+	// inFlight is the number of events consumed but not ACKed yet.
+	inFlight int
+
+	// pending holds the events not ACKed to the producers yet, in the order
+	// they have been published. firstSeq is the sequence number of the first
+	// pending event.
+	pending  []pendingEvent
+	firstSeq uint64
+
+	// ackMutex serializes the ACK callbacks, so producers are ACKed in order.
+	ackMutex sync.Mutex
+}
+
+// Settings configure a hybrid queue.
+type Settings struct {
+	Eventer queue.Eventer
+
+	// Events is the number of events kept in memory.
+	Events int
+
+	// Path is the path of the segment file, MaxBytes its maximum size.
+	Path     string
+	MaxBytes int64
+}
+
+type queuedEvent struct {
+	seq   uint64
+	event publisher.Event
+}
+
+type pendingEvent struct {
+	producer *producer
+	done     bool // ACKed by a consumer, or dropped
+	removed  bool // removed on producer cancel, not ACKed to the producer
+}
+
+type ackSignal struct {
+	producer *producer
+	count    int
+}
+
+func init() {
+	queue.RegisterType("hybrid", create)
+}
+
+func create(eventer queue.Eventer, cfg *common.Config) (queue.Queue, error) {
+	config := defaultConfig
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, err
+	}
+
+	return NewQueue(Settings{
+		Eventer:  eventer,
+		Events:   config.Events,
+		Path:     filepath.Join(paths.Resolve(paths.Data, config.Overflow.Path), segmentFileName),
+		MaxBytes: config.Overflow.MaxBytes,
+	})
+}
+
+// NewQueue creates a new hybrid queue, creating the segment file at
+// settings.Path.
+func NewQueue(settings Settings) (*Queue, error) {
+	segment, err := openSegment(settings.Path, settings.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &Queue{
+		eventer: settings.Eventer,
+		events:  settings.Events,
+		segment: segment,
+	}
+	q.cond.L = &q.mutex
+	return q, nil
+}
+
+// Close closes the queue and removes the segment file. Blocked producers and
+// consumers return.
+func (q *Queue) Close() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.closed {
+		return nil
+	}
+	q.closed = true
+	q.cond.Broadcast()
+	return q.segment.close()
+}
+
+func (q *Queue) BufferConfig() queue.BufferConfig {
+	return queue.BufferConfig{Events: q.events}
+}
+
+func (q *Queue) Producer(cfg queue.ProducerConfig) queue.Producer {
+	return &producer{
+		queue:        q,
+		ack:          cfg.ACK,
+		dropCB:       cfg.OnDrop,
+		dropOnCancel: cfg.DropOnCancel,
+	}
+}
+
+func (q *Queue) Consumer() queue.Consumer {
+	return &consumer{queue: q}
+}
+
+// publish adds an event to the queue. The event is kept in memory if there is
+// space left and no events are waiting in the segment file. Otherwise it is
+// written to the segment file. If the segment file is full, publish waits for
+// events to be consumed, unless block is false.
+func (q *Queue) publish(p *producer, event publisher.Event, block bool) bool {
+	var line []byte
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for {
+		if q.closed {
+			return false
+		}
+		if p.cancelled {
+			if p.dropCB != nil {
+				p.dropCB(event.Content)
+			}
+			return false
+		}
+
+		if q.segment.count == 0 && len(q.mem)+q.inFlight < q.events {
+			q.mem = append(q.mem, q.add(p, event))
+			q.cond.Broadcast()
+			return true
+		}
+
+		if line == nil {
+			var err error
+			line, err = encodeEvent(event.Content)
+			if err != nil {
+				logp.Err("Dropping event that can not be written to the queue overflow file: %v", err)
+				return false
+			}
+		}
+
+		if q.segment.fits(len(line)) {
+			if err := q.segment.write(line); err != nil {
+				logp.Err("Dropping event: %v", err)
+				return false
+			}
+
+			// The content is read from the segment file.
+			e := q.add(p, event)
+			e.event.Content = beat.Event{}
+			q.spilled = append(q.spilled, e)
+			q.cond.Broadcast()
+			return true
+		}
+
+		if !block {
+			return false
+		}
+		q.cond.Wait()
+	}
+}
+
+// add assigns the next sequence number to the event.
+func (q *Queue) add(p *producer, event publisher.Event) queuedEvent {
+	seq := q.firstSeq + uint64(len(q.pending))
+	q.pending = append(q.pending, pendingEvent{producer: p})
+	return queuedEvent{seq: seq, event: event}
+}
+
+func (q *Queue) pendingEvent(seq uint64) *pendingEvent {
+	return &q.pending[seq-q.firstSeq]
+}
+
+// get returns up to sz events in the order they have been published, waiting
+// until events are available. Events in memory are returned before the events
+// in the segment file.
+func (q *Queue) get(c *consumer, sz int) ([]uint64, []publisher.Event, bool) {
+	if sz <= 0 || sz > q.events {
+		sz = q.events
+	}
+
+	q.mutex.Lock()
+	for {
+		if q.closed || c.closed {
+			q.mutex.Unlock()
+			return nil, nil, false
+		}
+		if len(q.mem) == 0 && q.segment.count == 0 {
+			q.cond.Wait()
+			continue
+		}
+
+		var (
+			seqs   []uint64
+			events []publisher.Event
+		)
+		for len(events) < sz && len(q.mem) > 0 {
+			e := q.mem[0]
+			q.mem = q.mem[1:]
+			seqs = append(seqs, e.seq)
+			events = append(events, e.event)
+		}
+
+		dropped := false
+		for len(events) < sz && q.segment.count > 0 {
+			e := q.spilled[0]
+			q.spilled = q.spilled[1:]
+
+			content, err := q.segment.read()
+			if err != nil {
+				lost := []queuedEvent{e}
+				if q.segment.count == 0 {
+					// The segment file has been reset, the events not read
+					// yet are lost.
+					lost = append(lost, q.spilled...)
+					q.spilled = nil
+				}
+				for _, e := range lost {
+					if q.dropSpilled(e, err) {
+						dropped = true
+					}
+				}
+				continue
+			}
+			if e.seq < q.firstSeq || q.pendingEvent(e.seq).removed {
+				// Removed on producer cancel.
+				continue
+			}
+
+			e.event.Content = content
+			seqs = append(seqs, e.seq)
+			events = append(events, e.event)
+		}
+
+		// Room has been made in the segment file.
+		q.cond.Broadcast()
+
+		if dropped {
+			q.unlockAndACK()
+			q.mutex.Lock()
+		}
+		if len(events) > 0 {
+			q.inFlight += len(events)
+			q.mutex.Unlock()
+			return seqs, events, true
+		}
+	}
+}
+
+// dropSpilled marks an event that could not be read from the segment file as
+// done. It returns false if the event has been removed on producer cancel.
+func (q *Queue) dropSpilled(e queuedEvent, err error) bool {
+	if e.seq < q.firstSeq || q.pendingEvent(e.seq).removed {
+		return false
+	}
+	logp.Err("Dropping event: %v", err)
+	q.pendingEvent(e.seq).done = true
+	return true
+}
+
+// ack marks the events consumed in a batch as ACKed.
+func (q *Queue) ack(seqs []uint64) {
+	q.mutex.Lock()
+	for _, seq := range seqs {
+		q.pendingEvent(seq).done = true
+	}
+	q.inFlight -= len(seqs)
+	q.cond.Broadcast()
+	q.unlockAndACK()
+}
+
+// cancel marks the producer as cancelled. If the producer drops its events on
+// cancel, the events not consumed yet are removed from the queue.
+func (q *Queue) cancel(p *producer) int {
+	q.mutex.Lock()
+	p.cancelled = true
+	q.cond.Broadcast()
+
+	removed := 0
+	if p.dropOnCancel {
+		mem := q.mem[:0]
+		for _, e := range q.mem {
+			pending := q.pendingEvent(e.seq)
+			if pending.producer != p {
+				mem = append(mem, e)
+				continue
+			}
+			pending.done, pending.removed = true, true
+			removed++
+		}
+		q.mem = mem
+
+		// Events in the segment file are skipped when read.
+		for _, e := range q.spilled {
+			if pending := q.pendingEvent(e.seq); pending.producer == p {
+				pending.done, pending.removed = true, true
+				removed++
+			}
+		}
+	}
+
+	q.unlockAndACK()
+	return removed
+}
+
+// unlockAndACK removes the ACKed events from the start of the pending events,
+// and ACKs them to their producers. The queue mutex must be held, and is
+// released before the callbacks are run.
+func (q *Queue) unlockAndACK() {
+	var (
+		signals []ackSignal
+		total   int
+	)
+	for len(q.pending) > 0 && q.pending[0].done {
+		pending := q.pending[0]
+		q.pending = q.pending[1:]
+		q.firstSeq++
+
+		if pending.removed {
+			continue
+		}
+		total++
+
+		p := pending.producer
+		if p.ack == nil || p.cancelled {
+			continue
+		}
+		if n := len(signals); n > 0 && signals[n-1].producer == p {
+			signals[n-1].count++
+		} else {
+			signals = append(signals, ackSignal{producer: p, count: 1})
+		}
+	}
+
+	q.ackMutex.Lock()
+	defer q.ackMutex.Unlock()
+	q.mutex.Unlock()
+
+	for _, s := range signals {
+		s.producer.ack(s.count)
+	}
+	if total > 0 && q.eventer != nil {
+		q.eventer.OnACK(total)
+	}
+}
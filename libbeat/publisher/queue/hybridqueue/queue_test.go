@@ -0,0 +1,385 @@
+package hybridqueue
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/publisher"
+	"github.com/elastic/beats/libbeat/publisher/queue"
+	"github.com/elastic/beats/libbeat/publisher/queue/queuetest"
+)
+
+var seed int64
+
+const consumeTimeout = 10 * time.Second
+
+var testTimestamp = time.Date(2018, 10, 14, 7, 30, 0, 123000000, time.UTC)
+
+func init() {
+	flag.Int64Var(&seed, "seed", time.Now().UnixNano(), "test random seed")
+}
+
+func TestProduceConsumer(t *testing.T) {
+	maxEvents := 1024
+	minEvents := 32
+
+	rand.Seed(seed)
+	events := rand.Intn(maxEvents-minEvents) + minEvents
+	batchSize := rand.Intn(events-8) + 4
+	bufferSize := rand.Intn(batchSize*2) + 4
+
+	t.Log("seed: ", seed)
+	t.Log("events: ", events)
+	t.Log("batchSize: ", batchSize)
+	t.Log("bufferSize: ", bufferSize)
+
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	testWith := func(factory queuetest.QueueFactory) func(t *testing.T) {
+		return func(t *testing.T) {
+			t.Run("single", func(t *testing.T) {
+				queuetest.TestSingleProducerConsumer(t, events, batchSize, factory)
+			})
+			t.Run("multi", func(t *testing.T) {
+				queuetest.TestMultiProducerConsumer(t, events, batchSize, factory)
+			})
+		}
+	}
+
+	t.Run("memory", testWith(makeTestQueue(t, dir, events, 10*1024*1024)))
+	t.Run("overflow", testWith(makeTestQueue(t, dir, bufferSize, 10*1024*1024)))
+	t.Run("overflow full", testWith(makeTestQueue(t, dir, bufferSize, 1024)))
+}
+
+func TestProducerCancelRemovesEvents(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	queuetest.TestProducerCancelRemovesEvents(t, makeTestQueue(t, dir, 1024, 1024*1024))
+}
+
+func TestProducerCancelRemovesOverflowEvents(t *testing.T) {
+	q := newTestQueue(t, 2, 1024*1024)
+	defer q.Close()
+
+	acked := 0
+	cancelled := q.Producer(queue.ProducerConfig{
+		ACK:          func(n int) { acked += n },
+		DropOnCancel: true,
+	})
+	producer := q.Producer(queue.ProducerConfig{})
+
+	producer.Publish(makeTestEvent(0))
+	for i := 0; i < 3; i++ {
+		cancelled.Publish(makeTestEvent(100 + i))
+	}
+	producer.Publish(makeTestEvent(1))
+	producer.Publish(makeTestEvent(2))
+
+	assert.Equal(t, 3, cancelled.Cancel())
+	assert.False(t, cancelled.Publish(makeTestEvent(200)))
+
+	counts := consumeAll(t, q.Consumer(), 3)
+	assert.Equal(t, []int64{0, 1, 2}, counts)
+	assert.Equal(t, 0, q.segment.count)
+	assert.Equal(t, int64(0), q.segment.size)
+	assert.Equal(t, 0, acked)
+}
+
+func TestOverflowPreservesOrder(t *testing.T) {
+	q := newTestQueue(t, 4, 1024*1024)
+	defer q.Close()
+
+	acked := 0
+	producer := q.Producer(queue.ProducerConfig{ACK: func(n int) { acked += n }})
+	for i := 0; i < 20; i++ {
+		require.True(t, producer.Publish(makeTestEvent(i)))
+	}
+	assert.Len(t, q.mem, 4)
+	assert.Equal(t, 16, q.segment.count)
+
+	// Events are kept in the segment file until all events written have been
+	// read, even if there is space in memory.
+	batch, err := q.Consumer().Get(2)
+	require.NoError(t, err)
+	batch.ACK()
+	require.True(t, producer.Publish(makeTestEvent(20)))
+	assert.Equal(t, 17, q.segment.count)
+
+	counts := consumeAll(t, q.Consumer(), 19)
+	for i, count := range counts {
+		assert.Equal(t, int64(i+2), count)
+	}
+	assert.Equal(t, 21, acked)
+
+	// The segment file is truncated once it has been read.
+	assert.Equal(t, 0, q.segment.count)
+	info, err := os.Stat(q.segment.path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), info.Size())
+
+	// Memory is used again once the segment file is empty.
+	require.True(t, producer.Publish(makeTestEvent(21)))
+	assert.Len(t, q.mem, 1)
+	assert.Equal(t, 0, q.segment.count)
+}
+
+func TestOverflowRestoresEvent(t *testing.T) {
+	q := newTestQueue(t, 1, 1024*1024)
+	defer q.Close()
+
+	ts := time.Date(2018, 1, 2, 3, 4, 5, 6, time.UTC)
+	producer := q.Producer(queue.ProducerConfig{})
+	producer.Publish(makeTestEvent(0))
+	producer.Publish(publisher.Event{
+		Content: beat.Event{
+			Timestamp: ts,
+			Meta:      common.MapStr{"pipeline": "test"},
+			Fields: common.MapStr{
+				"message": "hello",
+				"nested":  common.MapStr{"value": 1.5},
+			},
+		},
+		Flags: publisher.GuaranteedSend,
+	})
+	assert.Equal(t, 1, q.segment.count)
+
+	consumer := q.Consumer()
+	batch, err := consumer.Get(1)
+	require.NoError(t, err)
+	batch.ACK()
+
+	batch, err = consumer.Get(1)
+	require.NoError(t, err)
+	require.Len(t, batch.Events(), 1)
+
+	event := batch.Events()[0]
+	assert.Equal(t, publisher.GuaranteedSend, event.Flags)
+	assert.True(t, ts.Equal(event.Content.Timestamp))
+	assert.Equal(t, common.MapStr{"pipeline": "test"}, event.Content.Meta)
+	assert.Equal(t, common.MapStr{
+		"message": "hello",
+		"nested":  map[string]interface{}{"value": 1.5},
+	}, event.Content.Fields)
+	batch.ACK()
+}
+
+func TestTryPublishOverflowFull(t *testing.T) {
+	line, err := encodeEvent(makeTestEvent(0).Content)
+	require.NoError(t, err)
+
+	q := newTestQueue(t, 2, int64(2*len(line)))
+	defer q.Close()
+
+	producer := q.Producer(queue.ProducerConfig{})
+	for i := 0; i < 4; i++ {
+		assert.True(t, producer.TryPublish(makeTestEvent(i)))
+	}
+	assert.False(t, producer.TryPublish(makeTestEvent(4)))
+
+	// Publish blocks until events have been read from the segment file.
+	published := make(chan bool)
+	go func() {
+		published <- producer.Publish(makeTestEvent(4))
+	}()
+
+	select {
+	case <-published:
+		t.Fatal("publish did not block with the queue being full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	counts := consumeAll(t, q.Consumer(), 5)
+	assert.Equal(t, []int64{0, 1, 2, 3, 4}, counts)
+	assert.True(t, <-published)
+}
+
+func TestOverflowReadFailureDropsEvents(t *testing.T) {
+	q := newTestQueue(t, 2, 1024*1024)
+	defer q.Close()
+
+	acked := 0
+	producer := q.Producer(queue.ProducerConfig{ACK: func(n int) { acked += n }})
+	for i := 0; i < 5; i++ {
+		require.True(t, producer.Publish(makeTestEvent(i)))
+	}
+	assert.Equal(t, 3, q.segment.count)
+
+	batch, err := q.Consumer().Get(1)
+	require.NoError(t, err)
+	batch.ACK()
+
+	// The events in the segment file are dropped, and ACKed with the batch
+	// of the event read from memory.
+	q.segment.reader = bufio.NewReader(failingReader{})
+	assert.Equal(t, []int64{1}, consumeAll(t, q.Consumer(), 1))
+	assert.Equal(t, 5, acked)
+	assert.Equal(t, 0, q.segment.count)
+	assert.Empty(t, q.spilled)
+
+	// The segment file is used again once it has been reset.
+	for i := 5; i < 8; i++ {
+		require.True(t, producer.Publish(makeTestEvent(i)))
+	}
+	assert.Equal(t, 1, q.segment.count)
+	assert.Equal(t, []int64{5, 6, 7}, consumeAll(t, q.Consumer(), 3))
+	assert.Equal(t, 8, acked)
+}
+
+func TestACKInOrder(t *testing.T) {
+	q := newTestQueue(t, 2, 1024*1024)
+	defer q.Close()
+
+	var acks []int
+	producer := q.Producer(queue.ProducerConfig{ACK: func(n int) { acks = append(acks, n) }})
+	for i := 0; i < 6; i++ {
+		producer.Publish(makeTestEvent(i))
+	}
+
+	consumer := q.Consumer()
+	var batches []queue.Batch
+	for i := 0; i < 3; i++ {
+		batch, err := consumer.Get(2)
+		require.NoError(t, err)
+		batches = append(batches, batch)
+	}
+
+	batches[2].ACK()
+	batches[1].ACK()
+	assert.Empty(t, acks)
+
+	batches[0].ACK()
+	batches[0].ACK()
+	assert.Equal(t, []int{6}, acks)
+}
+
+func TestCloseUnblocksConsumer(t *testing.T) {
+	q := newTestQueue(t, 2, 1024)
+
+	done := make(chan error)
+	go func() {
+		_, err := q.Consumer().Get(1)
+		done <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	q.Close()
+	assert.Error(t, <-done)
+
+	_, err := os.Stat(q.segment.path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+// failingReader fails all reads, to inject read failures on the segment file.
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("read failure")
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "hybridqueue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func newTestQueue(t *testing.T, events int, maxBytes int64) *Queue {
+	dir := tempDir(t)
+	q, err := NewQueue(Settings{
+		Events:   events,
+		Path:     filepath.Join(dir, segmentFileName),
+		MaxBytes: maxBytes,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func makeTestQueue(t *testing.T, dir string, events int, maxBytes int64) queuetest.QueueFactory {
+	return func() queue.Queue {
+		path, err := ioutil.TempDir(dir, "segment")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		q, err := NewQueue(Settings{
+			Events:   events,
+			Path:     filepath.Join(path, segmentFileName),
+			MaxBytes: maxBytes,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return q
+	}
+}
+
+// consumeAll reads n events, ACKing each batch, and returns the counts of the
+// events. The test fails if the events are not read within consumeTimeout.
+func consumeAll(t *testing.T, consumer queue.Consumer, n int) []int64 {
+	type result struct {
+		counts []int64
+		err    error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		var counts []int64
+		for len(counts) < n {
+			batch, err := consumer.Get(-1)
+			if err != nil {
+				done <- result{counts, err}
+				return
+			}
+
+			for _, event := range batch.Events() {
+				switch count := event.Content.Fields["count"].(type) {
+				case int:
+					counts = append(counts, int64(count))
+				case int64:
+					counts = append(counts, count)
+				default:
+					done <- result{counts, fmt.Errorf("unexpected count %v", count)}
+					return
+				}
+			}
+			batch.ACK()
+		}
+		done <- result{counts, nil}
+	}()
+
+	select {
+	case res := <-done:
+		require.NoError(t, res.err)
+		return res.counts
+	case <-time.After(consumeTimeout):
+		t.Fatalf("timeout waiting for %v events", n)
+		return nil
+	}
+}
+
+// makeTestEvent returns an event with a fixed timestamp, so events with counts
+// of the same number of digits have the same encoded size.
+func makeTestEvent(i int) publisher.Event {
+	return publisher.Event{Content: beat.Event{
+		Timestamp: testTimestamp,
+		Fields:    common.MapStr{"count": i},
+	}}
+}
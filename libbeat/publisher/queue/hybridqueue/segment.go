@@ -0,0 +1,165 @@
+package hybridqueue
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/jsontransform"
+)
+
+// segmentFileName is the name of the segment file in the overflow directory.
+const segmentFileName = "overflow.ndjson"
+
+// segment is the file events overflowing the memory are written to. Events are
+// appended as JSON lines, and read in the order they have been written. The
+// file is truncated once all events have been read.
+type segment struct {
+	path     string
+	maxBytes int64
+
+	file   *os.File
+	reader *bufio.Reader
+	size   int64 // bytes written since the file was truncated
+	count  int   // events written, but not read yet
+}
+
+// entry is the serialized form of an event in the segment file.
+type entry struct {
+	Timestamp time.Time     `json:"@timestamp"`
+	Meta      common.MapStr `json:"@metadata,omitempty"`
+	Fields    common.MapStr `json:"fields"`
+}
+
+// fileReader reads a file sequentially, independent of the write offset.
+type fileReader struct {
+	file   *os.File
+	offset int64
+}
+
+// openSegment creates the segment file at path. Events left in the file by a
+// previous run are dropped, as they have not been ACKed to the producers.
+func openSegment(path string, maxBytes int64) (*segment, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create queue overflow directory: %v", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open queue overflow file: %v", err)
+	}
+
+	s := &segment{path: path, maxBytes: maxBytes, file: file}
+	s.reader = bufio.NewReader(&fileReader{file: file})
+	return s, nil
+}
+
+func encodeEvent(event beat.Event) ([]byte, error) {
+	line, err := json.Marshal(entry{
+		Timestamp: event.Timestamp,
+		Meta:      event.Meta,
+		Fields:    event.Fields,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// fits returns true if an encoded event of n bytes can be written without
+// exceeding the maximum size of the segment file.
+func (s *segment) fits(n int) bool {
+	return s.size+int64(n) <= s.maxBytes
+}
+
+func (s *segment) write(line []byte) error {
+	n, err := s.file.WriteAt(line, s.size)
+	if err != nil {
+		// Remove a partially written line, as the reader reads ahead up to
+		// the end of the file.
+		s.file.Truncate(s.size)
+		return fmt.Errorf("failed to write to queue overflow file: %v", err)
+	}
+	s.size += int64(n)
+	s.count++
+	return nil
+}
+
+// read returns the next event. The file is truncated once the last event
+// written has been read, or if reading from the file fails.
+func (s *segment) read() (beat.Event, error) {
+	line, err := s.reader.ReadBytes('\n')
+	if err != nil {
+		// The events left cannot be located after a failed read, and are
+		// dropped with the content of the file.
+		s.count = 0
+		s.truncate()
+		return beat.Event{}, fmt.Errorf("failed to read from queue overflow file: %v", err)
+	}
+
+	s.count--
+	if s.count == 0 {
+		if err := s.truncate(); err != nil {
+			return beat.Event{}, err
+		}
+	}
+	return decodeEvent(line)
+}
+
+func (s *segment) truncate() error {
+	if err := s.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate queue overflow file: %v", err)
+	}
+	s.size = 0
+	s.reader.Reset(&fileReader{file: s.file})
+	return nil
+}
+
+// close closes and removes the segment file.
+func (s *segment) close() error {
+	err := s.file.Close()
+	os.Remove(s.path)
+	return err
+}
+
+func decodeEvent(line []byte) (beat.Event, error) {
+	var e entry
+
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if err := dec.Decode(&e); err != nil {
+		return beat.Event{}, fmt.Errorf("failed to decode event from queue overflow file: %v", err)
+	}
+
+	if e.Fields == nil {
+		e.Fields = common.MapStr{}
+	}
+	jsontransform.TransformNumbers(e.Fields)
+	if e.Meta != nil {
+		jsontransform.TransformNumbers(e.Meta)
+	}
+
+	return beat.Event{
+		Timestamp: e.Timestamp,
+		Meta:      e.Meta,
+		Fields:    e.Fields,
+	}, nil
+}
+
+// Read reports io.EOF only if no bytes have been read, as the buffered reader
+// keeps the error, while more events may be written to the file.
+func (r *fileReader) Read(p []byte) (int, error) {
+	n, err := r.file.ReadAt(p, r.offset)
+	r.offset += int64(n)
+	if n > 0 && err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
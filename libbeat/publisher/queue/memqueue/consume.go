@@ -20,7 +20,8 @@ type consumer struct {
 }
 
 type consumerStats struct {
-	totalGet, totalACK uint64
+	totalGet uint64
+	totalACK atomic.Uint64 // batches are ACKed concurrently by the output workers
 }
 
 type batch struct {
@@ -106,7 +107,7 @@ func (b *batch) ACK() {
 		}
 	}
 
-	c.stats.totalACK += uint64(b.ack.count)
+	c.stats.totalACK.Add(uint64(b.ack.count))
 	// log.Debug("consumer: total events ack = ", c.stats.totalACK)
 	// log.Debugf("ack batch: seq=%v, len=%v", b.ack.seq, len(b.events))
 	b.report()
@@ -6,6 +6,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/publisher"
 	"github.com/elastic/beats/libbeat/publisher/queue"
 	"github.com/elastic/beats/libbeat/publisher/queue/queuetest"
 )
@@ -63,3 +68,79 @@ func makeTestQueue(sz, minEvents int, flushTimeout time.Duration) queuetest.Queu
 		})
 	}
 }
+
+func TestFlushOnTimeout(t *testing.T) {
+	// low volume: fewer events than flush.min_events are forwarded once
+	// flush.timeout has passed since the first event was buffered.
+	timeout := 300 * time.Millisecond
+	b := NewBroker(Settings{Events: 1024, FlushMinEvents: 100, FlushTimeout: timeout})
+	defer b.Close()
+
+	producer := b.Producer(queue.ProducerConfig{})
+	start := time.Now()
+	producer.Publish(makeTestEvent(0))
+	time.Sleep(timeout / 2)
+	producer.Publish(makeTestEvent(1))
+	producer.Publish(makeTestEvent(2))
+
+	batch, err := b.Consumer().Get(50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	elapsed := time.Since(start)
+
+	assert.Len(t, batch.Events(), 3)
+	assert.True(t, elapsed >= timeout, "events flushed after %v, before flush.timeout", elapsed)
+
+	// the timeout must not be restarted by events added to the buffer
+	assert.True(t, elapsed < timeout+timeout/2, "events flushed after %v", elapsed)
+	batch.ACK()
+}
+
+func TestFlushOnMinEvents(t *testing.T) {
+	// high volume: events are forwarded as soon as flush.min_events is reached,
+	// without waiting for flush.timeout.
+	timeout := 10 * time.Second
+	b := NewBroker(Settings{Events: 1024, FlushMinEvents: 10, FlushTimeout: timeout})
+	defer b.Close()
+
+	producer := b.Producer(queue.ProducerConfig{})
+	start := time.Now()
+	for i := 0; i < 25; i++ {
+		producer.Publish(makeTestEvent(i))
+	}
+
+	consumer := b.Consumer()
+	for i := 0; i < 2; i++ {
+		batch, err := consumer.Get(50)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Len(t, batch.Events(), 10)
+		batch.ACK()
+	}
+	assert.True(t, time.Since(start) < timeout/2, "events not flushed on flush.min_events")
+
+	// the remaining events are flushed on timeout only
+	got := make(chan int, 1)
+	go func() {
+		batch, err := consumer.Get(50)
+		if err == nil {
+			got <- len(batch.Events())
+			batch.ACK()
+		}
+	}()
+
+	select {
+	case n := <-got:
+		t.Fatalf("%v events flushed before flush.timeout", n)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func makeTestEvent(i int) publisher.Event {
+	return publisher.Event{Content: beat.Event{
+		Timestamp: time.Now(),
+		Fields:    common.MapStr{"count": i},
+	}}
+}
@@ -0,0 +1,47 @@
+package tap
+
+import "errors"
+
+// Config configures the tap streaming a sample of the processed events to the
+// `/events/tap` endpoint of the HTTP metrics endpoint.
+type Config struct {
+	// Enabled enables the tap endpoint.
+	Enabled bool `config:"enabled"`
+
+	// Token is the bearer token clients must send to subscribe to the tap.
+	Token string `config:"token"`
+
+	// SampleRate is the fraction of events streamed, between 0 and 1.
+	SampleRate float64 `config:"sample_rate"`
+
+	// MaxEventsPerSecond limits the number of events streamed per second, for
+	// all subscribers.
+	MaxEventsPerSecond int `config:"max_events_per_second" validate:"min=1"`
+
+	// RedactFields are the fields masked in the streamed events.
+	RedactFields []string `config:"redact_fields"`
+
+	// BufferSize is the number of events buffered per subscriber. Events are
+	// dropped for subscribers reading slower than the events are streamed.
+	BufferSize int `config:"buffer_size" validate:"min=1"`
+}
+
+// DefaultConfig is the default tap configuration.
+var DefaultConfig = Config{
+	Enabled:            false,
+	SampleRate:         0.1,
+	MaxEventsPerSecond: 10,
+	BufferSize:         100,
+}
+
+// Validate checks the sample rate is a valid fraction and a token is set if
+// the tap is enabled.
+func (c *Config) Validate() error {
+	if c.SampleRate <= 0 || c.SampleRate > 1 {
+		return errors.New("tap.sample_rate must be greater than 0 and at most 1")
+	}
+	if c.Enabled && c.Token == "" {
+		return errors.New("tap.token is required if the tap is enabled")
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+// Package tap streams a sample of the events leaving the processing pipeline
+// to subscribers, like the clients of the `/events/tap` endpoint of the HTTP
+// metrics endpoint, for inspecting the processed events of a running beat
+// without reconfiguring its output.
+package tap
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/publisher/inspect"
+)
+
+// Tap distributes a sample of the events added to its subscribers, rate
+// limited and with the configured fields redacted. Events are only sampled
+// and encoded while there are subscribers. It is safe for concurrent use.
+type Tap struct {
+	config Config
+
+	// active is the number of subscribers, for skipping events without
+	// taking the mutex if there are none.
+	active int32
+
+	mutex       sync.Mutex
+	subscribers map[*Subscription]struct{}
+	encoder     *inspect.Encoder
+	window      time.Time // start of the current rate limit window
+	sent        int       // events sent in the current window
+	random      func() float64
+	now         func() time.Time
+}
+
+// Subscription receives the events streamed by a Tap, encoded as JSON.
+type Subscription struct {
+	tap       *Tap
+	events    chan []byte
+	dropped   int
+	closeOnce sync.Once
+}
+
+// New creates a Tap for the events of the beat.
+func New(info beat.Info, config Config) *Tap {
+	return &Tap{
+		config:      config,
+		subscribers: map[*Subscription]struct{}{},
+		encoder:     inspect.NewEncoder(info, config.RedactFields),
+		random:      rand.New(rand.NewSource(time.Now().UnixNano())).Float64,
+		now:         time.Now,
+	}
+}
+
+// Authorized checks the token matches the configured token.
+func (t *Tap) Authorized(token string) bool {
+	return inspect.Authorized(token, t.config.Token)
+}
+
+// Add streams the event to the subscribers, if it is selected by the sample
+// rate and the rate limit is not exceeded. The event is encoded right away, so
+// it can be modified once Add returns. Subscribers not keeping up with the
+// events miss events, Add never blocks.
+func (t *Tap) Add(event beat.Event) {
+	if atomic.LoadInt32(&t.active) == 0 {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if len(t.subscribers) == 0 {
+		return
+	}
+	if t.config.SampleRate < 1 && t.random() >= t.config.SampleRate {
+		return
+	}
+
+	now := t.now()
+	if now.Sub(t.window) >= time.Second {
+		t.window = now
+		t.sent = 0
+	}
+	if t.sent >= t.config.MaxEventsPerSecond {
+		return
+	}
+	t.sent++
+
+	line, err := t.encoder.Encode(event)
+	if err != nil {
+		logp.Debug("tap", "failed to encode event: %v", err)
+		return
+	}
+
+	for s := range t.subscribers {
+		select {
+		case s.events <- line:
+		default:
+			s.dropped++
+		}
+	}
+}
+
+// Subscribe starts streaming events to a new subscription. The subscription
+// must be closed once the events are not read anymore.
+func (t *Tap) Subscribe() *Subscription {
+	s := &Subscription{
+		tap:    t,
+		events: make(chan []byte, t.config.BufferSize),
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.subscribers[s] = struct{}{}
+	atomic.AddInt32(&t.active, 1)
+	return s
+}
+
+// Events returns the channel receiving the events. It is closed when the
+// subscription is closed.
+func (s *Subscription) Events() <-chan []byte {
+	return s.events
+}
+
+// Dropped returns the number of events missed, because the events were not
+// read fast enough.
+func (s *Subscription) Dropped() int {
+	s.tap.mutex.Lock()
+	defer s.tap.mutex.Unlock()
+	return s.dropped
+}
+
+// Close stops streaming events to the subscription.
+func (s *Subscription) Close() {
+	s.closeOnce.Do(func() {
+		t := s.tap
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+
+		delete(t.subscribers, s)
+		atomic.AddInt32(&t.active, -1)
+		close(s.events)
+	})
+}
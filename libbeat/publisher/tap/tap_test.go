@@ -0,0 +1,171 @@
+package tap
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/publisher/inspect/inspecttest"
+)
+
+func newTestTap(config Config) *Tap {
+	t := New(beat.Info{Beat: "testbeat", Version: "6.3.0"}, config)
+	now := time.Date(2017, 10, 14, 8, 0, 0, 0, time.UTC)
+	t.now = func() time.Time { return now }
+	return t
+}
+
+func testConfig() Config {
+	config := DefaultConfig
+	config.Enabled = true
+	config.Token = "secret"
+	config.SampleRate = 1
+	return config
+}
+
+func receive(t *testing.T, s *Subscription) []map[string]interface{} {
+	var events []map[string]interface{}
+	for {
+		select {
+		case line, open := <-s.Events():
+			if !open {
+				return events
+			}
+			var event map[string]interface{}
+			require.NoError(t, json.Unmarshal(line, &event))
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func ids(events []map[string]interface{}) []float64 {
+	var ids []float64
+	for _, event := range events {
+		ids = append(ids, event["id"].(float64))
+	}
+	return ids
+}
+
+func TestTapDeliversEvents(t *testing.T) {
+	tap := newTestTap(testConfig())
+
+	// Events are not encoded without subscribers.
+	tap.Add(inspecttest.Event(0))
+
+	a, b := tap.Subscribe(), tap.Subscribe()
+	defer a.Close()
+	tap.Add(inspecttest.Event(1))
+	b.Close()
+	tap.Add(inspecttest.Event(2))
+
+	events := receive(t, a)
+	assert.Equal(t, []float64{1, 2}, ids(events))
+	assert.Equal(t, "2017-10-14T08:00:00.000Z", events[0]["@timestamp"])
+	assert.Equal(t, map[string]interface{}{
+		"beat":    "testbeat",
+		"type":    "doc",
+		"version": "6.3.0",
+	}, events[0]["@metadata"])
+
+	// Closed subscriptions still deliver the buffered events.
+	assert.Equal(t, []float64{1}, ids(receive(t, b)))
+}
+
+func TestTapRedactsFields(t *testing.T) {
+	config := testConfig()
+	config.RedactFields = []string{"user.password", "missing"}
+	tap := newTestTap(config)
+	s := tap.Subscribe()
+	defer s.Close()
+
+	event := inspecttest.Event(1)
+	tap.Add(event)
+
+	events := receive(t, s)
+	require.Len(t, events, 1)
+	assert.Equal(t, map[string]interface{}{
+		"name":     "alice",
+		"password": "xxxxx",
+	}, events[0]["user"])
+	assert.NotContains(t, events[0], "missing")
+
+	// The published event is not modified.
+	assert.Equal(t, "hunter2", event.Fields["user"].(common.MapStr)["password"])
+}
+
+func TestTapSampling(t *testing.T) {
+	config := testConfig()
+	config.SampleRate = 0.5
+	tap := newTestTap(config)
+	values := []float64{0.1, 0.7, 0.4, 0.5}
+	tap.random = func() float64 {
+		v := values[0]
+		values = values[1:]
+		return v
+	}
+	s := tap.Subscribe()
+	defer s.Close()
+
+	for i := 1; i <= 4; i++ {
+		tap.Add(inspecttest.Event(i))
+	}
+	assert.Equal(t, []float64{1, 3}, ids(receive(t, s)))
+}
+
+func TestTapRateLimit(t *testing.T) {
+	config := testConfig()
+	config.MaxEventsPerSecond = 2
+	tap := newTestTap(config)
+	now := time.Now()
+	tap.now = func() time.Time { return now }
+	s := tap.Subscribe()
+	defer s.Close()
+
+	for i := 1; i <= 3; i++ {
+		tap.Add(inspecttest.Event(i))
+	}
+	now = now.Add(time.Second)
+	tap.Add(inspecttest.Event(4))
+	assert.Equal(t, []float64{1, 2, 4}, ids(receive(t, s)))
+}
+
+func TestTapDropsForSlowSubscribers(t *testing.T) {
+	config := testConfig()
+	config.BufferSize = 1
+	tap := newTestTap(config)
+	s := tap.Subscribe()
+	defer s.Close()
+
+	tap.Add(inspecttest.Event(1))
+	tap.Add(inspecttest.Event(2))
+	assert.Equal(t, []float64{1}, ids(receive(t, s)))
+	assert.Equal(t, 1, s.Dropped())
+}
+
+func TestTapAuthorized(t *testing.T) {
+	tap := newTestTap(testConfig())
+	assert.True(t, tap.Authorized("secret"))
+	assert.False(t, tap.Authorized("wrong"))
+	assert.False(t, tap.Authorized(""))
+}
+
+func TestConfigValidate(t *testing.T) {
+	for _, settings := range []map[string]interface{}{
+		{"enabled": true},
+		{"enabled": true, "token": "secret", "sample_rate": 0},
+		{"enabled": true, "token": "secret", "sample_rate": 1.5},
+		{"enabled": true, "token": "secret", "max_events_per_second": 0},
+	} {
+		cfg, err := common.NewConfigFrom(settings)
+		require.NoError(t, err)
+		config := DefaultConfig
+		assert.Error(t, cfg.Unpack(&config), "settings %v", settings)
+	}
+}
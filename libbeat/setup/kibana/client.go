@@ -105,6 +105,14 @@ func NewKibanaClient(cfg *common.Config) (*Client, error) {
 
 func (conn *Connection) Request(method, extraPath string,
 	params url.Values, body io.Reader) (int, []byte, error) {
+	return conn.RequestWithHeaders(method, extraPath, params, nil, body)
+}
+
+// RequestWithHeaders sends the request like Request, with the headers set in
+// addition to the default headers, or replacing them, like the Content-Type
+// of multipart requests.
+func (conn *Connection) RequestWithHeaders(method, extraPath string,
+	params url.Values, headers http.Header, body io.Reader) (int, []byte, error) {
 
 	reqURL := addToURL(conn.URL, extraPath, params)
 
@@ -124,6 +132,10 @@ func (conn *Connection) Request(method, extraPath string,
 		req.Header.Set("kbn-version", conn.version)
 	}
 
+	for name, values := range headers {
+		req.Header[http.CanonicalHeaderKey(name)] = values
+	}
+
 	resp, err := conn.http.Do(req)
 	if err != nil {
 		return 0, nil, fmt.Errorf("fail to execute the HTTP %s request: %v", method, err)
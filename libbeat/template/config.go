@@ -1,5 +1,7 @@
 package template
 
+import "time"
+
 type TemplateConfig struct {
 	Enabled   bool             `config:"enabled"`
 	Name      string           `config:"name"`
@@ -7,6 +9,13 @@ type TemplateConfig struct {
 	Fields    string           `config:"fields"`
 	Overwrite bool             `config:"overwrite"`
 	Settings  TemplateSettings `config:"settings"`
+
+	// SkipUnauthorized continues without the template if Elasticsearch
+	// denies loading it, instead of failing the connection.
+	SkipUnauthorized bool `config:"skip_unauthorized"`
+
+	// Lock ensures a single Beat loads the template, if enabled.
+	Lock LockConfig `config:"lock"`
 }
 
 type TemplateSettings struct {
@@ -19,5 +28,10 @@ var (
 	DefaultConfig = TemplateConfig{
 		Enabled: true,
 		Fields:  "fields.yml",
+		Lock: LockConfig{
+			Index:   ".beats-setup",
+			TTL:     time.Minute,
+			Timeout: 2 * time.Minute,
+		},
 	}
 )
@@ -2,6 +2,10 @@ package template
 
 import (
 	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
 
 	"github.com/elastic/beats/libbeat/beat"
 	"github.com/elastic/beats/libbeat/common"
@@ -17,10 +21,20 @@ type ESClient interface {
 	GetVersion() string
 }
 
+// errForbidden indicates that Elasticsearch denied loading the template, as
+// the user of the Beat is not allowed to manage templates.
+var errForbidden = errors.New("not allowed to manage templates")
+
 type Loader struct {
 	config   TemplateConfig
 	client   ESClient
 	beatInfo beat.Info
+
+	// lockPollInterval is the time between the checks of an install in
+	// progress by another Beat.
+	lockPollInterval time.Duration
+	now              func() time.Time
+	sleep            func(time.Duration)
 }
 
 func NewLoader(cfg *common.Config, client ESClient, beatInfo beat.Info) (*Loader, error) {
@@ -32,9 +46,12 @@ func NewLoader(cfg *common.Config, client ESClient, beatInfo beat.Info) (*Loader
 	}
 
 	return &Loader{
-		config:   config,
-		client:   client,
-		beatInfo: beatInfo,
+		config:           config,
+		client:           client,
+		beatInfo:         beatInfo,
+		lockPollInterval: time.Second,
+		now:              time.Now,
+		sleep:            time.Sleep,
 	}, nil
 }
 
@@ -65,8 +82,18 @@ func (l *Loader) Load() error {
 			return fmt.Errorf("error creating template from file %s: %v", fieldsPath, err)
 		}
 
-		err = l.LoadTemplate(tmpl.GetName(), output)
+		if l.config.Lock.Enabled {
+			err = l.loadLocked(tmpl, output)
+		} else {
+			err = l.LoadTemplate(tmpl.GetName(), output)
+		}
 		if err != nil {
+			if l.config.SkipUnauthorized && errors.Cause(err) == errForbidden {
+				// The template is assumed to be loaded by an administrator,
+				// so the Beat can run with the privileges to publish only.
+				logp.Warn("Template %s not loaded, continuing as skip_unauthorized is enabled: %v", tmpl.GetName(), err)
+				return nil
+			}
 			return fmt.Errorf("could not load template: %v", err)
 		}
 	} else {
@@ -82,7 +109,13 @@ func (l *Loader) Load() error {
 func (l *Loader) LoadTemplate(templateName string, template map[string]interface{}) error {
 	logp.Debug("template", "Try loading template with name: %s", templateName)
 	path := "/_template/" + templateName
-	body, err := l.client.LoadJSON(path, template)
+	status, body, err := l.client.Request("PUT", path, "", nil, template)
+	if status == http.StatusForbidden {
+		return errors.Wrapf(errForbidden, "couldn't load template. Response body: %s", body)
+	}
+	if err == nil && status > 300 {
+		err = fmt.Errorf("status %v", status)
+	}
 	if err != nil {
 		return fmt.Errorf("couldn't load template: %v. Response body: %s", err, body)
 	}
@@ -0,0 +1,85 @@
+// +build !integration
+
+package template
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// fakeClient answers the template requests with fixed status codes.
+type fakeClient struct {
+	headStatus, putStatus int
+	requests              []string
+}
+
+func (c *fakeClient) LoadJSON(path string, json map[string]interface{}) ([]byte, error) {
+	return nil, errors.New("unexpected LoadJSON")
+}
+
+func (c *fakeClient) Request(method, path string, pipeline string, params map[string]string, body interface{}) (int, []byte, error) {
+	c.requests = append(c.requests, method+" "+path)
+	status := c.headStatus
+	if method == "PUT" {
+		status = c.putStatus
+	}
+	if status >= 300 {
+		return status, []byte(`{"error":"denied"}`), errors.New(http.StatusText(status))
+	}
+	return status, []byte(`{}`), nil
+}
+
+func (c *fakeClient) GetVersion() string {
+	return "6.2.0"
+}
+
+func newTestLoader(t *testing.T, client ESClient, settings map[string]interface{}) *Loader {
+	fields, err := filepath.Abs("testdata/fields.yml")
+	require.NoError(t, err)
+	settings["fields"] = fields
+
+	cfg, err := common.NewConfigFrom(settings)
+	require.NoError(t, err)
+	loader, err := NewLoader(cfg, client, beat.Info{Beat: "testbeat", IndexPrefix: "testbeat", Version: "6.2.0"})
+	require.NoError(t, err)
+	return loader
+}
+
+func TestLoad(t *testing.T) {
+	client := &fakeClient{headStatus: 404, putStatus: 200}
+	loader := newTestLoader(t, client, map[string]interface{}{})
+
+	assert.NoError(t, loader.Load())
+	assert.Equal(t, []string{"HEAD /_template/testbeat-6.2.0", "PUT /_template/testbeat-6.2.0"}, client.requests)
+}
+
+func TestLoadForbidden(t *testing.T) {
+	client := &fakeClient{headStatus: 403, putStatus: 403}
+
+	loader := newTestLoader(t, client, map[string]interface{}{})
+	err := loader.Load()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "not allowed to manage templates")
+	}
+
+	loader = newTestLoader(t, client, map[string]interface{}{"skip_unauthorized": true})
+	assert.NoError(t, loader.Load())
+}
+
+func TestLoadFailureNotSkipped(t *testing.T) {
+	// Only a denied request is skipped, other failures still fail the
+	// loading.
+	for _, status := range []int{400, 401, 500} {
+		client := &fakeClient{headStatus: 404, putStatus: status}
+		loader := newTestLoader(t, client, map[string]interface{}{"skip_unauthorized": true})
+		assert.Error(t, loader.Load(), "status %v", status)
+	}
+}
@@ -0,0 +1,223 @@
+package template
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// States of the install marker of a template.
+const (
+	stateInstalling = "installing"
+	stateInstalled  = "installed"
+)
+
+// LockConfig configures the install lock, ensuring a single Beat of a fleet
+// loads a template when many Beats start at once.
+type LockConfig struct {
+	Enabled bool `config:"enabled"`
+
+	// Index is the index the install markers are stored in.
+	Index string `config:"index"`
+
+	// TTL is how long an install in progress holds the lock. Once expired,
+	// the install is assumed to have failed and another Beat takes over.
+	TTL time.Duration `config:"ttl" validate:"positive"`
+
+	// Timeout is how long a Beat waits for the install of another Beat.
+	Timeout time.Duration `config:"timeout" validate:"positive"`
+}
+
+// installMarker is the document recording the install of a template by a
+// Beat. The template is current if it has been installed by the same version
+// of the Beat with the same content.
+type installMarker struct {
+	Template  string    `json:"template"`
+	Version   string    `json:"version"`
+	Checksum  string    `json:"checksum"`
+	State     string    `json:"state"`
+	Owner     string    `json:"owner"`
+	Timestamp time.Time `json:"@timestamp"`
+}
+
+// markerVersion identifies the revision of the install marker, used to update
+// it by compare and set.
+type markerVersion struct {
+	Version     int   `json:"_version"`
+	SeqNo       int64 `json:"_seq_no"`
+	PrimaryTerm int64 `json:"_primary_term"`
+}
+
+// markerResponse is the response of Elasticsearch to the requests of the
+// install marker.
+type markerResponse struct {
+	markerVersion
+	Found  bool          `json:"found"`
+	Source installMarker `json:"_source"`
+}
+
+// seqNoVersion is the first version of Elasticsearch updating documents by
+// compare and set on their sequence number. Elasticsearch 7 rejects the
+// internal version for compare and set.
+var seqNoVersion, _ = common.NewVersion("6.7.0")
+
+// loadLocked loads the template unless it has been loaded by another Beat.
+// The Beat creating the install marker loads the template, the other Beats
+// wait for the install to complete and skip loading the template once they
+// confirmed it exists.
+func (l *Loader) loadLocked(tmpl *Template, template map[string]interface{}) error {
+	name := tmpl.GetName()
+	path := l.markerPath(tmpl)
+
+	checksum, err := templateChecksum(template)
+	if err != nil {
+		return err
+	}
+	marker := installMarker{
+		Template: name,
+		Version:  l.beatInfo.Version,
+		Checksum: checksum,
+		Owner:    fmt.Sprintf("%s/%s", l.beatInfo.Hostname, l.beatInfo.UUID),
+	}
+
+	deadline := l.now().Add(l.config.Lock.Timeout)
+	for {
+		current, found, err := l.getMarker(path)
+		if err != nil {
+			return err
+		}
+
+		currentVersion := found && current.Source.Version == marker.Version && current.Source.Checksum == marker.Checksum
+		switch {
+		case currentVersion && current.Source.State == stateInstalled && l.CheckTemplate(name):
+			logp.Info("Template %s already loaded by %s, skipping", name, current.Source.Owner)
+			return nil
+
+		case currentVersion && current.Source.State == stateInstalling && l.now().Sub(current.Source.Timestamp) < l.config.Lock.TTL:
+			logp.Debug("template", "Waiting for %s to load template %s", current.Source.Owner, name)
+
+		default:
+			// The template is not loaded yet, the install failed or a
+			// different template has been loaded, so the lock is acquired
+			// to load the template.
+			version, acquired, err := l.acquireMarker(tmpl, path, marker, found, current.markerVersion)
+			if err != nil {
+				return err
+			}
+			if acquired {
+				return l.installLocked(tmpl, template, path, marker, version)
+			}
+			// Another Beat acquired the lock first.
+			continue
+		}
+
+		if !l.now().Before(deadline) {
+			return fmt.Errorf("timeout waiting for %s to load template %s", current.Source.Owner, name)
+		}
+		l.sleep(l.lockPollInterval)
+	}
+}
+
+// installLocked loads the template while holding the lock, and marks the
+// template as installed.
+func (l *Loader) installLocked(tmpl *Template, template map[string]interface{}, path string, marker installMarker, version markerVersion) error {
+	name := tmpl.GetName()
+	params := compareAndSetParams(tmpl, version)
+	if err := l.LoadTemplate(name, template); err != nil {
+		// Release the lock, so another Beat can load the template without
+		// waiting for the lock to expire.
+		l.client.Request("DELETE", path, "", params, nil)
+		return err
+	}
+
+	marker.State = stateInstalled
+	marker.Timestamp = l.now().UTC()
+	if status, body, err := l.client.Request("PUT", path, "", params, marker); err != nil {
+		// The template is loaded, the other Beats load it again.
+		logp.Warn("Failed to mark template %s as loaded (status=%v): %v. Response body: %s", name, status, err, body)
+	}
+	return nil
+}
+
+// getMarker returns the install marker of the template, if it exists.
+func (l *Loader) getMarker(path string) (markerResponse, bool, error) {
+	var resp markerResponse
+	status, body, err := l.client.Request("GET", path, "", nil, nil)
+	if status == http.StatusNotFound {
+		return resp, false, nil
+	}
+	if err != nil {
+		return resp, false, fmt.Errorf("couldn't get template install marker: %v. Response body: %s", err, body)
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return resp, false, fmt.Errorf("couldn't parse template install marker: %v", err)
+	}
+	return resp, resp.Found, nil
+}
+
+// acquireMarker writes the install marker, creating it if it doesn't exist
+// yet or replacing the version read before. It returns the version of the
+// marker written, or false if another Beat has written the marker before.
+func (l *Loader) acquireMarker(tmpl *Template, path string, marker installMarker, found bool, version markerVersion) (markerVersion, bool, error) {
+	params := map[string]string{"op_type": "create"}
+	if found {
+		params = compareAndSetParams(tmpl, version)
+	}
+
+	marker.State = stateInstalling
+	marker.Timestamp = l.now().UTC()
+	status, body, err := l.client.Request("PUT", path, "", params, marker)
+	if status == http.StatusConflict {
+		return markerVersion{}, false, nil
+	}
+	if err != nil {
+		return markerVersion{}, false, fmt.Errorf("couldn't write template install marker: %v. Response body: %s", err, body)
+	}
+
+	var resp markerResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return markerVersion{}, false, fmt.Errorf("couldn't parse template install marker response: %v", err)
+	}
+	return resp.markerVersion, true, nil
+}
+
+// compareAndSetParams returns the parameters updating the install marker only
+// if it has not been changed since the version read, using the sequence number
+// if supported by the Elasticsearch version.
+func compareAndSetParams(tmpl *Template, version markerVersion) map[string]string {
+	if tmpl.esVersion.LessThan(seqNoVersion) {
+		return map[string]string{"version": strconv.Itoa(version.Version)}
+	}
+	return map[string]string{
+		"if_seq_no":       strconv.FormatInt(version.SeqNo, 10),
+		"if_primary_term": strconv.FormatInt(version.PrimaryTerm, 10),
+	}
+}
+
+// markerPath returns the path of the install marker document of the
+// template, of the type supported by the Elasticsearch version.
+func (l *Loader) markerPath(tmpl *Template) string {
+	docType := "doc"
+	if tmpl.esVersion.Major >= 7 {
+		docType = "_doc"
+	}
+	return "/" + l.config.Lock.Index + "/" + docType + "/" + tmpl.GetName()
+}
+
+// templateChecksum identifies the content of a template. The keys of the
+// template are sorted when encoded, so equal templates have the same checksum.
+func templateChecksum(template map[string]interface{}) (string, error) {
+	data, err := json.Marshal(template)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,314 @@
+// +build !integration
+
+package template
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+type fakeDoc struct {
+	version int
+	seqNo   int64
+	source  json.RawMessage
+}
+
+// fakeES emulates the template and document APIs used by the install lock,
+// for many Beats at once.
+type fakeES struct {
+	mu           sync.Mutex
+	templates    map[string]bool
+	docs         map[string]fakeDoc
+	installs     int
+	installDelay time.Duration
+	installFail  bool
+
+	// version is the version of Elasticsearch, deciding the compare and set
+	// parameters accepted.
+	version string
+	seqNo   int64
+}
+
+func newFakeES() *fakeES {
+	return &fakeES{templates: map[string]bool{}, docs: map[string]fakeDoc{}, version: "6.2.0"}
+}
+
+func (es *fakeES) LoadJSON(path string, json map[string]interface{}) ([]byte, error) {
+	return nil, errors.New("unexpected LoadJSON")
+}
+
+func (es *fakeES) GetVersion() string {
+	return es.version
+}
+
+// markerPath is the path of the install marker of the test template.
+func (es *fakeES) markerPath() string {
+	if strings.HasPrefix(es.version, "6.") {
+		return "/.beats-setup/doc/testbeat-6.2.0"
+	}
+	return "/.beats-setup/_doc/testbeat-6.2.0"
+}
+
+// seqNoSupported returns true if the version updates documents by compare and
+// set on the sequence number, Elasticsearch 6.7 has been the first one.
+func (es *fakeES) seqNoSupported() bool {
+	version, _ := common.NewVersion(es.version)
+	return !version.LessThan(seqNoVersion)
+}
+
+func (es *fakeES) Request(method, path string, pipeline string, params map[string]string, body interface{}) (int, []byte, error) {
+	if strings.HasPrefix(path, "/_template/") {
+		return es.template(method, strings.TrimPrefix(path, "/_template/"))
+	}
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	doc, found := es.docs[path]
+	if v, ok := params["version"]; ok {
+		// Elasticsearch 7 rejects the internal version for compare and set.
+		if !strings.HasPrefix(es.version, "6.") {
+			return fail(http.StatusBadRequest)
+		}
+		if !found || strconv.Itoa(doc.version) != v {
+			return fail(http.StatusConflict)
+		}
+	}
+	if v, ok := params["if_seq_no"]; ok {
+		if !es.seqNoSupported() || params["if_primary_term"] != "1" {
+			return fail(http.StatusBadRequest)
+		}
+		if !found || strconv.FormatInt(doc.seqNo, 10) != v {
+			return fail(http.StatusConflict)
+		}
+	}
+
+	switch method {
+	case "GET":
+		if !found {
+			return fail(http.StatusNotFound)
+		}
+		resp := map[string]interface{}{"found": true, "_version": doc.version, "_source": doc.source}
+		if es.seqNoSupported() {
+			resp["_seq_no"] = doc.seqNo
+			resp["_primary_term"] = 1
+		}
+		return respond(200, resp)
+	case "PUT":
+		if found && params["op_type"] == "create" {
+			return fail(http.StatusConflict)
+		}
+		source, _ := json.Marshal(body)
+		doc = es.putDoc(path, source)
+		return respond(201, map[string]interface{}{"_version": doc.version, "_seq_no": doc.seqNo, "_primary_term": 1})
+	case "DELETE":
+		delete(es.docs, path)
+		return respond(200, map[string]interface{}{"found": found})
+	}
+	return fail(http.StatusMethodNotAllowed)
+}
+
+// putDoc stores a new revision of the document.
+func (es *fakeES) putDoc(path string, source json.RawMessage) fakeDoc {
+	es.seqNo++
+	doc := fakeDoc{version: es.docs[path].version + 1, seqNo: es.seqNo, source: source}
+	es.docs[path] = doc
+	return doc
+}
+
+func (es *fakeES) template(method, name string) (int, []byte, error) {
+	if method == "HEAD" {
+		es.mu.Lock()
+		defer es.mu.Unlock()
+		if !es.templates[name] {
+			return fail(http.StatusNotFound)
+		}
+		return respond(200, nil)
+	}
+
+	// The template is loaded slowly, so the other Beats starting at the
+	// same time find the install in progress.
+	time.Sleep(es.installDelay)
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if es.installFail {
+		return fail(http.StatusInternalServerError)
+	}
+	es.templates[name] = true
+	es.installs++
+	return respond(200, map[string]interface{}{"acknowledged": true})
+}
+
+func (es *fakeES) marker(t *testing.T) installMarker {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	var marker installMarker
+	require.NoError(t, json.Unmarshal(es.docs[es.markerPath()].source, &marker))
+	return marker
+}
+
+func (es *fakeES) putMarker(t *testing.T, marker installMarker) {
+	source, err := json.Marshal(marker)
+	require.NoError(t, err)
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	es.putDoc(es.markerPath(), source)
+}
+
+func respond(status int, body interface{}) (int, []byte, error) {
+	data, _ := json.Marshal(body)
+	return status, data, nil
+}
+
+func fail(status int) (int, []byte, error) {
+	return status, []byte(`{"error":"failed"}`), errors.New(http.StatusText(status))
+}
+
+func newLockedLoader(t *testing.T, es *fakeES, settings map[string]interface{}) *Loader {
+	settings["lock.enabled"] = true
+	loader := newTestLoader(t, es, settings)
+	loader.lockPollInterval = time.Millisecond
+	return loader
+}
+
+func TestLoadLockedConcurrent(t *testing.T) {
+	es := newFakeES()
+	es.installDelay = 50 * time.Millisecond
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		loader := newLockedLoader(t, es, map[string]interface{}{})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- loader.Load()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, es.installs)
+	assert.Equal(t, stateInstalled, es.marker(t).State)
+}
+
+func TestLoadLockedSkipIfCurrent(t *testing.T) {
+	es := newFakeES()
+	settings := map[string]interface{}{"overwrite": true}
+
+	require.NoError(t, newLockedLoader(t, es, settings).Load())
+	marker := es.marker(t)
+	assert.Equal(t, "testbeat-6.2.0", marker.Template)
+	assert.Equal(t, "6.2.0", marker.Version)
+	assert.NotEmpty(t, marker.Checksum)
+
+	// The current template is not overwritten again.
+	require.NoError(t, newLockedLoader(t, es, settings).Load())
+	assert.Equal(t, 1, es.installs)
+
+	// A template deleted since is loaded again.
+	delete(es.templates, "testbeat-6.2.0")
+	require.NoError(t, newLockedLoader(t, es, settings).Load())
+	assert.Equal(t, 2, es.installs)
+
+	// A template with a different content is loaded.
+	settings["settings.index.number_of_shards"] = 3
+	require.NoError(t, newLockedLoader(t, es, settings).Load())
+	assert.Equal(t, 3, es.installs)
+	assert.NotEqual(t, marker.Checksum, es.marker(t).Checksum)
+}
+
+func TestLoadLockedInstallInProgress(t *testing.T) {
+	es := newFakeES()
+	loader := newLockedLoader(t, es, map[string]interface{}{"lock.ttl": "1m", "lock.timeout": "30s"})
+	now := time.Now()
+	loader.now = func() time.Time { return now }
+	loader.sleep = func(d time.Duration) { now = now.Add(time.Second) }
+
+	checksum := func() string {
+		tmpl, err := New("6.2.0", "testbeat", "6.2.0", loader.config)
+		require.NoError(t, err)
+		output, err := tmpl.Load(loader.config.Fields)
+		require.NoError(t, err)
+		sum, err := templateChecksum(output)
+		require.NoError(t, err)
+		return sum
+	}()
+	inProgress := installMarker{
+		Template:  "testbeat-6.2.0",
+		Version:   "6.2.0",
+		Checksum:  checksum,
+		State:     stateInstalling,
+		Owner:     "other",
+		Timestamp: now,
+	}
+
+	// The Beat waits for the install of another Beat, until it times out.
+	es.putMarker(t, inProgress)
+	err := loader.Load()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "timeout waiting for other")
+	}
+	assert.Equal(t, 0, es.installs)
+
+	// An install not completed within the TTL is taken over.
+	inProgress.Timestamp = now.Add(-2 * time.Minute)
+	es.putMarker(t, inProgress)
+	require.NoError(t, loader.Load())
+	assert.Equal(t, 1, es.installs)
+	assert.Equal(t, stateInstalled, es.marker(t).State)
+	assert.NotEqual(t, "other", es.marker(t).Owner)
+}
+
+func TestLoadLockedFailureReleasesLock(t *testing.T) {
+	es := newFakeES()
+	es.installFail = true
+
+	assert.Error(t, newLockedLoader(t, es, map[string]interface{}{}).Load())
+	assert.NotContains(t, es.docs, es.markerPath())
+
+	es.installFail = false
+	require.NoError(t, newLockedLoader(t, es, map[string]interface{}{}).Load())
+	assert.Equal(t, 1, es.installs)
+}
+
+func TestLoadLockedTakeOver(t *testing.T) {
+	// The marker of a previous version of the Beat is replaced by compare and
+	// set, on the sequence number if supported by Elasticsearch.
+	for _, version := range []string{"6.2.0", "6.7.0", "7.0.0", "7.4.2"} {
+		es := newFakeES()
+		es.version = version
+		es.putMarker(t, installMarker{
+			Template:  "testbeat-6.2.0",
+			Version:   "6.1.0",
+			State:     stateInstalled,
+			Owner:     "other",
+			Timestamp: time.Now(),
+		})
+
+		require.NoError(t, newLockedLoader(t, es, map[string]interface{}{}).Load(), version)
+		assert.Equal(t, 1, es.installs, version)
+
+		marker := es.marker(t)
+		assert.Equal(t, stateInstalled, marker.State, version)
+		assert.Equal(t, "6.2.0", marker.Version, version)
+		assert.NotEqual(t, "other", marker.Owner, version)
+	}
+}
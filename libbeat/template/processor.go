@@ -19,6 +19,10 @@ var (
 // long, geo_point, date, short, byte, float, double, boolean
 func (p *Processor) process(fields common.Fields, path string, output common.MapStr) error {
 	for _, field := range fields {
+		// Runtime fields are defined in the Kibana index pattern only.
+		if field.Runtime {
+			continue
+		}
 
 		field.Path = path
 		var mapping common.MapStr
@@ -40,6 +44,8 @@ func (p *Processor) process(fields common.Fields, path string, output common.Map
 			mapping = p.object(&field)
 		case "array":
 			mapping = p.array(&field)
+		case "alias":
+			mapping = p.alias(&field)
 		case "group":
 			var newPath string
 			if path == "" {
@@ -90,6 +96,15 @@ func (p *Processor) other(f *common.Field) common.MapStr {
 	return property
 }
 
+// alias maps an alias field to the field at its path. Alias fields do not
+// support any other mapping parameters.
+func (p *Processor) alias(f *common.Field) common.MapStr {
+	return common.MapStr{
+		"type": "alias",
+		"path": f.AliasPath,
+	}
+}
+
 func (p *Processor) integer(f *common.Field) common.MapStr {
 	property := getDefaultProperties(f)
 	property["type"] = "long"
@@ -212,6 +227,11 @@ func (p *Processor) object(f *common.Field) common.MapStr {
 	case "keyword":
 		dynProperties["type"] = f.ObjectType
 		addDynamicTemplate(f, dynProperties, "string")
+	case "double":
+		// Floating point values without a fraction are encoded as integers,
+		// so all values are matched.
+		dynProperties["type"] = f.ObjectType
+		addDynamicTemplate(f, dynProperties, "*")
 	}
 
 	properties := getDefaultProperties(f)
@@ -25,6 +25,10 @@ func TestProcessor(t *testing.T) {
 			output:   p.other(&common.Field{Type: "long"}),
 			expected: common.MapStr{"type": "long"},
 		},
+		{
+			output:   p.alias(&common.Field{Type: "alias", AliasPath: "a.b", Index: &falseVar, CopyTo: "c"}),
+			expected: common.MapStr{"type": "alias", "path": "a.b"},
+		},
 		{
 			output: p.scaledFloat(&common.Field{Type: "scaled_float"}),
 			expected: common.MapStr{
@@ -218,6 +222,19 @@ func TestDynamicTemplate(t *testing.T) {
 				},
 			},
 		},
+		{
+			field: common.Field{
+				Type: "object", ObjectType: "double",
+				Name: "metrics",
+			},
+			expected: common.MapStr{
+				"metrics": common.MapStr{
+					"mapping":            common.MapStr{"type": "double"},
+					"match_mapping_type": "*",
+					"path_match":         "metrics.*",
+				},
+			},
+		},
 		{
 			field: common.Field{
 				Type: "object", ObjectType: "text",
@@ -289,3 +306,24 @@ func TestPropertiesCombine(t *testing.T) {
 	assert.Equal(t, v1, common.MapStr{"type": "text", "norms": false})
 	assert.Equal(t, v2, common.MapStr{"type": "text", "norms": false})
 }
+
+func TestProcessRuntimeFields(t *testing.T) {
+	fields := common.Fields{
+		common.Field{Name: "status", Type: "long"},
+		common.Field{Name: "ok", Type: "boolean", Runtime: true, Script: "emit(doc['status'].value < 400)"},
+	}
+
+	output := common.MapStr{}
+	version, err := common.NewVersion("6.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := Processor{EsVersion: *version}
+	if err := p.process(fields, "", output); err != nil {
+		t.Fatal(err)
+	}
+
+	// runtime fields are not mapped
+	assert.Equal(t, common.MapStr{"status": common.MapStr{"type": "long"}}, output)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/elastic/beats/libbeat/beat"
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/common/fmtstr"
+	"github.com/elastic/beats/libbeat/version"
 )
 
 var (
@@ -161,7 +162,8 @@ func (t *Template) generate(properties common.MapStr, dynamicTemplates []common.
 		"mappings": common.MapStr{
 			mappingName: common.MapStr{
 				"_meta": common.MapStr{
-					"version": t.beatVersion.String(),
+					"version":     t.beatVersion.String(),
+					"ecs_version": version.ECSVersion,
 				},
 				"date_detection":    defaultDateDetection,
 				"dynamic_templates": dynamicTemplates,
@@ -0,0 +1,5 @@
+package version
+
+// ECSVersion is the version of the Elastic Common Schema the events published
+// by the Beats conform to. It is stamped into every event as `ecs.version`.
+const ECSVersion = "1.0.0"
@@ -21,9 +21,10 @@ import (
 
 // Metricbeat implements the Beater interface for metricbeat.
 type Metricbeat struct {
-	done    chan struct{}  // Channel used to initiate shutdown.
-	modules []staticModule // Active list of modules.
-	config  Config
+	done       chan struct{}    // Channel used to initiate shutdown.
+	modules    []staticModule   // Active list of modules.
+	discovered []cfgfile.Runner // Modules with hosts discovered via SRV records.
+	config     Config
 }
 
 type staticModule struct {
@@ -48,6 +49,7 @@ func New(b *beat.Beat, rawConfig *common.Config) (beat.Beater, error) {
 
 	var errs multierror.Errors
 	var modules []staticModule
+	var discovered []cfgfile.Runner
 	for _, moduleCfg := range config.Modules {
 		if !moduleCfg.Enabled() {
 			continue
@@ -61,6 +63,17 @@ func New(b *beat.Beat, rawConfig *common.Config) (beat.Beater, error) {
 			failed = true
 		}
 
+		if module.HasSRV(moduleCfg) {
+			factory := module.NewFactory(config.MaxStartDelay, b.Publisher)
+			runner, err := factory.Create(moduleCfg)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			discovered = append(discovered, runner)
+			continue
+		}
+
 		connector, err := module.NewConnector(b.Publisher, moduleCfg)
 		if err != nil {
 			errs = append(errs, err)
@@ -86,14 +99,15 @@ func New(b *beat.Beat, rawConfig *common.Config) (beat.Beater, error) {
 	if err := errs.Err(); err != nil {
 		return nil, err
 	}
-	if len(modules) == 0 && !dynamicCfgEnabled {
+	if len(modules) == 0 && len(discovered) == 0 && !dynamicCfgEnabled {
 		return nil, mb.ErrAllModulesDisabled
 	}
 
 	mb := &Metricbeat{
-		done:    make(chan struct{}),
-		modules: modules,
-		config:  config,
+		done:       make(chan struct{}),
+		modules:    modules,
+		discovered: discovered,
+		config:     config,
 	}
 	return mb, nil
 }
@@ -122,6 +136,16 @@ func (bt *Metricbeat) Run(b *beat.Beat) error {
 		}()
 	}
 
+	for _, r := range bt.discovered {
+		r.Start()
+		wg.Add(1)
+		go func(r cfgfile.Runner) {
+			defer wg.Done()
+			<-bt.done
+			r.Stop()
+		}(r)
+	}
+
 	if bt.config.ConfigModules.Enabled() {
 		moduleReloader := cfgfile.NewReloader(bt.config.ConfigModules)
 		factory := module.NewFactory(bt.config.MaxStartDelay, b.Publisher)
@@ -34,7 +34,13 @@ func (u *UdpEvent) GetMeta() server.Meta {
 }
 
 func NewUdpServer(base mb.BaseMetricSet) (server.Server, error) {
-	config := defaultUdpConfig()
+	return NewUdpServerWithDefaults(base, defaultUdpConfig())
+}
+
+// NewUdpServerWithDefaults creates a UDP server like NewUdpServer, with the
+// defaults for the settings not set in the configuration of the module.
+func NewUdpServerWithDefaults(base mb.BaseMetricSet, defaults UdpConfig) (server.Server, error) {
+	config := defaults
 	err := base.Module().UnpackConfig(&config)
 	if err != nil {
 		return nil, err
@@ -82,9 +88,13 @@ func (g *UdpServer) watchMetrics() {
 			continue
 		}
 
+		// The buffer is reused for the next message, so the data is copied.
+		data := make([]byte, length)
+		copy(data, buffer[:length])
+
 		g.eventQueue <- &UdpEvent{
 			event: common.MapStr{
-				server.EventDataKey: buffer[:length],
+				server.EventDataKey: data,
 			},
 			meta: server.Meta{
 				"client_ip": addr.IP.String(),
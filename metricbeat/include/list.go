@@ -12,17 +12,26 @@ import (
 	_ "github.com/elastic/beats/metricbeat/module/aerospike/namespace"
 	_ "github.com/elastic/beats/metricbeat/module/apache"
 	_ "github.com/elastic/beats/metricbeat/module/apache/status"
+	_ "github.com/elastic/beats/metricbeat/module/beat"
+	_ "github.com/elastic/beats/metricbeat/module/beat/stats"
 	_ "github.com/elastic/beats/metricbeat/module/ceph"
 	_ "github.com/elastic/beats/metricbeat/module/ceph/cluster_disk"
 	_ "github.com/elastic/beats/metricbeat/module/ceph/cluster_health"
 	_ "github.com/elastic/beats/metricbeat/module/ceph/cluster_status"
 	_ "github.com/elastic/beats/metricbeat/module/ceph/monitor_health"
 	_ "github.com/elastic/beats/metricbeat/module/ceph/pool_disk"
+	_ "github.com/elastic/beats/metricbeat/module/clickhouse"
+	_ "github.com/elastic/beats/metricbeat/module/clickhouse/status"
+	_ "github.com/elastic/beats/metricbeat/module/clickhouse/system_metrics"
+	_ "github.com/elastic/beats/metricbeat/module/consul"
+	_ "github.com/elastic/beats/metricbeat/module/consul/agent"
+	_ "github.com/elastic/beats/metricbeat/module/consul/health"
 	_ "github.com/elastic/beats/metricbeat/module/couchbase"
 	_ "github.com/elastic/beats/metricbeat/module/couchbase/bucket"
 	_ "github.com/elastic/beats/metricbeat/module/couchbase/cluster"
 	_ "github.com/elastic/beats/metricbeat/module/couchbase/node"
 	_ "github.com/elastic/beats/metricbeat/module/docker"
+	_ "github.com/elastic/beats/metricbeat/module/docker/activity"
 	_ "github.com/elastic/beats/metricbeat/module/docker/container"
 	_ "github.com/elastic/beats/metricbeat/module/docker/cpu"
 	_ "github.com/elastic/beats/metricbeat/module/docker/diskio"
@@ -36,6 +45,10 @@ import (
 	_ "github.com/elastic/beats/metricbeat/module/elasticsearch"
 	_ "github.com/elastic/beats/metricbeat/module/elasticsearch/node"
 	_ "github.com/elastic/beats/metricbeat/module/elasticsearch/node_stats"
+	_ "github.com/elastic/beats/metricbeat/module/etcd"
+	_ "github.com/elastic/beats/metricbeat/module/etcd/member"
+	_ "github.com/elastic/beats/metricbeat/module/etcd/metrics"
+	_ "github.com/elastic/beats/metricbeat/module/etcd/status"
 	_ "github.com/elastic/beats/metricbeat/module/golang"
 	_ "github.com/elastic/beats/metricbeat/module/golang/expvar"
 	_ "github.com/elastic/beats/metricbeat/module/golang/heap"
@@ -45,6 +58,7 @@ import (
 	_ "github.com/elastic/beats/metricbeat/module/haproxy/info"
 	_ "github.com/elastic/beats/metricbeat/module/haproxy/stat"
 	_ "github.com/elastic/beats/metricbeat/module/http"
+	_ "github.com/elastic/beats/metricbeat/module/http/graphql"
 	_ "github.com/elastic/beats/metricbeat/module/http/json"
 	_ "github.com/elastic/beats/metricbeat/module/http/server"
 	_ "github.com/elastic/beats/metricbeat/module/jolokia"
@@ -71,6 +85,7 @@ import (
 	_ "github.com/elastic/beats/metricbeat/module/memcached/stats"
 	_ "github.com/elastic/beats/metricbeat/module/mongodb"
 	_ "github.com/elastic/beats/metricbeat/module/mongodb/dbstats"
+	_ "github.com/elastic/beats/metricbeat/module/mongodb/replstatus"
 	_ "github.com/elastic/beats/metricbeat/module/mongodb/status"
 	_ "github.com/elastic/beats/metricbeat/module/mysql"
 	_ "github.com/elastic/beats/metricbeat/module/mysql/status"
@@ -86,23 +101,29 @@ import (
 	_ "github.com/elastic/beats/metricbeat/module/prometheus/collector"
 	_ "github.com/elastic/beats/metricbeat/module/prometheus/stats"
 	_ "github.com/elastic/beats/metricbeat/module/rabbitmq"
+	_ "github.com/elastic/beats/metricbeat/module/rabbitmq/federation"
 	_ "github.com/elastic/beats/metricbeat/module/rabbitmq/node"
 	_ "github.com/elastic/beats/metricbeat/module/rabbitmq/queue"
+	_ "github.com/elastic/beats/metricbeat/module/rabbitmq/shovel"
 	_ "github.com/elastic/beats/metricbeat/module/redis"
 	_ "github.com/elastic/beats/metricbeat/module/redis/info"
 	_ "github.com/elastic/beats/metricbeat/module/redis/keyspace"
+	_ "github.com/elastic/beats/metricbeat/module/statsd"
+	_ "github.com/elastic/beats/metricbeat/module/statsd/server"
 	_ "github.com/elastic/beats/metricbeat/module/system"
 	_ "github.com/elastic/beats/metricbeat/module/system/core"
 	_ "github.com/elastic/beats/metricbeat/module/system/cpu"
 	_ "github.com/elastic/beats/metricbeat/module/system/diskio"
 	_ "github.com/elastic/beats/metricbeat/module/system/filesystem"
 	_ "github.com/elastic/beats/metricbeat/module/system/fsstat"
+	_ "github.com/elastic/beats/metricbeat/module/system/gpu"
 	_ "github.com/elastic/beats/metricbeat/module/system/load"
 	_ "github.com/elastic/beats/metricbeat/module/system/memory"
 	_ "github.com/elastic/beats/metricbeat/module/system/network"
 	_ "github.com/elastic/beats/metricbeat/module/system/process"
 	_ "github.com/elastic/beats/metricbeat/module/system/process_summary"
 	_ "github.com/elastic/beats/metricbeat/module/system/socket"
+	_ "github.com/elastic/beats/metricbeat/module/system/systemd"
 	_ "github.com/elastic/beats/metricbeat/module/system/uptime"
 	_ "github.com/elastic/beats/metricbeat/module/vsphere"
 	_ "github.com/elastic/beats/metricbeat/module/vsphere/datastore"
@@ -12,11 +12,18 @@ type Connector struct {
 	pipeline   beat.Pipeline
 	processors *processors.Processors
 	eventMeta  common.EventMetadata
+
+	// processingPipeline is the name of the processing pipeline to use, if set.
+	processingPipeline string
 }
 
 type connectorConfig struct {
 	Processors           processors.PluginConfig `config:"processors"`
 	common.EventMetadata `config:",inline"`      // Fields and tags to add to events.
+	ProcessingPipeline   string                  `config:"processing_pipeline"`
+
+	// Percentiles to compute from histogram fields, before running processors.
+	HistogramPercentiles *common.Config `config:"histogram_percentiles"`
 }
 
 func NewConnector(pipeline beat.Pipeline, c *common.Config) (*Connector, error) {
@@ -25,21 +32,31 @@ func NewConnector(pipeline beat.Pipeline, c *common.Config) (*Connector, error)
 		return nil, err
 	}
 
-	processors, err := processors.New(config.Processors)
+	procs, err := processors.New(config.Processors)
 	if err != nil {
 		return nil, err
 	}
 
+	if config.HistogramPercentiles != nil {
+		histogram, err := newHistogramPercentiles(config.HistogramPercentiles)
+		if err != nil {
+			return nil, err
+		}
+		procs.List = append([]processors.Processor{histogram}, procs.List...)
+	}
+
 	return &Connector{
-		pipeline:   pipeline,
-		processors: processors,
-		eventMeta:  config.EventMetadata,
+		pipeline:           pipeline,
+		processors:         procs,
+		eventMeta:          config.EventMetadata,
+		processingPipeline: config.ProcessingPipeline,
 	}, nil
 }
 
 func (c *Connector) Connect() (beat.Client, error) {
 	return c.pipeline.ConnectWith(beat.ClientConfig{
-		EventMetadata: c.eventMeta,
-		Processor:     c.processors,
+		EventMetadata:      c.eventMeta,
+		Processor:          c.processors,
+		ProcessingPipeline: c.processingPipeline,
 	})
 }
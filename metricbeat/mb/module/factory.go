@@ -28,6 +28,14 @@ func NewFactory(maxStartDelay time.Duration, p beat.Pipeline) *Factory {
 }
 
 func (r *Factory) Create(c *common.Config) (cfgfile.Runner, error) {
+	if HasSRV(c) {
+		return NewSRVRunner(c, DefaultSRVResolver, r.create)
+	}
+	return r.create(c)
+}
+
+// create builds a Runner for a module configuration with static hosts.
+func (r *Factory) create(c *common.Config) (Runner, error) {
 	var errs multierror.Errors
 
 	err := cfgwarn.CheckRemoved5xSettings(c, "filters")
@@ -0,0 +1,210 @@
+package module
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+const (
+	histogramBucketKey     = "bucket"
+	histogramPercentileKey = "percentile"
+)
+
+// histogramConfig configures the percentiles computed from histogram fields.
+type histogramConfig struct {
+	Fields      []string  `config:"fields"      validate:"required"`
+	Percentiles []float64 `config:"percentiles" validate:"nonzero"`
+}
+
+var defaultHistogramConfig = histogramConfig{
+	Percentiles: []float64{50, 90, 99},
+}
+
+func (c *histogramConfig) Validate() error {
+	for _, p := range c.Percentiles {
+		if p <= 0 || p > 100 {
+			return fmt.Errorf("percentile %v must be in the range (0, 100]", p)
+		}
+	}
+	return nil
+}
+
+// histogramPercentiles is a processor estimating percentiles from bucketed
+// histograms, like the Prometheus histograms reported by the prometheus
+// collector metricset. A histogram field contains a `bucket` object mapping
+// the upper bound of each bucket to the cumulative number of observations.
+// The estimated percentiles are stored in a `percentile` object next to the
+// buckets, keyed by percentile, the same way Prometheus summaries are
+// reported.
+type histogramPercentiles struct {
+	fields      []string
+	percentiles []float64
+}
+
+func newHistogramPercentiles(c *common.Config) (*histogramPercentiles, error) {
+	config := defaultHistogramConfig
+	if err := c.Unpack(&config); err != nil {
+		return nil, errors.Wrap(err, "failed to unpack histogram_percentiles config")
+	}
+
+	return &histogramPercentiles{
+		fields:      config.Fields,
+		percentiles: config.Percentiles,
+	}, nil
+}
+
+func (h *histogramPercentiles) Run(event *beat.Event) (*beat.Event, error) {
+	for _, field := range h.fields {
+		v, err := event.GetValue(field + "." + histogramBucketKey)
+		if err != nil {
+			continue
+		}
+
+		buckets, err := parseBuckets(v)
+		if err != nil {
+			logp.Debug("histogram", "Ignoring histogram field %v: %v", field, err)
+			continue
+		}
+
+		percentiles := common.MapStr{}
+		for _, p := range h.percentiles {
+			value, ok := buckets.quantile(p / 100)
+			if !ok {
+				continue
+			}
+			percentiles[strconv.FormatFloat(p, 'f', -1, 64)] = value
+		}
+
+		if len(percentiles) > 0 {
+			event.PutValue(field+"."+histogramPercentileKey, percentiles)
+		}
+	}
+	return event, nil
+}
+
+func (h *histogramPercentiles) String() string {
+	percentiles := make([]string, len(h.percentiles))
+	for i, p := range h.percentiles {
+		percentiles[i] = strconv.FormatFloat(p, 'f', -1, 64)
+	}
+	return fmt.Sprintf("histogram_percentiles=[fields=%v, percentiles=%v]",
+		strings.Join(h.fields, ", "), strings.Join(percentiles, ", "))
+}
+
+// bucket of a histogram with the cumulative count of observations less than
+// or equal to the upper bound.
+type bucket struct {
+	upperBound float64
+	count      float64
+}
+
+// buckets of a histogram, sorted by upper bound.
+type buckets []bucket
+
+// parseBuckets parses an object mapping bucket upper bounds to cumulative
+// counts. The buckets must include the `+Inf` bucket holding the total count.
+func parseBuckets(v interface{}) (buckets, error) {
+	var m map[string]interface{}
+	switch v := v.(type) {
+	case common.MapStr:
+		m = v
+	case map[string]interface{}:
+		m = v
+	default:
+		return nil, fmt.Errorf("expected bucket object, but got %T", v)
+	}
+
+	bs := make(buckets, 0, len(m))
+	for key, value := range m {
+		upperBound, err := strconv.ParseFloat(key, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bucket upper bound '%v'", key)
+		}
+
+		count, err := toFloat(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid count of bucket '%v': %v", key, err)
+		}
+
+		bs = append(bs, bucket{upperBound: upperBound, count: count})
+	}
+
+	sort.Slice(bs, func(i, j int) bool { return bs[i].upperBound < bs[j].upperBound })
+	if len(bs) < 2 || !math.IsInf(bs[len(bs)-1].upperBound, +1) {
+		return nil, errors.New("at least one bucket and the +Inf bucket are required")
+	}
+
+	// Counts are cumulative. Enforce monotonicity in case buckets were
+	// scraped inconsistently.
+	for i := 1; i < len(bs); i++ {
+		if bs[i].count < bs[i-1].count {
+			bs[i].count = bs[i-1].count
+		}
+	}
+	return bs, nil
+}
+
+// quantile estimates the q-quantile (0 < q <= 1) of the observations, using
+// linear interpolation within the bucket the quantile falls into, like the
+// Prometheus histogram_quantile function. The lower bound of the first bucket
+// is assumed to be 0. If the quantile falls into the +Inf bucket, the upper
+// bound of the highest finite bucket is returned.
+func (bs buckets) quantile(q float64) (float64, bool) {
+	total := bs[len(bs)-1].count
+	if total == 0 {
+		return 0, false
+	}
+
+	rank := q * total
+	b := sort.Search(len(bs)-1, func(i int) bool { return bs[i].count >= rank })
+
+	if b == len(bs)-1 {
+		return bs[len(bs)-2].upperBound, true
+	}
+	if b == 0 && bs[0].upperBound <= 0 {
+		return bs[0].upperBound, true
+	}
+
+	var start, count float64
+	end := bs[b].upperBound
+	if b == 0 {
+		count = bs[0].count
+	} else {
+		start = bs[b-1].upperBound
+		count = bs[b].count - bs[b-1].count
+		rank -= bs[b-1].count
+	}
+
+	if count == 0 {
+		return end, true
+	}
+	return start + (end-start)*(rank/count), true
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case uint64:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	case float32:
+		return float64(n), nil
+	case common.Float:
+		return float64(n), nil
+	default:
+		return 0, fmt.Errorf("expected number, but got %T", v)
+	}
+}
@@ -0,0 +1,241 @@
+package module
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+	_ "github.com/elastic/beats/libbeat/processors/actions"
+)
+
+func newTestConfig(t *testing.T, config map[string]interface{}) *common.Config {
+	c, err := common.NewConfigFrom(config)
+	require.NoError(t, err)
+	return c
+}
+
+// makeHistogram builds the cumulative buckets of the observations for the
+// given upper bounds, in the format reported by the prometheus collector.
+func makeHistogram(observations []float64, upperBounds []float64) common.MapStr {
+	bucketMap := common.MapStr{}
+	for _, ub := range append(upperBounds, math.Inf(+1)) {
+		var count uint64
+		for _, o := range observations {
+			if o <= ub {
+				count++
+			}
+		}
+		bucketMap[strconv.FormatFloat(ub, 'f', -1, 64)] = count
+	}
+	return bucketMap
+}
+
+func linearBounds(start, width float64, count int) []float64 {
+	bounds := make([]float64, count)
+	for i := range bounds {
+		bounds[i] = start + float64(i)*width
+	}
+	return bounds
+}
+
+// exactPercentile returns the nearest-rank percentile of sorted observations.
+func exactPercentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+func estimatePercentiles(t *testing.T, observations, bounds, percentiles []float64) common.MapStr {
+	h := &histogramPercentiles{fields: []string{"latency"}, percentiles: percentiles}
+	event := &beat.Event{Fields: common.MapStr{
+		"latency": common.MapStr{"bucket": makeHistogram(observations, bounds)},
+	}}
+
+	event, err := h.Run(event)
+	require.NoError(t, err)
+
+	v, err := event.GetValue("latency.percentile")
+	require.NoError(t, err)
+	return v.(common.MapStr)
+}
+
+func TestHistogramPercentilesKnownDistributions(t *testing.T) {
+	rnd := rand.New(rand.NewSource(42))
+	percentiles := []float64{50, 90, 99}
+
+	tests := []struct {
+		name      string
+		generate  func() float64
+		bounds    []float64
+		tolerance float64
+	}{
+		{
+			name:      "uniform",
+			generate:  func() float64 { return rnd.Float64() * 1000 },
+			bounds:    linearBounds(10, 10, 100),
+			tolerance: 10,
+		},
+		{
+			name:      "normal",
+			generate:  func() float64 { return rnd.NormFloat64()*15 + 100 },
+			bounds:    linearBounds(5, 5, 40),
+			tolerance: 5,
+		},
+		{
+			name:      "exponential",
+			generate:  func() float64 { return rnd.ExpFloat64() * 0.2 },
+			bounds:    []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+			tolerance: 0.25,
+		},
+	}
+
+	for _, test := range tests {
+		observations := make([]float64, 20000)
+		for i := range observations {
+			observations[i] = test.generate()
+		}
+		sorted := append([]float64(nil), observations...)
+		sort.Float64s(sorted)
+
+		estimated := estimatePercentiles(t, observations, test.bounds, percentiles)
+		require.Len(t, estimated, len(percentiles), test.name)
+		for _, p := range percentiles {
+			key := strconv.FormatFloat(p, 'f', -1, 64)
+			assert.InDelta(t, exactPercentile(sorted, p), estimated[key], test.tolerance,
+				"%v p%v", test.name, key)
+		}
+	}
+}
+
+func TestHistogramQuantileInterpolation(t *testing.T) {
+	bs, err := parseBuckets(common.MapStr{
+		"1":    uint64(10),
+		"2":    uint64(30),
+		"4":    uint64(40),
+		"+Inf": uint64(40),
+	})
+	require.NoError(t, err)
+
+	tests := map[float64]float64{
+		0.1:  0.4, // 4 of the 10 observations in the first bucket [0, 1]
+		0.5:  1.5, // 10 of the 20 observations in the second bucket [1, 2]
+		0.75: 2,   // upper bound of the second bucket
+		0.9:  3.2, // 6 of the 10 observations in the third bucket [2, 4]
+		1:    4,
+	}
+	for q, expected := range tests {
+		v, ok := bs.quantile(q)
+		assert.True(t, ok)
+		assert.InDelta(t, expected, v, 1e-9, "q=%v", q)
+	}
+}
+
+func TestHistogramQuantileInfBucket(t *testing.T) {
+	bs, err := parseBuckets(common.MapStr{
+		"0.5":  int64(50),
+		"1":    int64(80),
+		"+Inf": int64(100),
+	})
+	require.NoError(t, err)
+
+	// Observations above the highest finite bucket are reported with its
+	// upper bound.
+	v, ok := bs.quantile(0.99)
+	assert.True(t, ok)
+	assert.Equal(t, 1.0, v)
+}
+
+func TestHistogramQuantileEmpty(t *testing.T) {
+	bs, err := parseBuckets(common.MapStr{"1": uint64(0), "+Inf": uint64(0)})
+	require.NoError(t, err)
+
+	_, ok := bs.quantile(0.5)
+	assert.False(t, ok)
+}
+
+func TestParseBucketsInvalid(t *testing.T) {
+	tests := []interface{}{
+		"not a histogram",
+		common.MapStr{"1": uint64(1)},
+		common.MapStr{"+Inf": uint64(1)},
+		common.MapStr{"abc": uint64(1), "+Inf": uint64(1)},
+		common.MapStr{"1": "one", "+Inf": uint64(1)},
+	}
+	for _, test := range tests {
+		_, err := parseBuckets(test)
+		assert.Error(t, err, "%v", test)
+	}
+}
+
+func TestHistogramPercentilesProcessor(t *testing.T) {
+	h, err := newHistogramPercentiles(newTestConfig(t, map[string]interface{}{
+		"fields":      []string{"prometheus.http.duration", "prometheus.http.missing", "prometheus.http.invalid"},
+		"percentiles": []float64{50, 99.9},
+	}))
+	require.NoError(t, err)
+
+	event, err := h.Run(&beat.Event{Fields: common.MapStr{
+		"prometheus": common.MapStr{
+			"http": common.MapStr{
+				"duration": common.MapStr{
+					"count":  uint64(4),
+					"bucket": common.MapStr{"1": uint64(2), "2": uint64(4), "+Inf": uint64(4)},
+				},
+				"invalid": common.MapStr{"bucket": "not a histogram"},
+			},
+		},
+	}})
+	require.NoError(t, err)
+
+	percentiles, err := event.GetValue("prometheus.http.duration.percentile")
+	require.NoError(t, err)
+	require.Len(t, percentiles, 2)
+	assert.InDelta(t, 1.0, percentiles.(common.MapStr)["50"], 1e-9)
+	assert.InDelta(t, 1.998, percentiles.(common.MapStr)["99.9"], 1e-9)
+
+	_, err = event.GetValue("prometheus.http.invalid.percentile")
+	assert.Error(t, err)
+	_, err = event.GetValue("prometheus.http.missing")
+	assert.Error(t, err)
+}
+
+func TestHistogramPercentilesConfig(t *testing.T) {
+	h, err := newHistogramPercentiles(newTestConfig(t, map[string]interface{}{
+		"fields": []string{"latency"},
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, []float64{50, 90, 99}, h.percentiles)
+
+	invalid := []map[string]interface{}{
+		{},
+		{"fields": []string{"latency"}, "percentiles": []float64{0}},
+		{"fields": []string{"latency"}, "percentiles": []float64{101}},
+	}
+	for _, config := range invalid {
+		_, err := newHistogramPercentiles(newTestConfig(t, config))
+		assert.Error(t, err, "%v", config)
+	}
+}
+
+func TestConnectorHistogramPercentiles(t *testing.T) {
+	c, err := NewConnector(nil, newTestConfig(t, map[string]interface{}{
+		"histogram_percentiles.fields": []string{"latency"},
+		"processors": []map[string]interface{}{
+			{"drop_fields": map[string]interface{}{"fields": []string{"latency.bucket"}}},
+		},
+	}))
+	require.NoError(t, err)
+
+	// Percentiles are computed before the configured processors run.
+	require.Len(t, c.processors.List, 2)
+	assert.IsType(t, &histogramPercentiles{}, c.processors.List[0])
+}
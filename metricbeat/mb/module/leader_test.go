@@ -0,0 +1,150 @@
+// +build !integration
+
+package module
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/atomic"
+	"github.com/elastic/beats/metricbeat/mb"
+	"github.com/elastic/beats/metricbeat/mb/module/leaderelection"
+)
+
+// fakeLeases stores a single lease in memory, shared by the electors of all
+// wrappers of a test.
+type fakeLeases struct {
+	mutex   sync.Mutex
+	lease   *leaderelection.Lease
+	version int
+}
+
+func (l *fakeLeases) Get() (*leaderelection.Lease, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.lease == nil {
+		return nil, nil
+	}
+	lease := *l.lease
+	return &lease, nil
+}
+
+func (l *fakeLeases) Create(lease *leaderelection.Lease) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.lease != nil {
+		return errors.New("lease already exists")
+	}
+	l.store(lease)
+	return nil
+}
+
+func (l *fakeLeases) Update(lease *leaderelection.Lease) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.lease == nil || l.lease.ResourceVersion != lease.ResourceVersion {
+		return errors.New("lease has been modified")
+	}
+	l.store(lease)
+	return nil
+}
+
+func (l *fakeLeases) store(lease *leaderelection.Lease) {
+	l.version++
+	stored := *lease
+	stored.ResourceVersion = strconv.Itoa(l.version)
+	l.lease = &stored
+}
+
+type singletonMetricSet struct {
+	mb.BaseMetricSet
+}
+
+func (ms *singletonMetricSet) Fetch() (common.MapStr, error) {
+	return common.MapStr{"metric": 1}, nil
+}
+
+var testLeaderConfig = leaderelection.Config{
+	Enabled:       true,
+	Lease:         "test",
+	InCluster:     true,
+	LeaseDuration: 3 * time.Second,
+	RenewDeadline: 2 * time.Second,
+	RetryPeriod:   20 * time.Millisecond,
+}
+
+// startLeaderTestWrapper starts a module fetching every 10ms, electing its
+// leader with the leases. The returned counter counts the published events.
+func startLeaderTestWrapper(t *testing.T, leases *fakeLeases, identity string, done chan struct{}) (*atomic.Int64, <-chan struct{}) {
+	r := mb.NewRegister()
+	require.NoError(t, r.AddMetricSet("singleton", "fetcher", func(base mb.BaseMetricSet) (mb.MetricSet, error) {
+		return &singletonMetricSet{BaseMetricSet: base}, nil
+	}))
+
+	c, err := common.NewConfigFrom(map[string]interface{}{
+		"module":     "singleton",
+		"metricsets": []string{"fetcher"},
+		"period":     "10ms",
+	})
+	require.NoError(t, err)
+
+	w, err := NewWrapper(0, c, r)
+	require.NoError(t, err)
+	w.elector = leaderelection.NewElector(leases, identity, testLeaderConfig)
+
+	var events atomic.Int64
+	stopped := make(chan struct{})
+	out := w.Start(done)
+	go func() {
+		defer close(stopped)
+		for range out {
+			events.Inc()
+		}
+	}()
+	return &events, stopped
+}
+
+func TestWrapperOnlyLeaderFetches(t *testing.T) {
+	leases := &fakeLeases{}
+
+	doneA, doneB := make(chan struct{}), make(chan struct{})
+	eventsA, stoppedA := startLeaderTestWrapper(t, leases, "a", doneA)
+	eventsB, stoppedB := startLeaderTestWrapper(t, leases, "b", doneB)
+	defer func() {
+		close(doneB)
+		<-stoppedB
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	assert.True(t, eventsA.Load() > 0, "leader must fetch")
+	assert.Equal(t, int64(0), eventsB.Load(), "follower must not fetch")
+
+	// Stopping the leader releases the lease, the other instance takes over.
+	close(doneA)
+	<-stoppedA
+	stoppedCount := eventsA.Load()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for eventsB.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for the new leader to fetch")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, stoppedCount, eventsA.Load())
+}
+
+func TestWrapperWithoutLeaderElectionFetches(t *testing.T) {
+	w := &Wrapper{}
+	assert.True(t, w.isLeader())
+}
@@ -0,0 +1,58 @@
+package leaderelection
+
+import (
+	"errors"
+	"time"
+)
+
+// Config is the leader election configuration of a module.
+type Config struct {
+	Enabled bool `config:"enabled"`
+
+	// Name and namespace of the Kubernetes Lease shared by the instances.
+	// If no namespace is set, the namespace of the client is used.
+	Lease     string `config:"lease"`
+	Namespace string `config:"namespace"`
+
+	// Identity of the instance in the lease. Defaults to the hostname, which
+	// is the pod name on Kubernetes.
+	Identity string `config:"identity"`
+
+	InCluster  bool   `config:"in_cluster"`
+	KubeConfig string `config:"kube_config"`
+
+	// LeaseDuration is the time followers wait before taking over a lease
+	// that has not been renewed. The leader stops acting as leader if the
+	// lease could not be renewed within RenewDeadline. Acquiring or renewing
+	// the lease is tried every RetryPeriod.
+	LeaseDuration time.Duration `config:"lease_duration" validate:"positive"`
+	RenewDeadline time.Duration `config:"renew_deadline" validate:"positive"`
+	RetryPeriod   time.Duration `config:"retry_period" validate:"positive"`
+}
+
+// DefaultConfig is the default leader election configuration.
+var DefaultConfig = Config{
+	InCluster:     true,
+	LeaseDuration: 15 * time.Second,
+	RenewDeadline: 10 * time.Second,
+	RetryPeriod:   2 * time.Second,
+}
+
+// Validate validates the configuration, if leader election is enabled.
+func (c *Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	switch {
+	case c.Lease == "":
+		return errors.New("leader_election.lease must be set")
+	case !c.InCluster && c.KubeConfig == "":
+		return errors.New("leader_election.kube_config can't be empty when in_cluster is set to false")
+	case c.RenewDeadline >= c.LeaseDuration:
+		return errors.New("leader_election.renew_deadline must be less than lease_duration")
+	case c.RetryPeriod >= c.RenewDeadline:
+		return errors.New("leader_election.retry_period must be less than renew_deadline")
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+// Package leaderelection elects a leader among the instances of a module
+// sharing a Kubernetes Lease, so singleton metricsets, like cluster level
+// metricsets, are only run by one instance.
+package leaderelection
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ericchiang/k8s"
+	"github.com/ghodss/yaml"
+
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// Elector acquires and renews the lease for its identity. It is the leader
+// while it holds a lease renewed within the renew deadline. Followers take
+// over a lease once it has not been renewed for the lease duration, as
+// observed with their own clock.
+type Elector struct {
+	client   LeaseClient
+	identity string
+	config   Config
+	now      func() time.Time
+
+	mutex        sync.Mutex
+	leader       bool
+	renewed      time.Time // time of the last successful acquire or renew
+	observed     *Lease    // lease record as last read
+	observedTime time.Time // time the observed record changed
+}
+
+// New creates the elector of the configuration, storing the lease in
+// Kubernetes.
+func New(config Config) (*Elector, error) {
+	client, err := newKubernetesClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := config.Identity
+	if identity == "" {
+		if identity, err = os.Hostname(); err != nil {
+			return nil, fmt.Errorf("failed to get the leader election identity: %v", err)
+		}
+	}
+
+	leases := newKubernetesLeaseClient(client, config.Namespace, config.Lease)
+	return NewElector(leases, identity, config), nil
+}
+
+func newKubernetesClient(config Config) (*k8s.Client, error) {
+	if config.InCluster {
+		client, err := k8s.NewInClusterClient()
+		if err != nil {
+			return nil, fmt.Errorf("unable to get in cluster configuration: %v", err)
+		}
+		return client, nil
+	}
+
+	data, err := ioutil.ReadFile(config.KubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("read kubeconfig: %v", err)
+	}
+
+	var kubeConfig k8s.Config
+	if err = yaml.Unmarshal(data, &kubeConfig); err != nil {
+		return nil, fmt.Errorf("unmarshal kubeconfig: %v", err)
+	}
+	return k8s.NewClient(&kubeConfig)
+}
+
+// NewElector creates an elector storing the lease with the client.
+func NewElector(client LeaseClient, identity string, config Config) *Elector {
+	return &Elector{
+		client:   client,
+		identity: identity,
+		config:   config,
+		now:      time.Now,
+	}
+}
+
+// IsLeader returns true if the elector holds the lease.
+func (e *Elector) IsLeader() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.isLeader(e.now())
+}
+
+func (e *Elector) isLeader(now time.Time) bool {
+	return e.leader && now.Before(e.renewed.Add(e.config.RenewDeadline))
+}
+
+// Start tries to acquire the lease, and keeps trying to acquire or renew it
+// every retry period in the background. Once done is closed, the lease is
+// released if held, so another instance can take over without waiting for
+// the lease to expire.
+func (e *Elector) Start(done <-chan struct{}, wg *sync.WaitGroup) {
+	e.tryAcquireOrRenew()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(e.config.RetryPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				e.release()
+				return
+			case <-ticker.C:
+				e.tryAcquireOrRenew()
+			}
+		}
+	}()
+}
+
+// WaitLeader blocks until the elector is the leader. It returns false if done
+// is closed before.
+func (e *Elector) WaitLeader(done <-chan struct{}) bool {
+	for !e.IsLeader() {
+		select {
+		case <-done:
+			return false
+		case <-time.After(e.config.RetryPeriod):
+		}
+	}
+	return true
+}
+
+// tryAcquireOrRenew creates the lease if it does not exist, renews it if it
+// is held by this instance, or takes it over if it expired.
+func (e *Elector) tryAcquireOrRenew() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	now := e.now()
+	lease, err := e.client.Get()
+	if err != nil {
+		logp.Err("Failed to get lease %v: %v", e.config.Lease, err)
+		return e.update(now, false)
+	}
+
+	if lease == nil {
+		lease = &Lease{
+			HolderIdentity: e.identity,
+			LeaseDuration:  e.config.LeaseDuration,
+			AcquireTime:    now,
+			RenewTime:      now,
+		}
+		if err := e.client.Create(lease); err != nil {
+			logp.Err("Failed to create lease %v: %v", e.config.Lease, err)
+			return e.update(now, false)
+		}
+		return e.update(now, true)
+	}
+
+	if e.observed == nil || !sameRecord(e.observed, lease) {
+		e.observed = lease
+		e.observedTime = now
+	}
+
+	held := lease.HolderIdentity != "" && lease.HolderIdentity != e.identity
+	if held && now.Before(e.observedTime.Add(lease.LeaseDuration)) {
+		return e.update(now, false)
+	}
+
+	updated := *lease
+	if lease.HolderIdentity != e.identity {
+		updated.HolderIdentity = e.identity
+		updated.AcquireTime = now
+		updated.LeaseTransitions++
+	}
+	updated.RenewTime = now
+	updated.LeaseDuration = e.config.LeaseDuration
+
+	if err := e.client.Update(&updated); err != nil {
+		logp.Err("Failed to update lease %v: %v", e.config.Lease, err)
+		return e.update(now, false)
+	}
+	return e.update(now, true)
+}
+
+// update records the result of an attempt to acquire or renew the lease. A
+// failed attempt only ends the leadership once the renew deadline passed.
+func (e *Elector) update(now time.Time, acquired bool) bool {
+	wasLeader := e.isLeader(now)
+	if acquired {
+		e.leader = true
+		e.renewed = now
+	}
+	isLeader := e.isLeader(now)
+	if !isLeader {
+		e.leader = false
+	}
+
+	switch {
+	case isLeader && !wasLeader:
+		logp.Info("Became leader of lease %v as %v", e.config.Lease, e.identity)
+	case !isLeader && wasLeader:
+		logp.Info("Lost leadership of lease %v as %v", e.config.Lease, e.identity)
+	}
+	return isLeader
+}
+
+// release gives up the lease if held, by clearing the holder.
+func (e *Elector) release() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if !e.isLeader(e.now()) {
+		return
+	}
+	e.leader = false
+
+	lease, err := e.client.Get()
+	if err != nil || lease == nil || lease.HolderIdentity != e.identity {
+		return
+	}
+	lease.HolderIdentity = ""
+	if err := e.client.Update(lease); err != nil {
+		logp.Err("Failed to release lease %v: %v", e.config.Lease, err)
+		return
+	}
+	logp.Info("Released lease %v as %v", e.config.Lease, e.identity)
+}
+
+func sameRecord(a, b *Lease) bool {
+	return a.HolderIdentity == b.HolderIdentity &&
+		a.RenewTime.Equal(b.RenewTime) &&
+		a.ResourceVersion == b.ResourceVersion
+}
@@ -0,0 +1,240 @@
+// +build !integration
+
+package leaderelection
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testConfig = Config{
+	Enabled:       true,
+	Lease:         "test",
+	InCluster:     true,
+	LeaseDuration: 15 * time.Second,
+	RenewDeadline: 10 * time.Second,
+	RetryPeriod:   2 * time.Second,
+}
+
+// errUnavailable is returned by all operations while the store is set
+// unavailable.
+var errUnavailable = errors.New("lease store unavailable")
+
+// memoryLeases stores a single lease in memory, shared by multiple electors.
+type memoryLeases struct {
+	mutex       sync.Mutex
+	lease       *Lease
+	version     int
+	unavailable bool
+}
+
+// Get returns a copy of the lease.
+func (l *memoryLeases) Get() (*Lease, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.unavailable {
+		return nil, errUnavailable
+	}
+	if l.lease == nil {
+		return nil, nil
+	}
+	lease := *l.lease
+	return &lease, nil
+}
+
+// Create stores the lease, if no lease is stored.
+func (l *memoryLeases) Create(lease *Lease) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.unavailable {
+		return errUnavailable
+	}
+	if l.lease != nil {
+		return errors.New("lease already exists")
+	}
+	l.store(lease)
+	return nil
+}
+
+// Update replaces the lease, if its resource version matches.
+func (l *memoryLeases) Update(lease *Lease) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.unavailable {
+		return errUnavailable
+	}
+	if l.lease == nil {
+		return errors.New("lease not found")
+	}
+	if lease.ResourceVersion != l.lease.ResourceVersion {
+		return errors.New("lease has been modified")
+	}
+	l.store(lease)
+	return nil
+}
+
+func (l *memoryLeases) store(lease *Lease) {
+	l.version++
+	stored := *lease
+	stored.ResourceVersion = strconv.Itoa(l.version)
+	l.lease = &stored
+}
+
+// Holder returns the holder of the lease.
+func (l *memoryLeases) Holder() string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.lease == nil {
+		return ""
+	}
+	return l.lease.HolderIdentity
+}
+
+// SetUnavailable makes all operations fail while unavailable is true.
+func (l *memoryLeases) SetUnavailable(unavailable bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.unavailable = unavailable
+}
+
+// clock is a manually advanced clock shared by the electors of a test.
+type clock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestElectors(leases *memoryLeases, c *clock, identities ...string) []*Elector {
+	var electors []*Elector
+	for _, identity := range identities {
+		e := NewElector(leases, identity, testConfig)
+		e.now = c.Now
+		electors = append(electors, e)
+	}
+	return electors
+}
+
+func TestElectorSingleLeader(t *testing.T) {
+	leases := &memoryLeases{}
+	c := &clock{now: time.Unix(1000, 0)}
+	electors := newTestElectors(leases, c, "a", "b")
+	a, b := electors[0], electors[1]
+
+	assert.True(t, a.tryAcquireOrRenew())
+	assert.False(t, b.tryAcquireOrRenew())
+	assert.True(t, a.IsLeader())
+	assert.False(t, b.IsLeader())
+	assert.Equal(t, "a", leases.Holder())
+
+	// The leader keeps the lease while renewing it.
+	for i := 0; i < 10; i++ {
+		c.Advance(testConfig.RetryPeriod)
+		assert.True(t, a.tryAcquireOrRenew())
+		assert.False(t, b.tryAcquireOrRenew())
+	}
+	assert.Equal(t, "a", leases.Holder())
+}
+
+func TestElectorFailover(t *testing.T) {
+	leases := &memoryLeases{}
+	c := &clock{now: time.Unix(1000, 0)}
+	electors := newTestElectors(leases, c, "a", "b")
+	a, b := electors[0], electors[1]
+
+	assert.True(t, a.tryAcquireOrRenew())
+	assert.False(t, b.tryAcquireOrRenew())
+
+	// a stops renewing, b takes over once the lease expired.
+	c.Advance(testConfig.LeaseDuration - time.Second)
+	assert.False(t, b.tryAcquireOrRenew())
+	assert.False(t, a.IsLeader(), "leader must step down after the renew deadline")
+
+	c.Advance(2 * time.Second)
+	assert.True(t, b.tryAcquireOrRenew())
+	assert.True(t, b.IsLeader())
+	assert.Equal(t, "b", leases.Holder())
+
+	// a does not get the lease back while b renews it.
+	assert.False(t, a.tryAcquireOrRenew())
+	assert.False(t, a.IsLeader())
+}
+
+func TestElectorStepsDownWithoutRenew(t *testing.T) {
+	leases := &memoryLeases{}
+	c := &clock{now: time.Unix(1000, 0)}
+	a := newTestElectors(leases, c, "a")[0]
+
+	assert.True(t, a.tryAcquireOrRenew())
+
+	// Failed renewals keep the leadership until the renew deadline.
+	leases.SetUnavailable(true)
+	c.Advance(testConfig.RetryPeriod)
+	assert.True(t, a.tryAcquireOrRenew())
+
+	c.Advance(testConfig.RenewDeadline)
+	assert.False(t, a.tryAcquireOrRenew())
+	assert.False(t, a.IsLeader())
+
+	leases.SetUnavailable(false)
+	assert.True(t, a.tryAcquireOrRenew())
+}
+
+func TestElectorReleaseOnStop(t *testing.T) {
+	leases := &memoryLeases{}
+	c := &clock{now: time.Unix(1000, 0)}
+	electors := newTestElectors(leases, c, "a", "b")
+	a, b := electors[0], electors[1]
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	a.Start(done, &wg)
+	assert.True(t, a.IsLeader())
+
+	close(done)
+	wg.Wait()
+	assert.False(t, a.IsLeader())
+	assert.Equal(t, "", leases.Holder())
+
+	// The released lease is taken over without waiting for it to expire.
+	assert.True(t, b.tryAcquireOrRenew())
+}
+
+func TestConfigValidate(t *testing.T) {
+	valid := testConfig
+	assert.NoError(t, valid.Validate())
+
+	disabled := Config{}
+	assert.NoError(t, disabled.Validate())
+
+	invalid := []func(c *Config){
+		func(c *Config) { c.Lease = "" },
+		func(c *Config) { c.RenewDeadline = c.LeaseDuration },
+		func(c *Config) { c.RetryPeriod = c.RenewDeadline },
+		func(c *Config) { c.InCluster, c.KubeConfig = false, "" },
+	}
+	for i, modify := range invalid {
+		config := testConfig
+		modify(&config)
+		assert.Error(t, config.Validate(), "case %v", i)
+	}
+}
@@ -0,0 +1,222 @@
+package leaderelection
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ericchiang/k8s"
+)
+
+// Lease is the leader election record stored in a Kubernetes Lease.
+type Lease struct {
+	HolderIdentity   string
+	LeaseDuration    time.Duration
+	AcquireTime      time.Time
+	RenewTime        time.Time
+	LeaseTransitions int
+
+	// ResourceVersion of the Lease object, used to detect concurrent updates.
+	ResourceVersion string
+}
+
+// LeaseClient reads and writes the lease.
+type LeaseClient interface {
+	// Get returns the lease, or nil if the lease does not exist.
+	Get() (*Lease, error)
+
+	// Create creates the lease. It fails if the lease already exists.
+	Create(lease *Lease) error
+
+	// Update updates the lease. It fails if the lease has been updated since
+	// it has been read, that is if its ResourceVersion changed.
+	Update(lease *Lease) error
+}
+
+// kubernetesLeaseClient stores the lease in a coordination.k8s.io/v1 Lease.
+type kubernetesLeaseClient struct {
+	client    *k8s.Client
+	namespace string
+	name      string
+}
+
+// microTimeFormat is the format of the Kubernetes MicroTime type.
+const microTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+type microTime time.Time
+
+func (t microTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Time(t).UTC().Format(microTimeFormat))
+}
+
+func (t *microTime) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		*t = microTime{}
+		return nil
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return err
+	}
+	*t = microTime(parsed)
+	return nil
+}
+
+type leaseObject struct {
+	APIVersion string          `json:"apiVersion"`
+	Kind       string          `json:"kind"`
+	Metadata   leaseObjectMeta `json:"metadata"`
+	Spec       leaseObjectSpec `json:"spec"`
+}
+
+type leaseObjectMeta struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+type leaseObjectSpec struct {
+	HolderIdentity       string     `json:"holderIdentity"`
+	LeaseDurationSeconds int        `json:"leaseDurationSeconds"`
+	AcquireTime          *microTime `json:"acquireTime,omitempty"`
+	RenewTime            *microTime `json:"renewTime,omitempty"`
+	LeaseTransitions     int        `json:"leaseTransitions"`
+}
+
+func newKubernetesLeaseClient(client *k8s.Client, namespace, name string) *kubernetesLeaseClient {
+	if namespace == "" {
+		namespace = client.Namespace
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	return &kubernetesLeaseClient{client: client, namespace: namespace, name: name}
+}
+
+func (c *kubernetesLeaseClient) url(name string) string {
+	endpoint := strings.TrimSuffix(c.client.Endpoint, "/")
+	url := fmt.Sprintf("%s/apis/coordination.k8s.io/v1/namespaces/%s/leases", endpoint, c.namespace)
+	if name != "" {
+		url += "/" + name
+	}
+	return url
+}
+
+func (c *kubernetesLeaseClient) Get() (*Lease, error) {
+	var obj leaseObject
+	status, err := c.do("GET", c.url(c.name), nil, &obj)
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	spec := obj.Spec
+	lease := &Lease{
+		HolderIdentity:   spec.HolderIdentity,
+		LeaseDuration:    time.Duration(spec.LeaseDurationSeconds) * time.Second,
+		LeaseTransitions: spec.LeaseTransitions,
+		ResourceVersion:  obj.Metadata.ResourceVersion,
+	}
+	if spec.AcquireTime != nil {
+		lease.AcquireTime = time.Time(*spec.AcquireTime)
+	}
+	if spec.RenewTime != nil {
+		lease.RenewTime = time.Time(*spec.RenewTime)
+	}
+	return lease, nil
+}
+
+func (c *kubernetesLeaseClient) Create(lease *Lease) error {
+	_, err := c.do("POST", c.url(""), c.object(lease), nil)
+	return err
+}
+
+func (c *kubernetesLeaseClient) Update(lease *Lease) error {
+	_, err := c.do("PUT", c.url(c.name), c.object(lease), nil)
+	return err
+}
+
+func (c *kubernetesLeaseClient) object(lease *Lease) *leaseObject {
+	acquire, renew := microTime(lease.AcquireTime), microTime(lease.RenewTime)
+	return &leaseObject{
+		APIVersion: "coordination.k8s.io/v1",
+		Kind:       "Lease",
+		Metadata: leaseObjectMeta{
+			Name:            c.name,
+			Namespace:       c.namespace,
+			ResourceVersion: lease.ResourceVersion,
+		},
+		Spec: leaseObjectSpec{
+			HolderIdentity:       lease.HolderIdentity,
+			LeaseDurationSeconds: int(lease.LeaseDuration / time.Second),
+			AcquireTime:          &acquire,
+			RenewTime:            &renew,
+			LeaseTransitions:     lease.LeaseTransitions,
+		},
+	}
+}
+
+// do sends the request with the JSON encoded body, and decodes the response
+// into out. It returns the status code of the response.
+func (c *kubernetesLeaseClient) do(method, url string, body, out interface{}) (int, error) {
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.client.SetHeaders != nil {
+		if err := c.client.SetHeaders(req.Header); err != nil {
+			return 0, err
+		}
+	}
+
+	httpClient := c.client.Client
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return resp.StatusCode, fmt.Errorf("%s lease %s/%s failed with status %v: %s",
+			method, c.namespace, c.name, resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return resp.StatusCode, err
+		}
+	}
+	return resp.StatusCode, nil
+}
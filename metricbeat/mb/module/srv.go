@@ -0,0 +1,208 @@
+package module
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+var errSRVWithHosts = errors.New("hosts and srv can not be used together")
+
+// SRVResolver looks up the SRV records of a DNS name.
+type SRVResolver interface {
+	LookupSRV(name string) ([]*net.SRV, error)
+}
+
+// DefaultSRVResolver resolves SRV records using the system resolver.
+var DefaultSRVResolver SRVResolver = netSRVResolver{}
+
+type netSRVResolver struct{}
+
+func (netSRVResolver) LookupSRV(name string) ([]*net.SRV, error) {
+	_, addrs, err := net.LookupSRV("", "", name)
+	return addrs, err
+}
+
+// srvConfig configures the discovery of module hosts via DNS SRV records.
+type srvConfig struct {
+	Name   string        `config:"name"   validate:"required"`
+	Period time.Duration `config:"period" validate:"nonzero,positive"`
+}
+
+var defaultSRVConfig = srvConfig{
+	Period: 1 * time.Minute,
+}
+
+// HasSRV returns true if the module configuration discovers its hosts using
+// DNS SRV records.
+func HasSRV(c *common.Config) bool {
+	return c.HasField("srv")
+}
+
+// srvRunner is a Runner that periodically resolves a DNS SRV record and runs
+// one module instance per discovered host. Module instances are started and
+// stopped as hosts are added to or removed from the record.
+type srvRunner struct {
+	srv      srvConfig
+	config   map[string]interface{} // Module config without the srv setting.
+	resolver SRVResolver
+	create   func(*common.Config) (Runner, error)
+
+	runners map[string]Runner // Running module instances by host.
+
+	done      chan struct{}
+	wg        sync.WaitGroup
+	startOnce sync.Once
+	stopOnce  sync.Once
+}
+
+// NewSRVRunner creates a Runner for a module configuration using the `srv`
+// setting. The create function is used to create a Runner for each discovered
+// host from a copy of the module configuration with `hosts` set to the host.
+func NewSRVRunner(
+	c *common.Config,
+	resolver SRVResolver,
+	create func(*common.Config) (Runner, error),
+) (Runner, error) {
+	settings := struct {
+		Hosts []string  `config:"hosts"`
+		SRV   srvConfig `config:"srv"`
+	}{
+		SRV: defaultSRVConfig,
+	}
+	if err := c.Unpack(&settings); err != nil {
+		return nil, errors.Wrap(err, "invalid srv configuration")
+	}
+	if len(settings.Hosts) > 0 {
+		return nil, errSRVWithHosts
+	}
+
+	config := map[string]interface{}{}
+	if err := c.Unpack(&config); err != nil {
+		return nil, err
+	}
+	delete(config, "srv")
+
+	return &srvRunner{
+		srv:      settings.SRV,
+		config:   config,
+		resolver: resolver,
+		create:   create,
+		runners:  map[string]Runner{},
+		done:     make(chan struct{}),
+	}, nil
+}
+
+func (r *srvRunner) Start() {
+	r.startOnce.Do(func() {
+		r.wg.Add(1)
+		go r.run()
+	})
+}
+
+func (r *srvRunner) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.done)
+		r.wg.Wait()
+	})
+}
+
+func (r *srvRunner) run() {
+	defer r.wg.Done()
+	defer r.stopAll()
+
+	r.update()
+
+	t := time.NewTicker(r.srv.Period)
+	defer t.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-t.C:
+			r.update()
+		}
+	}
+}
+
+// update resolves the SRV record and starts or stops module instances so that
+// exactly one instance runs per discovered host. If the record can not be
+// resolved, the already running instances are kept.
+func (r *srvRunner) update() {
+	hosts, err := r.lookup()
+	if err != nil {
+		logp.Err("Failed to resolve SRV record '%s', keeping %d known hosts: %v",
+			r.srv.Name, len(r.runners), err)
+		return
+	}
+
+	for host, runner := range r.runners {
+		if _, exists := hosts[host]; exists {
+			continue
+		}
+
+		logp.Info("Stopping module for host '%s' removed from SRV record '%s'", host, r.srv.Name)
+		runner.Stop()
+		delete(r.runners, host)
+	}
+
+	for host := range hosts {
+		if _, exists := r.runners[host]; exists {
+			continue
+		}
+
+		runner, err := r.createRunner(host)
+		if err != nil {
+			logp.Err("Failed to create module for host '%s' from SRV record '%s': %v",
+				host, r.srv.Name, err)
+			continue
+		}
+
+		logp.Info("Starting module for host '%s' found in SRV record '%s'", host, r.srv.Name)
+		runner.Start()
+		r.runners[host] = runner
+	}
+}
+
+// lookup returns the set of host:port pairs listed in the SRV record.
+func (r *srvRunner) lookup() (map[string]struct{}, error) {
+	addrs, err := r.resolver.LookupSRV(r.srv.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := map[string]struct{}{}
+	for _, addr := range addrs {
+		target := strings.TrimSuffix(addr.Target, ".")
+		hosts[net.JoinHostPort(target, strconv.Itoa(int(addr.Port)))] = struct{}{}
+	}
+	return hosts, nil
+}
+
+func (r *srvRunner) createRunner(host string) (Runner, error) {
+	config := make(map[string]interface{}, len(r.config)+1)
+	for k, v := range r.config {
+		config[k] = v
+	}
+	config["hosts"] = []string{host}
+
+	c, err := common.NewConfigFrom(config)
+	if err != nil {
+		return nil, err
+	}
+	return r.create(c)
+}
+
+func (r *srvRunner) stopAll() {
+	for host, runner := range r.runners {
+		runner.Stop()
+		delete(r.runners, host)
+	}
+}
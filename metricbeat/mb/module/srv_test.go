@@ -0,0 +1,171 @@
+// +build !integration
+
+package module
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+type mockSRVResolver struct {
+	mutex sync.Mutex
+	addrs []*net.SRV
+	err   error
+}
+
+func (r *mockSRVResolver) LookupSRV(name string) ([]*net.SRV, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.addrs, r.err
+}
+
+func (r *mockSRVResolver) set(addrs []*net.SRV, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.addrs, r.err = addrs, err
+}
+
+func srvRecord(target string, port uint16) *net.SRV {
+	return &net.SRV{Target: target, Port: port}
+}
+
+type fakeRunner struct {
+	host    string
+	started bool
+	stopped bool
+}
+
+func (r *fakeRunner) Start() { r.started = true }
+func (r *fakeRunner) Stop()  { r.stopped = true }
+
+type fakeRunnerFactory struct {
+	created []*fakeRunner
+}
+
+func (f *fakeRunnerFactory) create(c *common.Config) (Runner, error) {
+	var config struct {
+		Hosts []string `config:"hosts"`
+	}
+	if err := c.Unpack(&config); err != nil {
+		return nil, err
+	}
+	if len(config.Hosts) != 1 {
+		return nil, errors.New("expected exactly one host")
+	}
+
+	r := &fakeRunner{host: config.Hosts[0]}
+	f.created = append(f.created, r)
+	return r, nil
+}
+
+func newTestSRVRunner(t *testing.T, resolver SRVResolver, factory *fakeRunnerFactory) *srvRunner {
+	config, err := common.NewConfigFrom(map[string]interface{}{
+		"module":     "fake",
+		"metricsets": []string{"status"},
+		"srv.name":   "_fake._tcp.example.com",
+	})
+	require.NoError(t, err)
+
+	r, err := NewSRVRunner(config, resolver, factory.create)
+	require.NoError(t, err)
+	return r.(*srvRunner)
+}
+
+func runningHosts(r *srvRunner) []string {
+	var hosts []string
+	for host := range r.runners {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+func TestSRVRunnerUpdate(t *testing.T) {
+	resolver := &mockSRVResolver{}
+	factory := &fakeRunnerFactory{}
+	r := newTestSRVRunner(t, resolver, factory)
+
+	resolver.set([]*net.SRV{
+		srvRecord("a.example.com.", 6379),
+		srvRecord("b.example.com.", 6379),
+	}, nil)
+	r.update()
+	assert.Equal(t, []string{"a.example.com:6379", "b.example.com:6379"}, runningHosts(r))
+	require.Len(t, factory.created, 2)
+	for _, runner := range factory.created {
+		assert.True(t, runner.started)
+	}
+
+	// b is removed and c is added to the record.
+	resolver.set([]*net.SRV{
+		srvRecord("a.example.com.", 6379),
+		srvRecord("c.example.com.", 6380),
+	}, nil)
+	r.update()
+	assert.Equal(t, []string{"a.example.com:6379", "c.example.com:6380"}, runningHosts(r))
+	require.Len(t, factory.created, 3)
+	for _, runner := range factory.created {
+		assert.True(t, runner.started)
+		assert.Equal(t, runner.host == "b.example.com:6379", runner.stopped, runner.host)
+	}
+
+	// A failing lookup keeps the last known set of hosts.
+	resolver.set(nil, errors.New("no such host"))
+	r.update()
+	assert.Equal(t, []string{"a.example.com:6379", "c.example.com:6380"}, runningHosts(r))
+	assert.Len(t, factory.created, 3)
+
+	// An empty record stops all hosts.
+	resolver.set(nil, nil)
+	r.update()
+	assert.Empty(t, runningHosts(r))
+	for _, runner := range factory.created {
+		assert.True(t, runner.stopped)
+	}
+}
+
+func TestSRVRunnerStop(t *testing.T) {
+	resolver := &mockSRVResolver{}
+	resolver.set([]*net.SRV{srvRecord("a.example.com.", 80)}, nil)
+	factory := &fakeRunnerFactory{}
+	r := newTestSRVRunner(t, resolver, factory)
+
+	r.Start()
+	r.Stop()
+
+	require.Len(t, factory.created, 1)
+	assert.True(t, factory.created[0].started)
+	assert.True(t, factory.created[0].stopped)
+}
+
+func TestSRVRunnerConfig(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"missing name": {
+			"srv.period": "10s",
+		},
+		"hosts and srv": {
+			"hosts":    []string{"localhost:6379"},
+			"srv.name": "_fake._tcp.example.com",
+		},
+		"invalid period": {
+			"srv.name":   "_fake._tcp.example.com",
+			"srv.period": "0s",
+		},
+	}
+
+	for name, settings := range tests {
+		config, err := common.NewConfigFrom(settings)
+		require.NoError(t, err)
+
+		_, err = NewSRVRunner(config, &mockSRVResolver{}, (&fakeRunnerFactory{}).create)
+		assert.Error(t, err, name)
+	}
+}
@@ -44,3 +44,4 @@ func (r *testingReporter) ErrorWith(err error, event common.MapStr) bool {
 }
 
 func (r testingReporter) StartFetchTimer() {}
+func (r testingReporter) StopFetchTimer()  {}
@@ -0,0 +1,138 @@
+package module
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// uptimeMetricSetName is the name of the synthetic metricset reporting the
+// availability of the targets of a module.
+const uptimeMetricSetName = "uptime"
+
+// uptimeBuckets is the number of buckets the availability window is divided
+// into. The memory used per target does not depend on the window or the
+// period of the module.
+const uptimeBuckets = 60
+
+// uptimeConfig configures the synthetic uptime metricset of a module.
+type uptimeConfig struct {
+	Enabled bool          `config:"enabled"`
+	Window  time.Duration `config:"window"`
+}
+
+var defaultUptimeConfig = uptimeConfig{
+	Enabled: false,
+	Window:  1 * time.Hour,
+}
+
+func (c *uptimeConfig) Validate() error {
+	if c.Window < uptimeBuckets*time.Second {
+		return fmt.Errorf("uptime window %v must be at least %v", c.Window, uptimeBuckets*time.Second)
+	}
+	return nil
+}
+
+// uptimeBucket counts the fetches of one slice of the availability window.
+type uptimeBucket struct {
+	index    int64 // Index of the slice of time counted, since the epoch.
+	success  int64
+	failures int64
+}
+
+// uptimeTracker records the success of the fetches of a target and computes
+// the availability over a rolling window. The window is split into a fixed
+// number of buckets, the fetches of the oldest bucket are dropped as a whole
+// when the window moves forward.
+type uptimeTracker struct {
+	sync.Mutex
+	window   time.Duration
+	interval time.Duration // Duration of the time counted by a bucket.
+	buckets  [uptimeBuckets]uptimeBucket
+}
+
+// uptimeStats are the fetches counted over the availability window.
+type uptimeStats struct {
+	success  int64
+	failures int64
+}
+
+func newUptimeTracker(window time.Duration) *uptimeTracker {
+	return &uptimeTracker{
+		window:   window,
+		interval: window / uptimeBuckets,
+	}
+}
+
+// record counts a fetch done at the given time.
+func (t *uptimeTracker) record(now time.Time, failed bool) {
+	t.Lock()
+	defer t.Unlock()
+
+	index := now.UnixNano() / int64(t.interval)
+	bucket := &t.buckets[index%uptimeBuckets]
+	if bucket.index != index {
+		*bucket = uptimeBucket{index: index}
+	}
+	if failed {
+		bucket.failures++
+	} else {
+		bucket.success++
+	}
+}
+
+// stats returns the fetches counted in the window ending at the given time.
+func (t *uptimeTracker) stats(now time.Time) uptimeStats {
+	t.Lock()
+	defer t.Unlock()
+
+	var s uptimeStats
+	index := now.UnixNano() / int64(t.interval)
+	for _, bucket := range t.buckets {
+		if bucket.index > index-uptimeBuckets && bucket.index <= index {
+			s.success += bucket.success
+			s.failures += bucket.failures
+		}
+	}
+	return s
+}
+
+// availability returns the ratio of successful fetches, between 0 and 1. It
+// reports false if no fetch was done during the window.
+func (s uptimeStats) availability() (float64, bool) {
+	total := s.success + s.failures
+	if total == 0 {
+		return 0, false
+	}
+	return float64(s.success) / float64(total), true
+}
+
+// uptimeEvent creates the event of the uptime metricset for the target of the
+// metricset.
+func uptimeEvent(msw *metricSetWrapper, now time.Time) (beat.Event, error) {
+	s := msw.uptime.stats(now)
+	fields := common.MapStr{
+		"metricset": msw.Name(),
+		"window": common.MapStr{
+			"sec": int64(msw.uptime.window / time.Second),
+		},
+		"fetches": common.MapStr{
+			"success":  s.success,
+			"failures": s.failures,
+		},
+	}
+	if availability, ok := s.availability(); ok {
+		fields.Put("availability.pct", availability)
+	}
+
+	return EventBuilder{
+		ModuleName:    msw.Module().Name(),
+		MetricSetName: uptimeMetricSetName,
+		Host:          msw.Host(),
+		StartTime:     now,
+		Event:         fields,
+	}.Build()
+}
@@ -0,0 +1,95 @@
+// +build !integration
+
+package module
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUptimeAvailability(t *testing.T) {
+	window := 10 * time.Minute
+	tracker := newUptimeTracker(window)
+	start := time.Date(2018, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	_, ok := tracker.stats(start).availability()
+	assert.False(t, ok, "no availability without fetches")
+
+	// One fetch every 10 seconds, every fourth fetch fails.
+	now := start
+	for i := 0; i < 40; i++ {
+		tracker.record(now, i%4 == 3)
+		now = now.Add(10 * time.Second)
+	}
+
+	stats := tracker.stats(now)
+	assert.Equal(t, uptimeStats{success: 30, failures: 10}, stats)
+	availability, ok := stats.availability()
+	assert.True(t, ok)
+	assert.Equal(t, 0.75, availability)
+}
+
+func TestUptimeWindowMovesForward(t *testing.T) {
+	window := 10 * time.Minute
+	tracker := newUptimeTracker(window)
+	start := time.Date(2018, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	// All fetches fail during the first window, then all succeed.
+	now := start
+	for ; now.Before(start.Add(window)); now = now.Add(10 * time.Second) {
+		tracker.record(now, true)
+	}
+	for ; now.Before(start.Add(window + window/2)); now = now.Add(10 * time.Second) {
+		tracker.record(now, false)
+	}
+
+	// The window ends with the last fetch.
+	last := now.Add(-10 * time.Second)
+	availability, _ := tracker.stats(last).availability()
+	assert.Equal(t, 0.5, availability)
+
+	// Once a full window passed, the failures are dropped.
+	for ; now.Before(start.Add(2 * window)); now = now.Add(10 * time.Second) {
+		tracker.record(now, false)
+	}
+	last = now.Add(-10 * time.Second)
+	assert.Equal(t, uptimeStats{success: 60}, tracker.stats(last))
+
+	// Without fetches, the counts expire too.
+	_, ok := tracker.stats(last.Add(window)).availability()
+	assert.False(t, ok)
+}
+
+func TestUptimeBoundedMemory(t *testing.T) {
+	tracker := newUptimeTracker(time.Hour)
+	start := time.Date(2018, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	// Fetches every 100ms for a day do not grow the tracker.
+	now := start
+	for ; now.Before(start.Add(24 * time.Hour)); now = now.Add(100 * time.Millisecond) {
+		tracker.record(now, false)
+	}
+
+	assert.Len(t, tracker.buckets, uptimeBuckets)
+	assert.Equal(t, uptimeStats{success: 36000}, tracker.stats(now.Add(-100*time.Millisecond)))
+}
+
+func TestUptimeConfigValidate(t *testing.T) {
+	c := newTestConfig(t, map[string]interface{}{
+		"enabled": true,
+		"window":  "10s",
+	})
+	config := defaultUptimeConfig
+	assert.Error(t, c.Unpack(&config))
+
+	c = newTestConfig(t, map[string]interface{}{
+		"enabled": true,
+		"window":  "5m",
+	})
+	config = defaultUptimeConfig
+	if assert.NoError(t, c.Unpack(&config)) {
+		assert.Equal(t, 5*time.Minute, config.Window)
+	}
+}
@@ -8,10 +8,14 @@ import (
 
 	"github.com/elastic/beats/libbeat/beat"
 	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/clock"
+	"github.com/elastic/beats/libbeat/common/startlimit"
+	"github.com/elastic/beats/libbeat/common/supervisor"
 	"github.com/elastic/beats/libbeat/logp"
 	"github.com/elastic/beats/libbeat/monitoring"
 	"github.com/elastic/beats/libbeat/testing"
 	"github.com/elastic/beats/metricbeat/mb"
+	"github.com/elastic/beats/metricbeat/mb/module/leaderelection"
 )
 
 // Expvar metric names.
@@ -36,6 +40,10 @@ type Wrapper struct {
 	mb.Module
 	metricSets    []*metricSetWrapper // List of pointers to its associated MetricSets.
 	maxStartDelay time.Duration
+
+	// elector gates the MetricSets, if leader election is enabled. Only the
+	// leader fetches.
+	elector *leaderelection.Elector
 }
 
 // metricSetWrapper contains the MetricSet and the private data associated with
@@ -44,6 +52,10 @@ type metricSetWrapper struct {
 	mb.MetricSet
 	module *Wrapper // Parent Module.
 	stats  *stats   // stats for this MetricSet.
+
+	// uptime tracks the availability of the host, if the uptime metricset
+	// is enabled.
+	uptime *uptimeTracker
 }
 
 // stats bundles common metricset stats.
@@ -63,18 +75,36 @@ func NewWrapper(maxStartDelay time.Duration, config *common.Config, r *mb.Regist
 		return nil, err
 	}
 
+	wrapperConfig := struct {
+		LeaderElection leaderelection.Config `config:"leader_election"`
+		Uptime         uptimeConfig          `config:"uptime"`
+	}{leaderelection.DefaultConfig, defaultUptimeConfig}
+	if err := config.Unpack(&wrapperConfig); err != nil {
+		return nil, err
+	}
+
 	wrapper := &Wrapper{
 		Module:        module,
 		maxStartDelay: maxStartDelay,
 		metricSets:    make([]*metricSetWrapper, len(metricsets)),
 	}
 
+	if wrapperConfig.LeaderElection.Enabled {
+		wrapper.elector, err = leaderelection.New(wrapperConfig.LeaderElection)
+		if err != nil {
+			return nil, fmt.Errorf("error initializing leader election of module %s: %v", module.Name(), err)
+		}
+	}
+
 	for i, ms := range metricsets {
 		wrapper.metricSets[i] = &metricSetWrapper{
 			MetricSet: ms,
 			module:    wrapper,
 			stats:     getMetricSetStats(wrapper.Name(), ms.Name()),
 		}
+		if wrapperConfig.Uptime.Enabled {
+			wrapper.metricSets[i].uptime = newUptimeTracker(wrapperConfig.Uptime.Window)
+		}
 	}
 
 	return wrapper, nil
@@ -96,15 +126,24 @@ func (mw *Wrapper) Start(done <-chan struct{}) <-chan beat.Event {
 
 	out := make(chan beat.Event, 1)
 
-	// Start one worker per MetricSet + host combination.
 	var wg sync.WaitGroup
+	if mw.elector != nil {
+		mw.elector.Start(done, &wg)
+	}
+
+	// Start one worker per MetricSet + host combination.
 	wg.Add(len(mw.metricSets))
 	for _, msw := range mw.metricSets {
 		go func(msw *metricSetWrapper) {
 			defer releaseStats(msw.stats)
 			defer wg.Done()
 			defer msw.close()
-			msw.run(done, out)
+
+			// Restart the MetricSet with backoff if it panics.
+			name := fmt.Sprintf("'%s/%s' for host '%s'", msw.module.Name(), msw.Name(), msw.Host())
+			supervisor.Run(name, supervisor.DefaultConfig, done, func() {
+				msw.run(done, out)
+			})
 		}(msw)
 	}
 
@@ -124,6 +163,12 @@ func (mw *Wrapper) String() string {
 		mw.Name(), len(mw.metricSets))
 }
 
+// isLeader returns true if the MetricSets of the module may fetch. Modules
+// without leader election always fetch.
+func (mw *Wrapper) isLeader() bool {
+	return mw.elector == nil || mw.elector.IsLeader()
+}
+
 // MetricSets return the list of metricsets of the module
 func (mw *Wrapper) MetricSets() []*metricSetWrapper {
 	return mw.metricSets
@@ -132,9 +177,6 @@ func (mw *Wrapper) MetricSets() []*metricSetWrapper {
 // metricSetWrapper methods
 
 func (msw *metricSetWrapper) run(done <-chan struct{}, out chan<- beat.Event) {
-	defer logp.Recover(fmt.Sprintf("recovered from panic while fetching "+
-		"'%s/%s' for host '%s'", msw.module.Name(), msw.Name(), msw.Host()))
-
 	// Start each metricset randomly over a period of MaxDelayPeriod.
 	if msw.module.maxStartDelay > 0 {
 		delay := time.Duration(rand.Int63n(int64(msw.module.maxStartDelay)))
@@ -158,6 +200,10 @@ func (msw *metricSetWrapper) run(done <-chan struct{}, out chan<- beat.Event) {
 
 	switch ms := msw.MetricSet.(type) {
 	case mb.PushMetricSet:
+		// Push MetricSets are started once elected, and keep running.
+		if msw.module.elector != nil && !msw.module.elector.WaitLeader(done) {
+			return
+		}
 		ms.Run(reporter)
 	case mb.EventFetcher, mb.EventsFetcher, mb.ReportingMetricSet:
 		msw.startPeriodicFetching(reporter)
@@ -172,10 +218,16 @@ func (msw *metricSetWrapper) run(done <-chan struct{}, out chan<- beat.Event) {
 // begins a continuous timer scheduled loop to fetch data. To stop the loop the
 // done channel should be closed.
 func (msw *metricSetWrapper) startPeriodicFetching(reporter reporter) {
-	// Fetch immediately.
-	msw.fetch(reporter)
+	// Fetch immediately, once the limited number of metricsets starting at
+	// the same time allows it.
+	if !msw.firstFetch(reporter) {
+		return
+	}
 
-	// Start timer for future fetches.
+	// Start timer for future fetches. The ticker is timed with the monotonic
+	// clock, jumps of the system time do not change the period.
+	jumps := clock.NewJumpDetector(clock.DefaultJumpThreshold)
+	jumps.Check(clock.System.Now())
 	t := time.NewTicker(msw.Module().Config().Period)
 	defer t.Stop()
 	for {
@@ -183,11 +235,39 @@ func (msw *metricSetWrapper) startPeriodicFetching(reporter reporter) {
 		case <-reporter.Done():
 			return
 		case <-t.C:
-			msw.fetch(reporter)
+			if jump, detected := jumps.Check(clock.System.Now()); detected {
+				logp.Warn("Detected a jump of the system time by %v. %s continues to fetch every %v.",
+					jump, msw, msw.Module().Config().Period)
+			}
+			msw.fetchIfLeader(reporter)
 		}
 	}
 }
 
+// firstFetch does the first fetch of the metricset, queued behind other
+// metricsets doing their first fetch if too many start at the same time. It
+// reports false if the metricset is stopped while waiting.
+func (msw *metricSetWrapper) firstFetch(reporter reporter) bool {
+	limiter := startlimit.Default()
+	if !limiter.Acquire(reporter.Done()) {
+		return false
+	}
+	defer limiter.Release()
+
+	msw.fetchIfLeader(reporter)
+	return true
+}
+
+// fetchIfLeader fetches if the module is the leader, or does not use leader
+// election.
+func (msw *metricSetWrapper) fetchIfLeader(reporter reporter) {
+	if !msw.module.isLeader() {
+		debugf("Skipping fetch of %s, not the leader", msw)
+		return
+	}
+	msw.fetch(reporter)
+}
+
 // fetch invokes the appropriate Fetch method for the MetricSet and publishes
 // the result using the publisher client. This method will recover from panics
 // and log a stack track if one occurs.
@@ -202,6 +282,7 @@ func (msw *metricSetWrapper) fetch(reporter reporter) {
 	default:
 		panic(fmt.Sprintf("unexpected fetcher type for %v", msw))
 	}
+	reporter.StopFetchTimer()
 }
 
 func (msw *metricSetWrapper) singleEventFetch(fetcher mb.EventFetcher, reporter reporter) {
@@ -264,6 +345,7 @@ func (msw *metricSetWrapper) Test(d testing.Driver) {
 type reporter interface {
 	mb.PushReporter
 	StartFetchTimer()
+	StopFetchTimer()
 }
 
 // eventReporter implements the Reporter interface which is a callback interface
@@ -274,12 +356,36 @@ type eventReporter struct {
 	done  <-chan struct{}
 	out   chan<- beat.Event
 	start time.Time // Start time of the current fetch (or zero for push sources).
+
+	// failed is set if an error was reported during the current fetch.
+	failed bool
 }
 
 // startFetchTimer demarcates the start of a new fetch. The elapsed time of a
 // fetch is computed based on the time of this call.
 func (r *eventReporter) StartFetchTimer() {
 	r.start = time.Now()
+	r.failed = false
+}
+
+// StopFetchTimer demarcates the end of a fetch. If the uptime metricset is
+// enabled, the result of the fetch is recorded and the availability of the
+// host is reported.
+func (r *eventReporter) StopFetchTimer() {
+	if r.msw.uptime == nil {
+		return
+	}
+
+	now := time.Now()
+	r.msw.uptime.record(now, r.failed)
+	event, err := uptimeEvent(r.msw, now)
+	if err != nil {
+		logp.Err("createEvent failed: %v", err)
+		return
+	}
+	if writeEvent(r.done, r.out, event) {
+		r.msw.stats.events.Add(1)
+	}
 }
 
 func (r *eventReporter) Done() <-chan struct{} {
@@ -312,6 +418,7 @@ func (r *eventReporter) ErrorWith(err error, meta common.MapStr) bool {
 		r.msw.stats.success.Add(1)
 	} else {
 		r.msw.stats.failures.Add(1)
+		r.failed = true
 	}
 
 	event, err := createEvent(r.msw, meta, err, timestamp, elapsed)
@@ -3,10 +3,13 @@
 package module_test
 
 import (
+	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/startlimit"
 	"github.com/elastic/beats/metricbeat/mb"
 	"github.com/elastic/beats/metricbeat/mb/module"
 
@@ -85,6 +88,33 @@ func newFakePushMetricSet(base mb.BaseMetricSet) (mb.MetricSet, error) {
 	return &fakePushMetricSet{BaseMetricSet: base}, nil
 }
 
+// SlowFetcher
+
+// slowFetches tracks the number of fetches of the slow fetchers running at the
+// same time, and the maximum reached.
+var slowFetches, slowFetchesPeak int32
+
+type fakeSlowFetcher struct {
+	mb.BaseMetricSet
+}
+
+func (ms *fakeSlowFetcher) Fetch() (common.MapStr, error) {
+	n := atomic.AddInt32(&slowFetches, 1)
+	defer atomic.AddInt32(&slowFetches, -1)
+	for {
+		peak := atomic.LoadInt32(&slowFetchesPeak)
+		if n <= peak || atomic.CompareAndSwapInt32(&slowFetchesPeak, peak, n) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	return common.MapStr{"metric": 1}, nil
+}
+
+func newFakeSlowFetcher(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	return &fakeSlowFetcher{BaseMetricSet: base}, nil
+}
+
 // test utilities
 
 func newTestRegistry(t testing.TB) *mb.Register {
@@ -210,3 +240,84 @@ func TestWrapperOfPushMetricSet(t *testing.T) {
 		}
 	}
 }
+
+func TestWrapperLimitsConcurrentStarts(t *testing.T) {
+	startlimit.Configure(2)
+	defer startlimit.Configure(0)
+
+	r := mb.NewRegister()
+	if err := r.AddMetricSet(moduleName, "SlowFetcher", newFakeSlowFetcher); err != nil {
+		t.Fatal(err)
+	}
+
+	var hosts []string
+	for i := 0; i < 10; i++ {
+		hosts = append(hosts, fmt.Sprintf("host%d", i))
+	}
+	c := newConfig(t, map[string]interface{}{
+		"module":     moduleName,
+		"metricsets": []string{"SlowFetcher"},
+		"hosts":      hosts,
+		"period":     "1h",
+	})
+
+	m, err := module.NewWrapper(0, c, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	output := m.Start(done)
+	defer close(done)
+
+	// All metricsets do their first fetch, but never more than the limit at
+	// the same time.
+	for range hosts {
+		select {
+		case <-output:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for the first fetches")
+		}
+	}
+	peak := atomic.LoadInt32(&slowFetchesPeak)
+	assert.True(t, peak > 0 && peak <= 2, "peak %v", peak)
+}
+
+func TestWrapperReportsUptime(t *testing.T) {
+	c := newConfig(t, map[string]interface{}{
+		"module":     moduleName,
+		"metricsets": []string{eventFetcherName},
+		"hosts":      []string{"alpha"},
+		"period":     "1h",
+		"uptime": map[string]interface{}{
+			"enabled": true,
+			"window":  "10m",
+		},
+	})
+
+	m, err := module.NewWrapper(0, c, newTestRegistry(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	output := m.Start(done)
+	defer close(done)
+
+	// The event of the fetch is followed by the availability of the host.
+	<-output
+	event := <-output
+
+	name, _ := event.Fields.GetValue("metricset.name")
+	assert.Equal(t, "uptime", name)
+	host, _ := event.Fields.GetValue("metricset.host")
+	assert.Equal(t, "alpha", host)
+	assert.Equal(t, common.MapStr{
+		"metricset": "eventfetcher",
+		"window":    common.MapStr{"sec": int64(600)},
+		"fetches":   common.MapStr{"success": int64(1), "failures": int64(0)},
+		"availability": common.MapStr{
+			"pct": float64(1),
+		},
+	}, event.Fields[moduleName].(common.MapStr)["uptime"])
+}
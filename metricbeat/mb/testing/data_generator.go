@@ -80,10 +80,14 @@ func CreateFullEvent(ms mb.MetricSet, metricSetData common.MapStr) beat.Event {
 	if err != nil {
 		panic(err)
 	}
-	fullEvent.Fields["beat"] = common.MapStr{
-		"name":     "host.example.com",
-		"hostname": "host.example.com",
-	}
+	// Merged like by the publisher pipeline, as the beat module reports its
+	// events under `beat` too.
+	fullEvent.Fields.DeepUpdate(common.MapStr{
+		"beat": common.MapStr{
+			"name":     "host.example.com",
+			"hostname": "host.example.com",
+		},
+	})
 
 	return fullEvent
 }
@@ -0,0 +1,5 @@
+/*
+Package beat is a Metricbeat module reporting the resource usage of the beat
+itself.
+*/
+package beat
@@ -0,0 +1,5 @@
+/*
+Package stats reports the CPU and memory usage, the goroutines and the garbage
+collector statistics of the beat itself.
+*/
+package stats
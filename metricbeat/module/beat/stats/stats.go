@@ -0,0 +1,99 @@
+// +build darwin freebsd linux windows
+
+package stats
+
+import (
+	"os"
+	"runtime"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/metricbeat/mb"
+	"github.com/elastic/beats/metricbeat/mb/parse"
+	"github.com/elastic/beats/metricbeat/module/system/process"
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("beat", "stats", New, parse.EmptyHostParser); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet reports the resource usage of the beat process and the Go runtime
+// statistics of the beat.
+type MetricSet struct {
+	mb.BaseMetricSet
+	pid  int
+	last *process.Process
+
+	getProcess   func(pid int) (*process.Process, error)
+	readMemStats func(*runtime.MemStats)
+	numGoroutine func() int
+	totalMemory  uint64 // total physical memory, read on each fetch if 0
+}
+
+// New creates a new instance of the stats MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	cfgwarn.Experimental("The beat stats metricset is experimental")
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		pid:           os.Getpid(),
+		getProcess:    process.GetProcess,
+		readMemStats:  runtime.ReadMemStats,
+		numGoroutine:  runtime.NumGoroutine,
+	}, nil
+}
+
+// Fetch reports the CPU and memory usage of the beat process, and the
+// goroutines, memory and garbage collector statistics of the Go runtime. The
+// CPU usage percentages are computed since the previous fetch, they are 0 on
+// the first fetch.
+func (m *MetricSet) Fetch() (common.MapStr, error) {
+	proc, err := m.getProcess(m.pid)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the stats of the beat process")
+	}
+	normalizedPct, pct := process.GetProcCpuPercentage(m.last, proc)
+	m.last = proc
+
+	var mem runtime.MemStats
+	m.readMemStats(&mem)
+
+	return common.MapStr{
+		"pid": proc.Pid,
+		"cpu": common.MapStr{
+			"total": common.MapStr{
+				"pct":   pct,
+				"norm":  common.MapStr{"pct": normalizedPct},
+				"ticks": proc.Cpu.Total,
+			},
+			"user":   common.MapStr{"ticks": proc.Cpu.User},
+			"system": common.MapStr{"ticks": proc.Cpu.Sys},
+		},
+		"memory": common.MapStr{
+			"rss": common.MapStr{
+				"bytes": proc.Mem.Resident,
+				"pct":   process.GetProcMemPercentage(proc, m.totalMemory),
+			},
+			"size": proc.Mem.Size,
+		},
+		"runtime": common.MapStr{
+			"goroutines": m.numGoroutine(),
+			"memory": common.MapStr{
+				"alloc":       common.MapStr{"bytes": mem.Alloc},
+				"total_alloc": common.MapStr{"bytes": mem.TotalAlloc},
+				"sys":         common.MapStr{"bytes": mem.Sys},
+			},
+		},
+		"gc": common.MapStr{
+			"count": mem.NumGC,
+			"pause": common.MapStr{
+				"total": common.MapStr{"ns": mem.PauseTotalNs},
+			},
+			"next": common.MapStr{"bytes": mem.NextGC},
+		},
+	}, nil
+}
@@ -0,0 +1,163 @@
+// +build darwin freebsd linux windows
+
+package stats
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+	"github.com/elastic/beats/metricbeat/module/system"
+	"github.com/elastic/beats/metricbeat/module/system/process"
+	sigar "github.com/elastic/gosigar"
+)
+
+func getConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"module":     "beat",
+		"metricsets": []string{"stats"},
+	}
+}
+
+func TestData(t *testing.T) {
+	f := mbtest.NewEventFetcher(t, getConfig())
+
+	// Do a first fetch to have percentages
+	f.Fetch()
+	time.Sleep(1 * time.Second)
+
+	err := mbtest.WriteEvent(f, t)
+	if err != nil {
+		t.Fatal("write", err)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	f := mbtest.NewEventFetcher(t, getConfig())
+	event, err := f.Fetch()
+	require.NoError(t, err)
+
+	assert.Equal(t, os.Getpid(), event["pid"])
+	for _, key := range []string{
+		"cpu.total.pct",
+		"cpu.total.norm.pct",
+		"cpu.total.ticks",
+		"memory.rss.bytes",
+		"memory.rss.pct",
+		"memory.size",
+		"runtime.memory.alloc.bytes",
+		"gc.count",
+		"gc.pause.total.ns",
+	} {
+		_, err := event.GetValue(key)
+		assert.NoError(t, err, key)
+	}
+
+	goroutines, err := event.GetValue("runtime.goroutines")
+	require.NoError(t, err)
+	assert.True(t, goroutines.(int) > 0)
+
+	rss, err := event.GetValue("memory.rss.bytes")
+	require.NoError(t, err)
+	assert.True(t, rss.(uint64) > 0)
+}
+
+func TestFetchEventContents(t *testing.T) {
+	start := time.Now()
+	samples := []*process.Process{
+		{
+			Pid:        42,
+			Cpu:        sigar.ProcTime{User: 600, Sys: 400, Total: 1000},
+			Mem:        sigar.ProcMem{Size: 4000, Resident: 1000},
+			SampleTime: start,
+		},
+		{
+			Pid:        42,
+			Cpu:        sigar.ProcTime{User: 1100, Sys: 500, Total: 1600},
+			Mem:        sigar.ProcMem{Size: 4000, Resident: 2000},
+			SampleTime: start.Add(2 * time.Second),
+		},
+	}
+
+	var pids []int
+	m := &MetricSet{
+		pid: 42,
+		getProcess: func(pid int) (*process.Process, error) {
+			pids = append(pids, pid)
+			p := samples[0]
+			samples = samples[1:]
+			return p, nil
+		},
+		readMemStats: func(mem *runtime.MemStats) {
+			*mem = runtime.MemStats{
+				Alloc:        100,
+				TotalAlloc:   300,
+				Sys:          500,
+				NumGC:        7,
+				PauseTotalNs: 12000,
+				NextGC:       200,
+			}
+		},
+		numGoroutine: func() int { return 12 },
+		totalMemory:  10000,
+	}
+
+	// the CPU percentages are only computed from the second fetch
+	event, err := m.Fetch()
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, event["cpu"].(common.MapStr)["total"].(common.MapStr)["pct"])
+
+	event, err = m.Fetch()
+	require.NoError(t, err)
+	assert.Equal(t, []int{42, 42}, pids)
+
+	expected := common.MapStr{
+		"pid": 42,
+		"cpu": common.MapStr{
+			"total": common.MapStr{
+				"pct":   0.3,
+				"norm":  common.MapStr{"pct": system.Round(0.3 / float64(process.NumCPU))},
+				"ticks": uint64(1600),
+			},
+			"user":   common.MapStr{"ticks": uint64(1100)},
+			"system": common.MapStr{"ticks": uint64(500)},
+		},
+		"memory": common.MapStr{
+			"rss":  common.MapStr{"bytes": uint64(2000), "pct": 0.2},
+			"size": uint64(4000),
+		},
+		"runtime": common.MapStr{
+			"goroutines": 12,
+			"memory": common.MapStr{
+				"alloc":       common.MapStr{"bytes": uint64(100)},
+				"total_alloc": common.MapStr{"bytes": uint64(300)},
+				"sys":         common.MapStr{"bytes": uint64(500)},
+			},
+		},
+		"gc": common.MapStr{
+			"count": uint32(7),
+			"pause": common.MapStr{
+				"total": common.MapStr{"ns": uint64(12000)},
+			},
+			"next": common.MapStr{"bytes": uint64(200)},
+		},
+	}
+	assert.Equal(t, expected, event)
+}
+
+func TestFetchProcessError(t *testing.T) {
+	m := &MetricSet{
+		getProcess: func(pid int) (*process.Process, error) {
+			return nil, errors.New("no such process")
+		},
+	}
+	_, err := m.Fetch()
+	assert.Error(t, err)
+}
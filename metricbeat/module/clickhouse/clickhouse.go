@@ -0,0 +1,176 @@
+package clickhouse
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/metricbeat/helper"
+	"github.com/elastic/beats/metricbeat/mb/parse"
+)
+
+// HostParser is used for parsing the configured ClickHouse hosts. Hosts are
+// addresses of the ClickHouse HTTP interface.
+var HostParser = parse.URLHostParserBuilder{
+	DefaultScheme: "http",
+	DefaultPath:   "/",
+}.Build()
+
+// result is the result of a query in the JSON output format.
+type result struct {
+	Meta []column                 `json:"meta"`
+	Data []map[string]interface{} `json:"data"`
+}
+
+type column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Query runs a query on the ClickHouse HTTP interface at uri, and returns the
+// rows of the result. Values are converted according to the types of the
+// result columns, as 64 bit integers are quoted in the JSON output.
+func Query(http *helper.HTTP, uri, query string) ([]common.MapStr, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing ClickHouse URL")
+	}
+
+	params := u.Query()
+	params.Set("query", query+" FORMAT JSON")
+	u.RawQuery = params.Encode()
+	http.SetURI(u.String())
+
+	content, err := http.FetchContent()
+	if err != nil {
+		return nil, err
+	}
+	return parseResult(content)
+}
+
+func parseResult(content []byte) ([]common.MapStr, error) {
+	var res result
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+	if err := dec.Decode(&res); err != nil {
+		return nil, errors.Wrap(err, "error parsing ClickHouse response")
+	}
+
+	rows := make([]common.MapStr, 0, len(res.Data))
+	for _, data := range res.Data {
+		row := common.MapStr{}
+		for _, col := range res.Meta {
+			value, err := convertValue(col.Type, data[col.Name])
+			if err != nil {
+				return nil, errors.Wrapf(err, "error converting column '%v'", col.Name)
+			}
+			if value != nil {
+				row[col.Name] = value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// convertValue converts a value of the JSON output format to the type matching
+// the ClickHouse column type. Integers are returned as int64, or uint64 if they
+// overflow int64, floats as float64. Values not representable in JSON, like
+// nan or inf, are returned as nil.
+func convertValue(typ string, value interface{}) (interface{}, error) {
+	var s string
+	switch v := value.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	default:
+		return v, nil
+	}
+
+	typ = baseType(typ)
+	switch {
+	case strings.HasPrefix(typ, "UInt"):
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case strings.HasPrefix(typ, "Int"):
+		return strconv.ParseInt(s, 10, 64)
+	case strings.HasPrefix(typ, "Float"), strings.HasPrefix(typ, "Decimal"):
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, err
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, nil
+		}
+		return f, nil
+	}
+	return s, nil
+}
+
+// baseType strips the Nullable and LowCardinality modifiers from a type.
+func baseType(typ string) string {
+	for _, modifier := range []string{"Nullable(", "LowCardinality("} {
+		if strings.HasPrefix(typ, modifier) && strings.HasSuffix(typ, ")") {
+			return baseType(typ[len(modifier) : len(typ)-1])
+		}
+	}
+	return typ
+}
+
+// Metrics maps rows of metric names and values to fields named after the
+// metrics.
+func Metrics(rows []common.MapStr, nameColumn, valueColumn string) common.MapStr {
+	metrics := common.MapStr{}
+	for _, row := range rows {
+		name, ok := row[nameColumn].(string)
+		if !ok {
+			continue
+		}
+		if value, ok := row[valueColumn]; ok {
+			metrics[FieldName(name)] = value
+		}
+	}
+	return metrics
+}
+
+// FieldName converts the CamelCase name of a ClickHouse metric to a field
+// name, for example TCPConnection to tcp_connection. Dots are replaced, as
+// metric names can be prefixes of other metric names.
+func FieldName(name string) string {
+	runes := []rune(name)
+	var buf bytes.Buffer
+	for i, r := range runes {
+		if isSeparator(r) {
+			buf.WriteRune('_')
+			continue
+		}
+
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				buf.WriteRune('_')
+			}
+		}
+		buf.WriteRune(unicode.ToLower(r))
+	}
+	return buf.String()
+}
+
+func isSeparator(r rune) bool {
+	return r == '_' || r == '.' || r == '-' || r == ' '
+}
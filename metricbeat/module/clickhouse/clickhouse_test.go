@@ -0,0 +1,87 @@
+// +build !integration
+
+package clickhouse
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func TestParseResult(t *testing.T) {
+	content := []byte(`{
+		"meta": [
+			{"name": "name", "type": "String"},
+			{"name": "count", "type": "UInt64"},
+			{"name": "delta", "type": "Int32"},
+			{"name": "ratio", "type": "Nullable(Float64)"},
+			{"name": "size", "type": "LowCardinality(Nullable(UInt64))"},
+			{"name": "big", "type": "UInt64"}
+		],
+		"data": [
+			{"name": "a", "count": "42", "delta": -3, "ratio": 0.5, "size": "1024", "big": "18446744073709551615"},
+			{"name": "b", "count": "0", "delta": 0, "ratio": null, "size": null, "big": "1"}
+		],
+		"rows": 2
+	}`)
+
+	rows, err := parseResult(content)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, []common.MapStr{
+		{"name": "a", "count": int64(42), "delta": int64(-3), "ratio": 0.5, "size": int64(1024), "big": uint64(math.MaxUint64)},
+		{"name": "b", "count": int64(0), "delta": int64(0), "big": int64(1)},
+	}, rows)
+}
+
+func TestParseResultErrors(t *testing.T) {
+	_, err := parseResult([]byte(`Code: 60, e.displayText() = DB::Exception: Table system.foo doesn't exist`))
+	assert.Error(t, err)
+
+	_, err = parseResult([]byte(`{"meta": [{"name": "value", "type": "Int64"}], "data": [{"value": "abc"}]}`))
+	assert.Error(t, err)
+}
+
+func TestConvertValueDenormals(t *testing.T) {
+	for _, value := range []string{"nan", "inf", "-inf"} {
+		v, err := convertValue("Float64", value)
+		assert.NoError(t, err, value)
+		assert.Nil(t, v, value)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	rows := []common.MapStr{
+		{"metric": "Query", "value": int64(1)},
+		{"metric": "jemalloc.allocated", "value": float64(1024)},
+		{"metric": "LoadAverage1"},
+		{"value": int64(3)},
+	}
+
+	assert.Equal(t, common.MapStr{
+		"query":              int64(1),
+		"jemalloc_allocated": float64(1024),
+	}, Metrics(rows, "metric", "value"))
+}
+
+func TestFieldName(t *testing.T) {
+	tests := map[string]string{
+		"Query":                                  "query",
+		"TCPConnection":                          "tcp_connection",
+		"OSMemoryTotal":                          "os_memory_total",
+		"ReplicasMaxQueueSize":                   "replicas_max_queue_size",
+		"LoadAverage15":                          "load_average15",
+		"jemalloc.background_thread.num_threads": "jemalloc_background_thread_num_threads",
+		"CPUFrequencyMHz_0":                      "cpu_frequency_m_hz_0",
+		"already_snake":                          "already_snake",
+	}
+
+	for name, expected := range tests {
+		assert.Equal(t, expected, FieldName(name), name)
+	}
+}
@@ -0,0 +1,4 @@
+/*
+Package clickhouse is a Metricbeat module that contains MetricSets.
+*/
+package clickhouse
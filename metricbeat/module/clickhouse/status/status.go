@@ -0,0 +1,59 @@
+package status
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/metricbeat/helper"
+	"github.com/elastic/beats/metricbeat/mb"
+	"github.com/elastic/beats/metricbeat/module/clickhouse"
+)
+
+const (
+	metricsQuery = "SELECT metric, value FROM system.metrics"
+	eventsQuery  = "SELECT event, value FROM system.events"
+)
+
+// init registers the MetricSet with the central registry.
+// The New method will be called after the setup of the module and before starting to fetch data
+func init() {
+	if err := mb.Registry.AddMetricSet("clickhouse", "status", New, clickhouse.HostParser); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet type defines all fields of the MetricSet
+type MetricSet struct {
+	mb.BaseMetricSet
+	http *helper.HTTP
+}
+
+// New create a new instance of the MetricSet
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	cfgwarn.Experimental("The clickhouse status metricset is experimental")
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		http:          helper.NewHTTP(base),
+	}, nil
+}
+
+// Fetch fetches the current metrics of system.metrics, and the event counters
+// of system.events.
+func (m *MetricSet) Fetch() (common.MapStr, error) {
+	metrics, err := clickhouse.Query(m.http, m.HostData().SanitizedURI, metricsQuery)
+	if err != nil {
+		return nil, errors.Wrap(err, "error querying system.metrics")
+	}
+
+	events, err := clickhouse.Query(m.http, m.HostData().SanitizedURI, eventsQuery)
+	if err != nil {
+		return nil, errors.Wrap(err, "error querying system.events")
+	}
+
+	return common.MapStr{
+		"metrics": clickhouse.Metrics(metrics, "metric", "value"),
+		"events":  clickhouse.Metrics(events, "event", "value"),
+	}, nil
+}
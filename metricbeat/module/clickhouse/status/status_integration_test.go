@@ -0,0 +1,44 @@
+// +build integration
+
+package status
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/tests/compose"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+	"github.com/elastic/beats/metricbeat/module/clickhouse"
+)
+
+func TestFetch(t *testing.T) {
+	compose.EnsureUp(t, "clickhouse")
+
+	f := mbtest.NewEventFetcher(t, getConfig())
+	event, err := f.Fetch()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	assert.NotEmpty(t, event)
+	t.Logf("%s/%s event: %+v", f.Module().Name(), f.Name(), event)
+}
+
+func TestData(t *testing.T) {
+	compose.EnsureUp(t, "clickhouse")
+
+	f := mbtest.NewEventFetcher(t, getConfig())
+	err := mbtest.WriteEvent(f, t)
+	if err != nil {
+		t.Fatal("write", err)
+	}
+}
+
+func getConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"module":     "clickhouse",
+		"metricsets": []string{"status"},
+		"hosts":      []string{clickhouse.GetEnvHost() + ":" + clickhouse.GetEnvPort()},
+	}
+}
@@ -0,0 +1,100 @@
+// +build !integration
+
+package status
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+)
+
+func TestFetchEventContents(t *testing.T) {
+	var (
+		queries []string
+		user    string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("query")
+		queries = append(queries, query)
+		user, _, _ = r.BasicAuth()
+
+		file := "metrics.json"
+		if strings.Contains(query, "system.events") {
+			file = "events.json"
+		}
+
+		// Responses recorded from ClickHouse 18.14.
+		response, err := ioutil.ReadFile(filepath.Join("testdata", file))
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(200)
+		w.Write(response)
+	}))
+	defer server.Close()
+
+	config := map[string]interface{}{
+		"module":     "clickhouse",
+		"metricsets": []string{"status"},
+		"hosts":      []string{server.URL},
+		"username":   "metricbeat",
+		"password":   "secret",
+	}
+
+	f := mbtest.NewEventFetcher(t, config)
+	event, err := f.Fetch()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	t.Logf("%s/%s event: %+v", f.Module().Name(), f.Name(), event.StringToPrint())
+
+	assert.Equal(t, []string{
+		"SELECT metric, value FROM system.metrics FORMAT JSON",
+		"SELECT event, value FROM system.events FORMAT JSON",
+	}, queries)
+	assert.Equal(t, "metricbeat", user)
+
+	metrics := event["metrics"].(common.MapStr)
+	assert.Len(t, metrics, 14)
+	assert.Equal(t, int64(1), metrics["query"])
+	assert.Equal(t, int64(2), metrics["tcp_connection"])
+	assert.Equal(t, int64(1), metrics["http_connection"])
+	assert.Equal(t, int64(2101248), metrics["memory_tracking"])
+	assert.Equal(t, int64(0), metrics["readonly_replica"])
+
+	events := event["events"].(common.MapStr)
+	assert.Len(t, events, 10)
+	assert.Equal(t, int64(1342), events["query"])
+	assert.Equal(t, int64(1210), events["select_query"])
+	assert.Equal(t, int64(3), events["failed_query"])
+	assert.Equal(t, int64(1290856722), events["inserted_bytes"])
+	assert.Equal(t, int64(8534290284), events["read_buffer_from_file_descriptor_read_bytes"])
+	assert.Equal(t, int64(401481840), events["oscpu_virtual_time_microseconds"])
+}
+
+func TestFetchHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(516)
+		w.Write([]byte("Code: 516, e.displayText() = DB::Exception: default: Authentication failed"))
+	}))
+	defer server.Close()
+
+	config := map[string]interface{}{
+		"module":     "clickhouse",
+		"metricsets": []string{"status"},
+		"hosts":      []string{server.URL},
+	}
+
+	f := mbtest.NewEventFetcher(t, config)
+	_, err := f.Fetch()
+	assert.Error(t, err)
+}
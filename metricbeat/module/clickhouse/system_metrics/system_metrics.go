@@ -0,0 +1,48 @@
+package system_metrics
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/metricbeat/helper"
+	"github.com/elastic/beats/metricbeat/mb"
+	"github.com/elastic/beats/metricbeat/module/clickhouse"
+)
+
+const asynchronousMetricsQuery = "SELECT metric, value FROM system.asynchronous_metrics"
+
+// init registers the MetricSet with the central registry.
+// The New method will be called after the setup of the module and before starting to fetch data
+func init() {
+	if err := mb.Registry.AddMetricSet("clickhouse", "system_metrics", New, clickhouse.HostParser); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet type defines all fields of the MetricSet
+type MetricSet struct {
+	mb.BaseMetricSet
+	http *helper.HTTP
+}
+
+// New create a new instance of the MetricSet
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	cfgwarn.Experimental("The clickhouse system_metrics metricset is experimental")
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		http:          helper.NewHTTP(base),
+	}, nil
+}
+
+// Fetch fetches the metrics periodically calculated by the server, from
+// system.asynchronous_metrics.
+func (m *MetricSet) Fetch() (common.MapStr, error) {
+	rows, err := clickhouse.Query(m.http, m.HostData().SanitizedURI, asynchronousMetricsQuery)
+	if err != nil {
+		return nil, errors.Wrap(err, "error querying system.asynchronous_metrics")
+	}
+
+	return clickhouse.Metrics(rows, "metric", "value"), nil
+}
@@ -0,0 +1,56 @@
+// +build !integration
+
+package system_metrics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+)
+
+func TestFetchEventContents(t *testing.T) {
+	// Response recorded from ClickHouse 18.14.
+	response, err := ioutil.ReadFile(filepath.Join("testdata", "asynchronous_metrics.json"))
+	require.NoError(t, err)
+
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("query")
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(200)
+		w.Write(response)
+	}))
+	defer server.Close()
+
+	config := map[string]interface{}{
+		"module":     "clickhouse",
+		"metricsets": []string{"system_metrics"},
+		"hosts":      []string{server.URL},
+	}
+
+	f := mbtest.NewEventFetcher(t, config)
+	event, err := f.Fetch()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	t.Logf("%s/%s event: %+v", f.Module().Name(), f.Name(), event.StringToPrint())
+
+	assert.Equal(t, "SELECT metric, value FROM system.asynchronous_metrics FORMAT JSON", query)
+	assert.Len(t, event, 12)
+	assert.Equal(t, float64(586169), event["uptime"])
+	assert.Equal(t, float64(191697736), event["jemalloc_allocated"])
+	assert.Equal(t, float64(0), event["jemalloc_background_thread_num_threads"])
+	assert.Equal(t, float64(4392), event["mark_cache_bytes"])
+	assert.Equal(t, float64(27), event["number_of_tables"])
+	assert.Equal(t, 0.43, event["load_average1"])
+	assert.Equal(t, float64(8348520448), event["os_memory_total"])
+	assert.Equal(t, 2893.202, event["cpu_frequency_m_hz_0"])
+}
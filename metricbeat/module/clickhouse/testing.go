@@ -0,0 +1,25 @@
+package clickhouse
+
+import "os"
+
+// GetEnvHost returns the host of the ClickHouse HTTP interface used for
+// integration tests.
+func GetEnvHost() string {
+	host := os.Getenv("CLICKHOUSE_HOST")
+
+	if len(host) == 0 {
+		host = "127.0.0.1"
+	}
+	return host
+}
+
+// GetEnvPort returns the port of the ClickHouse HTTP interface used for
+// integration tests.
+func GetEnvPort() string {
+	port := os.Getenv("CLICKHOUSE_PORT")
+
+	if len(port) == 0 {
+		port = "8123"
+	}
+	return port
+}
@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/metricbeat/helper"
+	"github.com/elastic/beats/metricbeat/mb"
+	"github.com/elastic/beats/metricbeat/module/consul"
+)
+
+const defaultPath = "/v1/agent/metrics"
+
+func init() {
+	if err := mb.Registry.AddMetricSet("consul", "agent", New, consul.NewHostParser(defaultPath)); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet fetches the metrics of the Consul agent.
+type MetricSet struct {
+	mb.BaseMetricSet
+	http *helper.HTTP
+}
+
+// New creates a new instance of the agent MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	cfgwarn.Experimental("The consul agent metricset is experimental")
+
+	http, err := consul.NewHTTP(base)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		http:          http,
+	}, nil
+}
+
+// Fetch fetches the runtime and autopilot metrics of the agent.
+func (m *MetricSet) Fetch() (common.MapStr, error) {
+	content, err := m.http.FetchContent()
+	if err != nil {
+		return nil, err
+	}
+
+	return eventMapping(content)
+}
@@ -0,0 +1,114 @@
+// +build !integration
+
+package agent
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+)
+
+func newTestServer(t *testing.T, file string, token *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/agent/metrics", r.URL.Path)
+		*token = r.Header.Get("X-Consul-Token")
+
+		// Responses recorded from Consul 1.2.
+		response, err := ioutil.ReadFile(filepath.Join("testdata", file))
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(response)
+	}))
+}
+
+func TestFetchEventContents(t *testing.T) {
+	var token string
+	server := newTestServer(t, "metrics.json", &token)
+	defer server.Close()
+
+	config := map[string]interface{}{
+		"module":     "consul",
+		"metricsets": []string{"agent"},
+		"hosts":      []string{server.URL},
+		"token":      "secret",
+	}
+
+	f := mbtest.NewEventFetcher(t, config)
+	event, err := f.Fetch()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	t.Logf("%s/%s event: %+v", f.Module().Name(), f.Name(), event.StringToPrint())
+
+	assert.Equal(t, "secret", token)
+	assert.Equal(t, common.MapStr{
+		"runtime": common.MapStr{
+			"alloc":        common.MapStr{"bytes": int64(5183760)},
+			"sys":          common.MapStr{"bytes": int64(15732984)},
+			"heap_objects": int64(28596),
+			"malloc":       common.MapStr{"count": int64(157320)},
+			"free":         common.MapStr{"count": int64(128724)},
+			"goroutines":   int64(95),
+			"gc": common.MapStr{
+				"pause": common.MapStr{"total": common.MapStr{"ns": int64(3871259)}},
+				"runs":  common.MapStr{"total": int64(12)},
+			},
+		},
+		"autopilot": common.MapStr{
+			"healthy":           true,
+			"failure_tolerance": int64(1),
+		},
+	}, event)
+}
+
+func TestFetchClientAgent(t *testing.T) {
+	// Client agents report no autopilot metrics, and their runtime metrics
+	// are not prefixed by the hostname if `telemetry.disable_hostname` is
+	// set.
+	var token string
+	server := newTestServer(t, "metrics_client.json", &token)
+	defer server.Close()
+
+	config := map[string]interface{}{
+		"module":     "consul",
+		"metricsets": []string{"agent"},
+		"hosts":      []string{server.URL},
+	}
+
+	f := mbtest.NewEventFetcher(t, config)
+	event, err := f.Fetch()
+	require.NoError(t, err)
+
+	assert.Empty(t, token)
+	assert.NotContains(t, event, "autopilot")
+	goroutines, _ := event.GetValue("runtime.goroutines")
+	assert.Equal(t, int64(41), goroutines)
+}
+
+func TestFetchForbidden(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(403)
+		w.Write([]byte("Permission denied"))
+	}))
+	defer server.Close()
+
+	config := map[string]interface{}{
+		"module":     "consul",
+		"metricsets": []string{"agent"},
+		"hosts":      []string{server.URL},
+	}
+
+	f := mbtest.NewEventFetcher(t, config)
+	_, err := f.Fetch()
+	assert.Error(t, err)
+}
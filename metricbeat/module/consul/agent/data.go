@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// metrics is the response of the agent metrics endpoint, the current
+// telemetry of the agent.
+type metrics struct {
+	Gauges []gauge `json:"Gauges"`
+}
+
+type gauge struct {
+	Name  string  `json:"Name"`
+	Value float64 `json:"Value"`
+}
+
+// gauges maps the names of the gauges to fields. The names are matched by
+// suffix, as the runtime gauges are prefixed by the hostname of the agent
+// unless `telemetry.disable_hostname` is set.
+var gauges = map[string]string{
+	"runtime.alloc_bytes":         "runtime.alloc.bytes",
+	"runtime.sys_bytes":           "runtime.sys.bytes",
+	"runtime.heap_objects":        "runtime.heap_objects",
+	"runtime.malloc_count":        "runtime.malloc.count",
+	"runtime.free_count":          "runtime.free.count",
+	"runtime.num_goroutines":      "runtime.goroutines",
+	"runtime.total_gc_pause_ns":   "runtime.gc.pause.total.ns",
+	"runtime.total_gc_runs":       "runtime.gc.runs.total",
+	"autopilot.failure_tolerance": "autopilot.failure_tolerance",
+}
+
+func eventMapping(content []byte) (common.MapStr, error) {
+	var m metrics
+	if err := json.Unmarshal(content, &m); err != nil {
+		return nil, errors.Wrap(err, "error parsing consul agent metrics")
+	}
+
+	event := common.MapStr{}
+	for _, g := range m.Gauges {
+		// Autopilot is only reported by the servers.
+		if g.Name == "consul.autopilot.healthy" {
+			event.Put("autopilot.healthy", g.Value == 1)
+			continue
+		}
+		for suffix, field := range gauges {
+			if g.Name == "consul."+suffix || strings.HasSuffix(g.Name, "."+suffix) {
+				event.Put(field, int64(g.Value))
+				break
+			}
+		}
+	}
+	return event, nil
+}
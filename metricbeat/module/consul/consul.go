@@ -0,0 +1,43 @@
+package consul
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/metricbeat/helper"
+	"github.com/elastic/beats/metricbeat/mb"
+	"github.com/elastic/beats/metricbeat/mb/parse"
+)
+
+// tokenHeader is the header of the ACL token in the requests of the Consul
+// HTTP API.
+const tokenHeader = "X-Consul-Token"
+
+// NewHostParser returns a parser of the configured Consul hosts, addresses
+// of the HTTP API, by default requesting the endpoint at path.
+func NewHostParser(path string) mb.HostParser {
+	return parse.URLHostParserBuilder{
+		DefaultScheme: "http",
+		DefaultPath:   path,
+	}.Build()
+}
+
+// NewHTTP returns the HTTP helper requesting the Consul HTTP API, with the
+// ACL token of the module configuration.
+func NewHTTP(base mb.BaseMetricSet) (*helper.HTTP, error) {
+	config := struct {
+		Token string `config:"token"`
+	}{}
+	if err := base.Module().UnpackConfig(&config); err != nil {
+		return nil, errors.Wrap(err, "error unpacking the consul configuration")
+	}
+
+	http := helper.NewHTTP(base)
+	if http == nil {
+		return nil, errors.New("error creating the HTTP client of the consul module")
+	}
+	http.SetHeader("Accept", "application/json")
+	if config.Token != "" {
+		http.SetHeader(tokenHeader, config.Token)
+	}
+	return http, nil
+}
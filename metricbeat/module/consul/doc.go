@@ -0,0 +1,4 @@
+/*
+Package consul is a Metricbeat module that contains MetricSets.
+*/
+package consul
@@ -0,0 +1,132 @@
+package health
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// Statuses of the health checks. Checks put in maintenance are reported as
+// critical by Consul, they are identified by their ID.
+const (
+	statusPassing     = "passing"
+	statusWarning     = "warning"
+	statusCritical    = "critical"
+	statusMaintenance = "maintenance"
+)
+
+const (
+	nodeMaintenanceCheck    = "_node_maintenance"
+	serviceMaintenanceCheck = "_service_maintenance:"
+)
+
+// severity orders the statuses, the health of a node or a service is the
+// most severe status of its checks.
+var severity = map[string]int{
+	statusPassing:     0,
+	statusWarning:     1,
+	statusCritical:    2,
+	statusMaintenance: 3,
+}
+
+// check is a health check, as returned by the health endpoints. Checks of a
+// node have no service ID.
+type check struct {
+	Node        string   `json:"Node"`
+	CheckID     string   `json:"CheckID"`
+	Name        string   `json:"Name"`
+	Status      string   `json:"Status"`
+	Notes       string   `json:"Notes"`
+	Output      string   `json:"Output"`
+	ServiceID   string   `json:"ServiceID"`
+	ServiceName string   `json:"ServiceName"`
+	ServiceTags []string `json:"ServiceTags"`
+}
+
+func (c *check) status() string {
+	if c.CheckID == nodeMaintenanceCheck || strings.HasPrefix(c.CheckID, serviceMaintenanceCheck) {
+		return statusMaintenance
+	}
+	return c.Status
+}
+
+func eventsMapping(content []byte) ([]common.MapStr, error) {
+	var checks []check
+	if err := json.Unmarshal(content, &checks); err != nil {
+		return nil, errors.Wrap(err, "error parsing consul health checks")
+	}
+
+	// A node is as healthy as its own checks, a service instance as its
+	// checks and the checks of its node, like in the health of the Consul
+	// catalog.
+	nodes := map[string]string{}
+	services := map[string]string{}
+	for i := range checks {
+		c := &checks[i]
+		if c.ServiceID == "" {
+			nodes[c.Node] = worst(nodes[c.Node], c.status())
+		} else {
+			key := serviceKey(c)
+			services[key] = worst(services[key], c.status())
+		}
+	}
+
+	events := make([]common.MapStr, 0, len(checks))
+	for i := range checks {
+		c := &checks[i]
+		status := c.status()
+		nodeStatus := worst(nodes[c.Node], statusPassing)
+
+		event := common.MapStr{
+			"check": common.MapStr{
+				"id":     c.CheckID,
+				"name":   c.Name,
+				"status": status,
+				"output": c.Output,
+			},
+			"healthy": status == statusPassing,
+			"node": common.MapStr{
+				"name":   c.Node,
+				"status": nodeStatus,
+			},
+		}
+		if c.Notes != "" {
+			event.Put("check.notes", c.Notes)
+		}
+		if c.ServiceID != "" {
+			service := common.MapStr{
+				"id":     c.ServiceID,
+				"name":   c.ServiceName,
+				"status": worst(services[serviceKey(c)], nodeStatus),
+			}
+			if len(c.ServiceTags) > 0 {
+				service["tags"] = c.ServiceTags
+			}
+			event["service"] = service
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// serviceKey identifies a service instance, service IDs are only unique on a
+// node.
+func serviceKey(c *check) string {
+	return c.Node + "/" + c.ServiceID
+}
+
+// worst returns the most severe of two statuses. Unknown statuses are less
+// severe than all others, so an empty status is replaced by any valid status.
+func worst(a, b string) string {
+	sa, ok := severity[a]
+	if !ok {
+		return b
+	}
+	if sb, ok := severity[b]; ok && sb > sa {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,49 @@
+package health
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/metricbeat/helper"
+	"github.com/elastic/beats/metricbeat/mb"
+	"github.com/elastic/beats/metricbeat/module/consul"
+)
+
+const defaultPath = "/v1/health/state/any"
+
+func init() {
+	if err := mb.Registry.AddMetricSet("consul", "health", New, consul.NewHostParser(defaultPath)); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet fetches the health checks of the Consul catalog.
+type MetricSet struct {
+	mb.BaseMetricSet
+	http *helper.HTTP
+}
+
+// New creates a new instance of the health MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	cfgwarn.Experimental("The consul health metricset is experimental")
+
+	http, err := consul.NewHTTP(base)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		http:          http,
+	}, nil
+}
+
+// Fetch fetches the health checks of all nodes and services, one event per
+// check, with the health of the node and the service it belongs to.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	content, err := m.http.FetchContent()
+	if err != nil {
+		return nil, err
+	}
+
+	return eventsMapping(content)
+}
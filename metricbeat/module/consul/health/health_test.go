@@ -0,0 +1,148 @@
+//go:build !integration
+// +build !integration
+
+package health
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+)
+
+func TestFetchEventContents(t *testing.T) {
+	// Response recorded from Consul 1.2, with a node and a service in
+	// maintenance.
+	response, err := ioutil.ReadFile(filepath.Join("testdata", "health.json"))
+	require.NoError(t, err)
+
+	var token string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/health/state/any", r.URL.Path)
+		token = r.Header.Get("X-Consul-Token")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(response)
+	}))
+	defer server.Close()
+
+	config := map[string]interface{}{
+		"module":     "consul",
+		"metricsets": []string{"health"},
+		"hosts":      []string{server.URL},
+		"token":      "secret",
+	}
+
+	f := mbtest.NewEventsFetcher(t, config)
+	events, err := f.Fetch()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	require.Len(t, events, 9)
+	assert.Equal(t, "secret", token)
+
+	assert.Equal(t, common.MapStr{
+		"check": common.MapStr{
+			"id":     "web-1-latency",
+			"name":   "Web latency",
+			"status": "warning",
+			"output": "latency 350ms",
+			"notes":  "Latency above 200ms",
+		},
+		"healthy": false,
+		"node": common.MapStr{
+			"name":   "consul-1",
+			"status": "passing",
+		},
+		"service": common.MapStr{
+			"id":     "web-1",
+			"name":   "web",
+			"status": "warning",
+			"tags":   []string{"primary", "v1"},
+		},
+	}, events[2])
+
+	tests := []struct {
+		check                             string
+		status, nodeStatus, serviceStatus string
+		healthy                           bool
+	}{
+		// The service is as healthy as its worst check.
+		{"service:web-1", "passing", "passing", "warning", true},
+		// Maintenance checks are reported as critical by Consul.
+		{"_service_maintenance:db-1", "maintenance", "passing", "maintenance", false},
+		{"_node_maintenance", "maintenance", "maintenance", "", false},
+		// The services of a node are as healthy as the node.
+		{"service:web-1@consul-2", "passing", "maintenance", "maintenance", true},
+		{"serfHealth@consul-3", "critical", "critical", "", false},
+		{"service:api-1", "passing", "critical", "critical", true},
+	}
+
+	for _, test := range tests {
+		event := findCheck(t, events, test.check)
+		if event == nil {
+			continue
+		}
+
+		status, _ := event.GetValue("check.status")
+		nodeStatus, _ := event.GetValue("node.status")
+		serviceStatus, _ := event.GetValue("service.status")
+		if test.serviceStatus == "" {
+			serviceStatus = ""
+		}
+		assert.Equal(t, test.status, status, test.check)
+		assert.Equal(t, test.nodeStatus, nodeStatus, test.check)
+		assert.Equal(t, test.serviceStatus, serviceStatus, test.check)
+		assert.Equal(t, test.healthy, event["healthy"], test.check)
+	}
+}
+
+func TestFetchNodeChecks(t *testing.T) {
+	events, err := eventsMapping([]byte(`[
+		{"Node": "consul-1", "CheckID": "serfHealth", "Name": "Serf Health Status", "Status": "passing", "ServiceID": ""}
+	]`))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	assert.NotContains(t, events[0], "service")
+	assert.False(t, hasKey(events[0], "check.notes"))
+	assert.Equal(t, true, events[0]["healthy"])
+}
+
+func TestFetchInvalidResponse(t *testing.T) {
+	_, err := eventsMapping([]byte(`{"error": "Permission denied"}`))
+	assert.Error(t, err)
+}
+
+// findCheck returns the event of a check, given by its ID or by its ID and
+// node as `id@node`, as check IDs are only unique on a node.
+func findCheck(t *testing.T, events []common.MapStr, id string) common.MapStr {
+	node := ""
+	if i := strings.LastIndex(id, "@"); i >= 0 {
+		id, node = id[:i], id[i+1:]
+	}
+
+	for _, event := range events {
+		checkID, _ := event.GetValue("check.id")
+		nodeName, _ := event.GetValue("node.name")
+		if checkID == id && (node == "" || nodeName == node) {
+			return event
+		}
+	}
+	t.Errorf("no event for check %v on node '%v'", id, node)
+	return nil
+}
+
+func hasKey(event common.MapStr, key string) bool {
+	ok, _ := event.HasKey(key)
+	return ok
+}
@@ -0,0 +1,25 @@
+package consul
+
+import "os"
+
+// GetEnvHost returns the host of the Consul HTTP API used for integration
+// tests.
+func GetEnvHost() string {
+	host := os.Getenv("CONSUL_HOST")
+
+	if len(host) == 0 {
+		host = "127.0.0.1"
+	}
+	return host
+}
+
+// GetEnvPort returns the port of the Consul HTTP API used for integration
+// tests.
+func GetEnvPort() string {
+	port := os.Getenv("CONSUL_PORT")
+
+	if len(port) == 0 {
+		port = "8500"
+	}
+	return port
+}
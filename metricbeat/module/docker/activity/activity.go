@@ -0,0 +1,145 @@
+package activity
+
+import (
+	"time"
+
+	dc "github.com/fsouza/go-dockerclient"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/mb"
+	"github.com/elastic/beats/metricbeat/module/docker"
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("docker", "activity", New, docker.HostParser); err != nil {
+		panic(err)
+	}
+}
+
+type MetricSet struct {
+	mb.BaseMetricSet
+	dockerClient *dc.Client
+
+	// logs contains the log position of each running container, from the
+	// previous fetch.
+	logs map[string]*logState
+	now  func() time.Time
+}
+
+type logState struct {
+	// last is the timestamp of the last log line read, as reported by Docker.
+	last time.Time
+	// fetched is the time of the fetch the log position was updated in.
+	fetched time.Time
+}
+
+// New creates a new instance of the docker activity MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	cfgwarn.Experimental("The docker activity metricset is experimental")
+
+	config := docker.Config{}
+	if err := base.Module().UnpackConfig(&config); err != nil {
+		return nil, err
+	}
+
+	client, err := docker.NewDockerClient(base.HostData().URI, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		dockerClient:  client,
+		logs:          map[string]*logState{},
+		now:           time.Now,
+	}, nil
+}
+
+// Fetch returns the restart count and the log rate of each running container.
+// The log rate is computed from the log lines written since the previous
+// fetch, so it is not reported on the first fetch of a container.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	containers, err := m.dockerClient.ListContainers(dc.ListContainersOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	now := m.now()
+	running := make(map[string]*logState, len(containers))
+	var events []common.MapStr
+	for _, container := range containers {
+		info, err := m.dockerClient.InspectContainer(container.ID)
+		if err != nil {
+			logp.Err("Error inspecting container %v: %v", container.ID, err)
+			continue
+		}
+
+		event := common.MapStr{
+			mb.ModuleDataKey: common.MapStr{
+				"container": docker.NewContainer(&container).ToMapStr(),
+			},
+			"restart_count": info.RestartCount,
+		}
+
+		state, err := m.fetchLogs(info, m.logs[container.ID], now, event)
+		if err != nil {
+			logp.Debug("docker", "Error reading logs of container %v: %v", container.ID, err)
+		}
+		if state != nil {
+			running[container.ID] = state
+		}
+		events = append(events, event)
+	}
+
+	// Containers not running anymore are removed.
+	m.logs = running
+	return events, nil
+}
+
+// fetchLogs reads the log lines written by the container since the previous
+// fetch, and adds the log metrics to the event. It returns the updated log
+// position.
+func (m *MetricSet) fetchLogs(info *dc.Container, state *logState, now time.Time, event common.MapStr) (*logState, error) {
+	tty := info.Config != nil && info.Config.Tty
+
+	if state == nil {
+		// Only the position of the last line is stored on the first fetch, as
+		// the rate can not be computed yet.
+		counter := newLogCounter(time.Time{})
+		err := m.readLogs(info.ID, tty, counter, dc.LogsOptions{Tail: "1"})
+		if err != nil {
+			return nil, err
+		}
+		return &logState{last: counter.last, fetched: now}, nil
+	}
+
+	counter := newLogCounter(state.last)
+	err := m.readLogs(info.ID, tty, counter, dc.LogsOptions{Since: state.last.Unix()})
+	if err != nil {
+		return state, err
+	}
+
+	log := common.MapStr{
+		"bytes": counter.bytes,
+	}
+	if elapsed := now.Sub(state.fetched).Seconds(); elapsed > 0 {
+		log["bytes_per_sec"] = float64(counter.bytes) / elapsed
+	}
+	event["log"] = log
+
+	return &logState{last: counter.last, fetched: now}, nil
+}
+
+func (m *MetricSet) readLogs(id string, tty bool, counter *logCounter, opts dc.LogsOptions) error {
+	opts.Container = id
+	opts.Stdout = true
+	opts.Stderr = true
+	opts.Timestamps = true
+	opts.RawTerminal = tty
+	opts.OutputStream = counter.stream()
+	opts.ErrorStream = counter.stream()
+	opts.InactivityTimeout = m.Module().Config().Timeout
+	return m.dockerClient.Logs(opts)
+}
@@ -0,0 +1,177 @@
+// +build !integration
+
+package activity
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/metricbeat/mb"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+)
+
+type logLine struct {
+	timestamp time.Time
+	stderr    bool
+	message   string
+}
+
+// fakeDocker serves the parts of the Docker API used by the metricset for a
+// single container.
+type fakeDocker struct {
+	sync.Mutex
+	restartCount int
+	lines        []logLine
+}
+
+func (d *fakeDocker) setState(restartCount int, lines ...logLine) {
+	d.Lock()
+	defer d.Unlock()
+	d.restartCount = restartCount
+	d.lines = append(d.lines, lines...)
+}
+
+func (d *fakeDocker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.Lock()
+	defer d.Unlock()
+
+	switch r.URL.Path {
+	case "/containers/json":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"Id": "abc", "Names": []string{"/web"}},
+		})
+	case "/containers/abc/json":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"Id":           "abc",
+			"RestartCount": d.restartCount,
+			"Config":       map[string]interface{}{"Tty": false},
+		})
+	case "/containers/abc/logs":
+		d.serveLogs(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveLogs writes the log lines filtered by since and tail, multiplexed as
+// done by the Docker API.
+func (d *fakeDocker) serveLogs(w http.ResponseWriter, r *http.Request) {
+	since, _ := strconv.ParseInt(r.URL.Query().Get("since"), 10, 64)
+
+	var lines []logLine
+	for _, line := range d.lines {
+		if line.timestamp.Unix() >= since {
+			lines = append(lines, line)
+		}
+	}
+	if tail, err := strconv.Atoi(r.URL.Query().Get("tail")); err == nil && tail < len(lines) {
+		lines = lines[len(lines)-tail:]
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.docker.raw-stream")
+	for _, line := range lines {
+		payload := fmt.Sprintf("%s %s\n", line.timestamp.Format(time.RFC3339Nano), line.message)
+
+		header := make([]byte, 8)
+		header[0] = 1
+		if line.stderr {
+			header[0] = 2
+		}
+		binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+		w.Write(header)
+		w.Write([]byte(payload))
+	}
+}
+
+func TestFetchRestartCountAndLogRate(t *testing.T) {
+	docker := &fakeDocker{}
+	server := httptest.NewServer(docker)
+	defer server.Close()
+
+	start := time.Date(2018, 1, 1, 10, 0, 0, 0, time.UTC)
+	docker.setState(1,
+		logLine{timestamp: start, message: "starting"},
+		logLine{timestamp: start.Add(100 * time.Millisecond), message: "started"},
+	)
+
+	f := mbtest.NewEventsFetcher(t, map[string]interface{}{
+		"module":     "docker",
+		"metricsets": []string{"activity"},
+		"hosts":      []string{server.URL},
+	})
+	now := start.Add(time.Second)
+	f.(*MetricSet).now = func() time.Time { return now }
+
+	// The first fetch only reports the restart count.
+	events, err := f.Fetch()
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, 1, events[0]["restart_count"])
+	assert.NotContains(t, events[0], "log")
+
+	container, _ := events[0].GetValue(mb.ModuleDataKey + ".container")
+	assert.Equal(t, common.MapStr{"id": "abc", "name": "web"}, container)
+
+	// Lines in the same second as the last line of the previous fetch must
+	// only be counted if they are newer.
+	docker.setState(3,
+		logLine{timestamp: start.Add(500 * time.Millisecond), message: "request"},
+		logLine{timestamp: start.Add(2 * time.Second), stderr: true, message: "error"},
+	)
+	now = now.Add(10 * time.Second)
+
+	events, err = f.Fetch()
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, 3, events[0]["restart_count"])
+
+	expectedBytes := int64(len("request\n") + len("error\n"))
+	assert.Equal(t, common.MapStr{
+		"bytes":         expectedBytes,
+		"bytes_per_sec": float64(expectedBytes) / 10,
+	}, events[0]["log"])
+
+	// No new lines.
+	now = now.Add(10 * time.Second)
+
+	events, err = f.Fetch()
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, common.MapStr{
+		"bytes":         int64(0),
+		"bytes_per_sec": float64(0),
+	}, events[0]["log"])
+}
+
+func TestStreamCounterSplitWrites(t *testing.T) {
+	since := time.Date(2018, 1, 1, 10, 0, 0, 0, time.UTC)
+	counter := newLogCounter(since)
+	stream := counter.stream()
+
+	data := "2018-01-01T10:00:00Z old\n" +
+		"2018-01-01T10:00:01.5Z first line\n" +
+		"2018-01-01T10:00:02Z second\n"
+
+	// Write one byte at a time, splitting timestamps and lines.
+	for i := 0; i < len(data); i++ {
+		n, err := stream.Write([]byte{data[i]})
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+	}
+
+	assert.Equal(t, int64(len("first line\n")+len("second\n")), counter.bytes)
+	assert.Equal(t, since.Add(2*time.Second), counter.last)
+}
@@ -0,0 +1,80 @@
+package activity
+
+import (
+	"bytes"
+	"time"
+)
+
+// logCounter counts the bytes of the log lines written after since. The log
+// lines are expected to be prefixed by their timestamp, as returned by the
+// Docker API when timestamps are requested.
+type logCounter struct {
+	since time.Time
+	// last is the timestamp of the last line, or since if no newer line has
+	// been read.
+	last  time.Time
+	bytes int64
+}
+
+func newLogCounter(since time.Time) *logCounter {
+	return &logCounter{since: since, last: since}
+}
+
+// stream returns a writer for one of the streams of the container. Each stream
+// must use its own writer, as lines of different streams are not written in
+// order.
+func (c *logCounter) stream() *streamCounter {
+	return &streamCounter{counter: c}
+}
+
+// streamCounter splits the written data in lines, and counts the bytes of the
+// lines newer than since. A line can be split across multiple writes.
+type streamCounter struct {
+	counter *logCounter
+
+	timestamp []byte
+	inLine    bool
+	counting  bool
+}
+
+func (s *streamCounter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		if !s.inLine {
+			i := bytes.IndexByte(p, ' ')
+			if i < 0 {
+				s.timestamp = append(s.timestamp, p...)
+				break
+			}
+			s.timestamp = append(s.timestamp, p[:i]...)
+			p = p[i+1:]
+			s.startLine()
+			continue
+		}
+
+		line := p
+		if i := bytes.IndexByte(p, '\n'); i >= 0 {
+			line = p[:i+1]
+			s.inLine = false
+		}
+		p = p[len(line):]
+
+		if s.counting {
+			s.counter.bytes += int64(len(line))
+		}
+	}
+	return n, nil
+}
+
+func (s *streamCounter) startLine() {
+	ts, err := time.Parse(time.RFC3339Nano, string(s.timestamp))
+	s.timestamp = s.timestamp[:0]
+	s.inLine = true
+
+	// The Docker API filters logs by seconds only, lines of the previous
+	// fetch are ignored by their timestamp.
+	s.counting = err == nil && ts.After(s.counter.since)
+	if s.counting && ts.After(s.counter.last) {
+		s.counter.last = ts
+	}
+}
@@ -0,0 +1,44 @@
+package member
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/metricbeat/module/etcd"
+)
+
+func eventsMapping(response map[string]interface{}) []common.MapStr {
+	clusterID := etcd.ID(etcd.Header(response), "cluster_id")
+	members, _ := response["members"].([]interface{})
+
+	events := make([]common.MapStr, 0, len(members))
+	for _, m := range members {
+		member, ok := m.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		// Members added but not started yet have no name and client URLs.
+		name, _ := member["name"].(string)
+		isLearner, _ := member["isLearner"].(bool)
+		events = append(events, common.MapStr{
+			"id":          etcd.ID(member, "ID"),
+			"name":        name,
+			"cluster_id":  clusterID,
+			"peer_urls":   urls(member["peerURLs"]),
+			"client_urls": urls(member["clientURLs"]),
+			"is_learner":  isLearner,
+			"started":     name != "",
+		})
+	}
+	return events
+}
+
+func urls(v interface{}) []string {
+	list, _ := v.([]interface{})
+	urls := make([]string, 0, len(list))
+	for _, u := range list {
+		if s, ok := u.(string); ok {
+			urls = append(urls, s)
+		}
+	}
+	return urls
+}
@@ -0,0 +1,4 @@
+/*
+Package member reports the members of an etcd cluster from the v3 API.
+*/
+package member
@@ -0,0 +1,42 @@
+package member
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/metricbeat/mb"
+	"github.com/elastic/beats/metricbeat/module/etcd"
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("etcd", "member", New, etcd.HostParser); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet reports the members of the etcd cluster from the cluster API.
+type MetricSet struct {
+	mb.BaseMetricSet
+	client *etcd.V3Client
+}
+
+// New creates a new instance of the member MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	cfgwarn.Experimental("The etcd member metricset is experimental")
+
+	client, err := etcd.NewV3Client(base, "cluster/member/list")
+	if err != nil {
+		return nil, err
+	}
+	return &MetricSet{BaseMetricSet: base, client: client}, nil
+}
+
+// Fetch reports an event per member of the cluster.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	response, err := m.client.Call()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the etcd members")
+	}
+	return eventsMapping(response), nil
+}
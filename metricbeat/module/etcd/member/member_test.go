@@ -0,0 +1,89 @@
+// +build !integration
+
+package member
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+)
+
+// newFakeEtcd returns a server serving the member list fixture like the v3
+// API of etcd.
+func newFakeEtcd(t *testing.T) *httptest.Server {
+	response, err := ioutil.ReadFile("./_meta/test/members.json")
+	require.NoError(t, err)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/v3/cluster/member/list" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(response)
+	}))
+}
+
+func getConfig(host string) map[string]interface{} {
+	return map[string]interface{}{
+		"module":     "etcd",
+		"metricsets": []string{"member"},
+		"hosts":      []string{host},
+	}
+}
+
+func TestData(t *testing.T) {
+	server := newFakeEtcd(t)
+	defer server.Close()
+
+	f := mbtest.NewEventsFetcher(t, getConfig(server.URL))
+	if err := mbtest.WriteEvents(f, t); err != nil {
+		t.Fatal("write", err)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	server := newFakeEtcd(t)
+	defer server.Close()
+
+	f := mbtest.NewEventsFetcher(t, getConfig(server.URL))
+	events, err := f.Fetch()
+	require.NoError(t, err)
+
+	assert.Equal(t, []common.MapStr{
+		{
+			"id":          "8e9e05c52164694d",
+			"name":        "etcd-1",
+			"cluster_id":  "cdf818194e3a8c32",
+			"peer_urls":   []string{"https://10.0.0.1:2380"},
+			"client_urls": []string{"https://10.0.0.1:2379"},
+			"is_learner":  false,
+			"started":     true,
+		},
+		{
+			"id":          "deadbeef",
+			"name":        "etcd-2",
+			"cluster_id":  "cdf818194e3a8c32",
+			"peer_urls":   []string{"https://10.0.0.2:2380"},
+			"client_urls": []string{"https://10.0.0.2:2379"},
+			"is_learner":  true,
+			"started":     true,
+		},
+		{
+			"id":          "beef",
+			"name":        "",
+			"cluster_id":  "cdf818194e3a8c32",
+			"peer_urls":   []string{"https://10.0.0.3:2380"},
+			"client_urls": []string{},
+			"is_learner":  false,
+			"started":     false,
+		},
+	}, events)
+}
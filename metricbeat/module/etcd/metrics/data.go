@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// counters are the counter and gauge metrics reported, by the field they are
+// reported in. The values of all series of a metric are summed up.
+var counters = map[string]string{
+	"etcd_server_leader_changes_seen_total":         "server.leader_changes.count",
+	"etcd_server_proposals_committed_total":         "server.proposals.committed",
+	"etcd_server_proposals_applied_total":           "server.proposals.applied",
+	"etcd_server_proposals_pending":                 "server.proposals.pending",
+	"etcd_server_proposals_failed_total":            "server.proposals.failed",
+	"etcd_mvcc_db_total_size_in_bytes":              "mvcc.db_total_size.bytes",
+	"etcd_network_client_grpc_received_bytes_total": "network.client_grpc.received.bytes",
+	"etcd_network_client_grpc_sent_bytes_total":     "network.client_grpc.sent.bytes",
+	"etcd_network_peer_received_bytes_total":        "network.peer.received.bytes",
+	"etcd_network_peer_sent_bytes_total":            "network.peer.sent.bytes",
+}
+
+// fallbacks are the names of metrics in older etcd versions, used if the
+// current metric is missing.
+var fallbacks = map[string]string{
+	"etcd_debugging_mvcc_db_total_size_in_bytes": "etcd_mvcc_db_total_size_in_bytes",
+}
+
+// flags are the gauge metrics reported as booleans.
+var flags = map[string]string{
+	"etcd_server_has_leader": "server.has_leader",
+	"etcd_server_is_leader":  "server.is_leader",
+}
+
+// histograms are the histogram metrics reported with their count and sum.
+var histograms = map[string]string{
+	"etcd_disk_wal_fsync_duration_seconds":      "disk.wal_fsync_duration",
+	"etcd_disk_backend_commit_duration_seconds": "disk.backend_commit_duration",
+}
+
+func eventMapping(families []*dto.MetricFamily) common.MapStr {
+	byName := make(map[string]*dto.MetricFamily, len(families))
+	for _, family := range families {
+		byName[family.GetName()] = family
+	}
+	for old, current := range fallbacks {
+		if _, found := byName[current]; !found && byName[old] != nil {
+			byName[current] = byName[old]
+		}
+	}
+
+	event := common.MapStr{}
+	for name, field := range counters {
+		if family, found := byName[name]; found {
+			event.Put(field, int64(sum(family)))
+		}
+	}
+	for name, field := range flags {
+		if family, found := byName[name]; found {
+			event.Put(field, sum(family) > 0)
+		}
+	}
+	for name, field := range histograms {
+		family, found := byName[name]
+		if !found {
+			continue
+		}
+		var count uint64
+		var total float64
+		for _, m := range family.GetMetric() {
+			count += m.GetHistogram().GetSampleCount()
+			total += m.GetHistogram().GetSampleSum()
+		}
+		event.Put(field+".count", int64(count))
+		event.Put(field+".sum.sec", total)
+	}
+	return event
+}
+
+// sum returns the sum of the values of all series of a counter or gauge.
+func sum(family *dto.MetricFamily) float64 {
+	var total float64
+	for _, m := range family.GetMetric() {
+		switch {
+		case m.Counter != nil:
+			total += m.GetCounter().GetValue()
+		case m.Gauge != nil:
+			total += m.GetGauge().GetValue()
+		case m.Untyped != nil:
+			total += m.GetUntyped().GetValue()
+		}
+	}
+	return total
+}
@@ -0,0 +1,5 @@
+/*
+Package metrics reports the metrics of an etcd member from its Prometheus
+endpoint.
+*/
+package metrics
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/metricbeat/helper"
+	"github.com/elastic/beats/metricbeat/mb"
+	"github.com/elastic/beats/metricbeat/mb/parse"
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("etcd", "metrics", New, hostParser); err != nil {
+		panic(err)
+	}
+}
+
+var hostParser = parse.URLHostParserBuilder{
+	DefaultScheme: "http",
+	PathConfigKey: "metrics_path",
+	DefaultPath:   "metrics",
+}.Build()
+
+// MetricSet reports the server, disk and network metrics of an etcd member
+// from its Prometheus endpoint.
+type MetricSet struct {
+	mb.BaseMetricSet
+	prometheus *helper.Prometheus
+}
+
+// New creates a new instance of the metrics MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	cfgwarn.Experimental("The etcd metrics metricset is experimental")
+
+	http := helper.NewHTTP(base)
+	if http == nil {
+		return nil, errors.New("failed to create the HTTP client, check the ssl settings")
+	}
+	return &MetricSet{
+		BaseMetricSet: base,
+		prometheus:    &helper.Prometheus{HTTP: *http},
+	}, nil
+}
+
+// Fetch reports the metrics of the member.
+func (m *MetricSet) Fetch() (common.MapStr, error) {
+	families, err := m.prometheus.GetFamilies()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the etcd metrics")
+	}
+	return eventMapping(families), nil
+}
@@ -0,0 +1,114 @@
+// +build !integration
+
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+)
+
+// newFakeEtcd returns a server serving the metrics fixture like the
+// Prometheus endpoint of etcd.
+func newFakeEtcd(t *testing.T, fixture string) *httptest.Server {
+	response, err := ioutil.ReadFile(fixture)
+	require.NoError(t, err)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metrics" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write(response)
+	}))
+}
+
+func getConfig(host string) map[string]interface{} {
+	return map[string]interface{}{
+		"module":     "etcd",
+		"metricsets": []string{"metrics"},
+		"hosts":      []string{host},
+	}
+}
+
+func TestData(t *testing.T) {
+	server := newFakeEtcd(t, "./_meta/test/metrics")
+	defer server.Close()
+
+	f := mbtest.NewEventFetcher(t, getConfig(server.URL))
+	if err := mbtest.WriteEvent(f, t); err != nil {
+		t.Fatal("write", err)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	server := newFakeEtcd(t, "./_meta/test/metrics")
+	defer server.Close()
+
+	f := mbtest.NewEventFetcher(t, getConfig(server.URL))
+	event, err := f.Fetch()
+	require.NoError(t, err)
+
+	assert.Equal(t, common.MapStr{
+		"server": common.MapStr{
+			"has_leader":     true,
+			"is_leader":      false,
+			"leader_changes": common.MapStr{"count": int64(2)},
+			"proposals": common.MapStr{
+				"committed": int64(57),
+				"applied":   int64(56),
+				"pending":   int64(0),
+				"failed":    int64(1),
+			},
+		},
+		"mvcc": common.MapStr{
+			"db_total_size": common.MapStr{"bytes": int64(24576)},
+		},
+		"disk": common.MapStr{
+			"wal_fsync_duration": common.MapStr{
+				"count": int64(12),
+				"sum":   common.MapStr{"sec": 0.025},
+			},
+			"backend_commit_duration": common.MapStr{
+				"count": int64(5),
+				"sum":   common.MapStr{"sec": 0.0075},
+			},
+		},
+		"network": common.MapStr{
+			"client_grpc": common.MapStr{
+				"received": common.MapStr{"bytes": int64(1024)},
+				"sent":     common.MapStr{"bytes": int64(2048)},
+			},
+			"peer": common.MapStr{
+				"received": common.MapStr{"bytes": int64(300)},
+				"sent":     common.MapStr{"bytes": int64(300)},
+			},
+		},
+	}, event)
+}
+
+func TestFetchOldMetricNames(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write([]byte("# TYPE etcd_debugging_mvcc_db_total_size_in_bytes gauge\n" +
+			"etcd_debugging_mvcc_db_total_size_in_bytes 16384\n"))
+	}))
+	defer server.Close()
+
+	f := mbtest.NewEventFetcher(t, getConfig(server.URL))
+	event, err := f.Fetch()
+	require.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"mvcc": common.MapStr{
+			"db_total_size": common.MapStr{"bytes": int64(16384)},
+		},
+	}, event)
+}
@@ -0,0 +1,47 @@
+package status
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/metricbeat/module/etcd"
+)
+
+func eventMapping(response map[string]interface{}) common.MapStr {
+	header := etcd.Header(response)
+	memberID := etcd.ID(header, "member_id")
+	leader := etcd.ID(response, "leader")
+	isLearner, _ := response["isLearner"].(bool)
+
+	event := common.MapStr{
+		"cluster_id": etcd.ID(header, "cluster_id"),
+		"member_id":  memberID,
+		"leader":     leader,
+		"is_leader":  leader != "" && leader == memberID,
+		"is_learner": isLearner,
+		"revision":   etcd.Int(header, "revision"),
+		"db_size": common.MapStr{
+			"bytes": etcd.Int(response, "dbSize"),
+		},
+		"raft": common.MapStr{
+			"index":         etcd.Int(response, "raftIndex"),
+			"term":          etcd.Int(response, "raftTerm"),
+			"applied_index": etcd.Int(response, "raftAppliedIndex"),
+		},
+	}
+	if version, ok := response["version"].(string); ok {
+		event["version"] = version
+	}
+	// The size in use is reported since etcd 3.4.
+	if _, found := response["dbSizeInUse"]; found {
+		event.Put("db_size_in_use.bytes", etcd.Int(response, "dbSizeInUse"))
+	}
+	if errs, ok := response["errors"].([]interface{}); ok && len(errs) > 0 {
+		messages := make([]string, 0, len(errs))
+		for _, e := range errs {
+			if s, ok := e.(string); ok {
+				messages = append(messages, s)
+			}
+		}
+		event["errors"] = messages
+	}
+	return event
+}
@@ -0,0 +1,4 @@
+/*
+Package status reports the status of an etcd member from the v3 API.
+*/
+package status
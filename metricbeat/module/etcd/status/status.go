@@ -0,0 +1,42 @@
+package status
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/metricbeat/mb"
+	"github.com/elastic/beats/metricbeat/module/etcd"
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("etcd", "status", New, etcd.HostParser); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet reports the status of an etcd member from the maintenance API.
+type MetricSet struct {
+	mb.BaseMetricSet
+	client *etcd.V3Client
+}
+
+// New creates a new instance of the status MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	cfgwarn.Experimental("The etcd status metricset is experimental")
+
+	client, err := etcd.NewV3Client(base, "maintenance/status")
+	if err != nil {
+		return nil, err
+	}
+	return &MetricSet{BaseMetricSet: base, client: client}, nil
+}
+
+// Fetch reports the version, database size and raft state of the member.
+func (m *MetricSet) Fetch() (common.MapStr, error) {
+	response, err := m.client.Call()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the etcd status")
+	}
+	return eventMapping(response), nil
+}
@@ -0,0 +1,157 @@
+// +build !integration
+
+package status
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/outputs/transport/transptest"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+)
+
+// newFakeEtcd returns a handler serving the status fixture like the v3 API
+// of etcd under prefix.
+func newFakeEtcd(t *testing.T, prefix string) http.Handler {
+	response, err := ioutil.ReadFile("./_meta/test/status.json")
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/"+prefix+"/maintenance/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(response)
+	})
+	return mux
+}
+
+func getConfig(host string) map[string]interface{} {
+	return map[string]interface{}{
+		"module":     "etcd",
+		"metricsets": []string{"status"},
+		"hosts":      []string{host},
+	}
+}
+
+func TestData(t *testing.T) {
+	server := httptest.NewServer(newFakeEtcd(t, "v3"))
+	defer server.Close()
+
+	f := mbtest.NewEventFetcher(t, getConfig(server.URL))
+	if err := mbtest.WriteEvent(f, t); err != nil {
+		t.Fatal("write", err)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(newFakeEtcd(t, "v3"))
+	defer server.Close()
+
+	f := mbtest.NewEventFetcher(t, getConfig(server.URL))
+	event, err := f.Fetch()
+	require.NoError(t, err)
+
+	assert.Equal(t, common.MapStr{
+		"version":    "3.4.3",
+		"cluster_id": "cdf818194e3a8c32",
+		"member_id":  "8e9e05c52164694d",
+		"leader":     "8e9e05c52164694d",
+		"is_leader":  true,
+		"is_learner": false,
+		"revision":   int64(42),
+		"db_size":    common.MapStr{"bytes": int64(24576)},
+		"db_size_in_use": common.MapStr{
+			"bytes": int64(20480),
+		},
+		"raft": common.MapStr{
+			"index":         int64(57),
+			"term":          int64(3),
+			"applied_index": int64(56),
+		},
+	}, event)
+}
+
+func TestFetchAPIPrefix(t *testing.T) {
+	server := httptest.NewServer(newFakeEtcd(t, "v3beta"))
+	defer server.Close()
+
+	config := getConfig(server.URL)
+	f := mbtest.NewEventFetcher(t, config)
+	_, err := f.Fetch()
+	assert.Error(t, err)
+
+	config["api_prefix"] = "v3beta"
+	f = mbtest.NewEventFetcher(t, config)
+	event, err := f.Fetch()
+	require.NoError(t, err)
+	assert.Equal(t, "3.4.3", event["version"])
+}
+
+func TestEventMappingFollower(t *testing.T) {
+	event := eventMapping(map[string]interface{}{
+		"header":    map[string]interface{}{"member_id": "3735928559"},
+		"leader":    "10276657743932975437",
+		"isLearner": true,
+		"errors":    []interface{}{"NOSPACE"},
+	})
+
+	assert.Equal(t, false, event["is_leader"])
+	assert.Equal(t, true, event["is_learner"])
+	assert.Equal(t, []string{"NOSPACE"}, event["errors"])
+	assert.NotContains(t, event, "db_size_in_use")
+}
+
+func TestFetchMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "etcd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// The generated certificate is used as CA, server and client certificate.
+	cert := filepath.Join(dir, "ca_test")
+	require.NoError(t, transptest.GenCertsForIPIfMIssing(t, net.IPv4(127, 0, 0, 1), cert))
+
+	keyPair, err := tls.LoadX509KeyPair(cert+".pem", cert+".key")
+	require.NoError(t, err)
+	pem, err := ioutil.ReadFile(cert + ".pem")
+	require.NoError(t, err)
+	clientCAs := x509.NewCertPool()
+	require.True(t, clientCAs.AppendCertsFromPEM(pem))
+
+	server := httptest.NewUnstartedServer(newFakeEtcd(t, "v3"))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{keyPair},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	config := getConfig(server.URL)
+	config["ssl.certificate_authorities"] = []string{cert + ".pem"}
+
+	// Requests without client certificate are rejected.
+	f := mbtest.NewEventFetcher(t, config)
+	_, err = f.Fetch()
+	assert.Error(t, err)
+
+	config["ssl.certificate"] = cert + ".pem"
+	config["ssl.key"] = cert + ".key"
+	f = mbtest.NewEventFetcher(t, config)
+	event, err := f.Fetch()
+	require.NoError(t, err)
+	assert.Equal(t, "8e9e05c52164694d", event["member_id"])
+}
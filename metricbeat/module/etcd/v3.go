@@ -0,0 +1,97 @@
+package etcd
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/metricbeat/helper"
+	"github.com/elastic/beats/metricbeat/mb"
+	"github.com/elastic/beats/metricbeat/mb/parse"
+)
+
+// HostParser parses the hosts of the metricsets using the v3 API. The API is
+// served by the JSON gateway of the gRPC API of etcd, under the `api_prefix`
+// path. etcd 3.3 and older serve it under `v3beta` or `v3alpha` instead of
+// `v3`.
+var HostParser = parse.URLHostParserBuilder{
+	DefaultScheme: "http",
+	PathConfigKey: "api_prefix",
+	DefaultPath:   "v3",
+}.Build()
+
+// V3Client calls a method of the v3 API of etcd.
+type V3Client struct {
+	http *helper.HTTP
+}
+
+// NewV3Client creates a client calling the method of the v3 API at path, like
+// `maintenance/status`. The TLS settings of the module are used for the
+// requests, so client certificates can be configured with `ssl.certificate`
+// and `ssl.key`.
+func NewV3Client(base mb.BaseMetricSet, path string) (*V3Client, error) {
+	http := helper.NewHTTP(base)
+	if http == nil {
+		return nil, errors.New("failed to create the HTTP client, check the ssl settings")
+	}
+
+	// The methods are called with POST and a JSON encoded request, all
+	// methods used have an empty request.
+	http.SetMethod("POST")
+	http.SetHeader("Content-Type", "application/json")
+	http.SetBody([]byte("{}"))
+	http.SetURI(strings.TrimSuffix(base.HostData().SanitizedURI, "/") + "/" + path)
+	return &V3Client{http: http}, nil
+}
+
+// Call calls the method and decodes its JSON encoded response.
+func (c *V3Client) Call() (map[string]interface{}, error) {
+	content, err := c.http.FetchContent()
+	if err != nil {
+		return nil, err
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(content, &response); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the etcd response")
+	}
+	return response, nil
+}
+
+// Int returns the integer value of a field of a response. The gateway encodes
+// 64 bit integers as strings, fields with the default value are omitted.
+func Int(response map[string]interface{}, key string) int64 {
+	switch v := response[key].(type) {
+	case string:
+		i, _ := strconv.ParseInt(v, 10, 64)
+		return i
+	case float64:
+		return int64(v)
+	default:
+		return 0
+	}
+}
+
+// ID returns the hexadecimal representation of an id of a response, like the
+// member ids shown by etcdctl. Ids are unsigned 64 bit integers, encoded as
+// strings by the gateway. It returns an empty string if the id is not set.
+func ID(response map[string]interface{}, key string) string {
+	s, ok := response[key].(string)
+	if !ok {
+		return ""
+	}
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return ""
+	}
+	return strconv.FormatUint(id, 16)
+}
+
+// Header returns the response header, with the ids of the cluster and of the
+// member that responded.
+func Header(response map[string]interface{}) map[string]interface{} {
+	header, _ := response["header"].(map[string]interface{})
+	return header
+}
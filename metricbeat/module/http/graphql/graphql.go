@@ -0,0 +1,232 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/metricbeat/helper"
+	"github.com/elastic/beats/metricbeat/mb"
+	"github.com/elastic/beats/metricbeat/mb/parse"
+)
+
+// init registers the MetricSet with the central registry.
+// The New method will be called after the setup of the module and before starting to fetch data
+func init() {
+	if err := mb.Registry.AddMetricSet("http", "graphql", New, hostParser); err != nil {
+		panic(err)
+	}
+}
+
+const (
+	// defaultScheme is the default scheme to use when it is not specified in the host config.
+	defaultScheme = "http"
+
+	// defaultPath is the path to use when it is not specified in the host config.
+	defaultPath = "/graphql"
+)
+
+var (
+	hostParser = parse.URLHostParserBuilder{
+		DefaultScheme: defaultScheme,
+		PathConfigKey: "path",
+		DefaultPath:   defaultPath,
+	}.Build()
+)
+
+// MetricSet polls a GraphQL endpoint with a query, adding the response data
+// or the fields mapped from it to the configured namespace.
+type MetricSet struct {
+	mb.BaseMetricSet
+	namespace string
+	http      *helper.HTTP
+	mapping   map[string]string
+}
+
+// request is the body of a GraphQL request sent as JSON via POST.
+type request struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+// response is the body of a GraphQL response.
+type response struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []responseError        `json:"errors"`
+}
+
+type responseError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path"`
+}
+
+// New create a new instance of the MetricSet
+// Part of new is also setting up the configuration by processing additional
+// configuration entries if needed.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	cfgwarn.Beta("The http graphql metricset is in beta.")
+
+	config := struct {
+		Namespace string                 `config:"namespace" validate:"required"`
+		Query     string                 `config:"query" validate:"required"`
+		Variables map[string]interface{} `config:"variables"`
+		Mapping   map[string]interface{} `config:"mapping"`
+	}{}
+
+	if err := base.Module().UnpackConfig(&config); err != nil {
+		return nil, err
+	}
+
+	mapping, err := flattenMapping(config.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(request{
+		Query:     config.Query,
+		Variables: config.Variables,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding graphql request: %v", err)
+	}
+
+	http := helper.NewHTTP(base)
+	if http == nil {
+		return nil, fmt.Errorf("error creating http client for %v", base.HostData().SanitizedURI)
+	}
+	http.SetMethod("POST")
+	http.SetHeader("Content-Type", "application/json")
+	http.SetHeader("Accept", "application/json")
+	http.SetBody(body)
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		namespace:     config.Namespace,
+		http:          http,
+		mapping:       mapping,
+	}, nil
+}
+
+// Fetch sends the query and returns the data of the response. Errors returned
+// by the GraphQL endpoint in the `errors` array are reported as error.
+func (m *MetricSet) Fetch() (common.MapStr, error) {
+	resp, err := m.http.FetchResponse()
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// GraphQL endpoints might report errors with a status code other than
+	// 200, so the body is checked for errors first.
+	var result response
+	if err := json.Unmarshal(body, &result); err != nil {
+		if resp.StatusCode != 200 {
+			return nil, fmt.Errorf("HTTP error %d in %s: %s", resp.StatusCode, m.Name(), resp.Status)
+		}
+		return nil, fmt.Errorf("error decoding graphql response: %v", err)
+	}
+
+	if len(result.Errors) > 0 {
+		return nil, queryError(result.Errors)
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("HTTP error %d in %s: %s", resp.StatusCode, m.Name(), resp.Status)
+	}
+
+	event, err := m.mapFields(result.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set dynamic namespace
+	event["_namespace"] = m.namespace
+
+	return event, nil
+}
+
+// mapFields returns the fields mapped from the response data. The complete
+// data is returned if no mapping is configured.
+func (m *MetricSet) mapFields(data map[string]interface{}) (common.MapStr, error) {
+	if len(m.mapping) == 0 {
+		if data == nil {
+			return common.MapStr{}, nil
+		}
+		return common.MapStr(data), nil
+	}
+
+	event := common.MapStr{}
+	for field, path := range m.mapping {
+		value, err := lookup(data, path)
+		if err != nil {
+			return nil, fmt.Errorf("error mapping field %v: %v", field, err)
+		}
+		if value != nil {
+			event.Put(field, value)
+		}
+	}
+	return event, nil
+}
+
+// lookup returns the value at the dot separated path in the response data.
+// Numeric path segments select elements of lists.
+func lookup(data map[string]interface{}, path string) (interface{}, error) {
+	var current interface{} = data
+	for _, key := range strings.Split(path, ".") {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			value, found := v[key]
+			if !found {
+				return nil, fmt.Errorf("key '%v' of path '%v' not found", key, path)
+			}
+			current = value
+		case []interface{}:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return nil, fmt.Errorf("invalid index '%v' of path '%v'", key, path)
+			}
+			current = v[idx]
+		default:
+			return nil, fmt.Errorf("key '%v' of path '%v' not found", key, path)
+		}
+	}
+	return current, nil
+}
+
+// flattenMapping returns the paths of the mapped fields by the full field name,
+// as dotted field names are unpacked into nested objects.
+func flattenMapping(mapping map[string]interface{}) (map[string]string, error) {
+	paths := map[string]string{}
+	for field, path := range common.MapStr(mapping).Flatten() {
+		p, ok := path.(string)
+		if !ok || p == "" {
+			return nil, fmt.Errorf("mapping of field '%v' must be a path", field)
+		}
+		paths[field] = p
+	}
+	return paths, nil
+}
+
+func queryError(errs []responseError) error {
+	messages := make([]string, 0, len(errs))
+	for _, e := range errs {
+		msg := e.Message
+		if len(e.Path) > 0 {
+			path := make([]string, len(e.Path))
+			for i, p := range e.Path {
+				path[i] = fmt.Sprint(p)
+			}
+			msg = fmt.Sprintf("%v (path: %v)", msg, strings.Join(path, "."))
+		}
+		messages = append(messages, msg)
+	}
+	return fmt.Errorf("graphql query failed: %v", strings.Join(messages, "; "))
+}
@@ -0,0 +1,158 @@
+// +build !integration
+
+package graphql
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+)
+
+// fakeServer returns a GraphQL server responding with the given body. The
+// requests received are passed to the handler.
+func fakeServer(t *testing.T, status int, body string, handler func(*http.Request, request)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		var req request
+		require.NoError(t, json.Unmarshal(data, &req))
+		if handler != nil {
+			handler(r, req)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+}
+
+func getConfig(host string) map[string]interface{} {
+	return map[string]interface{}{
+		"module":     "http",
+		"metricsets": []string{"graphql"},
+		"hosts":      []string{host},
+		"path":       "/graphql",
+		"namespace":  "test",
+		"query":      "query Stats($name: String!) { repository(name: $name) { stars issues { total } } }",
+		"variables": map[string]interface{}{
+			"name": "beats",
+		},
+	}
+}
+
+func TestFetch(t *testing.T) {
+	var (
+		received request
+		path     string
+		method   string
+		user     string
+		password string
+	)
+	server := fakeServer(t, 200, `{"data": {"repository": {"stars": 42, "issues": {"total": 7}}}}`,
+		func(r *http.Request, req request) {
+			received, path, method = req, r.URL.Path, r.Method
+			user, password, _ = r.BasicAuth()
+		})
+	defer server.Close()
+
+	config := getConfig(server.URL)
+	config["username"] = "elastic"
+	config["password"] = "changeme"
+
+	f := mbtest.NewEventFetcher(t, config)
+	event, err := f.Fetch()
+	require.NoError(t, err)
+
+	assert.Equal(t, common.MapStr{
+		"repository": map[string]interface{}{
+			"stars":  float64(42),
+			"issues": map[string]interface{}{"total": float64(7)},
+		},
+		"_namespace": "test",
+	}, event)
+
+	assert.Equal(t, "POST", method)
+	assert.Equal(t, "/graphql", path)
+	assert.Equal(t, "elastic", user)
+	assert.Equal(t, "changeme", password)
+	assert.Contains(t, received.Query, "repository(name: $name)")
+	assert.Equal(t, map[string]interface{}{"name": "beats"}, received.Variables)
+}
+
+func TestFetchMapping(t *testing.T) {
+	server := fakeServer(t, 200, `{"data": {"repository": {"stars": 42, "issues": {"total": 7}, "releases": [{"name": "6.0.0"}], "license": null}}}`, nil)
+	defer server.Close()
+
+	config := getConfig(server.URL)
+	config["mapping"] = map[string]interface{}{
+		"stars":          "repository.stars",
+		"issues.open":    "repository.issues.total",
+		"latest_release": "repository.releases.0.name",
+		"license":        "repository.license",
+	}
+
+	f := mbtest.NewEventFetcher(t, config)
+	event, err := f.Fetch()
+	require.NoError(t, err)
+
+	assert.Equal(t, common.MapStr{
+		"stars":          float64(42),
+		"issues":         common.MapStr{"open": float64(7)},
+		"latest_release": "6.0.0",
+		"_namespace":     "test",
+	}, event)
+}
+
+func TestFetchMappingMissing(t *testing.T) {
+	server := fakeServer(t, 200, `{"data": {"repository": {"stars": 42}}}`, nil)
+	defer server.Close()
+
+	config := getConfig(server.URL)
+	config["mapping"] = map[string]interface{}{
+		"forks": "repository.forks",
+	}
+
+	f := mbtest.NewEventFetcher(t, config)
+	_, err := f.Fetch()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "repository.forks")
+	}
+}
+
+func TestFetchErrors(t *testing.T) {
+	server := fakeServer(t, 200, `{
+		"data": {"repository": null},
+		"errors": [
+			{"message": "Could not resolve to a Repository with the name 'beats'.", "path": ["repository"]},
+			{"message": "Rate limit exceeded"}
+		]
+	}`, nil)
+	defer server.Close()
+
+	f := mbtest.NewEventFetcher(t, getConfig(server.URL))
+	event, err := f.Fetch()
+	assert.Nil(t, event)
+	if assert.Error(t, err) {
+		assert.Equal(t, "graphql query failed: Could not resolve to a Repository with the name 'beats'. (path: repository); Rate limit exceeded", err.Error())
+	}
+}
+
+func TestFetchHTTPError(t *testing.T) {
+	server := fakeServer(t, 500, `Internal Server Error`, nil)
+	defer server.Close()
+
+	f := mbtest.NewEventFetcher(t, getConfig(server.URL))
+	_, err := f.Fetch()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "HTTP error 500")
+	}
+}
@@ -5,6 +5,10 @@ import "encoding/json"
 type JMXMapping struct {
 	MBean      string
 	Attributes []Attribute
+
+	// Namespace overrides the module namespace for the metrics of this mapping.
+	// All mappings sharing a namespace are reported in one event.
+	Namespace string
 }
 
 type Attribute struct {
@@ -37,21 +41,42 @@ type RequestBlock struct {
 	Attribute []string `json:"attribute"`
 }
 
-func buildRequestBodyAndMapping(mappings []JMXMapping) ([]byte, map[string]string, error) {
-	responseMapping := map[string]string{}
+// blockMapping maps the response of a single request block back to the
+// namespace and event fields configured for the mapping. Jolokia answers bulk
+// requests in request order, so the n-th response entry belongs to the n-th
+// block mapping.
+type blockMapping struct {
+	mbean     string
+	namespace string
+	fields    map[string]string // attribute -> event field
+}
+
+// buildRequestBodyAndMapping creates a single bulk request for all mappings.
+// Mappings without a namespace use the given default namespace.
+func buildRequestBodyAndMapping(mappings []JMXMapping, namespace string) ([]byte, []blockMapping, error) {
 	var blocks []RequestBlock
+	var responseMapping []blockMapping
 
 	for _, mapping := range mappings {
 		rb := RequestBlock{
 			Type:  "read",
 			MBean: mapping.MBean,
 		}
+		bm := blockMapping{
+			mbean:     mapping.MBean,
+			namespace: namespace,
+			fields:    map[string]string{},
+		}
+		if mapping.Namespace != "" {
+			bm.namespace = mapping.Namespace
+		}
 
 		for _, attribute := range mapping.Attributes {
 			rb.Attribute = append(rb.Attribute, attribute.Attr)
-			responseMapping[mapping.MBean+"_"+attribute.Attr] = attribute.Field
+			bm.fields[attribute.Attr] = attribute.Field
 		}
 		blocks = append(blocks, rb)
+		responseMapping = append(responseMapping, bm)
 	}
 
 	content, err := json.Marshal(blocks)
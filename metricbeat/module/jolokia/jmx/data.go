@@ -13,7 +13,17 @@ type Entry struct {
 	Request struct {
 		Mbean string `json:"mbean"`
 	}
-	Value map[string]interface{}
+	Value  map[string]interface{}
+	Status int
+	Error  string
+}
+
+// namespaceEvent collects the metrics and errors of all mappings sharing a
+// namespace.
+type namespaceEvent struct {
+	namespace string
+	event     common.MapStr
+	errs      multierror.Errors
 }
 
 // Map responseBody to common.MapStr
@@ -47,41 +57,69 @@ type Entry struct {
 //        "status": 200
 //     }
 //  ]
-func eventMapping(content []byte, mapping map[string]string) (common.MapStr, error) {
+//
+// Failed reads are reported with an error status and message instead of a
+// value:
+//  {
+//      "request": {...},
+//      "error_type": "javax.management.InstanceNotFoundException",
+//      "error": "javax.management.InstanceNotFoundException : java.lang:type=Unknown",
+//      "status": 404
+//  }
+//
+// The entries are split into one event per namespace. Errors are reported with
+// the namespace of the failed request.
+func eventMapping(content []byte, mapping []blockMapping) ([]*namespaceEvent, error) {
 	var entries []Entry
 	if err := json.Unmarshal(content, &entries); err != nil {
 		return nil, errors.Wrapf(err, "failed to unmarshal jolokia JSON response '%v'", string(content))
 	}
 
-	event := common.MapStr{}
-	var errs multierror.Errors
+	if len(entries) != len(mapping) {
+		return nil, errors.Errorf("expected %v entries in jolokia response, but got %v",
+			len(mapping), len(entries))
+	}
+
+	var events []*namespaceEvent
+	byNamespace := map[string]*namespaceEvent{}
+
+	for i, v := range entries {
+		m := mapping[i]
+
+		ns, exists := byNamespace[m.namespace]
+		if !exists {
+			ns = &namespaceEvent{namespace: m.namespace, event: common.MapStr{}}
+			byNamespace[m.namespace] = ns
+			events = append(events, ns)
+		}
+
+		if v.Status != 0 && v.Status != 200 {
+			ns.errs = append(ns.errs, errors.Errorf("failed to read mbean '%v' (status %v): %v",
+				m.mbean, v.Status, v.Error))
+			continue
+		}
 
-	for _, v := range entries {
 		for attribute, value := range v.Value {
 			// Extend existing event
-			err := parseResponseEntry(v.Request.Mbean, attribute, value, event, mapping)
+			err := parseResponseEntry(attribute, value, ns.event, m)
 			if err != nil {
-				errs = append(errs, err)
+				ns.errs = append(ns.errs, err)
 			}
 		}
 	}
 
-	return event, errs.Err()
+	return events, nil
 }
 
 func parseResponseEntry(
-	mbeanName string,
 	attributeName string,
 	attibuteValue interface{},
 	event common.MapStr,
-	mapping map[string]string,
+	mapping blockMapping,
 ) error {
-	// Create metric name by merging mbean and attribute fields.
-	var metricName = mbeanName + "_" + attributeName
-
-	key, exists := mapping[metricName]
+	key, exists := mapping.fields[attributeName]
 	if !exists {
-		return errors.Errorf("metric key '%v' not found in response", key)
+		return errors.Errorf("metric key '%v_%v' not found in mapping", mapping.mbean, attributeName)
 	}
 
 	_, err := event.Put(key, attibuteValue)
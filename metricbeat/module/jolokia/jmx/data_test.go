@@ -20,17 +20,38 @@ func TestEventMapper(t *testing.T) {
 
 	assert.Nil(t, err)
 
-	var mapping = map[string]string{
-		"java.lang:type=Runtime_Uptime":                                            "uptime",
-		"java.lang:type=GarbageCollector,name=ConcurrentMarkSweep_CollectionTime":  "gc.cms_collection_time",
-		"java.lang:type=GarbageCollector,name=ConcurrentMarkSweep_CollectionCount": "gc.cms_collection_count",
-		"java.lang:type=Memory_HeapMemoryUsage":                                    "memory.heap_usage",
-		"java.lang:type=Memory_NonHeapMemoryUsage":                                 "memory.non_heap_usage",
+	var mapping = []blockMapping{
+		{
+			mbean:     "java.lang:type=Runtime",
+			namespace: "test",
+			fields:    map[string]string{"Uptime": "uptime"},
+		},
+		{
+			mbean:     "java.lang:type=GarbageCollector,name=ConcurrentMarkSweep",
+			namespace: "test",
+			fields: map[string]string{
+				"CollectionTime":  "gc.cms_collection_time",
+				"CollectionCount": "gc.cms_collection_count",
+			},
+		},
+		{
+			mbean:     "java.lang:type=Memory",
+			namespace: "test",
+			fields: map[string]string{
+				"HeapMemoryUsage":    "memory.heap_usage",
+				"NonHeapMemoryUsage": "memory.non_heap_usage",
+			},
+		},
 	}
 
-	event, err := eventMapping(jolokiaResponse, mapping)
+	events, err := eventMapping(jolokiaResponse, mapping)
 
 	assert.Nil(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, "test", events[0].namespace)
+	assert.Nil(t, events[0].errs.Err())
+
+	event := events[0].event
 	assert.EqualValues(t, 47283, event["uptime"])
 	assert.EqualValues(t, 53, event["gc"].(common.MapStr)["cms_collection_time"])
 	assert.EqualValues(t, 1, event["gc"].(common.MapStr)["cms_collection_count"])
@@ -43,3 +64,37 @@ func TestEventMapper(t *testing.T) {
 	assert.EqualValues(t, -1, event["memory"].(common.MapStr)["non_heap_usage"].(map[string]interface{})["max"])
 	assert.EqualValues(t, 50519768, event["memory"].(common.MapStr)["non_heap_usage"].(map[string]interface{})["used"])
 }
+
+func TestEventMapperPartialErrors(t *testing.T) {
+	jolokiaResponse := []byte(`[
+		{"request": {"mbean": "java.lang:type=Runtime", "type": "read"}, "value": {"Uptime": 47283}, "status": 200},
+		{"request": {"mbean": "java.lang:type=Unknown", "type": "read"}, "error": "javax.management.InstanceNotFoundException : java.lang:type=Unknown", "status": 404},
+		{"request": {"mbean": "java.lang:type=Threading", "type": "read"}, "value": {"ThreadCount": 12}, "status": 200}
+	]`)
+
+	var mapping = []blockMapping{
+		{mbean: "java.lang:type=Runtime", namespace: "runtime", fields: map[string]string{"Uptime": "uptime"}},
+		{mbean: "java.lang:type=Unknown", namespace: "threads", fields: map[string]string{"Count": "unknown"}},
+		{mbean: "java.lang:type=Threading", namespace: "threads", fields: map[string]string{"ThreadCount": "count"}},
+	}
+
+	events, err := eventMapping(jolokiaResponse, mapping)
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+
+	assert.Equal(t, "runtime", events[0].namespace)
+	assert.Equal(t, common.MapStr{"uptime": float64(47283)}, events[0].event)
+	assert.NoError(t, events[0].errs.Err())
+
+	assert.Equal(t, "threads", events[1].namespace)
+	assert.Equal(t, common.MapStr{"count": float64(12)}, events[1].event)
+	if assert.Error(t, events[1].errs.Err()) {
+		assert.Contains(t, events[1].errs.Err().Error(), "java.lang:type=Unknown")
+	}
+}
+
+func TestEventMapperEntryCountMismatch(t *testing.T) {
+	jolokiaResponse := []byte(`[{"request": {"mbean": "java.lang:type=Runtime"}, "value": {"Uptime": 1}, "status": 200}]`)
+	_, err := eventMapping(jolokiaResponse, nil)
+	assert.Error(t, err)
+}
@@ -1,7 +1,6 @@
 package jmx
 
 import (
-	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/common/cfgwarn"
 	"github.com/elastic/beats/libbeat/logp"
 	"github.com/elastic/beats/metricbeat/helper"
@@ -42,7 +41,7 @@ var (
 // MetricSet type defines all fields of the MetricSet
 type MetricSet struct {
 	mb.BaseMetricSet
-	mapping   map[string]string
+	mapping   []blockMapping
 	namespace string
 	http      *helper.HTTP
 }
@@ -61,7 +60,7 @@ func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
 		return nil, err
 	}
 
-	body, mapping, err := buildRequestBodyAndMapping(config.Mappings)
+	body, mapping, err := buildRequestBodyAndMapping(config.Mappings, config.Namespace)
 	if err != nil {
 		return nil, err
 	}
@@ -83,11 +82,14 @@ func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
 	}, nil
 }
 
-// Fetch methods implements the data gathering and data conversion to the right format
-func (m *MetricSet) Fetch() (common.MapStr, error) {
+// Fetch methods implements the data gathering and data conversion to the right format.
+// All mappings are read with a single bulk request. One event is reported per
+// namespace, failed reads are reported as errors of their namespace.
+func (m *MetricSet) Fetch(r mb.Reporter) {
 	body, err := m.http.FetchContent()
 	if err != nil {
-		return nil, err
+		r.Error(err)
+		return
 	}
 
 	if logp.IsDebug(metricsetName) {
@@ -95,13 +97,20 @@ func (m *MetricSet) Fetch() (common.MapStr, error) {
 			logPrefix, m.HostData().Host, string(body))
 	}
 
-	event, err := eventMapping(body, m.mapping)
+	events, err := eventMapping(body, m.mapping)
 	if err != nil {
-		return nil, err
+		r.Error(err)
+		return
 	}
 
-	// Set dynamic namespace.
-	event[mb.NamespaceKey] = m.namespace
+	for _, e := range events {
+		// Set dynamic namespace.
+		e.event[mb.NamespaceKey] = e.namespace
 
-	return event, nil
+		if err := e.errs.Err(); err != nil {
+			r.ErrorWith(err, e.event)
+		} else {
+			r.Event(e.event)
+		}
+	}
 }
@@ -15,21 +15,29 @@ import (
 func TestFetch(t *testing.T) {
 	compose.EnsureUp(t, "jolokia")
 
-	f := mbtest.NewEventFetcher(t, getConfig())
-	event, err := f.Fetch()
-	if !assert.NoError(t, err) {
+	f := mbtest.NewReportingMetricSet(t, getConfig())
+	events, errs := mbtest.ReportingFetch(f)
+	if !assert.Empty(t, errs) {
+		t.FailNow()
+	}
+	if !assert.NotEmpty(t, events) {
 		t.FailNow()
 	}
 
-	t.Logf("%s/%s event: %+v", f.Module().Name(), f.Name(), event)
+	t.Logf("%s/%s event: %+v", f.Module().Name(), f.Name(), events[0])
 }
 
 func TestData(t *testing.T) {
-	f := mbtest.NewEventFetcher(t, getConfig())
-	err := mbtest.WriteEvent(f, t)
-	if err != nil {
-		t.Fatal("write", err)
+	f := mbtest.NewReportingMetricSet(t, getConfig())
+	events, errs := mbtest.ReportingFetch(f)
+	if len(errs) > 0 {
+		t.Fatal(errs)
 	}
+	if len(events) == 0 {
+		t.Fatal("no events returned")
+	}
+
+	mbtest.WriteEventToDataJSON(t, mbtest.CreateFullEvent(f, events[0]))
 }
 
 func getConfig() map[string]interface{} {
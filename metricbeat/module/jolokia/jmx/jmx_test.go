@@ -0,0 +1,135 @@
+package jmx
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/metricbeat/mb"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+)
+
+type reportedEvent struct {
+	event common.MapStr
+	err   error
+}
+
+type recordingReporter struct {
+	events []reportedEvent
+}
+
+func (r *recordingReporter) Event(event common.MapStr) bool {
+	return r.ErrorWith(nil, event)
+}
+
+func (r *recordingReporter) ErrorWith(err error, meta common.MapStr) bool {
+	r.events = append(r.events, reportedEvent{event: meta, err: err})
+	return true
+}
+
+func (r *recordingReporter) Error(err error) bool {
+	return r.ErrorWith(err, nil)
+}
+
+func TestFetchBulkRequest(t *testing.T) {
+	requests := 0
+	var blocks []RequestBlock
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(body, &blocks))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[
+			{"request": {"mbean": "java.lang:type=Runtime", "type": "read"}, "value": {"Uptime": 47283}, "status": 200},
+			{"request": {"mbean": "java.lang:type=Memory", "type": "read"}, "value": {"HeapMemoryUsage": {"used": 1024}}, "status": 200},
+			{"request": {"mbean": "kafka.server:type=Missing", "type": "read"}, "error": "javax.management.InstanceNotFoundException : kafka.server:type=Missing", "status": 404}
+		]`))
+	}))
+	defer server.Close()
+
+	config := map[string]interface{}{
+		"module":     "jolokia",
+		"metricsets": []string{"jmx"},
+		"hosts":      []string{strings.TrimPrefix(server.URL, "http://")},
+		"namespace":  "jvm",
+		"jmx.mappings": []map[string]interface{}{
+			{
+				"mbean":      "java.lang:type=Runtime",
+				"attributes": []map[string]string{{"attr": "Uptime", "field": "uptime"}},
+			},
+			{
+				"mbean":      "java.lang:type=Memory",
+				"attributes": []map[string]string{{"attr": "HeapMemoryUsage", "field": "memory.heap_usage"}},
+			},
+			{
+				"mbean":      "kafka.server:type=Missing",
+				"namespace":  "kafka",
+				"attributes": []map[string]string{{"attr": "Value", "field": "missing"}},
+			},
+		},
+	}
+
+	f := mbtest.NewReportingMetricSet(t, config)
+	r := &recordingReporter{}
+	f.Fetch(r)
+
+	// all mappings are read with a single bulk request
+	assert.Equal(t, 1, requests)
+	require.Len(t, blocks, 3)
+	assert.Equal(t, "java.lang:type=Runtime", blocks[0].MBean)
+	assert.Equal(t, "kafka.server:type=Missing", blocks[2].MBean)
+
+	require.Len(t, r.events, 2)
+
+	jvm := r.events[0]
+	assert.NoError(t, jvm.err)
+	assert.Equal(t, "jvm", jvm.event[mb.NamespaceKey])
+	assert.EqualValues(t, 47283, jvm.event["uptime"])
+	used, err := jvm.event.GetValue("memory.heap_usage.used")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1024, used)
+
+	kafka := r.events[1]
+	assert.Equal(t, "kafka", kafka.event[mb.NamespaceKey])
+	if assert.Error(t, kafka.err) {
+		assert.Contains(t, kafka.err.Error(), "kafka.server:type=Missing")
+	}
+}
+
+func TestFetchHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	config := map[string]interface{}{
+		"module":     "jolokia",
+		"metricsets": []string{"jmx"},
+		"hosts":      []string{strings.TrimPrefix(server.URL, "http://")},
+		"namespace":  "jvm",
+		"jmx.mappings": []map[string]interface{}{
+			{
+				"mbean":      "java.lang:type=Runtime",
+				"attributes": []map[string]string{{"attr": "Uptime", "field": "uptime"}},
+			},
+		},
+	}
+
+	f := mbtest.NewReportingMetricSet(t, config)
+	r := &recordingReporter{}
+	f.Fetch(r)
+
+	require.Len(t, r.events, 1)
+	assert.Error(t, r.events[0].err)
+	assert.Nil(t, r.events[0].event)
+}
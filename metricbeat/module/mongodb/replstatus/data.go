@@ -0,0 +1,118 @@
+package replstatus
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// Member states of replSetGetStatus.
+const (
+	statePrimary   = 1
+	stateSecondary = 2
+)
+
+// replSetStatus is the response of the replSetGetStatus command.
+type replSetStatus struct {
+	Set     string   `bson:"set"`
+	Members []member `bson:"members"`
+}
+
+type member struct {
+	Name       string    `bson:"name"`
+	Health     float64   `bson:"health"`
+	State      int       `bson:"state"`
+	StateStr   string    `bson:"stateStr"`
+	OptimeDate time.Time `bson:"optimeDate"`
+	Self       bool      `bson:"self"`
+}
+
+// oplogEntry is an entry of the oplog, only its timestamp is read.
+type oplogEntry struct {
+	Timestamp bson.MongoTimestamp `bson:"ts"`
+}
+
+// oplogInfo holds the first and the last entries of the oplog of a host.
+type oplogInfo struct {
+	first, last oplogEntry
+}
+
+// eventsMapping creates an event per secondary of the replica set. The lag of
+// a secondary is the time its last applied operation is behind the one of the
+// primary, or of the most recent member if there is no primary, like during an
+// election. A single event without member is returned if there are no
+// secondaries.
+func eventsMapping(status replSetStatus, oplog oplogInfo) []common.MapStr {
+	base := common.MapStr{
+		"set_name": status.Set,
+		"oplog":    oplogMapping(oplog),
+	}
+
+	var primary *member
+	var latest time.Time
+	for i, m := range status.Members {
+		if m.State == statePrimary {
+			primary = &status.Members[i]
+		}
+		if m.OptimeDate.After(latest) {
+			latest = m.OptimeDate
+		}
+		if m.Self {
+			base["state"] = m.StateStr
+		}
+	}
+	if primary != nil {
+		base["primary"] = common.MapStr{"name": primary.Name}
+		latest = primary.OptimeDate
+	}
+
+	var events []common.MapStr
+	for _, m := range status.Members {
+		if m.State != stateSecondary {
+			continue
+		}
+
+		lag := latest.Sub(m.OptimeDate)
+		if lag < 0 {
+			lag = 0
+		}
+
+		event := base.Clone()
+		event["member"] = common.MapStr{
+			"name":    m.Name,
+			"healthy": m.Health == 1,
+			"self":    m.Self,
+		}
+		event["lag"] = common.MapStr{
+			"ms": int64(lag / time.Millisecond),
+		}
+		events = append(events, event)
+	}
+
+	if len(events) == 0 {
+		return []common.MapStr{base}
+	}
+	return events
+}
+
+// oplogMapping reports the time window covered by the oplog, the time between
+// its first and its last entries.
+func oplogMapping(oplog oplogInfo) common.MapStr {
+	first, last := timestamp(oplog.first.Timestamp), timestamp(oplog.last.Timestamp)
+	return common.MapStr{
+		"first": common.MapStr{"timestamp": common.Time(first)},
+		"last":  common.MapStr{"timestamp": common.Time(last)},
+		"window": common.MapStr{
+			"sec": int64(last.Sub(first) / time.Second),
+		},
+	}
+}
+
+// timestamp returns the time of an oplog timestamp. The seconds since the
+// epoch are stored in the upper 32 bits, the lower ones order the operations
+// of the same second.
+func timestamp(ts bson.MongoTimestamp) time.Time {
+	return time.Unix(int64(ts)>>32, 0).UTC()
+}
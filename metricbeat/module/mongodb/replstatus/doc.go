@@ -0,0 +1,5 @@
+/*
+Package replstatus reports the replication lag of the secondaries of a MongoDB
+replica set, and the time window of the oplog.
+*/
+package replstatus
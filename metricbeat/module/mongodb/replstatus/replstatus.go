@@ -0,0 +1,79 @@
+package replstatus
+
+import (
+	"github.com/pkg/errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/metricbeat/mb"
+	"github.com/elastic/beats/metricbeat/module/mongodb"
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("mongodb", "replstatus", New, mongodb.ParseURL); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet reports the replication lag and the oplog window of a replica set
+// member.
+type MetricSet struct {
+	mb.BaseMetricSet
+	dialInfo *mgo.DialInfo
+}
+
+// New creates a new instance of the replstatus MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	cfgwarn.Experimental("The mongodb replstatus metricset is experimental")
+
+	dialInfo, err := mgo.ParseURL(base.HostData().URI)
+	if err != nil {
+		return nil, err
+	}
+	dialInfo.Timeout = base.Module().Config().Timeout
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		dialInfo:      dialInfo,
+	}, nil
+}
+
+// Fetch reports an event per secondary of the replica set, with its
+// replication lag and the oplog window of the host.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	session, err := mongodb.NewDirectSession(m.dialInfo)
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	// Secondaries serve the reads of the oplog.
+	session.SetMode(mgo.Monotonic, true)
+
+	var status replSetStatus
+	if err := session.DB("admin").Run(bson.D{{Name: "replSetGetStatus", Value: 1}}, &status); err != nil {
+		return nil, errors.Wrap(err, "failed to get the replica set status")
+	}
+
+	oplog, err := getOplog(session)
+	if err != nil {
+		return nil, err
+	}
+
+	return eventsMapping(status, oplog), nil
+}
+
+// getOplog reads the first and the last entries of the oplog.
+func getOplog(session *mgo.Session) (oplogInfo, error) {
+	var first, last oplogEntry
+	collection := session.DB("local").C("oplog.rs")
+	if err := collection.Find(nil).Sort("$natural").One(&first); err != nil {
+		return oplogInfo{}, errors.Wrap(err, "failed to read the first oplog entry")
+	}
+	if err := collection.Find(nil).Sort("-$natural").One(&last); err != nil {
+		return oplogInfo{}, errors.Wrap(err, "failed to read the last oplog entry")
+	}
+	return oplogInfo{first: first, last: last}, nil
+}
@@ -0,0 +1,109 @@
+// +build !integration
+
+package replstatus
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// decodeFixture decodes a recorded command response, in the extended JSON of
+// the mongo shell, into out like the driver decodes the BSON response.
+func decodeFixture(t *testing.T, path string, out interface{}) {
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+
+	var doc interface{}
+	require.NoError(t, bson.UnmarshalJSON(data, &doc))
+	raw, err := bson.Marshal(bson.M{"doc": doc})
+	require.NoError(t, err)
+
+	var wrapper struct {
+		Doc bson.Raw `bson:"doc"`
+	}
+	require.NoError(t, bson.Unmarshal(raw, &wrapper))
+	require.NoError(t, wrapper.Doc.Unmarshal(out))
+}
+
+func loadFixtures(t *testing.T) (replSetStatus, oplogInfo) {
+	var status replSetStatus
+	decodeFixture(t, "./_meta/test/replSetGetStatus.json", &status)
+
+	var entries []oplogEntry
+	decodeFixture(t, "./_meta/test/oplog.json", &entries)
+	require.Len(t, entries, 2)
+
+	return status, oplogInfo{first: entries[0], last: entries[1]}
+}
+
+func TestEventsMapping(t *testing.T) {
+	status, oplog := loadFixtures(t)
+
+	events := eventsMapping(status, oplog)
+	require.Len(t, events, 2)
+
+	expectedOplog := common.MapStr{
+		"first":  common.MapStr{"timestamp": common.Time(time.Date(2017, 10, 14, 8, 0, 0, 0, time.UTC))},
+		"last":   common.MapStr{"timestamp": common.Time(time.Date(2017, 10, 14, 10, 0, 10, 0, time.UTC))},
+		"window": common.MapStr{"sec": int64(7210)},
+	}
+
+	assert.Equal(t, common.MapStr{
+		"set_name": "rs0",
+		"state":    "PRIMARY",
+		"primary":  common.MapStr{"name": "mongo1:27017"},
+		"member": common.MapStr{
+			"name":    "mongo2:27017",
+			"healthy": true,
+			"self":    false,
+		},
+		"lag":   common.MapStr{"ms": int64(0)},
+		"oplog": expectedOplog,
+	}, events[0])
+
+	assert.Equal(t, "mongo3:27017", events[1]["member"].(common.MapStr)["name"])
+	assert.Equal(t, common.MapStr{"ms": int64(2500)}, events[1]["lag"])
+}
+
+func TestEventsMappingWithoutPrimary(t *testing.T) {
+	status, oplog := loadFixtures(t)
+
+	// During an election, the lag is relative to the most recent member.
+	status.Members[0].State = stateSecondary
+	status.Members[0].StateStr = "SECONDARY"
+	status.Members[0].OptimeDate = status.Members[0].OptimeDate.Add(-time.Second)
+
+	events := eventsMapping(status, oplog)
+	require.Len(t, events, 3)
+
+	lags := map[string]interface{}{}
+	for _, event := range events {
+		assert.NotContains(t, event, "primary")
+		name := event["member"].(common.MapStr)["name"].(string)
+		lags[name] = event["lag"].(common.MapStr)["ms"]
+	}
+	assert.Equal(t, map[string]interface{}{
+		"mongo1:27017": int64(1000),
+		"mongo2:27017": int64(0),
+		"mongo3:27017": int64(2500),
+	}, lags)
+}
+
+func TestEventsMappingWithoutSecondaries(t *testing.T) {
+	status, oplog := loadFixtures(t)
+	status.Members = status.Members[:1]
+
+	events := eventsMapping(status, oplog)
+	require.Len(t, events, 1)
+	assert.Equal(t, "mongo1:27017", events[0]["primary"].(common.MapStr)["name"])
+	assert.NotContains(t, events[0], "member")
+	assert.NotContains(t, events[0], "lag")
+	assert.Equal(t, common.MapStr{"sec": int64(7210)}, events[0]["oplog"].(common.MapStr)["window"])
+}
@@ -0,0 +1,73 @@
+package federation
+
+import (
+	"encoding/json"
+
+	"github.com/elastic/beats/libbeat/common"
+	s "github.com/elastic/beats/libbeat/common/schema"
+	c "github.com/elastic/beats/libbeat/common/schema/mapstriface"
+)
+
+var (
+	schema = s.Schema{
+		"id":       c.Str("id", s.Optional),
+		"vhost":    c.Str("vhost"),
+		"node":     c.Str("node"),
+		"type":     c.Str("type"),
+		"queue":    c.Str("queue", s.Optional),
+		"exchange": c.Str("exchange", s.Optional),
+		"state":    c.Str("status"),
+		"error":    c.Str("error", s.Optional),
+		"upstream": s.Object{
+			"name":     c.Str("upstream"),
+			"uri":      c.Str("uri", s.Optional),
+			"queue":    c.Str("upstream_queue", s.Optional),
+			"exchange": c.Str("upstream_exchange", s.Optional),
+		},
+		// The local channel is only reported for running links.
+		"channel": c.Dict("local_channel", s.Schema{
+			"state": c.Str("state"),
+			"consumers": s.Object{
+				"count": c.Int("consumer_count"),
+			},
+			"messages": s.Object{
+				"unacknowledged": s.Object{
+					"count": c.Int("messages_unacknowledged"),
+				},
+				"unconfirmed": s.Object{
+					"count": c.Int("messages_unconfirmed"),
+				},
+				"uncommitted": s.Object{
+					"count": c.Int("messages_uncommitted"),
+				},
+			},
+			"acks": s.Object{
+				"uncommitted": s.Object{
+					"count": c.Int("acks_uncommitted"),
+				},
+			},
+		}, c.DictOptional),
+	}
+)
+
+func eventsMapping(content []byte) ([]common.MapStr, error) {
+	var links []map[string]interface{}
+	if err := json.Unmarshal(content, &links); err != nil {
+		return nil, err
+	}
+
+	events := make([]common.MapStr, 0, len(links))
+	for _, link := range links {
+		event, _ := eventMapping(link)
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// eventMapping maps the status of a federation link. The state is
+// `starting`, `running`, `shutdown` or `error`, with the error of the link.
+func eventMapping(link map[string]interface{}) (common.MapStr, *s.Errors) {
+	event, errs := schema.Apply(link)
+	event["running"] = event["state"] == "running"
+	return event, errs
+}
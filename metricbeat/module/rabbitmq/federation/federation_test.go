@@ -0,0 +1,85 @@
+package federation
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchEventContents(t *testing.T) {
+	absPath, err := filepath.Abs("../_meta/testdata/")
+	require.NoError(t, err)
+
+	response, err := ioutil.ReadFile(absPath + "/federation_sample_response.json")
+	require.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/federation-links", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json;")
+		w.WriteHeader(200)
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	config := map[string]interface{}{
+		"module":     "rabbitmq",
+		"metricsets": []string{"federation"},
+		"hosts":      []string{server.URL},
+	}
+
+	f := mbtest.NewEventsFetcher(t, config)
+	events, err := f.Fetch()
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	event := events[0]
+	t.Logf("%s/%s event: %+v", f.Module().Name(), f.Name(), event.StringToPrint())
+
+	assert.EqualValues(t, "b40a6e4c", event["id"])
+	assert.EqualValues(t, "/", event["vhost"])
+	assert.EqualValues(t, "rabbit@localhost", event["node"])
+	assert.EqualValues(t, "exchange", event["type"])
+	assert.EqualValues(t, "events", event["exchange"])
+	assert.EqualValues(t, "running", event["state"])
+	assert.EqualValues(t, true, event["running"])
+	assert.NotContains(t, event, "error")
+	assert.Equal(t, common.MapStr{
+		"name":     "dc2",
+		"uri":      "amqp://dc2.example.com",
+		"exchange": "events",
+	}, event["upstream"])
+
+	channel := event["channel"].(common.MapStr)
+	assert.EqualValues(t, "running", channel["state"])
+	v, _ := channel.GetValue("consumers.count")
+	assert.EqualValues(t, 1, v)
+	v, _ = channel.GetValue("messages.unacknowledged.count")
+	assert.EqualValues(t, 2, v)
+	v, _ = channel.GetValue("messages.unconfirmed.count")
+	assert.EqualValues(t, 17, v)
+	v, _ = channel.GetValue("messages.uncommitted.count")
+	assert.EqualValues(t, 0, v)
+	v, _ = channel.GetValue("acks.uncommitted.count")
+	assert.EqualValues(t, 0, v)
+
+	// Failed links report their error, and have no local channel.
+	event = events[1]
+	assert.EqualValues(t, "queue", event["type"])
+	assert.EqualValues(t, "jobs", event["queue"])
+	assert.EqualValues(t, "error", event["state"])
+	assert.EqualValues(t, false, event["running"])
+	assert.Contains(t, event["error"], "ACCESS_REFUSED")
+	assert.NotContains(t, event, "channel")
+}
+
+func TestEventsMappingInvalid(t *testing.T) {
+	_, err := eventsMapping([]byte(`{"error": "Object Not Found"}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,57 @@
+package shovel
+
+import (
+	"encoding/json"
+
+	"github.com/elastic/beats/libbeat/common"
+	s "github.com/elastic/beats/libbeat/common/schema"
+	c "github.com/elastic/beats/libbeat/common/schema/mapstriface"
+)
+
+var (
+	schema = s.Schema{
+		"name":   c.Str("name"),
+		"vhost":  c.Str("vhost"),
+		"type":   c.Str("type"),
+		"node":   c.Str("node"),
+		"state":  c.Str("state"),
+		"reason": c.Str("reason", s.Optional),
+		"src":    endpoint("src"),
+		"dest":   endpoint("dest"),
+	}
+)
+
+// endpoint maps the source or the destination of a shovel, only reported for
+// started shovels, by queue or by exchange. The URIs are reported without
+// credentials.
+func endpoint(prefix string) s.Object {
+	return s.Object{
+		"uri":          c.Str(prefix+"_uri", s.Optional),
+		"protocol":     c.Str(prefix+"_protocol", s.Optional),
+		"queue":        c.Str(prefix+"_queue", s.Optional),
+		"exchange":     c.Str(prefix+"_exchange", s.Optional),
+		"exchange_key": c.Str(prefix+"_exchange_key", s.Optional),
+	}
+}
+
+func eventsMapping(content []byte) ([]common.MapStr, error) {
+	var shovels []map[string]interface{}
+	if err := json.Unmarshal(content, &shovels); err != nil {
+		return nil, err
+	}
+
+	events := make([]common.MapStr, 0, len(shovels))
+	for _, shovel := range shovels {
+		event, _ := eventMapping(shovel)
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// eventMapping maps the status of a shovel. The state is `starting`,
+// `running` or `terminated`, with the reason of the termination.
+func eventMapping(shovel map[string]interface{}) (common.MapStr, *s.Errors) {
+	event, errs := schema.Apply(shovel)
+	event["running"] = event["state"] == "running"
+	return event, errs
+}
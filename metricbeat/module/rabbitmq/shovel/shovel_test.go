@@ -0,0 +1,79 @@
+package shovel
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchEventContents(t *testing.T) {
+	absPath, err := filepath.Abs("../_meta/testdata/")
+	require.NoError(t, err)
+
+	response, err := ioutil.ReadFile(absPath + "/shovel_sample_response.json")
+	require.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/shovels", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json;")
+		w.WriteHeader(200)
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	config := map[string]interface{}{
+		"module":     "rabbitmq",
+		"metricsets": []string{"shovel"},
+		"hosts":      []string{server.URL},
+	}
+
+	f := mbtest.NewEventsFetcher(t, config)
+	events, err := f.Fetch()
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	event := events[0]
+	t.Logf("%s/%s event: %+v", f.Module().Name(), f.Name(), event.StringToPrint())
+
+	assert.EqualValues(t, "orders-to-dc2", event["name"])
+	assert.EqualValues(t, "/", event["vhost"])
+	assert.EqualValues(t, "dynamic", event["type"])
+	assert.EqualValues(t, "rabbit@localhost", event["node"])
+	assert.EqualValues(t, "running", event["state"])
+	assert.EqualValues(t, true, event["running"])
+	assert.NotContains(t, event, "reason")
+
+	assert.Equal(t, common.MapStr{
+		"uri":      "amqp://localhost",
+		"protocol": "amqp091",
+		"queue":    "orders",
+	}, event["src"])
+	assert.Equal(t, common.MapStr{
+		"uri":          "amqp://dc2.example.com",
+		"protocol":     "amqp091",
+		"exchange":     "orders",
+		"exchange_key": "eu",
+	}, event["dest"])
+
+	// Terminated shovels report the reason of the termination.
+	event = events[1]
+	assert.EqualValues(t, "terminated", event["state"])
+	assert.EqualValues(t, false, event["running"])
+	assert.Contains(t, event["reason"], "econnrefused")
+
+	event = events[2]
+	assert.EqualValues(t, "starting", event["state"])
+	assert.EqualValues(t, false, event["running"])
+}
+
+func TestEventsMappingInvalid(t *testing.T) {
+	_, err := eventsMapping([]byte(`{"error": "Object Not Found"}`))
+	assert.Error(t, err)
+}
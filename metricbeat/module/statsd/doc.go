@@ -0,0 +1,4 @@
+/*
+Package statsd is a Metricbeat module receiving metrics from StatsD clients.
+*/
+package statsd
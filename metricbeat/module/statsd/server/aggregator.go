@@ -0,0 +1,177 @@
+package server
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// aggregator aggregates the metrics received during a period, like a StatsD
+// server flushing its metrics at each interval.
+type aggregator struct {
+	buckets map[string]*bucket
+
+	// gauges keeps the last value of the gauges across periods, so gauges
+	// can be modified by signed values.
+	gauges map[string]float64
+}
+
+// bucket aggregates the values of a metric with the same name, type and tags.
+type bucket struct {
+	name       string
+	metricType string
+	tags       map[string]string
+
+	count  float64 // sum of the counter values, or number of timer values, scaled by the sample rate
+	gauge  float64
+	timers []float64
+	set    map[string]struct{}
+}
+
+func newAggregator() *aggregator {
+	return &aggregator{
+		buckets: map[string]*bucket{},
+		gauges:  map[string]float64{},
+	}
+}
+
+// add aggregates the value of the metric. The value of the metric must have
+// been validated by the parser.
+func (a *aggregator) add(m metric) {
+	key := bucketKey(m)
+	b, exists := a.buckets[key]
+	if !exists {
+		b = &bucket{name: m.name, metricType: m.metricType, tags: m.tags}
+		a.buckets[key] = b
+	}
+
+	switch m.metricType {
+	case counterType:
+		value, _ := strconv.ParseFloat(m.value, 64)
+		b.count += value / m.sampleRate
+	case gaugeType:
+		value, _ := strconv.ParseFloat(m.value, 64)
+		if strings.HasPrefix(m.value, "+") || strings.HasPrefix(m.value, "-") {
+			value += a.gauges[key]
+		}
+		a.gauges[key] = value
+		b.gauge = value
+	case timerType:
+		value, _ := strconv.ParseFloat(m.value, 64)
+		b.timers = append(b.timers, value)
+		b.count += 1 / m.sampleRate
+	case setType:
+		if b.set == nil {
+			b.set = map[string]struct{}{}
+		}
+		b.set[m.value] = struct{}{}
+	}
+}
+
+// flush returns an event per metric received since the last flush, in a
+// stable order, and resets the aggregated values. The rate of counters is
+// computed per second of the period.
+func (a *aggregator) flush(period time.Duration) []common.MapStr {
+	keys := make([]string, 0, len(a.buckets))
+	for key := range a.buckets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	events := make([]common.MapStr, 0, len(keys))
+	for _, key := range keys {
+		events = append(events, a.buckets[key].event(period))
+	}
+	a.buckets = map[string]*bucket{}
+	return events
+}
+
+func (b *bucket) event(period time.Duration) common.MapStr {
+	event := common.MapStr{
+		"name": b.name,
+		"type": b.metricType,
+	}
+	if len(b.tags) > 0 {
+		tags := common.MapStr{}
+		for k, v := range b.tags {
+			tags[k] = v
+		}
+		event["tags"] = tags
+	}
+
+	switch b.metricType {
+	case counterType:
+		event[counterType] = common.MapStr{
+			"value": b.count,
+			"rate":  b.count / period.Seconds(),
+		}
+	case gaugeType:
+		event[gaugeType] = common.MapStr{
+			"value": b.gauge,
+		}
+	case timerType:
+		event[timerType] = timerStats(b.timers, b.count)
+	case setType:
+		event[setType] = common.MapStr{
+			"count": len(b.set),
+		}
+	}
+	return event
+}
+
+// timerStats summarizes the timer values. The count is scaled by the sample
+// rate, the other statistics are computed from the received values.
+func timerStats(values []float64, count float64) common.MapStr {
+	sort.Float64s(values)
+
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+
+	n := len(values)
+	median := values[n/2]
+	if n%2 == 0 {
+		median = (values[n/2-1] + values[n/2]) / 2
+	}
+
+	return common.MapStr{
+		"count":  count,
+		"min":    values[0],
+		"max":    values[n-1],
+		"sum":    sum,
+		"mean":   sum / float64(n),
+		"median": median,
+		"p95":    percentile(values, 95),
+		"p99":    percentile(values, 99),
+	}
+}
+
+// percentile returns the nearest-rank percentile p of the sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// bucketKey identifies the metrics aggregated together by name, type and
+// tags, independently of the order of the tags.
+func bucketKey(m metric) string {
+	key := m.metricType + "|" + m.name
+	if len(m.tags) == 0 {
+		return key
+	}
+
+	tags := make([]string, 0, len(m.tags))
+	for k, v := range m.tags {
+		tags = append(tags, k+":"+v)
+	}
+	sort.Strings(tags)
+	return key + "|" + strings.Join(tags, ",")
+}
@@ -0,0 +1,142 @@
+// +build !integration
+
+package server
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func aggregate(t *testing.T, a *aggregator, packet string) {
+	metrics, errs := parsePacket(packet)
+	require.Empty(t, errs)
+	for _, m := range metrics {
+		a.add(m)
+	}
+}
+
+func TestAggregatorCounters(t *testing.T) {
+	a := newAggregator()
+	aggregate(t, a, "page.views:1|c\npage.views:2|c\npage.views:1|c|@0.1")
+
+	events := a.flush(10 * time.Second)
+	assert.Equal(t, []common.MapStr{
+		{
+			"name":    "page.views",
+			"type":    "counter",
+			"counter": common.MapStr{"value": float64(13), "rate": 1.3},
+		},
+	}, events)
+
+	// Counters are reset after each period.
+	assert.Empty(t, a.flush(10*time.Second))
+}
+
+func TestAggregatorGauges(t *testing.T) {
+	a := newAggregator()
+	aggregate(t, a, "fuel.level:10|g\nfuel.level:-3|g\nfuel.level:+1.5|g")
+
+	events := a.flush(10 * time.Second)
+	require.Len(t, events, 1)
+	assert.Equal(t, common.MapStr{"value": 8.5}, events[0]["gauge"])
+
+	// Signed values modify the gauge of the previous period, unsigned values
+	// replace it.
+	aggregate(t, a, "fuel.level:-0.5|g")
+	events = a.flush(10 * time.Second)
+	require.Len(t, events, 1)
+	assert.Equal(t, common.MapStr{"value": float64(8)}, events[0]["gauge"])
+
+	aggregate(t, a, "fuel.level:2|g")
+	events = a.flush(10 * time.Second)
+	require.Len(t, events, 1)
+	assert.Equal(t, common.MapStr{"value": float64(2)}, events[0]["gauge"])
+}
+
+func TestAggregatorTimers(t *testing.T) {
+	a := newAggregator()
+	for i := 1; i <= 100; i++ {
+		aggregate(t, a, fmt.Sprintf("request.time:%d|ms", i))
+	}
+	aggregate(t, a, "request.size:10|h|@0.5\nrequest.size:30|h|@0.5")
+
+	events := a.flush(10 * time.Second)
+	require.Len(t, events, 2)
+	assert.Equal(t, "request.size", events[0]["name"])
+	assert.Equal(t, common.MapStr{
+		"count":  float64(4),
+		"min":    float64(10),
+		"max":    float64(30),
+		"sum":    float64(40),
+		"mean":   float64(20),
+		"median": float64(20),
+		"p95":    float64(30),
+		"p99":    float64(30),
+	}, events[0]["timer"])
+
+	assert.Equal(t, "request.time", events[1]["name"])
+	assert.Equal(t, "timer", events[1]["type"])
+	assert.Equal(t, common.MapStr{
+		"count":  float64(100),
+		"min":    float64(1),
+		"max":    float64(100),
+		"sum":    float64(5050),
+		"mean":   50.5,
+		"median": 50.5,
+		"p95":    float64(95),
+		"p99":    float64(99),
+	}, events[1]["timer"])
+}
+
+func TestAggregatorSets(t *testing.T) {
+	a := newAggregator()
+	aggregate(t, a, "users.unique:alice|s\nusers.unique:bob|s\nusers.unique:alice|s")
+
+	events := a.flush(10 * time.Second)
+	assert.Equal(t, []common.MapStr{
+		{
+			"name": "users.unique",
+			"type": "set",
+			"set":  common.MapStr{"count": 2},
+		},
+	}, events)
+}
+
+func TestAggregatorTags(t *testing.T) {
+	a := newAggregator()
+	aggregate(t, a, strings.Join([]string{
+		"page.views:1|c|#env:prod,region:eu",
+		"page.views:2|c|#region:eu,env:prod",
+		"page.views:5|c|#env:dev",
+		"page.views:7|c",
+	}, "\n"))
+
+	// Metrics are aggregated per set of tags, independently of their order.
+	events := a.flush(time.Second)
+	assert.Equal(t, []common.MapStr{
+		{
+			"name":    "page.views",
+			"type":    "counter",
+			"counter": common.MapStr{"value": float64(7), "rate": float64(7)},
+		},
+		{
+			"name":    "page.views",
+			"type":    "counter",
+			"tags":    common.MapStr{"env": "dev"},
+			"counter": common.MapStr{"value": float64(5), "rate": float64(5)},
+		},
+		{
+			"name":    "page.views",
+			"type":    "counter",
+			"tags":    common.MapStr{"env": "prod", "region": "eu"},
+			"counter": common.MapStr{"value": float64(3), "rate": float64(3)},
+		},
+	}, events)
+}
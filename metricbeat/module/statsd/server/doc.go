@@ -0,0 +1,5 @@
+/*
+Package server receives StatsD metrics over UDP and reports them aggregated over
+the period of the metricset.
+*/
+package server
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Types of the StatsD metrics.
+const (
+	counterType = "counter"
+	gaugeType   = "gauge"
+	timerType   = "timer"
+	setType     = "set"
+)
+
+// metricTypes maps the type in a StatsD line to the type of the metric.
+// Histograms and distributions of the DogStatsD extension are aggregated like
+// timers.
+var metricTypes = map[string]string{
+	"c":  counterType,
+	"g":  gaugeType,
+	"ms": timerType,
+	"h":  timerType,
+	"d":  timerType,
+	"s":  setType,
+}
+
+// metric is a single value sent by a StatsD client.
+type metric struct {
+	name       string
+	metricType string
+	value      string
+	sampleRate float64
+	tags       map[string]string
+}
+
+// parsePacket parses the lines of a StatsD packet. Lines that can not be
+// parsed are reported as errors, the other metrics of the packet are still
+// returned.
+func parsePacket(packet string) ([]metric, []error) {
+	var metrics []metric
+	var errs []error
+	for _, line := range strings.Split(packet, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		m, err := parseLine(line)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, errs
+}
+
+// parseLine parses a line in the StatsD format `<name>:<value>|<type>`,
+// optionally followed by the sample rate `|@<rate>` and the tags of the
+// DogStatsD extension `|#<tag>:<value>,<tag>`.
+func parseLine(line string) (metric, error) {
+	sep := strings.LastIndex(strings.SplitN(line, "|", 2)[0], ":")
+	if sep <= 0 {
+		return metric{}, errors.Errorf("invalid StatsD line '%v': missing metric name or value", line)
+	}
+
+	m := metric{name: line[:sep], sampleRate: 1}
+	parts := strings.Split(line[sep+1:], "|")
+	if len(parts) < 2 || parts[0] == "" {
+		return metric{}, errors.Errorf("invalid StatsD line '%v': missing metric value or type", line)
+	}
+	m.value = parts[0]
+
+	metricType, ok := metricTypes[parts[1]]
+	if !ok {
+		return metric{}, errors.Errorf("invalid StatsD line '%v': unsupported metric type '%v'", line, parts[1])
+	}
+	m.metricType = metricType
+
+	for _, part := range parts[2:] {
+		switch {
+		case strings.HasPrefix(part, "@"):
+			rate, err := strconv.ParseFloat(part[1:], 64)
+			if err != nil || rate <= 0 || rate > 1 {
+				return metric{}, errors.Errorf("invalid StatsD line '%v': invalid sample rate '%v'", line, part[1:])
+			}
+			m.sampleRate = rate
+		case strings.HasPrefix(part, "#"):
+			m.tags = parseTags(part[1:])
+		default:
+			return metric{}, errors.Errorf("invalid StatsD line '%v': unknown field '%v'", line, part)
+		}
+	}
+
+	if m.metricType != setType {
+		if _, err := strconv.ParseFloat(m.value, 64); err != nil {
+			return metric{}, errors.Errorf("invalid StatsD line '%v': invalid value '%v'", line, m.value)
+		}
+	}
+	return m, nil
+}
+
+// parseTags parses the comma separated DogStatsD tags. Tags without value are
+// reported with an empty value.
+func parseTags(s string) map[string]string {
+	tags := map[string]string{}
+	for _, tag := range strings.Split(s, ",") {
+		if tag == "" {
+			continue
+		}
+		kv := strings.SplitN(tag, ":", 2)
+		if len(kv) == 2 {
+			tags[kv[0]] = kv[1]
+		} else {
+			tags[kv[0]] = ""
+		}
+	}
+	return tags
+}
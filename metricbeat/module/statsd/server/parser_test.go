@@ -0,0 +1,73 @@
+// +build !integration
+
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := map[string]metric{
+		"page.views:1|c": {
+			name: "page.views", metricType: counterType, value: "1", sampleRate: 1,
+		},
+		"page.views:3|c|@0.1": {
+			name: "page.views", metricType: counterType, value: "3", sampleRate: 0.1,
+		},
+		"fuel.level:-0.5|g": {
+			name: "fuel.level", metricType: gaugeType, value: "-0.5", sampleRate: 1,
+		},
+		"request.time:320|ms|@0.5|#env:prod,region:eu,canary": {
+			name: "request.time", metricType: timerType, value: "320", sampleRate: 0.5,
+			tags: map[string]string{"env": "prod", "region": "eu", "canary": ""},
+		},
+		"request.size:2048|h|#env:prod": {
+			name: "request.size", metricType: timerType, value: "2048", sampleRate: 1,
+			tags: map[string]string{"env": "prod"},
+		},
+		"users.unique:alice|s": {
+			name: "users.unique", metricType: setType, value: "alice", sampleRate: 1,
+		},
+	}
+
+	for line, expected := range tests {
+		m, err := parseLine(line)
+		if assert.NoError(t, err, line) {
+			assert.Equal(t, expected, m, line)
+		}
+	}
+}
+
+func TestParseLineInvalid(t *testing.T) {
+	lines := []string{
+		"page.views",
+		":1|c",
+		"page.views:1",
+		"page.views:|c",
+		"page.views:1|x",
+		"page.views:one|c",
+		"page.views:1|c|@0",
+		"page.views:1|c|@2",
+		"page.views:1|c|@rate",
+		"page.views:1|c|unknown",
+	}
+
+	for _, line := range lines {
+		_, err := parseLine(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func TestParsePacket(t *testing.T) {
+	metrics, errs := parsePacket("page.views:1|c\ninvalid\n\nusers.unique:alice|s|#env:prod\n")
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "invalid")
+
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "page.views", metrics[0].name)
+	assert.Equal(t, "users.unique", metrics[1].name)
+	assert.Equal(t, map[string]string{"env": "prod"}, metrics[1].tags)
+}
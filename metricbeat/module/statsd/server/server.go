@@ -0,0 +1,92 @@
+package server
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/libbeat/logp"
+	serverhelper "github.com/elastic/beats/metricbeat/helper/server"
+	"github.com/elastic/beats/metricbeat/helper/server/udp"
+	"github.com/elastic/beats/metricbeat/mb"
+)
+
+// defaultUdpConfig listens on the standard StatsD port. The receive buffer
+// fits the packets of StatsD clients batching several metrics.
+var defaultUdpConfig = udp.UdpConfig{
+	Host:              "localhost",
+	Port:              8125,
+	ReceiveBufferSize: 8192,
+}
+
+func init() {
+	if err := mb.Registry.AddMetricSet("statsd", "server", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet receives StatsD metrics over UDP, and reports them aggregated
+// over the period of the metricset.
+type MetricSet struct {
+	mb.BaseMetricSet
+	server     serverhelper.Server
+	aggregator *aggregator
+}
+
+// New creates a new instance of the server MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	cfgwarn.Experimental("The statsd server metricset is experimental")
+
+	s, err := udp.NewUdpServerWithDefaults(base, defaultUdpConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		server:        s,
+		aggregator:    newAggregator(),
+	}, nil
+}
+
+// Run receives the StatsD packets and reports an event per metric at the end
+// of each period. Metrics received since the last period are not reported
+// when the metricset is stopped.
+func (m *MetricSet) Run(reporter mb.PushReporter) {
+	if err := m.server.Start(); err != nil {
+		err = errors.Wrap(err, "failed to start statsd server")
+		logp.Err("%v", err)
+		reporter.Error(err)
+		return
+	}
+
+	period := m.Module().Config().Period
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-reporter.Done():
+			m.server.Stop()
+			return
+		case <-ticker.C:
+			for _, event := range m.aggregator.flush(period) {
+				reporter.Event(event)
+			}
+		case msg := <-m.server.GetEvents():
+			data, ok := msg.GetEvent()[serverhelper.EventDataKey].([]byte)
+			if !ok || len(data) == 0 {
+				continue
+			}
+
+			metrics, errs := parsePacket(string(data))
+			for _, err := range errs {
+				reporter.Error(err)
+			}
+			for _, metric := range metrics {
+				m.aggregator.add(metric)
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+// +build !integration
+
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+)
+
+func TestRunReportsAggregatedMetrics(t *testing.T) {
+	config := map[string]interface{}{
+		"module":     "statsd",
+		"metricsets": []string{"server"},
+		"host":       "127.0.0.1",
+		"port":       18125,
+		"period":     "100ms",
+	}
+	ms := mbtest.NewPushMetricSet(t, config)
+
+	go func() {
+		// Give the metricset time to start listening.
+		time.Sleep(300 * time.Millisecond)
+		conn, err := net.Dial("udp", "127.0.0.1:18125")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("page.views:1|c|#env:prod\npage.views:2|c|#env:prod\ninvalid\n"))
+	}()
+
+	events, errs := mbtest.RunPushMetricSet(time.Second, ms)
+	require.Len(t, errs, 1)
+	require.Len(t, events, 1)
+	assert.Equal(t, "page.views", events[0]["name"])
+	assert.Equal(t, common.MapStr{"env": "prod"}, events[0]["tags"])
+	assert.Equal(t, common.MapStr{"value": float64(3), "rate": float64(30)}, events[0]["counter"])
+}
@@ -46,9 +46,7 @@ func (m *MetricSet) Fetch() ([]common.MapStr, error) {
 		return nil, errors.Wrap(err, "filesystem list")
 	}
 
-	if len(m.config.IgnoreTypes) > 0 {
-		fss = Filter(fss, BuildTypeFilter(m.config.IgnoreTypes...))
-	}
+	fss = m.config.FilterFileSystems(fss)
 
 	filesSystems := make([]common.MapStr, 0, len(fss))
 	for _, fs := range fss {
@@ -0,0 +1,154 @@
+// +build darwin freebsd linux openbsd windows
+
+package filesystem
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	sigar "github.com/elastic/gosigar"
+)
+
+// labelsDir contains symlinks named after the filesystem labels, pointing to
+// the labeled devices. It is only available on Linux.
+const labelsDir = "/dev/disk/by-label"
+
+// make deviceLabels available for unit testing
+var deviceLabels = func() map[string]string { return getDeviceLabels(labelsDir) }
+
+// Validate checks the mount point and label patterns are valid globs.
+func (c *Config) Validate() error {
+	patterns := [][]string{
+		c.IncludeMountPoints, c.ExcludeMountPoints,
+		c.IncludeLabels, c.ExcludeLabels,
+	}
+	for _, list := range patterns {
+		for _, pattern := range list {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return errors.Wrapf(err, "invalid filesystem filter pattern '%v'", pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// FilterFileSystems removes all filesystems not matching the configured
+// include settings or matching any of the exclude settings. The in parameter
+// is used as backing storage for the returned slice.
+func (c *Config) FilterFileSystems(in []sigar.FileSystem) []sigar.FileSystem {
+	if len(c.IgnoreTypes) > 0 {
+		in = Filter(in, BuildTypeFilter(c.IgnoreTypes...))
+	}
+
+	if !c.hasFilters() {
+		return in
+	}
+
+	var labels map[string]string
+	if len(c.IncludeLabels) > 0 || len(c.ExcludeLabels) > 0 {
+		labels = deviceLabels()
+	}
+	return Filter(in, c.buildFilter(labels))
+}
+
+func (c *Config) hasFilters() bool {
+	return len(c.IncludeTypes) > 0 ||
+		len(c.IncludeMountPoints) > 0 || len(c.ExcludeMountPoints) > 0 ||
+		len(c.IncludeLabels) > 0 || len(c.ExcludeLabels) > 0
+}
+
+// buildFilter returns a predicate for the include and exclude settings. The
+// labels map resolved device paths to their filesystem label.
+func (c *Config) buildFilter(labels map[string]string) Predicate {
+	return func(fs *sigar.FileSystem) bool {
+		if len(c.IncludeTypes) > 0 && BuildTypeFilter(c.IncludeTypes...)(fs) {
+			debugf("Filtering filesystem with type not included %+v", *fs)
+			return false
+		}
+
+		if !matchFilters(fs.DirName, c.IncludeMountPoints, c.ExcludeMountPoints) {
+			debugf("Filtering filesystem by mount point %+v", *fs)
+			return false
+		}
+
+		if len(c.IncludeLabels) > 0 || len(c.ExcludeLabels) > 0 {
+			label := labels[resolveDevice(fs.DevName)]
+			if !matchFilters(label, c.IncludeLabels, c.ExcludeLabels) {
+				debugf("Filtering filesystem by label '%v' %+v", label, *fs)
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// matchFilters returns true if no include patterns are given or the value
+// matches any of them, and the value does not match any exclude pattern.
+func matchFilters(value string, include, exclude []string) bool {
+	if len(include) > 0 && !matchAny(value, include) {
+		return false
+	}
+	return !matchAny(value, exclude)
+}
+
+func matchAny(value string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// getDeviceLabels reads the filesystem labels from the symlinks in dir. The
+// returned map uses the resolved device path as key. No labels are returned
+// if dir does not exist.
+func getDeviceLabels(dir string) map[string]string {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		debugf("Failed to read filesystem labels from '%v': %v", dir, err)
+		return nil
+	}
+
+	labels := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		device := resolveDevice(filepath.Join(dir, entry.Name()))
+		labels[device] = unescapeLabel(entry.Name())
+	}
+	return labels
+}
+
+// resolveDevice follows symlinks to the device, so devices referenced by
+// different paths (e.g. /dev/mapper/root and /dev/dm-0) are matched.
+func resolveDevice(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
+
+// unescapeLabel decodes `\xNN` escape sequences used by udev for special
+// characters (like spaces) in label names.
+func unescapeLabel(label string) string {
+	if !strings.Contains(label, `\x`) {
+		return label
+	}
+
+	var buf []byte
+	for i := 0; i < len(label); i++ {
+		if label[i] == '\\' && i+3 < len(label) && label[i+1] == 'x' {
+			if b, err := strconv.ParseUint(label[i+2:i+4], 16, 8); err == nil {
+				buf = append(buf, byte(b))
+				i += 3
+				continue
+			}
+		}
+		buf = append(buf, label[i])
+	}
+	return string(buf)
+}
@@ -14,7 +14,12 @@ import (
 )
 
 type Config struct {
-	IgnoreTypes []string `config:"filesystem.ignore_types"`
+	IgnoreTypes        []string `config:"filesystem.ignore_types"`
+	IncludeTypes       []string `config:"filesystem.include_types"`
+	IncludeMountPoints []string `config:"filesystem.include_mount_points"`
+	ExcludeMountPoints []string `config:"filesystem.exclude_mount_points"`
+	IncludeLabels      []string `config:"filesystem.include_labels"`
+	ExcludeLabels      []string `config:"filesystem.exclude_labels"`
 }
 
 type FileSystemStat struct {
@@ -4,8 +4,11 @@
 package filesystem
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -61,3 +64,159 @@ func TestFilter(t *testing.T) {
 		assert.Equal(t, "ext4", out[0].SysTypeName)
 	}
 }
+
+func TestFilterFileSystems(t *testing.T) {
+	labels := map[string]string{
+		"/dev/sda1": "root",
+		"/dev/sdb1": "data",
+		"/dev/sdc1": "BACKUP DISK",
+	}
+
+	tests := []struct {
+		name     string
+		config   Config
+		expected []string
+	}{
+		{
+			name:   "no filters",
+			config: Config{},
+			expected: []string{"/sys", "/proc", "/dev", "/run", "/", "/dev/shm", "/boot",
+				"/data", "/media/backup", "/var/lib/docker/overlay2/2f5a/merged", "/mnt/nfs"},
+		},
+		{
+			name: "ignore types",
+			config: Config{
+				IgnoreTypes: []string{"sysfs", "proc", "devtmpfs", "tmpfs", "overlay"},
+			},
+			expected: []string{"/", "/boot", "/data", "/media/backup", "/mnt/nfs"},
+		},
+		{
+			name: "include types",
+			config: Config{
+				IncludeTypes: []string{"ext4", "xfs"},
+			},
+			expected: []string{"/", "/boot", "/data"},
+		},
+		{
+			name: "include and ignore types",
+			config: Config{
+				IncludeTypes: []string{"ext4", "xfs"},
+				IgnoreTypes:  []string{"xfs"},
+			},
+			expected: []string{"/", "/boot"},
+		},
+		{
+			name: "exclude mount points",
+			config: Config{
+				IncludeTypes:       []string{"ext4", "xfs", "vfat", "overlay"},
+				ExcludeMountPoints: []string{"/boot", "/var/lib/docker/*/*/merged"},
+			},
+			expected: []string{"/", "/data", "/media/backup"},
+		},
+		{
+			name: "include mount points",
+			config: Config{
+				IncludeMountPoints: []string{"/", "/media/*", "/mnt/*"},
+				ExcludeMountPoints: []string{"/mnt/nfs"},
+			},
+			expected: []string{"/", "/media/backup"},
+		},
+		{
+			name: "include labels",
+			config: Config{
+				IncludeLabels: []string{"root", "BACKUP*"},
+			},
+			expected: []string{"/", "/media/backup"},
+		},
+		{
+			name: "exclude labels",
+			config: Config{
+				IncludeTypes:  []string{"ext4", "xfs", "vfat"},
+				ExcludeLabels: []string{"data"},
+			},
+			expected: []string{"/", "/boot", "/media/backup"},
+		},
+	}
+
+	origDeviceLabels := deviceLabels
+	defer func() { deviceLabels = origDeviceLabels }()
+	deviceLabels = func() map[string]string { return labels }
+
+	for _, test := range tests {
+		fss := test.config.FilterFileSystems(readMountTable(t, "testdata/mounts"))
+
+		var mounts []string
+		for _, fs := range fss {
+			mounts = append(mounts, fs.DirName)
+		}
+		assert.Equal(t, test.expected, mounts, test.name)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.NoError(t, (&Config{IncludeMountPoints: []string{"/var/*"}}).Validate())
+	assert.Error(t, (&Config{ExcludeMountPoints: []string{"/var/["}}).Validate())
+	assert.Error(t, (&Config{IncludeLabels: []string{"[a-"}}).Validate())
+}
+
+func TestGetDeviceLabels(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Symlinks are not supported on Windows")
+	}
+
+	tmp, err := ioutil.TempDir("", "filesystem-labels")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	devices := filepath.Join(tmp, "dev")
+	byLabel := filepath.Join(tmp, "by-label")
+	for _, dir := range []string{devices, byLabel} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, dev := range []string{"sda1", "sdb1"} {
+		if err := ioutil.WriteFile(filepath.Join(devices, dev), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("../dev/sda1", filepath.Join(byLabel, "root")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../dev/sdb1", filepath.Join(byLabel, `BACKUP\x20DISK`)); err != nil {
+		t.Fatal(err)
+	}
+
+	labels := getDeviceLabels(byLabel)
+	assert.Equal(t, map[string]string{
+		resolveDevice(filepath.Join(devices, "sda1")): "root",
+		resolveDevice(filepath.Join(devices, "sdb1")): "BACKUP DISK",
+	}, labels)
+
+	assert.Nil(t, getDeviceLabels(filepath.Join(tmp, "notexistent")))
+}
+
+// readMountTable parses a mount table in the /proc/mounts format.
+func readMountTable(t *testing.T, path string) []sigar.FileSystem {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fss []sigar.FileSystem
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		fss = append(fss, sigar.FileSystem{
+			DevName:     fields[0],
+			DirName:     fields[1],
+			SysTypeName: fields[2],
+			Options:     fields[3],
+		})
+	}
+	return fss
+}
@@ -47,9 +47,7 @@ func (m *MetricSet) Fetch() (common.MapStr, error) {
 		return nil, errors.Wrap(err, "filesystem list")
 	}
 
-	if len(m.config.IgnoreTypes) > 0 {
-		fss = filesystem.Filter(fss, filesystem.BuildTypeFilter(m.config.IgnoreTypes...))
-	}
+	fss = m.config.FilterFileSystems(fss)
 
 	// These values are optional and could also be calculated by Kibana
 	var totalFiles, totalSize, totalSizeFree, totalSizeUsed uint64
@@ -0,0 +1,11 @@
+package gpu
+
+// Config is the configuration specific to the gpu MetricSet.
+type Config struct {
+	// Library is the name or path of the NVML shared library.
+	Library string `config:"gpu.library"`
+}
+
+var defaultConfig = Config{
+	Library: defaultLibrary,
+}
@@ -0,0 +1,7 @@
+/*
+Package gpu collects the utilization, memory, temperature and power usage of
+NVIDIA GPUs with the NVIDIA Management Library (NVML). The library is loaded
+when the metricset is created, the metricset is disabled if it is not
+installed.
+*/
+package gpu
@@ -0,0 +1,179 @@
+package gpu
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/mb"
+	"github.com/elastic/beats/metricbeat/mb/parse"
+	"github.com/elastic/beats/metricbeat/module/system"
+)
+
+var debugf = logp.MakeDebug("system.gpu")
+
+var (
+	// errUnavailable indicates that NVML is not installed, or that no NVIDIA
+	// driver is loaded.
+	errUnavailable = errors.New("NVML is not available")
+
+	// errNotSupported indicates a metric not supported by a GPU.
+	errNotSupported = errors.New("not supported by the GPU")
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("system", "gpu", New, parse.EmptyHostParser); err != nil {
+		panic(err)
+	}
+}
+
+// library is the part of NVML used by the metricset. Devices are addressed by
+// their index.
+type library interface {
+	DeviceCount() (int, error)
+	Name(index int) (string, error)
+	UUID(index int) (string, error)
+	// Utilization returns the percentage of time the GPU and its memory
+	// controller were busy.
+	Utilization(index int) (gpu, memory uint, err error)
+	// Memory returns the total and used memory in bytes.
+	Memory(index int) (total, used uint64, err error)
+	// Temperature returns the temperature of the GPU in degrees Celsius.
+	Temperature(index int) (uint, error)
+	// Power returns the power usage and limit in milliwatts.
+	Power(index int) (usage, limit uint, err error)
+	Shutdown() error
+}
+
+// openLibrary loads and initializes NVML, it fails with errUnavailable if
+// NVML can not be used on this host.
+var openLibrary = openNVML
+
+// MetricSet reports the metrics of each NVIDIA GPU.
+type MetricSet struct {
+	mb.BaseMetricSet
+	nvml library
+}
+
+// New creates a new instance of the gpu MetricSet. If NVML is not available,
+// the metricset is created disabled, so the other metricsets keep running on
+// hosts without NVIDIA GPUs.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	cfgwarn.Experimental("The system gpu metricset is experimental")
+
+	c := defaultConfig
+	if err := base.Module().UnpackConfig(&c); err != nil {
+		return nil, err
+	}
+
+	nvml, err := openLibrary(c.Library)
+	if err != nil {
+		if errors.Cause(err) != errUnavailable {
+			return nil, errors.Wrap(err, "failed to initialize NVML")
+		}
+		logp.Warn("The system gpu metricset is disabled: %v", err)
+		nvml = nil
+	}
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		nvml:          nvml,
+	}, nil
+}
+
+// Fetch returns an event for each GPU. No events are returned if the
+// metricset is disabled. Metrics not supported by a GPU are omitted.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	if m.nvml == nil {
+		return nil, nil
+	}
+	return fetchDevices(m.nvml)
+}
+
+// Close shuts NVML down.
+func (m *MetricSet) Close() error {
+	if m.nvml == nil {
+		return nil
+	}
+	err := m.nvml.Shutdown()
+	m.nvml = nil
+	return err
+}
+
+func fetchDevices(nvml library) ([]common.MapStr, error) {
+	count, err := nvml.DeviceCount()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the number of GPUs")
+	}
+
+	events := make([]common.MapStr, 0, count)
+	for i := 0; i < count; i++ {
+		events = append(events, fetchDevice(nvml, i))
+	}
+	return events, nil
+}
+
+func fetchDevice(nvml library, index int) common.MapStr {
+	event := common.MapStr{"index": index}
+
+	// failed reports if getting a metric failed. Unsupported metrics are
+	// omitted silently.
+	failed := func(metric string, err error) bool {
+		if err == nil {
+			return false
+		}
+		if err != errNotSupported {
+			debugf("failed to get the %v of GPU %v: %v", metric, index, err)
+		}
+		return true
+	}
+
+	if name, err := nvml.Name(index); !failed("name", err) {
+		event["name"] = name
+	}
+	if uuid, err := nvml.UUID(index); !failed("UUID", err) {
+		event["uuid"] = uuid
+	}
+
+	if gpu, memory, err := nvml.Utilization(index); !failed("utilization", err) {
+		event["utilization"] = common.MapStr{
+			"gpu":    common.MapStr{"pct": percent(float64(gpu))},
+			"memory": common.MapStr{"pct": percent(float64(memory))},
+		}
+	}
+
+	if total, used, err := nvml.Memory(index); !failed("memory", err) {
+		memory := common.MapStr{
+			"total": common.MapStr{"bytes": total},
+			"used":  common.MapStr{"bytes": used},
+		}
+		if total > 0 {
+			memory.Put("used.pct", system.Round(float64(used)/float64(total)))
+		}
+		event["memory"] = memory
+	}
+
+	if temperature, err := nvml.Temperature(index); !failed("temperature", err) {
+		event["temperature"] = common.MapStr{"celsius": temperature}
+	}
+
+	if usage, limit, err := nvml.Power(index); !failed("power", err) {
+		power := common.MapStr{"usage": common.MapStr{"watts": watts(usage)}}
+		if limit > 0 {
+			power["limit"] = common.MapStr{"watts": watts(limit)}
+		}
+		event["power"] = power
+	}
+	return event
+}
+
+// percent converts a percentage reported by NVML to the fraction reported by
+// Metricbeat.
+func percent(value float64) float64 {
+	return system.Round(value / 100)
+}
+
+func watts(milliwatts uint) float64 {
+	return system.Round(float64(milliwatts) / 1000)
+}
@@ -0,0 +1,172 @@
+package gpu
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+)
+
+// fakeDevice holds the metrics of a GPU returned by fakeNVML, nil errors
+// return the metric.
+type fakeDevice struct {
+	name, uuid              string
+	gpuUtil, memUtil        uint
+	memTotal, memUsed       uint64
+	temperature             uint
+	powerUsage, powerCap    uint
+	utilErr, powerErr       error
+	temperatureErr, uuidErr error
+}
+
+// fakeNVML is a mocked NVML layer.
+type fakeNVML struct {
+	devices  []fakeDevice
+	countErr error
+	shutdown bool
+}
+
+func (f *fakeNVML) DeviceCount() (int, error)  { return len(f.devices), f.countErr }
+func (f *fakeNVML) Name(i int) (string, error) { return f.devices[i].name, nil }
+func (f *fakeNVML) UUID(i int) (string, error) { return f.devices[i].uuid, f.devices[i].uuidErr }
+
+func (f *fakeNVML) Utilization(i int) (uint, uint, error) {
+	d := f.devices[i]
+	return d.gpuUtil, d.memUtil, d.utilErr
+}
+
+func (f *fakeNVML) Memory(i int) (uint64, uint64, error) {
+	d := f.devices[i]
+	return d.memTotal, d.memUsed, nil
+}
+
+func (f *fakeNVML) Temperature(i int) (uint, error) {
+	d := f.devices[i]
+	return d.temperature, d.temperatureErr
+}
+
+func (f *fakeNVML) Power(i int) (uint, uint, error) {
+	d := f.devices[i]
+	return d.powerUsage, d.powerCap, d.powerErr
+}
+
+func (f *fakeNVML) Shutdown() error {
+	f.shutdown = true
+	return nil
+}
+
+var testConfig = map[string]interface{}{
+	"module":     "system",
+	"metricsets": []string{"gpu"},
+}
+
+// withLibrary replaces the NVML library opened by the metricset.
+func withLibrary(t *testing.T, nvml library, err error) func() {
+	original := openLibrary
+	openLibrary = func(path string) (library, error) {
+		assert.Equal(t, defaultLibrary, path)
+		if err != nil {
+			return nil, err
+		}
+		return nvml, nil
+	}
+	return func() { openLibrary = original }
+}
+
+func TestFetchPerGPU(t *testing.T) {
+	fake := &fakeNVML{devices: []fakeDevice{
+		{
+			name: "Tesla V100-SXM2-16GB", uuid: "GPU-6f1a9e3c",
+			gpuUtil: 85, memUtil: 40,
+			memTotal: 16 << 30, memUsed: 4 << 30,
+			temperature: 65,
+			powerUsage:  250500, powerCap: 300000,
+		},
+		{
+			name: "GeForce GTX 1080", uuid: "GPU-0c2d7b41",
+			gpuUtil: 0, memUtil: 0,
+			memTotal: 8 << 30, memUsed: 0,
+			temperature: 40,
+			powerErr:    errNotSupported,
+		},
+	}}
+	defer withLibrary(t, fake, nil)()
+
+	f := mbtest.NewEventsFetcher(t, testConfig)
+	events, err := f.Fetch()
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	assert.Equal(t, common.MapStr{
+		"index": 0,
+		"name":  "Tesla V100-SXM2-16GB",
+		"uuid":  "GPU-6f1a9e3c",
+		"utilization": common.MapStr{
+			"gpu":    common.MapStr{"pct": 0.85},
+			"memory": common.MapStr{"pct": 0.4},
+		},
+		"memory": common.MapStr{
+			"total": common.MapStr{"bytes": uint64(16 << 30)},
+			"used":  common.MapStr{"bytes": uint64(4 << 30), "pct": 0.25},
+		},
+		"temperature": common.MapStr{"celsius": uint(65)},
+		"power": common.MapStr{
+			"usage": common.MapStr{"watts": 250.5},
+			"limit": common.MapStr{"watts": 300.0},
+		},
+	}, events[0])
+
+	// unsupported metrics are omitted
+	assert.Equal(t, 1, events[1]["index"])
+	assert.NotContains(t, events[1], "power")
+	assert.Equal(t, common.MapStr{"celsius": uint(40)}, events[1]["temperature"])
+
+	require.NoError(t, f.(*MetricSet).Close())
+	assert.True(t, fake.shutdown)
+}
+
+func TestFetchMetricErrors(t *testing.T) {
+	fake := &fakeNVML{devices: []fakeDevice{
+		{
+			name:           "Tesla T4",
+			uuidErr:        errors.New("GPU is lost"),
+			utilErr:        errNotSupported,
+			temperatureErr: errors.New("unknown error"),
+			memTotal:       16 << 30,
+			powerUsage:     70000,
+		},
+	}}
+
+	events, err := fetchDevices(fake)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	// the metrics failing are omitted, the others still reported
+	assert.Equal(t, common.MapStr{
+		"index": 0,
+		"name":  "Tesla T4",
+		"memory": common.MapStr{
+			"total": common.MapStr{"bytes": uint64(16 << 30)},
+			"used":  common.MapStr{"bytes": uint64(0), "pct": 0.0},
+		},
+		"power": common.MapStr{"usage": common.MapStr{"watts": 70.0}},
+	}, events[0])
+
+	fake.countErr = errors.New("driver error")
+	_, err = fetchDevices(fake)
+	assert.Error(t, err)
+}
+
+func TestDisabledWithoutNVML(t *testing.T) {
+	defer withLibrary(t, nil, errUnavailable)()
+
+	f := mbtest.NewEventsFetcher(t, testConfig)
+	events, err := f.Fetch()
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+	assert.NoError(t, f.(*MetricSet).Close())
+}
@@ -0,0 +1,246 @@
+// +build linux,cgo
+
+package gpu
+
+/*
+#cgo LDFLAGS: -ldl
+
+#include <dlfcn.h>
+#include <stdlib.h>
+
+// The types and functions of nvml.h used by the metricset. NVML is loaded with
+// dlopen, so the beat does not depend on it.
+typedef int nvmlReturn_t;
+typedef void *nvmlDevice_t;
+typedef struct { unsigned int gpu; unsigned int memory; } nvmlUtilization_t;
+typedef struct { unsigned long long total; unsigned long long free; unsigned long long used; } nvmlMemory_t;
+
+#define NVML_TEMPERATURE_GPU 0
+
+static nvmlReturn_t (*nvmlInit_v2_f)(void);
+static nvmlReturn_t (*nvmlShutdown_f)(void);
+static const char *(*nvmlErrorString_f)(nvmlReturn_t);
+static nvmlReturn_t (*nvmlDeviceGetCount_v2_f)(unsigned int *);
+static nvmlReturn_t (*nvmlDeviceGetHandleByIndex_v2_f)(unsigned int, nvmlDevice_t *);
+static nvmlReturn_t (*nvmlDeviceGetName_f)(nvmlDevice_t, char *, unsigned int);
+static nvmlReturn_t (*nvmlDeviceGetUUID_f)(nvmlDevice_t, char *, unsigned int);
+static nvmlReturn_t (*nvmlDeviceGetUtilizationRates_f)(nvmlDevice_t, nvmlUtilization_t *);
+static nvmlReturn_t (*nvmlDeviceGetMemoryInfo_f)(nvmlDevice_t, nvmlMemory_t *);
+static nvmlReturn_t (*nvmlDeviceGetTemperature_f)(nvmlDevice_t, int, unsigned int *);
+static nvmlReturn_t (*nvmlDeviceGetPowerUsage_f)(nvmlDevice_t, unsigned int *);
+static nvmlReturn_t (*nvmlDeviceGetEnforcedPowerLimit_f)(nvmlDevice_t, unsigned int *);
+
+// nvml_load loads the library and its functions. It returns the error of
+// dlopen or dlsym, or NULL on success.
+static const char *nvml_load(const char *path) {
+	void *handle = dlopen(path, RTLD_LAZY);
+	if (!handle) {
+		return dlerror();
+	}
+
+#define NVML_SYM(name) \
+	*(void **)(&name##_f) = dlsym(handle, #name); \
+	if (!name##_f) { \
+		const char *err = dlerror(); \
+		dlclose(handle); \
+		return err; \
+	}
+
+	NVML_SYM(nvmlInit_v2)
+	NVML_SYM(nvmlShutdown)
+	NVML_SYM(nvmlErrorString)
+	NVML_SYM(nvmlDeviceGetCount_v2)
+	NVML_SYM(nvmlDeviceGetHandleByIndex_v2)
+	NVML_SYM(nvmlDeviceGetName)
+	NVML_SYM(nvmlDeviceGetUUID)
+	NVML_SYM(nvmlDeviceGetUtilizationRates)
+	NVML_SYM(nvmlDeviceGetMemoryInfo)
+	NVML_SYM(nvmlDeviceGetTemperature)
+	NVML_SYM(nvmlDeviceGetPowerUsage)
+	NVML_SYM(nvmlDeviceGetEnforcedPowerLimit)
+#undef NVML_SYM
+	return NULL;
+}
+
+// Function pointers can not be called from Go, so they are called by these
+// wrappers.
+static nvmlReturn_t nvml_init(void) { return nvmlInit_v2_f(); }
+static nvmlReturn_t nvml_shutdown(void) { return nvmlShutdown_f(); }
+static const char *nvml_error_string(nvmlReturn_t ret) { return nvmlErrorString_f(ret); }
+static nvmlReturn_t nvml_device_count(unsigned int *count) { return nvmlDeviceGetCount_v2_f(count); }
+
+static nvmlReturn_t nvml_device_name(unsigned int index, char *name, unsigned int length) {
+	nvmlDevice_t device;
+	nvmlReturn_t ret = nvmlDeviceGetHandleByIndex_v2_f(index, &device);
+	return ret ? ret : nvmlDeviceGetName_f(device, name, length);
+}
+
+static nvmlReturn_t nvml_device_uuid(unsigned int index, char *uuid, unsigned int length) {
+	nvmlDevice_t device;
+	nvmlReturn_t ret = nvmlDeviceGetHandleByIndex_v2_f(index, &device);
+	return ret ? ret : nvmlDeviceGetUUID_f(device, uuid, length);
+}
+
+static nvmlReturn_t nvml_device_utilization(unsigned int index, nvmlUtilization_t *utilization) {
+	nvmlDevice_t device;
+	nvmlReturn_t ret = nvmlDeviceGetHandleByIndex_v2_f(index, &device);
+	return ret ? ret : nvmlDeviceGetUtilizationRates_f(device, utilization);
+}
+
+static nvmlReturn_t nvml_device_memory(unsigned int index, nvmlMemory_t *memory) {
+	nvmlDevice_t device;
+	nvmlReturn_t ret = nvmlDeviceGetHandleByIndex_v2_f(index, &device);
+	return ret ? ret : nvmlDeviceGetMemoryInfo_f(device, memory);
+}
+
+static nvmlReturn_t nvml_device_temperature(unsigned int index, unsigned int *temperature) {
+	nvmlDevice_t device;
+	nvmlReturn_t ret = nvmlDeviceGetHandleByIndex_v2_f(index, &device);
+	return ret ? ret : nvmlDeviceGetTemperature_f(device, NVML_TEMPERATURE_GPU, temperature);
+}
+
+static nvmlReturn_t nvml_device_power(unsigned int index, unsigned int *usage, unsigned int *limit) {
+	nvmlDevice_t device;
+	nvmlReturn_t ret = nvmlDeviceGetHandleByIndex_v2_f(index, &device);
+	if (ret) {
+		return ret;
+	}
+	ret = nvmlDeviceGetPowerUsage_f(device, usage);
+	if (ret) {
+		return ret;
+	}
+	// The limit is optional, it is not reported if not supported.
+	if (nvmlDeviceGetEnforcedPowerLimit_f(device, limit)) {
+		*limit = 0;
+	}
+	return 0;
+}
+*/
+import "C"
+
+import (
+	"sync"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+const defaultLibrary = "libnvml.so.1"
+
+// Return codes of NVML.
+const (
+	nvmlSuccess            = 0
+	nvmlErrorNotSupported  = 3
+	nvmlErrorDriverMissing = 9
+	nvmlErrorLibNotFound   = 12
+)
+
+const (
+	nvmlNameBufferSize = 96
+	nvmlUUIDBufferSize = 80
+)
+
+// The library is loaded once, from the path configured for the first
+// metricset. NVML counts the calls of nvmlInit and nvmlShutdown itself.
+var (
+	loadOnce sync.Once
+	loadErr  error
+)
+
+// nvmlLibrary calls the functions of the shared NVML library.
+type nvmlLibrary struct{}
+
+func openNVML(path string) (library, error) {
+	loadOnce.Do(func() {
+		cpath := C.CString(path)
+		defer C.free(unsafe.Pointer(cpath))
+
+		if msg := C.nvml_load(cpath); msg != nil {
+			loadErr = errors.Wrapf(errUnavailable, "failed to load %v: %v", path, C.GoString(msg))
+		}
+	})
+	if loadErr != nil {
+		return nil, loadErr
+	}
+
+	if ret := C.nvml_init(); ret != nvmlSuccess {
+		err := nvmlError(ret)
+		if ret == nvmlErrorDriverMissing || ret == nvmlErrorLibNotFound {
+			return nil, errors.Wrap(errUnavailable, err.Error())
+		}
+		return nil, err
+	}
+	return nvmlLibrary{}, nil
+}
+
+// nvmlError converts an NVML return code to an error, errNotSupported for
+// metrics not supported by a GPU.
+func nvmlError(ret C.nvmlReturn_t) error {
+	switch ret {
+	case nvmlSuccess:
+		return nil
+	case nvmlErrorNotSupported:
+		return errNotSupported
+	}
+	return errors.Errorf("NVML error %v: %v", int(ret), C.GoString(C.nvml_error_string(ret)))
+}
+
+func (nvmlLibrary) DeviceCount() (int, error) {
+	var count C.uint
+	if err := nvmlError(C.nvml_device_count(&count)); err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+func (nvmlLibrary) Name(index int) (string, error) {
+	var name [nvmlNameBufferSize]C.char
+	if err := nvmlError(C.nvml_device_name(C.uint(index), &name[0], nvmlNameBufferSize)); err != nil {
+		return "", err
+	}
+	return C.GoString(&name[0]), nil
+}
+
+func (nvmlLibrary) UUID(index int) (string, error) {
+	var uuid [nvmlUUIDBufferSize]C.char
+	if err := nvmlError(C.nvml_device_uuid(C.uint(index), &uuid[0], nvmlUUIDBufferSize)); err != nil {
+		return "", err
+	}
+	return C.GoString(&uuid[0]), nil
+}
+
+func (nvmlLibrary) Utilization(index int) (uint, uint, error) {
+	var utilization C.nvmlUtilization_t
+	if err := nvmlError(C.nvml_device_utilization(C.uint(index), &utilization)); err != nil {
+		return 0, 0, err
+	}
+	return uint(utilization.gpu), uint(utilization.memory), nil
+}
+
+func (nvmlLibrary) Memory(index int) (uint64, uint64, error) {
+	var memory C.nvmlMemory_t
+	if err := nvmlError(C.nvml_device_memory(C.uint(index), &memory)); err != nil {
+		return 0, 0, err
+	}
+	return uint64(memory.total), uint64(memory.used), nil
+}
+
+func (nvmlLibrary) Temperature(index int) (uint, error) {
+	var temperature C.uint
+	if err := nvmlError(C.nvml_device_temperature(C.uint(index), &temperature)); err != nil {
+		return 0, err
+	}
+	return uint(temperature), nil
+}
+
+func (nvmlLibrary) Power(index int) (uint, uint, error) {
+	var usage, limit C.uint
+	if err := nvmlError(C.nvml_device_power(C.uint(index), &usage, &limit)); err != nil {
+		return 0, 0, err
+	}
+	return uint(usage), uint(limit), nil
+}
+
+func (nvmlLibrary) Shutdown() error {
+	return nvmlError(C.nvml_shutdown())
+}
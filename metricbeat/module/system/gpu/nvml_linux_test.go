@@ -0,0 +1,18 @@
+// +build linux,cgo
+
+package gpu
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenNVMLMissingLibrary(t *testing.T) {
+	_, err := openNVML("libnvml-missing.so.1")
+	if assert.Error(t, err) {
+		assert.Equal(t, errUnavailable, errors.Cause(err))
+		assert.Contains(t, err.Error(), "libnvml-missing.so.1")
+	}
+}
@@ -0,0 +1,10 @@
+// +build !linux !cgo
+
+package gpu
+
+const defaultLibrary = "libnvml.so.1"
+
+// openNVML fails with errUnavailable, NVML is only loaded on Linux.
+func openNVML(path string) (library, error) {
+	return nil, errUnavailable
+}
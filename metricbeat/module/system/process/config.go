@@ -22,7 +22,7 @@ type Config struct {
 
 func (c Config) Validate() error {
 	if c.CPUTicks != nil {
-		cfgwarn.Deprecate("6.1", "cpu_ticks is deprecated. Use process.include_cpu_ticks instead")
+		cfgwarn.DeprecateSetting("cpu_ticks", "process.include_cpu_ticks", "6.1")
 	}
 	return nil
 }
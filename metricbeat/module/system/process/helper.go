@@ -125,6 +125,20 @@ func (proc *Process) getDetails(envPredicate func(string) bool) error {
 	return nil
 }
 
+// GetProcess returns the state and the CPU, memory and file descriptor usage
+// of the process with the given pid. The environment variables of the process
+// are not collected.
+func GetProcess(pid int) (*Process, error) {
+	proc, err := newProcess(pid, "", common.MapStr{})
+	if err != nil {
+		return nil, err
+	}
+	if err := proc.getDetails(nil); err != nil {
+		return nil, err
+	}
+	return proc, nil
+}
+
 // getProcFDUsage returns file descriptor usage information for the process
 // identified by the given PID. If the feature is not implemented then nil
 // is returned with no error. If there is a permission error while reading the
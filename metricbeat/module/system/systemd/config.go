@@ -0,0 +1,27 @@
+package systemd
+
+import (
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// Config is the configuration specific to the systemd MetricSet.
+type Config struct {
+	// Units are glob patterns of the names of the units to report.
+	Units []string `config:"systemd.units"`
+}
+
+// Validate checks that the unit patterns are valid glob patterns.
+func (c *Config) Validate() error {
+	for _, pattern := range c.Units {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return errors.Wrapf(err, "invalid unit pattern '%v'", pattern)
+		}
+	}
+	return nil
+}
+
+var defaultConfig = Config{
+	Units: []string{"*.service"},
+}
@@ -0,0 +1,720 @@
+// +build linux
+
+package systemd
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// This file implements the parts of the D-Bus wire protocol required to call
+// the methods of systemd on the system bus: the EXTERNAL authentication
+// mechanism, and method calls with basic, array, struct, dict entry and
+// variant types. Unix file descriptors are not supported.
+
+const (
+	msgMethodCall   = 1
+	msgMethodReturn = 2
+	msgError        = 3
+
+	fieldPath        = 1
+	fieldInterface   = 2
+	fieldMember      = 3
+	fieldErrorName   = 4
+	fieldReplySerial = 5
+	fieldDestination = 6
+	fieldSignature   = 8
+
+	// maxMessageSize is the maximum size of a message defined by the D-Bus
+	// specification.
+	maxMessageSize = 1 << 27
+)
+
+// variant is a value of a D-Bus variant, with its signature.
+type variant struct {
+	sig   string
+	value interface{}
+}
+
+// message is a decoded D-Bus message.
+type message struct {
+	typ         byte
+	serial      uint32
+	replySerial uint32
+	member      string
+	errorName   string
+	body        []interface{}
+}
+
+// dbusError is an error reply to a method call.
+type dbusError struct {
+	name    string
+	message string
+}
+
+func (e *dbusError) Error() string {
+	if e.message == "" {
+		return e.name
+	}
+	return e.name + ": " + e.message
+}
+
+// dbusConn is a connection to a message bus.
+type dbusConn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	serial  uint32
+	timeout time.Duration
+}
+
+// dialDBus connects and authenticates to the bus at address, a D-Bus server
+// address like `unix:path=/var/run/dbus/system_bus_socket`.
+func dialDBus(address string, timeout time.Duration) (*dbusConn, error) {
+	var lastErr error
+	for _, addr := range strings.Split(address, ";") {
+		network, path, err := parseAddress(addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		conn, err := net.DialTimeout(network, path, timeout)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		c, err := newDBusConn(conn, timeout)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return c, nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no D-Bus address in '%v'", address)
+	}
+	return nil, lastErr
+}
+
+// parseAddress returns the network and path to dial for a unix socket
+// address. Abstract socket names are prefixed with `@`.
+func parseAddress(address string) (string, string, error) {
+	if !strings.HasPrefix(address, "unix:") {
+		return "", "", fmt.Errorf("unsupported D-Bus address '%v'", address)
+	}
+	for _, kv := range strings.Split(strings.TrimPrefix(address, "unix:"), ",") {
+		switch {
+		case strings.HasPrefix(kv, "path="):
+			return "unix", strings.TrimPrefix(kv, "path="), nil
+		case strings.HasPrefix(kv, "abstract="):
+			return "unix", "@" + strings.TrimPrefix(kv, "abstract="), nil
+		}
+	}
+	return "", "", fmt.Errorf("unsupported D-Bus address '%v'", address)
+}
+
+// newDBusConn authenticates on conn and registers with the bus.
+func newDBusConn(conn net.Conn, timeout time.Duration) (*dbusConn, error) {
+	c := &dbusConn{
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		timeout: timeout,
+	}
+	if err := c.auth(); err != nil {
+		return nil, err
+	}
+	if _, err := c.Call("org.freedesktop.DBus", "/org/freedesktop/DBus", "org.freedesktop.DBus", "Hello", ""); err != nil {
+		return nil, errors.Wrap(err, "D-Bus Hello failed")
+	}
+	return c, nil
+}
+
+func (c *dbusConn) auth() error {
+	c.setDeadline()
+
+	uid := hex.EncodeToString([]byte(strconv.Itoa(os.Getuid())))
+	if _, err := io.WriteString(c.conn, "\x00AUTH EXTERNAL "+uid+"\r\n"); err != nil {
+		return err
+	}
+
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "OK ") {
+		return fmt.Errorf("D-Bus authentication failed: %v", strings.TrimSpace(line))
+	}
+
+	_, err = io.WriteString(c.conn, "BEGIN\r\n")
+	return err
+}
+
+func (c *dbusConn) setDeadline() {
+	if c.timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+}
+
+// Call calls a method and returns the values of the reply. The arguments
+// are encoded with the signature sig.
+func (c *dbusConn) Call(dest, path, iface, member, sig string, args ...interface{}) ([]interface{}, error) {
+	c.serial++
+	serial := c.serial
+
+	fields := []interface{}{
+		[]interface{}{byte(fieldPath), variant{"o", path}},
+		[]interface{}{byte(fieldMember), variant{"s", member}},
+		[]interface{}{byte(fieldDestination), variant{"s", dest}},
+	}
+	if iface != "" {
+		fields = append(fields, []interface{}{byte(fieldInterface), variant{"s", iface}})
+	}
+	msg, err := encodeMessage(msgMethodCall, serial, fields, sig, args)
+	if err != nil {
+		return nil, err
+	}
+
+	c.setDeadline()
+	if _, err := c.conn.Write(msg); err != nil {
+		return nil, err
+	}
+
+	for {
+		reply, err := readMessage(c.reader)
+		if err != nil {
+			return nil, err
+		}
+		if reply.replySerial != serial {
+			// signals and replies to other calls
+			continue
+		}
+
+		switch reply.typ {
+		case msgMethodReturn:
+			return reply.body, nil
+		case msgError:
+			e := &dbusError{name: reply.errorName}
+			if len(reply.body) > 0 {
+				e.message, _ = reply.body[0].(string)
+			}
+			return nil, e
+		default:
+			return nil, fmt.Errorf("unexpected D-Bus message type %v", reply.typ)
+		}
+	}
+}
+
+// Close closes the connection.
+func (c *dbusConn) Close() error {
+	return c.conn.Close()
+}
+
+// encodeMessage encodes a message with the header fields and the body args
+// encoded with the signature sig.
+func encodeMessage(typ byte, serial uint32, fields []interface{}, sig string, args []interface{}) ([]byte, error) {
+	body := &encoder{}
+	types, err := splitSignature(sig)
+	if err != nil {
+		return nil, err
+	}
+	if len(types) != len(args) {
+		return nil, fmt.Errorf("signature '%v' does not match %v arguments", sig, len(args))
+	}
+	for i, t := range types {
+		if err := body.encode(t, args[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	if sig != "" {
+		fields = append(fields, []interface{}{byte(fieldSignature), variant{"g", sig}})
+	}
+
+	e := &encoder{}
+	e.buf = append(e.buf, 'l', typ, 0, 1)
+	e.uint32(uint32(len(body.buf)))
+	e.uint32(serial)
+	if err := e.encode("a(yv)", fields); err != nil {
+		return nil, err
+	}
+	e.align(8)
+	return append(e.buf, body.buf...), nil
+}
+
+// readMessage reads and decodes the next message.
+func readMessage(r io.Reader) (*message, error) {
+	fixed := make([]byte, 16)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, err
+	}
+
+	var order binary.ByteOrder
+	switch fixed[0] {
+	case 'l':
+		order = binary.LittleEndian
+	case 'B':
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("invalid D-Bus message endianness %q", fixed[0])
+	}
+
+	bodyLen := int64(order.Uint32(fixed[4:8]))
+	headerLen := int64(16 + order.Uint32(fixed[12:16]))
+	headerLen += (8 - headerLen%8) % 8
+	if headerLen+bodyLen > maxMessageSize {
+		return nil, errors.New("D-Bus message exceeds the maximum size")
+	}
+
+	rest := make([]byte, headerLen-16+bodyLen)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, err
+	}
+	header := append(fixed, rest[:headerLen-16]...)
+	body := rest[headerLen-16:]
+
+	msg := &message{
+		typ:    fixed[1],
+		serial: order.Uint32(fixed[8:12]),
+	}
+
+	d := &decoder{buf: header, pos: 12, order: order}
+	v, err := d.decode("a(yv)")
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid D-Bus message header")
+	}
+
+	var sig string
+	for _, f := range v.([]interface{}) {
+		field := f.([]interface{})
+		switch field[0].(byte) {
+		case fieldReplySerial:
+			msg.replySerial, _ = field[1].(uint32)
+		case fieldMember:
+			msg.member, _ = field[1].(string)
+		case fieldErrorName:
+			msg.errorName, _ = field[1].(string)
+		case fieldSignature:
+			sig, _ = field[1].(string)
+		}
+	}
+
+	types, err := splitSignature(sig)
+	if err != nil {
+		return nil, err
+	}
+	d = &decoder{buf: body, order: order}
+	for _, t := range types {
+		v, err := d.decode(t)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid D-Bus message body")
+		}
+		msg.body = append(msg.body, v)
+	}
+	return msg, nil
+}
+
+// splitSignature splits a signature into its complete types.
+func splitSignature(sig string) ([]string, error) {
+	var types []string
+	for sig != "" {
+		n, err := typeLen(sig)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, sig[:n])
+		sig = sig[n:]
+	}
+	return types, nil
+}
+
+// typeLen returns the length of the first complete type of the signature.
+func typeLen(sig string) (int, error) {
+	if sig == "" {
+		return 0, errors.New("incomplete D-Bus signature")
+	}
+
+	switch sig[0] {
+	case 'y', 'b', 'n', 'q', 'i', 'u', 'x', 't', 'd', 's', 'o', 'g', 'v':
+		return 1, nil
+	case 'a':
+		n, err := typeLen(sig[1:])
+		return n + 1, err
+	case '(', '{':
+		end := byte(')')
+		if sig[0] == '{' {
+			end = '}'
+		}
+		i := 1
+		for i < len(sig) && sig[i] != end {
+			n, err := typeLen(sig[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+		if i >= len(sig) {
+			return 0, fmt.Errorf("unterminated D-Bus signature '%v'", sig)
+		}
+		return i + 1, nil
+	}
+	return 0, fmt.Errorf("unsupported D-Bus type '%c'", sig[0])
+}
+
+// alignment returns the alignment of values of the type.
+func alignment(t byte) int {
+	switch t {
+	case 'n', 'q':
+		return 2
+	case 'b', 'i', 'u', 's', 'o', 'a':
+		return 4
+	case 'x', 't', 'd', '(', '{':
+		return 8
+	}
+	return 1
+}
+
+// encoder encodes values in little endian byte order. Values are aligned
+// relative to the start of the buffer.
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) align(n int) {
+	for len(e.buf)%n != 0 {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *encoder) uint32(v uint32) {
+	e.align(4)
+	e.buf = append(e.buf, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(e.buf[len(e.buf)-4:], v)
+}
+
+func (e *encoder) uint64(v uint64) {
+	e.align(8)
+	e.buf = append(e.buf, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint64(e.buf[len(e.buf)-8:], v)
+}
+
+// encode encodes v as a value of the complete type t.
+func (e *encoder) encode(t string, v interface{}) error {
+	mismatch := func() error {
+		return fmt.Errorf("can not encode %T as D-Bus type '%v'", v, t)
+	}
+
+	switch t[0] {
+	case 'y':
+		b, ok := v.(byte)
+		if !ok {
+			return mismatch()
+		}
+		e.buf = append(e.buf, b)
+	case 'b':
+		b, ok := v.(bool)
+		if !ok {
+			return mismatch()
+		}
+		if b {
+			e.uint32(1)
+		} else {
+			e.uint32(0)
+		}
+	case 'u':
+		u, ok := v.(uint32)
+		if !ok {
+			return mismatch()
+		}
+		e.uint32(u)
+	case 'i':
+		i, ok := v.(int32)
+		if !ok {
+			return mismatch()
+		}
+		e.uint32(uint32(i))
+	case 't':
+		u, ok := v.(uint64)
+		if !ok {
+			return mismatch()
+		}
+		e.uint64(u)
+	case 'x':
+		i, ok := v.(int64)
+		if !ok {
+			return mismatch()
+		}
+		e.uint64(uint64(i))
+	case 's', 'o':
+		s, ok := v.(string)
+		if !ok {
+			return mismatch()
+		}
+		e.uint32(uint32(len(s)))
+		e.buf = append(e.buf, s...)
+		e.buf = append(e.buf, 0)
+	case 'g':
+		s, ok := v.(string)
+		if !ok || len(s) > 255 {
+			return mismatch()
+		}
+		e.buf = append(e.buf, byte(len(s)))
+		e.buf = append(e.buf, s...)
+		e.buf = append(e.buf, 0)
+	case 'v':
+		vv, ok := v.(variant)
+		if !ok {
+			return mismatch()
+		}
+		if err := e.encode("g", vv.sig); err != nil {
+			return err
+		}
+		return e.encode(vv.sig, vv.value)
+	case 'a':
+		var elems []interface{}
+		switch a := v.(type) {
+		case []interface{}:
+			elems = a
+		case []string:
+			for _, s := range a {
+				elems = append(elems, s)
+			}
+		default:
+			return mismatch()
+		}
+
+		e.uint32(0)
+		lenPos := len(e.buf) - 4
+		e.align(alignment(t[1]))
+		start := len(e.buf)
+		for _, elem := range elems {
+			if err := e.encode(t[1:], elem); err != nil {
+				return err
+			}
+		}
+		binary.LittleEndian.PutUint32(e.buf[lenPos:], uint32(len(e.buf)-start))
+	case '(', '{':
+		fields, ok := v.([]interface{})
+		if !ok {
+			return mismatch()
+		}
+		types, err := splitSignature(t[1 : len(t)-1])
+		if err != nil {
+			return err
+		}
+		if len(types) != len(fields) {
+			return mismatch()
+		}
+		e.align(8)
+		for i, ft := range types {
+			if err := e.encode(ft, fields[i]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported D-Bus type '%v'", t)
+	}
+	return nil
+}
+
+// decoder decodes values. Values are aligned relative to the start of the
+// buffer.
+type decoder struct {
+	buf   []byte
+	pos   int
+	order binary.ByteOrder
+}
+
+func (d *decoder) align(n int) error {
+	if rem := d.pos % n; rem != 0 {
+		d.pos += n - rem
+	}
+	if d.pos > len(d.buf) {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+func (d *decoder) read(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *decoder) uint32() (uint32, error) {
+	if err := d.align(4); err != nil {
+		return 0, err
+	}
+	b, err := d.read(4)
+	if err != nil {
+		return 0, err
+	}
+	return d.order.Uint32(b), nil
+}
+
+func (d *decoder) uint64() (uint64, error) {
+	if err := d.align(8); err != nil {
+		return 0, err
+	}
+	b, err := d.read(8)
+	if err != nil {
+		return 0, err
+	}
+	return d.order.Uint64(b), nil
+}
+
+// string reads n bytes followed by a nul byte.
+func (d *decoder) string(n int) (string, error) {
+	b, err := d.read(n + 1)
+	if err != nil {
+		return "", err
+	}
+	return string(b[:n]), nil
+}
+
+// decode decodes a value of the complete type t. Arrays and structs are
+// returned as []interface{}, arrays of dict entries as
+// map[string]interface{}, and variants as their value.
+func (d *decoder) decode(t string) (interface{}, error) {
+	switch t[0] {
+	case 'y':
+		b, err := d.read(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0], nil
+	case 'b':
+		u, err := d.uint32()
+		return u != 0, err
+	case 'n', 'q':
+		if err := d.align(2); err != nil {
+			return nil, err
+		}
+		b, err := d.read(2)
+		if err != nil {
+			return nil, err
+		}
+		if t[0] == 'n' {
+			return int16(d.order.Uint16(b)), nil
+		}
+		return d.order.Uint16(b), nil
+	case 'i':
+		u, err := d.uint32()
+		return int32(u), err
+	case 'u':
+		return d.uint32()
+	case 'x':
+		u, err := d.uint64()
+		return int64(u), err
+	case 't':
+		return d.uint64()
+	case 'd':
+		u, err := d.uint64()
+		return math.Float64frombits(u), err
+	case 's', 'o':
+		n, err := d.uint32()
+		if err != nil {
+			return nil, err
+		}
+		return d.string(int(n))
+	case 'g':
+		b, err := d.read(1)
+		if err != nil {
+			return nil, err
+		}
+		return d.string(int(b[0]))
+	case 'v':
+		sig, err := d.decode("g")
+		if err != nil {
+			return nil, err
+		}
+		if n, err := typeLen(sig.(string)); err != nil || n != len(sig.(string)) {
+			return nil, fmt.Errorf("invalid D-Bus variant signature '%v'", sig)
+		}
+		return d.decode(sig.(string))
+	case 'a':
+		return d.decodeArray(t)
+	case '(':
+		types, err := splitSignature(t[1 : len(t)-1])
+		if err != nil {
+			return nil, err
+		}
+		if err := d.align(8); err != nil {
+			return nil, err
+		}
+		fields := make([]interface{}, 0, len(types))
+		for _, ft := range types {
+			v, err := d.decode(ft)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, v)
+		}
+		return fields, nil
+	}
+	return nil, fmt.Errorf("unsupported D-Bus type '%v'", t)
+}
+
+func (d *decoder) decodeArray(t string) (interface{}, error) {
+	n, err := d.uint32()
+	if err != nil {
+		return nil, err
+	}
+	if err := d.align(alignment(t[1])); err != nil {
+		return nil, err
+	}
+	end := d.pos + int(n)
+	if end > len(d.buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	if t[1] != '{' {
+		elems := []interface{}{}
+		for d.pos < end {
+			v, err := d.decode(t[1:])
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, v)
+		}
+		return elems, nil
+	}
+
+	types, err := splitSignature(t[2 : len(t)-1])
+	if err != nil {
+		return nil, err
+	}
+	if len(types) != 2 || !strings.ContainsAny(types[0], "sog") {
+		return nil, fmt.Errorf("unsupported D-Bus dict type '%v'", t)
+	}
+	dict := map[string]interface{}{}
+	for d.pos < end {
+		if err := d.align(8); err != nil {
+			return nil, err
+		}
+		k, err := d.decode(types[0])
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.decode(types[1])
+		if err != nil {
+			return nil, err
+		}
+		dict[k.(string)] = v
+	}
+	return dict, nil
+}
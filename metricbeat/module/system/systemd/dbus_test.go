@@ -0,0 +1,202 @@
+// +build linux
+
+package systemd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	sig := "ybuitxsoga(ssou)a{sv}"
+	args := []interface{}{
+		byte(7),
+		true,
+		uint32(42),
+		int32(-42),
+		uint64(1 << 40),
+		int64(-1 << 40),
+		"nginx.service",
+		"/org/freedesktop/systemd1/unit/nginx_2eservice",
+		"a{sv}",
+		[]interface{}{
+			[]interface{}{"a.service", "active", "/a", uint32(1)},
+			[]interface{}{"b.service", "inactive", "/b", uint32(2)},
+		},
+		[]interface{}{
+			[]interface{}{"MainPID", variant{"u", uint32(1234)}},
+			[]interface{}{"MemoryCurrent", variant{"t", uint64(4096)}},
+			[]interface{}{"ControlGroup", variant{"s", "/system.slice"}},
+		},
+	}
+
+	msg, err := encodeMessage(msgMethodReturn, 3, []interface{}{
+		[]interface{}{byte(fieldReplySerial), variant{"u", uint32(2)}},
+	}, sig, args)
+	require.NoError(t, err)
+
+	decoded, err := readMessage(bytes.NewReader(msg))
+	require.NoError(t, err)
+	assert.Equal(t, byte(msgMethodReturn), decoded.typ)
+	assert.Equal(t, uint32(3), decoded.serial)
+	assert.Equal(t, uint32(2), decoded.replySerial)
+
+	expected := append([]interface{}{}, args[:10]...)
+	expected = append(expected, map[string]interface{}{
+		"MainPID":       uint32(1234),
+		"MemoryCurrent": uint64(4096),
+		"ControlGroup":  "/system.slice",
+	})
+	assert.Equal(t, expected, decoded.body)
+}
+
+func TestDecodeBigEndian(t *testing.T) {
+	d := &decoder{
+		buf:   []byte{0, 0, 0, 3, 'f', 'o', 'o', 0, 0, 0, 0, 0, 0, 0, 0, 9},
+		order: binary.BigEndian,
+	}
+	s, err := d.decode("s")
+	require.NoError(t, err)
+	assert.Equal(t, "foo", s)
+
+	u, err := d.decode("t")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(9), u)
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	msg, err := encodeMessage(msgMethodReturn, 1, nil, "s", []interface{}{"nginx.service"})
+	require.NoError(t, err)
+
+	for n := 0; n < len(msg); n++ {
+		_, err := readMessage(bytes.NewReader(msg[:n]))
+		assert.Error(t, err, "message truncated to %v bytes", n)
+	}
+
+	// A string length beyond the end of the body.
+	d := &decoder{buf: []byte{0xff, 0xff, 0, 0, 'a', 0}, order: binary.LittleEndian}
+	_, err = d.decode("s")
+	assert.Error(t, err)
+}
+
+func TestSplitSignature(t *testing.T) {
+	types, err := splitSignature("sa(ssou)a{sv}u")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"s", "a(ssou)", "a{sv}", "u"}, types)
+
+	for _, sig := range []string{"a", "(ss", "a{sv", "h"} {
+		_, err := splitSignature(sig)
+		assert.Error(t, err, "signature %v", sig)
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	network, path, err := parseAddress("unix:path=/var/run/dbus/system_bus_socket")
+	require.NoError(t, err)
+	assert.Equal(t, "unix", network)
+	assert.Equal(t, "/var/run/dbus/system_bus_socket", path)
+
+	_, path, err = parseAddress("unix:abstract=/tmp/dbus-test,guid=1234")
+	require.NoError(t, err)
+	assert.Equal(t, "@/tmp/dbus-test", path)
+
+	_, _, err = parseAddress("tcp:host=localhost,port=1234")
+	assert.Error(t, err)
+}
+
+// serveFakeBus authenticates the client and replies to its method calls with
+// handle.
+func serveFakeBus(t *testing.T, conn net.Conn, handle func(*message) ([]interface{}, string, []interface{})) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	line, err := r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "\x00AUTH EXTERNAL ") {
+		t.Errorf("unexpected auth request %q: %v", line, err)
+		return
+	}
+	conn.Write([]byte("OK 1234deadbeef\r\n"))
+	if line, err = r.ReadString('\n'); err != nil || line != "BEGIN\r\n" {
+		t.Errorf("unexpected auth command %q: %v", line, err)
+		return
+	}
+
+	var serial uint32
+	for {
+		call, err := readMessage(r)
+		if err != nil {
+			return
+		}
+
+		fields, sig, body := handle(call)
+		fields = append(fields, []interface{}{byte(fieldReplySerial), variant{"u", call.serial}})
+		typ := byte(msgMethodReturn)
+		if len(fields) > 1 {
+			typ = msgError
+		}
+
+		// A signal sent before the reply is skipped by the client.
+		serial++
+		signal, _ := encodeMessage(4, serial, nil, "", nil)
+		conn.Write(signal)
+
+		serial++
+		reply, err := encodeMessage(typ, serial, fields, sig, body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.Write(reply)
+	}
+}
+
+func TestDBusConnCall(t *testing.T) {
+	client, server := net.Pipe()
+	go serveFakeBus(t, server, func(call *message) ([]interface{}, string, []interface{}) {
+		switch call.member {
+		case "Hello":
+			return nil, "s", []interface{}{":1.42"}
+		case "GetAll":
+			return nil, "a{sv}", []interface{}{[]interface{}{
+				[]interface{}{"NRestarts", variant{"u", uint32(3)}},
+			}}
+		}
+		return []interface{}{
+			[]interface{}{byte(fieldErrorName), variant{"s", "org.freedesktop.DBus.Error.UnknownMethod"}},
+		}, "s", []interface{}{"Unknown method " + call.member}
+	})
+
+	conn, err := newDBusConn(client, time.Second)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	reply, err := conn.Call(systemdDest, "/org/freedesktop/systemd1/unit/nginx_2eservice",
+		propertiesIface, "GetAll", "s", "org.freedesktop.systemd1.Service")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{map[string]interface{}{"NRestarts": uint32(3)}}, reply)
+
+	_, err = conn.Call(systemdDest, systemdPath, managerIface, "Reboot", "")
+	if assert.Error(t, err) {
+		assert.Equal(t, "org.freedesktop.DBus.Error.UnknownMethod: Unknown method Reboot", err.Error())
+	}
+}
+
+func TestDBusConnAuthRejected(t *testing.T) {
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		bufio.NewReader(server).ReadString('\n')
+		server.Write([]byte("REJECTED EXTERNAL\r\n"))
+	}()
+
+	_, err := newDBusConn(client, time.Second)
+	assert.Error(t, err)
+}
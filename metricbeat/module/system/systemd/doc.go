@@ -0,0 +1,5 @@
+/*
+Package systemd collects the state and resource usage of systemd units over
+the D-Bus API of systemd.
+*/
+package systemd
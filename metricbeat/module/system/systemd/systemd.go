@@ -0,0 +1,253 @@
+// +build linux
+
+package systemd
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/mb"
+	"github.com/elastic/beats/metricbeat/mb/parse"
+	"github.com/elastic/beats/metricbeat/module/system"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	systemdDest     = "org.freedesktop.systemd1"
+	systemdPath     = "/org/freedesktop/systemd1"
+	managerIface    = "org.freedesktop.systemd1.Manager"
+	propertiesIface = "org.freedesktop.DBus.Properties"
+
+	systemBusSocket = "/var/run/dbus/system_bus_socket"
+)
+
+var debugf = logp.MakeDebug("system.systemd")
+
+// unitInterfaces are the D-Bus interfaces with the cgroup and process
+// properties of a unit, by unit type suffix.
+var unitInterfaces = map[string]string{
+	".service": "org.freedesktop.systemd1.Service",
+	".socket":  "org.freedesktop.systemd1.Socket",
+	".mount":   "org.freedesktop.systemd1.Mount",
+	".swap":    "org.freedesktop.systemd1.Swap",
+	".slice":   "org.freedesktop.systemd1.Slice",
+	".scope":   "org.freedesktop.systemd1.Scope",
+}
+
+func init() {
+	if err := mb.Registry.AddMetricSet("system", "systemd", New, parse.EmptyHostParser); err != nil {
+		panic(err)
+	}
+}
+
+// caller calls methods over D-Bus.
+type caller interface {
+	Call(dest, path, iface, member, sig string, args ...interface{}) ([]interface{}, error)
+	Close() error
+}
+
+// MetricSet reports the systemd units whose names match the configured
+// patterns.
+type MetricSet struct {
+	mb.BaseMetricSet
+	units []string
+	dial  func() (caller, error)
+	conn  caller
+}
+
+// New creates a new instance of the systemd MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	c := defaultConfig
+	if err := base.Module().UnpackConfig(&c); err != nil {
+		return nil, err
+	}
+
+	systemModule, ok := base.Module().(*system.Module)
+	if !ok {
+		return nil, errors.New("unexpected module type")
+	}
+
+	address := os.Getenv("DBUS_SYSTEM_BUS_ADDRESS")
+	if address == "" {
+		address = "unix:path=" + filepath.Join(systemModule.HostFS, systemBusSocket)
+	}
+	timeout := base.Module().Config().Timeout
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		units:         c.Units,
+		dial: func() (caller, error) {
+			return dialDBus(address, timeout)
+		},
+	}, nil
+}
+
+// Fetch returns an event for each unit loaded by systemd matching the
+// configured patterns. The connection to the system bus is opened again on
+// the next fetch after an error.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	if m.conn == nil {
+		conn, err := m.dial()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to connect to the D-Bus system bus")
+		}
+		m.conn = conn
+	}
+
+	events, err := fetchUnits(m.conn, m.units)
+	if err != nil {
+		m.conn.Close()
+		m.conn = nil
+		return nil, err
+	}
+	return events, nil
+}
+
+// Close closes the connection to the system bus.
+func (m *MetricSet) Close() error {
+	if m.conn == nil {
+		return nil
+	}
+	err := m.conn.Close()
+	m.conn = nil
+	return err
+}
+
+// unitStatus is a unit as returned by the ListUnits method.
+type unitStatus struct {
+	name        string
+	description string
+	loadState   string
+	activeState string
+	subState    string
+	path        string
+}
+
+func fetchUnits(c caller, patterns []string) ([]common.MapStr, error) {
+	units, err := listUnits(c)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]common.MapStr, 0, len(units))
+	for _, u := range units {
+		if !matchUnit(u.name, patterns) {
+			continue
+		}
+
+		event := common.MapStr{
+			"name":        u.name,
+			"description": u.description,
+			"load_state":  u.loadState,
+			"state":       u.activeState,
+			"sub_state":   u.subState,
+		}
+
+		if iface, ok := unitInterfaces[filepath.Ext(u.name)]; ok {
+			props, err := unitProperties(c, u.path, iface)
+			if err != nil {
+				// The unit can be unloaded after it has been listed.
+				debugf("failed to get the properties of unit %v: %v", u.name, err)
+			} else {
+				addProperties(event, props)
+			}
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func listUnits(c caller) ([]unitStatus, error) {
+	reply, err := c.Call(systemdDest, systemdPath, managerIface, "ListUnits", "")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the systemd units")
+	}
+
+	if len(reply) != 1 {
+		return nil, errors.New("unexpected reply to ListUnits")
+	}
+	list, ok := reply[0].([]interface{})
+	if !ok {
+		return nil, errors.New("unexpected reply to ListUnits")
+	}
+
+	units := make([]unitStatus, 0, len(list))
+	for _, item := range list {
+		fields, _ := item.([]interface{})
+		if len(fields) < 7 {
+			return nil, errors.New("unexpected unit in the reply to ListUnits")
+		}
+
+		var values [7]string
+		for i := range values {
+			s, ok := fields[i].(string)
+			if !ok {
+				return nil, errors.New("unexpected unit in the reply to ListUnits")
+			}
+			values[i] = s
+		}
+		units = append(units, unitStatus{
+			name:        values[0],
+			description: values[1],
+			loadState:   values[2],
+			activeState: values[3],
+			subState:    values[4],
+			path:        values[6],
+		})
+	}
+	return units, nil
+}
+
+func unitProperties(c caller, path, iface string) (map[string]interface{}, error) {
+	reply, err := c.Call(systemdDest, path, propertiesIface, "GetAll", "s", iface)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(reply) != 1 {
+		return nil, errors.New("unexpected reply to GetAll")
+	}
+	props, ok := reply[0].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("unexpected reply to GetAll")
+	}
+	return props, nil
+}
+
+// addProperties adds the process and cgroup properties of a unit to the
+// event. systemd reports the maximum integer value for the resources that are
+// not accounted, these are not added.
+func addProperties(event common.MapStr, props map[string]interface{}) {
+	if v, ok := props["ControlGroup"].(string); ok && v != "" {
+		event["cgroup"] = v
+	}
+	if v, ok := props["MainPID"].(uint32); ok && v != 0 {
+		event["main_pid"] = v
+	}
+	if v, ok := props["NRestarts"].(uint32); ok {
+		event["restarts"] = v
+	}
+	if v, ok := props["TasksCurrent"].(uint64); ok && v != math.MaxUint64 {
+		event["tasks"] = v
+	}
+	if v, ok := props["MemoryCurrent"].(uint64); ok && v != math.MaxUint64 {
+		event.Put("memory.bytes", v)
+	}
+	if v, ok := props["CPUUsageNSec"].(uint64); ok && v != math.MaxUint64 {
+		event.Put("cpu.ns", v)
+	}
+}
+
+// matchUnit returns true if the name of the unit matches any of the patterns.
+func matchUnit(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+// +build linux
+
+package systemd
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// fakeSystemd replies to the D-Bus calls of the metricset with fixed units
+// and properties.
+type fakeSystemd struct {
+	units  []interface{}
+	props  map[string]map[string]interface{}
+	err    error
+	calls  []string
+	closed bool
+}
+
+func (f *fakeSystemd) Call(dest, path, iface, member, sig string, args ...interface{}) ([]interface{}, error) {
+	f.calls = append(f.calls, member+" "+path)
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	switch member {
+	case "ListUnits":
+		return []interface{}{f.units}, nil
+	case "GetAll":
+		props, ok := f.props[path]
+		if !ok {
+			return nil, &dbusError{name: "org.freedesktop.DBus.Error.UnknownObject"}
+		}
+		return []interface{}{props}, nil
+	}
+	return nil, &dbusError{name: "org.freedesktop.DBus.Error.UnknownMethod"}
+}
+
+func (f *fakeSystemd) Close() error {
+	f.closed = true
+	return nil
+}
+
+func unit(name, load, active, sub string) []interface{} {
+	return []interface{}{
+		name, "Description of " + name, load, active, sub, "",
+		"/org/freedesktop/systemd1/unit/" + name, uint32(0), "", "/",
+	}
+}
+
+func TestFetchUnitStates(t *testing.T) {
+	fake := &fakeSystemd{
+		units: []interface{}{
+			unit("nginx.service", "loaded", "active", "running"),
+			unit("backup.service", "loaded", "failed", "failed"),
+			unit("dbus.socket", "loaded", "active", "listening"),
+			unit("missing.service", "not-found", "inactive", "dead"),
+		},
+		props: map[string]map[string]interface{}{
+			"/org/freedesktop/systemd1/unit/nginx.service": {
+				"ControlGroup":  "/system.slice/nginx.service",
+				"MainPID":       uint32(1234),
+				"NRestarts":     uint32(2),
+				"TasksCurrent":  uint64(5),
+				"MemoryCurrent": uint64(4096),
+				"CPUUsageNSec":  uint64(1000000),
+			},
+			"/org/freedesktop/systemd1/unit/backup.service": {
+				"ControlGroup":  "",
+				"MainPID":       uint32(0),
+				"NRestarts":     uint32(0),
+				"TasksCurrent":  uint64(math.MaxUint64),
+				"MemoryCurrent": uint64(math.MaxUint64),
+				"CPUUsageNSec":  uint64(math.MaxUint64),
+			},
+		},
+	}
+
+	events, err := fetchUnits(fake, []string{"*.service"})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	assert.Equal(t, common.MapStr{
+		"name":        "nginx.service",
+		"description": "Description of nginx.service",
+		"load_state":  "loaded",
+		"state":       "active",
+		"sub_state":   "running",
+		"cgroup":      "/system.slice/nginx.service",
+		"main_pid":    uint32(1234),
+		"restarts":    uint32(2),
+		"tasks":       uint64(5),
+		"memory":      common.MapStr{"bytes": uint64(4096)},
+		"cpu":         common.MapStr{"ns": uint64(1000000)},
+	}, events[0])
+
+	// Resources that are not accounted are not reported.
+	assert.Equal(t, common.MapStr{
+		"name":        "backup.service",
+		"description": "Description of backup.service",
+		"load_state":  "loaded",
+		"state":       "failed",
+		"sub_state":   "failed",
+		"restarts":    uint32(0),
+	}, events[1])
+
+	// Units without properties are reported with their state only.
+	assert.Equal(t, common.MapStr{
+		"name":        "missing.service",
+		"description": "Description of missing.service",
+		"load_state":  "not-found",
+		"state":       "inactive",
+		"sub_state":   "dead",
+	}, events[2])
+}
+
+func TestFetchUnitsFilter(t *testing.T) {
+	fake := &fakeSystemd{
+		units: []interface{}{
+			unit("nginx.service", "loaded", "active", "running"),
+			unit("ssh.service", "loaded", "active", "running"),
+			unit("dbus.socket", "loaded", "active", "listening"),
+			unit("user.slice", "loaded", "active", "active"),
+		},
+	}
+
+	events, err := fetchUnits(fake, []string{"ssh*", "*.socket"})
+	require.NoError(t, err)
+
+	var names []string
+	for _, e := range events {
+		names = append(names, e["name"].(string))
+	}
+	assert.Equal(t, []string{"ssh.service", "dbus.socket"}, names)
+
+	// The properties are only requested for the matching units.
+	assert.Equal(t, []string{
+		"ListUnits " + systemdPath,
+		"GetAll /org/freedesktop/systemd1/unit/ssh.service",
+		"GetAll /org/freedesktop/systemd1/unit/dbus.socket",
+	}, fake.calls)
+}
+
+func TestFetchReconnectsAfterError(t *testing.T) {
+	fake := &fakeSystemd{err: errors.New("connection reset")}
+	dials := 0
+	m := &MetricSet{
+		units: defaultConfig.Units,
+		dial: func() (caller, error) {
+			dials++
+			return fake, nil
+		},
+	}
+
+	_, err := m.Fetch()
+	assert.Error(t, err)
+	assert.True(t, fake.closed)
+
+	fake.err = nil
+	events, err := m.Fetch()
+	require.NoError(t, err)
+	assert.Empty(t, events)
+	assert.Equal(t, 2, dials)
+}
+
+func TestListUnitsUnexpectedReply(t *testing.T) {
+	fake := &fakeSystemd{units: []interface{}{[]interface{}{"nginx.service", "loaded"}}}
+	_, err := listUnits(fake)
+	assert.Error(t, err)
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.NoError(t, (&Config{Units: []string{"*.service", "ssh?.socket"}}).Validate())
+	assert.Error(t, (&Config{Units: []string{"[.service"}}).Validate())
+}
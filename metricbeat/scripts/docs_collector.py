@@ -43,6 +43,8 @@ This file is generated! See scripts/docs_collector.py
         with open(beat_path + "/fields.yml") as f:
             fields = yaml.load(f.read())
             title = fields[0]["title"]
+            # Modules sharing their key with other fields set an anchor
+            anchor = fields[0].get("anchor", module)
 
         modules_list[module] = title
 
@@ -108,7 +110,7 @@ in <<configuration-metricbeat>>. Here is an example configuration:
 ==== Fields
 
 For a description of each field in the metricset, see the
-<<exported-fields-""" + module + """,exported fields>> section.
+<<exported-fields-""" + anchor + """,exported fields>> section.
 
 """
 
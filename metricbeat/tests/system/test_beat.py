@@ -0,0 +1,30 @@
+import metricbeat
+
+BEAT_STATS_FIELDS = ["pid", "cpu", "memory", "runtime", "gc"]
+
+
+class Test(metricbeat.BaseTest):
+
+    def test_stats(self):
+        """
+        beat stats metricset test
+        """
+        self.render_config_template(modules=[{
+            "name": "beat",
+            "metricsets": ["stats"],
+            "period": "1s"
+        }])
+        proc = self.start_beat()
+        self.wait_until(lambda: self.output_lines() > 0)
+        proc.check_kill_and_wait()
+        self.assert_no_logged_warnings()
+
+        output = self.read_output_json()
+        evt = output[0]
+
+        stats = evt["beat"]["stats"]
+        self.assertItemsEqual(BEAT_STATS_FIELDS, stats.keys(), stats)
+        self.assertGreater(stats["runtime"]["goroutines"], 0)
+        self.assertGreater(stats["memory"]["rss"]["bytes"], 0)
+
+        self.assert_fields_are_documented(evt)
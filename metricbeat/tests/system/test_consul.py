@@ -0,0 +1,60 @@
+import os
+import metricbeat
+import unittest
+
+
+class Test(metricbeat.BaseTest):
+
+    COMPOSE_SERVICES = ['consul']
+
+    @unittest.skipUnless(metricbeat.INTEGRATION_TESTS, "integration test")
+    def test_agent(self):
+        """
+        consul agent metricset test
+        """
+        self.render_config_template(modules=[{
+            "name": "consul",
+            "metricsets": ["agent"],
+            "hosts": self.get_hosts(),
+            "period": "1s"
+        }])
+        proc = self.start_beat()
+        self.wait_until(lambda: self.output_lines() > 0, max_timeout=20)
+        proc.check_kill_and_wait()
+        self.assert_no_logged_warnings()
+
+        output = self.read_output_json()
+        self.assertTrue(len(output) >= 1)
+        evt = output[0]
+        print evt
+
+        self.assert_fields_are_documented(evt)
+
+    @unittest.skipUnless(metricbeat.INTEGRATION_TESTS, "integration test")
+    def test_health(self):
+        """
+        consul health metricset test
+        """
+        self.render_config_template(modules=[{
+            "name": "consul",
+            "metricsets": ["health"],
+            "hosts": self.get_hosts(),
+            "period": "1s"
+        }])
+        proc = self.start_beat()
+        self.wait_until(lambda: self.output_lines() > 0, max_timeout=20)
+        proc.check_kill_and_wait()
+        self.assert_no_logged_warnings()
+
+        output = self.read_output_json()
+        self.assertTrue(len(output) >= 1)
+        evt = output[0]
+        print evt
+
+        # The agent of the development server has a single serf health check.
+        self.assertEqual(evt["consul"]["health"]["check"]["id"], "serfHealth")
+        self.assert_fields_are_documented(evt)
+
+    def get_hosts(self):
+        return [os.getenv('CONSUL_HOST', 'localhost') + ':' +
+                os.getenv('CONSUL_PORT', '8500')]